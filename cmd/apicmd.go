@@ -4,25 +4,47 @@ import (
 	"context"
 	"fmt"
 	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/adrianoccosta/exercise-qonto/internal/fees"
 	"github.com/adrianoccosta/exercise-qonto/internal/handlers/bankaccounthdl"
+	"github.com/adrianoccosta/exercise-qonto/internal/handlers/beneficiaryhdl"
 	"github.com/adrianoccosta/exercise-qonto/internal/handlers/healthhdl"
+	"github.com/adrianoccosta/exercise-qonto/internal/handlers/ledgerhdl"
 	"github.com/adrianoccosta/exercise-qonto/internal/handlers/metricshdl"
+	"github.com/adrianoccosta/exercise-qonto/internal/handlers/templatehdl"
 	"github.com/adrianoccosta/exercise-qonto/internal/handlers/transactionhdl"
 	"github.com/adrianoccosta/exercise-qonto/internal/handlers/transferhdl"
+	"github.com/adrianoccosta/exercise-qonto/internal/handlers/webhookhdl"
+	"github.com/adrianoccosta/exercise-qonto/internal/middleware"
 	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/beneficiaryrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/idempotencyrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/templaterepo"
 	"github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/webhookrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/scheduler"
+	"github.com/adrianoccosta/exercise-qonto/internal/screening"
 	"github.com/adrianoccosta/exercise-qonto/internal/services/bankaccountsvc"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/beneficiarysvc"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/ledgersvc"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/templatesvc"
 	"github.com/adrianoccosta/exercise-qonto/internal/services/transactionsvc"
 	"github.com/adrianoccosta/exercise-qonto/internal/services/transfersvc"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/webhooksvc"
+	"github.com/adrianoccosta/exercise-qonto/internal/workerpool"
 	"github.com/adrianoccosta/exercise-qonto/log"
 	"github.com/adrianoccosta/exercise-qonto/tools"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/swaggo/http-swagger"
 	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -40,6 +62,27 @@ const (
 	databaseMaxIdleConnsProp = "database-max-idle-conns"
 	databaseMaxOpenConnsProp = "database-max-open-conns"
 	databaseconnMaxLifetime  = "database-max-conn-lifetime"
+
+	idempotencyKeyRetentionProp = "idempotency-key-retention"
+
+	schedulerIntervalProp = "scheduler-interval"
+
+	jobWorkersProp      = "job-workers"
+	jobPollIntervalProp = "job-poll-interval"
+
+	webhookWorkersProp      = "webhook-workers"
+	webhookPollIntervalProp = "webhook-poll-interval"
+	webhookMaxAttemptsProp  = "webhook-max-attempts"
+	webhookBackoffProp      = "webhook-backoff"
+
+	screeningListPathProp       = "screening-list-path"
+	screeningModeProp           = "screening-mode"
+	screeningThresholdProp      = "screening-threshold"
+	screeningReloadIntervalProp = "screening-reload-interval"
+
+	duplicateWindowProp = "duplicate-window"
+
+	feeSchedulePathProp = "fee-schedule-path"
 )
 
 // APICommand is the command to run the web server
@@ -47,14 +90,39 @@ var APICommand = &cli.Command{
 	Name:   "api",
 	Usage:  "service-qonto API",
 	Action: runAPICommand,
-	Flags: []cli.Flag{
+	Flags: append([]cli.Flag{
 		&cli.IntFlag{Name: listenPortProp, Value: tools.EnvIntOrDefault("PORT", 8080), Usage: "listen port"},
 		&cli.StringFlag{Name: listenAddressProp, Value: "0.0.0.0", Usage: "HTTP listen address"},
-		&cli.StringFlag{Name: databaseFilePathProp, Value: tools.GetEnv("DATABASE_FILE_PATH"), Usage: "database file path (e.g., qonto.db)"},
-		&cli.IntFlag{Name: databaseMaxIdleConnsProp, Value: tools.EnvIntOrDefault("DATABASE_MAX_IDLE_CONNS", 15), Usage: "database max idle connections (e.g., 15)"},
-		&cli.IntFlag{Name: databaseMaxOpenConnsProp, Value: tools.EnvIntOrDefault("DATABASE_MAX_OPEN_CONNS", 15), Usage: "database max open connections (e.g., 15)"},
-		&cli.IntFlag{Name: databaseconnMaxLifetime, Value: tools.EnvIntOrDefault("DATABASE_MAX_CONN_LIFETIME", 30), Usage: "database max connection lifetime in minutes (e.g., 5)"},
-	},
+		idempotencyKeyRetentionFlag,
+		&cli.IntFlag{Name: schedulerIntervalProp, Value: tools.EnvIntOrDefault("SCHEDULER_INTERVAL", 60), Usage: "seconds between runs of the scheduler, 0 to not run it with the API (e.g., 60)"},
+		&cli.IntFlag{Name: jobWorkersProp, Value: tools.EnvIntOrDefault("JOB_WORKERS", 4), Usage: "workers executing the bulk transfers submitted asynchronously, 0 to not run them with the API (e.g., 4)"},
+		&cli.IntFlag{Name: jobPollIntervalProp, Value: tools.EnvIntOrDefault("JOB_POLL_INTERVAL", 1), Usage: "seconds between the checks for queued bulk transfer jobs (e.g., 1)"},
+		&cli.IntFlag{Name: webhookWorkersProp, Value: tools.EnvIntOrDefault("WEBHOOK_WORKERS", 2), Usage: "workers delivering the webhook events, 0 to not run them with the API (e.g., 2)"},
+		&cli.IntFlag{Name: webhookPollIntervalProp, Value: tools.EnvIntOrDefault("WEBHOOK_POLL_INTERVAL", 1), Usage: "seconds between the checks for due webhook deliveries (e.g., 1)"},
+		&cli.IntFlag{Name: webhookMaxAttemptsProp, Value: tools.EnvIntOrDefault("WEBHOOK_MAX_ATTEMPTS", 8), Usage: "attempts of a webhook delivery before it fails (e.g., 8)"},
+		&cli.IntFlag{Name: webhookBackoffProp, Value: tools.EnvIntOrDefault("WEBHOOK_BACKOFF", 30), Usage: "seconds before attempting a webhook delivery again, doubled after every attempt (e.g., 30)"},
+	}, append(databaseFlags, executionFlags...)...),
+}
+
+// idempotencyKeyRetentionFlag the retention of the idempotency keys, checked by the API and purged by the scheduler
+var idempotencyKeyRetentionFlag = &cli.IntFlag{Name: idempotencyKeyRetentionProp, Value: tools.EnvIntOrDefault("IDEMPOTENCY_KEY_RETENTION", 24), Usage: "idempotency keys retention in hours (e.g., 24)"}
+
+// databaseFlags the flags of the database connection, shared by every command
+var databaseFlags = []cli.Flag{
+	&cli.StringFlag{Name: databaseFilePathProp, Value: tools.GetEnv("DATABASE_FILE_PATH"), Usage: "database file path (e.g., qonto.db)"},
+	&cli.IntFlag{Name: databaseMaxIdleConnsProp, Value: tools.EnvIntOrDefault("DATABASE_MAX_IDLE_CONNS", 15), Usage: "database max idle connections (e.g., 15)"},
+	&cli.IntFlag{Name: databaseMaxOpenConnsProp, Value: tools.EnvIntOrDefault("DATABASE_MAX_OPEN_CONNS", 15), Usage: "database max open connections (e.g., 15)"},
+	&cli.IntFlag{Name: databaseconnMaxLifetime, Value: tools.EnvIntOrDefault("DATABASE_MAX_CONN_LIFETIME", 30), Usage: "database max connection lifetime in minutes (e.g., 5)"},
+}
+
+// executionFlags the flags of the checks of the bulk transfers, shared by the commands executing them
+var executionFlags = []cli.Flag{
+	&cli.StringFlag{Name: screeningListPathProp, Value: tools.GetEnv("SCREENING_LIST_PATH"), Usage: "sanctions list or blocklist, a .csv file or a .json snapshot, the counterparties aren't screened when empty (e.g., sanctions.csv)"},
+	&cli.StringFlag{Name: screeningModeProp, Value: tools.EnvOrDefault("SCREENING_MODE", string(screening.ModeReject)), Usage: "what happens to the bulk transfers paying a listed counterparty, reject or review (e.g., reject)"},
+	&cli.Float64Flag{Name: screeningThresholdProp, Value: tools.EnvFloat64OrDefault("SCREENING_THRESHOLD", screening.DefaultThreshold), Usage: "similarity from which a counterparty name matches a listed name, from 0 to 1 (e.g., 0.9)"},
+	&cli.IntFlag{Name: screeningReloadIntervalProp, Value: tools.EnvIntOrDefault("SCREENING_RELOAD_INTERVAL", 10), Usage: "seconds between the checks of the screening list for changes (e.g., 10)"},
+	&cli.IntFlag{Name: duplicateWindowProp, Value: tools.EnvIntOrDefault("DUPLICATE_WINDOW", 48), Usage: "hours within which paying the same counterparty the same amount is a suspected duplicate, 0 to not detect them (e.g., 48)"},
+	&cli.StringFlag{Name: feeSchedulePathProp, Value: tools.GetEnv("FEE_SCHEDULE_PATH"), Usage: "fee schedule, a .json file of fee rules, the transfers aren't charged when empty (e.g., fees.json)"},
 }
 
 func runAPICommand(ctx *cli.Context) error {
@@ -62,7 +130,27 @@ func runAPICommand(ctx *cli.Context) error {
 	addr := fmt.Sprintf("%s:%d", ctx.String(listenAddressProp), ctx.Int(listenPortProp))
 	logger := ctx.App.Metadata["Logger"].(log.Logger)
 
-	r := configAPIHandlers(ctx, logger)
+	// scheduler
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+
+	r, jobs, pools := configAPIHandlers(ctx, schedulerCtx, logger)
+	if interval := ctx.Int(schedulerIntervalProp); interval > 0 {
+		go scheduler.New(time.Duration(interval)*time.Second, logger, jobs...).Start(schedulerCtx)
+	}
+
+	// workers, the jobs and deliveries left unfinished by a previous run are resumed right away
+	var workersDone sync.WaitGroup
+	for _, pool := range pools {
+		if pool.workers <= 0 {
+			continue
+		}
+		workersDone.Add(1)
+		go func(pool workers) {
+			defer workersDone.Done()
+			workerpool.New(pool.workers, pool.interval, pool.source, logger).Start(schedulerCtx)
+		}(pool)
+	}
 
 	// server
 	s := &http.Server{
@@ -93,6 +181,7 @@ func runAPICommand(ctx *cli.Context) error {
 		logger.WithError(err).Fatal("error starting server")
 
 	case <-osSignals:
+		stopScheduler()
 		// Create a context to attempt a graceful 5 second shutdown.
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -105,6 +194,8 @@ func runAPICommand(ctx *cli.Context) error {
 				logger.WithError(err).Fatal("could not stop http server")
 			}
 		}
+		// the running jobs and deliveries are finished before leaving
+		workersDone.Wait()
 	}
 
 	logger.Info("Shut down successful")
@@ -112,7 +203,14 @@ func runAPICommand(ctx *cli.Context) error {
 	return nil
 }
 
-func configAPIHandlers(ctx *cli.Context, logger log.Logger) *mux.Router {
+// workers a pool of workers run along with the API
+type workers struct {
+	workers  int
+	interval time.Duration
+	source   workerpool.Source
+}
+
+func configAPIHandlers(ctx *cli.Context, watchCtx context.Context, logger log.Logger) (*mux.Router, []scheduler.Job, []workers) {
 
 	buildTime := fmt.Sprint(ctx.App.Metadata["BuildTime"])
 	commitVersion := fmt.Sprint(ctx.App.Metadata["CommitVersion"])
@@ -134,30 +232,40 @@ func configAPIHandlers(ctx *cli.Context, logger log.Logger) *mux.Router {
 	// simpler/default metrics configuration
 	metrics := metricshdl.NewMetrics(prometheusNamespace, prometheusSubsystem)
 
-	// rds connection. Probably we need to set this to a common path
-	conn := config.DBConnection{
-		Path:              ctx.String(databaseFilePathProp),
-		MaxIdleConns:      ctx.Int(databaseMaxIdleConnsProp),
-		MaxOpenConns:      ctx.Int(databaseMaxOpenConnsProp),
-		ConnMaxTTLMinutes: ctx.Int(databaseconnMaxLifetime),
-	}
-	rds := config.InitDBConnection(conn, logger)
+	rds := configDatabase(ctx, logger)
 
 	// Repository
 	bankAccountRepository := bankaccountrepo.New(rds)
 	transactionRepository := transactionrepo.New(rds)
+	bulkTransferRepository := bulktransferrepo.New(rds)
+	idempotencyRepository := idempotencyrepo.New(rds)
+	templateRepository := templaterepo.New(rds)
+	ledgerRepository := ledgerrepo.New(rds)
+	beneficiaryRepository := beneficiaryrepo.New(rds)
+	webhookRepository := webhookrepo.New(rds)
+	unitOfWork := uow.New(rds)
+	screener := configScreener(ctx, watchCtx, logger)
 
 	// services
-	bankAccountService := bankaccountsvc.New(bankAccountRepository, logger)
-	transactionService := transactionsvc.New(transactionRepository, logger)
-	transferService := transfersvc.New(transactionRepository, bankAccountRepository, logger)
+	bankAccountService := bankaccountsvc.New(unitOfWork, bankAccountRepository, tools.SystemClock{}, logger)
+	transactionService := transactionsvc.New(unitOfWork, transactionRepository, tools.SystemClock{}, logger)
+	transferService := transfersvc.New(unitOfWork, bulkTransferRepository, transactionRepository, screener, duplicateWindow(ctx), configFees(ctx, logger), tools.SystemClock{}, logger)
+	templateService := templatesvc.New(unitOfWork, templateRepository, transferService, tools.SystemClock{}, logger)
+	ledgerService := ledgersvc.New(ledgerRepository, bankAccountRepository, logger)
+	beneficiaryService := beneficiarysvc.New(unitOfWork, beneficiaryRepository, tools.SystemClock{}, logger)
+	webhookService := webhooksvc.New(unitOfWork, webhookRepository, &http.Client{Timeout: 10 * time.Second}, webhookRetry(ctx), tools.SystemClock{}, logger)
 
 	// handlers
 	handlerHealth := healthhdl.New(ctx.App.Name, ctx.App.Version, buildTime, commitVersion, pipelineNumber, rds.DBHealth())
 
 	handlerBankAccount := bankaccounthdl.New(bankAccountService, logger)
-	handlertransaction := transactionhdl.New(transactionService, logger)
-	handlerTransfer := transferhdl.New(transferService, logger)
+	idempotency := middleware.Idempotency(idempotencyRepository, idempotencyKeyRetention(ctx), tools.SystemClock{}, logger)
+	handlertransaction := transactionhdl.New(transactionService, idempotency, logger)
+	handlerTransfer := transferhdl.New(transferService, idempotency, logger)
+	handlerTemplate := templatehdl.New(templateService, idempotency, logger)
+	handlerLedger := ledgerhdl.New(ledgerService, logger)
+	handlerBeneficiary := beneficiaryhdl.New(beneficiaryService, logger)
+	handlerWebhook := webhookhdl.New(webhookService, logger)
 
 	apiRouter := r.PathPrefix("/qonto/api").Subrouter()
 
@@ -176,6 +284,103 @@ func configAPIHandlers(ctx *cli.Context, logger log.Logger) *mux.Router {
 	handlerBankAccount.Handlers(apiV1Router)
 	handlertransaction.Handlers(apiV1Router)
 	handlerTransfer.Handlers(apiV1Router)
+	handlerTemplate.Handlers(apiV1Router)
+	handlerLedger.Handlers(apiV1Router)
+	handlerBeneficiary.Handlers(apiV1Router)
+	handlerWebhook.Handlers(apiV1Router)
+
+	return r, scheduledJobs(ctx, transferService, templateService, idempotencyRepository), []workers{
+		{workers: ctx.Int(jobWorkersProp), interval: time.Duration(ctx.Int(jobPollIntervalProp)) * time.Second, source: jobSource(transferService)},
+		{workers: ctx.Int(webhookWorkersProp), interval: time.Duration(ctx.Int(webhookPollIntervalProp)) * time.Second, source: deliverySource(webhookService)},
+	}
+}
+
+// configDatabase opens the database connection and migrates its schema
+func configDatabase(ctx *cli.Context, logger log.Logger) config.Conn {
+	// rds connection. Probably we need to set this to a common path
+	conn := config.DBConnection{
+		Path:              ctx.String(databaseFilePathProp),
+		MaxIdleConns:      ctx.Int(databaseMaxIdleConnsProp),
+		MaxOpenConns:      ctx.Int(databaseMaxOpenConnsProp),
+		ConnMaxTTLMinutes: ctx.Int(databaseconnMaxLifetime),
+	}
+	rds := config.InitDBConnection(conn, logger)
+	if err := config.Migrate(rds); err != nil {
+		logger.WithError(err).Fatal("could not migrate the database schema")
+	}
+
+	return rds
+}
+
+// configScreener loads the screening list, watching it for changes until the context is done. The
+// counterparties aren't screened when no list is configured.
+func configScreener(ctx *cli.Context, watchCtx context.Context, logger log.Logger) screening.Screener {
+	path := ctx.String(screeningListPathProp)
+	if path == "" {
+		logger.Warn("no screening list configured, the counterparties aren't screened")
+		return screening.Disabled{}
+	}
+
+	screener, err := screening.NewFileScreener(path, screening.Mode(ctx.String(screeningModeProp)), ctx.Float64(screeningThresholdProp), logger)
+	if err != nil {
+		logger.WithError(err).Fatal("could not load the screening list")
+	}
+	if interval := ctx.Int(screeningReloadIntervalProp); interval > 0 {
+		go screener.Watch(watchCtx, time.Duration(interval)*time.Second)
+	}
+
+	return screener
+}
+
+// duplicateWindow how far back the payments are looked for duplicates
+func duplicateWindow(ctx *cli.Context) time.Duration {
+	return time.Duration(ctx.Int(duplicateWindowProp)) * time.Hour
+}
+
+// idempotencyKeyRetention how long the idempotency keys are kept
+func idempotencyKeyRetention(ctx *cli.Context) time.Duration {
+	return time.Duration(ctx.Int(idempotencyKeyRetentionProp)) * time.Hour
+}
+
+// webhookRetry how the webhook deliveries the receivers didn't acknowledge are attempted again
+func webhookRetry(ctx *cli.Context) webhooksvc.Retry {
+	return webhooksvc.Retry{
+		MaxAttempts: ctx.Int(webhookMaxAttemptsProp),
+		Backoff:     time.Duration(ctx.Int(webhookBackoffProp)) * time.Second,
+	}
+}
+
+// configFees loads the fee schedule, the transfers aren't charged when none is configured
+func configFees(ctx *cli.Context, logger log.Logger) fees.Schedule {
+	path := ctx.String(feeSchedulePathProp)
+	if path == "" {
+		return fees.Schedule{}
+	}
+
+	schedule, err := fees.Load(path)
+	if err != nil {
+		logger.WithError(err).Fatal("could not load the fee schedule")
+	}
+	logger.Info("fee schedule loaded", zap.String("path", path), zap.Int("rules", schedule.Len()))
+
+	return schedule
+}
+
+// scheduledJobs the jobs run by the scheduler
+func scheduledJobs(ctx *cli.Context, transferService transfersvc.TransferService, templateService templatesvc.TemplateService, idempotencyRepository idempotencyrepo.IdempotencyRepository) []scheduler.Job {
+	return []scheduler.Job{
+		{Name: "execute-scheduled-bulk-transfers", Run: transferService.ExecuteDue},
+		{Name: "instantiate-transfer-templates", Run: templateService.InstantiateDue},
+		{Name: "purge-idempotency-keys", Run: middleware.PurgeIdempotencyKeys(idempotencyRepository, idempotencyKeyRetention(ctx), tools.SystemClock{})},
+	}
+}
+
+// jobSource the bulk transfer jobs run by the workers
+func jobSource(transferService transfersvc.TransferService) workerpool.Source {
+	return workerpool.Source{Pending: transferService.PendingJobs, Run: transferService.RunJob}
+}
 
-	return r
+// deliverySource the webhook deliveries attempted by the workers
+func deliverySource(webhookService webhooksvc.WebhookService) workerpool.Source {
+	return workerpool.Source{Pending: webhookService.PendingDeliveries, Run: webhookService.Deliver}
 }
@@ -0,0 +1,534 @@
+package config
+
+import "time"
+
+// migration represents a versioned change to the database schema
+type migration struct {
+	version    int
+	statements []string
+}
+
+// migrations lists every schema change in the order they must be applied.
+// Applied migrations must never be edited, add a new version instead.
+var migrations = []migration{
+	{
+		version: 1,
+		statements: []string{
+			"CREATE TABLE IF NOT EXISTS transactions (" +
+				"id INTEGER PRIMARY KEY, " +
+				"counterparty_name TEXT, " +
+				"counterparty_iban TEXT, " +
+				"counterparty_bic TEXT, " +
+				"amount_cents INTEGER, " +
+				"amount_currency TEXT, " +
+				"bank_account_id INTEGER, " +
+				"description TEXT)",
+			"CREATE TABLE IF NOT EXISTS bank_accounts (" +
+				"id INTEGER PRIMARY KEY, " +
+				"organization_name TEXT, " +
+				"balance_cents INTEGER, " +
+				"iban TEXT, " +
+				"bic TEXT)",
+		},
+	},
+	{
+		version: 2,
+		statements: []string{
+			"CREATE TABLE idempotency_keys (" +
+				"idempotency_key TEXT PRIMARY KEY, " +
+				"request_hash TEXT NOT NULL, " +
+				"status_code INTEGER NOT NULL DEFAULT 0, " +
+				"content_type TEXT NOT NULL DEFAULT '', " +
+				"response_body BLOB, " +
+				"created_at DATETIME NOT NULL)",
+			"CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at)",
+		},
+	},
+	{
+		version: 3,
+		statements: []string{
+			"CREATE TABLE bulk_transfers (" +
+				"id INTEGER PRIMARY KEY, " +
+				"bank_account_id INTEGER, " +
+				"organization_name TEXT NOT NULL, " +
+				"organization_iban TEXT NOT NULL, " +
+				"organization_bic TEXT NOT NULL, " +
+				"transfers_count INTEGER NOT NULL, " +
+				"total_cents INTEGER NOT NULL, " +
+				"status TEXT NOT NULL, " +
+				"failure_reason TEXT NOT NULL DEFAULT '', " +
+				"created_at DATETIME NOT NULL, " +
+				"updated_at DATETIME NOT NULL)",
+			"CREATE INDEX idx_bulk_transfers_organization_iban ON bulk_transfers (organization_iban)",
+			"ALTER TABLE transactions ADD COLUMN bulk_transfer_id INTEGER REFERENCES bulk_transfers (id)",
+			"ALTER TABLE transactions ADD COLUMN created_at DATETIME",
+			"CREATE INDEX idx_transactions_bulk_transfer_id ON transactions (bulk_transfer_id)",
+		},
+	},
+	{
+		version: 4,
+		statements: []string{
+			"ALTER TABLE bulk_transfers ADD COLUMN execution_date DATETIME",
+			"CREATE INDEX idx_bulk_transfers_status_execution_date ON bulk_transfers (status, execution_date)",
+			"CREATE TABLE bulk_transfer_lines (" +
+				"id INTEGER PRIMARY KEY, " +
+				"bulk_transfer_id INTEGER NOT NULL REFERENCES bulk_transfers (id), " +
+				"line_index INTEGER NOT NULL, " +
+				"counterparty_name TEXT NOT NULL, " +
+				"counterparty_iban TEXT NOT NULL, " +
+				"counterparty_bic TEXT NOT NULL, " +
+				"amount_cents INTEGER NOT NULL, " +
+				"amount_currency TEXT NOT NULL, " +
+				"description TEXT NOT NULL DEFAULT '', " +
+				"UNIQUE (bulk_transfer_id, line_index))",
+		},
+	},
+	{
+		version: 5,
+		statements: []string{
+			"CREATE TABLE transfer_templates (" +
+				"id INTEGER PRIMARY KEY, " +
+				"name TEXT NOT NULL, " +
+				"organization_name TEXT NOT NULL, " +
+				"organization_iban TEXT NOT NULL, " +
+				"organization_bic TEXT NOT NULL, " +
+				"frequency TEXT NOT NULL, " +
+				"interval_count INTEGER NOT NULL, " +
+				"day_of_month INTEGER NOT NULL DEFAULT 0, " +
+				"transfers_count INTEGER NOT NULL, " +
+				"total_cents INTEGER NOT NULL, " +
+				"start_date DATETIME NOT NULL, " +
+				"next_occurrence DATETIME NOT NULL, " +
+				"last_occurrence DATETIME, " +
+				"status TEXT NOT NULL, " +
+				"created_at DATETIME NOT NULL, " +
+				"updated_at DATETIME NOT NULL)",
+			"CREATE INDEX idx_transfer_templates_status_next_occurrence ON transfer_templates (status, next_occurrence)",
+			"CREATE TABLE transfer_template_lines (" +
+				"id INTEGER PRIMARY KEY, " +
+				"template_id INTEGER NOT NULL REFERENCES transfer_templates (id), " +
+				"line_index INTEGER NOT NULL, " +
+				"counterparty_name TEXT NOT NULL, " +
+				"counterparty_iban TEXT NOT NULL, " +
+				"counterparty_bic TEXT NOT NULL, " +
+				"amount_cents INTEGER NOT NULL, " +
+				"amount_currency TEXT NOT NULL, " +
+				"description TEXT NOT NULL DEFAULT '', " +
+				"UNIQUE (template_id, line_index))",
+			"ALTER TABLE bulk_transfers ADD COLUMN template_id INTEGER REFERENCES transfer_templates (id)",
+			"CREATE INDEX idx_bulk_transfers_template_id ON bulk_transfers (template_id)",
+		},
+	},
+	{
+		version: 6,
+		statements: []string{
+			"ALTER TABLE bulk_transfers ADD COLUMN cancellation_reason TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE bulk_transfers ADD COLUMN cancelled_by TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE bulk_transfers ADD COLUMN cancelled_at DATETIME",
+			"ALTER TABLE bulk_transfer_lines ADD COLUMN cancellation_reason TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE bulk_transfer_lines ADD COLUMN cancelled_by TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE bulk_transfer_lines ADD COLUMN cancelled_at DATETIME",
+		},
+	},
+	{
+		version: 7,
+		statements: []string{
+			"ALTER TABLE transactions ADD COLUMN direction TEXT NOT NULL DEFAULT 'outgoing'",
+			"CREATE INDEX idx_transactions_bank_account_id ON transactions (bank_account_id)",
+		},
+	},
+	{
+		version: 8,
+		statements: []string{
+			"ALTER TABLE transactions ADD COLUMN reversed_transaction_id INTEGER REFERENCES transactions (id)",
+			"ALTER TABLE transactions ADD COLUMN reversed_cents INTEGER NOT NULL DEFAULT 0",
+			"CREATE INDEX idx_transactions_reversed_transaction_id ON transactions (reversed_transaction_id)",
+		},
+	},
+	{
+		version: 9,
+		statements: []string{
+			"CREATE TABLE ledger_journals (" +
+				"id INTEGER PRIMARY KEY, " +
+				"operation TEXT NOT NULL, " +
+				"reference TEXT NOT NULL DEFAULT '', " +
+				"description TEXT NOT NULL DEFAULT '', " +
+				"created_at DATETIME NOT NULL)",
+			"CREATE TABLE ledger_entries (" +
+				"id INTEGER PRIMARY KEY, " +
+				"journal_id INTEGER NOT NULL REFERENCES ledger_journals (id), " +
+				"account TEXT NOT NULL, " +
+				"side TEXT NOT NULL CHECK (side IN ('debit', 'credit')), " +
+				"amount_cents INTEGER NOT NULL CHECK (amount_cents > 0), " +
+				"currency TEXT NOT NULL, " +
+				"transaction_id INTEGER REFERENCES transactions (id), " +
+				"created_at DATETIME NOT NULL)",
+			"CREATE INDEX idx_ledger_entries_account ON ledger_entries (account)",
+			"CREATE INDEX idx_ledger_entries_journal_id ON ledger_entries (journal_id)",
+			"CREATE INDEX idx_ledger_entries_transaction_id ON ledger_entries (transaction_id)",
+			// the balances held before the ledger existed are posted as opening balances
+			"INSERT INTO ledger_journals (operation, reference, description, created_at) " +
+				"SELECT 'opening_balance', 'migration', 'balances held before the ledger', CURRENT_TIMESTAMP " +
+				"WHERE EXISTS (SELECT 1 FROM bank_accounts WHERE balance_cents <> 0)",
+			"INSERT INTO ledger_entries (journal_id, account, side, amount_cents, currency, created_at) " +
+				"SELECT (SELECT MAX(id) FROM ledger_journals), 'bank_account:' || id, CASE WHEN balance_cents > 0 THEN 'credit' ELSE 'debit' END, ABS(balance_cents), 'EUR', CURRENT_TIMESTAMP " +
+				"FROM bank_accounts WHERE balance_cents <> 0",
+			"INSERT INTO ledger_entries (journal_id, account, side, amount_cents, currency, created_at) " +
+				"SELECT (SELECT MAX(id) FROM ledger_journals), 'equity', CASE WHEN balance_cents > 0 THEN 'debit' ELSE 'credit' END, ABS(balance_cents), 'EUR', CURRENT_TIMESTAMP " +
+				"FROM bank_accounts WHERE balance_cents <> 0",
+		},
+	},
+	{
+		version: 10,
+		statements: []string{
+			// the balances of the existing accounts are assumed consistent with their transactions
+			"ALTER TABLE bank_accounts ADD COLUMN opening_balance_cents INTEGER NOT NULL DEFAULT 0",
+			"UPDATE bank_accounts SET opening_balance_cents = balance_cents - COALESCE((" +
+				"SELECT SUM(CASE direction WHEN 'incoming' THEN amount_cents ELSE -amount_cents END) " +
+				"FROM transactions WHERE bank_account_id = bank_accounts.id), 0)",
+			"CREATE TABLE balance_adjustments (" +
+				"id INTEGER PRIMARY KEY, " +
+				"bank_account_id INTEGER NOT NULL, " +
+				"amount_cents INTEGER NOT NULL, " +
+				"reason TEXT NOT NULL, " +
+				"created_at DATETIME NOT NULL)",
+			"CREATE INDEX balance_adjustments_bank_account_id ON balance_adjustments (bank_account_id)",
+		},
+	},
+	{
+		version: 11,
+		statements: []string{
+			// zero when the limit doesn't apply
+			"ALTER TABLE bank_accounts ADD COLUMN transaction_limit_cents INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE bank_accounts ADD COLUMN batch_limit_cents INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE bank_accounts ADD COLUMN daily_limit_cents INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE bank_accounts ADD COLUMN monthly_limit_cents INTEGER NOT NULL DEFAULT 0",
+			"CREATE INDEX idx_transactions_bank_account_id_created_at ON transactions (bank_account_id, created_at)",
+		},
+	},
+	{
+		version: 12,
+		statements: []string{
+			// zero when the bulk transfers of the account never need approval
+			"ALTER TABLE bank_accounts ADD COLUMN approval_threshold_cents INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE bank_accounts ADD COLUMN required_approvals INTEGER NOT NULL DEFAULT 0",
+			"CREATE TABLE bank_account_approvers (" +
+				"bank_account_id INTEGER NOT NULL REFERENCES bank_accounts (id), " +
+				"approver TEXT NOT NULL, " +
+				"PRIMARY KEY (bank_account_id, approver))",
+			"ALTER TABLE bulk_transfers ADD COLUMN submitted_by TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE bulk_transfers ADD COLUMN required_approvals INTEGER NOT NULL DEFAULT 0",
+			"CREATE TABLE bulk_transfer_decisions (" +
+				"id INTEGER PRIMARY KEY, " +
+				"bulk_transfer_id INTEGER NOT NULL REFERENCES bulk_transfers (id), " +
+				"actor TEXT NOT NULL, " +
+				"decision TEXT NOT NULL CHECK (decision IN ('approved', 'rejected')), " +
+				"comment TEXT NOT NULL DEFAULT '', " +
+				"created_at DATETIME NOT NULL, " +
+				"UNIQUE (bulk_transfer_id, actor))",
+		},
+	},
+	{
+		version: 13,
+		statements: []string{
+			"CREATE TABLE beneficiaries (" +
+				"id INTEGER PRIMARY KEY, " +
+				"bank_account_id INTEGER NOT NULL REFERENCES bank_accounts (id), " +
+				"name TEXT NOT NULL, " +
+				"iban TEXT NOT NULL, " +
+				"bic TEXT NOT NULL, " +
+				"created_at DATETIME NOT NULL, " +
+				"updated_at DATETIME NOT NULL, " +
+				"UNIQUE (bank_account_id, iban))",
+			// the beneficiary a credit transfer referenced instead of holding its counterparty
+			"ALTER TABLE bulk_transfer_lines ADD COLUMN beneficiary_id INTEGER REFERENCES beneficiaries (id)",
+			"ALTER TABLE transfer_template_lines ADD COLUMN beneficiary_id INTEGER REFERENCES beneficiaries (id)",
+			"ALTER TABLE transactions ADD COLUMN beneficiary_id INTEGER REFERENCES beneficiaries (id)",
+			"CREATE INDEX idx_transactions_bank_account_id_counterparty_iban ON transactions (bank_account_id, counterparty_iban)",
+			// the counterparties paid, or to be paid, so far become beneficiaries of the paying account. The latest
+			// details of a counterparty are kept, the transactions coming first.
+			"INSERT OR IGNORE INTO beneficiaries (bank_account_id, name, iban, bic, created_at, updated_at) " +
+				"SELECT bank_account_id, COALESCE(counterparty_name, ''), counterparty_iban, COALESCE(counterparty_bic, ''), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP " +
+				"FROM transactions WHERE direction = 'outgoing' AND bulk_transfer_id IS NOT NULL AND COALESCE(counterparty_iban, '') <> '' " +
+				"ORDER BY id DESC",
+			"INSERT OR IGNORE INTO beneficiaries (bank_account_id, name, iban, bic, created_at, updated_at) " +
+				"SELECT a.id, l.counterparty_name, l.counterparty_iban, l.counterparty_bic, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP " +
+				"FROM bulk_transfer_lines l " +
+				"INNER JOIN bulk_transfers t ON t.id = l.bulk_transfer_id " +
+				"INNER JOIN bank_accounts a ON a.iban = t.organization_iban " +
+				"ORDER BY l.id DESC",
+			"INSERT OR IGNORE INTO beneficiaries (bank_account_id, name, iban, bic, created_at, updated_at) " +
+				"SELECT a.id, l.counterparty_name, l.counterparty_iban, l.counterparty_bic, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP " +
+				"FROM transfer_template_lines l " +
+				"INNER JOIN transfer_templates t ON t.id = l.template_id " +
+				"INNER JOIN bank_accounts a ON a.iban = t.organization_iban " +
+				"ORDER BY l.id DESC",
+		},
+	},
+	{
+		version: 14,
+		statements: []string{
+			// how many screening matches held the bulk transfer for review, zero when it never was
+			"ALTER TABLE bulk_transfers ADD COLUMN screening_hits INTEGER NOT NULL DEFAULT 0",
+			"CREATE TABLE screening_matches (" +
+				"id INTEGER PRIMARY KEY, " +
+				"bulk_transfer_id INTEGER NOT NULL REFERENCES bulk_transfers (id), " +
+				"line_index INTEGER NOT NULL, " +
+				"field TEXT NOT NULL, " +
+				"entry_id TEXT NOT NULL, " +
+				"entry_name TEXT NOT NULL, " +
+				"entry_source TEXT NOT NULL DEFAULT '', " +
+				"score REAL NOT NULL, " +
+				"created_at DATETIME NOT NULL)",
+			"CREATE INDEX idx_screening_matches_bulk_transfer_id ON screening_matches (bulk_transfer_id)",
+			"CREATE TABLE screening_reviews (" +
+				"id INTEGER PRIMARY KEY, " +
+				"bulk_transfer_id INTEGER NOT NULL UNIQUE REFERENCES bulk_transfers (id), " +
+				"actor TEXT NOT NULL, " +
+				"outcome TEXT NOT NULL CHECK (outcome IN ('released', 'blocked')), " +
+				"comment TEXT NOT NULL DEFAULT '', " +
+				"created_at DATETIME NOT NULL)",
+		},
+	},
+	{
+		version: 15,
+		statements: []string{
+			// the bulk transfer is executed even though it looks like a duplicate of a recent one
+			"ALTER TABLE bulk_transfers ADD COLUMN allow_duplicates BOOLEAN NOT NULL DEFAULT 0",
+			// the duplicate payments lookup, it also serves the lookups of the first payments to a counterparty
+			"CREATE INDEX idx_transactions_duplicates ON transactions (bank_account_id, counterparty_iban, amount_cents, created_at)",
+			"DROP INDEX idx_transactions_bank_account_id_counterparty_iban",
+		},
+	},
+	{
+		version: 16,
+		statements: []string{
+			// how far below zero the balance may go, no overdraft is authorised by default
+			"ALTER TABLE bank_accounts ADD COLUMN overdraft_limit_cents INTEGER NOT NULL DEFAULT 0",
+			// the part of an outgoing transaction paid from the overdraft
+			"ALTER TABLE transactions ADD COLUMN overdrawn_cents INTEGER NOT NULL DEFAULT 0",
+			"CREATE INDEX idx_transactions_overdrawn ON transactions (bank_account_id, created_at) WHERE overdrawn_cents > 0",
+		},
+	},
+	{
+		version: 17,
+		statements: []string{
+			// the plan of the account picks the rules of the fee schedule
+			"ALTER TABLE bank_accounts ADD COLUMN plan TEXT NOT NULL DEFAULT ''",
+			// the transfer a fee transaction was charged for, NULL for any other transaction
+			"ALTER TABLE transactions ADD COLUMN fee_of_transaction_id INTEGER REFERENCES transactions (id)",
+		},
+	},
+	{
+		version: 18,
+		statements: []string{
+			// a best effort bulk transfer executes what it can instead of being rejected as a whole
+			"ALTER TABLE bulk_transfers ADD COLUMN execution_mode TEXT NOT NULL DEFAULT 'all_or_nothing'",
+			// the lines of a best effort bulk transfer are executed by priority
+			"ALTER TABLE bulk_transfer_lines ADD COLUMN priority INTEGER NOT NULL DEFAULT 0",
+			// the outcome of every line of a best effort bulk transfer once executed
+			"CREATE TABLE bulk_transfer_outcomes (" +
+				"id INTEGER PRIMARY KEY, " +
+				"bulk_transfer_id INTEGER NOT NULL REFERENCES bulk_transfers (id), " +
+				"line_index INTEGER NOT NULL, " +
+				"status TEXT NOT NULL CHECK (status IN ('executed', 'rejected', 'skipped')), " +
+				"reason TEXT NOT NULL DEFAULT '', " +
+				"message TEXT NOT NULL DEFAULT '', " +
+				"transaction_id INTEGER REFERENCES transactions (id), " +
+				"created_at DATETIME NOT NULL, " +
+				"UNIQUE (bulk_transfer_id, line_index))",
+		},
+	},
+	{
+		version: 19,
+		statements: []string{
+			// the bulk transfers submitted asynchronously are executed by the workers, and resumed on restart
+			"CREATE TABLE bulk_transfer_jobs (" +
+				"id INTEGER PRIMARY KEY, " +
+				"bulk_transfer_id INTEGER NOT NULL UNIQUE REFERENCES bulk_transfers (id), " +
+				"status TEXT NOT NULL CHECK (status IN ('queued', 'running', 'done', 'failed')), " +
+				"lines_total INTEGER NOT NULL, " +
+				"lines_done INTEGER NOT NULL DEFAULT 0, " +
+				"error TEXT NOT NULL DEFAULT '', " +
+				"created_at DATETIME NOT NULL, " +
+				"started_at DATETIME, " +
+				"finished_at DATETIME, " +
+				"updated_at DATETIME NOT NULL)",
+			"CREATE INDEX idx_bulk_transfer_jobs_status ON bulk_transfer_jobs (status)",
+		},
+	},
+	{
+		version: 20,
+		statements: []string{
+			// the subscriptions of an organization to its events, deleted ones are kept for their deliveries
+			"CREATE TABLE webhooks (" +
+				"id INTEGER PRIMARY KEY, " +
+				"bank_account_id INTEGER NOT NULL REFERENCES bank_accounts (id), " +
+				"url TEXT NOT NULL, " +
+				"secret TEXT NOT NULL, " +
+				"created_at DATETIME NOT NULL, " +
+				"deleted_at DATETIME)",
+			"CREATE INDEX idx_webhooks_bank_account_id ON webhooks (bank_account_id)",
+			"CREATE TABLE webhook_events (" +
+				"webhook_id INTEGER NOT NULL REFERENCES webhooks (id), " +
+				"event TEXT NOT NULL, " +
+				"PRIMARY KEY (webhook_id, event))",
+			// the delivery log, written along with the change raising the event and read by the workers posting them
+			"CREATE TABLE webhook_deliveries (" +
+				"id INTEGER PRIMARY KEY, " +
+				"webhook_id INTEGER NOT NULL REFERENCES webhooks (id), " +
+				"event TEXT NOT NULL, " +
+				"payload TEXT NOT NULL, " +
+				"status TEXT NOT NULL CHECK (status IN ('pending', 'delivered', 'failed')), " +
+				"attempts INTEGER NOT NULL DEFAULT 0, " +
+				"next_attempt_at DATETIME, " +
+				"response_status INTEGER NOT NULL DEFAULT 0, " +
+				"error TEXT NOT NULL DEFAULT '', " +
+				"replay_of INTEGER REFERENCES webhook_deliveries (id), " +
+				"created_at DATETIME NOT NULL, " +
+				"delivered_at DATETIME, " +
+				"updated_at DATETIME NOT NULL)",
+			"CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id)",
+			"CREATE INDEX idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt_at) WHERE status = 'pending'",
+		},
+	},
+	{
+		version: 21,
+		statements: []string{
+			// the transactions recorded before the bulk transfers were persisted, without creation date nor bulk
+			// transfer, were all taken as outgoing by the version 7. Those the bulk transfers registered held positive
+			// amounts, while the accounts seeded by hand stored signed amounts, negative when outgoing: on an account
+			// holding a negative amount, the positive ones are incoming. The opening balances follow the new signs.
+			"UPDATE bank_accounts SET opening_balance_cents = opening_balance_cents + COALESCE((" +
+				"SELECT SUM(CASE direction WHEN 'incoming' THEN amount_cents ELSE -amount_cents END) " +
+				"FROM transactions WHERE bank_account_id = bank_accounts.id AND created_at IS NULL AND bulk_transfer_id IS NULL), 0)",
+			"UPDATE transactions SET direction = CASE WHEN amount_cents > 0 AND EXISTS (" +
+				"SELECT 1 FROM transactions signed WHERE signed.bank_account_id = transactions.bank_account_id " +
+				"AND signed.created_at IS NULL AND signed.bulk_transfer_id IS NULL AND signed.amount_cents < 0) " +
+				"THEN 'incoming' ELSE 'outgoing' END " +
+				"WHERE created_at IS NULL AND bulk_transfer_id IS NULL",
+			"UPDATE transactions SET amount_cents = ABS(amount_cents) WHERE created_at IS NULL AND bulk_transfer_id IS NULL",
+			"UPDATE bank_accounts SET opening_balance_cents = opening_balance_cents - COALESCE((" +
+				"SELECT SUM(CASE direction WHEN 'incoming' THEN amount_cents ELSE -amount_cents END) " +
+				"FROM transactions WHERE bank_account_id = bank_accounts.id AND created_at IS NULL AND bulk_transfer_id IS NULL), 0)",
+		},
+	},
+	{
+		version: 22,
+		statements: []string{
+			// the counterparties paid by the outgoing transactions the version 13 left out, those without bulk
+			// transfer, become beneficiaries of the paying account unless already registered
+			"INSERT OR IGNORE INTO beneficiaries (bank_account_id, name, iban, bic, created_at, updated_at) " +
+				"SELECT bank_account_id, COALESCE(counterparty_name, ''), counterparty_iban, COALESCE(counterparty_bic, ''), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP " +
+				"FROM transactions WHERE direction = 'outgoing' AND bulk_transfer_id IS NULL AND COALESCE(counterparty_iban, '') <> '' " +
+				"ORDER BY id DESC",
+		},
+	},
+	{
+		version: 23,
+		statements: []string{
+			// the worker holding a running job, and when its lease expires unless renewed. The jobs left running
+			// before have no lease, they are claimed again as when their worker stopped.
+			"ALTER TABLE bulk_transfer_jobs ADD COLUMN owner TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE bulk_transfer_jobs ADD COLUMN lease_expires_at DATETIME",
+		},
+	},
+	{
+		version: 24,
+		statements: []string{
+			// a job is deferred when its bulk transfer is left pending approval, scheduled or held for review, the
+			// table is rebuilt for its status check
+			"CREATE TABLE bulk_transfer_jobs_v24 (" +
+				"id INTEGER PRIMARY KEY, " +
+				"bulk_transfer_id INTEGER NOT NULL UNIQUE REFERENCES bulk_transfers (id), " +
+				"status TEXT NOT NULL CHECK (status IN ('queued', 'running', 'done', 'failed', 'deferred')), " +
+				"lines_total INTEGER NOT NULL, " +
+				"lines_done INTEGER NOT NULL DEFAULT 0, " +
+				"error TEXT NOT NULL DEFAULT '', " +
+				"created_at DATETIME NOT NULL, " +
+				"started_at DATETIME, " +
+				"finished_at DATETIME, " +
+				"updated_at DATETIME NOT NULL, " +
+				"owner TEXT NOT NULL DEFAULT '', " +
+				"lease_expires_at DATETIME)",
+			"INSERT INTO bulk_transfer_jobs_v24 (id, bulk_transfer_id, status, lines_total, lines_done, error, created_at, started_at, finished_at, updated_at, owner, lease_expires_at) " +
+				"SELECT j.id, j.bulk_transfer_id, " +
+				"CASE WHEN j.status = 'done' AND t.status IN ('pending_approval', 'scheduled', 'held_for_review') THEN 'deferred' ELSE j.status END, " +
+				"j.lines_total, j.lines_done, j.error, j.created_at, j.started_at, j.finished_at, j.updated_at, j.owner, j.lease_expires_at " +
+				"FROM bulk_transfer_jobs j INNER JOIN bulk_transfers t ON t.id = j.bulk_transfer_id",
+			"DROP TABLE bulk_transfer_jobs",
+			"ALTER TABLE bulk_transfer_jobs_v24 RENAME TO bulk_transfer_jobs",
+			"CREATE INDEX idx_bulk_transfer_jobs_status ON bulk_transfer_jobs (status)",
+		},
+	},
+	{
+		version: 25,
+		statements: []string{
+			// idempotency keys are scoped by the account the request acts on, or by its path when it names none.
+			// The scope of the stored keys is taken from the organization_iban of their response when there is one,
+			// the others are left unscoped until they expire.
+			"CREATE TABLE idempotency_keys_v25 (" +
+				"scope TEXT NOT NULL DEFAULT '', " +
+				"idempotency_key TEXT NOT NULL, " +
+				"request_hash TEXT NOT NULL, " +
+				"status_code INTEGER NOT NULL DEFAULT 0, " +
+				"content_type TEXT NOT NULL DEFAULT '', " +
+				"response_body BLOB, " +
+				"created_at DATETIME NOT NULL, " +
+				"PRIMARY KEY (scope, idempotency_key))",
+			"INSERT INTO idempotency_keys_v25 (scope, idempotency_key, request_hash, status_code, content_type, response_body, created_at) " +
+				"SELECT CASE WHEN json_valid(CAST(response_body AS TEXT)) " +
+				"THEN COALESCE(json_extract(CAST(response_body AS TEXT), '$.organization_iban'), '') ELSE '' END, " +
+				"idempotency_key, request_hash, status_code, content_type, response_body, created_at " +
+				"FROM idempotency_keys",
+			"DROP TABLE idempotency_keys",
+			"ALTER TABLE idempotency_keys_v25 RENAME TO idempotency_keys",
+			"CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at)",
+		},
+	},
+}
+
+// Migrate applies the pending schema migrations, each one inside its own transaction
+func Migrate(db Conn) error {
+	_, err := db.Conn.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL)")
+	if err != nil {
+		return err
+	}
+
+	var current int
+	if err = db.Conn.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err = apply(db, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func apply(db Conn, m migration) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range m.statements {
+		if _, err = tx.Exec(statement); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if _, err = tx.Exec("INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)", m.version, time.Now().UTC()); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "qonto.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	conn := Conn{Conn: db}
+
+	t.Run("Test Migrate applies every migration on an empty database", func(t *testing.T) {
+		require.NoError(t, Migrate(conn))
+
+		var version int
+		require.NoError(t, db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version))
+		assert.Equal(t, migrations[len(migrations)-1].version, version)
+
+		_, err := db.Exec("INSERT INTO bank_accounts (organization_name, balance_cents, iban, bic) VALUES ('ACME Corp', 100, 'FR81474608000002006107XXXXX', 'OIVUSCLQXXX')")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Migrate is a no-op when the schema is up to date", func(t *testing.T) {
+		require.NoError(t, Migrate(conn))
+
+		var applied int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&applied))
+		assert.Equal(t, len(migrations), applied)
+	})
+
+	t.Run("Test Migrate posts the existing balances as ledger opening balances", func(t *testing.T) {
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "qonto.sqlite"))
+		require.NoError(t, err)
+		defer db.Close()
+
+		conn := Conn{Conn: db}
+		_, err = db.Exec("CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL)")
+		require.NoError(t, err)
+		for _, m := range migrations {
+			if m.version < 9 {
+				require.NoError(t, apply(conn, m))
+			}
+		}
+		_, err = db.Exec("INSERT INTO bank_accounts (organization_name, balance_cents, iban, bic) VALUES " +
+			"('ACME Corp', 10000, 'FR81474608000002006107XXXXX', 'OIVUSCLQXXX'), ('Bip Bip', 0, 'EE303680981021245685', 'CRLYFRPPTOU')")
+		require.NoError(t, err)
+
+		require.NoError(t, Migrate(conn))
+
+		var balance, total int64
+		require.NoError(t, db.QueryRow("SELECT SUM(CASE side WHEN 'credit' THEN amount_cents ELSE -amount_cents END) FROM ledger_entries WHERE account = 'bank_account:1'").Scan(&balance))
+		require.NoError(t, db.QueryRow("SELECT SUM(CASE side WHEN 'credit' THEN amount_cents ELSE -amount_cents END) FROM ledger_entries").Scan(&total))
+		assert.Equal(t, int64(10000), balance)
+		assert.Equal(t, int64(0), total)
+	})
+
+	t.Run("Test Migrate derives the opening balances from the transactions", func(t *testing.T) {
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "qonto.sqlite"))
+		require.NoError(t, err)
+		defer db.Close()
+
+		conn := Conn{Conn: db}
+		_, err = db.Exec("CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL)")
+		require.NoError(t, err)
+		for _, m := range migrations {
+			if m.version < 10 {
+				require.NoError(t, apply(conn, m))
+			}
+		}
+		_, err = db.Exec("INSERT INTO bank_accounts (organization_name, balance_cents, iban, bic) VALUES ('ACME Corp', 10000, 'FR81474608000002006107XXXXX', 'OIVUSCLQXXX')")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO transactions (amount_cents, bank_account_id, direction, created_at) VALUES (2500, 1, 'outgoing', CURRENT_TIMESTAMP), (500, 1, 'incoming', CURRENT_TIMESTAMP)")
+		require.NoError(t, err)
+
+		require.NoError(t, Migrate(conn))
+
+		var openingBalance int64
+		require.NoError(t, db.QueryRow("SELECT opening_balance_cents FROM bank_accounts WHERE id = 1").Scan(&openingBalance))
+		assert.Equal(t, int64(12000), openingBalance)
+	})
+
+	t.Run("Test Migrate derives the direction and the beneficiaries of the transactions recorded before the series", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "qonto.sqlite")
+		fixture, err := os.ReadFile(filepath.Join("..", "..", "test", "qonto_accounts.sqlite"))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, fixture, 0600))
+
+		db, err := sql.Open("sqlite3", path)
+		require.NoError(t, err)
+		defer db.Close()
+
+		// an account debited by a bulk transfer of the baseline, which stored its amounts as positive
+		_, err = db.Exec("INSERT INTO bank_accounts (organization_name, balance_cents, iban, bic) VALUES ('Bip Bip', 7500, 'EE383680981021245685', 'CRLYFRPPTOU')")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO transactions (counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, bank_account_id, description) " +
+			"VALUES ('Wile E Coyote', 'DE44354208100362090817', 'CRLYFRPPTOU', 2500, 'EUR', 2, 'Rocket skates')")
+		require.NoError(t, err)
+
+		require.NoError(t, Migrate(Conn{Conn: db}))
+
+		rows, err := db.Query("SELECT id, direction, amount_cents FROM transactions ORDER BY id")
+		require.NoError(t, err)
+		defer rows.Close()
+		var transactions []string
+		for rows.Next() {
+			var id, amountCents int64
+			var direction string
+			require.NoError(t, rows.Scan(&id, &direction, &amountCents))
+			transactions = append(transactions, fmt.Sprintf("%d %s %d", id, direction, amountCents))
+		}
+		assert.Equal(t, []string{"1 incoming 11000000", "2 outgoing 1000000", "3 outgoing 2500"}, transactions)
+
+		rows, err = db.Query("SELECT id, balance_cents, opening_balance_cents FROM bank_accounts ORDER BY id")
+		require.NoError(t, err)
+		defer rows.Close()
+		var balances []string
+		for rows.Next() {
+			var id, balance, openingBalance int64
+			require.NoError(t, rows.Scan(&id, &balance, &openingBalance))
+			balances = append(balances, fmt.Sprintf("%d %d %d", id, balance, openingBalance))
+		}
+		assert.Equal(t, []string{"1 10000000 0", "2 7500 10000"}, balances)
+
+		// the counterparties paid before the bulk transfers became beneficiaries, the one paying didn't
+		rows, err = db.Query("SELECT bank_account_id, name, iban, bic FROM beneficiaries ORDER BY id")
+		require.NoError(t, err)
+		defer rows.Close()
+		var beneficiaries []string
+		for rows.Next() {
+			var bankAccountID int
+			var name, iban, bic string
+			require.NoError(t, rows.Scan(&bankAccountID, &name, &iban, &bic))
+			beneficiaries = append(beneficiaries, fmt.Sprintf("%d %s %s %s", bankAccountID, name, iban, bic))
+		}
+		assert.Equal(t, []string{"2 Wile E Coyote DE44354208100362090817 CRLYFRPPTOU", "1 Bip Bip EE383680981021245685 CRLYFRPPTOU"}, beneficiaries)
+	})
+
+	t.Run("Test Migrate backfills the beneficiaries from the counterparties", func(t *testing.T) {
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "qonto.sqlite"))
+		require.NoError(t, err)
+		defer db.Close()
+
+		conn := Conn{Conn: db}
+		_, err = db.Exec("CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL)")
+		require.NoError(t, err)
+		for _, m := range migrations {
+			if m.version < 13 {
+				require.NoError(t, apply(conn, m))
+			}
+		}
+		_, err = db.Exec("INSERT INTO bank_accounts (organization_name, balance_cents, iban, bic) VALUES ('ACME Corp', 10000, 'FR81474608000002006107XXXXX', 'OIVUSCLQXXX')")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO bulk_transfers (organization_name, organization_iban, organization_bic, transfers_count, total_cents, status, created_at, updated_at) " +
+			"VALUES ('ACME Corp', 'FR81474608000002006107XXXXX', 'OIVUSCLQXXX', 2, 3000, 'scheduled', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO bulk_transfer_lines (bulk_transfer_id, line_index, counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency) VALUES " +
+			"(1, 0, 'Bip Bip Old', 'EE303680981021245685', 'CRLYFRPPTOU', 1000, 'EUR'), (1, 1, 'Wile E Coyote', 'DE44354208100362090817', 'CRLYFRPPTOU', 2000, 'EUR')")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO transactions (counterparty_name, counterparty_iban, counterparty_bic, amount_cents, bank_account_id, direction, bulk_transfer_id) VALUES " +
+			"('Bip Bip', 'EE303680981021245685', 'CRLYFRPPTOU', 2500, 1, 'outgoing', 1), ('Bip Bip', 'EE303680981021245685', 'CRLYFRPPTOU', 500, 1, 'incoming', 1)")
+		require.NoError(t, err)
+
+		require.NoError(t, Migrate(conn))
+
+		rows, err := db.Query("SELECT bank_account_id, name, iban FROM beneficiaries ORDER BY iban")
+		require.NoError(t, err)
+		defer rows.Close()
+		var beneficiaries []string
+		for rows.Next() {
+			var bankAccountID int
+			var name, iban string
+			require.NoError(t, rows.Scan(&bankAccountID, &name, &iban))
+			beneficiaries = append(beneficiaries, fmt.Sprintf("%d %s %s", bankAccountID, name, iban))
+		}
+		assert.Equal(t, []string{"1 Wile E Coyote DE44354208100362090817", "1 Bip Bip EE303680981021245685"}, beneficiaries)
+	})
+
+	t.Run("Test Migrate defers the jobs done of the bulk transfers that weren't executed", func(t *testing.T) {
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "qonto.sqlite"))
+		require.NoError(t, err)
+		defer db.Close()
+
+		conn := Conn{Conn: db}
+		_, err = db.Exec("CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL)")
+		require.NoError(t, err)
+		for _, m := range migrations {
+			if m.version < 24 {
+				require.NoError(t, apply(conn, m))
+			}
+		}
+		_, err = db.Exec("INSERT INTO bulk_transfers (organization_name, organization_iban, organization_bic, transfers_count, total_cents, status, created_at, updated_at) VALUES " +
+			"('ACME Corp', 'FR81474608000002006107XXXXX', 'OIVUSCLQXXX', 1, 3000, 'pending_approval', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP), " +
+			"('ACME Corp', 'FR81474608000002006107XXXXX', 'OIVUSCLQXXX', 1, 1000, 'completed', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO bulk_transfer_jobs (bulk_transfer_id, status, lines_total, lines_done, created_at, updated_at) VALUES " +
+			"(1, 'done', 1, 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP), (2, 'done', 1, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)")
+		require.NoError(t, err)
+
+		require.NoError(t, Migrate(conn))
+
+		rows, err := db.Query("SELECT id, status FROM bulk_transfer_jobs ORDER BY id")
+		require.NoError(t, err)
+		defer rows.Close()
+		var jobs []string
+		for rows.Next() {
+			var id int
+			var status string
+			require.NoError(t, rows.Scan(&id, &status))
+			jobs = append(jobs, fmt.Sprintf("%d %s", id, status))
+		}
+		assert.Equal(t, []string{"1 deferred", "2 done"}, jobs)
+
+		_, err = db.Exec("UPDATE bulk_transfer_jobs SET status = 'unknown' WHERE id = 1")
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Migrate scopes the idempotency keys by the account of their response", func(t *testing.T) {
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "qonto.sqlite"))
+		require.NoError(t, err)
+		defer db.Close()
+
+		conn := Conn{Conn: db}
+		_, err = db.Exec("CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL)")
+		require.NoError(t, err)
+		for _, m := range migrations {
+			if m.version < 25 {
+				require.NoError(t, apply(conn, m))
+			}
+		}
+		_, err = db.Exec("INSERT INTO idempotency_keys (idempotency_key, request_hash, status_code, content_type, response_body, created_at) VALUES " +
+			"('payroll', 'h1', 201, 'application/json', CAST('{\"organization_iban\": \"FR10474608000002006107XXXXX\"}' AS BLOB), CURRENT_TIMESTAMP), " +
+			"('reverse', 'h2', 201, 'application/json', CAST('{\"id\": 1}' AS BLOB), CURRENT_TIMESTAMP), " +
+			"('failed', 'h3', 500, 'text/plain', CAST('error' AS BLOB), CURRENT_TIMESTAMP), " +
+			"('pending', 'h4', 0, '', NULL, CURRENT_TIMESTAMP)")
+		require.NoError(t, err)
+
+		require.NoError(t, Migrate(conn))
+
+		rows, err := db.Query("SELECT scope, idempotency_key FROM idempotency_keys ORDER BY idempotency_key")
+		require.NoError(t, err)
+		defer rows.Close()
+		var keys []string
+		for rows.Next() {
+			var scope, key string
+			require.NoError(t, rows.Scan(&scope, &key))
+			keys = append(keys, fmt.Sprintf("%s/%s", scope, key))
+		}
+		assert.Equal(t, []string{"/failed", "FR10474608000002006107XXXXX/payroll", "/pending", "/reverse"}, keys)
+
+		_, err = db.Exec("INSERT INTO idempotency_keys (scope, idempotency_key, request_hash, created_at) VALUES ('EE383680981021245685', 'payroll', 'h5', CURRENT_TIMESTAMP)")
+		assert.NoError(t, err)
+	})
+}
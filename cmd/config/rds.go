@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"github.com/adrianoccosta/exercise-qonto/internal/handlers/healthhdl"
 	"github.com/adrianoccosta/exercise-qonto/log"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -12,6 +13,14 @@ import (
 // Conn Contains information for current db connection.
 type Conn struct {
 	Conn *sql.DB
+	Tx   *sql.Tx
+}
+
+// Executor is the set of operations shared by *sql.DB and *sql.Tx used by the repositories
+type Executor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
 }
 
 // DBConnection DB connections mandatory fields
@@ -24,7 +33,7 @@ type DBConnection struct {
 
 // InitDBConnection function init the database connection.
 func InitDBConnection(conn DBConnection, logger log.Logger) Conn {
-	db, err := sql.Open("sqlite3", conn.Path)
+	db, err := sql.Open("sqlite3", dsn(conn.Path))
 
 	if err != nil {
 		if err != nil {
@@ -39,6 +48,30 @@ func InitDBConnection(conn DBConnection, logger log.Logger) Conn {
 	return Conn{Conn: db}
 }
 
+// Executor returns the transaction bound to the connection, or the connection itself when there is none
+func (db Conn) Executor() Executor {
+	if db.Tx != nil {
+		return db.Tx
+	}
+	return db.Conn
+}
+
+// WithTx returns a copy of the connection bound to the given transaction
+func (db Conn) WithTx(tx *sql.Tx) Conn {
+	return Conn{Conn: db.Conn, Tx: tx}
+}
+
+// dsn adds the sqlite options needed by concurrent writers: transactions take the write lock as soon
+// as they begin, so read-then-write transactions are serialised instead of failing on lock upgrade,
+// and connections wait for the lock instead of failing with "database is locked".
+func dsn(path string) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + "_busy_timeout=5000&_txlock=immediate"
+}
+
 // DBHealth validator for db connection
 func (db Conn) DBHealth() healthhdl.Validator {
 	return func() healthhdl.Response {
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/reconciliationsvc"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/urfave/cli/v2"
+	"io"
+	"text/tabwriter"
+)
+
+const (
+	reconcileOutputProp = "output"
+	reconcileFixProp    = "fix"
+	reconcileReasonProp = "reason"
+
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// ReconcileCommand is the command to check the balances of the bank accounts against their transactions and their
+// ledger accounts
+var ReconcileCommand = &cli.Command{
+	Name:   "reconcile",
+	Usage:  "service-qonto reconcile, reports the bank accounts whose balance drifted from their transactions or their ledger account",
+	Action: runReconcileCommand,
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{Name: reconcileOutputProp, Value: outputTable, Usage: "format of the report, table or json"},
+		&cli.BoolFlag{Name: reconcileFixProp, Usage: "write an adjustment accounting for each drift and post it on the ledger"},
+		&cli.StringFlag{Name: reconcileReasonProp, Usage: "audit reason of the adjustments, required with --fix"},
+	}, databaseFlags...),
+}
+
+func runReconcileCommand(ctx *cli.Context) error {
+	logger := ctx.App.Metadata["Logger"].(log.Logger)
+
+	output := ctx.String(reconcileOutputProp)
+	if output != outputTable && output != outputJSON {
+		return cli.Exit(fmt.Sprintf("unknown output %q, expected table or json", output), 2)
+	}
+
+	rds := configDatabase(ctx, logger)
+	reconciliationService := reconciliationsvc.New(uow.New(rds), tools.SystemClock{}, logger)
+
+	reconciliation, err := reconciliationService.Reconcile(ctx.Bool(reconcileFixProp), ctx.String(reconcileReasonProp))
+	if err != nil {
+		return cli.Exit(err.Error(), 2)
+	}
+
+	if output == outputJSON {
+		err = json.NewEncoder(ctx.App.Writer).Encode(reconciliation)
+	} else {
+		err = writeReconciliationTable(ctx.App.Writer, reconciliation)
+	}
+	if err != nil {
+		return err
+	}
+
+	if unresolved := reconciliation.Unresolved(); unresolved > 0 {
+		return cli.Exit(fmt.Sprintf("balance drift on %d of %d bank accounts", unresolved, reconciliation.Accounts), 1)
+	}
+
+	return nil
+}
+
+// writeReconciliationTable writes a line per drift, or a summary when every balance is consistent
+func writeReconciliationTable(w io.Writer, reconciliation domain.Reconciliation) error {
+	if len(reconciliation.Drifts) == 0 {
+		_, err := fmt.Fprintf(w, "%d bank accounts reconciled, no drift\n", reconciliation.Accounts)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "IBAN\tNAME\tBALANCE\tEXPECTED\tDRIFT\tLEDGER\tLEDGER DRIFT\tFIXED")
+	for _, drift := range reconciliation.Drifts {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%t\n", drift.Iban, drift.Name, drift.Balance, drift.ExpectedBalance, drift.Drift,
+			drift.LedgerBalance, drift.LedgerDrift, drift.Fixed)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/idempotencyrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/templaterepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/scheduler"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/templatesvc"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/transfersvc"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/urfave/cli/v2"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// SchedulerCommand is the command to run the scheduler without the web server
+var SchedulerCommand = &cli.Command{
+	Name:   "scheduler",
+	Usage:  "service-qonto scheduler, executes the scheduled bulk transfers and the transfer templates when they are due and purges the expired idempotency keys",
+	Action: runSchedulerCommand,
+	Flags: append([]cli.Flag{
+		&cli.IntFlag{Name: schedulerIntervalProp, Value: tools.EnvIntOrDefault("SCHEDULER_INTERVAL", 60), Usage: "seconds between runs of the scheduler (e.g., 60)"},
+		idempotencyKeyRetentionFlag,
+	}, append(databaseFlags, executionFlags...)...),
+}
+
+func runSchedulerCommand(ctx *cli.Context) error {
+	logger := ctx.App.Metadata["Logger"].(log.Logger)
+
+	interval := ctx.Int(schedulerIntervalProp)
+	if interval <= 0 {
+		return cli.Exit("the scheduler interval must be positive", 1)
+	}
+
+	schedulerCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rds := configDatabase(ctx, logger)
+	unitOfWork := uow.New(rds)
+	screener := configScreener(ctx, schedulerCtx, logger)
+	transferService := transfersvc.New(unitOfWork, bulktransferrepo.New(rds), transactionrepo.New(rds), screener, duplicateWindow(ctx), configFees(ctx, logger), tools.SystemClock{}, logger)
+	templateService := templatesvc.New(unitOfWork, templaterepo.New(rds), transferService, tools.SystemClock{}, logger)
+
+	scheduler.New(time.Duration(interval)*time.Second, logger, scheduledJobs(ctx, transferService, templateService, idempotencyrepo.New(rds))...).Start(schedulerCtx)
+
+	logger.Info("Shut down successful")
+
+	return nil
+}
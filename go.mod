@@ -14,6 +14,7 @@ require (
 	github.com/swaggo/swag v1.8.2
 	github.com/urfave/cli/v2 v2.8.1
 	go.uber.org/zap v1.21.0
+	golang.org/x/text v0.3.7
 )
 
 require (
@@ -45,7 +46,6 @@ require (
 	golang.org/x/crypto v0.0.0-20211215153901-e495a2d5b3d3 // indirect
 	golang.org/x/net v0.0.0-20220425223048-2871e0cb64e4 // indirect
 	golang.org/x/sys v0.0.0-20220114195835-da31bd327af9 // indirect
-	golang.org/x/text v0.3.7 // indirect
 	golang.org/x/tools v0.1.10 // indirect
 	google.golang.org/protobuf v1.26.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
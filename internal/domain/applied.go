@@ -0,0 +1,31 @@
+package domain
+
+import "errors"
+
+// notApplied wraps the error of a request that failed before anything was written
+type notApplied struct {
+	err error
+}
+
+func (e notApplied) Error() string {
+	return e.err.Error()
+}
+
+func (e notApplied) Unwrap() error {
+	return e.err
+}
+
+// NotApplied marks the error of a request that failed before anything was written, so it may be retried as is.
+// The errors that aren't marked may have happened once some changes were committed.
+func NotApplied(err error) error {
+	if err == nil {
+		return nil
+	}
+	return notApplied{err: err}
+}
+
+// IsNotApplied tells whether the error was marked as happening before anything was written
+func IsNotApplied(err error) bool {
+	var n notApplied
+	return errors.As(err, &n)
+}
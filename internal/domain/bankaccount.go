@@ -1,17 +1,190 @@
 package domain
 
-import "github.com/go-playground/validator/v10"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
 
 // BankAccount Struct that represents a use back account
 type BankAccount struct {
-	Name    string  `json:"name" validate:"required"`
-	Balance float64 `json:"balance,string" validate:"required"`
-	Iban    string  `json:"iban" validate:"required"`
-	Bic     string  `json:"bic" validate:"required"`
+	Name    string        `json:"name" validate:"required"`
+	Balance Money         `json:"balance" validate:"required" swaggertype:"string" example:"100000.00"`
+	Iban    string        `json:"iban" validate:"required,iban"`
+	Bic     string        `json:"bic" validate:"required,bic"`
+	Limits  AccountLimits `json:"limits"`
+	// Approval the maker-checker rule of the bulk transfers of the account
+	Approval ApprovalPolicy `json:"approval"`
+	// Overdraft the authorised overdraft, how far below zero the balance may go. None is authorised when missing.
+	Overdraft *Money `json:"overdraft,omitempty" validate:"omitempty,gt=0" swaggertype:"string" example:"5000.00"`
+	// OverdraftHeadroom what is left of the overdraft, it is reported by the reads and can't be set
+	OverdraftHeadroom *Money `json:"overdraft_headroom,omitempty" swaggertype:"string" example:"4500.00"`
+	// Plan the plan of the account, it picks the rules of the fee schedule charging its transfers
+	Plan string `json:"plan,omitempty" example:"standard"`
+}
+
+// AccountLimits Struct that represents the transfer limits of a bank account, the missing ones don't apply
+type AccountLimits struct {
+	// PerTransaction the maximum amount of a credit transfer
+	PerTransaction *Money `json:"per_transaction,omitempty" validate:"omitempty,gt=0" swaggertype:"string" example:"5000.00"`
+	// PerBatch the maximum total of a bulk transfer
+	PerBatch *Money `json:"per_batch,omitempty" validate:"omitempty,gt=0" swaggertype:"string" example:"20000.00"`
+	// Daily the maximum total transferred per day (UTC)
+	Daily *Money `json:"daily,omitempty" validate:"omitempty,gt=0" swaggertype:"string" example:"50000.00"`
+	// Monthly the maximum total transferred per calendar month (UTC)
+	Monthly *Money `json:"monthly,omitempty" validate:"omitempty,gt=0" swaggertype:"string" example:"200000.00"`
+}
+
+// OverdrawnCents the part of a debit of amountCents paid from the overdraft, given the balance after the debit
+func OverdrawnCents(balanceAfterCents, amountCents int64) int64 {
+	if balanceAfterCents >= 0 {
+		return 0
+	}
+	if -balanceAfterCents > amountCents {
+		// the balance was already below zero
+		return amountCents
+	}
+	return -balanceAfterCents
+}
+
+// UnmarshalJSON parses the limits exactly in the account currency
+func (l *AccountLimits) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		PerTransaction *string `json:"per_transaction"`
+		PerBatch       *string `json:"per_batch"`
+		Daily          *string `json:"daily"`
+		Monthly        *string `json:"monthly"`
+	}{}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	for _, limit := range []struct {
+		field  string
+		amount *string
+		money  **Money
+	}{
+		{"limits.per_transaction", aux.PerTransaction, &l.PerTransaction},
+		{"limits.per_batch", aux.PerBatch, &l.PerBatch},
+		{"limits.daily", aux.Daily, &l.Daily},
+		{"limits.monthly", aux.Monthly, &l.Monthly},
+	} {
+		if limit.amount == nil {
+			continue
+		}
+		money, err := parseAmount(limit.field, *limit.amount, AccountCurrency)
+		if err != nil {
+			return err
+		}
+		*limit.money = &money
+	}
+
+	return nil
+}
+
+// ApprovalPolicy Struct that represents the maker-checker rule of a bank account: the bulk transfers above the
+// threshold wait for RequiredApprovals approvals (N) out of the Approvers (M) before their execution
+type ApprovalPolicy struct {
+	// Threshold the total above which a bulk transfer needs approval, none does when missing
+	Threshold *Money `json:"threshold,omitempty" validate:"omitempty,gt=0" swaggertype:"string" example:"10000.00"`
+	// RequiredApprovals how many approvers must approve a bulk transfer, 1 when missing
+	RequiredApprovals int `json:"required_approvals,omitempty" validate:"gte=0" example:"2"`
+	// Approvers who may approve the bulk transfers, anyone but the submitter when missing
+	Approvers []string `json:"approvers,omitempty" validate:"omitempty,unique,dive,required" example:"jane.doe,john.smith"`
+}
+
+// UnmarshalJSON parses the threshold exactly in the account currency
+func (l *ApprovalPolicy) UnmarshalJSON(data []byte) error {
+	type approvalPolicy ApprovalPolicy
+	aux := struct {
+		*approvalPolicy
+		Threshold *string `json:"threshold"`
+	}{approvalPolicy: (*approvalPolicy)(l)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if aux.Threshold != nil {
+		threshold, err := parseAmount("approval.threshold", *aux.Threshold, AccountCurrency)
+		if err != nil {
+			return err
+		}
+		l.Threshold = &threshold
+	}
+
+	return nil
 }
 
 //Validate validates the BankAccount struct based on 'validate' tags of its fields
 func (l *BankAccount) Validate() error {
-	v := validator.New()
-	return v.Struct(l)
+	var validationError ValidationError
+	if err := validate(l); err != nil && !errors.As(err, &validationError) {
+		return err
+	}
+
+	// N approvals can't be required out of fewer approvers
+	if approvers := len(l.Approval.Approvers); approvers > 0 && l.Approval.RequiredApprovals > approvers {
+		validationError.set(FieldError{
+			Field:   "approval.required_approvals",
+			Rule:    "max",
+			Message: fmt.Sprintf("must be at most %d, the number of approvers", approvers),
+		})
+	}
+
+	if len(validationError.Fields) == 0 {
+		return nil
+	}
+	return validationError
+}
+
+// ValidateUpdate validates the edition of a registered BankAccount. Its IBAN only references the account, so the
+// accounts registered before the IBANs were validated can still be edited.
+func (l *BankAccount) ValidateUpdate() error {
+	err := l.Validate()
+
+	var validationError ValidationError
+	if !errors.As(err, &validationError) || !ValidAccountIban(l.Iban) {
+		return err
+	}
+
+	validationError.drop("iban")
+	if len(validationError.Fields) == 0 {
+		return nil
+	}
+	return validationError
+}
+
+// UnmarshalJSON parses the balance and the overdraft exactly in the account currency, the overdraft headroom is ignored
+func (l *BankAccount) UnmarshalJSON(data []byte) error {
+	type bankAccount BankAccount
+	aux := struct {
+		*bankAccount
+		Balance           string          `json:"balance"`
+		Overdraft         *string         `json:"overdraft"`
+		OverdraftHeadroom json.RawMessage `json:"overdraft_headroom"`
+	}{bankAccount: (*bankAccount)(l)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	balance, err := parseAmount("balance", aux.Balance, AccountCurrency)
+	if err != nil {
+		return err
+	}
+	l.Balance = balance
+
+	l.Overdraft = nil
+	if aux.Overdraft != nil {
+		overdraft, err := parseAmount("overdraft", *aux.Overdraft, AccountCurrency)
+		if err != nil {
+			return err
+		}
+		l.Overdraft = &overdraft
+	}
+	l.OverdraftHeadroom = nil
+
+	return nil
 }
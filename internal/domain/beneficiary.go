@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// Beneficiary Struct that represents a counterparty registered on the bank account of an organization,
+// so its credit transfers can reference it instead of repeating its details
+type Beneficiary struct {
+	OrganizationIban string `json:"organization_iban" validate:"required,account_iban" example:"FR10474608000002006107XXXXX"`
+	Name             string `json:"name" validate:"required" example:"Bip Bip"`
+	Iban             string `json:"iban" validate:"required,iban" example:"EE383680981021245685"`
+	Bic              string `json:"bic" validate:"required,bic" example:"CRLYFRPPTOU"`
+}
+
+// Validate validates the Beneficiary struct based on 'validate' tags of its fields
+func (l *Beneficiary) Validate() error {
+	return validate(l)
+}
+
+// BeneficiaryDetail Struct that represents a stored beneficiary
+type BeneficiaryDetail struct {
+	ID               uint      `json:"id"`
+	OrganizationIban string    `json:"organization_iban"`
+	Name             string    `json:"name"`
+	Iban             string    `json:"iban"`
+	Bic              string    `json:"bic"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// BeneficiaryDetailList Struct that represents a list of stored beneficiaries
+type BeneficiaryDetailList []BeneficiaryDetail
@@ -1,26 +1,342 @@
 package domain
 
-import "github.com/go-playground/validator/v10"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
 
 // BulkTransfer Struct that represents a payment request
 type BulkTransfer struct {
 	OrganizationName string           `json:"organization_name" validate:"required"`
-	OrganizationBic  string           `json:"organization_bic" validate:"required"`
-	OrganizationIban string           `json:"organization_iban" validate:"required"`
-	CreditTransfers  []CreditTransfer `json:"credit_transfers" validate:"required"`
+	OrganizationBic  string           `json:"organization_bic" validate:"required,bic"`
+	OrganizationIban string           `json:"organization_iban" validate:"required,account_iban"`
+	CreditTransfers  []CreditTransfer `json:"credit_transfers" validate:"required,min=1,dive"`
+	// ExecutionDate optional day, in UTC, the bulk transfer must be executed on. It is executed on reception when missing.
+	ExecutionDate string `json:"execution_date,omitempty" validate:"omitempty,datetime=2006-01-02" example:"2022-09-30"`
+	// AllowDuplicates executes the bulk transfer even though credit transfers look like duplicates of recent ones
+	AllowDuplicates bool `json:"allow_duplicates,omitempty"`
+	// ExecutionMode all_or_nothing when missing. A best_effort bulk transfer executes its valid credit transfers, by
+	// priority, until funds or limits run out.
+	ExecutionMode ExecutionMode `json:"execution_mode,omitempty" validate:"omitempty,oneof=all_or_nothing best_effort" example:"best_effort"`
+	// TemplateID the transfer template the bulk transfer is an occurrence of, it can't be set by the clients
+	TemplateID uint `json:"-"`
+	// SubmittedBy who submitted the bulk transfer, the authenticated actor of the request or its X-Actor header
+	SubmittedBy string `json:"-"`
 }
 
 type CreditTransfer struct {
-	Amount           float64 `json:"amount,string" validate:"required"`
-	Currency         string  `json:"currency" validate:"required"`
-	CounterPartyName string  `json:"counterparty_name" validate:"required"`
-	CounterPartyBic  string  `json:"counterparty_bic" validate:"required"`
-	CounterPartyIban string  `json:"counterparty_iban" validate:"required"`
-	Description      string  `json:"description"`
+	Amount   Money  `json:"amount" validate:"required,gt=0" swaggertype:"string" example:"14.53"`
+	Currency string `json:"currency" validate:"required"`
+	// BeneficiaryID a beneficiary of the organization account to pay, in place of the counterparty fields
+	BeneficiaryID    uint   `json:"beneficiary_id,omitempty" example:"3"`
+	CounterPartyName string `json:"counterparty_name,omitempty" validate:"required_without=BeneficiaryID,excluded_with=BeneficiaryID"`
+	CounterPartyBic  string `json:"counterparty_bic,omitempty" validate:"required_without=BeneficiaryID,excluded_with=BeneficiaryID,omitempty,bic"`
+	CounterPartyIban string `json:"counterparty_iban,omitempty" validate:"required_without=BeneficiaryID,excluded_with=BeneficiaryID,omitempty,iban"`
+	Description      string `json:"description"`
+	// Priority the credit transfers of a best effort bulk transfer with the highest priority are executed first,
+	// those with the same priority in their order
+	Priority int `json:"priority,omitempty" example:"1"`
+	// amountErr the amount couldn't be parsed, it is reported by the validation along with the line
+	amountErr error
+}
+
+// UnmarshalJSON parses the amount exactly in the currency of the credit transfer. An invalid amount
+// doesn't fail the decoding, it is reported by Validate.
+func (c *CreditTransfer) UnmarshalJSON(data []byte) error {
+	type creditTransfer CreditTransfer
+	aux := struct {
+		*creditTransfer
+		Amount string `json:"amount"`
+	}{creditTransfer: (*creditTransfer)(c)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	c.Amount = NewMoney(0, c.Currency)
+	c.amountErr = nil
+	if aux.Amount != "" {
+		c.Amount, c.amountErr = ParseMoney(aux.Amount, c.Currency)
+	}
+
+	return nil
 }
 
 //Validate validates the BulkTransfer struct based on 'validate' tags of its fields
 func (l *BulkTransfer) Validate() error {
-	v := validator.New()
-	return v.Struct(l)
+	return validateWithLines(l, l.CreditTransfers)
+}
+
+// validateWithLines validates s along with the amounts of its credit transfers that couldn't be parsed
+func validateWithLines(s interface{}, creditTransfers []CreditTransfer) error {
+	var validationError ValidationError
+	if err := validate(s); err != nil && !errors.As(err, &validationError) {
+		return err
+	}
+
+	for i, creditTransfer := range creditTransfers {
+		if creditTransfer.amountErr != nil {
+			validationError.set(FieldError{
+				Field:   fmt.Sprintf("credit_transfers[%d].amount", i),
+				Rule:    "amount",
+				Message: creditTransfer.amountErr.Error(),
+			})
+		}
+	}
+
+	if len(validationError.Fields) == 0 {
+		return nil
+	}
+	return validationError
+}
+
+// Mode returns the execution mode of the bulk transfer, all_or_nothing when it is missing
+func (l BulkTransfer) Mode() ExecutionMode {
+	if l.ExecutionMode == "" {
+		return ExecutionAllOrNothing
+	}
+	return l.ExecutionMode
+}
+
+// ExecutionTime returns when the bulk transfer is due, or the zero time when it must be executed on reception
+func (l BulkTransfer) ExecutionTime() (time.Time, error) {
+	if l.ExecutionDate == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(ExecutionDateLayout, l.ExecutionDate)
+}
+
+// ExecutionDateLayout layout of the execution date of a bulk transfer
+const ExecutionDateLayout = "2006-01-02"
+
+// BulkTransferStatus represents the lifecycle state of a stored bulk transfer
+type BulkTransferStatus string
+
+const (
+	// BulkTransferReceived the request was stored and is waiting to be processed
+	BulkTransferReceived BulkTransferStatus = "received"
+	// BulkTransferQueued the request was submitted asynchronously and waits for a worker
+	BulkTransferQueued BulkTransferStatus = "queued"
+	// BulkTransferScheduled the request was stored and waits for its execution date
+	BulkTransferScheduled BulkTransferStatus = "scheduled"
+	// BulkTransferPendingApproval the request is above the approval threshold of the account and waits for its approvers
+	BulkTransferPendingApproval BulkTransferStatus = "pending_approval"
+	// BulkTransferProcessing the request is being executed
+	BulkTransferProcessing BulkTransferStatus = "processing"
+	// BulkTransferCompleted every credit transfer was registered and the account debited
+	BulkTransferCompleted BulkTransferStatus = "completed"
+	// BulkTransferPartiallyCompleted some credit transfers of a best effort request were registered, the others weren't
+	BulkTransferPartiallyCompleted BulkTransferStatus = "partially_completed"
+	// BulkTransferFailed the request was rejected and nothing was registered
+	BulkTransferFailed BulkTransferStatus = "failed"
+	// BulkTransferCancelled the request was cancelled before its execution
+	BulkTransferCancelled BulkTransferStatus = "cancelled"
+	// BulkTransferRejected the request was rejected by an approver
+	BulkTransferRejected BulkTransferStatus = "rejected"
+	// BulkTransferHeldForReview a counterparty matches the screening list, the request waits for a reviewer
+	BulkTransferHeldForReview BulkTransferStatus = "held_for_review"
+	// BulkTransferBlocked the request was blocked by a reviewer of its screening matches
+	BulkTransferBlocked BulkTransferStatus = "blocked"
+)
+
+// ApprovalDecision represents the decision of an approver on a bulk transfer pending approval
+type ApprovalDecision string
+
+const (
+	// ApprovalApproved the approver approved the bulk transfer
+	ApprovalApproved ApprovalDecision = "approved"
+	// ApprovalRejected the approver rejected the bulk transfer
+	ApprovalRejected ApprovalDecision = "rejected"
+)
+
+// BulkTransferApproval Struct that represents the approval of a bulk transfer pending approval
+type BulkTransferApproval struct {
+	Comment string `json:"comment,omitempty" example:"checked against the payroll"`
+}
+
+// BulkTransferRejection Struct that represents the rejection of a bulk transfer pending approval
+type BulkTransferRejection struct {
+	Reason string `json:"reason" validate:"required" example:"wrong payroll month"`
+}
+
+// Validate validates the BulkTransferRejection struct based on 'validate' tags of its fields
+func (l *BulkTransferRejection) Validate() error {
+	return validate(l)
+}
+
+// Approval Struct that represents the decision trail of a bulk transfer that needed approval
+type Approval struct {
+	RequiredApprovals int        `json:"required_approvals"`
+	Decisions         []Decision `json:"decisions"`
+}
+
+// Decision Struct that represents who approved, or rejected, a bulk transfer, when and why
+type Decision struct {
+	Actor    string           `json:"actor"`
+	Decision ApprovalDecision `json:"decision"`
+	Comment  string           `json:"comment,omitempty"`
+	At       time.Time        `json:"at"`
+}
+
+// BulkTransferCancellation Struct that represents the cancellation of a bulk transfer waiting for its execution
+type BulkTransferCancellation struct {
+	Reason string `json:"reason" validate:"required" example:"duplicate payroll"`
+	// LineIndexes the index, in credit_transfers, of the lines to cancel. The whole bulk transfer is cancelled when missing.
+	LineIndexes []int `json:"line_indexes,omitempty" validate:"omitempty,dive,gte=0" example:"0,2"`
+}
+
+// Validate validates the BulkTransferCancellation struct based on 'validate' tags of its fields
+func (l *BulkTransferCancellation) Validate() error {
+	return validate(l)
+}
+
+// Cancellation Struct that represents why, by whom and when a bulk transfer, or the line at Index, was cancelled
+type Cancellation struct {
+	Index  *int      `json:"index,omitempty"`
+	Reason string    `json:"reason"`
+	Actor  string    `json:"actor"`
+	At     time.Time `json:"at"`
+}
+
+// Duplicate Struct that represents a credit transfer, the line at Index, paying the same counterparty the same amount
+// with the same description as a transaction registered recently from the same account
+type Duplicate struct {
+	Index          int       `json:"index"`
+	TransactionID  uint      `json:"transaction_id"`
+	BulkTransferID uint      `json:"bulk_transfer_id"`
+	At             time.Time `json:"at"`
+}
+
+// Fee Struct that represents the fee charged for the credit transfer at Index, by the rule of the fee schedule
+type Fee struct {
+	Index  int    `json:"index"`
+	Rule   string `json:"rule"`
+	Amount Money  `json:"amount" swaggertype:"string" example:"0.50"`
+	// TransactionID the transaction the fee was debited with, once the bulk transfer is executed
+	TransactionID uint `json:"transaction_id,omitempty"`
+}
+
+// FeeBreakdown Struct that represents the fees of a bulk transfer, they are debited on top of its total
+type FeeBreakdown struct {
+	TotalAmount Money `json:"total_amount" swaggertype:"string" example:"1.00"`
+	Fees        []Fee `json:"fees"`
+}
+
+// BulkTransferDetail Struct that represents a stored bulk transfer and the transactions it created
+type BulkTransferDetail struct {
+	ID               uint               `json:"id"`
+	OrganizationName string             `json:"organization_name"`
+	OrganizationBic  string             `json:"organization_bic"`
+	OrganizationIban string             `json:"organization_iban"`
+	TransfersCount   int                `json:"transfers_count"`
+	TotalAmount      Money              `json:"total_amount" swaggertype:"string" example:"29.06"`
+	Status           BulkTransferStatus `json:"status"`
+	FailureReason    string             `json:"failure_reason,omitempty"`
+	ExecutionDate    string             `json:"execution_date,omitempty"`
+	TemplateID       uint               `json:"template_id,omitempty"`
+	SubmittedBy      string             `json:"submitted_by,omitempty"`
+	AllowDuplicates  bool               `json:"allow_duplicates,omitempty"`
+	ExecutionMode    ExecutionMode      `json:"execution_mode,omitempty"`
+	Approval         *Approval          `json:"approval,omitempty"`
+	Screening        *Screening         `json:"screening,omitempty"`
+	Cancellation     *Cancellation      `json:"cancellation,omitempty"`
+	CancelledLines   []Cancellation     `json:"cancelled_lines,omitempty"`
+	Fees             *FeeBreakdown      `json:"fees,omitempty"`
+	// Report the outcome of every credit transfer of a best effort bulk transfer, once executed
+	Report       *ExecutionReport `json:"report,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+	Transactions TransactionList  `json:"transactions,omitempty"`
+}
+
+// BulkTransferQuote Struct that represents the outcome of the checks of a bulk transfer that wasn't executed
+type BulkTransferQuote struct {
+	OrganizationIban string `json:"organization_iban"`
+	TransfersCount   int    `json:"transfers_count"`
+	TotalAmount      Money  `json:"total_amount" swaggertype:"string" example:"29.06"`
+	Balance          *Money `json:"balance,omitempty" swaggertype:"string" example:"100000.00"`
+	BalanceAfter     *Money `json:"balance_after,omitempty" swaggertype:"string" example:"99970.94"`
+	// Fees the fees the bulk transfer would be charged, when the fee schedule charges it
+	Fees *FeeBreakdown `json:"fees,omitempty"`
+	// OverdrawnAmount the part of the total and the fees paid from the overdraft, when the balance doesn't cover them
+	OverdrawnAmount *Money `json:"overdrawn_amount,omitempty" swaggertype:"string" example:"29.06"`
+	Executable      bool   `json:"executable"`
+	// RequiresApproval the bulk transfer is above the approval threshold of the account
+	RequiresApproval bool `json:"requires_approval,omitempty"`
+	// RequiresReview a counterparty matches the screening list, the bulk transfer would be held for review
+	RequiresReview   bool             `json:"requires_review,omitempty"`
+	ScreeningMatches []ScreeningMatch `json:"screening_matches,omitempty"`
+	// Duplicates the credit transfers looking like duplicates of recent ones, they are problems unless allowed
+	Duplicates []Duplicate `json:"duplicates,omitempty"`
+	// Report the outcome every credit transfer of a best effort bulk transfer would have
+	Report   *ExecutionReport `json:"report,omitempty"`
+	Problems []*Rejection     `json:"problems"`
+}
+
+// BulkTransferDetailList Struct that represents a list of stored bulk transfers
+type BulkTransferDetailList []BulkTransferDetail
+
+// UnmarshalJSON parses the total amount exactly in the account currency
+func (l *BulkTransferDetail) UnmarshalJSON(data []byte) error {
+	type bulkTransferDetail BulkTransferDetail
+	aux := struct {
+		*bulkTransferDetail
+		TotalAmount string `json:"total_amount"`
+	}{bulkTransferDetail: (*bulkTransferDetail)(l)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	totalAmount, err := parseAmount("total_amount", aux.TotalAmount, AccountCurrency)
+	if err != nil {
+		return err
+	}
+	l.TotalAmount = totalAmount
+
+	return nil
+}
+
+// UnmarshalJSON parses the amount exactly in the account currency
+func (l *Fee) UnmarshalJSON(data []byte) error {
+	type fee Fee
+	aux := struct {
+		*fee
+		Amount string `json:"amount"`
+	}{fee: (*fee)(l)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	amount, err := parseAmount("amount", aux.Amount, AccountCurrency)
+	if err != nil {
+		return err
+	}
+	l.Amount = amount
+
+	return nil
+}
+
+// UnmarshalJSON parses the total amount exactly in the account currency
+func (l *FeeBreakdown) UnmarshalJSON(data []byte) error {
+	type feeBreakdown FeeBreakdown
+	aux := struct {
+		*feeBreakdown
+		TotalAmount string `json:"total_amount"`
+	}{feeBreakdown: (*feeBreakdown)(l)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	totalAmount, err := parseAmount("total_amount", aux.TotalAmount, AccountCurrency)
+	if err != nil {
+		return err
+	}
+	l.TotalAmount = totalAmount
+
+	return nil
 }
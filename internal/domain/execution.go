@@ -0,0 +1,57 @@
+package domain
+
+// ExecutionMode represents what happens to a bulk transfer when some of its credit transfers can't be executed
+type ExecutionMode string
+
+const (
+	// ExecutionAllOrNothing the bulk transfer is rejected as soon as one of its credit transfers can't be executed
+	ExecutionAllOrNothing ExecutionMode = "all_or_nothing"
+	// ExecutionBestEffort the valid credit transfers are executed by priority until funds or limits run out
+	ExecutionBestEffort ExecutionMode = "best_effort"
+)
+
+// LineStatus represents the outcome of a credit transfer of a best effort bulk transfer
+type LineStatus string
+
+const (
+	// LineExecuted the credit transfer was registered and the account debited
+	LineExecuted LineStatus = "executed"
+	// LineRejected the credit transfer is invalid, it is never executed
+	LineRejected LineStatus = "rejected"
+	// LineSkipped funds or limits ran out before the credit transfer
+	LineSkipped LineStatus = "skipped"
+)
+
+// LineOutcome Struct that represents the outcome of the credit transfer at Index, the reason being set when it wasn't executed
+type LineOutcome struct {
+	Index         int             `json:"index"`
+	Status        LineStatus      `json:"status"`
+	TransactionID uint            `json:"transaction_id,omitempty"`
+	Reason        RejectionReason `json:"reason,omitempty"`
+	Message       string          `json:"message,omitempty"`
+}
+
+// ExecutionReport Struct that represents the outcome of every credit transfer of a best effort bulk transfer, in the
+// order of the lines, and how many were executed, rejected and skipped
+type ExecutionReport struct {
+	Executed int           `json:"executed"`
+	Rejected int           `json:"rejected"`
+	Skipped  int           `json:"skipped"`
+	Lines    []LineOutcome `json:"lines"`
+}
+
+// NewExecutionReport summarises the outcomes of the lines
+func NewExecutionReport(lines []LineOutcome) *ExecutionReport {
+	report := &ExecutionReport{Lines: lines}
+	for _, line := range lines {
+		switch line.Status {
+		case LineExecuted:
+			report.Executed++
+		case LineRejected:
+			report.Rejected++
+		case LineSkipped:
+			report.Skipped++
+		}
+	}
+	return report
+}
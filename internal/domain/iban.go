@@ -0,0 +1,59 @@
+package domain
+
+import "regexp"
+
+// ibanLengths length of the IBAN of each country of the SWIFT IBAN registry
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22, "BH": 22, "BI": 27,
+	"BR": 29, "BY": 28, "CH": 21, "CR": 22, "CY": 28, "CZ": 24, "DE": 22, "DJ": 27, "DK": 18, "DO": 28,
+	"EE": 20, "EG": 29, "ES": 24, "FI": 18, "FO": 18, "FR": 27, "GB": 22, "GE": 22, "GI": 23, "GL": 18,
+	"GR": 27, "GT": 28, "HR": 21, "HU": 28, "IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27, "JO": 30,
+	"KW": 30, "KZ": 20, "LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20, "LV": 21, "LY": 25, "MC": 27,
+	"MD": 24, "ME": 22, "MK": 19, "MR": 27, "MT": 31, "MU": 30, "NL": 18, "NO": 15, "PK": 24, "PL": 28,
+	"PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "RU": 33, "SA": 24, "SC": 31, "SD": 18, "SE": 24,
+	"SI": 19, "SK": 24, "SM": 27, "ST": 25, "SV": 28, "TL": 23, "TN": 24, "TR": 26, "UA": 29, "VA": 22,
+	"VG": 24, "XK": 20,
+}
+
+var (
+	ibanFormat = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]+$`)
+	// bicFormat ISO 9362: 4 letters institution, 2 letters country, 2 characters location and an optional 3 characters branch
+	bicFormat = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+)
+
+// ValidIban reports whether the IBAN, in its electronic format (no spaces, upper case), has the
+// length of its country and a valid ISO 13616 mod-97 checksum
+func ValidIban(iban string) bool {
+	if !ibanFormat.MatchString(iban) {
+		return false
+	}
+
+	length, ok := ibanLengths[iban[:2]]
+	if !ok || len(iban) != length {
+		return false
+	}
+
+	// move the country code and check digits to the end, then compute the remainder of the number
+	// obtained replacing every letter by two digits (A = 10, ..., Z = 35) piece by piece
+	remainder := 0
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' {
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(r-'0')) % 97
+		}
+	}
+
+	return remainder == 1
+}
+
+// ValidAccountIban reports whether the IBAN may reference a registered bank account: only its electronic format is
+// checked, the accounts registered before the IBANs were validated keeping an IBAN that may fail the checksum
+func ValidAccountIban(iban string) bool {
+	return ibanFormat.MatchString(iban)
+}
+
+// ValidBic reports whether the BIC has the ISO 9362 format, with 8 or 11 characters
+func ValidBic(bic string) bool {
+	return bicFormat.MatchString(bic)
+}
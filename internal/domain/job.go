@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// JobStatus represents the lifecycle state of the job executing a bulk transfer submitted asynchronously
+type JobStatus string
+
+const (
+	// JobQueued the job waits for a worker
+	JobQueued JobStatus = "queued"
+	// JobRunning a worker is executing the bulk transfer
+	JobRunning JobStatus = "running"
+	// JobDone the bulk transfer was executed, its status tells whether in full or in part
+	JobDone JobStatus = "done"
+	// JobFailed the bulk transfer was rejected, the error of the job tells why
+	JobFailed JobStatus = "failed"
+	// JobDeferred the bulk transfer wasn't executed, being left pending approval, scheduled or held for review, its
+	// status tells which. It is executed outside of the job.
+	JobDeferred JobStatus = "deferred"
+)
+
+// BulkTransferJob Struct that represents the asynchronous processing of a bulk transfer, LinesDone being how many
+// of its LinesTotal credit transfers were registered so far
+type BulkTransferJob struct {
+	ID             uint       `json:"id"`
+	BulkTransferID uint       `json:"bulk_transfer_id"`
+	Status         JobStatus  `json:"status"`
+	LinesTotal     int        `json:"lines_total"`
+	LinesDone      int        `json:"lines_done"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+// LedgerOperation identifies the operation a ledger journal was posted for
+type LedgerOperation string
+
+const (
+	// LedgerOpeningBalance the balance a bank account was registered with
+	LedgerOpeningBalance LedgerOperation = "opening_balance"
+	// LedgerAdjustment a drift of the balance fixed by a reconciliation
+	LedgerAdjustment LedgerOperation = "adjustment"
+	// LedgerBulkTransfer the execution of a bulk transfer
+	LedgerBulkTransfer LedgerOperation = "bulk_transfer"
+	// LedgerReversal the reversal of a transaction
+	LedgerReversal LedgerOperation = "reversal"
+)
+
+// LedgerSide tells whether a ledger entry debits or credits its account
+type LedgerSide string
+
+const (
+	// LedgerDebit the entry takes the amount out of its account
+	LedgerDebit LedgerSide = "debit"
+	// LedgerCredit the entry puts the amount into its account
+	LedgerCredit LedgerSide = "credit"
+)
+
+// LedgerEntry Struct that represents a posting of a ledger journal. The entries of a journal are balanced,
+// their debits and credits add up to the same amount.
+type LedgerEntry struct {
+	ID          uint            `json:"id"`
+	JournalID   uint            `json:"journal_id"`
+	Operation   LedgerOperation `json:"operation"`
+	Reference   string          `json:"reference,omitempty" example:"bulk_transfer:3"`
+	Description string          `json:"description,omitempty"`
+	// Account the ledger account, bank_account:<id> for the bank accounts, external for the other banks and equity
+	// for the opening balances and adjustments
+	Account       string     `json:"account" example:"bank_account:1"`
+	Side          LedgerSide `json:"side"`
+	Amount        Money      `json:"amount" swaggertype:"string" example:"14.53"`
+	TransactionID uint       `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// LedgerEntryList Struct that represents a list of ledger entries
+type LedgerEntryList []LedgerEntry
+
+// LedgerBalance Struct that represents the balance of a bank account derived from its ledger entries,
+// along with the balance cached on the bank account
+type LedgerBalance struct {
+	Iban          string `json:"iban"`
+	Account       string `json:"account" example:"bank_account:1"`
+	Balance       Money  `json:"balance" swaggertype:"string" example:"100000.00"`
+	CachedBalance Money  `json:"cached_balance" swaggertype:"string" example:"100000.00"`
+	Consistent    bool   `json:"consistent"`
+}
@@ -0,0 +1,163 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AccountCurrency is the currency every bank account is held in
+const AccountCurrency = "EUR"
+
+// maxDigits keeps parsed amounts within the int64 range
+const maxDigits = 18
+
+var (
+	// ErrInvalidAmount is returned when an amount is not a plain decimal number
+	ErrInvalidAmount = errors.New("invalid amount")
+	// ErrUnknownCurrency is returned when the currency exponent is not known
+	ErrUnknownCurrency = errors.New("unknown currency")
+	// ErrExcessPrecision is returned when an amount has more decimals than its currency allows
+	ErrExcessPrecision = errors.New("amount has more decimals than the currency allows")
+)
+
+// currencyExponents number of decimals of the minor unit of each supported ISO 4217 currency
+var currencyExponents = map[string]int{
+	"AUD": 2,
+	"BGN": 2,
+	"BHD": 3,
+	"CAD": 2,
+	"CHF": 2,
+	"CZK": 2,
+	"DKK": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HUF": 2,
+	"ISK": 0,
+	"JPY": 0,
+	"KWD": 3,
+	"NOK": 2,
+	"PLN": 2,
+	"RON": 2,
+	"SEK": 2,
+	"USD": 2,
+}
+
+// Money represents an exact monetary amount in the minor units of its currency (e.g. cents).
+// It is exchanged as a decimal string, such as "14.53".
+type Money struct {
+	MinorUnits int64
+	Currency   string
+}
+
+// NewMoney returns the amount of minor units in the given currency
+func NewMoney(minorUnits int64, currency string) Money {
+	return Money{MinorUnits: minorUnits, Currency: currency}
+}
+
+// CurrencyExponent returns the number of decimals of the minor unit of the currency
+func CurrencyExponent(currency string) (int, error) {
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return exponent, nil
+}
+
+// ParseMoney parses a decimal string, such as "14.53" or "-10", exactly into minor units of the
+// currency. Amounts with more significant decimals than the currency allows are rejected.
+func ParseMoney(amount, currency string) (Money, error) {
+	exponent, err := CurrencyExponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	s := amount
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = s[1:]
+	}
+
+	integer, fraction, hasPoint := strings.Cut(s, ".")
+	if integer == "" || (hasPoint && fraction == "") || !isDigits(integer) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+
+	if len(fraction) > exponent {
+		if strings.Trim(fraction[exponent:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrExcessPrecision, amount, exponent, currency)
+		}
+		fraction = fraction[:exponent]
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	digits := strings.TrimLeft(integer+fraction, "0")
+	if digits == "" {
+		return NewMoney(0, currency), nil
+	}
+	if len(digits) > maxDigits {
+		return Money{}, fmt.Errorf("%w: %q is too large", ErrInvalidAmount, amount)
+	}
+
+	minorUnits, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if negative {
+		minorUnits = -minorUnits
+	}
+
+	return NewMoney(minorUnits, currency), nil
+}
+
+// String returns the amount as a decimal string using the exponent of its currency
+func (m Money) String() string {
+	exponent, err := CurrencyExponent(m.Currency)
+	if err != nil || exponent == 0 {
+		return strconv.FormatInt(m.MinorUnits, 10)
+	}
+
+	sign := ""
+	units := m.MinorUnits
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	digits := strconv.FormatInt(units, 10)
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// MarshalJSON serializes the amount as a decimal string
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseAmount parses the decimal amount of a JSON payload in the given currency.
+// A missing amount is left at zero for the validation to report.
+func parseAmount(field, amount, currency string) (Money, error) {
+	if amount == "" {
+		return NewMoney(0, currency), nil
+	}
+
+	money, err := ParseMoney(amount, currency)
+	if err != nil {
+		return Money{}, fmt.Errorf("%s: %w", field, err)
+	}
+
+	return money, nil
+}
@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+		err      error
+	}{
+		{amount: "0.29", currency: "EUR", want: 29},
+		{amount: "14.55", currency: "EUR", want: 1455},
+		{amount: "14.5", currency: "EUR", want: 1450},
+		{amount: "14.550", currency: "EUR", want: 1455},
+		{amount: "1000", currency: "EUR", want: 100000},
+		{amount: "-10.01", currency: "EUR", want: -1001},
+		{amount: "0", currency: "EUR", want: 0},
+		{amount: "1500", currency: "JPY", want: 1500},
+		{amount: "1.234", currency: "KWD", want: 1234},
+		{amount: "14.555", currency: "EUR", err: ErrExcessPrecision},
+		{amount: "15.5", currency: "JPY", err: ErrExcessPrecision},
+		{amount: "14,55", currency: "EUR", err: ErrInvalidAmount},
+		{amount: "1e3", currency: "EUR", err: ErrInvalidAmount},
+		{amount: ".5", currency: "EUR", err: ErrInvalidAmount},
+		{amount: "5.", currency: "EUR", err: ErrInvalidAmount},
+		{amount: "+5", currency: "EUR", err: ErrInvalidAmount},
+		{amount: "", currency: "EUR", err: ErrInvalidAmount},
+		{amount: "99999999999999999999", currency: "EUR", err: ErrInvalidAmount},
+		{amount: "10", currency: "XXX", err: ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			m, err := ParseMoney(tt.amount, tt.currency)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, NewMoney(tt.want, tt.currency), m)
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	assert.Equal(t, "0.29", NewMoney(29, "EUR").String())
+	assert.Equal(t, "0.05", NewMoney(5, "EUR").String())
+	assert.Equal(t, "14.55", NewMoney(1455, "EUR").String())
+	assert.Equal(t, "-10.01", NewMoney(-1001, "EUR").String())
+	assert.Equal(t, "1500", NewMoney(1500, "JPY").String())
+	assert.Equal(t, "1.234", NewMoney(1234, "KWD").String())
+
+	for _, amount := range []string{"0.29", "14.55", "100000.00", "-0.01"} {
+		m, err := ParseMoney(amount, "EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, amount, m.String())
+	}
+}
+
+func TestCreditTransferJSON(t *testing.T) {
+	t.Run("Test amount is parsed exactly", func(t *testing.T) {
+		var c CreditTransfer
+		err := json.Unmarshal([]byte(`{"amount": "0.29", "currency": "EUR", "counterparty_name": "Bip Bip"}`), &c)
+		assert.NoError(t, err)
+		assert.Equal(t, NewMoney(29, "EUR"), c.Amount)
+		assert.Equal(t, "Bip Bip", c.CounterPartyName)
+
+		b, err := json.Marshal(c)
+		assert.NoError(t, err)
+		assert.Contains(t, string(b), `"amount":"0.29"`)
+	})
+
+	t.Run("Test excess precision is rejected by the validation of its line", func(t *testing.T) {
+		var b BulkTransfer
+		err := json.Unmarshal([]byte(`{"organization_name": "ACME Corp", "organization_bic": "OIVUSCLQXXX", "organization_iban": "FR81474608000002006107XXXXX",
+			"credit_transfers": [{"amount": "14.555", "currency": "EUR", "counterparty_name": "Bip Bip", "counterparty_bic": "CRLYFRPPTOU", "counterparty_iban": "EE303680981021245685"}]}`), &b)
+		assert.NoError(t, err)
+
+		var validationError ValidationError
+		assert.True(t, errors.As(b.Validate(), &validationError))
+		assert.Len(t, validationError.Fields, 1)
+		assert.Equal(t, "credit_transfers[0].amount", validationError.Fields[0].Field)
+		assert.Equal(t, "amount", validationError.Fields[0].Rule)
+		assert.Contains(t, validationError.Fields[0].Message, ErrExcessPrecision.Error())
+	})
+
+	t.Run("Test invalid balance is rejected", func(t *testing.T) {
+		var bankAccount BankAccount
+		err := json.Unmarshal([]byte(`{"name": "ACME Corp", "balance": "12.345"}`), &bankAccount)
+		assert.ErrorIs(t, err, ErrExcessPrecision)
+	})
+
+	t.Run("Test overdraft is parsed and the overdraft headroom ignored", func(t *testing.T) {
+		var bankAccount BankAccount
+		err := json.Unmarshal([]byte(`{"name": "ACME Corp", "balance": "-12.00", "overdraft": "500", "overdraft_headroom": "488.00"}`), &bankAccount)
+		assert.NoError(t, err)
+		assert.Equal(t, NewMoney(-1200, "EUR"), bankAccount.Balance)
+		assert.Equal(t, NewMoney(50000, "EUR"), *bankAccount.Overdraft)
+		assert.Nil(t, bankAccount.OverdraftHeadroom)
+	})
+
+	t.Run("Test the fees of a bulk transfer are parsed exactly", func(t *testing.T) {
+		var detail BulkTransferDetail
+		err := json.Unmarshal([]byte(`{"id": 3, "total_amount": "14.53", "fees": {"total_amount": "0.50", "fees": [{"index": 0, "rule": "sepa", "amount": "0.50", "transaction_id": 8}]}}`), &detail)
+		assert.NoError(t, err)
+		assert.Equal(t, &FeeBreakdown{
+			TotalAmount: NewMoney(50, "EUR"),
+			Fees:        []Fee{{Index: 0, Rule: "sepa", Amount: NewMoney(50, "EUR"), TransactionID: 8}},
+		}, detail.Fees)
+	})
+
+	t.Run("Test missing amount is reported by the validation", func(t *testing.T) {
+		var b BulkTransfer
+		err := json.Unmarshal([]byte(`{"organization_name": "ACME Corp", "organization_bic": "OIVUSCLQXXX", "organization_iban": "FR81474608000002006107XXXXX",
+			"credit_transfers": [{"currency": "EUR", "counterparty_name": "Bip Bip", "counterparty_bic": "CRLYFRPPTOU", "counterparty_iban": "EE303680981021245685"}]}`), &b)
+		assert.NoError(t, err)
+		assert.Error(t, b.Validate())
+	})
+}
+
+func TestOverdrawnCents(t *testing.T) {
+	assert.Equal(t, int64(0), OverdrawnCents(100, 500))
+	assert.Equal(t, int64(0), OverdrawnCents(0, 500))
+	assert.Equal(t, int64(200), OverdrawnCents(-200, 500))
+	// the balance was below zero before the debit
+	assert.Equal(t, int64(500), OverdrawnCents(-700, 500))
+}
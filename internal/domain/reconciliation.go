@@ -0,0 +1,34 @@
+package domain
+
+// BalanceDrift Struct that represents a bank account whose balance doesn't match the balance expected from its
+// opening balance, its adjustments and its transactions, or the balance of its ledger account
+type BalanceDrift struct {
+	Iban            string `json:"iban"`
+	Name            string `json:"name"`
+	Balance         Money  `json:"balance"`
+	ExpectedBalance Money  `json:"expected_balance"`
+	// Drift the balance minus the expected balance
+	Drift         Money `json:"drift"`
+	LedgerBalance Money `json:"ledger_balance"`
+	// LedgerDrift the balance minus the ledger balance
+	LedgerDrift Money `json:"ledger_drift"`
+	// Fixed tells whether an adjustment accounting for the drift was written and posted on the ledger
+	Fixed bool `json:"fixed"`
+}
+
+// Reconciliation Struct that represents the report of a balance reconciliation
+type Reconciliation struct {
+	Accounts int            `json:"accounts"`
+	Drifts   []BalanceDrift `json:"drifts"`
+}
+
+// Unresolved returns the number of drifts that weren't fixed
+func (r Reconciliation) Unresolved() int {
+	unresolved := 0
+	for _, drift := range r.Drifts {
+		if !drift.Fixed {
+			unresolved++
+		}
+	}
+	return unresolved
+}
@@ -0,0 +1,164 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+)
+
+// RejectionReason identifies why a bulk transfer was rejected
+type RejectionReason string
+
+const (
+	// RejectionInvalidBody the payload is not a valid JSON bulk transfer
+	RejectionInvalidBody RejectionReason = "invalid_body"
+	// RejectionInvalidFields one or more fields, of the batch or of its lines, are invalid
+	RejectionInvalidFields RejectionReason = "invalid_fields"
+	// RejectionAccountNotFound the organization bank account doesn't exist
+	RejectionAccountNotFound RejectionReason = "account_not_found"
+	// RejectionInsufficientFunds the balance doesn't cover the total of the bulk transfer
+	RejectionInsufficientFunds RejectionReason = "insufficient_funds"
+	// RejectionLimitExceeded the bulk transfer breaches a transfer limit of the account
+	RejectionLimitExceeded RejectionReason = "limit_exceeded"
+	// RejectionScreeningHit a counterparty matches an entry of the sanctions list or blocklist
+	RejectionScreeningHit RejectionReason = "screening_hit"
+	// RejectionDuplicateSuspected credit transfers look like duplicates of recent ones and duplicates weren't allowed
+	RejectionDuplicateSuspected RejectionReason = "duplicate_suspected"
+	// RejectionFailed the bulk transfer couldn't be executed
+	RejectionFailed RejectionReason = "failed"
+)
+
+// TransferLimit identifies a transfer limit of a bank account
+type TransferLimit string
+
+const (
+	// LimitPerTransaction the maximum amount of a credit transfer
+	LimitPerTransaction TransferLimit = "per_transaction"
+	// LimitPerBatch the maximum total of a bulk transfer
+	LimitPerBatch TransferLimit = "per_batch"
+	// LimitDaily the maximum total transferred per day
+	LimitDaily TransferLimit = "daily"
+	// LimitMonthly the maximum total transferred per calendar month
+	LimitMonthly TransferLimit = "monthly"
+)
+
+// LineError describes an invalid field. Index is the position of the line in credit_transfers and is
+// omitted for the fields of the batch itself.
+type LineError struct {
+	Index   *int   `json:"index,omitempty"`
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Rejection Struct that reports why a bulk transfer was rejected, listing the failing lines
+type Rejection struct {
+	Reason          RejectionReason `json:"reason"`
+	Message         string          `json:"message"`
+	Errors          []LineError     `json:"errors,omitempty"`
+	RequiredAmount  *Money          `json:"required_amount,omitempty" swaggertype:"string"`
+	AvailableAmount *Money          `json:"available_amount,omitempty" swaggertype:"string"`
+	// Limit the breached transfer limit and Headroom what can still be transferred within it
+	Limit    TransferLimit `json:"limit,omitempty"`
+	Headroom *Money        `json:"headroom,omitempty" swaggertype:"string"`
+	// Matches the entries of the screening list the counterparties match
+	Matches []ScreeningMatch `json:"matches,omitempty"`
+	// Duplicates the recent transactions the credit transfers look like duplicates of
+	Duplicates []Duplicate `json:"duplicates,omitempty"`
+	err        error
+}
+
+// NewRejection returns a rejection for the reason, caused by err
+func NewRejection(reason RejectionReason, err error) *Rejection {
+	return &Rejection{Reason: reason, Message: err.Error(), err: err}
+}
+
+// NewInsufficientFundsRejection returns a rejection reporting the required amount and, when known, the available one
+func NewInsufficientFundsRejection(err error, required Money, available *Money) *Rejection {
+	rejection := NewRejection(RejectionInsufficientFunds, err)
+	rejection.RequiredAmount = &required
+	rejection.AvailableAmount = available
+	return rejection
+}
+
+// NewLimitRejection returns a rejection naming the breached limit and what can still be transferred within it
+func NewLimitRejection(err error, limit TransferLimit, headroom Money) *Rejection {
+	rejection := NewRejection(RejectionLimitExceeded, err)
+	rejection.Limit = limit
+	rejection.Headroom = &headroom
+	return rejection
+}
+
+// NewScreeningRejection returns a rejection listing the entries of the screening list the counterparties match
+func NewScreeningRejection(err error, matches []ScreeningMatch) *Rejection {
+	rejection := NewRejection(RejectionScreeningHit, err)
+	rejection.Matches = matches
+	return rejection
+}
+
+// NewDuplicateRejection returns a rejection listing the recent transactions the credit transfers look like duplicates of
+func NewDuplicateRejection(err error, duplicates []Duplicate) *Rejection {
+	rejection := NewRejection(RejectionDuplicateSuspected, err)
+	rejection.Duplicates = duplicates
+	return rejection
+}
+
+// WithLineError adds an error on a field of the line at index
+func (r *Rejection) WithLineError(index int, field, rule, message string) *Rejection {
+	r.Errors = append(r.Errors, LineError{Index: &index, Field: field, Rule: rule, Message: message})
+	return r
+}
+
+// WithFieldError adds an error on a field of the request itself
+func (r *Rejection) WithFieldError(field, rule, message string) *Rejection {
+	r.Errors = append(r.Errors, LineError{Field: field, Rule: rule, Message: message})
+	return r
+}
+
+// LinesOnly tells whether the rejection only reports errors on fields of the lines
+func (r *Rejection) LinesOnly() bool {
+	for _, lineError := range r.Errors {
+		if lineError.Index == nil {
+			return false
+		}
+	}
+	return len(r.Errors) > 0
+}
+
+func (r *Rejection) Error() string {
+	return r.Message
+}
+
+func (r *Rejection) Unwrap() error {
+	return r.err
+}
+
+// lineField matches the fields of a credit transfer, e.g. credit_transfers[3].amount
+var lineField = regexp.MustCompile(`^credit_transfers\[(\d+)\]\.(.+)$`)
+
+// AsRejection returns the rejection carried by err. Validation errors are reported by line and any
+// other error is reported as a failure.
+func AsRejection(err error) *Rejection {
+	var rejection *Rejection
+	if errors.As(err, &rejection) {
+		return rejection
+	}
+
+	var validationError ValidationError
+	if !errors.As(err, &validationError) {
+		return NewRejection(RejectionFailed, err)
+	}
+
+	rejection = NewRejection(RejectionInvalidFields, err)
+	for _, field := range validationError.Fields {
+		lineError := LineError{Field: field.Field, Rule: field.Rule, Message: field.Message}
+		if match := lineField.FindStringSubmatch(field.Field); match != nil {
+			index, _ := strconv.Atoi(match[1])
+			lineError.Index = &index
+			lineError.Field = match[2]
+		}
+		rejection.Errors = append(rejection.Errors, lineError)
+	}
+
+	return rejection
+}
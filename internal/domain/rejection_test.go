@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAsRejection(t *testing.T) {
+	t.Run("Test validation errors are reported by line", func(t *testing.T) {
+		err := ValidationError{Fields: []FieldError{
+			{Field: "organization_iban", Rule: "iban", Message: "is not a valid IBAN"},
+			{Field: "credit_transfers[12].counterparty_bic", Rule: "required", Message: "is required"},
+		}}
+
+		rejection := AsRejection(err)
+
+		index := 12
+		assert.Equal(t, RejectionInvalidFields, rejection.Reason)
+		assert.Equal(t, []LineError{
+			{Field: "organization_iban", Rule: "iban", Message: "is not a valid IBAN"},
+			{Index: &index, Field: "counterparty_bic", Rule: "required", Message: "is required"},
+		}, rejection.Errors)
+	})
+
+	t.Run("Test wrapped rejections are returned as is", func(t *testing.T) {
+		cause := errors.New("Insufficient credits to complete the transfer")
+		rejection := NewInsufficientFundsRejection(cause, NewMoney(1453, "EUR"), nil)
+
+		res := AsRejection(fmt.Errorf("bulk transfer: %w", rejection))
+
+		assert.Same(t, rejection, res)
+		assert.ErrorIs(t, res, cause)
+		assert.Nil(t, res.AvailableAmount)
+	})
+
+	t.Run("Test other errors are reported as failures", func(t *testing.T) {
+		rejection := AsRejection(errors.New("error"))
+
+		assert.Equal(t, RejectionFailed, rejection.Reason)
+		assert.Equal(t, "error", rejection.Message)
+		assert.Empty(t, rejection.Errors)
+	})
+}
+
+func TestRejectionLinesOnly(t *testing.T) {
+	cause := errors.New("invalid fields")
+
+	assert.True(t, NewRejection(RejectionInvalidFields, cause).WithLineError(1, "amount", "gt", "must be positive").LinesOnly())
+	assert.False(t, NewRejection(RejectionInvalidFields, cause).WithLineError(1, "amount", "gt", "must be positive").
+		WithFieldError("organization_iban", "iban", "is not a valid IBAN").LinesOnly())
+	assert.False(t, NewRejection(RejectionInsufficientFunds, cause).LinesOnly())
+}
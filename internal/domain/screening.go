@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// ScreeningOutcome represents the decision of a reviewer on a bulk transfer held for review
+type ScreeningOutcome string
+
+const (
+	// ScreeningReleased the reviewer cleared the matches, the bulk transfer goes on
+	ScreeningReleased ScreeningOutcome = "released"
+	// ScreeningBlocked the reviewer confirmed a match, the bulk transfer is never executed
+	ScreeningBlocked ScreeningOutcome = "blocked"
+)
+
+// ScreeningMatch Struct that represents a counterparty of the line at Index matching an entry of the screening list
+type ScreeningMatch struct {
+	Index int `json:"index"`
+	// Field the matching counterparty field, counterparty_name or counterparty_iban
+	Field       string  `json:"field"`
+	EntryID     string  `json:"entry_id"`
+	EntryName   string  `json:"entry_name"`
+	EntrySource string  `json:"entry_source,omitempty"`
+	Score       float64 `json:"score"`
+}
+
+// BulkTransferRelease Struct that represents the release of a bulk transfer held for review
+type BulkTransferRelease struct {
+	Comment string `json:"comment,omitempty" example:"namesake, different birth date"`
+}
+
+// BulkTransferBlock Struct that represents the block of a bulk transfer held for review
+type BulkTransferBlock struct {
+	Reason string `json:"reason" validate:"required" example:"confirmed sanctioned party"`
+}
+
+// Validate validates the BulkTransferBlock struct based on 'validate' tags of its fields
+func (l *BulkTransferBlock) Validate() error {
+	return validate(l)
+}
+
+// Screening Struct that represents why a bulk transfer was held for review and how it was reviewed
+type Screening struct {
+	Matches []ScreeningMatch `json:"matches"`
+	Review  *ScreeningReview `json:"review,omitempty"`
+}
+
+// ScreeningReview Struct that represents who released, or blocked, a bulk transfer held for review, when and why
+type ScreeningReview struct {
+	Actor   string           `json:"actor"`
+	Outcome ScreeningOutcome `json:"outcome"`
+	Comment string           `json:"comment,omitempty"`
+	At      time.Time        `json:"at"`
+}
@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// RecurrenceDaily the template is executed every Interval days
+	RecurrenceDaily = "daily"
+	// RecurrenceWeekly the template is executed every Interval weeks, on the weekday of its start date
+	RecurrenceWeekly = "weekly"
+	// RecurrenceMonthly the template is executed every Interval months, on DayOfMonth
+	RecurrenceMonthly = "monthly"
+)
+
+// Recurrence Struct that represents when a transfer template is executed. Occurrences start on the start
+// date of the template and repeat every Interval days, weeks or months. Monthly occurrences fall on
+// DayOfMonth, or on the last day of the months that are shorter.
+type Recurrence struct {
+	Frequency  string `json:"frequency" validate:"required,oneof=daily weekly monthly" example:"monthly"`
+	Interval   int    `json:"interval,omitempty" validate:"gte=0" example:"1"`
+	DayOfMonth int    `json:"day_of_month,omitempty" validate:"required_if=Frequency monthly,omitempty,min=1,max=31" example:"25"`
+}
+
+// Next returns the first occurrence, in a recurrence starting on start, that is on or after from
+func (r Recurrence) Next(start, from time.Time) time.Time {
+	start, from = day(start), day(from)
+	if from.Before(start) {
+		from = start
+	}
+
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+
+	step := interval
+	switch r.Frequency {
+	case RecurrenceMonthly:
+		months := (from.Year()-start.Year())*12 + int(from.Month()-start.Month())
+		for n := months / interval * interval; ; n += interval {
+			if occurrence := r.inMonth(start, n); !occurrence.Before(from) {
+				return occurrence
+			}
+		}
+	case RecurrenceWeekly:
+		step = 7 * interval
+	}
+
+	days := int(from.Sub(start).Hours() / 24)
+	return start.AddDate(0, 0, (days+step-1)/step*step)
+}
+
+// Upcoming returns the next count occurrences, in a recurrence starting on start, that are on or after from
+func (r Recurrence) Upcoming(start, from time.Time, count int) []time.Time {
+	occurrences := make([]time.Time, 0, count)
+	for i := 0; i < count; i++ {
+		occurrence := r.Next(start, from)
+		occurrences = append(occurrences, occurrence)
+		from = occurrence.AddDate(0, 0, 1)
+	}
+	return occurrences
+}
+
+// inMonth the monthly occurrence n months after the month of start
+func (r Recurrence) inMonth(start time.Time, n int) time.Time {
+	first := time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	if r.DayOfMonth < last {
+		last = r.DayOfMonth
+	}
+	return first.AddDate(0, 0, last-1)
+}
+
+// day truncates t to the start of its day in UTC
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// TransferTemplate Struct that represents a reusable bulk transfer, executed on every occurrence of its recurrence
+type TransferTemplate struct {
+	Name             string           `json:"name" validate:"required" example:"Monthly payroll"`
+	OrganizationName string           `json:"organization_name" validate:"required"`
+	OrganizationBic  string           `json:"organization_bic" validate:"required,bic"`
+	OrganizationIban string           `json:"organization_iban" validate:"required,account_iban"`
+	CreditTransfers  []CreditTransfer `json:"credit_transfers" validate:"required,dive"`
+	Recurrence       Recurrence       `json:"recurrence"`
+	// StartDate day, in UTC, the recurrence starts on
+	StartDate string `json:"start_date" validate:"required,datetime=2006-01-02" example:"2022-09-25"`
+}
+
+// Validate validates the TransferTemplate struct based on 'validate' tags of its fields
+func (l *TransferTemplate) Validate() error {
+	return validateWithLines(l, l.CreditTransfers)
+}
+
+// StartTime returns the day the recurrence starts on
+func (l TransferTemplate) StartTime() (time.Time, error) {
+	return time.Parse(ExecutionDateLayout, l.StartDate)
+}
+
+// TransferTemplateStatus represents whether a transfer template is executed
+type TransferTemplateStatus string
+
+const (
+	// TransferTemplateActive a bulk transfer is created on every occurrence of the template
+	TransferTemplateActive TransferTemplateStatus = "active"
+	// TransferTemplatePaused the occurrences of the template are skipped until it is resumed
+	TransferTemplatePaused TransferTemplateStatus = "paused"
+)
+
+// TransferTemplateDetail Struct that represents a stored transfer template
+type TransferTemplateDetail struct {
+	ID               uint                   `json:"id"`
+	Name             string                 `json:"name"`
+	OrganizationName string                 `json:"organization_name"`
+	OrganizationBic  string                 `json:"organization_bic"`
+	OrganizationIban string                 `json:"organization_iban"`
+	TransfersCount   int                    `json:"transfers_count"`
+	TotalAmount      Money                  `json:"total_amount" swaggertype:"string" example:"29.06"`
+	Recurrence       Recurrence             `json:"recurrence"`
+	StartDate        string                 `json:"start_date"`
+	Status           TransferTemplateStatus `json:"status"`
+	NextOccurrence   string                 `json:"next_occurrence,omitempty"`
+	LastOccurrence   string                 `json:"last_occurrence,omitempty"`
+	CreatedAt        time.Time              `json:"created_at"`
+	UpdatedAt        time.Time              `json:"updated_at"`
+	CreditTransfers  []CreditTransfer       `json:"credit_transfers,omitempty"`
+}
+
+// TransferTemplateDetailList Struct that represents a list of stored transfer templates
+type TransferTemplateDetailList []TransferTemplateDetail
+
+// UnmarshalJSON parses the total amount exactly in the account currency
+func (l *TransferTemplateDetail) UnmarshalJSON(data []byte) error {
+	type transferTemplateDetail TransferTemplateDetail
+	aux := struct {
+		*transferTemplateDetail
+		TotalAmount string `json:"total_amount"`
+	}{transferTemplateDetail: (*transferTemplateDetail)(l)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	totalAmount, err := parseAmount("total_amount", aux.TotalAmount, AccountCurrency)
+	if err != nil {
+		return err
+	}
+	l.TotalAmount = totalAmount
+
+	return nil
+}
+
+// TransferTemplateOccurrences Struct that represents the upcoming days a transfer template is executed on
+type TransferTemplateOccurrences struct {
+	TemplateID  uint     `json:"template_id"`
+	Occurrences []string `json:"occurrences" example:"2022-09-25,2022-10-25"`
+}
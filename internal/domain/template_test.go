@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(ExecutionDateLayout, s)
+	return t
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	tests := []struct {
+		name       string
+		recurrence Recurrence
+		start      string
+		from       string
+		want       string
+	}{
+		{name: "monthly before the start", recurrence: Recurrence{Frequency: RecurrenceMonthly, DayOfMonth: 25}, start: "2022-09-01", from: "2022-08-01", want: "2022-09-25"},
+		{name: "monthly on the day", recurrence: Recurrence{Frequency: RecurrenceMonthly, DayOfMonth: 25}, start: "2022-09-01", from: "2022-10-25", want: "2022-10-25"},
+		{name: "monthly after the day", recurrence: Recurrence{Frequency: RecurrenceMonthly, DayOfMonth: 25}, start: "2022-09-01", from: "2022-10-26", want: "2022-11-25"},
+		{name: "monthly day before the start day", recurrence: Recurrence{Frequency: RecurrenceMonthly, DayOfMonth: 5}, start: "2022-09-10", from: "2022-09-10", want: "2022-10-05"},
+		{name: "monthly on a shorter month", recurrence: Recurrence{Frequency: RecurrenceMonthly, DayOfMonth: 31}, start: "2022-01-31", from: "2022-02-01", want: "2022-02-28"},
+		{name: "monthly on a leap year", recurrence: Recurrence{Frequency: RecurrenceMonthly, DayOfMonth: 30}, start: "2024-01-31", from: "2024-02-01", want: "2024-02-29"},
+		{name: "quarterly", recurrence: Recurrence{Frequency: RecurrenceMonthly, Interval: 3, DayOfMonth: 1}, start: "2022-01-01", from: "2022-02-15", want: "2022-04-01"},
+		{name: "quarterly across years", recurrence: Recurrence{Frequency: RecurrenceMonthly, Interval: 3, DayOfMonth: 1}, start: "2022-01-01", from: "2022-11-02", want: "2023-01-01"},
+		{name: "weekly", recurrence: Recurrence{Frequency: RecurrenceWeekly}, start: "2022-08-26", from: "2022-08-27", want: "2022-09-02"},
+		{name: "fortnightly", recurrence: Recurrence{Frequency: RecurrenceWeekly, Interval: 2}, start: "2022-08-26", from: "2022-09-02", want: "2022-09-09"},
+		{name: "daily", recurrence: Recurrence{Frequency: RecurrenceDaily}, start: "2022-08-26", from: "2022-09-02", want: "2022-09-02"},
+		{name: "every other day", recurrence: Recurrence{Frequency: RecurrenceDaily, Interval: 2}, start: "2022-08-26", from: "2022-08-29", want: "2022-08-30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, date(tt.want), tt.recurrence.Next(date(tt.start), date(tt.from)))
+		})
+	}
+
+	t.Run("Test the time of the day is ignored", func(t *testing.T) {
+		recurrence := Recurrence{Frequency: RecurrenceMonthly, DayOfMonth: 25}
+		from := time.Date(2022, 10, 25, 18, 30, 0, 0, time.UTC)
+		assert.Equal(t, date("2022-10-25"), recurrence.Next(date("2022-09-01"), from))
+	})
+}
+
+func TestRecurrenceUpcoming(t *testing.T) {
+	recurrence := Recurrence{Frequency: RecurrenceMonthly, DayOfMonth: 31}
+
+	occurrences := recurrence.Upcoming(date("2022-01-01"), date("2022-01-15"), 4)
+
+	assert.Equal(t, []time.Time{date("2022-01-31"), date("2022-02-28"), date("2022-03-31"), date("2022-04-30")}, occurrences)
+}
+
+func TestTransferTemplateValidate(t *testing.T) {
+	template := TransferTemplate{
+		Name:             "Monthly payroll",
+		OrganizationName: "ACME Corp",
+		OrganizationBic:  "OIVUSCLQXXX",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		CreditTransfers: []CreditTransfer{{
+			Amount:           NewMoney(1450, "EUR"),
+			Currency:         "EUR",
+			CounterPartyName: "Bip Bip",
+			CounterPartyBic:  "CRLYFRPPTOU",
+			CounterPartyIban: "EE303680981021245685",
+		}},
+		Recurrence: Recurrence{Frequency: RecurrenceMonthly, DayOfMonth: 25},
+		StartDate:  "2022-09-01",
+	}
+
+	t.Run("Test valid template", func(t *testing.T) {
+		assert.NoError(t, template.Validate())
+	})
+
+	t.Run("Test monthly recurrence requires the day of the month", func(t *testing.T) {
+		invalid := template
+		invalid.Recurrence = Recurrence{Frequency: RecurrenceMonthly}
+
+		var validationError ValidationError
+		assert.True(t, errors.As(invalid.Validate(), &validationError))
+		assert.Equal(t, []FieldError{{Field: "recurrence.day_of_month", Rule: "required_if", Message: "is required"}}, validationError.Fields)
+	})
+
+	t.Run("Test weekly recurrence doesn't require the day of the month", func(t *testing.T) {
+		weekly := template
+		weekly.Recurrence = Recurrence{Frequency: RecurrenceWeekly}
+		assert.NoError(t, weekly.Validate())
+	})
+
+	t.Run("Test unknown frequency and invalid start date", func(t *testing.T) {
+		invalid := template
+		invalid.Recurrence = Recurrence{Frequency: "yearly"}
+		invalid.StartDate = "01/09/2022"
+
+		var validationError ValidationError
+		assert.True(t, errors.As(invalid.Validate(), &validationError))
+		assert.Equal(t, []FieldError{
+			{Field: "recurrence.frequency", Rule: "oneof", Message: "must be one of daily, weekly, monthly"},
+			{Field: "start_date", Rule: "datetime", Message: "must be formatted as 2006-01-02"},
+		}, validationError.Fields)
+	})
+}
@@ -1,16 +1,121 @@
 package domain
 
+import (
+	"encoding/json"
+	"time"
+)
+
+// TransactionDirection tells whether a transaction moved funds out of or into the bank account
+type TransactionDirection string
+
+const (
+	// TransactionOutgoing the transaction debited the bank account
+	TransactionOutgoing TransactionDirection = "outgoing"
+	// TransactionIncoming the transaction credited the bank account, e.g. a transfer from another local account
+	TransactionIncoming TransactionDirection = "incoming"
+)
+
 // Transaction Struct that represents a payment transaction
 type Transaction struct {
-	Name             string  `json:"name"`
-	Iban             string  `json:"iban"`
-	Bic              string  `json:"bic"`
-	CounterPartyName string  `json:"counterparty_name"`
-	CounterPartyIban string  `json:"counterparty_iban"`
-	CounterPartyBic  string  `json:"counterparty_bic"`
-	Amount           float64 `json:"amount,string"`
-	Currency         string  `json:"currency"`
-	Description      string  `json:"description"`
+	ID               uint                 `json:"id"`
+	Name             string               `json:"name"`
+	Iban             string               `json:"iban"`
+	Bic              string               `json:"bic"`
+	CounterPartyName string               `json:"counterparty_name"`
+	CounterPartyIban string               `json:"counterparty_iban"`
+	CounterPartyBic  string               `json:"counterparty_bic"`
+	Amount           Money                `json:"amount" swaggertype:"string" example:"14.53"`
+	Currency         string               `json:"currency"`
+	Description      string               `json:"description"`
+	Direction        TransactionDirection `json:"direction"`
+	BulkTransferID   uint                 `json:"bulk_transfer_id,omitempty"`
+	// ReversedTransactionID the transaction this one reverses, when it is a reversal
+	ReversedTransactionID uint `json:"reversed_transaction_id,omitempty"`
+	// ReversedAmount the part of the transaction reversed so far, when it was reversed
+	ReversedAmount *Money `json:"reversed_amount,omitempty" swaggertype:"string" example:"14.53"`
+	// BeneficiaryID the beneficiary the credit transfer referenced, when it referenced one
+	BeneficiaryID uint `json:"beneficiary_id,omitempty"`
+	// OverdrawnAmount the part of the transaction paid from the overdraft, when it took the balance below zero
+	OverdrawnAmount *Money `json:"overdrawn_amount,omitempty" swaggertype:"string" example:"4.53"`
+	// FeeOfTransactionID the transfer the fee was charged for, when the transaction is a fee
+	FeeOfTransactionID uint `json:"fee_of_transaction_id,omitempty"`
+	// FirstPayment the account never paid the counterparty before this credit transfer
+	FirstPayment bool       `json:"first_payment,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+}
+
+// UnmarshalJSON parses the amounts exactly in the currency of the transaction
+func (l *Transaction) UnmarshalJSON(data []byte) error {
+	type transaction Transaction
+	aux := struct {
+		*transaction
+		Amount          string `json:"amount"`
+		ReversedAmount  string `json:"reversed_amount"`
+		OverdrawnAmount string `json:"overdrawn_amount"`
+	}{transaction: (*transaction)(l)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	amount, err := parseAmount("amount", aux.Amount, l.Currency)
+	if err != nil {
+		return err
+	}
+	l.Amount = amount
+
+	l.ReversedAmount = nil
+	if aux.ReversedAmount != "" {
+		reversedAmount, err := parseAmount("reversed_amount", aux.ReversedAmount, l.Currency)
+		if err != nil {
+			return err
+		}
+		l.ReversedAmount = &reversedAmount
+	}
+
+	l.OverdrawnAmount = nil
+	if aux.OverdrawnAmount != "" {
+		overdrawnAmount, err := parseAmount("overdrawn_amount", aux.OverdrawnAmount, l.Currency)
+		if err != nil {
+			return err
+		}
+		l.OverdrawnAmount = &overdrawnAmount
+	}
+
+	return nil
+}
+
+// TransactionReversal Struct that represents the reversal of an outgoing transaction, in full or in part
+type TransactionReversal struct {
+	// Amount to give back, in the account currency. What wasn't reversed yet is given back when missing.
+	Amount Money  `json:"amount,omitempty" validate:"gte=0" swaggertype:"string" example:"14.53"`
+	Reason string `json:"reason" validate:"required" example:"paid twice"`
+}
+
+// UnmarshalJSON parses the amount exactly in the account currency
+func (l *TransactionReversal) UnmarshalJSON(data []byte) error {
+	type transactionReversal TransactionReversal
+	aux := struct {
+		*transactionReversal
+		Amount string `json:"amount"`
+	}{transactionReversal: (*transactionReversal)(l)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	amount, err := parseAmount("amount", aux.Amount, AccountCurrency)
+	if err != nil {
+		return err
+	}
+	l.Amount = amount
+
+	return nil
+}
+
+// Validate validates the TransactionReversal struct based on 'validate' tags of its fields
+func (l *TransactionReversal) Validate() error {
+	return validate(l)
 }
 
 // TransactionList Struct that represents a list of payment transactions
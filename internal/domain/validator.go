@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// newValidator returns a validator aware of the domain types and of the `iban`, `account_iban`, `bic` and `http_url` tags.
+// Fields are reported by their json name.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	// amounts are validated through their minor units, so `required,gt=0` works on Money fields
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		return field.Interface().(Money).MinorUnits
+	}, Money{})
+	_ = v.RegisterValidation("iban", func(fl validator.FieldLevel) bool {
+		return ValidIban(fl.Field().String())
+	})
+	_ = v.RegisterValidation("account_iban", func(fl validator.FieldLevel) bool {
+		return ValidAccountIban(fl.Field().String())
+	})
+	_ = v.RegisterValidation("bic", func(fl validator.FieldLevel) bool {
+		return ValidBic(fl.Field().String())
+	})
+	_ = v.RegisterValidation("http_url", func(fl validator.FieldLevel) bool {
+		u, err := url.Parse(fl.Field().String())
+		return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+	})
+	return v
+}
+
+// FieldError describes a field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every field that failed validation
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+" "+field.Message)
+	}
+	return "invalid fields: " + strings.Join(messages, "; ")
+}
+
+// set reports the field error, replacing any other error of the same field
+func (e *ValidationError) set(fieldError FieldError) {
+	for i, field := range e.Fields {
+		if field.Field == fieldError.Field {
+			e.Fields[i] = fieldError
+			return
+		}
+	}
+	e.Fields = append(e.Fields, fieldError)
+}
+
+// drop removes the error of the field
+func (e *ValidationError) drop(field string) {
+	fields := e.Fields[:0]
+	for _, fieldError := range e.Fields {
+		if fieldError.Field != field {
+			fields = append(fields, fieldError)
+		}
+	}
+	e.Fields = fields
+}
+
+// validate validates the struct, translating validator errors into a ValidationError
+func validate(s interface{}) error {
+	err := newValidator().Struct(s)
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	res := ValidationError{}
+	for _, fe := range validationErrors {
+		// drop the name of the validated struct from the namespace, e.g. BulkTransfer.credit_transfers[0].amount
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		res.Fields = append(res.Fields, FieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Message: fieldMessage(fe),
+		})
+	}
+
+	return res
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_if", "required_without":
+		return "is required"
+	case "excluded_with":
+		return "must be left out when referencing a beneficiary"
+	case "iban":
+		return fmt.Sprintf("%q is not a valid IBAN", fe.Value())
+	case "account_iban":
+		return fmt.Sprintf("%q is not an IBAN", fe.Value())
+	case "bic":
+		return fmt.Sprintf("%q is not a valid BIC", fe.Value())
+	case "http_url":
+		return fmt.Sprintf("%q is not a valid http or https URL", fe.Value())
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte", "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "datetime":
+		return "must be formatted as " + fe.Param()
+	default:
+		return fmt.Sprintf("failed on the %q rule", fe.Tag())
+	}
+}
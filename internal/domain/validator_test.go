@@ -0,0 +1,221 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidIban(t *testing.T) {
+	valid := []string{
+		"FR81474608000002006107XXXXX",
+		"FR1420041010050500013M02606",
+		"EE303680981021245685",
+		"DE44354208100362090817",
+		"NL24ABNA5055036109",
+		"GB82WEST12345698765432",
+		"NO9386011117947",
+	}
+	for _, iban := range valid {
+		assert.True(t, ValidIban(iban), iban)
+	}
+
+	invalid := []string{
+		"",
+		"FR10474608000002006107XXXXX",       // wrong checksum
+		"DE9935420810036209081725212",       // wrong length for DE
+		"FR81 4746 0800 0002 0061 07XX XXX", // not in electronic format
+		"fr81474608000002006107xxxxx",       // lower case
+		"ZZ81474608000002006107XXXXX",       // unknown country
+		"FR8147460800000200610-XXXXX",       // invalid character
+	}
+	for _, iban := range invalid {
+		assert.False(t, ValidIban(iban), iban)
+	}
+}
+
+func TestValidAccountIban(t *testing.T) {
+	// the IBAN of the account of the fixture, registered before the IBANs were validated
+	for _, iban := range []string{"FR81474608000002006107XXXXX", "FR10474608000002006107XXXXX"} {
+		assert.True(t, ValidAccountIban(iban), iban)
+	}
+	for _, iban := range []string{"", "FR81 4746 0800 0002 0061 07XX XXX", "fr10474608000002006107xxxxx", "FR8147460800000200610-XXXXX"} {
+		assert.False(t, ValidAccountIban(iban), iban)
+	}
+}
+
+func TestValidBic(t *testing.T) {
+	for _, bic := range []string{"OIVUSCLQXXX", "CRLYFRPPTOU", "DDFCNLAM", "DEUTDEFF500"} {
+		assert.True(t, ValidBic(bic), bic)
+	}
+	for _, bic := range []string{"", "OIVUSCLQXX", "OIVUSCLQXXXX", "OIVU1CLQ", "oivusclqxxx", "OIVU SCLQ"} {
+		assert.False(t, ValidBic(bic), bic)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("Test BankAccount with valid fields", func(t *testing.T) {
+		bankAccount := BankAccount{Name: "ACME Corp", Balance: NewMoney(1240, "EUR"), Iban: "FR81474608000002006107XXXXX", Bic: "OIVUSCLQXXX"}
+		assert.NoError(t, bankAccount.Validate())
+	})
+
+	t.Run("Test BankAccount reports invalid iban and bic", func(t *testing.T) {
+		bankAccount := BankAccount{Name: "ACME Corp", Balance: NewMoney(1240, "EUR"), Iban: "FR10474608000002006107XXXXX", Bic: "OIVU"}
+
+		var validationError ValidationError
+		err := bankAccount.Validate()
+		assert.True(t, errors.As(err, &validationError))
+		assert.Equal(t, []FieldError{
+			{Field: "iban", Rule: "iban", Message: `"FR10474608000002006107XXXXX" is not a valid IBAN`},
+			{Field: "bic", Rule: "bic", Message: `"OIVU" is not a valid BIC`},
+		}, validationError.Fields)
+	})
+
+	t.Run("Test BankAccount edition accepts the IBAN of an account registered before the IBANs were validated", func(t *testing.T) {
+		bankAccount := BankAccount{Name: "ACME Corp", Balance: NewMoney(1240, "EUR"), Iban: "FR10474608000002006107XXXXX", Bic: "OIVUSCLQXXX"}
+		assert.NoError(t, bankAccount.ValidateUpdate())
+
+		bankAccount.Bic = "OIVU"
+		var validationError ValidationError
+		assert.True(t, errors.As(bankAccount.ValidateUpdate(), &validationError))
+		assert.Equal(t, []FieldError{{Field: "bic", Rule: "bic", Message: `"OIVU" is not a valid BIC`}}, validationError.Fields)
+
+		bankAccount = BankAccount{Name: "ACME Corp", Balance: NewMoney(1240, "EUR"), Iban: "fr10 4746", Bic: "OIVUSCLQXXX"}
+		assert.True(t, errors.As(bankAccount.ValidateUpdate(), &validationError))
+		assert.Equal(t, []FieldError{{Field: "iban", Rule: "iban", Message: `"fr10 4746" is not a valid IBAN`}}, validationError.Fields)
+	})
+
+	t.Run("Test BulkTransfer of the samples accepts their account and reports their counterparties", func(t *testing.T) {
+		sample, err := os.ReadFile(filepath.Join("..", "..", "test", "sample1.json"))
+		require.NoError(t, err)
+
+		var bulkTransfer BulkTransfer
+		require.NoError(t, json.Unmarshal(sample, &bulkTransfer))
+		assert.Equal(t, "FR10474608000002006107XXXXX", bulkTransfer.OrganizationIban)
+
+		var validationError ValidationError
+		assert.True(t, errors.As(bulkTransfer.Validate(), &validationError))
+		assert.Equal(t, []FieldError{
+			{Field: "credit_transfers[0].counterparty_iban", Rule: "iban", Message: `"EE383680981021245685" is not a valid IBAN`},
+			{Field: "credit_transfers[1].counterparty_iban", Rule: "iban", Message: `"DE9935420810036209081725212" is not a valid IBAN`},
+			{Field: "credit_transfers[2].counterparty_iban", Rule: "iban", Message: `"FR0010009380540930414023042" is not a valid IBAN`},
+		}, validationError.Fields)
+
+		bulkTransfer.OrganizationIban = "FR10 4746"
+		assert.True(t, errors.As(bulkTransfer.Validate(), &validationError))
+		assert.Contains(t, validationError.Fields, FieldError{Field: "organization_iban", Rule: "account_iban", Message: `"FR10 4746" is not an IBAN`})
+	})
+
+	t.Run("Test BankAccount parses and validates its limits", func(t *testing.T) {
+		var bankAccount BankAccount
+		err := json.Unmarshal([]byte(`{"name": "ACME Corp", "balance": "12.40", "iban": "FR81474608000002006107XXXXX", "bic": "OIVUSCLQXXX",
+			"limits": {"per_transaction": "500.00", "daily": "0"}}`), &bankAccount)
+		assert.NoError(t, err)
+
+		perTransaction := NewMoney(50000, "EUR")
+		assert.Equal(t, AccountLimits{PerTransaction: &perTransaction, Daily: &Money{Currency: "EUR"}}, bankAccount.Limits)
+
+		var validationError ValidationError
+		assert.True(t, errors.As(bankAccount.Validate(), &validationError))
+		assert.Equal(t, []FieldError{{Field: "limits.daily", Rule: "gt", Message: "must be greater than 0"}}, validationError.Fields)
+
+		err = json.Unmarshal([]byte(`{"balance": "12.40", "limits": {"monthly": "10.001"}}`), &bankAccount)
+		assert.ErrorIs(t, err, ErrExcessPrecision)
+	})
+
+	t.Run("Test BankAccount parses and validates its approval policy", func(t *testing.T) {
+		var bankAccount BankAccount
+		err := json.Unmarshal([]byte(`{"name": "ACME Corp", "balance": "12.40", "iban": "FR81474608000002006107XXXXX", "bic": "OIVUSCLQXXX",
+			"approval": {"threshold": "10000.00", "required_approvals": 3, "approvers": ["jane.doe", "john.smith"]}}`), &bankAccount)
+		assert.NoError(t, err)
+
+		threshold := NewMoney(1000000, "EUR")
+		assert.Equal(t, ApprovalPolicy{Threshold: &threshold, RequiredApprovals: 3, Approvers: []string{"jane.doe", "john.smith"}}, bankAccount.Approval)
+
+		var validationError ValidationError
+		assert.True(t, errors.As(bankAccount.Validate(), &validationError))
+		assert.Equal(t, []FieldError{{Field: "approval.required_approvals", Rule: "max", Message: "must be at most 2, the number of approvers"}}, validationError.Fields)
+
+		bankAccount.Approval.RequiredApprovals = 2
+		assert.NoError(t, bankAccount.Validate())
+
+		err = json.Unmarshal([]byte(`{"balance": "12.40", "approval": {"threshold": "10.001"}}`), &bankAccount)
+		assert.ErrorIs(t, err, ErrExcessPrecision)
+	})
+
+	t.Run("Test BulkTransfer reports the credit transfer of each invalid field", func(t *testing.T) {
+		bulkTransfer := BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+			CreditTransfers: []CreditTransfer{
+				{Amount: NewMoney(1453, "EUR"), Currency: "EUR", CounterPartyName: "Bip Bip", CounterPartyBic: "CRLYFRPPTOU", CounterPartyIban: "EE303680981021245685"},
+				{Currency: "EUR", CounterPartyName: "Bip Bip", CounterPartyBic: "CRLYFRPPTOU", CounterPartyIban: "EE383680981021245685"},
+			},
+		}
+
+		var validationError ValidationError
+		err := bulkTransfer.Validate()
+		assert.True(t, errors.As(err, &validationError))
+		assert.Equal(t, []FieldError{
+			{Field: "credit_transfers[1].amount", Rule: "required", Message: "is required"},
+			{Field: "credit_transfers[1].counterparty_iban", Rule: "iban", Message: `"EE383680981021245685" is not a valid IBAN`},
+		}, validationError.Fields)
+		assert.Contains(t, err.Error(), "credit_transfers[1].counterparty_iban")
+	})
+
+	t.Run("Test BulkTransfer reports an empty list of credit transfers", func(t *testing.T) {
+		bulkTransfer := BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+			CreditTransfers:  []CreditTransfer{},
+		}
+
+		var validationError ValidationError
+		assert.True(t, errors.As(bulkTransfer.Validate(), &validationError))
+		assert.Equal(t, []FieldError{{Field: "credit_transfers", Rule: "min", Message: "must be at least 1"}}, validationError.Fields)
+	})
+
+	t.Run("Test BulkTransfer credit transfers reference a beneficiary or hold the counterparty", func(t *testing.T) {
+		bulkTransfer := BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+			CreditTransfers: []CreditTransfer{
+				{Amount: NewMoney(1453, "EUR"), Currency: "EUR", BeneficiaryID: 3},
+				{Amount: NewMoney(1453, "EUR"), Currency: "EUR", BeneficiaryID: 3, CounterPartyIban: "EE303680981021245685"},
+				{Amount: NewMoney(1453, "EUR"), Currency: "EUR", CounterPartyName: "Bip Bip", CounterPartyBic: "CRLYFRPPTOU"},
+			},
+		}
+
+		var validationError ValidationError
+		assert.True(t, errors.As(bulkTransfer.Validate(), &validationError))
+		assert.Equal(t, []FieldError{
+			{Field: "credit_transfers[1].counterparty_iban", Rule: "excluded_with", Message: "must be left out when referencing a beneficiary"},
+			{Field: "credit_transfers[2].counterparty_iban", Rule: "required_without", Message: "is required"},
+		}, validationError.Fields)
+	})
+
+	t.Run("Test Webhook with valid fields", func(t *testing.T) {
+		webhook := Webhook{OrganizationIban: "FR81474608000002006107XXXXX", URL: "https://erp.acme.corp/hooks",
+			Events: []WebhookEvent{WebhookBulkTransferCompleted, WebhookTransactionCreated}}
+		assert.NoError(t, webhook.Validate())
+	})
+
+	t.Run("Test Webhook reports invalid url and events", func(t *testing.T) {
+		webhook := Webhook{OrganizationIban: "FR81474608000002006107XXXXX", URL: "ftp://erp.acme.corp/hooks",
+			Events: []WebhookEvent{WebhookTransactionCreated, "transaction.deleted"}}
+
+		var validationError ValidationError
+		assert.True(t, errors.As(webhook.Validate(), &validationError))
+		assert.Equal(t, []FieldError{
+			{Field: "url", Rule: "http_url", Message: `"ftp://erp.acme.corp/hooks" is not a valid http or https URL`},
+			{Field: "events[1]", Rule: "oneof", Message: "must be one of bulk_transfer.completed, bulk_transfer.rejected, transaction.created"},
+		}, validationError.Fields)
+	})
+}
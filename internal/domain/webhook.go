@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEvent represents the kind of event the webhooks of an organization are notified of
+type WebhookEvent string
+
+const (
+	// WebhookBulkTransferCompleted a bulk transfer was executed, in full or, when best effort, in part
+	WebhookBulkTransferCompleted WebhookEvent = "bulk_transfer.completed"
+	// WebhookBulkTransferRejected a bulk transfer failed at its execution, was rejected by an approver or blocked by a reviewer
+	WebhookBulkTransferRejected WebhookEvent = "bulk_transfer.rejected"
+	// WebhookTransactionCreated a transaction was registered on the bank account
+	WebhookTransactionCreated WebhookEvent = "transaction.created"
+)
+
+// DeliveryStatus represents the lifecycle state of the delivery of an event to a webhook
+type DeliveryStatus string
+
+const (
+	// DeliveryPending the event waits for its next attempt
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliveryDelivered the receiver acknowledged the event with a 2xx status
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryFailed every attempt failed, the event can still be replayed
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// Webhook Struct that represents the subscription of an organization to some events, posted to its url
+type Webhook struct {
+	OrganizationIban string         `json:"organization_iban" validate:"required,account_iban" example:"FR10474608000002006107XXXXX"`
+	URL              string         `json:"url" validate:"required,http_url" example:"https://erp.acme.corp/hooks/qonto"`
+	Events           []WebhookEvent `json:"events" validate:"required,min=1,dive,oneof=bulk_transfer.completed bulk_transfer.rejected transaction.created"`
+}
+
+// Validate validates the Webhook struct based on 'validate' tags of its fields
+func (l *Webhook) Validate() error {
+	return validate(l)
+}
+
+// WebhookDetail Struct that represents a stored webhook. Its secret, signing the deliveries, is only returned
+// at its creation.
+type WebhookDetail struct {
+	ID               uint           `json:"id"`
+	OrganizationIban string         `json:"organization_iban"`
+	URL              string         `json:"url"`
+	Events           []WebhookEvent `json:"events"`
+	Secret           string         `json:"secret,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+// WebhookDetailList Struct that represents a list of stored webhooks
+type WebhookDetailList []WebhookDetail
+
+// WebhookDelivery Struct that represents the delivery of an event to a webhook and the outcome of its attempts
+type WebhookDelivery struct {
+	ID        uint            `json:"id"`
+	WebhookID uint            `json:"webhook_id"`
+	Event     WebhookEvent    `json:"event"`
+	Payload   json.RawMessage `json:"payload" swaggertype:"object"`
+	Status    DeliveryStatus  `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// NextAttemptAt when the event is posted again, while pending
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	// ResponseStatus the status the receiver answered the last attempt with, when it answered
+	ResponseStatus int    `json:"response_status,omitempty"`
+	Error          string `json:"error,omitempty"`
+	// ReplayOf the delivery this one replays, when it is a replay
+	ReplayOf    uint       `json:"replay_of,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookDeliveryList Struct that represents a list of deliveries
+type WebhookDeliveryList []WebhookDelivery
+
+// WebhookPayload Struct that represents the body posted to the webhooks
+type WebhookPayload struct {
+	Event      WebhookEvent `json:"event"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Data       interface{}  `json:"data"`
+}
+
+// BulkTransferEvent Struct that represents the bulk transfer the bulk transfer events are about
+type BulkTransferEvent struct {
+	ID               uint               `json:"id"`
+	OrganizationIban string             `json:"organization_iban"`
+	TransfersCount   int                `json:"transfers_count"`
+	TotalAmount      Money              `json:"total_amount" swaggertype:"string" example:"29.06"`
+	Status           BulkTransferStatus `json:"status"`
+	FailureReason    string             `json:"failure_reason,omitempty"`
+}
@@ -0,0 +1,152 @@
+package fees
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"os"
+	"strings"
+)
+
+// Rule Struct that represents a rule of the fee schedule. The rules without a plan, a currency or countries
+// match any plan, currency or destination country.
+type Rule struct {
+	Name string `json:"name"`
+	// Plan the plan of the organization bank account
+	Plan     string `json:"plan,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	// Countries the countries of the counterparty ibans, from their prefix
+	Countries []string `json:"countries,omitempty"`
+	// Fixed the flat part of the fee, in the account currency
+	Fixed string `json:"fixed,omitempty"`
+	// RateBps the part of the fee proportional to the amount, in basis points
+	RateBps int64 `json:"rate_bps,omitempty"`
+
+	fixedCents int64
+}
+
+// Charge Struct that represents the fee of a credit transfer and the rule it was computed with
+type Charge struct {
+	Rule        string
+	AmountCents int64
+}
+
+// Schedule the fee rules, a credit transfer is charged by the most specific rule matching it and by the
+// first one listed among the rules as specific. The zero Schedule charges nothing.
+type Schedule struct {
+	rules []Rule
+}
+
+// NewSchedule validates the rules of the schedule
+func NewSchedule(rules []Rule) (Schedule, error) {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Name == "" {
+			return Schedule{}, fmt.Errorf("rules[%d]: the name is required", i)
+		}
+		if rule.RateBps < 0 {
+			return Schedule{}, fmt.Errorf("rule %s: the rate can't be negative", rule.Name)
+		}
+		if rule.Fixed != "" {
+			fixed, err := domain.ParseMoney(rule.Fixed, domain.AccountCurrency)
+			if err != nil {
+				return Schedule{}, fmt.Errorf("rule %s: %w", rule.Name, err)
+			}
+			if fixed.MinorUnits < 0 {
+				return Schedule{}, fmt.Errorf("rule %s: the fixed fee can't be negative", rule.Name)
+			}
+			rule.fixedCents = fixed.MinorUnits
+		}
+		for j, country := range rule.Countries {
+			rule.Countries[j] = strings.ToUpper(country)
+		}
+	}
+	return Schedule{rules: rules}, nil
+}
+
+// Len how many rules the schedule holds
+func (s Schedule) Len() int {
+	return len(s.rules)
+}
+
+// Fee returns the fee of a credit transfer of amountCents in the currency to the iban, from an account on the
+// plan. ok is false when no rule matches it.
+func (s Schedule) Fee(plan, currency, iban string, amountCents int64) (Charge, bool) {
+	country := ""
+	if len(iban) >= 2 {
+		country = strings.ToUpper(iban[:2])
+	}
+
+	best, bestScore := -1, -1
+	for i, rule := range s.rules {
+		score, ok := rule.match(plan, currency, country)
+		if ok && score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 {
+		return Charge{}, false
+	}
+
+	rule := s.rules[best]
+	return Charge{Rule: rule.Name, AmountCents: rule.fixedCents + proportional(amountCents, rule.RateBps)}, true
+}
+
+// match tells whether the rule applies, scoring how specific it is
+func (r Rule) match(plan, currency, country string) (int, bool) {
+	score := 0
+	if r.Plan != "" {
+		if r.Plan != plan {
+			return 0, false
+		}
+		score++
+	}
+	if r.Currency != "" {
+		if r.Currency != currency {
+			return 0, false
+		}
+		score++
+	}
+	if len(r.Countries) > 0 {
+		found := false
+		for _, c := range r.Countries {
+			found = found || c == country
+		}
+		if !found {
+			return 0, false
+		}
+		score++
+	}
+	return score, true
+}
+
+// proportional the rate of the amount, rounded half up to the minor unit
+func proportional(amountCents, rateBps int64) int64 {
+	// split so the product can't overflow
+	return amountCents/10000*rateBps + (amountCents%10000*rateBps+5000)/10000
+}
+
+// Load reads the schedule of the JSON file at path, e.g. {"rules": [{"name": "sepa", "countries": ["FR"], "fixed": "0.50"}]}
+func Load(path string) (Schedule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	var file struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err = json.Unmarshal(content, &file); err != nil {
+		return Schedule{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(file.Rules) == 0 {
+		return Schedule{}, fmt.Errorf("%s: %w", path, errors.New("no fee rules"))
+	}
+
+	schedule, err := NewSchedule(file.Rules)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return schedule, nil
+}
@@ -0,0 +1,84 @@
+package fees
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSchedule(t *testing.T) {
+	schedule, err := NewSchedule([]Rule{
+		{Name: "default", Fixed: "5.00", RateBps: 20},
+		{Name: "sepa", Currency: "EUR", Countries: []string{"fr", "DE", "EE"}, Fixed: "0.50"},
+		{Name: "sepa-premium", Plan: "premium", Currency: "EUR", Countries: []string{"FR", "DE", "EE"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, schedule.Len())
+
+	t.Run("Test Fee applies the most specific rule", func(t *testing.T) {
+		charge, ok := schedule.Fee("standard", "EUR", "FR7630006000011234567890189", 100000)
+		assert.True(t, ok)
+		assert.Equal(t, Charge{Rule: "sepa", AmountCents: 50}, charge)
+
+		charge, ok = schedule.Fee("premium", "EUR", "EE303680981021245685", 100000)
+		assert.True(t, ok)
+		assert.Equal(t, Charge{Rule: "sepa-premium", AmountCents: 0}, charge)
+	})
+
+	t.Run("Test Fee adds the rate of the amount, rounded half up", func(t *testing.T) {
+		// 0.20% of 1234.75 is 2.4695
+		charge, ok := schedule.Fee("standard", "EUR", "GB33BUKB20201555555555", 123475)
+		assert.True(t, ok)
+		assert.Equal(t, Charge{Rule: "default", AmountCents: 500 + 247}, charge)
+	})
+
+	t.Run("Test Fee doesn't charge without a matching rule", func(t *testing.T) {
+		_, ok := Schedule{}.Fee("standard", "EUR", "FR7630006000011234567890189", 100000)
+		assert.False(t, ok)
+	})
+
+	t.Run("Test NewSchedule return error on invalid rules", func(t *testing.T) {
+		_, err := NewSchedule([]Rule{{Fixed: "1.00"}})
+		assert.Error(t, err)
+
+		_, err = NewSchedule([]Rule{{Name: "sepa", Fixed: "0.505"}})
+		assert.Error(t, err)
+
+		_, err = NewSchedule([]Rule{{Name: "sepa", RateBps: -1}})
+		assert.Error(t, err)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("Test Load reads the rules", func(t *testing.T) {
+		schedule, err := Load(write("fees.json", `{"rules": [{"name": "sepa", "currency": "EUR", "countries": ["FR"], "fixed": "0.50", "rate_bps": 10}]}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, schedule.Len())
+		charge, ok := schedule.Fee("", "EUR", "FR7630006000011234567890189", 10000)
+		assert.True(t, ok)
+		assert.Equal(t, int64(60), charge.AmountCents)
+	})
+
+	t.Run("Test Load return error without rules", func(t *testing.T) {
+		_, err := Load(write("empty.json", `{"rules": []}`))
+
+		assert.ErrorContains(t, err, "no fee rules")
+	})
+
+	t.Run("Test Load return error when the file is missing", func(t *testing.T) {
+		_, err := Load(filepath.Join(dir, "missing.json"))
+
+		assert.Error(t, err)
+	})
+}
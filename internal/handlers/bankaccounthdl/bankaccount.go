@@ -2,6 +2,7 @@ package bankaccounthdl
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/adrianoccosta/exercise-qonto/internal/domain"
 	"github.com/adrianoccosta/exercise-qonto/internal/services/bankaccountsvc"
@@ -104,6 +105,7 @@ func (h handler) read(w http.ResponseWriter, r *http.Request) {
 // @Param data body domain.BankAccount true "bank account data"
 // @Success 201 {string}  string
 // @Failure 400 {string}  string
+// @Failure 409 {string}  string
 // @Failure 500 {string}  string
 // @Router /v1/bank-account [put]
 func (h handler) update(w http.ResponseWriter, r *http.Request) {
@@ -116,13 +118,18 @@ func (h handler) update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = bankAccount.Validate(); err != nil {
+	if err = bankAccount.ValidateUpdate(); err != nil {
 		h.logger.WithError(err).Error("Missing mandatory fields")
 		tools.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
 	err = h.bankAccountService.Update(bankAccount)
+	if errors.Is(err, bankaccountsvc.ErrBalanceNotEditable) {
+		h.logger.WithError(err).Error("error updating bank account")
+		tools.WriteError(w, http.StatusConflict, err)
+		return
+	}
 	if err != nil {
 		h.logger.WithError(err).Error("error updating bank account")
 		tools.WriteError(w, http.StatusInternalServerError, err)
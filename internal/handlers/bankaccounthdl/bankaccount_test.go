@@ -3,7 +3,9 @@ package bankaccounthdl
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/bankaccountsvc"
 	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
 	"github.com/adrianoccosta/exercise-qonto/test/mocks/services"
 	"github.com/golang/mock/gomock"
@@ -26,8 +28,8 @@ func TestBankAccountHandler(t *testing.T) {
 
 		bankAccount := domain.BankAccount{
 			Name:    "ACME Corp",
-			Balance: 12.40,
-			Iban:    "FR10474608000002006107XXXXX",
+			Balance: domain.NewMoney(1240, "EUR"),
+			Iban:    "FR81474608000002006107XXXXX",
 			Bic:     "OIVUSCLQXXX",
 		}
 
@@ -40,7 +42,7 @@ func TestBankAccountHandler(t *testing.T) {
 		r := mux.NewRouter()
 		h.Handlers(r)
 
-		req, err := http.NewRequest("POST", "/bank-account", strings.NewReader("{ \"name\": \"ACME Corp\", \"balance\": \"12.40\", \"iban\": \"FR10474608000002006107XXXXX\", \"bic\": \"OIVUSCLQXXX\"}"))
+		req, err := http.NewRequest("POST", "/bank-account", strings.NewReader("{ \"name\": \"ACME Corp\", \"balance\": \"12.40\", \"iban\": \"FR81474608000002006107XXXXX\", \"bic\": \"OIVUSCLQXXX\"}"))
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -103,7 +105,7 @@ func TestBankAccountHandler(t *testing.T) {
 		r := mux.NewRouter()
 		h.Handlers(r)
 
-		req, err := http.NewRequest("POST", "/bank-account", strings.NewReader("{ \"name\": \"ACME Corp\", \"balance\": \"12.40\", \"iban\": \"FR10474608000002006107XXXXX\", \"bic\": \"OIVUSCLQXXX\"}"))
+		req, err := http.NewRequest("POST", "/bank-account", strings.NewReader("{ \"name\": \"ACME Corp\", \"balance\": \"12.40\", \"iban\": \"FR81474608000002006107XXXXX\", \"bic\": \"OIVUSCLQXXX\"}"))
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -117,13 +119,13 @@ func TestBankAccountHandler(t *testing.T) {
 
 		bankAccount := domain.BankAccount{
 			Name:    "ACME Corp",
-			Balance: 12.40,
-			Iban:    "FR10474608000002006107XXXXX",
+			Balance: domain.NewMoney(1240, "EUR"),
+			Iban:    "FR81474608000002006107XXXXX",
 			Bic:     "OIVUSCLQXXX",
 		}
 
 		serviceMock.EXPECT().
-			Read("FR10474608000002006107XXXXX").
+			Read("FR81474608000002006107XXXXX").
 			Return(bankAccount, nil).Times(1)
 
 		h := New(serviceMock, logMock)
@@ -131,7 +133,7 @@ func TestBankAccountHandler(t *testing.T) {
 		r := mux.NewRouter()
 		h.Handlers(r)
 
-		req, err := http.NewRequest("GET", "/bank-account/iban/FR10474608000002006107XXXXX", nil)
+		req, err := http.NewRequest("GET", "/bank-account/iban/FR81474608000002006107XXXXX", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -152,14 +154,14 @@ func TestBankAccountHandler(t *testing.T) {
 
 		serviceMock.EXPECT().Read(gomock.Any()).Return(domain.BankAccount{}, errors.New("error"))
 		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
-		logMock.EXPECT().Error("error reading bank account with iban FR10474608000002006107XXXXX").Times(1)
+		logMock.EXPECT().Error("error reading bank account with iban FR81474608000002006107XXXXX").Times(1)
 
 		h := New(serviceMock, logMock)
 		rr := httptest.NewRecorder()
 		r := mux.NewRouter()
 		h.Handlers(r)
 
-		req, err := http.NewRequest("GET", "/bank-account/iban/FR10474608000002006107XXXXX", nil)
+		req, err := http.NewRequest("GET", "/bank-account/iban/FR81474608000002006107XXXXX", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -173,8 +175,8 @@ func TestBankAccountHandler(t *testing.T) {
 
 		bankAccount := domain.BankAccount{
 			Name:    "ACME Corp",
-			Balance: 12.40,
-			Iban:    "FR10474608000002006107XXXXX",
+			Balance: domain.NewMoney(1240, "EUR"),
+			Iban:    "FR81474608000002006107XXXXX",
 			Bic:     "OIVUSCLQXXX",
 		}
 
@@ -187,7 +189,7 @@ func TestBankAccountHandler(t *testing.T) {
 		r := mux.NewRouter()
 		h.Handlers(r)
 
-		req, err := http.NewRequest("PUT", "/bank-account", strings.NewReader("{ \"name\": \"ACME Corp\", \"balance\": \"12.40\", \"iban\": \"FR10474608000002006107XXXXX\", \"bic\": \"OIVUSCLQXXX\"}"))
+		req, err := http.NewRequest("PUT", "/bank-account", strings.NewReader("{ \"name\": \"ACME Corp\", \"balance\": \"12.40\", \"iban\": \"FR81474608000002006107XXXXX\", \"bic\": \"OIVUSCLQXXX\"}"))
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -197,6 +199,33 @@ func TestBankAccountHandler(t *testing.T) {
 		assert.Equal(t, "", rr.Body.String())
 	})
 
+	t.Run("Test update accepts the IBAN of an account registered before the IBANs were validated", func(t *testing.T) {
+
+		bankAccount := domain.BankAccount{
+			Name:    "ACME Corp",
+			Balance: domain.NewMoney(10000000, "EUR"),
+			Iban:    "FR10474608000002006107XXXXX",
+			Bic:     "OIVUSCLQXXX",
+		}
+
+		serviceMock.EXPECT().
+			Update(bankAccount).
+			Return(nil).Times(1)
+
+		h := New(serviceMock, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("PUT", "/bank-account", strings.NewReader("{ \"name\": \"ACME Corp\", \"balance\": \"100000\", \"iban\": \"FR10474608000002006107XXXXX\", \"bic\": \"OIVUSCLQXXX\"}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
 	t.Run("Test update return error when missing mandatory fields", func(t *testing.T) {
 
 		serviceMock.EXPECT().Update(gomock.Any()).Times(0)
@@ -218,6 +247,27 @@ func TestBankAccountHandler(t *testing.T) {
 		assert.NotEmpty(t, rr.Body.String())
 	})
 
+	t.Run("Test update return conflict when the balance changes", func(t *testing.T) {
+
+		serviceMock.EXPECT().Update(gomock.Any()).Return(fmt.Errorf("%w: it is 14.53", bankaccountsvc.ErrBalanceNotEditable)).Times(1)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error updating bank account").Times(1)
+
+		h := New(serviceMock, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("PUT", "/bank-account", strings.NewReader("{ \"name\": \"ACME Corp\", \"balance\": \"12.40\", \"iban\": \"FR81474608000002006107XXXXX\", \"bic\": \"OIVUSCLQXXX\"}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, "the balance of a bank account can't be edited: it is 14.53", rr.Body.String())
+	})
+
 	t.Run("Test update return error", func(t *testing.T) {
 
 		serviceMock.EXPECT().Update(gomock.Any()).Return(errors.New("error")).Times(1)
@@ -229,7 +279,7 @@ func TestBankAccountHandler(t *testing.T) {
 		r := mux.NewRouter()
 		h.Handlers(r)
 
-		req, err := http.NewRequest("PUT", "/bank-account", strings.NewReader("{ \"name\": \"ACME Corp\", \"balance\": \"12.40\", \"iban\": \"FR10474608000002006107XXXXX\", \"bic\": \"OIVUSCLQXXX\"}"))
+		req, err := http.NewRequest("PUT", "/bank-account", strings.NewReader("{ \"name\": \"ACME Corp\", \"balance\": \"12.40\", \"iban\": \"FR81474608000002006107XXXXX\", \"bic\": \"OIVUSCLQXXX\"}"))
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -242,7 +292,7 @@ func TestBankAccountHandler(t *testing.T) {
 	t.Run("Test delete return success", func(t *testing.T) {
 
 		serviceMock.EXPECT().
-			Delete("FR10474608000002006107XXXXX").
+			Delete("FR81474608000002006107XXXXX").
 			Return(nil).Times(1)
 
 		h := New(serviceMock, logMock)
@@ -250,7 +300,7 @@ func TestBankAccountHandler(t *testing.T) {
 		r := mux.NewRouter()
 		h.Handlers(r)
 
-		req, err := http.NewRequest("DELETE", "/bank-account/iban/FR10474608000002006107XXXXX", nil)
+		req, err := http.NewRequest("DELETE", "/bank-account/iban/FR81474608000002006107XXXXX", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -270,7 +320,7 @@ func TestBankAccountHandler(t *testing.T) {
 		r := mux.NewRouter()
 		h.Handlers(r)
 
-		req, err := http.NewRequest("DELETE", "/bank-account/iban/FR10474608000002006107XXXXX", nil)
+		req, err := http.NewRequest("DELETE", "/bank-account/iban/FR81474608000002006107XXXXX", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
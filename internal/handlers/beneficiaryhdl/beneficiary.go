@@ -0,0 +1,216 @@
+package beneficiaryhdl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/beneficiarysvc"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+const (
+	pathSelection   = "/beneficiaries"
+	pathSelectionID = "/beneficiaries/{id:[0-9]+}"
+)
+
+// Handler defines the handler interface
+type Handler interface {
+	Handlers(r *mux.Router)
+}
+
+// New returns an implementation of the beneficiary handler
+func New(beneficiaryService beneficiarysvc.BeneficiaryService, logger log.Logger) Handler {
+	return handler{
+		logger:             logger,
+		beneficiaryService: beneficiaryService,
+	}
+}
+
+type handler struct {
+	logger             log.Logger
+	beneficiaryService beneficiarysvc.BeneficiaryService
+}
+
+func (h handler) Handlers(r *mux.Router) {
+	// handlers
+	r.HandleFunc(pathSelection, h.create).Methods(http.MethodPost)
+	r.HandleFunc(pathSelection, h.list).Methods(http.MethodGet)
+	r.HandleFunc(pathSelectionID, h.read).Methods(http.MethodGet)
+	r.HandleFunc(pathSelectionID, h.update).Methods(http.MethodPut)
+	r.HandleFunc(pathSelectionID, h.delete).Methods(http.MethodDelete)
+}
+
+// @Summary register a beneficiary on a bank account
+// @ID create-beneficiary
+// @Tags beneficiary
+// @Produce json
+// @Param data body domain.Beneficiary true "beneficiary data"
+// @Success 201 {object} domain.BeneficiaryDetail
+// @Failure 409 {string}  string
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/beneficiaries [post]
+func (h handler) create(w http.ResponseWriter, r *http.Request) {
+
+	beneficiary, ok := h.decode(w, r)
+	if !ok {
+		return
+	}
+
+	detail, err := h.beneficiaryService.Create(beneficiary)
+	if err != nil {
+		h.logger.WithError(err).Error("error registering beneficiary")
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, detail.ID))
+	tools.WriteJSON(w, http.StatusCreated, detail)
+}
+
+// @Summary read a beneficiary
+// @ID read-beneficiary
+// @Tags beneficiary
+// @Produce json
+// @Param id path int true "beneficiary id"
+// @Success 200 {object} domain.BeneficiaryDetail
+// @Failure 404 {string}  string
+// @Router /v1/beneficiaries/{id} [get]
+func (h handler) read(w http.ResponseWriter, r *http.Request) {
+
+	id := beneficiaryID(r)
+	detail, err := h.beneficiaryService.Read(id)
+
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error reading beneficiary with id %d", id))
+		writeError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// @Summary list the beneficiaries of a bank account
+// @ID read-beneficiaries
+// @Tags beneficiary
+// @Produce json
+// @Param organization_iban query string true "iban of the bank account"
+// @Param iban query string false "beneficiary search by iban"
+// @Param name query string false "beneficiary search by name"
+// @Success 200 {array} domain.BeneficiaryDetail
+// @Failure 400 {string}  string
+// @Router /v1/beneficiaries [get]
+func (h handler) list(w http.ResponseWriter, r *http.Request) {
+	queries := r.URL.Query()
+
+	filters := make(map[string]string)
+	for k, v := range queries {
+		if len(v) > 0 {
+			filters[k] = v[0]
+		}
+	}
+
+	list, err := h.beneficiaryService.ReadByFilter(filters)
+
+	if err != nil {
+		h.logger.WithError(err).Error("error reading beneficiaries")
+		tools.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, list)
+}
+
+// @Summary edit the details of a beneficiary, the bulk transfers referencing it are paid with them from now on
+// @ID update-beneficiary
+// @Tags beneficiary
+// @Produce json
+// @Param id path int true "beneficiary id"
+// @Param data body domain.Beneficiary true "beneficiary data"
+// @Success 200 {object} domain.BeneficiaryDetail
+// @Failure 404 {string}  string
+// @Failure 409 {string}  string
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/beneficiaries/{id} [put]
+func (h handler) update(w http.ResponseWriter, r *http.Request) {
+
+	beneficiary, ok := h.decode(w, r)
+	if !ok {
+		return
+	}
+
+	id := beneficiaryID(r)
+	detail, err := h.beneficiaryService.Update(id, beneficiary)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error updating beneficiary with id %d", id))
+		writeError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// @Summary delete a beneficiary, the bulk transfers still referencing it fail at their execution
+// @ID delete-beneficiary
+// @Tags beneficiary
+// @Produce json
+// @Param id path int true "beneficiary id"
+// @Success 200 {string}  string
+// @Failure 404 {string}  string
+// @Failure 500 {string}  string
+// @Router /v1/beneficiaries/{id} [delete]
+func (h handler) delete(w http.ResponseWriter, r *http.Request) {
+
+	id := beneficiaryID(r)
+	if err := h.beneficiaryService.Delete(id); err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error deleting beneficiary with id %d", id))
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// decode reads and validates the beneficiary of the request, reporting the rejection when it is invalid
+func (h handler) decode(w http.ResponseWriter, r *http.Request) (domain.Beneficiary, bool) {
+	var beneficiary domain.Beneficiary
+
+	if err := json.NewDecoder(r.Body).Decode(&beneficiary); err != nil {
+		h.logger.WithError(err).Error("error parsing message body")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.NewRejection(domain.RejectionInvalidBody, err))
+		return beneficiary, false
+	}
+
+	if err := beneficiary.Validate(); err != nil {
+		h.logger.WithError(err).Error("Missing mandatory fields")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.AsRejection(err))
+		return beneficiary, false
+	}
+
+	return beneficiary, true
+}
+
+// writeError writes the status matching the service error
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, beneficiarysvc.ErrBeneficiaryNotFound):
+		tools.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, beneficiarysvc.ErrDuplicateBeneficiary):
+		tools.WriteError(w, http.StatusConflict, err)
+	case errors.Is(err, beneficiarysvc.ErrAccountNotFound):
+		tools.WriteError(w, http.StatusUnprocessableEntity, err)
+	default:
+		tools.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func beneficiaryID(r *http.Request) uint {
+	id, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	return uint(id)
+}
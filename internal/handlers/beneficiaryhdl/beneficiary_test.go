@@ -0,0 +1,193 @@
+package beneficiaryhdl
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/beneficiarysvc"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/services"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const beneficiaryBody = `{"organization_iban": "FR81474608000002006107XXXXX", "name": "Bip Bip", "iban": "EE303680981021245685", "bic": "CRLYFRPPTOU"}`
+
+func TestBeneficiaryHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	serviceMock := mockservice.NewMockBeneficiaryService(ctrl)
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		h := New(serviceMock, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	beneficiary := domain.Beneficiary{
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		Name:             "Bip Bip",
+		Iban:             "EE303680981021245685",
+		Bic:              "CRLYFRPPTOU",
+	}
+
+	detail := domain.BeneficiaryDetail{
+		ID:               3,
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		Name:             "Bip Bip",
+		Iban:             "EE303680981021245685",
+		Bic:              "CRLYFRPPTOU",
+		CreatedAt:        time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC),
+		UpdatedAt:        time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Test create return success", func(t *testing.T) {
+		serviceMock.EXPECT().Create(beneficiary).Return(detail, nil)
+
+		rr := serve("POST", "/beneficiaries", beneficiaryBody)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "/beneficiaries/3", rr.Header().Get("Location"))
+
+		var res domain.BeneficiaryDetail
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, detail, res)
+	})
+
+	t.Run("Test create return the invalid fields", func(t *testing.T) {
+		serviceMock.EXPECT().Create(gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("Missing mandatory fields")
+
+		rr := serve("POST", "/beneficiaries", strings.Replace(beneficiaryBody, "EE303680981021245685", "EE383680981021245685", 1))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.JSONEq(t, `{"reason": "invalid_fields", "message": "invalid fields: iban \"EE383680981021245685\" is not a valid IBAN",
+			"errors": [{"field": "iban", "rule": "iban", "message": "\"EE383680981021245685\" is not a valid IBAN"}]}`, rr.Body.String())
+	})
+
+	t.Run("Test create return error when body is wrong", func(t *testing.T) {
+		serviceMock.EXPECT().Create(gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error parsing message body")
+
+		rr := serve("POST", "/beneficiaries", `{ "test":`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("Test create return conflict when the iban is already a beneficiary", func(t *testing.T) {
+		serviceMock.EXPECT().Create(beneficiary).Return(domain.BeneficiaryDetail{}, beneficiarysvc.ErrDuplicateBeneficiary)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error registering beneficiary")
+
+		rr := serve("POST", "/beneficiaries", beneficiaryBody)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Test create return error when the bank account doesn't exist", func(t *testing.T) {
+		serviceMock.EXPECT().Create(beneficiary).Return(domain.BeneficiaryDetail{}, beneficiarysvc.ErrAccountNotFound)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error registering beneficiary")
+
+		rr := serve("POST", "/beneficiaries", beneficiaryBody)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("Test read return success", func(t *testing.T) {
+		serviceMock.EXPECT().Read(uint(3)).Return(detail, nil)
+
+		rr := serve("GET", "/beneficiaries/3", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test read return not found", func(t *testing.T) {
+		serviceMock.EXPECT().Read(uint(3)).Return(domain.BeneficiaryDetail{}, beneficiarysvc.ErrBeneficiaryNotFound)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error reading beneficiary with id 3")
+
+		rr := serve("GET", "/beneficiaries/3", "")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Test list return the beneficiaries of the bank account", func(t *testing.T) {
+		serviceMock.EXPECT().
+			ReadByFilter(map[string]string{"organization_iban": "FR81474608000002006107XXXXX"}).
+			Return(domain.BeneficiaryDetailList{detail}, nil)
+
+		rr := serve("GET", "/beneficiaries?organization_iban=FR81474608000002006107XXXXX", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var res domain.BeneficiaryDetailList
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, domain.BeneficiaryDetailList{detail}, res)
+	})
+
+	t.Run("Test list return error without the bank account", func(t *testing.T) {
+		serviceMock.EXPECT().ReadByFilter(map[string]string{}).Return(nil, beneficiarysvc.ErrMissingAccount)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error reading beneficiaries")
+
+		rr := serve("GET", "/beneficiaries", "")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Test update return success", func(t *testing.T) {
+		serviceMock.EXPECT().Update(uint(3), beneficiary).Return(detail, nil)
+
+		rr := serve("PUT", "/beneficiaries/3", beneficiaryBody)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test update return not found", func(t *testing.T) {
+		serviceMock.EXPECT().Update(uint(3), beneficiary).Return(domain.BeneficiaryDetail{}, beneficiarysvc.ErrBeneficiaryNotFound)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error updating beneficiary with id 3")
+
+		rr := serve("PUT", "/beneficiaries/3", beneficiaryBody)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Test delete return success", func(t *testing.T) {
+		serviceMock.EXPECT().Delete(uint(3)).Return(nil)
+
+		rr := serve("DELETE", "/beneficiaries/3", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test delete return error", func(t *testing.T) {
+		serviceMock.EXPECT().Delete(uint(3)).Return(errors.New("error"))
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error deleting beneficiary with id 3")
+
+		rr := serve("DELETE", "/beneficiaries/3", "")
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
@@ -0,0 +1,106 @@
+package ledgerhdl
+
+import (
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/ledgersvc"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+const (
+	pathSelectionEntries = "/ledger/entries"
+	pathSelectionBalance = "/ledger/balance/iban/{iban}"
+)
+
+// Handler defines the handler interface
+type Handler interface {
+	Handlers(r *mux.Router)
+}
+
+// New returns an implementation of the ledger handler
+func New(ledgerService ledgersvc.LedgerService, logger log.Logger) Handler {
+	return handler{
+		logger:        logger,
+		ledgerService: ledgerService,
+	}
+}
+
+type handler struct {
+	logger        log.Logger
+	ledgerService ledgersvc.LedgerService
+}
+
+func (h handler) Handlers(r *mux.Router) {
+	// handlers
+	r.HandleFunc(pathSelectionEntries, h.readEntries).Methods(http.MethodGet)
+	r.HandleFunc(pathSelectionBalance, h.readBalance).Methods(http.MethodGet)
+}
+
+// @Summary list the ledger entries
+// @Description Get the balanced debit and credit entries posted for every balance movement
+// @Tags ledger
+// @ID read-ledger-entries
+// @Produce json
+// @Param iban query string false "entries of the bank account with the iban"
+// @Param account query string false "entries of the ledger account, e.g. bank_account:1, external or equity"
+// @Param journal_id query int false "entries of the journal"
+// @Param transaction_id query int false "entries posted for the transaction"
+// @Param operation query string false "entries of the operation, opening_balance, adjustment, bulk_transfer or reversal"
+// @Param reference query string false "entries of the reference, e.g. bulk_transfer:3"
+// @Success 200 {array} domain.LedgerEntry
+// @Failure 400 {string}  string
+// @Failure 404 {string}  string
+// @Router /v1/ledger/entries [get]
+func (h handler) readEntries(w http.ResponseWriter, r *http.Request) {
+	queries := r.URL.Query()
+
+	filters := make(map[string]string)
+	for k, v := range queries {
+		if len(v) > 0 {
+			filters[k] = v[0]
+		}
+	}
+
+	entries, err := h.ledgerService.ReadEntries(filters)
+	if err != nil {
+		h.logger.WithError(err).Error("error reading ledger entries")
+		if errors.Is(err, ledgersvc.ErrBankAccountNotFound) {
+			tools.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		tools.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, entries)
+}
+
+// @Summary read the ledger balance of a bank account
+// @Description Get the balance derived from the ledger entries of a bank account, compared to its cached balance
+// @Tags ledger
+// @ID read-ledger-balance-by-iban
+// @Produce json
+// @Param iban path string true "bank account iban"
+// @Success 200 {object} domain.LedgerBalance
+// @Failure 404 {string}  string
+// @Failure 500 {string}  string
+// @Router /v1/ledger/balance/iban/{iban} [get]
+func (h handler) readBalance(w http.ResponseWriter, r *http.Request) {
+	iban := mux.Vars(r)["iban"]
+
+	balance, err := h.ledgerService.Balance(iban)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error reading ledger balance of %s", iban))
+		if errors.Is(err, ledgersvc.ErrBankAccountNotFound) {
+			tools.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		tools.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, balance)
+}
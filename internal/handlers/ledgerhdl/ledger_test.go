@@ -0,0 +1,110 @@
+package ledgerhdl
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/ledgersvc"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/services"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLedgerHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	serviceMock := mockservice.NewMockLedgerService(ctrl)
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		h := New(serviceMock, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Test read entries return success", func(t *testing.T) {
+		entries := domain.LedgerEntryList{{ID: 1, JournalID: 7, Operation: domain.LedgerBulkTransfer, Account: "bank_account:1", Side: domain.LedgerDebit, Amount: domain.NewMoney(1453, "EUR")}}
+		serviceMock.EXPECT().ReadEntries(map[string]string{"iban": "FR81474608000002006107XXXXX"}).Return(entries, nil)
+
+		rr := serve("/ledger/entries?iban=FR81474608000002006107XXXXX")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"amount":"14.53"`)
+		assert.Contains(t, rr.Body.String(), `"side":"debit"`)
+	})
+
+	t.Run("Test read entries return not found", func(t *testing.T) {
+		serviceMock.EXPECT().ReadEntries(gomock.Any()).Return(nil, ledgersvc.ErrBankAccountNotFound)
+		logMock.EXPECT().WithError(ledgersvc.ErrBankAccountNotFound).Return(logMock)
+		logMock.EXPECT().Error("error reading ledger entries")
+
+		rr := serve("/ledger/entries?iban=EE303680981021245685")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Test read entries return error", func(t *testing.T) {
+		serviceMock.EXPECT().ReadEntries(gomock.Any()).Return(nil, errors.New("error"))
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error reading ledger entries")
+
+		rr := serve("/ledger/entries?unknown=1")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Test read balance return success", func(t *testing.T) {
+		balance := domain.LedgerBalance{
+			Iban:          "FR81474608000002006107XXXXX",
+			Account:       "bank_account:1",
+			Balance:       domain.NewMoney(8547, "EUR"),
+			CachedBalance: domain.NewMoney(8547, "EUR"),
+			Consistent:    true,
+		}
+		serviceMock.EXPECT().Balance("FR81474608000002006107XXXXX").Return(balance, nil)
+
+		rr := serve("/ledger/balance/iban/FR81474608000002006107XXXXX")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var res map[string]any
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, "85.47", res["balance"])
+		assert.Equal(t, true, res["consistent"])
+	})
+
+	t.Run("Test read balance return not found", func(t *testing.T) {
+		serviceMock.EXPECT().Balance("EE303680981021245685").Return(domain.LedgerBalance{}, ledgersvc.ErrBankAccountNotFound)
+		logMock.EXPECT().WithError(ledgersvc.ErrBankAccountNotFound).Return(logMock)
+		logMock.EXPECT().Error("error reading ledger balance of EE303680981021245685")
+
+		rr := serve("/ledger/balance/iban/EE303680981021245685")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Test read balance return error", func(t *testing.T) {
+		serviceMock.EXPECT().Balance("FR81474608000002006107XXXXX").Return(domain.LedgerBalance{}, errors.New("error"))
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error reading ledger balance of FR81474608000002006107XXXXX")
+
+		rr := serve("/ledger/balance/iban/FR81474608000002006107XXXXX")
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
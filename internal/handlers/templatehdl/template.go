@@ -0,0 +1,285 @@
+package templatehdl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/middleware"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/templatesvc"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+const (
+	pathSelection            = "/transfer/templates"
+	pathSelectionID          = "/transfer/templates/{id:[0-9]+}"
+	pathSelectionPause       = "/transfer/templates/{id:[0-9]+}/pause"
+	pathSelectionResume      = "/transfer/templates/{id:[0-9]+}/resume"
+	pathSelectionOccurrences = "/transfer/templates/{id:[0-9]+}/occurrences"
+
+	defaultOccurrences = 5
+	maxOccurrences     = 100
+)
+
+var errInvalidCount = fmt.Errorf("count must be a number between 1 and %d", maxOccurrences)
+
+// Handler defines the handler interface
+type Handler interface {
+	Handlers(r *mux.Router)
+}
+
+// New returns an implementation of the transfer template handler.
+// idempotency wraps the template creation so retried submissions don't create it twice.
+func New(templateService templatesvc.TemplateService, idempotency mux.MiddlewareFunc, logger log.Logger) Handler {
+	return handler{
+		logger:          logger,
+		templateService: templateService,
+		idempotency:     idempotency,
+	}
+}
+
+type handler struct {
+	logger          log.Logger
+	templateService templatesvc.TemplateService
+	idempotency     mux.MiddlewareFunc
+}
+
+func (h handler) Handlers(r *mux.Router) {
+	// handlers
+	r.Handle(pathSelection, h.idempotency(http.HandlerFunc(h.create))).Methods(http.MethodPost)
+	r.HandleFunc(pathSelection, h.list).Methods(http.MethodGet)
+	r.HandleFunc(pathSelectionID, h.read).Methods(http.MethodGet)
+	r.HandleFunc(pathSelectionID, h.update).Methods(http.MethodPut)
+	r.HandleFunc(pathSelectionPause, h.pause).Methods(http.MethodPost)
+	r.HandleFunc(pathSelectionResume, h.resume).Methods(http.MethodPost)
+	r.HandleFunc(pathSelectionOccurrences, h.occurrences).Methods(http.MethodGet)
+}
+
+// @Summary create a recurring transfer template
+// @ID create-transfer-template
+// @Tags transfer
+// @Produce json
+// @Param Idempotency-Key header string false "key to safely retry the request"
+// @Param data body domain.TransferTemplate true "transfer template data"
+// @Success 201 {object} domain.TransferTemplateDetail
+// @Failure 409 {string}  string
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/transfer/templates [post]
+func (h handler) create(w http.ResponseWriter, r *http.Request) {
+
+	template, ok := h.decode(w, r)
+	if !ok {
+		return
+	}
+
+	detail, err := h.templateService.Create(template)
+	if err != nil {
+		h.logger.WithError(err).Error("error registering transfer template")
+		if domain.IsNotApplied(err) {
+			middleware.ReleaseIdempotencyKey(r)
+		}
+		tools.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, detail.ID))
+	tools.WriteJSON(w, http.StatusCreated, detail)
+}
+
+// @Summary read a transfer template and its credit transfers
+// @ID read-transfer-template
+// @Tags transfer
+// @Produce json
+// @Param id path int true "transfer template id"
+// @Success 200 {object} domain.TransferTemplateDetail
+// @Failure 404 {string}  string
+// @Router /v1/transfer/templates/{id} [get]
+func (h handler) read(w http.ResponseWriter, r *http.Request) {
+
+	id := templateID(r)
+	detail, err := h.templateService.Read(id)
+
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error reading transfer template with id %d", id))
+		writeError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// @Summary list transfer templates
+// @ID read-transfer-templates
+// @Tags transfer
+// @Produce json
+// @Param organization_iban query string false "transfer template search by organization_iban"
+// @Param status query string false "transfer template search by status"
+// @Success 200 {array} domain.TransferTemplateDetail
+// @Failure 400 {string}  string
+// @Router /v1/transfer/templates [get]
+func (h handler) list(w http.ResponseWriter, r *http.Request) {
+	queries := r.URL.Query()
+
+	filters := make(map[string]string)
+	for k, v := range queries {
+		if len(v) > 0 {
+			filters[k] = v[0]
+		}
+	}
+
+	list, err := h.templateService.ReadByFilter(filters)
+
+	if err != nil {
+		h.logger.WithError(err).Error("error reading transfer templates")
+		tools.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, list)
+}
+
+// @Summary edit a transfer template, replacing its values and credit transfers
+// @ID update-transfer-template
+// @Tags transfer
+// @Produce json
+// @Param id path int true "transfer template id"
+// @Param data body domain.TransferTemplate true "transfer template data"
+// @Success 200 {object} domain.TransferTemplateDetail
+// @Failure 404 {string}  string
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/transfer/templates/{id} [put]
+func (h handler) update(w http.ResponseWriter, r *http.Request) {
+
+	template, ok := h.decode(w, r)
+	if !ok {
+		return
+	}
+
+	id := templateID(r)
+	detail, err := h.templateService.Update(id, template)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error updating transfer template with id %d", id))
+		writeError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// @Summary pause a transfer template, its occurrences are skipped until it is resumed
+// @ID pause-transfer-template
+// @Tags transfer
+// @Produce json
+// @Param id path int true "transfer template id"
+// @Success 200 {object} domain.TransferTemplateDetail
+// @Failure 404 {string}  string
+// @Failure 409 {string}  string
+// @Router /v1/transfer/templates/{id}/pause [post]
+func (h handler) pause(w http.ResponseWriter, r *http.Request) {
+
+	id := templateID(r)
+	detail, err := h.templateService.Pause(id)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error pausing transfer template with id %d", id))
+		writeError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// @Summary resume a paused transfer template
+// @ID resume-transfer-template
+// @Tags transfer
+// @Produce json
+// @Param id path int true "transfer template id"
+// @Success 200 {object} domain.TransferTemplateDetail
+// @Failure 404 {string}  string
+// @Failure 409 {string}  string
+// @Router /v1/transfer/templates/{id}/resume [post]
+func (h handler) resume(w http.ResponseWriter, r *http.Request) {
+
+	id := templateID(r)
+	detail, err := h.templateService.Resume(id)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error resuming transfer template with id %d", id))
+		writeError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// @Summary list the upcoming occurrences of a transfer template
+// @ID read-transfer-template-occurrences
+// @Tags transfer
+// @Produce json
+// @Param id path int true "transfer template id"
+// @Param count query int false "number of occurrences, 5 by default"
+// @Success 200 {object} domain.TransferTemplateOccurrences
+// @Failure 400 {string}  string
+// @Failure 404 {string}  string
+// @Router /v1/transfer/templates/{id}/occurrences [get]
+func (h handler) occurrences(w http.ResponseWriter, r *http.Request) {
+
+	count := defaultOccurrences
+	if value := r.URL.Query().Get("count"); value != "" {
+		var err error
+		if count, err = strconv.Atoi(value); err != nil || count < 1 || count > maxOccurrences {
+			tools.WriteError(w, http.StatusBadRequest, errInvalidCount)
+			return
+		}
+	}
+
+	id := templateID(r)
+	occurrences, err := h.templateService.Upcoming(id, count)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error reading occurrences of transfer template with id %d", id))
+		writeError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, occurrences)
+}
+
+// decode reads and validates the transfer template of the request, reporting the rejection when it is invalid
+func (h handler) decode(w http.ResponseWriter, r *http.Request) (domain.TransferTemplate, bool) {
+	var template domain.TransferTemplate
+
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		h.logger.WithError(err).Error("error parsing message body")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.NewRejection(domain.RejectionInvalidBody, err))
+		return template, false
+	}
+
+	if err := template.Validate(); err != nil {
+		h.logger.WithError(err).Error("Missing mandatory fields")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.AsRejection(err))
+		return template, false
+	}
+
+	return template, true
+}
+
+// writeError writes the status matching the service error
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, templatesvc.ErrTemplateNotFound):
+		tools.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, templatesvc.ErrTemplateNotActive), errors.Is(err, templatesvc.ErrTemplateNotPaused):
+		tools.WriteError(w, http.StatusConflict, err)
+	default:
+		tools.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func templateID(r *http.Request) uint {
+	id, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	return uint(id)
+}
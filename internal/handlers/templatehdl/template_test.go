@@ -0,0 +1,231 @@
+package templatehdl
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/templatesvc"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/services"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const templateBody = `{"name": "Monthly payroll", "organization_name": "ACME Corp", "organization_bic": "OIVUSCLQXXX", "organization_iban": "FR81474608000002006107XXXXX",
+	"recurrence": {"frequency": "monthly", "day_of_month": 25}, "start_date": "2022-09-01",
+	"credit_transfers": [{"amount": "14.53", "currency": "EUR", "counterparty_name": "Bip Bip", "counterparty_bic": "CRLYFRPPTOU", "counterparty_iban": "EE303680981021245685", "description": "Salary"}]}`
+
+func TestTemplateHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	serviceMock := mockservice.NewMockTemplateService(ctrl)
+	logMock := mocklog.NewMockLogger(ctrl)
+	idempotency := func(next http.Handler) http.Handler { return next }
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	template := domain.TransferTemplate{
+		Name:             "Monthly payroll",
+		OrganizationName: "ACME Corp",
+		OrganizationBic:  "OIVUSCLQXXX",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		CreditTransfers: []domain.CreditTransfer{
+			{
+				Amount:           domain.NewMoney(1453, "EUR"),
+				Currency:         "EUR",
+				CounterPartyName: "Bip Bip",
+				CounterPartyBic:  "CRLYFRPPTOU",
+				CounterPartyIban: "EE303680981021245685",
+				Description:      "Salary",
+			},
+		},
+		Recurrence: domain.Recurrence{Frequency: domain.RecurrenceMonthly, DayOfMonth: 25},
+		StartDate:  "2022-09-01",
+	}
+
+	detail := domain.TransferTemplateDetail{
+		ID:             4,
+		Name:           "Monthly payroll",
+		TotalAmount:    domain.NewMoney(1453, "EUR"),
+		Status:         domain.TransferTemplateActive,
+		NextOccurrence: "2022-09-25",
+	}
+
+	t.Run("Test create return success", func(t *testing.T) {
+		serviceMock.EXPECT().Create(template).Return(detail, nil)
+
+		rr := serve("POST", "/transfer/templates", templateBody)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "/transfer/templates/4", rr.Header().Get("Location"))
+
+		var res domain.TransferTemplateDetail
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, uint(4), res.ID)
+		assert.Equal(t, "2022-09-25", res.NextOccurrence)
+		assert.Equal(t, domain.NewMoney(1453, "EUR"), res.TotalAmount)
+	})
+
+	t.Run("Test create return the invalid fields", func(t *testing.T) {
+		serviceMock.EXPECT().Create(gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("Missing mandatory fields")
+
+		rr := serve("POST", "/transfer/templates", strings.Replace(templateBody, `"day_of_month": 25`, `"interval": 1`, 1))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+		var res domain.Rejection
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, domain.RejectionInvalidFields, res.Reason)
+		assert.Equal(t, []domain.LineError{{Field: "recurrence.day_of_month", Rule: "required_if", Message: "is required"}}, res.Errors)
+	})
+
+	t.Run("Test create return error when body is wrong", func(t *testing.T) {
+		serviceMock.EXPECT().Create(gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error parsing message body")
+
+		rr := serve("POST", "/transfer/templates", `{ "test":`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("Test read return success", func(t *testing.T) {
+		serviceMock.EXPECT().Read(uint(4)).Return(detail, nil)
+
+		rr := serve("GET", "/transfer/templates/4", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test read return not found", func(t *testing.T) {
+		serviceMock.EXPECT().Read(uint(5)).Return(domain.TransferTemplateDetail{}, templatesvc.ErrTemplateNotFound)
+		logMock.EXPECT().WithError(templatesvc.ErrTemplateNotFound).Return(logMock)
+		logMock.EXPECT().Error("error reading transfer template with id 5")
+
+		rr := serve("GET", "/transfer/templates/5", "")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Test list return success", func(t *testing.T) {
+		serviceMock.EXPECT().ReadByFilter(map[string]string{"status": "paused"}).Return(domain.TransferTemplateDetailList{detail}, nil)
+
+		rr := serve("GET", "/transfer/templates?status=paused", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var res domain.TransferTemplateDetailList
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Len(t, res, 1)
+	})
+
+	t.Run("Test list return error", func(t *testing.T) {
+		serviceMock.EXPECT().ReadByFilter(gomock.Any()).Return(nil, errors.New("error"))
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error reading transfer templates")
+
+		rr := serve("GET", "/transfer/templates?unknown=1", "")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Test update return success", func(t *testing.T) {
+		serviceMock.EXPECT().Update(uint(4), template).Return(detail, nil)
+
+		rr := serve("PUT", "/transfer/templates/4", templateBody)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test update return not found", func(t *testing.T) {
+		serviceMock.EXPECT().Update(uint(5), template).Return(domain.TransferTemplateDetail{}, templatesvc.ErrTemplateNotFound)
+		logMock.EXPECT().WithError(templatesvc.ErrTemplateNotFound).Return(logMock)
+		logMock.EXPECT().Error("error updating transfer template with id 5")
+
+		rr := serve("PUT", "/transfer/templates/5", templateBody)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Test pause return success", func(t *testing.T) {
+		paused := detail
+		paused.Status = domain.TransferTemplatePaused
+		serviceMock.EXPECT().Pause(uint(4)).Return(paused, nil)
+
+		rr := serve("POST", "/transfer/templates/4/pause", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"status":"paused"`)
+	})
+
+	t.Run("Test pause return conflict", func(t *testing.T) {
+		serviceMock.EXPECT().Pause(uint(4)).Return(domain.TransferTemplateDetail{}, templatesvc.ErrTemplateNotActive)
+		logMock.EXPECT().WithError(templatesvc.ErrTemplateNotActive).Return(logMock)
+		logMock.EXPECT().Error("error pausing transfer template with id 4")
+
+		rr := serve("POST", "/transfer/templates/4/pause", "")
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Test resume return conflict", func(t *testing.T) {
+		serviceMock.EXPECT().Resume(uint(4)).Return(domain.TransferTemplateDetail{}, templatesvc.ErrTemplateNotPaused)
+		logMock.EXPECT().WithError(templatesvc.ErrTemplateNotPaused).Return(logMock)
+		logMock.EXPECT().Error("error resuming transfer template with id 4")
+
+		rr := serve("POST", "/transfer/templates/4/resume", "")
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Test occurrences return the upcoming occurrences", func(t *testing.T) {
+		occurrences := domain.TransferTemplateOccurrences{TemplateID: 4, Occurrences: []string{"2022-09-25", "2022-10-25"}}
+		serviceMock.EXPECT().Upcoming(uint(4), 2).Return(occurrences, nil)
+
+		rr := serve("GET", "/transfer/templates/4/occurrences?count=2", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var res domain.TransferTemplateOccurrences
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, occurrences, res)
+	})
+
+	t.Run("Test occurrences default count", func(t *testing.T) {
+		serviceMock.EXPECT().Upcoming(uint(4), defaultOccurrences).Return(domain.TransferTemplateOccurrences{TemplateID: 4}, nil)
+
+		rr := serve("GET", "/transfer/templates/4/occurrences", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test occurrences return error on invalid count", func(t *testing.T) {
+		serviceMock.EXPECT().Upcoming(gomock.Any(), gomock.Any()).Times(0)
+
+		for _, count := range []string{"0", "101", "abc"} {
+			rr := serve("GET", "/transfer/templates/4/occurrences?count="+count, "")
+			assert.Equal(t, http.StatusBadRequest, rr.Code, count)
+		}
+	})
+}
@@ -1,15 +1,22 @@
 package transactionhdl
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/middleware"
 	"github.com/adrianoccosta/exercise-qonto/internal/services/transactionsvc"
 	"github.com/adrianoccosta/exercise-qonto/log"
 	"github.com/adrianoccosta/exercise-qonto/tools"
 	"github.com/gorilla/mux"
 	"net/http"
+	"strconv"
 )
 
 const (
-	pathSelection = "/transaction"
+	pathSelection        = "/transaction"
+	pathSelectionReverse = "/transaction/{id:[0-9]+}/reverse"
 )
 
 // Handler defines the handler interface
@@ -17,22 +24,26 @@ type Handler interface {
 	Handlers(r *mux.Router)
 }
 
-// New returns an implementation of the transaction handler
-func New(transactionService transactionsvc.TransactionService, logger log.Logger) Handler {
+// New returns an implementation of the transaction handler.
+// idempotency wraps the reversals so retried requests don't give the amount back twice.
+func New(transactionService transactionsvc.TransactionService, idempotency mux.MiddlewareFunc, logger log.Logger) Handler {
 	return handler{
 		logger:             logger,
 		transactionService: transactionService,
+		idempotency:        idempotency,
 	}
 }
 
 type handler struct {
 	logger             log.Logger
 	transactionService transactionsvc.TransactionService
+	idempotency        mux.MiddlewareFunc
 }
 
 func (h handler) Handlers(r *mux.Router) {
 	// handlers
 	r.HandleFunc(pathSelection, h.read).Methods(http.MethodGet)
+	r.Handle(pathSelectionReverse, h.idempotency(http.HandlerFunc(h.reverse))).Methods(http.MethodPost)
 }
 
 // @Summary Retrieves a bank account based on given iban
@@ -43,6 +54,8 @@ func (h handler) Handlers(r *mux.Router) {
 // @Param name query string false "transaction search by name"
 // @Param iban query string false "transaction search by iban"
 // @Param counterparty_name query string false "transaction search by counterparty_name"
+// @Param direction query string false "transaction search by direction, outgoing or incoming"
+// @Param reversed_transaction_id query int false "transaction search by the transaction they reverse"
 // @Success 200 {array} domain.BankAccount
 // @Failure 500 {string}  string
 // @Router /v1/transaction [get]
@@ -66,3 +79,57 @@ func (h handler) read(w http.ResponseWriter, r *http.Request) {
 
 	tools.WriteJSON(w, http.StatusOK, transactionList)
 }
+
+// @Summary reverse an outgoing transaction, in full or in part
+// @Description Gives the amount back to the bank account through a compensating incoming transaction
+// @Tags transactions
+// @ID reverse-transaction
+// @Produce json
+// @Param id path int true "transaction id"
+// @Param Idempotency-Key header string false "key to safely retry the request"
+// @Param data body domain.TransactionReversal true "reversal data"
+// @Success 201 {object} domain.Transaction
+// @Failure 404 {string}  string
+// @Failure 409 {string}  string
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/transaction/{id}/reverse [post]
+func (h handler) reverse(w http.ResponseWriter, r *http.Request) {
+
+	var reversal domain.TransactionReversal
+
+	if err := json.NewDecoder(r.Body).Decode(&reversal); err != nil {
+		h.logger.WithError(err).Error("error parsing message body")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.NewRejection(domain.RejectionInvalidBody, err))
+		return
+	}
+
+	if err := reversal.Validate(); err != nil {
+		h.logger.WithError(err).Error("Missing mandatory fields")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.AsRejection(err))
+		return
+	}
+
+	id, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	transaction, err := h.transactionService.Reverse(uint(id), reversal)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error reversing transaction with id %d", id))
+		var rejection *domain.Rejection
+		switch {
+		case errors.Is(err, transactionsvc.ErrTransactionNotFound):
+			tools.WriteError(w, http.StatusNotFound, err)
+		case errors.Is(err, transactionsvc.ErrNotReversible):
+			tools.WriteError(w, http.StatusConflict, err)
+		case errors.As(err, &rejection):
+			tools.WriteJSON(w, http.StatusUnprocessableEntity, rejection)
+		default:
+			if domain.IsNotApplied(err) {
+				middleware.ReleaseIdempotencyKey(r)
+			}
+			tools.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusCreated, transaction)
+}
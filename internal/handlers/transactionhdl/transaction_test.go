@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/transactionsvc"
 	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
 	"github.com/adrianoccosta/exercise-qonto/test/mocks/services"
 	"github.com/golang/mock/gomock"
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -20,38 +22,42 @@ func TestTransactionHandler(t *testing.T) {
 
 	serviceMock := mockservice.NewMockTransactionService(ctrl)
 	logMock := mocklog.NewMockLogger(ctrl)
+	idempotency := func(next http.Handler) http.Handler { return next }
 
 	t.Run("Test read return success", func(t *testing.T) {
 
+		reversedAmount := domain.NewMoney(450, "EUR")
 		transactionList := domain.TransactionList{
 			{
 				Name:             "ACME Corp",
-				Iban:             "FR10474608000002006107XXXXX",
+				Iban:             "FR81474608000002006107XXXXX",
 				Bic:              "OIVUSCLQXXX",
 				CounterPartyName: "Bip Bip",
-				CounterPartyIban: "EE383680981021245685",
+				CounterPartyIban: "EE303680981021245685",
 				CounterPartyBic:  "CRLYFRPPTOU",
-				Amount:           14.50,
+				Amount:           domain.NewMoney(1450, "EUR"),
 				Currency:         "EUR",
 				Description:      "Wonderland/4410",
+				Direction:        domain.TransactionOutgoing,
+				ReversedAmount:   &reversedAmount,
 			},
 		}
 
 		filters := map[string]string{
 			"name": "ACME Corp",
-			"iban": "FR10474608000002006107XXXXX",
+			"iban": "FR81474608000002006107XXXXX",
 		}
 
 		serviceMock.EXPECT().
 			ReadByFilter(filters).
 			Return(transactionList, nil).Times(1)
 
-		h := New(serviceMock, logMock)
+		h := New(serviceMock, idempotency, logMock)
 		rr := httptest.NewRecorder()
 		r := mux.NewRouter()
 		h.Handlers(r)
 
-		req, err := http.NewRequest("GET", "/transaction?name=ACME Corp&iban=FR10474608000002006107XXXXX", nil)
+		req, err := http.NewRequest("GET", "/transaction?name=ACME Corp&iban=FR81474608000002006107XXXXX", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -75,7 +81,7 @@ func TestTransactionHandler(t *testing.T) {
 		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
 		logMock.EXPECT().Error("error reading transactions").Times(1)
 
-		h := New(serviceMock, logMock)
+		h := New(serviceMock, idempotency, logMock)
 		rr := httptest.NewRecorder()
 		r := mux.NewRouter()
 		h.Handlers(r)
@@ -89,4 +95,90 @@ func TestTransactionHandler(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, rr.Code)
 		assert.NotEmpty(t, rr.Body.String())
 	})
+
+	reverse := func(id, body string) *httptest.ResponseRecorder {
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transaction/"+id+"/reverse", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Test reverse return the compensating transaction", func(t *testing.T) {
+
+		reversal := domain.Transaction{ID: 8, Amount: domain.NewMoney(1000, "EUR"), Currency: "EUR", Direction: domain.TransactionIncoming, ReversedTransactionID: 4}
+		serviceMock.EXPECT().
+			Reverse(uint(4), domain.TransactionReversal{Amount: domain.NewMoney(1000, "EUR"), Reason: "paid twice"}).
+			Return(reversal, nil).Times(1)
+
+		rr := reverse("4", `{"amount": "10.00", "reason": "paid twice"}`)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		var actual domain.Transaction
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &actual))
+		assert.Equal(t, uint(4), actual.ReversedTransactionID)
+		assert.Equal(t, domain.NewMoney(1000, "EUR"), actual.Amount)
+	})
+
+	t.Run("Test reverse return error when the reason is missing", func(t *testing.T) {
+
+		serviceMock.EXPECT().Reverse(gomock.Any(), gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("Missing mandatory fields").Times(1)
+
+		rr := reverse("4", `{"amount": "10.00"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("Test reverse return error when the amount is invalid", func(t *testing.T) {
+
+		serviceMock.EXPECT().Reverse(gomock.Any(), gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error parsing message body").Times(1)
+
+		rr := reverse("4", `{"amount": "10.001", "reason": "paid twice"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("Test reverse return the amount left to reverse when it is exceeded", func(t *testing.T) {
+
+		rejection := domain.NewRejection(domain.RejectionInvalidFields, errors.New("reversal exceeds the amount not reversed yet")).WithFieldError("amount", "lte", "must be at most 4.50")
+		serviceMock.EXPECT().Reverse(uint(4), gomock.Any()).Return(domain.Transaction{}, rejection).Times(1)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error reversing transaction with id 4").Times(1)
+
+		rr := reverse("4", `{"amount": "10.00", "reason": "paid twice"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+		var res domain.Rejection
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, []domain.LineError{{Field: "amount", Rule: "lte", Message: "must be at most 4.50"}}, res.Errors)
+	})
+
+	t.Run("Test reverse return conflict when the transaction isn't outgoing", func(t *testing.T) {
+
+		serviceMock.EXPECT().Reverse(uint(8), gomock.Any()).Return(domain.Transaction{}, transactionsvc.ErrNotReversible).Times(1)
+		logMock.EXPECT().WithError(transactionsvc.ErrNotReversible).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error reversing transaction with id 8").Times(1)
+
+		rr := reverse("8", `{"reason": "paid twice"}`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Test reverse return not found", func(t *testing.T) {
+
+		serviceMock.EXPECT().Reverse(uint(9), gomock.Any()).Return(domain.Transaction{}, transactionsvc.ErrTransactionNotFound).Times(1)
+		logMock.EXPECT().WithError(transactionsvc.ErrTransactionNotFound).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error reversing transaction with id 9").Times(1)
+
+		rr := reverse("9", `{"reason": "paid twice"}`)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
 }
@@ -1,71 +1,554 @@
 package transferhdl
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/middleware"
 	"github.com/adrianoccosta/exercise-qonto/internal/services/transfersvc"
 	"github.com/adrianoccosta/exercise-qonto/log"
 	"github.com/adrianoccosta/exercise-qonto/tools"
 	"github.com/gorilla/mux"
+	"io"
 	"net/http"
+	"strconv"
 )
 
 const (
-	pathSelection = "/transfer/bulk"
+	pathSelection         = "/transfer/bulk"
+	pathSelectionID       = "/transfer/bulk/{id:[0-9]+}"
+	pathSelectionValidate = "/transfer/bulk/validate"
+	pathSelectionCancel   = "/transfer/bulk/{id:[0-9]+}/cancel"
+	pathSelectionApprove  = "/transfer/bulk/{id:[0-9]+}/approve"
+	pathSelectionReject   = "/transfer/bulk/{id:[0-9]+}/reject"
+	pathSelectionRelease  = "/transfer/bulk/{id:[0-9]+}/release"
+	pathSelectionBlock    = "/transfer/bulk/{id:[0-9]+}/block"
+	pathSelectionJobs     = "/transfer/bulk/jobs"
+	pathSelectionJobID    = "/transfer/bulk/jobs/{id:[0-9]+}"
 )
 
+var errMissingActor = fmt.Errorf("the %s header is required", tools.HeaderActor)
+
 // Handler defines the handler interface
 type Handler interface {
 	Handlers(r *mux.Router)
 }
 
-// New returns an implementation of the back account handler
-func New(transferService transfersvc.TransferService, logger log.Logger) Handler {
+// New returns an implementation of the transfer handler.
+// idempotency wraps the bulk transfer creation so retried submissions are not executed twice.
+func New(transferService transfersvc.TransferService, idempotency mux.MiddlewareFunc, logger log.Logger) Handler {
 	return handler{
 		logger:          logger,
 		transferService: transferService,
+		idempotency:     idempotency,
 	}
 }
 
 type handler struct {
 	logger          log.Logger
 	transferService transfersvc.TransferService
+	idempotency     mux.MiddlewareFunc
 }
 
 func (h handler) Handlers(r *mux.Router) {
 	// handlers
-	r.HandleFunc(pathSelection, h.transfer).Methods(http.MethodPost)
+	r.Handle(pathSelection, h.idempotency(http.HandlerFunc(h.transfer))).Methods(http.MethodPost)
+	r.HandleFunc(pathSelectionValidate, h.validate).Methods(http.MethodPost)
+	r.HandleFunc(pathSelection, h.list).Methods(http.MethodGet)
+	r.HandleFunc(pathSelectionID, h.read).Methods(http.MethodGet)
+	r.HandleFunc(pathSelectionCancel, h.cancel).Methods(http.MethodPost)
+	r.HandleFunc(pathSelectionApprove, h.approve).Methods(http.MethodPost)
+	r.HandleFunc(pathSelectionReject, h.reject).Methods(http.MethodPost)
+	r.HandleFunc(pathSelectionRelease, h.release).Methods(http.MethodPost)
+	r.HandleFunc(pathSelectionBlock, h.block).Methods(http.MethodPost)
+	r.Handle(pathSelectionJobs, h.idempotency(http.HandlerFunc(h.submit))).Methods(http.MethodPost)
+	r.HandleFunc(pathSelectionJobID, h.readJob).Methods(http.MethodGet)
 }
 
 // @Summary transfer funds in bulk
 // @ID create-bulk-transfers
 // @Tags transfer
 // @Produce json
+// @Param Idempotency-Key header string false "key to safely retry the request"
+// @Param X-Actor header string false "who submits the bulk transfer, they can't approve it, required above the approval threshold"
 // @Param data body domain.BulkTransfer true "bulk transfer data"
-// @Success 201 {string}  string
-// @Failure 422 {string}  string
+// @Success 201 {object} domain.BulkTransferDetail
+// @Success 207 {object} domain.BulkTransferDetail
+// @Failure 400 {string}  string
+// @Failure 409 {string}  string
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
 // @Router /v1/transfer/bulk [post]
 func (h handler) transfer(w http.ResponseWriter, r *http.Request) {
 
+	bulkTransfer, ok := h.decodeBulkTransfer(w, r)
+	if !ok {
+		return
+	}
+
+	detail, err := h.transferService.BulkTransfer(bulkTransfer)
+	if err != nil {
+		h.logger.WithError(err).Error("error registering bulk transfer")
+		writeTransferError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, detail.ID))
+	if detail.Status == domain.BulkTransferPartiallyCompleted {
+		tools.WriteJSON(w, http.StatusMultiStatus, detail)
+		return
+	}
+	tools.WriteJSON(w, http.StatusCreated, detail)
+}
+
+// @Summary submit a bulk transfer executed in the background
+// @ID submit-bulk-transfers
+// @Tags transfer
+// @Produce json
+// @Param Idempotency-Key header string false "key to safely retry the request"
+// @Param X-Actor header string false "who submits the bulk transfer, they can't approve it, required above the approval threshold"
+// @Param data body domain.BulkTransfer true "bulk transfer data"
+// @Success 202 {object} domain.BulkTransferJob
+// @Failure 400 {string}  string
+// @Failure 409 {string}  string
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/transfer/bulk/jobs [post]
+func (h handler) submit(w http.ResponseWriter, r *http.Request) {
+
+	bulkTransfer, ok := h.decodeBulkTransfer(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.transferService.Submit(bulkTransfer)
+	if err != nil {
+		h.logger.WithError(err).Error("error submitting bulk transfer")
+		writeTransferError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, job.ID))
+	tools.WriteJSON(w, http.StatusAccepted, job)
+}
+
+// @Summary read the status and the progress of a bulk transfer job
+// @ID read-bulk-transfer-job
+// @Tags transfer
+// @Produce json
+// @Param id path int true "job id"
+// @Success 200 {object} domain.BulkTransferJob
+// @Failure 404 {string}  string
+// @Failure 500 {string}  string
+// @Router /v1/transfer/bulk/jobs/{id} [get]
+func (h handler) readJob(w http.ResponseWriter, r *http.Request) {
+
+	id, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	job, err := h.transferService.ReadJob(uint(id))
+
+	if errors.Is(err, transfersvc.ErrJobNotFound) {
+		tools.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error reading job with id %d", id))
+		tools.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, job)
+}
+
+// decodeBulkTransfer decodes and validates the bulk transfer of the request, writing the rejection when it is invalid
+func (h handler) decodeBulkTransfer(w http.ResponseWriter, r *http.Request) (domain.BulkTransfer, bool) {
+
 	var bulkTransfer domain.BulkTransfer
 
 	if err := json.NewDecoder(r.Body).Decode(&bulkTransfer); err != nil {
 		h.logger.WithError(err).Error("error parsing message body")
-		tools.WriteError(w, http.StatusUnprocessableEntity, err)
-		return
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.NewRejection(domain.RejectionInvalidBody, err))
+		return domain.BulkTransfer{}, false
 	}
 
 	if err := bulkTransfer.Validate(); err != nil {
+		rejection := domain.AsRejection(err)
+		// a best effort bulk transfer executes its valid lines, the invalid ones are reported by line
+		if bulkTransfer.Mode() != domain.ExecutionBestEffort || !rejection.LinesOnly() {
+			h.logger.WithError(err).Error("Missing mandatory fields")
+			tools.WriteJSON(w, http.StatusUnprocessableEntity, rejection)
+			return domain.BulkTransfer{}, false
+		}
+	}
+
+	bulkTransfer.SubmittedBy = middleware.Actor(r)
+	return bulkTransfer, true
+}
+
+// @Summary check a bulk transfer without executing it
+// @ID validate-bulk-transfers
+// @Tags transfer
+// @Produce json
+// @Param data body domain.BulkTransfer true "bulk transfer data"
+// @Success 200 {object} domain.BulkTransferQuote
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/transfer/bulk/validate [post]
+func (h handler) validate(w http.ResponseWriter, r *http.Request) {
+
+	var bulkTransfer domain.BulkTransfer
+
+	if err := json.NewDecoder(r.Body).Decode(&bulkTransfer); err != nil {
+		h.logger.WithError(err).Error("error parsing message body")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.NewRejection(domain.RejectionInvalidBody, err))
+		return
+	}
+
+	quote, err := h.transferService.Quote(bulkTransfer)
+	if err != nil {
+		h.logger.WithError(err).Error("error checking bulk transfer")
+		tools.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, quote)
+}
+
+// @Summary read a bulk transfer and its transactions
+// @ID read-bulk-transfer
+// @Tags transfer
+// @Produce json
+// @Param id path int true "bulk transfer id"
+// @Success 200 {object} domain.BulkTransferDetail
+// @Failure 404 {string}  string
+// @Failure 500 {string}  string
+// @Router /v1/transfer/bulk/{id} [get]
+func (h handler) read(w http.ResponseWriter, r *http.Request) {
+
+	id, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	detail, err := h.transferService.Read(uint(id))
+
+	if errors.Is(err, transfersvc.ErrBulkTransferNotFound) || errors.Is(err, sql.ErrNoRows) {
+		h.logger.WithError(err).Error(fmt.Sprintf("error reading bulk transfer with id %d", id))
+		tools.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error reading bulk transfer with id %d", id))
+		tools.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// @Summary list bulk transfers
+// @ID read-bulk-transfers
+// @Tags transfer
+// @Produce json
+// @Param organization_iban query string false "bulk transfer search by organization_iban"
+// @Param status query string false "bulk transfer search by status"
+// @Success 200 {array} domain.BulkTransferDetail
+// @Failure 400 {string}  string
+// @Router /v1/transfer/bulk [get]
+func (h handler) list(w http.ResponseWriter, r *http.Request) {
+	queries := r.URL.Query()
+
+	filters := make(map[string]string)
+	for k, v := range queries {
+		if len(v) > 0 {
+			filters[k] = v[0]
+		}
+	}
+
+	list, err := h.transferService.ReadByFilter(filters)
+
+	if err != nil {
+		h.logger.WithError(err).Error("error reading bulk transfers")
+		tools.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, list)
+}
+
+// @Summary cancel a bulk transfer, or some of its lines, before its execution
+// @ID cancel-bulk-transfer
+// @Tags transfer
+// @Produce json
+// @Param id path int true "bulk transfer id"
+// @Param X-Actor header string true "who cancels the bulk transfer"
+// @Param data body domain.BulkTransferCancellation true "cancellation data"
+// @Success 200 {object} domain.BulkTransferDetail
+// @Failure 400 {string}  string
+// @Failure 404 {string}  string
+// @Failure 409 {string}  string
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/transfer/bulk/{id}/cancel [post]
+func (h handler) cancel(w http.ResponseWriter, r *http.Request) {
+
+	actor := middleware.Actor(r)
+	if actor == "" {
+		tools.WriteError(w, http.StatusBadRequest, errMissingActor)
+		return
+	}
+
+	var cancellation domain.BulkTransferCancellation
+
+	if err := json.NewDecoder(r.Body).Decode(&cancellation); err != nil {
+		h.logger.WithError(err).Error("error parsing message body")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.NewRejection(domain.RejectionInvalidBody, err))
+		return
+	}
+
+	if err := cancellation.Validate(); err != nil {
 		h.logger.WithError(err).Error("Missing mandatory fields")
-		tools.WriteError(w, http.StatusUnprocessableEntity, err)
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.AsRejection(err))
 		return
 	}
 
-	if err := h.transferService.BulkTransfer(bulkTransfer); err != nil {
-		h.logger.WithError(err).Error("error registering bulk transfer")
-		tools.WriteError(w, http.StatusUnprocessableEntity, err)
+	id, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	detail, err := h.transferService.Cancel(uint(id), cancellation, actor)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error cancelling bulk transfer with id %d", id))
+		var rejection *domain.Rejection
+		switch {
+		case errors.Is(err, transfersvc.ErrBulkTransferNotFound):
+			tools.WriteError(w, http.StatusNotFound, err)
+		case errors.Is(err, transfersvc.ErrNotCancellable):
+			tools.WriteError(w, http.StatusConflict, err)
+		case errors.As(err, &rejection):
+			tools.WriteJSON(w, http.StatusUnprocessableEntity, rejection)
+		default:
+			tools.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// @Summary approve a bulk transfer pending approval, it is executed once it has every approval it needs
+// @Description The approver is the authenticated actor of the request when an authentication middleware set one,
+// @Description the X-Actor header otherwise. The header is trusted as sent: without authentication the maker-checker
+// @Description rule only holds behind a gateway that authenticates the callers and sets the header itself.
+// @ID approve-bulk-transfer
+// @Tags transfer
+// @Produce json
+// @Param id path int true "bulk transfer id"
+// @Param X-Actor header string true "who approves the bulk transfer, trusted as sent unless the request is authenticated"
+// @Param data body domain.BulkTransferApproval false "approval data"
+// @Success 200 {object} domain.BulkTransferDetail
+// @Failure 400 {string}  string
+// @Failure 403 {string}  string
+// @Failure 404 {string}  string
+// @Failure 409 {string}  string
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/transfer/bulk/{id}/approve [post]
+func (h handler) approve(w http.ResponseWriter, r *http.Request) {
+
+	actor := middleware.Actor(r)
+	if actor == "" {
+		tools.WriteError(w, http.StatusBadRequest, errMissingActor)
+		return
+	}
+
+	// the comment is optional, and so is the body
+	var approval domain.BulkTransferApproval
+
+	if err := json.NewDecoder(r.Body).Decode(&approval); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.WithError(err).Error("error parsing message body")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.NewRejection(domain.RejectionInvalidBody, err))
+		return
+	}
+
+	id, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	detail, err := h.transferService.Approve(uint(id), approval, actor)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error approving bulk transfer with id %d", id))
+		writeDecisionError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// @Summary reject a bulk transfer pending approval
+// @Description The approver is the authenticated actor of the request when an authentication middleware set one,
+// @Description the X-Actor header otherwise. The header is trusted as sent: without authentication the maker-checker
+// @Description rule only holds behind a gateway that authenticates the callers and sets the header itself.
+// @ID reject-bulk-transfer
+// @Tags transfer
+// @Produce json
+// @Param id path int true "bulk transfer id"
+// @Param X-Actor header string true "who rejects the bulk transfer, trusted as sent unless the request is authenticated"
+// @Param data body domain.BulkTransferRejection true "rejection data"
+// @Success 200 {object} domain.BulkTransferDetail
+// @Failure 400 {string}  string
+// @Failure 403 {string}  string
+// @Failure 404 {string}  string
+// @Failure 409 {string}  string
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/transfer/bulk/{id}/reject [post]
+func (h handler) reject(w http.ResponseWriter, r *http.Request) {
+
+	actor := middleware.Actor(r)
+	if actor == "" {
+		tools.WriteError(w, http.StatusBadRequest, errMissingActor)
+		return
+	}
+
+	var rejection domain.BulkTransferRejection
+
+	if err := json.NewDecoder(r.Body).Decode(&rejection); err != nil {
+		h.logger.WithError(err).Error("error parsing message body")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.NewRejection(domain.RejectionInvalidBody, err))
+		return
+	}
+
+	if err := rejection.Validate(); err != nil {
+		h.logger.WithError(err).Error("Missing mandatory fields")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.AsRejection(err))
+		return
+	}
+
+	id, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	detail, err := h.transferService.Reject(uint(id), rejection, actor)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error rejecting bulk transfer with id %d", id))
+		writeDecisionError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// @Summary release a bulk transfer held for review, it is executed without screening its counterparties again
+// @ID release-bulk-transfer
+// @Tags transfer
+// @Produce json
+// @Param id path int true "bulk transfer id"
+// @Param X-Actor header string true "who releases the bulk transfer"
+// @Param data body domain.BulkTransferRelease false "release data"
+// @Success 200 {object} domain.BulkTransferDetail
+// @Failure 400 {string}  string
+// @Failure 403 {string}  string
+// @Failure 404 {string}  string
+// @Failure 409 {string}  string
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/transfer/bulk/{id}/release [post]
+func (h handler) release(w http.ResponseWriter, r *http.Request) {
+
+	actor := middleware.Actor(r)
+	if actor == "" {
+		tools.WriteError(w, http.StatusBadRequest, errMissingActor)
+		return
+	}
+
+	// the comment is optional, and so is the body
+	var release domain.BulkTransferRelease
+
+	if err := json.NewDecoder(r.Body).Decode(&release); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.WithError(err).Error("error parsing message body")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.NewRejection(domain.RejectionInvalidBody, err))
+		return
+	}
+
+	id, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	detail, err := h.transferService.Release(uint(id), release, actor)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error releasing bulk transfer with id %d", id))
+		writeDecisionError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// @Summary block a bulk transfer held for review, it is never executed
+// @ID block-bulk-transfer
+// @Tags transfer
+// @Produce json
+// @Param id path int true "bulk transfer id"
+// @Param X-Actor header string true "who blocks the bulk transfer"
+// @Param data body domain.BulkTransferBlock true "block data"
+// @Success 200 {object} domain.BulkTransferDetail
+// @Failure 400 {string}  string
+// @Failure 403 {string}  string
+// @Failure 404 {string}  string
+// @Failure 409 {string}  string
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/transfer/bulk/{id}/block [post]
+func (h handler) block(w http.ResponseWriter, r *http.Request) {
+
+	actor := middleware.Actor(r)
+	if actor == "" {
+		tools.WriteError(w, http.StatusBadRequest, errMissingActor)
+		return
+	}
+
+	var block domain.BulkTransferBlock
+
+	if err := json.NewDecoder(r.Body).Decode(&block); err != nil {
+		h.logger.WithError(err).Error("error parsing message body")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.NewRejection(domain.RejectionInvalidBody, err))
+		return
+	}
+
+	if err := block.Validate(); err != nil {
+		h.logger.WithError(err).Error("Missing mandatory fields")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.AsRejection(err))
+		return
+	}
+
+	id, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	detail, err := h.transferService.Block(uint(id), block, actor)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error blocking bulk transfer with id %d", id))
+		writeDecisionError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// writeTransferError writes the rejection of a bulk transfer, any other error being internal. The Idempotency-Key of
+// an internal error raised before anything was stored is released so the submission can be retried with it.
+func writeTransferError(w http.ResponseWriter, r *http.Request, err error) {
+	var rejection *domain.Rejection
+	switch {
+	case errors.Is(err, transfersvc.ErrMissingSubmitter):
+		tools.WriteError(w, http.StatusBadRequest, err)
+	case errors.As(err, &rejection):
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, rejection)
+	default:
+		if domain.IsNotApplied(err) {
+			middleware.ReleaseIdempotencyKey(r)
+		}
+		tools.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// writeDecisionError writes the error of the approval, the rejection or the review of a bulk transfer
+func writeDecisionError(w http.ResponseWriter, err error) {
+	var rejection *domain.Rejection
+	switch {
+	case errors.Is(err, transfersvc.ErrBulkTransferNotFound):
+		tools.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, transfersvc.ErrSelfApproval), errors.Is(err, transfersvc.ErrNotApprover), errors.Is(err, transfersvc.ErrSelfReview),
+		errors.Is(err, transfersvc.ErrMissingSubmitter):
+		tools.WriteError(w, http.StatusForbidden, err)
+	case errors.Is(err, transfersvc.ErrNotPendingApproval), errors.Is(err, transfersvc.ErrAlreadyDecided), errors.Is(err, transfersvc.ErrNotHeldForReview):
+		tools.WriteError(w, http.StatusConflict, err)
+	case errors.As(err, &rejection):
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, rejection)
+	default:
+		tools.WriteError(w, http.StatusInternalServerError, err)
+	}
 }
@@ -1,10 +1,18 @@
 package transferhdl
 
 import (
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/middleware"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/idempotencyrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/transfersvc"
 	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/repository"
 	"github.com/adrianoccosta/exercise-qonto/test/mocks/services"
+	"github.com/adrianoccosta/exercise-qonto/tools"
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
@@ -12,6 +20,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestTransferHandler(t *testing.T) {
@@ -20,20 +29,21 @@ func TestTransferHandler(t *testing.T) {
 
 	serviceMock := mockservice.NewMockTransferService(ctrl)
 	logMock := mocklog.NewMockLogger(ctrl)
+	idempotency := func(next http.Handler) http.Handler { return next }
 
 	t.Run("Test transfer return success", func(t *testing.T) {
 
 		bulkTransfer := domain.BulkTransfer{
 			OrganizationName: "ACME Corp",
 			OrganizationBic:  "OIVUSCLQXXX",
-			OrganizationIban: "FR10474608000002006107XXXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
 			CreditTransfers: []domain.CreditTransfer{
 				{
-					Amount:           14.53,
+					Amount:           domain.NewMoney(1453, "EUR"),
 					Currency:         "EUR",
 					CounterPartyName: "Bip Bip",
 					CounterPartyBic:  "CRLYFRPPTOU",
-					CounterPartyIban: "EE383680981021245685",
+					CounterPartyIban: "EE303680981021245685",
 					Description:      "Wonderland/4410",
 				},
 			},
@@ -41,21 +51,26 @@ func TestTransferHandler(t *testing.T) {
 
 		serviceMock.EXPECT().
 			BulkTransfer(bulkTransfer).
-			Return(nil).Times(1)
+			Return(domain.BulkTransferDetail{ID: 3, Status: domain.BulkTransferCompleted}, nil).Times(1)
 
-		h := New(serviceMock, logMock)
+		h := New(serviceMock, idempotency, logMock)
 		rr := httptest.NewRecorder()
 		r := mux.NewRouter()
 		h.Handlers(r)
 
-		req, err := http.NewRequest("POST", "/transfer/bulk", strings.NewReader("{\"organization_name\": \"ACME Corp\", \"organization_bic\": \"OIVUSCLQXXX\", \"organization_iban\": \"FR10474608000002006107XXXXX\", \"credit_transfers\": [ { \"amount\": \"14.53\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE383680981021245685\", \"description\": \"Wonderland/4410\"}]}"))
+		req, err := http.NewRequest("POST", "/transfer/bulk", strings.NewReader("{\"organization_name\": \"ACME Corp\", \"organization_bic\": \"OIVUSCLQXXX\", \"organization_iban\": \"FR81474608000002006107XXXXX\", \"credit_transfers\": [ { \"amount\": \"14.53\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE303680981021245685\", \"description\": \"Wonderland/4410\"}]}"))
 		if err != nil {
 			t.Fatal(err)
 		}
 
 		r.ServeHTTP(rr, req)
 		assert.Equal(t, http.StatusCreated, rr.Code)
-		assert.Equal(t, "", rr.Body.String())
+		assert.Equal(t, "/transfer/bulk/3", rr.Header().Get("Location"))
+
+		var res domain.BulkTransferDetail
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, uint(3), res.ID)
+		assert.Equal(t, domain.BulkTransferCompleted, res.Status)
 	})
 
 	t.Run("Test transfer return error when body is wrong", func(t *testing.T) {
@@ -64,7 +79,7 @@ func TestTransferHandler(t *testing.T) {
 		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
 		logMock.EXPECT().Error("error parsing message body").Times(1)
 
-		h := New(serviceMock, logMock)
+		h := New(serviceMock, idempotency, logMock)
 		rr := httptest.NewRecorder()
 		r := mux.NewRouter()
 		h.Handlers(r)
@@ -85,7 +100,7 @@ func TestTransferHandler(t *testing.T) {
 		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
 		logMock.EXPECT().Error("Missing mandatory fields").Times(1)
 
-		h := New(serviceMock, logMock)
+		h := New(serviceMock, idempotency, logMock)
 		rr := httptest.NewRecorder()
 		r := mux.NewRouter()
 		h.Handlers(r)
@@ -100,24 +115,771 @@ func TestTransferHandler(t *testing.T) {
 		assert.NotEmpty(t, rr.Body.String())
 	})
 
-	t.Run("Test transfer return error", func(t *testing.T) {
+	t.Run("Test transfer return the invalid lines", func(t *testing.T) {
 
-		serviceMock.EXPECT().BulkTransfer(gomock.Any()).Return(errors.New("error")).Times(1)
+		serviceMock.EXPECT().BulkTransfer(gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("Missing mandatory fields").Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk", strings.NewReader("{\"organization_name\": \"ACME Corp\", \"organization_bic\": \"OIVUSCLQXXX\", \"organization_iban\": \"FR81474608000002006107XXXXX\", \"credit_transfers\": ["+
+			"{ \"amount\": \"14.53\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE303680981021245685\"},"+
+			"{ \"amount\": \"14.555\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE383680981021245685\"}]}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+		var res struct {
+			Reason string `json:"reason"`
+			Errors []struct {
+				Index *int   `json:"index"`
+				Field string `json:"field"`
+				Rule  string `json:"rule"`
+			} `json:"errors"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, "invalid_fields", res.Reason)
+		assert.Len(t, res.Errors, 2)
+		for _, lineError := range res.Errors {
+			assert.Equal(t, 1, *lineError.Index)
+		}
+		assert.Equal(t, "amount", res.Errors[0].Field)
+		assert.Equal(t, "amount", res.Errors[0].Rule)
+		assert.Equal(t, "counterparty_iban", res.Errors[1].Field)
+		assert.Equal(t, "iban", res.Errors[1].Rule)
+	})
+
+	t.Run("Test transfer executes the valid lines of a best effort bulk transfer", func(t *testing.T) {
+
+		report := domain.NewExecutionReport([]domain.LineOutcome{
+			{Index: 0, Status: domain.LineExecuted, TransactionID: 7},
+			{Index: 1, Status: domain.LineRejected, Reason: domain.RejectionInvalidFields, Message: "amount: too many decimals"},
+		})
+		serviceMock.EXPECT().
+			BulkTransfer(gomock.Any()).
+			DoAndReturn(func(data domain.BulkTransfer) (domain.BulkTransferDetail, error) {
+				assert.Equal(t, domain.ExecutionBestEffort, data.ExecutionMode)
+				assert.Equal(t, 2, data.CreditTransfers[0].Priority)
+				return domain.BulkTransferDetail{ID: 4, Status: domain.BulkTransferPartiallyCompleted, Report: report}, nil
+			}).Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk", strings.NewReader("{\"organization_name\": \"ACME Corp\", \"organization_bic\": \"OIVUSCLQXXX\", \"organization_iban\": \"FR81474608000002006107XXXXX\", \"execution_mode\": \"best_effort\", \"credit_transfers\": ["+
+			"{ \"amount\": \"14.53\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE303680981021245685\", \"priority\": 2},"+
+			"{ \"amount\": \"14.555\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE303680981021245685\"}]}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusMultiStatus, rr.Code)
+		assert.Equal(t, "/transfer/bulk/4", rr.Header().Get("Location"))
+
+		var res domain.BulkTransferDetail
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, domain.BulkTransferPartiallyCompleted, res.Status)
+		assert.Equal(t, report, res.Report)
+	})
+
+	t.Run("Test transfer return error when a best effort bulk transfer has invalid fields of its own", func(t *testing.T) {
+
+		serviceMock.EXPECT().BulkTransfer(gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("Missing mandatory fields").Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk", strings.NewReader("{\"organization_name\": \"ACME Corp\", \"organization_bic\": \"OIVUSCLQXXX\", \"execution_mode\": \"best_effort\", \"credit_transfers\": ["+
+			"{ \"amount\": \"14.555\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE303680981021245685\"}]}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Contains(t, rr.Body.String(), "organization_iban")
+	})
+
+	t.Run("Test transfer return the required and available amounts when funds are not enough", func(t *testing.T) {
+
+		available := domain.NewMoney(1450, "EUR")
+		serviceMock.EXPECT().
+			BulkTransfer(gomock.Any()).
+			Return(domain.BulkTransferDetail{}, domain.NewInsufficientFundsRejection(errors.New("Insufficient credits to complete the transfer"), domain.NewMoney(1453, "EUR"), &available)).
+			Times(1)
 		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
 		logMock.EXPECT().Error("error registering bulk transfer").Times(1)
 
-		h := New(serviceMock, logMock)
+		h := New(serviceMock, idempotency, logMock)
 		rr := httptest.NewRecorder()
 		r := mux.NewRouter()
 		h.Handlers(r)
 
-		req, err := http.NewRequest("POST", "/transfer/bulk", strings.NewReader("{\"organization_name\": \"ACME Corp\", \"organization_bic\": \"OIVUSCLQXXX\", \"organization_iban\": \"FR10474608000002006107XXXXX\", \"credit_transfers\": [ { \"amount\": \"14.53\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE383680981021245685\", \"description\": \"Wonderland/4410\"}]}"))
+		req, err := http.NewRequest("POST", "/transfer/bulk", strings.NewReader("{\"organization_name\": \"ACME Corp\", \"organization_bic\": \"OIVUSCLQXXX\", \"organization_iban\": \"FR81474608000002006107XXXXX\", \"credit_transfers\": [ { \"amount\": \"14.53\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE303680981021245685\", \"description\": \"Wonderland/4410\"}]}"))
 		if err != nil {
 			t.Fatal(err)
 		}
 
 		r.ServeHTTP(rr, req)
 		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.JSONEq(t, `{"reason": "insufficient_funds", "message": "Insufficient credits to complete the transfer", "required_amount": "14.53", "available_amount": "14.50"}`, rr.Body.String())
+	})
+
+	t.Run("Test transfer return error", func(t *testing.T) {
+
+		serviceMock.EXPECT().BulkTransfer(gomock.Any()).Return(domain.BulkTransferDetail{}, errors.New("error")).Times(1)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error registering bulk transfer").Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk", strings.NewReader("{\"organization_name\": \"ACME Corp\", \"organization_bic\": \"OIVUSCLQXXX\", \"organization_iban\": \"FR81474608000002006107XXXXX\", \"credit_transfers\": [ { \"amount\": \"14.53\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE303680981021245685\", \"description\": \"Wonderland/4410\"}]}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
 		assert.NotEmpty(t, rr.Body.String())
 	})
+
+	t.Run("Test transfer failing before any write releases the idempotency key", func(t *testing.T) {
+
+		repoMock := mockrepository.NewMockIdempotencyRepository(ctrl)
+		repoMock.EXPECT().Read("FR81474608000002006107XXXXX", "key-1").Return(idempotencyrepo.IdempotencyKey{}, sql.ErrNoRows)
+		repoMock.EXPECT().Create(gomock.Any()).Return(nil)
+		repoMock.EXPECT().Delete("FR81474608000002006107XXXXX", "key-1").Return(nil)
+		repoMock.EXPECT().Update(gomock.Any()).Times(0)
+		serviceMock.EXPECT().BulkTransfer(gomock.Any()).Return(domain.BulkTransferDetail{}, domain.NotApplied(errors.New("error"))).Times(1)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error registering bulk transfer").Times(1)
+
+		h := New(serviceMock, middleware.Idempotency(repoMock, time.Hour, tools.SystemClock{}, logMock), logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk", strings.NewReader("{\"organization_name\": \"ACME Corp\", \"organization_bic\": \"OIVUSCLQXXX\", \"organization_iban\": \"FR81474608000002006107XXXXX\", \"credit_transfers\": [ { \"amount\": \"14.53\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE303680981021245685\", \"description\": \"Wonderland/4410\"}]}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(tools.HeaderIdempotencyKey, "key-1")
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("Test validate return the quote", func(t *testing.T) {
+
+		balanceAfter := domain.NewMoney(7, "EUR")
+		serviceMock.EXPECT().
+			Quote(gomock.Any()).
+			Return(domain.BulkTransferQuote{
+				OrganizationIban: "FR81474608000002006107XXXXX",
+				TransfersCount:   1,
+				TotalAmount:      domain.NewMoney(1453, "EUR"),
+				BalanceAfter:     &balanceAfter,
+				Executable:       true,
+				Problems:         []*domain.Rejection{},
+			}, nil).Times(1)
+		serviceMock.EXPECT().BulkTransfer(gomock.Any()).Times(0)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk/validate", strings.NewReader("{\"organization_name\": \"ACME Corp\", \"organization_bic\": \"OIVUSCLQXXX\", \"organization_iban\": \"FR81474608000002006107XXXXX\", \"credit_transfers\": [ { \"amount\": \"14.53\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE303680981021245685\", \"description\": \"Wonderland/4410\"}]}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"organization_iban": "FR81474608000002006107XXXXX", "transfers_count": 1, "total_amount": "14.53", "balance_after": "0.07", "executable": true, "problems": []}`, rr.Body.String())
+	})
+
+	t.Run("Test validate return error", func(t *testing.T) {
+
+		serviceMock.EXPECT().Quote(gomock.Any()).Return(domain.BulkTransferQuote{}, errors.New("error")).Times(1)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error checking bulk transfer").Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk/validate", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("Test read return success", func(t *testing.T) {
+
+		serviceMock.EXPECT().
+			Read(uint(3)).
+			Return(domain.BulkTransferDetail{ID: 3, Status: domain.BulkTransferCompleted, TotalAmount: domain.NewMoney(1453, "EUR"), Transactions: domain.TransactionList{{ID: 1, Amount: domain.NewMoney(1453, "EUR"), Currency: "EUR", BulkTransferID: 3}}}, nil).Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("GET", "/transfer/bulk/3", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var res domain.BulkTransferDetail
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, uint(3), res.ID)
+		assert.Len(t, res.Transactions, 1)
+		assert.Equal(t, domain.NewMoney(1453, "EUR"), res.Transactions[0].Amount)
+	})
+
+	t.Run("Test read return error when not found", func(t *testing.T) {
+
+		serviceMock.EXPECT().Read(uint(4)).Return(domain.BulkTransferDetail{}, sql.ErrNoRows).Times(1)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error reading bulk transfer with id 4").Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("GET", "/transfer/bulk/4", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Test read return error", func(t *testing.T) {
+
+		serviceMock.EXPECT().Read(uint(4)).Return(domain.BulkTransferDetail{}, errors.New("error")).Times(1)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error reading bulk transfer with id 4").Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("GET", "/transfer/bulk/4", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("Test list return success", func(t *testing.T) {
+
+		serviceMock.EXPECT().
+			ReadByFilter(map[string]string{"status": "failed"}).
+			Return(domain.BulkTransferDetailList{{ID: 3, Status: domain.BulkTransferFailed}}, nil).Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("GET", "/transfer/bulk?status=failed", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var res domain.BulkTransferDetailList
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Len(t, res, 1)
+	})
+
+	cancel := func(id, actor, body string) *httptest.ResponseRecorder {
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk/"+id+"/cancel", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actor != "" {
+			req.Header.Set("X-Actor", actor)
+		}
+
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Test cancel return success", func(t *testing.T) {
+
+		serviceMock.EXPECT().
+			Cancel(uint(3), domain.BulkTransferCancellation{Reason: "left the company", LineIndexes: []int{1}}, "jane@acme.corp").
+			Return(domain.BulkTransferDetail{ID: 3, Status: domain.BulkTransferScheduled}, nil).Times(1)
+
+		rr := cancel("3", "jane@acme.corp", `{"reason": "left the company", "line_indexes": [1]}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test cancel return error when the actor is missing", func(t *testing.T) {
+
+		serviceMock.EXPECT().Cancel(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		rr := cancel("3", "", `{"reason": "duplicate payroll"}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Test cancel return error when the reason is missing", func(t *testing.T) {
+
+		serviceMock.EXPECT().Cancel(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("Missing mandatory fields").Times(1)
+
+		rr := cancel("3", "jane@acme.corp", `{"line_indexes": [-1]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+		var res domain.Rejection
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Len(t, res.Errors, 2)
+	})
+
+	t.Run("Test cancel return the lines that can't be cancelled", func(t *testing.T) {
+
+		rejection := domain.NewRejection(domain.RejectionInvalidFields, transfersvc.ErrLinesNotCancellable).WithLineError(4, "line_indexes[0]", "exists", "no such line")
+		serviceMock.EXPECT().Cancel(uint(3), gomock.Any(), "jane@acme.corp").Return(domain.BulkTransferDetail{}, rejection).Times(1)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error cancelling bulk transfer with id 3").Times(1)
+
+		rr := cancel("3", "jane@acme.corp", `{"reason": "left the company", "line_indexes": [4]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+		var res domain.Rejection
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, "no such line", res.Errors[0].Message)
+	})
+
+	t.Run("Test cancel return conflict when the bulk transfer was executed", func(t *testing.T) {
+
+		err := fmt.Errorf("%w: it is completed", transfersvc.ErrNotCancellable)
+		serviceMock.EXPECT().Cancel(uint(3), gomock.Any(), "jane@acme.corp").Return(domain.BulkTransferDetail{}, err).Times(1)
+		logMock.EXPECT().WithError(err).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error cancelling bulk transfer with id 3").Times(1)
+
+		rr := cancel("3", "jane@acme.corp", `{"reason": "duplicate payroll"}`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, "bulk transfer can't be cancelled: it is completed", rr.Body.String())
+	})
+
+	t.Run("Test cancel return not found", func(t *testing.T) {
+
+		serviceMock.EXPECT().Cancel(uint(9), gomock.Any(), "jane@acme.corp").Return(domain.BulkTransferDetail{}, transfersvc.ErrBulkTransferNotFound).Times(1)
+		logMock.EXPECT().WithError(transfersvc.ErrBulkTransferNotFound).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error cancelling bulk transfer with id 9").Times(1)
+
+		rr := cancel("9", "jane@acme.corp", `{"reason": "duplicate payroll"}`)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Test transfer passes the submitter on", func(t *testing.T) {
+
+		serviceMock.EXPECT().
+			BulkTransfer(gomock.Any()).
+			DoAndReturn(func(data domain.BulkTransfer) (domain.BulkTransferDetail, error) {
+				assert.Equal(t, "john@acme.corp", data.SubmittedBy)
+				return domain.BulkTransferDetail{ID: 3, Status: domain.BulkTransferPendingApproval}, nil
+			}).Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk", strings.NewReader(`{"organization_name": "ACME Corp", "organization_bic": "OIVUSCLQXXX", "organization_iban": "FR81474608000002006107XXXXX",
+			"credit_transfers": [{"amount": "14.53", "currency": "EUR", "counterparty_name": "Bip Bip", "counterparty_bic": "CRLYFRPPTOU", "counterparty_iban": "EE303680981021245685"}]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Actor", "john@acme.corp")
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("Test transfer return error when the submitter of a bulk transfer needing approval is missing", func(t *testing.T) {
+
+		serviceMock.EXPECT().BulkTransfer(gomock.Any()).Return(domain.BulkTransferDetail{}, transfersvc.ErrMissingSubmitter).Times(1)
+		logMock.EXPECT().WithError(transfersvc.ErrMissingSubmitter).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error registering bulk transfer").Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk", strings.NewReader(`{"organization_name": "ACME Corp", "organization_bic": "OIVUSCLQXXX", "organization_iban": "FR81474608000002006107XXXXX",
+			"credit_transfers": [{"amount": "14.53", "currency": "EUR", "counterparty_name": "Bip Bip", "counterparty_bic": "CRLYFRPPTOU", "counterparty_iban": "EE303680981021245685"}]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	decide := func(id, decision, actor, body string) *httptest.ResponseRecorder {
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk/"+id+"/"+decision, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actor != "" {
+			req.Header.Set("X-Actor", actor)
+		}
+
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Test approve return success", func(t *testing.T) {
+
+		serviceMock.EXPECT().
+			Approve(uint(3), domain.BulkTransferApproval{Comment: "checked"}, "jane@acme.corp").
+			Return(domain.BulkTransferDetail{ID: 3, Status: domain.BulkTransferCompleted}, nil).Times(1)
+
+		rr := decide("3", "approve", "jane@acme.corp", `{"comment": "checked"}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test approve return success without body", func(t *testing.T) {
+
+		serviceMock.EXPECT().
+			Approve(uint(3), domain.BulkTransferApproval{}, "jane@acme.corp").
+			Return(domain.BulkTransferDetail{ID: 3, Status: domain.BulkTransferPendingApproval}, nil).Times(1)
+
+		rr := decide("3", "approve", "jane@acme.corp", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test approve takes the approver from the authenticated actor rather than the header", func(t *testing.T) {
+
+		serviceMock.EXPECT().
+			Approve(uint(3), domain.BulkTransferApproval{}, "jane@acme.corp").
+			Return(domain.BulkTransferDetail{ID: 3, Status: domain.BulkTransferPendingApproval}, nil).Times(1)
+
+		authenticated := func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(middleware.WithActor(r.Context(), "jane@acme.corp")))
+			})
+		}
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		r.Use(authenticated)
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk/3/approve", strings.NewReader(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Actor", "john@acme.corp")
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test approve return error when the actor is missing", func(t *testing.T) {
+
+		serviceMock.EXPECT().Approve(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		rr := decide("3", "approve", "", "")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Test approve return forbidden when the submitter approves", func(t *testing.T) {
+
+		serviceMock.EXPECT().Approve(uint(3), gomock.Any(), "john@acme.corp").Return(domain.BulkTransferDetail{}, transfersvc.ErrSelfApproval).Times(1)
+		logMock.EXPECT().WithError(transfersvc.ErrSelfApproval).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error approving bulk transfer with id 3").Times(1)
+
+		rr := decide("3", "approve", "john@acme.corp", "")
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("Test approve return forbidden when the submitter is unknown", func(t *testing.T) {
+
+		serviceMock.EXPECT().Approve(uint(3), gomock.Any(), "jane@acme.corp").Return(domain.BulkTransferDetail{}, transfersvc.ErrMissingSubmitter).Times(1)
+		logMock.EXPECT().WithError(transfersvc.ErrMissingSubmitter).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error approving bulk transfer with id 3").Times(1)
+
+		rr := decide("3", "approve", "jane@acme.corp", "")
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("Test approve return conflict when the approver already decided", func(t *testing.T) {
+
+		err := fmt.Errorf("%w: it was approved", transfersvc.ErrAlreadyDecided)
+		serviceMock.EXPECT().Approve(uint(3), gomock.Any(), "jane@acme.corp").Return(domain.BulkTransferDetail{}, err).Times(1)
+		logMock.EXPECT().WithError(err).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error approving bulk transfer with id 3").Times(1)
+
+		rr := decide("3", "approve", "jane@acme.corp", "")
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Test approve return the rejection when funds are not enough", func(t *testing.T) {
+
+		rejection := domain.NewInsufficientFundsRejection(transfersvc.ErrInsufficientFunds, domain.NewMoney(1453, "EUR"), nil)
+		serviceMock.EXPECT().Approve(uint(3), gomock.Any(), "jane@acme.corp").Return(domain.BulkTransferDetail{}, rejection).Times(1)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error approving bulk transfer with id 3").Times(1)
+
+		rr := decide("3", "approve", "jane@acme.corp", "")
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.JSONEq(t, `{"reason": "insufficient_funds", "message": "Insufficient credits to complete the transfer", "required_amount": "14.53"}`, rr.Body.String())
+	})
+
+	t.Run("Test reject return success", func(t *testing.T) {
+
+		serviceMock.EXPECT().
+			Reject(uint(3), domain.BulkTransferRejection{Reason: "wrong month"}, "jane@acme.corp").
+			Return(domain.BulkTransferDetail{ID: 3, Status: domain.BulkTransferRejected}, nil).Times(1)
+
+		rr := decide("3", "reject", "jane@acme.corp", `{"reason": "wrong month"}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test reject return error when the reason is missing", func(t *testing.T) {
+
+		serviceMock.EXPECT().Reject(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("Missing mandatory fields").Times(1)
+
+		rr := decide("3", "reject", "jane@acme.corp", `{}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("Test reject return forbidden when the actor isn't an approver", func(t *testing.T) {
+
+		err := fmt.Errorf("%w: joe@acme.corp", transfersvc.ErrNotApprover)
+		serviceMock.EXPECT().Reject(uint(3), gomock.Any(), "joe@acme.corp").Return(domain.BulkTransferDetail{}, err).Times(1)
+		logMock.EXPECT().WithError(err).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error rejecting bulk transfer with id 3").Times(1)
+
+		rr := decide("3", "reject", "joe@acme.corp", `{"reason": "wrong month"}`)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("Test reject return conflict when the bulk transfer isn't pending approval", func(t *testing.T) {
+
+		err := fmt.Errorf("%w: it is completed", transfersvc.ErrNotPendingApproval)
+		serviceMock.EXPECT().Reject(uint(3), gomock.Any(), "jane@acme.corp").Return(domain.BulkTransferDetail{}, err).Times(1)
+		logMock.EXPECT().WithError(err).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error rejecting bulk transfer with id 3").Times(1)
+
+		rr := decide("3", "reject", "jane@acme.corp", `{"reason": "wrong month"}`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, "bulk transfer isn't pending approval: it is completed", rr.Body.String())
+	})
+
+	t.Run("Test release return success without body", func(t *testing.T) {
+
+		serviceMock.EXPECT().
+			Release(uint(3), domain.BulkTransferRelease{}, "compliance@acme.corp").
+			Return(domain.BulkTransferDetail{ID: 3, Status: domain.BulkTransferCompleted}, nil).Times(1)
+
+		rr := decide("3", "release", "compliance@acme.corp", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test release return forbidden when the submitter releases", func(t *testing.T) {
+
+		serviceMock.EXPECT().Release(uint(3), gomock.Any(), "john@acme.corp").Return(domain.BulkTransferDetail{}, transfersvc.ErrSelfReview).Times(1)
+		logMock.EXPECT().WithError(transfersvc.ErrSelfReview).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error releasing bulk transfer with id 3").Times(1)
+
+		rr := decide("3", "release", "john@acme.corp", `{"comment": "namesake"}`)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("Test block return success", func(t *testing.T) {
+
+		serviceMock.EXPECT().
+			Block(uint(3), domain.BulkTransferBlock{Reason: "confirmed match"}, "compliance@acme.corp").
+			Return(domain.BulkTransferDetail{ID: 3, Status: domain.BulkTransferBlocked}, nil).Times(1)
+
+		rr := decide("3", "block", "compliance@acme.corp", `{"reason": "confirmed match"}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test block return error when the actor is missing", func(t *testing.T) {
+
+		serviceMock.EXPECT().Block(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		rr := decide("3", "block", "", `{"reason": "confirmed match"}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Test block return conflict when the bulk transfer isn't held for review", func(t *testing.T) {
+
+		err := fmt.Errorf("%w: it is completed", transfersvc.ErrNotHeldForReview)
+		serviceMock.EXPECT().Block(uint(3), gomock.Any(), "compliance@acme.corp").Return(domain.BulkTransferDetail{}, err).Times(1)
+		logMock.EXPECT().WithError(err).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error blocking bulk transfer with id 3").Times(1)
+
+		rr := decide("3", "block", "compliance@acme.corp", `{"reason": "confirmed match"}`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Test submit return the queued job", func(t *testing.T) {
+
+		serviceMock.EXPECT().
+			Submit(gomock.Any()).
+			DoAndReturn(func(data domain.BulkTransfer) (domain.BulkTransferJob, error) {
+				assert.Equal(t, "john@acme.corp", data.SubmittedBy)
+				assert.Len(t, data.CreditTransfers, 1)
+				return domain.BulkTransferJob{ID: 5, BulkTransferID: 3, Status: domain.JobQueued, LinesTotal: 1}, nil
+			}).Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk/jobs", strings.NewReader("{\"organization_name\": \"ACME Corp\", \"organization_bic\": \"OIVUSCLQXXX\", \"organization_iban\": \"FR81474608000002006107XXXXX\", \"credit_transfers\": [ { \"amount\": \"14.53\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE303680981021245685\", \"description\": \"Wonderland/4410\"}]}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Actor", "john@acme.corp")
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, "/transfer/bulk/jobs/5", rr.Header().Get("Location"))
+
+		var res domain.BulkTransferJob
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, uint(5), res.ID)
+		assert.Equal(t, uint(3), res.BulkTransferID)
+		assert.Equal(t, domain.JobQueued, res.Status)
+	})
+
+	t.Run("Test submit return error when missing mandatory fields", func(t *testing.T) {
+
+		serviceMock.EXPECT().Submit(gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("Missing mandatory fields").Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk/jobs", strings.NewReader("{\"organization_name\": \"ACME Corp\"}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("Test submit return error", func(t *testing.T) {
+
+		serviceMock.EXPECT().Submit(gomock.Any()).Return(domain.BulkTransferJob{}, errors.New("error")).Times(1)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("error submitting bulk transfer").Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("POST", "/transfer/bulk/jobs", strings.NewReader("{\"organization_name\": \"ACME Corp\", \"organization_bic\": \"OIVUSCLQXXX\", \"organization_iban\": \"FR81474608000002006107XXXXX\", \"credit_transfers\": [ { \"amount\": \"14.53\", \"currency\": \"EUR\", \"counterparty_name\": \"Bip Bip\", \"counterparty_bic\": \"CRLYFRPPTOU\", \"counterparty_iban\": \"EE303680981021245685\", \"description\": \"Wonderland/4410\"}]}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("Test readJob return the progress of the job", func(t *testing.T) {
+
+		serviceMock.EXPECT().
+			ReadJob(uint(5)).
+			Return(domain.BulkTransferJob{ID: 5, BulkTransferID: 3, Status: domain.JobRunning, LinesTotal: 10, LinesDone: 4}, nil).Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("GET", "/transfer/bulk/jobs/5", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var res domain.BulkTransferJob
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, domain.JobRunning, res.Status)
+		assert.Equal(t, 4, res.LinesDone)
+		assert.Equal(t, 10, res.LinesTotal)
+	})
+
+	t.Run("Test readJob return not found", func(t *testing.T) {
+
+		serviceMock.EXPECT().ReadJob(uint(6)).Return(domain.BulkTransferJob{}, transfersvc.ErrJobNotFound).Times(1)
+
+		h := New(serviceMock, idempotency, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest("GET", "/transfer/bulk/jobs/6", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
 }
@@ -0,0 +1,221 @@
+package webhookhdl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/webhooksvc"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+const (
+	pathSelection           = "/webhooks"
+	pathSelectionID         = "/webhooks/{id:[0-9]+}"
+	pathSelectionDeliveries = "/webhooks/{id:[0-9]+}/deliveries"
+	pathSelectionReplay     = "/webhooks/{id:[0-9]+}/deliveries/{delivery_id:[0-9]+}/replay"
+)
+
+// Handler defines the handler interface
+type Handler interface {
+	Handlers(r *mux.Router)
+}
+
+// New returns an implementation of the webhook handler
+func New(webhookService webhooksvc.WebhookService, logger log.Logger) Handler {
+	return handler{
+		logger:         logger,
+		webhookService: webhookService,
+	}
+}
+
+type handler struct {
+	logger         log.Logger
+	webhookService webhooksvc.WebhookService
+}
+
+func (h handler) Handlers(r *mux.Router) {
+	// handlers
+	r.HandleFunc(pathSelection, h.create).Methods(http.MethodPost)
+	r.HandleFunc(pathSelection, h.list).Methods(http.MethodGet)
+	r.HandleFunc(pathSelectionID, h.read).Methods(http.MethodGet)
+	r.HandleFunc(pathSelectionID, h.delete).Methods(http.MethodDelete)
+	r.HandleFunc(pathSelectionDeliveries, h.deliveries).Methods(http.MethodGet)
+	r.HandleFunc(pathSelectionReplay, h.replay).Methods(http.MethodPost)
+}
+
+// @Summary subscribe a bank account to events, the secret signing their deliveries is only returned here
+// @ID create-webhook
+// @Tags webhook
+// @Produce json
+// @Param data body domain.Webhook true "webhook data"
+// @Success 201 {object} domain.WebhookDetail
+// @Failure 422 {object} domain.Rejection
+// @Failure 500 {string}  string
+// @Router /v1/webhooks [post]
+func (h handler) create(w http.ResponseWriter, r *http.Request) {
+	var webhook domain.Webhook
+
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		h.logger.WithError(err).Error("error parsing message body")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.NewRejection(domain.RejectionInvalidBody, err))
+		return
+	}
+
+	if err := webhook.Validate(); err != nil {
+		h.logger.WithError(err).Error("Missing mandatory fields")
+		tools.WriteJSON(w, http.StatusUnprocessableEntity, domain.AsRejection(err))
+		return
+	}
+
+	detail, err := h.webhookService.Create(webhook)
+	if err != nil {
+		h.logger.WithError(err).Error("error registering webhook")
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, detail.ID))
+	tools.WriteJSON(w, http.StatusCreated, detail)
+}
+
+// @Summary read a webhook
+// @ID read-webhook
+// @Tags webhook
+// @Produce json
+// @Param id path int true "webhook id"
+// @Success 200 {object} domain.WebhookDetail
+// @Failure 404 {string}  string
+// @Router /v1/webhooks/{id} [get]
+func (h handler) read(w http.ResponseWriter, r *http.Request) {
+
+	id := pathID(r, "id")
+	detail, err := h.webhookService.Read(id)
+
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error reading webhook with id %d", id))
+		writeError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, detail)
+}
+
+// @Summary list the webhooks of a bank account
+// @ID read-webhooks
+// @Tags webhook
+// @Produce json
+// @Param organization_iban query string true "iban of the bank account"
+// @Success 200 {array} domain.WebhookDetail
+// @Failure 400 {string}  string
+// @Router /v1/webhooks [get]
+func (h handler) list(w http.ResponseWriter, r *http.Request) {
+	queries := r.URL.Query()
+
+	filters := make(map[string]string)
+	for k, v := range queries {
+		if len(v) > 0 {
+			filters[k] = v[0]
+		}
+	}
+
+	list, err := h.webhookService.ReadByFilter(filters)
+
+	if err != nil {
+		h.logger.WithError(err).Error("error reading webhooks")
+		tools.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, list)
+}
+
+// @Summary delete a webhook, it is no longer notified and its pending deliveries fail
+// @ID delete-webhook
+// @Tags webhook
+// @Produce json
+// @Param id path int true "webhook id"
+// @Success 200 {string}  string
+// @Failure 404 {string}  string
+// @Failure 500 {string}  string
+// @Router /v1/webhooks/{id} [delete]
+func (h handler) delete(w http.ResponseWriter, r *http.Request) {
+
+	id := pathID(r, "id")
+	if err := h.webhookService.Delete(id); err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error deleting webhook with id %d", id))
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// @Summary the delivery log of a webhook, latest first
+// @ID read-webhook-deliveries
+// @Tags webhook
+// @Produce json
+// @Param id path int true "webhook id"
+// @Success 200 {array} domain.WebhookDelivery
+// @Failure 404 {string}  string
+// @Failure 500 {string}  string
+// @Router /v1/webhooks/{id}/deliveries [get]
+func (h handler) deliveries(w http.ResponseWriter, r *http.Request) {
+
+	id := pathID(r, "id")
+	list, err := h.webhookService.ReadDeliveries(id)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error reading deliveries of webhook with id %d", id))
+		writeError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, list)
+}
+
+// @Summary deliver again the payload of a delivered or failed delivery, as a new delivery
+// @ID replay-webhook-delivery
+// @Tags webhook
+// @Produce json
+// @Param id path int true "webhook id"
+// @Param delivery_id path int true "delivery id"
+// @Success 202 {object} domain.WebhookDelivery
+// @Failure 404 {string}  string
+// @Failure 409 {string}  string
+// @Failure 500 {string}  string
+// @Router /v1/webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func (h handler) replay(w http.ResponseWriter, r *http.Request) {
+
+	id, deliveryID := pathID(r, "id"), pathID(r, "delivery_id")
+	delivery, err := h.webhookService.Replay(id, deliveryID)
+	if err != nil {
+		h.logger.WithError(err).Error(fmt.Sprintf("error replaying delivery %d of webhook with id %d", deliveryID, id))
+		writeError(w, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusAccepted, delivery)
+}
+
+// writeError writes the status matching the service error
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhooksvc.ErrWebhookNotFound), errors.Is(err, webhooksvc.ErrDeliveryNotFound):
+		tools.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, webhooksvc.ErrDeliveryPending):
+		tools.WriteError(w, http.StatusConflict, err)
+	case errors.Is(err, webhooksvc.ErrAccountNotFound):
+		tools.WriteError(w, http.StatusUnprocessableEntity, err)
+	default:
+		tools.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func pathID(r *http.Request, name string) uint {
+	id, _ := strconv.ParseUint(mux.Vars(r)[name], 10, 64)
+	return uint(id)
+}
@@ -0,0 +1,204 @@
+package webhookhdl
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/webhooksvc"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/services"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const webhookBody = `{"organization_iban": "FR81474608000002006107XXXXX", "url": "https://erp.acme.corp/hooks", "events": ["bulk_transfer.completed"]}`
+
+func TestWebhookHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	serviceMock := mockservice.NewMockWebhookService(ctrl)
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		h := New(serviceMock, logMock)
+		rr := httptest.NewRecorder()
+		r := mux.NewRouter()
+		h.Handlers(r)
+
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	webhook := domain.Webhook{
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		URL:              "https://erp.acme.corp/hooks",
+		Events:           []domain.WebhookEvent{domain.WebhookBulkTransferCompleted},
+	}
+
+	detail := domain.WebhookDetail{
+		ID:               3,
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		URL:              "https://erp.acme.corp/hooks",
+		Events:           []domain.WebhookEvent{domain.WebhookBulkTransferCompleted},
+		Secret:           "whsec_5ec2e7",
+		CreatedAt:        time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC),
+	}
+
+	delivery := domain.WebhookDelivery{
+		ID:        8,
+		WebhookID: 3,
+		Event:     domain.WebhookBulkTransferCompleted,
+		Payload:   json.RawMessage(`{"event":"bulk_transfer.completed"}`),
+		Status:    domain.DeliveryPending,
+		ReplayOf:  7,
+		CreatedAt: time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Test create return success", func(t *testing.T) {
+		serviceMock.EXPECT().Create(webhook).Return(detail, nil)
+
+		rr := serve("POST", "/webhooks", webhookBody)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "/webhooks/3", rr.Header().Get("Location"))
+
+		var res domain.WebhookDetail
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, detail, res)
+	})
+
+	t.Run("Test create return the invalid fields", func(t *testing.T) {
+		serviceMock.EXPECT().Create(gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("Missing mandatory fields")
+
+		rr := serve("POST", "/webhooks", strings.Replace(webhookBody, "https://", "ftp://", 1))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.JSONEq(t, `{"reason": "invalid_fields", "message": "invalid fields: url \"ftp://erp.acme.corp/hooks\" is not a valid http or https URL",
+			"errors": [{"field": "url", "rule": "http_url", "message": "\"ftp://erp.acme.corp/hooks\" is not a valid http or https URL"}]}`, rr.Body.String())
+	})
+
+	t.Run("Test create return error when body is wrong", func(t *testing.T) {
+		serviceMock.EXPECT().Create(gomock.Any()).Times(0)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error parsing message body")
+
+		rr := serve("POST", "/webhooks", `{ "test":`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("Test create return error when the bank account doesn't exist", func(t *testing.T) {
+		serviceMock.EXPECT().Create(webhook).Return(domain.WebhookDetail{}, webhooksvc.ErrAccountNotFound)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error registering webhook")
+
+		rr := serve("POST", "/webhooks", webhookBody)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("Test read return not found", func(t *testing.T) {
+		serviceMock.EXPECT().Read(uint(3)).Return(domain.WebhookDetail{}, webhooksvc.ErrWebhookNotFound)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error reading webhook with id 3")
+
+		rr := serve("GET", "/webhooks/3", "")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Test list return the webhooks of the bank account", func(t *testing.T) {
+		detail := detail
+		detail.Secret = ""
+		serviceMock.EXPECT().
+			ReadByFilter(map[string]string{"organization_iban": "FR81474608000002006107XXXXX"}).
+			Return(domain.WebhookDetailList{detail}, nil)
+
+		rr := serve("GET", "/webhooks?organization_iban=FR81474608000002006107XXXXX", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "secret")
+	})
+
+	t.Run("Test list return error without the bank account", func(t *testing.T) {
+		serviceMock.EXPECT().ReadByFilter(map[string]string{}).Return(nil, webhooksvc.ErrMissingAccount)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error reading webhooks")
+
+		rr := serve("GET", "/webhooks", "")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Test delete return success", func(t *testing.T) {
+		serviceMock.EXPECT().Delete(uint(3)).Return(nil)
+
+		rr := serve("DELETE", "/webhooks/3", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Test delete return error", func(t *testing.T) {
+		serviceMock.EXPECT().Delete(uint(3)).Return(errors.New("error"))
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error deleting webhook with id 3")
+
+		rr := serve("DELETE", "/webhooks/3", "")
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("Test deliveries return the delivery log", func(t *testing.T) {
+		serviceMock.EXPECT().ReadDeliveries(uint(3)).Return(domain.WebhookDeliveryList{delivery}, nil)
+
+		rr := serve("GET", "/webhooks/3/deliveries", "")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var res domain.WebhookDeliveryList
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, domain.WebhookDeliveryList{delivery}, res)
+	})
+
+	t.Run("Test replay return accepted", func(t *testing.T) {
+		serviceMock.EXPECT().Replay(uint(3), uint(7)).Return(delivery, nil)
+
+		rr := serve("POST", "/webhooks/3/deliveries/7/replay", "")
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+	})
+
+	t.Run("Test replay return conflict when the delivery is pending", func(t *testing.T) {
+		serviceMock.EXPECT().Replay(uint(3), uint(7)).Return(domain.WebhookDelivery{}, webhooksvc.ErrDeliveryPending)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error replaying delivery 7 of webhook with id 3")
+
+		rr := serve("POST", "/webhooks/3/deliveries/7/replay", "")
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Test replay return not found when the delivery isn't one of the webhook", func(t *testing.T) {
+		serviceMock.EXPECT().Replay(uint(3), uint(7)).Return(domain.WebhookDelivery{}, webhooksvc.ErrDeliveryNotFound)
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Error("error replaying delivery 7 of webhook with id 3")
+
+		rr := serve("POST", "/webhooks/3/deliveries/7/replay", "")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
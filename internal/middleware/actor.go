@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"context"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"net/http"
+)
+
+// actorKey the context key of the authenticated actor of the request
+type actorKey struct{}
+
+// WithActor returns a copy of the context carrying the actor an authentication middleware verified
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor who performs the request: the authenticated actor of its context when there is one, the X-Actor header
+// otherwise. The header is declared by the client and trusted as is, the service doesn't authenticate its callers:
+// without an authentication middleware calling WithActor, it must run behind a gateway that authenticates them and
+// sets the header, dropping the one they sent.
+func Actor(r *http.Request) string {
+	if actor, ok := r.Context().Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return r.Header.Get(tools.HeaderActor)
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestActor(t *testing.T) {
+	t.Run("Test actor is taken from the X-Actor header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/transfer/bulk/1/approve", nil)
+		req.Header.Set(tools.HeaderActor, "alice")
+
+		assert.Equal(t, "alice", Actor(req))
+	})
+
+	t.Run("Test authenticated actor wins over the X-Actor header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/transfer/bulk/1/approve", nil)
+		req.Header.Set(tools.HeaderActor, "mallory")
+		req = req.WithContext(WithActor(req.Context(), "alice"))
+
+		assert.Equal(t, "alice", Actor(req))
+	})
+
+	t.Run("Test request without actor", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/transfer/bulk/1/approve", nil)
+
+		assert.Empty(t, Actor(req))
+	})
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/idempotencyrepo"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	errIdempotencyKeyReused     = errors.New("Idempotency-Key was already used with a different request")
+	errIdempotencyKeyInProgress = errors.New("a request with the same Idempotency-Key is still being processed")
+)
+
+// releaseKey the context key of the flag releasing the Idempotency-Key of the request
+type releaseKey struct{}
+
+// Idempotency returns a middleware that deduplicates requests carrying the Idempotency-Key header.
+// The first request with a key is executed and its response stored; a replay with the same payload
+// gets the stored status and body back, while reusing the key with a different payload returns 409.
+// Server errors are stored as well, as the request may have been applied before failing, unless the
+// handler released the key with ReleaseIdempotencyKey. Keys are scoped by the account the request acts on, so
+// two organizations can use the same key, and expire after the retention period.
+func Idempotency(repo idempotencyrepo.IdempotencyRepository, retention time.Duration, clock tools.Clock, logger log.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(tools.HeaderIdempotencyKey)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				logger.WithError(err).Error("error reading message body")
+				tools.WriteError(w, http.StatusBadRequest, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			now := clock.Now().UTC()
+			scope := idempotencyScope(r, body)
+			hash := requestHash(r, body)
+			stored, err := repo.Read(scope, key)
+			switch {
+			case err == nil && !stored.CreatedAt.Before(now.Add(-retention)):
+				replay(w, stored, hash)
+				return
+			case err == nil:
+				// expired but not purged yet
+				if err = repo.Delete(scope, key); err != nil {
+					logger.WithError(err).Error("error removing expired idempotency key")
+					tools.WriteError(w, http.StatusInternalServerError, err)
+					return
+				}
+			case !errors.Is(err, sql.ErrNoRows):
+				logger.WithError(err).Error("error reading idempotency key")
+				tools.WriteError(w, http.StatusInternalServerError, err)
+				return
+			}
+
+			err = repo.Create(idempotencyrepo.IdempotencyKey{Scope: scope, Key: key, RequestHash: hash, CreatedAt: now})
+			if errors.Is(err, idempotencyrepo.ErrAlreadyExists) {
+				tools.WriteError(w, http.StatusConflict, errIdempotencyKeyInProgress)
+				return
+			}
+			if err != nil {
+				logger.WithError(err).Error("error registering idempotency key")
+				tools.WriteError(w, http.StatusInternalServerError, err)
+				return
+			}
+
+			released := false
+			r = r.WithContext(context.WithValue(r.Context(), releaseKey{}, &released))
+
+			var buf bytes.Buffer
+			lrw := WrapResponseWriter(w, &buf)
+			next.ServeHTTP(lrw, r)
+
+			status := lrw.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if status >= http.StatusInternalServerError && released {
+				err = repo.Delete(scope, key)
+			} else {
+				err = repo.Update(idempotencyrepo.IdempotencyKey{
+					Scope:        scope,
+					Key:          key,
+					StatusCode:   status,
+					ContentType:  lrw.Header().Get(tools.HeaderContentType),
+					ResponseBody: buf.Bytes(),
+				})
+			}
+			if err != nil {
+				logger.WithError(err).Error("error storing idempotent response", zap.String("idempotency_key", key))
+			}
+		})
+	}
+}
+
+// PurgeIdempotencyKeys returns the scheduled job removing the idempotency keys older than the retention period
+func PurgeIdempotencyKeys(repo idempotencyrepo.IdempotencyRepository, retention time.Duration, clock tools.Clock) func() (int, error) {
+	return func() (int, error) {
+		purged, err := repo.DeleteExpired(clock.Now().UTC().Add(-retention))
+		return int(purged), err
+	}
+}
+
+// ReleaseIdempotencyKey releases the Idempotency-Key of a request failing with a server error before anything
+// was written, so the client can retry it with the same key. It does nothing for requests without a key.
+func ReleaseIdempotencyKey(r *http.Request) {
+	if released, ok := r.Context().Value(releaseKey{}).(*bool); ok {
+		*released = true
+	}
+}
+
+func replay(w http.ResponseWriter, stored idempotencyrepo.IdempotencyKey, hash string) {
+	if stored.RequestHash != hash {
+		tools.WriteError(w, http.StatusConflict, errIdempotencyKeyReused)
+		return
+	}
+	if stored.StatusCode == 0 {
+		tools.WriteError(w, http.StatusConflict, errIdempotencyKeyInProgress)
+		return
+	}
+
+	if stored.ContentType != "" {
+		w.Header().Set(tools.HeaderContentType, stored.ContentType)
+	}
+	w.Header().Set(tools.HeaderIdempotentReplayed, "true")
+	w.WriteHeader(stored.StatusCode)
+	_, _ = w.Write(stored.ResponseBody)
+}
+
+// idempotencyScope the scope of the key of the request: the account it acts on, or its path when its body names none
+func idempotencyScope(r *http.Request, body []byte) string {
+	var target struct {
+		OrganizationIban string `json:"organization_iban"`
+	}
+	if json.Unmarshal(body, &target) == nil && target.OrganizationIban != "" {
+		return target.OrganizationIban
+	}
+	return r.URL.Path
+}
+
+// requestHash fingerprints the request so a key can't be reused for a different operation
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
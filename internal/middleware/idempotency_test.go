@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/idempotencyrepo"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/repository"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mockrepository.NewMockIdempotencyRepository(ctrl)
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	now := time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC)
+	clock := tools.ClockFunc(func() time.Time { return now })
+	scope := "FR81474608000002006107XXXXX"
+	body := "{\"organization_iban\": \"FR81474608000002006107XXXXX\"}"
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		tools.WriteJSON(w, http.StatusCreated, map[string]string{"status": "completed"})
+	})
+
+	newRequest := func(key, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/transfer/bulk", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(tools.HeaderIdempotencyKey, key)
+		}
+		return req
+	}
+
+	h := Idempotency(repoMock, 24*time.Hour, clock, logMock)(next)
+
+	t.Run("Test request without key is not deduplicated", func(t *testing.T) {
+		calls = 0
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest("", body))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Test first request is executed and its response stored", func(t *testing.T) {
+		calls = 0
+		repoMock.EXPECT().Read(scope, "key-1").Return(idempotencyrepo.IdempotencyKey{}, sql.ErrNoRows)
+		repoMock.EXPECT().
+			Create(gomock.Any()).
+			DoAndReturn(func(data idempotencyrepo.IdempotencyKey) error {
+				assert.Equal(t, scope, data.Scope)
+				assert.Equal(t, now, data.CreatedAt)
+				return nil
+			})
+		repoMock.EXPECT().
+			Update(gomock.Any()).
+			DoAndReturn(func(data idempotencyrepo.IdempotencyKey) error {
+				assert.Equal(t, scope, data.Scope)
+				assert.Equal(t, "key-1", data.Key)
+				assert.Equal(t, http.StatusCreated, data.StatusCode)
+				assert.JSONEq(t, "{\"status\": \"completed\"}", string(data.ResponseBody))
+				return nil
+			})
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest("key-1", body))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Test replay returns the stored response", func(t *testing.T) {
+		calls = 0
+		first := newRequest("key-1", body)
+		stored := idempotencyrepo.IdempotencyKey{
+			Key:          "key-1",
+			RequestHash:  requestHash(first, []byte(body)),
+			StatusCode:   http.StatusCreated,
+			ContentType:  "application/json; charset=utf-8",
+			ResponseBody: []byte("{\"status\":\"completed\"}\n"),
+			CreatedAt:    now.Add(-time.Hour),
+		}
+		repoMock.EXPECT().Read(scope, "key-1").Return(stored, nil)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest("key-1", body))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "{\"status\":\"completed\"}\n", rr.Body.String())
+		assert.Equal(t, "true", rr.Header().Get(tools.HeaderIdempotentReplayed))
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("Test key reused with a different payload returns conflict", func(t *testing.T) {
+		calls = 0
+		repoMock.EXPECT().Read(scope, "key-1").Return(idempotencyrepo.IdempotencyKey{Key: "key-1", RequestHash: "other", StatusCode: http.StatusCreated, CreatedAt: now}, nil)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest("key-1", body))
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("Test key still being processed returns conflict", func(t *testing.T) {
+		calls = 0
+		repoMock.EXPECT().Read(scope, "key-2").Return(idempotencyrepo.IdempotencyKey{}, sql.ErrNoRows)
+		repoMock.EXPECT().Create(gomock.Any()).Return(idempotencyrepo.ErrAlreadyExists)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest("key-2", body))
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("Test server errors are stored as the request may have been applied", func(t *testing.T) {
+		failing := Idempotency(repoMock, 24*time.Hour, clock, logMock)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tools.WriteError(w, http.StatusInternalServerError, errors.New("error"))
+		}))
+		repoMock.EXPECT().Read(scope, "key-3").Return(idempotencyrepo.IdempotencyKey{}, sql.ErrNoRows)
+		repoMock.EXPECT().Create(gomock.Any()).Return(nil)
+		repoMock.EXPECT().
+			Update(gomock.Any()).
+			DoAndReturn(func(data idempotencyrepo.IdempotencyKey) error {
+				assert.Equal(t, "key-3", data.Key)
+				assert.Equal(t, http.StatusInternalServerError, data.StatusCode)
+				return nil
+			})
+		repoMock.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+
+		rr := httptest.NewRecorder()
+		failing.ServeHTTP(rr, newRequest("key-3", body))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("Test server errors released by the handler are not stored", func(t *testing.T) {
+		failing := Idempotency(repoMock, 24*time.Hour, clock, logMock)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ReleaseIdempotencyKey(r)
+			tools.WriteError(w, http.StatusInternalServerError, errors.New("error"))
+		}))
+		repoMock.EXPECT().Read(scope, "key-5").Return(idempotencyrepo.IdempotencyKey{}, sql.ErrNoRows)
+		repoMock.EXPECT().Create(gomock.Any()).Return(nil)
+		repoMock.EXPECT().Delete(scope, "key-5").Return(nil)
+
+		rr := httptest.NewRecorder()
+		failing.ServeHTTP(rr, newRequest("key-5", body))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("Test releasing the key of a request without key does nothing", func(t *testing.T) {
+		ReleaseIdempotencyKey(newRequest("", body))
+	})
+
+	t.Run("Test expired key not purged yet is used again", func(t *testing.T) {
+		calls = 0
+		repoMock.EXPECT().Read(scope, "key-4").Return(idempotencyrepo.IdempotencyKey{Scope: scope, Key: "key-4", RequestHash: "other", StatusCode: http.StatusCreated, CreatedAt: now.Add(-25 * time.Hour)}, nil)
+		repoMock.EXPECT().Delete(scope, "key-4").Return(nil)
+		repoMock.EXPECT().Create(gomock.Any()).Return(nil)
+		repoMock.EXPECT().Update(gomock.Any()).Return(nil)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest("key-4", body))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Test keys are scoped by the account of the request", func(t *testing.T) {
+		calls = 0
+		repoMock.EXPECT().Read("EE383680981021245685", "key-1").Return(idempotencyrepo.IdempotencyKey{}, sql.ErrNoRows)
+		repoMock.EXPECT().Create(gomock.Any()).Return(nil)
+		repoMock.EXPECT().Update(gomock.Any()).Return(nil)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest("key-1", "{\"organization_iban\": \"EE383680981021245685\"}"))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Test keys of requests naming no account are scoped by their path", func(t *testing.T) {
+		repoMock.EXPECT().Read("/transfer/bulk", "key-1").Return(idempotencyrepo.IdempotencyKey{}, sql.ErrNoRows)
+		repoMock.EXPECT().Create(gomock.Any()).Return(nil)
+		repoMock.EXPECT().Update(gomock.Any()).Return(nil)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest("key-1", "{\"amount\": \"14.53\"}"))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+}
+
+func TestPurgeIdempotencyKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mockrepository.NewMockIdempotencyRepository(ctrl)
+	now := time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC)
+	purge := PurgeIdempotencyKeys(repoMock, 24*time.Hour, tools.ClockFunc(func() time.Time { return now }))
+
+	t.Run("Test expired keys are purged using the retention", func(t *testing.T) {
+		repoMock.EXPECT().DeleteExpired(now.Add(-24*time.Hour)).Return(int64(3), nil)
+
+		purged, err := purge()
+		assert.NoError(t, err)
+		assert.Equal(t, 3, purged)
+	})
+
+	t.Run("Test purge return error", func(t *testing.T) {
+		repoMock.EXPECT().DeleteExpired(gomock.Any()).Return(int64(0), errors.New("error"))
+
+		_, err := purge()
+		assert.Error(t, err)
+	})
+}
@@ -1,10 +1,14 @@
 package bankaccountrepo
 
 import (
+	"database/sql"
 	"errors"
 	"github.com/adrianoccosta/exercise-qonto/cmd/config"
 )
 
+// ErrInsufficientFunds is returned when a debit would take the balance below the overdraft
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
 // Repo struct
 type Repo struct {
 	DB config.Conn
@@ -14,9 +18,30 @@ type Repo struct {
 type BankAccount struct {
 	ID               uint
 	OrganizationName string
-	BalanceCents     int
+	BalanceCents     int64
 	Iban             string
 	Bic              string
+	Limits           Limits
+	Approval         Approval
+	// OverdraftCents how far below zero the balance may go, zero when no overdraft is authorised
+	OverdraftCents int64
+	// Plan the plan of the account, it picks the rules of the fee schedule
+	Plan string
+}
+
+// Limits the transfer limits of a bank account, zero when the limit doesn't apply
+type Limits struct {
+	TransactionCents int64
+	BatchCents       int64
+	DailyCents       int64
+	MonthlyCents     int64
+}
+
+// Approval the approval rule of the bulk transfers of a bank account. Bulk transfers with a total above
+// ThresholdCents need RequiredApprovals approvals, none do when ThresholdCents is zero.
+type Approval struct {
+	ThresholdCents    int64
+	RequiredApprovals int
 }
 
 // BankAccountRepository Interface for the back account registry
@@ -25,7 +50,11 @@ type BankAccountRepository interface {
 	Read(bankAccountID uint) (BankAccount, error)
 	ReadByIban(iban string) (BankAccount, error)
 	Update(data BankAccount) error
+	Debit(bankAccountID uint, amountCents int64) (int64, error)
+	Credit(bankAccountID uint, amountCents int64) error
 	DeleteByIban(iban string) error
+	ReadApprovers(bankAccountID uint) ([]string, error)
+	ReplaceApprovers(bankAccountID uint, approvers []string) error
 }
 
 // New Returns a new instance of DB.
@@ -41,10 +70,25 @@ func (repo Repo) Create(data BankAccount) (int, error) {
 		return 0, errors.New("Register with same iban already exists")
 	}
 	insertQuery := "INSERT INTO bank_accounts" +
-		"(organization_name, balance_cents, iban, bic) " +
-		"VALUES (?, ?, ?, ?)"
+		"(organization_name, balance_cents, opening_balance_cents, iban, bic, transaction_limit_cents, batch_limit_cents, daily_limit_cents, monthly_limit_cents, approval_threshold_cents, required_approvals, overdraft_limit_cents, plan) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
 
-	res, err := repo.DB.Conn.Exec(insertQuery, data.OrganizationName, data.BalanceCents, data.Iban, data.Bic)
+	// the balance the account is created with is its opening balance
+	res, err := repo.DB.Executor().Exec(
+		insertQuery,
+		data.OrganizationName,
+		data.BalanceCents,
+		data.BalanceCents,
+		data.Iban,
+		data.Bic,
+		data.Limits.TransactionCents,
+		data.Limits.BatchCents,
+		data.Limits.DailyCents,
+		data.Limits.MonthlyCents,
+		data.Approval.ThresholdCents,
+		data.Approval.RequiredApprovals,
+		data.OverdraftCents,
+		data.Plan)
 
 	if err != nil {
 		return 0, err
@@ -57,11 +101,11 @@ func (repo Repo) Create(data BankAccount) (int, error) {
 
 // Read a bank account
 func (repo Repo) Read(bankAccountID uint) (BankAccount, error) {
-	query := "SELECT id, organization_name, balance_cents, iban, bic " +
+	query := "SELECT id, organization_name, balance_cents, iban, bic, transaction_limit_cents, batch_limit_cents, daily_limit_cents, monthly_limit_cents, approval_threshold_cents, required_approvals, overdraft_limit_cents, plan " +
 		" FROM bank_accounts" +
 		" WHERE id = ?"
 
-	row := repo.DB.Conn.QueryRow(query, bankAccountID)
+	row := repo.DB.Executor().QueryRow(query, bankAccountID)
 
 	var bankAccount BankAccount
 	err := row.Scan(
@@ -70,6 +114,14 @@ func (repo Repo) Read(bankAccountID uint) (BankAccount, error) {
 		&bankAccount.BalanceCents,
 		&bankAccount.Iban,
 		&bankAccount.Bic,
+		&bankAccount.Limits.TransactionCents,
+		&bankAccount.Limits.BatchCents,
+		&bankAccount.Limits.DailyCents,
+		&bankAccount.Limits.MonthlyCents,
+		&bankAccount.Approval.ThresholdCents,
+		&bankAccount.Approval.RequiredApprovals,
+		&bankAccount.OverdraftCents,
+		&bankAccount.Plan,
 	)
 	if err != nil {
 		return BankAccount{}, err
@@ -80,11 +132,11 @@ func (repo Repo) Read(bankAccountID uint) (BankAccount, error) {
 
 // ReadByIban a bank account
 func (repo Repo) ReadByIban(iban string) (BankAccount, error) {
-	query := "SELECT id, organization_name, balance_cents, iban, bic " +
+	query := "SELECT id, organization_name, balance_cents, iban, bic, transaction_limit_cents, batch_limit_cents, daily_limit_cents, monthly_limit_cents, approval_threshold_cents, required_approvals, overdraft_limit_cents, plan " +
 		" FROM bank_accounts" +
 		" WHERE iban = ?"
 
-	row := repo.DB.Conn.QueryRow(query, iban)
+	row := repo.DB.Executor().QueryRow(query, iban)
 
 	var bankAccount BankAccount
 	err := row.Scan(
@@ -93,6 +145,14 @@ func (repo Repo) ReadByIban(iban string) (BankAccount, error) {
 		&bankAccount.BalanceCents,
 		&bankAccount.Iban,
 		&bankAccount.Bic,
+		&bankAccount.Limits.TransactionCents,
+		&bankAccount.Limits.BatchCents,
+		&bankAccount.Limits.DailyCents,
+		&bankAccount.Limits.MonthlyCents,
+		&bankAccount.Approval.ThresholdCents,
+		&bankAccount.Approval.RequiredApprovals,
+		&bankAccount.OverdraftCents,
+		&bankAccount.Plan,
 	)
 	if err != nil {
 		return BankAccount{}, err
@@ -104,28 +164,154 @@ func (repo Repo) ReadByIban(iban string) (BankAccount, error) {
 // Update the values of a bank account
 func (repo Repo) Update(data BankAccount) error {
 	updateQuery := "UPDATE bank_accounts " +
-		"SET organization_name = ?, balance_cents = ?, bic = ? " +
+		"SET organization_name = ?, balance_cents = ?, bic = ?, " +
+		"transaction_limit_cents = ?, batch_limit_cents = ?, daily_limit_cents = ?, monthly_limit_cents = ?, " +
+		"approval_threshold_cents = ?, required_approvals = ?, overdraft_limit_cents = ?, plan = ? " +
+		"WHERE id = ?"
+
+	_, err := repo.DB.Executor().Exec(
+		updateQuery,
+		data.OrganizationName,
+		data.BalanceCents,
+		data.Bic,
+		data.Limits.TransactionCents,
+		data.Limits.BatchCents,
+		data.Limits.DailyCents,
+		data.Limits.MonthlyCents,
+		data.Approval.ThresholdCents,
+		data.Approval.RequiredApprovals,
+		data.OverdraftCents,
+		data.Plan,
+		data.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Debit subtracts the amount from the balance of a bank account, returning the balance after the debit. The
+// update is guarded by the balance and the overdraft, so concurrent debits can never take the balance below the
+// overdraft: when they don't cover the amount nothing is changed and ErrInsufficientFunds is returned.
+func (repo Repo) Debit(bankAccountID uint, amountCents int64) (int64, error) {
+	updateQuery := "UPDATE bank_accounts " +
+		"SET balance_cents = balance_cents - ? " +
+		"WHERE id = ? AND balance_cents + overdraft_limit_cents >= ?"
+
+	res, err := repo.DB.Executor().Exec(updateQuery, amountCents, bankAccountID, amountCents)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, ErrInsufficientFunds
+	}
+
+	query := "SELECT balance_cents " +
+		" FROM bank_accounts" +
+		" WHERE id = ?"
+
+	var balanceCents int64
+	err = repo.DB.Executor().QueryRow(query, bankAccountID).Scan(&balanceCents)
+
+	return balanceCents, err
+}
+
+// Credit adds the amount to the balance of a bank account, sql.ErrNoRows is returned when it doesn't exist
+func (repo Repo) Credit(bankAccountID uint, amountCents int64) error {
+	updateQuery := "UPDATE bank_accounts " +
+		"SET balance_cents = balance_cents + ? " +
 		"WHERE id = ?"
 
-	_, err := repo.DB.Conn.Exec(updateQuery, data.OrganizationName, data.BalanceCents, data.Bic, data.ID)
+	res, err := repo.DB.Executor().Exec(updateQuery, amountCents, bankAccountID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
 
 	return nil
 }
 
-// DeleteByIban a bank account
+// DeleteByIban a bank account and its approvers
 func (repo Repo) DeleteByIban(iban string) error {
+	deleteApproversQuery := "DELETE " +
+		" FROM bank_account_approvers" +
+		" WHERE bank_account_id IN (SELECT id FROM bank_accounts WHERE iban = ?)"
+
+	if _, err := repo.DB.Executor().Exec(deleteApproversQuery, iban); err != nil {
+		return err
+	}
+
 	deleteQuery := "DELETE " +
 		" FROM bank_accounts" +
 		" WHERE iban = ?"
 
-	_, err := repo.DB.Conn.Exec(deleteQuery, iban)
+	_, err := repo.DB.Executor().Exec(deleteQuery, iban)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReadApprovers list who may approve the bulk transfers of a bank account, sorted
+func (repo Repo) ReadApprovers(bankAccountID uint) ([]string, error) {
+	query := "SELECT approver " +
+		" FROM bank_account_approvers" +
+		" WHERE bank_account_id = ?" +
+		" ORDER BY approver"
 
+	rows, err := repo.DB.Executor().Query(query, bankAccountID)
 	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	approvers := []string{}
+	for rows.Next() {
+		var approver string
+		if err = rows.Scan(&approver); err != nil {
+			return nil, err
+		}
+		approvers = append(approvers, approver)
+	}
+
+	return approvers, rows.Err()
+}
+
+// ReplaceApprovers replaces who may approve the bulk transfers of a bank account
+func (repo Repo) ReplaceApprovers(bankAccountID uint, approvers []string) error {
+	deleteQuery := "DELETE " +
+		" FROM bank_account_approvers" +
+		" WHERE bank_account_id = ?"
+
+	if _, err := repo.DB.Executor().Exec(deleteQuery, bankAccountID); err != nil {
 		return err
 	}
 
+	insertQuery := "INSERT INTO bank_account_approvers" +
+		"(bank_account_id, approver) " +
+		"VALUES (?, ?)"
+
+	for _, approver := range approvers {
+		if _, err := repo.DB.Executor().Exec(insertQuery, bankAccountID, approver); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -1,6 +1,7 @@
 package bankaccountrepo
 
 import (
+	"database/sql"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/adrianoccosta/exercise-qonto/cmd/config"
@@ -44,8 +45,12 @@ func TestBankAccountRepo(t *testing.T) {
 		ID:               1,
 		OrganizationName: "ACME Corp",
 		BalanceCents:     123456,
-		Iban:             "FR10474608000002006107XXXXX",
+		Iban:             "FR81474608000002006107XXXXX",
 		Bic:              "OIVUSCLQXXX",
+		Limits:           Limits{TransactionCents: 500000, DailyCents: 1000000},
+		Approval:         Approval{ThresholdCents: 2000000, RequiredApprovals: 2},
+		OverdraftCents:   50000,
+		Plan:             "premium",
 	}
 
 	t.Run("Test Create return success", func(t *testing.T) {
@@ -53,9 +58,18 @@ func TestBankAccountRepo(t *testing.T) {
 
 		mock.ExpectExec(insertQuery).
 			WithArgs(bankAccount.OrganizationName,
+				bankAccount.BalanceCents,
 				bankAccount.BalanceCents,
 				bankAccount.Iban,
-				bankAccount.Bic).
+				bankAccount.Bic,
+				bankAccount.Limits.TransactionCents,
+				bankAccount.Limits.BatchCents,
+				bankAccount.Limits.DailyCents,
+				bankAccount.Limits.MonthlyCents,
+				bankAccount.Approval.ThresholdCents,
+				bankAccount.Approval.RequiredApprovals,
+				bankAccount.OverdraftCents,
+				bankAccount.Plan).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		r, err := repo.Create(bankAccount)
@@ -68,9 +82,18 @@ func TestBankAccountRepo(t *testing.T) {
 
 		mock.ExpectExec(insertQuery).
 			WithArgs(bankAccount.OrganizationName,
+				bankAccount.BalanceCents,
 				bankAccount.BalanceCents,
 				bankAccount.Iban,
-				bankAccount.Bic).
+				bankAccount.Bic,
+				bankAccount.Limits.TransactionCents,
+				bankAccount.Limits.BatchCents,
+				bankAccount.Limits.DailyCents,
+				bankAccount.Limits.MonthlyCents,
+				bankAccount.Approval.ThresholdCents,
+				bankAccount.Approval.RequiredApprovals,
+				bankAccount.OverdraftCents,
+				bankAccount.Plan).
 			WillReturnError(fmt.Errorf("error"))
 
 		_, err := repo.Create(bankAccount)
@@ -78,10 +101,10 @@ func TestBankAccountRepo(t *testing.T) {
 	})
 
 	t.Run("Test Read return success", func(t *testing.T) {
-		selectQuery := "SELECT id, organization_name, balance_cents, iban, bic FROM bank_accounts"
+		selectQuery := "SELECT id, organization_name, balance_cents, iban, bic, transaction_limit_cents, batch_limit_cents, daily_limit_cents, monthly_limit_cents, approval_threshold_cents, required_approvals, overdraft_limit_cents, plan FROM bank_accounts"
 
-		rows := sqlmock.NewRows([]string{"id", "organization_name", "balance_cents", "iban", "bic"})
-		rows.AddRow(bankAccount.ID, bankAccount.OrganizationName, bankAccount.BalanceCents, bankAccount.Iban, bankAccount.Bic)
+		rows := sqlmock.NewRows([]string{"id", "organization_name", "balance_cents", "iban", "bic", "transaction_limit_cents", "batch_limit_cents", "daily_limit_cents", "monthly_limit_cents", "approval_threshold_cents", "required_approvals", "overdraft_limit_cents", "plan"})
+		rows.AddRow(bankAccount.ID, bankAccount.OrganizationName, bankAccount.BalanceCents, bankAccount.Iban, bankAccount.Bic, 500000, 0, 1000000, 0, 2000000, 2, 50000, "premium")
 
 		mock.ExpectQuery(selectQuery).
 			WithArgs(bankAccount.ID).
@@ -95,10 +118,14 @@ func TestBankAccountRepo(t *testing.T) {
 		assert.Equal(t, bankAccount.BalanceCents, s.BalanceCents)
 		assert.Equal(t, bankAccount.Iban, s.Iban)
 		assert.Equal(t, bankAccount.Bic, s.Bic)
+		assert.Equal(t, bankAccount.Limits, s.Limits)
+		assert.Equal(t, bankAccount.Approval, s.Approval)
+		assert.Equal(t, bankAccount.OverdraftCents, s.OverdraftCents)
+		assert.Equal(t, bankAccount.Plan, s.Plan)
 	})
 
 	t.Run("Test Read return error", func(t *testing.T) {
-		selectQuery := "SELECT id, organization_name, balance_cents, iban, bic FROM bank_accounts"
+		selectQuery := "SELECT id, organization_name, balance_cents, iban, bic, transaction_limit_cents, batch_limit_cents, daily_limit_cents, monthly_limit_cents, approval_threshold_cents, required_approvals, overdraft_limit_cents, plan FROM bank_accounts"
 
 		mock.ExpectQuery(selectQuery).
 			WithArgs(bankAccount.ID).
@@ -109,10 +136,10 @@ func TestBankAccountRepo(t *testing.T) {
 	})
 
 	t.Run("Test ReadByIban return success", func(t *testing.T) {
-		selectQuery := "SELECT id, organization_name, balance_cents, iban, bic FROM bank_accounts"
+		selectQuery := "SELECT id, organization_name, balance_cents, iban, bic, transaction_limit_cents, batch_limit_cents, daily_limit_cents, monthly_limit_cents, approval_threshold_cents, required_approvals, overdraft_limit_cents, plan FROM bank_accounts"
 
-		rows := sqlmock.NewRows([]string{"id", "organization_name", "balance_cents", "iban", "bic"})
-		rows.AddRow(bankAccount.ID, bankAccount.OrganizationName, bankAccount.BalanceCents, bankAccount.Iban, bankAccount.Bic)
+		rows := sqlmock.NewRows([]string{"id", "organization_name", "balance_cents", "iban", "bic", "transaction_limit_cents", "batch_limit_cents", "daily_limit_cents", "monthly_limit_cents", "approval_threshold_cents", "required_approvals", "overdraft_limit_cents", "plan"})
+		rows.AddRow(bankAccount.ID, bankAccount.OrganizationName, bankAccount.BalanceCents, bankAccount.Iban, bankAccount.Bic, 500000, 0, 1000000, 0, 2000000, 2, 50000, "premium")
 
 		mock.ExpectQuery(selectQuery).
 			WithArgs(bankAccount.Iban).
@@ -126,10 +153,14 @@ func TestBankAccountRepo(t *testing.T) {
 		assert.Equal(t, bankAccount.BalanceCents, s.BalanceCents)
 		assert.Equal(t, bankAccount.Iban, s.Iban)
 		assert.Equal(t, bankAccount.Bic, s.Bic)
+		assert.Equal(t, bankAccount.Limits, s.Limits)
+		assert.Equal(t, bankAccount.Approval, s.Approval)
+		assert.Equal(t, bankAccount.OverdraftCents, s.OverdraftCents)
+		assert.Equal(t, bankAccount.Plan, s.Plan)
 	})
 
 	t.Run("Test ReadByIban return error", func(t *testing.T) {
-		selectQuery := "SELECT id, organization_name, balance_cents, iban, bic FROM bank_accounts"
+		selectQuery := "SELECT id, organization_name, balance_cents, iban, bic, transaction_limit_cents, batch_limit_cents, daily_limit_cents, monthly_limit_cents, approval_threshold_cents, required_approvals, overdraft_limit_cents, plan FROM bank_accounts"
 
 		mock.ExpectQuery(selectQuery).
 			WithArgs(bankAccount.Iban).
@@ -144,7 +175,7 @@ func TestBankAccountRepo(t *testing.T) {
 		updateQuery := "UPDATE bank_accounts"
 
 		mock.ExpectExec(updateQuery).
-			WithArgs(bankAccount.OrganizationName, bankAccount.BalanceCents, bankAccount.Bic, bankAccount.ID).
+			WithArgs(bankAccount.OrganizationName, bankAccount.BalanceCents, bankAccount.Bic, int64(500000), int64(0), int64(1000000), int64(0), int64(2000000), 2, int64(50000), "premium", bankAccount.ID).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		err := repo.Update(bankAccount)
@@ -156,22 +187,122 @@ func TestBankAccountRepo(t *testing.T) {
 		updateQuery := "UPDATE bank_accounts"
 
 		mock.ExpectExec(updateQuery).
-			WithArgs(bankAccount.OrganizationName, bankAccount.BalanceCents, bankAccount.Bic, bankAccount.ID).
+			WithArgs(bankAccount.OrganizationName, bankAccount.BalanceCents, bankAccount.Bic, int64(500000), int64(0), int64(1000000), int64(0), int64(2000000), 2, int64(50000), "premium", bankAccount.ID).
 			WillReturnError(fmt.Errorf("error"))
 
 		err := repo.Update(bankAccount)
 		assert.Error(t, err)
 	})
 
-	t.Run("Test DeleteByIban return success.", func(t *testing.T) {
+	t.Run("Test Debit return success.", func(t *testing.T) {
+
+		mock.ExpectExec("UPDATE bank_accounts SET balance_cents = balance_cents - \\? WHERE id = \\? AND balance_cents \\+ overdraft_limit_cents >= \\?").
+			WithArgs(1453, bankAccount.ID, 1453).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT balance_cents FROM bank_accounts WHERE id = \\?").
+			WithArgs(bankAccount.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(-453))
+
+		balance, err := repo.Debit(bankAccount.ID, 1453)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(-453), balance)
+	})
+
+	t.Run("Test Debit return ErrInsufficientFunds when the guard doesn't match.", func(t *testing.T) {
+
+		mock.ExpectExec("UPDATE bank_accounts").
+			WithArgs(1453, bankAccount.ID, 1453).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err := repo.Debit(bankAccount.ID, 1453)
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+	})
+
+	t.Run("Test Debit return error.", func(t *testing.T) {
+
+		mock.ExpectExec("UPDATE bank_accounts").
+			WithArgs(1453, bankAccount.ID, 1453).
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.Debit(bankAccount.ID, 1453)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Credit return success.", func(t *testing.T) {
+
+		mock.ExpectExec("UPDATE bank_accounts SET balance_cents = balance_cents \\+ \\? WHERE id = \\?").
+			WithArgs(1453, bankAccount.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Credit(bankAccount.ID, 1453)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Credit return ErrNoRows when the bank account doesn't exist.", func(t *testing.T) {
+
+		mock.ExpectExec("UPDATE bank_accounts").
+			WithArgs(1453, bankAccount.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Credit(bankAccount.ID, 1453)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
 
-		deleteQuery := "DELETE"
+	t.Run("Test DeleteByIban return success.", func(t *testing.T) {
 
-		mock.ExpectExec(deleteQuery).
+		mock.ExpectExec("DELETE FROM bank_account_approvers").
+			WithArgs(bankAccount.Iban).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("DELETE FROM bank_accounts").
 			WithArgs(bankAccount.Iban).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		err := repo.DeleteByIban(bankAccount.Iban)
 		assert.NoError(t, err)
 	})
+
+	t.Run("Test ReadApprovers return success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"approver"}).AddRow("alice").AddRow("bob")
+
+		mock.ExpectQuery("SELECT approver FROM bank_account_approvers").
+			WithArgs(bankAccount.ID).
+			WillReturnRows(rows)
+
+		approvers, err := repo.ReadApprovers(bankAccount.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"alice", "bob"}, approvers)
+	})
+
+	t.Run("Test ReadApprovers return error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT approver FROM bank_account_approvers").
+			WithArgs(bankAccount.ID).
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.ReadApprovers(bankAccount.ID)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test ReplaceApprovers return success.", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM bank_account_approvers").
+			WithArgs(bankAccount.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO bank_account_approvers").
+			WithArgs(bankAccount.ID, "alice").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO bank_account_approvers").
+			WithArgs(bankAccount.ID, "bob").
+			WillReturnResult(sqlmock.NewResult(2, 1))
+
+		err := repo.ReplaceApprovers(bankAccount.ID, []string{"alice", "bob"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test ReplaceApprovers return error.", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM bank_account_approvers").
+			WithArgs(bankAccount.ID).
+			WillReturnError(fmt.Errorf("error"))
+
+		err := repo.ReplaceApprovers(bankAccount.ID, []string{"alice"})
+		assert.Error(t, err)
+	})
 }
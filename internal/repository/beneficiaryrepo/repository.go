@@ -0,0 +1,186 @@
+package beneficiaryrepo
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"time"
+)
+
+// filterColumns maps the filters of the beneficiaries to their columns
+var filterColumns = map[string]string{
+	"organization_iban": "a.iban",
+	"iban":              "b.iban",
+	"name":              "b.name",
+}
+
+// Repo struct
+type Repo struct {
+	DB config.Conn
+}
+
+// BeneficiaryList list of Beneficiary
+type BeneficiaryList []Beneficiary
+
+// Beneficiary Struct that represents a counterparty registered on a bank account
+type Beneficiary struct {
+	ID            uint
+	BankAccountID uint
+	// OrganizationIban the iban of the bank account, read along with the beneficiary
+	OrganizationIban string
+	Name             string
+	Iban             string
+	Bic              string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// BeneficiaryRepository Interface for the beneficiaries registry
+type BeneficiaryRepository interface {
+	Create(data Beneficiary) (int, error)
+	Read(beneficiaryID uint) (Beneficiary, error)
+	ReadByIban(bankAccountID uint, iban string) (Beneficiary, error)
+	ReadByFilter(filters map[string]string) (BeneficiaryList, error)
+	Update(data Beneficiary) error
+	Delete(beneficiaryID uint) error
+}
+
+// New Returns a new instance of DB.
+func New(db config.Conn) Repo {
+	return Repo{
+		DB: db,
+	}
+}
+
+// Create new beneficiary
+func (repo Repo) Create(data Beneficiary) (int, error) {
+	insertQuery := "INSERT INTO beneficiaries" +
+		"(bank_account_id, name, iban, bic, created_at, updated_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?)"
+
+	res, err := repo.DB.Executor().Exec(
+		insertQuery,
+		data.BankAccountID,
+		data.Name,
+		data.Iban,
+		data.Bic,
+		data.CreatedAt,
+		data.UpdatedAt)
+
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+
+	return int(id), err
+}
+
+// Read a beneficiary
+func (repo Repo) Read(beneficiaryID uint) (Beneficiary, error) {
+	query := "SELECT b.id, b.bank_account_id, a.iban, b.name, b.iban, b.bic, b.created_at, b.updated_at" +
+		" FROM beneficiaries b" +
+		" INNER JOIN bank_accounts a ON a.id = b.bank_account_id" +
+		" WHERE b.id = ?"
+
+	return scan(repo.DB.Executor().QueryRow(query, beneficiaryID))
+}
+
+// ReadByIban the beneficiary of a bank account holding the iban
+func (repo Repo) ReadByIban(bankAccountID uint, iban string) (Beneficiary, error) {
+	query := "SELECT b.id, b.bank_account_id, a.iban, b.name, b.iban, b.bic, b.created_at, b.updated_at" +
+		" FROM beneficiaries b" +
+		" INNER JOIN bank_accounts a ON a.id = b.bank_account_id" +
+		" WHERE b.bank_account_id = ? AND b.iban = ?"
+
+	return scan(repo.DB.Executor().QueryRow(query, bankAccountID, iban))
+}
+
+// ReadByFilter list of beneficiaries, sorted by name
+func (repo Repo) ReadByFilter(filters map[string]string) (BeneficiaryList, error) {
+	query := "SELECT b.id, b.bank_account_id, a.iban, b.name, b.iban, b.bic, b.created_at, b.updated_at" +
+		" FROM beneficiaries b" +
+		" INNER JOIN bank_accounts a ON a.id = b.bank_account_id" +
+		" WHERE 1 = 1"
+
+	var bind []any
+	for k, v := range filters {
+		column, ok := filterColumns[k]
+		if !ok {
+			return nil, fmt.Errorf("beneficiaries can't be filtered by %s", k)
+		}
+		query += fmt.Sprintf(" and %s = ?", column)
+		bind = append(bind, v)
+	}
+	query += " ORDER BY b.name, b.id"
+
+	rows, err := repo.DB.Executor().Query(query, bind...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	beneficiaries := BeneficiaryList{}
+	for rows.Next() {
+		beneficiary, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		beneficiaries = append(beneficiaries, beneficiary)
+	}
+
+	return beneficiaries, rows.Err()
+}
+
+// Update the values of a beneficiary, it stays on its bank account
+func (repo Repo) Update(data Beneficiary) error {
+	updateQuery := "UPDATE beneficiaries " +
+		"SET name = ?, iban = ?, bic = ?, updated_at = ? " +
+		"WHERE id = ?"
+
+	_, err := repo.DB.Executor().Exec(
+		updateQuery,
+		data.Name,
+		data.Iban,
+		data.Bic,
+		data.UpdatedAt,
+		data.ID)
+
+	return err
+}
+
+// Delete a beneficiary. The credit transfers that referenced it keep the counterparty they were paid with.
+func (repo Repo) Delete(beneficiaryID uint) error {
+	deleteQuery := "DELETE " +
+		" FROM beneficiaries" +
+		" WHERE id = ?"
+
+	_, err := repo.DB.Executor().Exec(deleteQuery, beneficiaryID)
+
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scan(row scanner) (Beneficiary, error) {
+	var beneficiary Beneficiary
+	err := row.Scan(
+		&beneficiary.ID,
+		&beneficiary.BankAccountID,
+		&beneficiary.OrganizationIban,
+		&beneficiary.Name,
+		&beneficiary.Iban,
+		&beneficiary.Bic,
+		&beneficiary.CreatedAt,
+		&beneficiary.UpdatedAt,
+	)
+	if err != nil {
+		return Beneficiary{}, err
+	}
+
+	return beneficiary, nil
+}
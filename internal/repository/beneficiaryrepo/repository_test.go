@@ -0,0 +1,162 @@
+package beneficiaryrepo
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+	"time"
+)
+
+func setupBeneficiaryRepo() (config.Conn, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return config.Conn{Conn: db}, mock
+}
+
+func TestBeneficiaryRepo(t *testing.T) {
+
+	conn, mock := setupBeneficiaryRepo()
+	defer func() {
+		mock.ExpectClose()
+		err := conn.Conn.Close()
+		if err != nil {
+			t.Errorf("Error closing connection: %+v", err)
+		}
+	}()
+
+	repo := Repo{DB: conn}
+
+	t.Run("Test constructor.", func(t *testing.T) {
+		r := New(conn)
+
+		assert.NotEmpty(t, r)
+	})
+
+	beneficiary := Beneficiary{
+		ID:               3,
+		BankAccountID:    1,
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		Name:             "Bip Bip",
+		Iban:             "EE303680981021245685",
+		Bic:              "CRLYFRPPTOU",
+		CreatedAt:        time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC),
+		UpdatedAt:        time.Date(2022, 8, 25, 10, 0, 1, 0, time.UTC),
+	}
+
+	columns := []string{"id", "bank_account_id", "iban", "name", "iban", "bic", "created_at", "updated_at"}
+	row := func(rows *sqlmock.Rows) *sqlmock.Rows {
+		return rows.AddRow(beneficiary.ID, beneficiary.BankAccountID, beneficiary.OrganizationIban, beneficiary.Name, beneficiary.Iban, beneficiary.Bic,
+			beneficiary.CreatedAt, beneficiary.UpdatedAt)
+	}
+
+	t.Run("Test Create return success", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO beneficiaries").
+			WithArgs(beneficiary.BankAccountID, beneficiary.Name, beneficiary.Iban, beneficiary.Bic, beneficiary.CreatedAt, beneficiary.UpdatedAt).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+
+		r, err := repo.Create(beneficiary)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, r)
+	})
+
+	t.Run("Test Create return error while inserting on database.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO beneficiaries").
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.Create(beneficiary)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Read return success", func(t *testing.T) {
+		mock.ExpectQuery("SELECT b.id, b.bank_account_id, a.iban, b.name, b.iban, b.bic, b.created_at, b.updated_at FROM beneficiaries b INNER JOIN bank_accounts a ON a.id = b.bank_account_id WHERE b.id = (.+)").
+			WithArgs(beneficiary.ID).
+			WillReturnRows(row(sqlmock.NewRows(columns)))
+
+		s, err := repo.Read(beneficiary.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, beneficiary, s)
+	})
+
+	t.Run("Test Read return no rows", func(t *testing.T) {
+		mock.ExpectQuery("FROM beneficiaries b").
+			WithArgs(beneficiary.ID).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := repo.Read(beneficiary.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("Test ReadByIban return success", func(t *testing.T) {
+		mock.ExpectQuery("FROM beneficiaries b (.+) WHERE b.bank_account_id = (.+) AND b.iban = (.+)").
+			WithArgs(beneficiary.BankAccountID, beneficiary.Iban).
+			WillReturnRows(row(sqlmock.NewRows(columns)))
+
+		s, err := repo.ReadByIban(beneficiary.BankAccountID, beneficiary.Iban)
+		assert.NoError(t, err)
+		assert.Equal(t, beneficiary, s)
+	})
+
+	t.Run("Test ReadByFilter return success", func(t *testing.T) {
+		mock.ExpectQuery("FROM beneficiaries b (.+) WHERE 1 = 1 and a.iban = (.+) ORDER BY b.name, b.id").
+			WithArgs(beneficiary.OrganizationIban).
+			WillReturnRows(row(sqlmock.NewRows(columns)))
+
+		s, err := repo.ReadByFilter(map[string]string{"organization_iban": beneficiary.OrganizationIban})
+		assert.NoError(t, err)
+		assert.Equal(t, BeneficiaryList{beneficiary}, s)
+	})
+
+	t.Run("Test ReadByFilter return error on unknown filter", func(t *testing.T) {
+		_, err := repo.ReadByFilter(map[string]string{"1 = 1; DROP TABLE beneficiaries; --": "x"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Test ReadByFilter return error", func(t *testing.T) {
+		mock.ExpectQuery("FROM beneficiaries b").
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.ReadByFilter(map[string]string{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Update return success.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE beneficiaries").
+			WithArgs(beneficiary.Name, beneficiary.Iban, beneficiary.Bic, beneficiary.UpdatedAt, beneficiary.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Update(beneficiary)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Update return error.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE beneficiaries").
+			WillReturnError(fmt.Errorf("error"))
+
+		err := repo.Update(beneficiary)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Delete return success.", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM beneficiaries WHERE id = (.+)").
+			WithArgs(beneficiary.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Delete(beneficiary.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Delete return error.", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM beneficiaries").
+			WillReturnError(fmt.Errorf("error"))
+
+		err := repo.Delete(beneficiary.ID)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,819 @@
+package bulktransferrepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"time"
+)
+
+// ErrStatusConflict is returned when the bulk transfer is no longer in the expected status
+var ErrStatusConflict = errors.New("bulk transfer status changed")
+
+// filterColumns lists the columns bulk transfers can be filtered by
+var filterColumns = map[string]bool{
+	"organization_iban": true,
+	"status":            true,
+	"template_id":       true,
+}
+
+// Repo struct
+type Repo struct {
+	DB config.Conn
+}
+
+// BulkTransferList list of BulkTransfer
+type BulkTransferList []BulkTransfer
+
+// BulkTransfer Struct that represents a stored bulk transfer request
+type BulkTransfer struct {
+	ID               uint
+	BankAccountID    uint
+	OrganizationName string
+	OrganizationIban string
+	OrganizationBic  string
+	TransfersCount   int
+	TotalCents       int64
+	Status           string
+	FailureReason    string
+	// ExecutionDate when a scheduled bulk transfer is due, zero when it is executed on reception
+	ExecutionDate time.Time
+	// TemplateID the transfer template the bulk transfer is an occurrence of, zero when it was submitted
+	TemplateID uint
+	// CancellationReason, CancelledBy and CancelledAt record why, by whom and when the bulk transfer was cancelled
+	CancellationReason string
+	CancelledBy        string
+	CancelledAt        time.Time
+	// SubmittedBy who submitted the bulk transfer, empty when unknown
+	SubmittedBy string
+	// RequiredApprovals how many approvals the bulk transfer needed before its execution, zero when none
+	RequiredApprovals int
+	// ScreeningHits how many screening matches held the bulk transfer for review, zero when it never was
+	ScreeningHits int
+	// AllowDuplicates the bulk transfer is executed even though it looks like a duplicate of a recent one
+	AllowDuplicates bool
+	// ExecutionMode all_or_nothing or best_effort
+	ExecutionMode string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// DecisionList list of Decision
+type DecisionList []Decision
+
+// Decision Struct that represents the approval, or the rejection, of a bulk transfer by an approver
+type Decision struct {
+	ID             uint
+	BulkTransferID uint
+	Actor          string
+	Decision       string
+	Comment        string
+	CreatedAt      time.Time
+}
+
+// MatchList list of Match
+type MatchList []Match
+
+// Match Struct that represents a counterparty of a line matching an entry of the screening list
+type Match struct {
+	ID             uint
+	BulkTransferID uint
+	Index          int
+	Field          string
+	EntryID        string
+	EntryName      string
+	EntrySource    string
+	Score          float64
+	CreatedAt      time.Time
+}
+
+// Review Struct that represents the release, or the block, of a bulk transfer held for review
+type Review struct {
+	ID             uint
+	BulkTransferID uint
+	Actor          string
+	Outcome        string
+	Comment        string
+	CreatedAt      time.Time
+}
+
+// LineList list of Line
+type LineList []Line
+
+// Line Struct that represents a stored credit transfer of a bulk transfer
+type Line struct {
+	ID               uint
+	BulkTransferID   uint
+	Index            int
+	CounterPartyName string
+	CounterPartyIban string
+	CounterPartyBic  string
+	AmountCents      int64
+	AmountCurrency   string
+	Description      string
+	// BeneficiaryID the beneficiary the line pays, zero when it holds its own counterparty
+	BeneficiaryID uint
+	// Priority the lines of a best effort bulk transfer with the highest priority are executed first
+	Priority int
+	// CancellationReason, CancelledBy and CancelledAt record why, by whom and when the line was cancelled
+	CancellationReason string
+	CancelledBy        string
+	CancelledAt        time.Time
+}
+
+// OutcomeList list of Outcome
+type OutcomeList []Outcome
+
+// Outcome Struct that represents the outcome of a line of a best effort bulk transfer
+type Outcome struct {
+	ID             uint
+	BulkTransferID uint
+	Index          int
+	Status         string
+	Reason         string
+	Message        string
+	// TransactionID the transaction of the executed line, zero when it wasn't executed
+	TransactionID uint
+	CreatedAt     time.Time
+}
+
+// JobList list of Job
+type JobList []Job
+
+// Job Struct that represents the asynchronous processing of a bulk transfer
+type Job struct {
+	ID             uint
+	BulkTransferID uint
+	Status         string
+	LinesTotal     int
+	LinesDone      int
+	Error          string
+	CreatedAt      time.Time
+	// StartedAt and FinishedAt when a worker started and finished the job, zero until then
+	StartedAt  time.Time
+	FinishedAt time.Time
+	UpdatedAt  time.Time
+	// Owner the worker holding the running job, its lease expiring at LeaseExpiresAt unless renewed. A running job
+	// whose lease expired was left by its worker, any other may claim it.
+	Owner          string
+	LeaseExpiresAt time.Time
+}
+
+// BulkTransferRepository Interface for the bulk transfers registry
+type BulkTransferRepository interface {
+	Create(data BulkTransfer) (int, error)
+	Read(bulkTransferID uint) (BulkTransfer, error)
+	ReadByFilter(filters map[string]string) (BulkTransferList, error)
+	ReadDue(at time.Time) (BulkTransferList, error)
+	Update(data BulkTransfer) error
+	Transition(data BulkTransfer, from string) error
+	Cancel(data BulkTransfer, from string) error
+	CreateLines(bulkTransferID uint, lines LineList) error
+	ReadLines(bulkTransferID uint) (LineList, error)
+	CancelLines(bulkTransferID uint, lines LineList) error
+	CreateDecision(data Decision) (int, error)
+	ReadDecisions(bulkTransferID uint) (DecisionList, error)
+	Hold(data BulkTransfer, matches MatchList, from string) error
+	ReadMatches(bulkTransferID uint) (MatchList, error)
+	CreateReview(data Review) (int, error)
+	ReadReview(bulkTransferID uint) (Review, error)
+	CreateOutcomes(bulkTransferID uint, outcomes OutcomeList) error
+	ReadOutcomes(bulkTransferID uint) (OutcomeList, error)
+	CreateJob(data Job) (int, error)
+	ReadJob(jobID uint) (Job, error)
+	ReadPendingJobs() (JobList, error)
+	UpdateJob(data Job) error
+	ClaimJob(data Job, at time.Time) error
+	RenewJob(data Job) error
+	FinishJob(data Job) error
+}
+
+// New Returns a new instance of DB.
+func New(db config.Conn) Repo {
+	return Repo{
+		DB: db,
+	}
+}
+
+// Create new bulk transfer
+func (repo Repo) Create(data BulkTransfer) (int, error) {
+	insertQuery := "INSERT INTO bulk_transfers" +
+		"(bank_account_id, organization_name, organization_iban, organization_bic, transfers_count, total_cents, status, failure_reason, execution_date, template_id, submitted_by, required_approvals, allow_duplicates, execution_mode, created_at, updated_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+	res, err := repo.DB.Executor().Exec(
+		insertQuery,
+		data.BankAccountID,
+		data.OrganizationName,
+		data.OrganizationIban,
+		data.OrganizationBic,
+		data.TransfersCount,
+		data.TotalCents,
+		data.Status,
+		data.FailureReason,
+		nullableTime(data.ExecutionDate),
+		nullableID(data.TemplateID),
+		data.SubmittedBy,
+		data.RequiredApprovals,
+		data.AllowDuplicates,
+		data.ExecutionMode,
+		data.CreatedAt,
+		data.UpdatedAt)
+
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+
+	return int(id), err
+}
+
+// Read a bulk transfer
+func (repo Repo) Read(bulkTransferID uint) (BulkTransfer, error) {
+	query := "SELECT id, bank_account_id, organization_name, organization_iban, organization_bic, transfers_count, total_cents, status, failure_reason, execution_date, template_id, cancellation_reason, cancelled_by, cancelled_at, submitted_by, required_approvals, screening_hits, allow_duplicates, execution_mode, created_at, updated_at " +
+		" FROM bulk_transfers" +
+		" WHERE id = ?"
+
+	row := repo.DB.Executor().QueryRow(query, bulkTransferID)
+
+	return scan(row)
+}
+
+// ReadByFilter list the bulk transfers matching every filter, most recent first
+func (repo Repo) ReadByFilter(filters map[string]string) (BulkTransferList, error) {
+	query := "SELECT id, bank_account_id, organization_name, organization_iban, organization_bic, transfers_count, total_cents, status, failure_reason, execution_date, template_id, cancellation_reason, cancelled_by, cancelled_at, submitted_by, required_approvals, screening_hits, allow_duplicates, execution_mode, created_at, updated_at " +
+		" FROM bulk_transfers" +
+		" WHERE 1 = 1"
+
+	var bind []any
+	for k, v := range filters {
+		if !filterColumns[k] {
+			return nil, fmt.Errorf("bulk transfers can't be filtered by %s", k)
+		}
+		query += fmt.Sprintf(" and %s = ?", k)
+		bind = append(bind, v)
+	}
+	query += " ORDER BY id DESC"
+
+	return repo.query(query, bind...)
+}
+
+// ReadDue list the scheduled bulk transfers due at the given time, oldest first
+func (repo Repo) ReadDue(at time.Time) (BulkTransferList, error) {
+	query := "SELECT id, bank_account_id, organization_name, organization_iban, organization_bic, transfers_count, total_cents, status, failure_reason, execution_date, template_id, cancellation_reason, cancelled_by, cancelled_at, submitted_by, required_approvals, screening_hits, allow_duplicates, execution_mode, created_at, updated_at " +
+		" FROM bulk_transfers" +
+		" WHERE status = 'scheduled' and execution_date <= ?" +
+		" ORDER BY execution_date, id"
+
+	return repo.query(query, at)
+}
+
+func (repo Repo) query(query string, args ...any) (BulkTransferList, error) {
+	rows, err := repo.DB.Executor().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var bulkTransfers BulkTransferList
+	for rows.Next() {
+		bulkTransfer, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		bulkTransfers = append(bulkTransfers, bulkTransfer)
+	}
+
+	return bulkTransfers, rows.Err()
+}
+
+// Update the mutable values of a bulk transfer
+func (repo Repo) Update(data BulkTransfer) error {
+	updateQuery := "UPDATE bulk_transfers " +
+		"SET bank_account_id = ?, status = ?, failure_reason = ?, updated_at = ? " +
+		"WHERE id = ?"
+
+	_, err := repo.DB.Executor().Exec(updateQuery, data.BankAccountID, data.Status, data.FailureReason, data.UpdatedAt, data.ID)
+
+	return err
+}
+
+// Transition updates the mutable values of a bulk transfer only if it is still in the from status,
+// otherwise ErrStatusConflict is returned. It lets concurrent processes claim a bulk transfer.
+func (repo Repo) Transition(data BulkTransfer, from string) error {
+	updateQuery := "UPDATE bulk_transfers " +
+		"SET bank_account_id = ?, status = ?, failure_reason = ?, updated_at = ? " +
+		"WHERE id = ? AND status = ?"
+
+	return repo.exec(updateQuery, data.BankAccountID, data.Status, data.FailureReason, data.UpdatedAt, data.ID, from)
+}
+
+// Cancel updates the status, the totals, the required approvals and the cancellation of a bulk transfer only if it is still in the
+// from status, otherwise ErrStatusConflict is returned
+func (repo Repo) Cancel(data BulkTransfer, from string) error {
+	updateQuery := "UPDATE bulk_transfers " +
+		"SET status = ?, transfers_count = ?, total_cents = ?, required_approvals = ?, cancellation_reason = ?, cancelled_by = ?, cancelled_at = ?, updated_at = ? " +
+		"WHERE id = ? AND status = ?"
+
+	return repo.exec(updateQuery, data.Status, data.TransfersCount, data.TotalCents, data.RequiredApprovals, data.CancellationReason, data.CancelledBy,
+		nullableTime(data.CancelledAt), data.UpdatedAt, data.ID, from)
+}
+
+// exec runs the guarded update, returning ErrStatusConflict when nothing was updated
+func (repo Repo) exec(query string, args ...any) error {
+	res, err := repo.DB.Executor().Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrStatusConflict
+	}
+
+	return nil
+}
+
+// CreateLines stores the credit transfers of a bulk transfer
+func (repo Repo) CreateLines(bulkTransferID uint, lines LineList) error {
+	insertQuery := "INSERT INTO bulk_transfer_lines" +
+		"(bulk_transfer_id, line_index, counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, description, beneficiary_id, priority) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+	for _, line := range lines {
+		_, err := repo.DB.Executor().Exec(
+			insertQuery,
+			bulkTransferID,
+			line.Index,
+			line.CounterPartyName,
+			line.CounterPartyIban,
+			line.CounterPartyBic,
+			line.AmountCents,
+			line.AmountCurrency,
+			line.Description,
+			nullableID(line.BeneficiaryID),
+			line.Priority)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadLines list the credit transfers of a bulk transfer in their original order
+func (repo Repo) ReadLines(bulkTransferID uint) (LineList, error) {
+	query := "SELECT id, bulk_transfer_id, line_index, counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, description, beneficiary_id, priority, cancellation_reason, cancelled_by, cancelled_at " +
+		" FROM bulk_transfer_lines" +
+		" WHERE bulk_transfer_id = ?" +
+		" ORDER BY line_index"
+
+	rows, err := repo.DB.Executor().Query(query, bulkTransferID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var lines LineList
+	for rows.Next() {
+		var line Line
+		var beneficiaryID sql.NullInt64
+		var cancelledAt sql.NullTime
+		err := rows.Scan(
+			&line.ID,
+			&line.BulkTransferID,
+			&line.Index,
+			&line.CounterPartyName,
+			&line.CounterPartyIban,
+			&line.CounterPartyBic,
+			&line.AmountCents,
+			&line.AmountCurrency,
+			&line.Description,
+			&beneficiaryID,
+			&line.Priority,
+			&line.CancellationReason,
+			&line.CancelledBy,
+			&cancelledAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		line.BeneficiaryID = uint(beneficiaryID.Int64)
+		line.CancelledAt = cancelledAt.Time
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
+
+// CancelLines records the cancellation of the lines of a bulk transfer, found by their index. ErrStatusConflict
+// is returned when a line doesn't exist or was already cancelled.
+func (repo Repo) CancelLines(bulkTransferID uint, lines LineList) error {
+	updateQuery := "UPDATE bulk_transfer_lines " +
+		"SET cancellation_reason = ?, cancelled_by = ?, cancelled_at = ? " +
+		"WHERE bulk_transfer_id = ? AND line_index = ? AND cancelled_at IS NULL"
+
+	for _, line := range lines {
+		err := repo.exec(updateQuery, line.CancellationReason, line.CancelledBy, line.CancelledAt, bulkTransferID, line.Index)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateDecision records the decision of an approver on a bulk transfer
+func (repo Repo) CreateDecision(data Decision) (int, error) {
+	insertQuery := "INSERT INTO bulk_transfer_decisions" +
+		"(bulk_transfer_id, actor, decision, comment, created_at) " +
+		"VALUES (?, ?, ?, ?, ?)"
+
+	res, err := repo.DB.Executor().Exec(insertQuery, data.BulkTransferID, data.Actor, data.Decision, data.Comment, data.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+
+	return int(id), err
+}
+
+// ReadDecisions list the decisions taken on a bulk transfer, oldest first
+func (repo Repo) ReadDecisions(bulkTransferID uint) (DecisionList, error) {
+	query := "SELECT id, bulk_transfer_id, actor, decision, comment, created_at " +
+		" FROM bulk_transfer_decisions" +
+		" WHERE bulk_transfer_id = ?" +
+		" ORDER BY id"
+
+	rows, err := repo.DB.Executor().Query(query, bulkTransferID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var decisions DecisionList
+	for rows.Next() {
+		var decision Decision
+		err := rows.Scan(
+			&decision.ID,
+			&decision.BulkTransferID,
+			&decision.Actor,
+			&decision.Decision,
+			&decision.Comment,
+			&decision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, decision)
+	}
+
+	return decisions, rows.Err()
+}
+
+// Hold flags the bulk transfer as held for review and records the screening matches holding it, only if it is
+// still in the from status, otherwise ErrStatusConflict is returned
+func (repo Repo) Hold(data BulkTransfer, matches MatchList, from string) error {
+	updateQuery := "UPDATE bulk_transfers " +
+		"SET bank_account_id = ?, status = ?, screening_hits = ?, updated_at = ? " +
+		"WHERE id = ? AND status = ?"
+
+	err := repo.exec(updateQuery, data.BankAccountID, data.Status, len(matches), data.UpdatedAt, data.ID, from)
+	if err != nil {
+		return err
+	}
+
+	insertQuery := "INSERT INTO screening_matches" +
+		"(bulk_transfer_id, line_index, field, entry_id, entry_name, entry_source, score, created_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+
+	for _, match := range matches {
+		_, err = repo.DB.Executor().Exec(insertQuery, data.ID, match.Index, match.Field, match.EntryID, match.EntryName,
+			match.EntrySource, match.Score, data.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadMatches list the screening matches that held a bulk transfer for review, in the order of its lines
+func (repo Repo) ReadMatches(bulkTransferID uint) (MatchList, error) {
+	query := "SELECT id, bulk_transfer_id, line_index, field, entry_id, entry_name, entry_source, score, created_at " +
+		" FROM screening_matches" +
+		" WHERE bulk_transfer_id = ?" +
+		" ORDER BY line_index, id"
+
+	rows, err := repo.DB.Executor().Query(query, bulkTransferID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var matches MatchList
+	for rows.Next() {
+		var match Match
+		err := rows.Scan(
+			&match.ID,
+			&match.BulkTransferID,
+			&match.Index,
+			&match.Field,
+			&match.EntryID,
+			&match.EntryName,
+			&match.EntrySource,
+			&match.Score,
+			&match.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+
+	return matches, rows.Err()
+}
+
+// CreateReview records the release, or the block, of a bulk transfer held for review
+func (repo Repo) CreateReview(data Review) (int, error) {
+	insertQuery := "INSERT INTO screening_reviews" +
+		"(bulk_transfer_id, actor, outcome, comment, created_at) " +
+		"VALUES (?, ?, ?, ?, ?)"
+
+	res, err := repo.DB.Executor().Exec(insertQuery, data.BulkTransferID, data.Actor, data.Outcome, data.Comment, data.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+
+	return int(id), err
+}
+
+// ReadReview the review of a bulk transfer, sql.ErrNoRows when it wasn't reviewed
+func (repo Repo) ReadReview(bulkTransferID uint) (Review, error) {
+	query := "SELECT id, bulk_transfer_id, actor, outcome, comment, created_at " +
+		" FROM screening_reviews" +
+		" WHERE bulk_transfer_id = ?"
+
+	var review Review
+	err := repo.DB.Executor().QueryRow(query, bulkTransferID).Scan(
+		&review.ID,
+		&review.BulkTransferID,
+		&review.Actor,
+		&review.Outcome,
+		&review.Comment,
+		&review.CreatedAt,
+	)
+	if err != nil {
+		return Review{}, err
+	}
+
+	return review, nil
+}
+
+// CreateOutcomes records the outcome of the lines of a best effort bulk transfer
+func (repo Repo) CreateOutcomes(bulkTransferID uint, outcomes OutcomeList) error {
+	insertQuery := "INSERT INTO bulk_transfer_outcomes" +
+		"(bulk_transfer_id, line_index, status, reason, message, transaction_id, created_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?)"
+
+	for _, outcome := range outcomes {
+		_, err := repo.DB.Executor().Exec(insertQuery, bulkTransferID, outcome.Index, outcome.Status, outcome.Reason,
+			outcome.Message, nullableID(outcome.TransactionID), outcome.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadOutcomes list the outcomes of the lines of a best effort bulk transfer, in the order of its lines
+func (repo Repo) ReadOutcomes(bulkTransferID uint) (OutcomeList, error) {
+	query := "SELECT id, bulk_transfer_id, line_index, status, reason, message, transaction_id, created_at " +
+		" FROM bulk_transfer_outcomes" +
+		" WHERE bulk_transfer_id = ?" +
+		" ORDER BY line_index"
+
+	rows, err := repo.DB.Executor().Query(query, bulkTransferID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var outcomes OutcomeList
+	for rows.Next() {
+		var outcome Outcome
+		var transactionID sql.NullInt64
+		err := rows.Scan(
+			&outcome.ID,
+			&outcome.BulkTransferID,
+			&outcome.Index,
+			&outcome.Status,
+			&outcome.Reason,
+			&outcome.Message,
+			&transactionID,
+			&outcome.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		outcome.TransactionID = uint(transactionID.Int64)
+		outcomes = append(outcomes, outcome)
+	}
+
+	return outcomes, rows.Err()
+}
+
+// CreateJob records the job of a bulk transfer submitted asynchronously
+func (repo Repo) CreateJob(data Job) (int, error) {
+	insertQuery := "INSERT INTO bulk_transfer_jobs" +
+		"(bulk_transfer_id, status, lines_total, lines_done, error, created_at, started_at, finished_at, updated_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+	res, err := repo.DB.Executor().Exec(insertQuery, data.BulkTransferID, data.Status, data.LinesTotal, data.LinesDone, data.Error,
+		data.CreatedAt, nullableTime(data.StartedAt), nullableTime(data.FinishedAt), data.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+
+	return int(id), err
+}
+
+// ReadJob a job, sql.ErrNoRows when it doesn't exist
+func (repo Repo) ReadJob(jobID uint) (Job, error) {
+	query := "SELECT id, bulk_transfer_id, status, lines_total, lines_done, error, created_at, started_at, finished_at, updated_at, owner, lease_expires_at " +
+		" FROM bulk_transfer_jobs" +
+		" WHERE id = ?"
+
+	return scanJob(repo.DB.Executor().QueryRow(query, jobID))
+}
+
+// ReadPendingJobs list the jobs that are queued or were left running, oldest first
+func (repo Repo) ReadPendingJobs() (JobList, error) {
+	query := "SELECT id, bulk_transfer_id, status, lines_total, lines_done, error, created_at, started_at, finished_at, updated_at, owner, lease_expires_at " +
+		" FROM bulk_transfer_jobs" +
+		" WHERE status IN ('queued', 'running')" +
+		" ORDER BY id"
+
+	rows, err := repo.DB.Executor().Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var jobs JobList
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// UpdateJob the status, the progress and the lease of a job
+func (repo Repo) UpdateJob(data Job) error {
+	updateQuery := "UPDATE bulk_transfer_jobs " +
+		"SET status = ?, lines_done = ?, error = ?, started_at = ?, finished_at = ?, updated_at = ?, owner = ?, lease_expires_at = ? " +
+		"WHERE id = ?"
+
+	_, err := repo.DB.Executor().Exec(updateQuery, data.Status, data.LinesDone, data.Error, nullableTime(data.StartedAt),
+		nullableTime(data.FinishedAt), data.UpdatedAt, data.Owner, nullableTime(data.LeaseExpiresAt), data.ID)
+
+	return err
+}
+
+// ClaimJob moves the job to running on behalf of its owner, with its lease, only if it is queued or if the lease of
+// its previous owner expired at the time, otherwise ErrStatusConflict is returned. It lets concurrent workers, of
+// this process or of another one, claim a job.
+func (repo Repo) ClaimJob(data Job, at time.Time) error {
+	updateQuery := "UPDATE bulk_transfer_jobs " +
+		"SET status = 'running', started_at = ?, updated_at = ?, owner = ?, lease_expires_at = ? " +
+		"WHERE id = ? AND (status = 'queued' OR (status = 'running' AND (lease_expires_at IS NULL OR lease_expires_at <= ?)))"
+
+	return repo.exec(updateQuery, nullableTime(data.StartedAt), data.UpdatedAt, data.Owner, nullableTime(data.LeaseExpiresAt), data.ID, at)
+}
+
+// RenewJob extends the lease of the running job while its owner holds it, otherwise ErrStatusConflict is returned
+func (repo Repo) RenewJob(data Job) error {
+	updateQuery := "UPDATE bulk_transfer_jobs " +
+		"SET updated_at = ?, lease_expires_at = ? " +
+		"WHERE id = ? AND status = 'running' AND owner = ?"
+
+	return repo.exec(updateQuery, data.UpdatedAt, nullableTime(data.LeaseExpiresAt), data.ID, data.Owner)
+}
+
+// FinishJob records the outcome of the running job, releasing its lease, while its owner holds it, otherwise
+// ErrStatusConflict is returned
+func (repo Repo) FinishJob(data Job) error {
+	updateQuery := "UPDATE bulk_transfer_jobs " +
+		"SET status = ?, lines_done = ?, error = ?, finished_at = ?, updated_at = ?, lease_expires_at = NULL " +
+		"WHERE id = ? AND status = 'running' AND owner = ?"
+
+	return repo.exec(updateQuery, data.Status, data.LinesDone, data.Error, nullableTime(data.FinishedAt), data.UpdatedAt, data.ID, data.Owner)
+}
+
+func scanJob(row scanner) (Job, error) {
+	var job Job
+	var startedAt sql.NullTime
+	var finishedAt sql.NullTime
+	var leaseExpiresAt sql.NullTime
+	err := row.Scan(
+		&job.ID,
+		&job.BulkTransferID,
+		&job.Status,
+		&job.LinesTotal,
+		&job.LinesDone,
+		&job.Error,
+		&job.CreatedAt,
+		&startedAt,
+		&finishedAt,
+		&job.UpdatedAt,
+		&job.Owner,
+		&leaseExpiresAt,
+	)
+	if err != nil {
+		return Job{}, err
+	}
+	job.StartedAt = startedAt.Time
+	job.FinishedAt = finishedAt.Time
+	job.LeaseExpiresAt = leaseExpiresAt.Time
+
+	return job, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scan(row scanner) (BulkTransfer, error) {
+	var bulkTransfer BulkTransfer
+	var bankAccountID sql.NullInt64
+	var executionDate sql.NullTime
+	var templateID sql.NullInt64
+	var cancelledAt sql.NullTime
+	err := row.Scan(
+		&bulkTransfer.ID,
+		&bankAccountID,
+		&bulkTransfer.OrganizationName,
+		&bulkTransfer.OrganizationIban,
+		&bulkTransfer.OrganizationBic,
+		&bulkTransfer.TransfersCount,
+		&bulkTransfer.TotalCents,
+		&bulkTransfer.Status,
+		&bulkTransfer.FailureReason,
+		&executionDate,
+		&templateID,
+		&bulkTransfer.CancellationReason,
+		&bulkTransfer.CancelledBy,
+		&cancelledAt,
+		&bulkTransfer.SubmittedBy,
+		&bulkTransfer.RequiredApprovals,
+		&bulkTransfer.ScreeningHits,
+		&bulkTransfer.AllowDuplicates,
+		&bulkTransfer.ExecutionMode,
+		&bulkTransfer.CreatedAt,
+		&bulkTransfer.UpdatedAt,
+	)
+	if err != nil {
+		return BulkTransfer{}, err
+	}
+	bulkTransfer.BankAccountID = uint(bankAccountID.Int64)
+	bulkTransfer.ExecutionDate = executionDate.Time
+	bulkTransfer.TemplateID = uint(templateID.Int64)
+	bulkTransfer.CancelledAt = cancelledAt.Time
+
+	return bulkTransfer, nil
+}
+
+func nullableTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func nullableID(id uint) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
@@ -0,0 +1,590 @@
+package bulktransferrepo
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+	"time"
+)
+
+func setupBulkTransferRepo() (config.Conn, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return config.Conn{Conn: db}, mock
+}
+
+func TestBulkTransferRepo(t *testing.T) {
+
+	conn, mock := setupBulkTransferRepo()
+	defer func() {
+		mock.ExpectClose()
+		err := conn.Conn.Close()
+		if err != nil {
+			t.Errorf("Error closing connection: %+v", err)
+		}
+	}()
+
+	repo := Repo{DB: conn}
+
+	t.Run("Test constructor.", func(t *testing.T) {
+		r := New(conn)
+
+		assert.NotEmpty(t, r)
+	})
+
+	bulkTransfer := BulkTransfer{
+		ID:               3,
+		BankAccountID:    1,
+		OrganizationName: "ACME Corp",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		OrganizationBic:  "OIVUSCLQXXX",
+		TransfersCount:   2,
+		TotalCents:       2906,
+		Status:           "completed",
+		SubmittedBy:      "john.doe",
+		ExecutionMode:    "all_or_nothing",
+		CreatedAt:        time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC),
+		UpdatedAt:        time.Date(2022, 8, 25, 10, 0, 1, 0, time.UTC),
+	}
+
+	columns := []string{"id", "bank_account_id", "organization_name", "organization_iban", "organization_bic", "transfers_count", "total_cents", "status", "failure_reason", "execution_date", "template_id", "cancellation_reason", "cancelled_by", "cancelled_at", "submitted_by", "required_approvals", "screening_hits", "allow_duplicates", "execution_mode", "created_at", "updated_at"}
+
+	t.Run("Test Create return success", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO bulk_transfers").
+			WithArgs(bulkTransfer.BankAccountID,
+				bulkTransfer.OrganizationName,
+				bulkTransfer.OrganizationIban,
+				bulkTransfer.OrganizationBic,
+				bulkTransfer.TransfersCount,
+				bulkTransfer.TotalCents,
+				bulkTransfer.Status,
+				bulkTransfer.FailureReason,
+				sql.NullTime{},
+				sql.NullInt64{},
+				bulkTransfer.SubmittedBy,
+				bulkTransfer.RequiredApprovals,
+				bulkTransfer.AllowDuplicates,
+				bulkTransfer.ExecutionMode,
+				bulkTransfer.CreatedAt,
+				bulkTransfer.UpdatedAt).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+
+		r, err := repo.Create(bulkTransfer)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, r)
+	})
+
+	t.Run("Test Create return error while inserting on database.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO bulk_transfers").
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.Create(bulkTransfer)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Read return success", func(t *testing.T) {
+		rows := sqlmock.NewRows(columns)
+		rows.AddRow(bulkTransfer.ID, bulkTransfer.BankAccountID, bulkTransfer.OrganizationName, bulkTransfer.OrganizationIban, bulkTransfer.OrganizationBic,
+			bulkTransfer.TransfersCount, bulkTransfer.TotalCents, bulkTransfer.Status, bulkTransfer.FailureReason, nil, nil, "", "", nil, bulkTransfer.SubmittedBy, 0, 0, false, bulkTransfer.ExecutionMode, bulkTransfer.CreatedAt, bulkTransfer.UpdatedAt)
+
+		mock.ExpectQuery("SELECT id, bank_account_id, organization_name, organization_iban, organization_bic, transfers_count, total_cents, status, failure_reason, execution_date, template_id, cancellation_reason, cancelled_by, cancelled_at, submitted_by, required_approvals, screening_hits, allow_duplicates, execution_mode, created_at, updated_at FROM bulk_transfers").
+			WithArgs(bulkTransfer.ID).
+			WillReturnRows(rows)
+
+		s, err := repo.Read(bulkTransfer.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, bulkTransfer, s)
+	})
+
+	t.Run("Test Read return error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, bank_account_id").
+			WithArgs(bulkTransfer.ID).
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.Read(bulkTransfer.ID)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test ReadByFilter return success", func(t *testing.T) {
+		rows := sqlmock.NewRows(columns)
+		rows.AddRow(bulkTransfer.ID, nil, bulkTransfer.OrganizationName, bulkTransfer.OrganizationIban, bulkTransfer.OrganizationBic,
+			bulkTransfer.TransfersCount, bulkTransfer.TotalCents, "failed", "Insufficient credits to complete the transfer", nil, nil, "", "", nil, bulkTransfer.SubmittedBy, 0, 0, false, bulkTransfer.ExecutionMode, bulkTransfer.CreatedAt, bulkTransfer.UpdatedAt)
+
+		mock.ExpectQuery("FROM bulk_transfers WHERE 1 = 1 and status = (.+) ORDER BY id DESC").
+			WithArgs("failed").
+			WillReturnRows(rows)
+
+		s, err := repo.ReadByFilter(map[string]string{"status": "failed"})
+		assert.NoError(t, err)
+		assert.Len(t, s, 1)
+		assert.Zero(t, s[0].BankAccountID)
+		assert.Equal(t, "failed", s[0].Status)
+	})
+
+	t.Run("Test ReadByFilter return error on unknown filter", func(t *testing.T) {
+		_, err := repo.ReadByFilter(map[string]string{"1 = 1; DROP TABLE bulk_transfers; --": "x"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Update return success.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE bulk_transfers").
+			WithArgs(bulkTransfer.BankAccountID, bulkTransfer.Status, bulkTransfer.FailureReason, bulkTransfer.UpdatedAt, bulkTransfer.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.Update(bulkTransfer)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Update return error.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE bulk_transfers").
+			WillReturnError(fmt.Errorf("error"))
+
+		err := repo.Update(bulkTransfer)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test ReadDue return the scheduled bulk transfers due", func(t *testing.T) {
+		at := time.Date(2022, 8, 31, 0, 0, 0, 0, time.UTC)
+		rows := sqlmock.NewRows(columns)
+		rows.AddRow(bulkTransfer.ID, nil, bulkTransfer.OrganizationName, bulkTransfer.OrganizationIban, bulkTransfer.OrganizationBic,
+			bulkTransfer.TransfersCount, bulkTransfer.TotalCents, "scheduled", "", at, nil, "", "", nil, bulkTransfer.SubmittedBy, 0, 0, false, bulkTransfer.ExecutionMode, bulkTransfer.CreatedAt, bulkTransfer.UpdatedAt)
+
+		mock.ExpectQuery("FROM bulk_transfers WHERE status = 'scheduled' and execution_date <= (.+) ORDER BY execution_date, id").
+			WithArgs(at).
+			WillReturnRows(rows)
+
+		s, err := repo.ReadDue(at)
+		assert.NoError(t, err)
+		assert.Len(t, s, 1)
+		assert.Equal(t, at, s[0].ExecutionDate)
+	})
+
+	t.Run("Test Transition return success.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE bulk_transfers (.+) WHERE id = (.+) AND status = (.+)").
+			WithArgs(bulkTransfer.BankAccountID, bulkTransfer.Status, bulkTransfer.FailureReason, bulkTransfer.UpdatedAt, bulkTransfer.ID, "processing").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Transition(bulkTransfer, "processing")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Transition return conflict when the status changed.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE bulk_transfers").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Transition(bulkTransfer, "processing")
+		assert.ErrorIs(t, err, ErrStatusConflict)
+	})
+
+	t.Run("Test Cancel return success.", func(t *testing.T) {
+		cancelled := bulkTransfer
+		cancelled.Status = "cancelled"
+		cancelled.CancellationReason = "duplicate payroll"
+		cancelled.CancelledBy = "jane.doe"
+		cancelled.CancelledAt = time.Date(2022, 8, 26, 9, 0, 0, 0, time.UTC)
+
+		mock.ExpectExec("UPDATE bulk_transfers (.+) WHERE id = (.+) AND status = (.+)").
+			WithArgs(cancelled.Status, cancelled.TransfersCount, cancelled.TotalCents, cancelled.RequiredApprovals, cancelled.CancellationReason, cancelled.CancelledBy,
+				sql.NullTime{Time: cancelled.CancelledAt, Valid: true}, cancelled.UpdatedAt, cancelled.ID, "scheduled").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Cancel(cancelled, "scheduled")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Cancel return conflict when the status changed.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE bulk_transfers").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Cancel(bulkTransfer, "scheduled")
+		assert.ErrorIs(t, err, ErrStatusConflict)
+	})
+
+	line := Line{
+		ID:               1,
+		BulkTransferID:   bulkTransfer.ID,
+		Index:            0,
+		CounterPartyName: "Bip Bip",
+		CounterPartyIban: "EE303680981021245685",
+		CounterPartyBic:  "CRLYFRPPTOU",
+		AmountCents:      1453,
+		AmountCurrency:   "EUR",
+		Description:      "Wonderland/4410",
+		Priority:         1,
+	}
+
+	t.Run("Test CreateLines return success.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO bulk_transfer_lines").
+			WithArgs(bulkTransfer.ID, line.Index, line.CounterPartyName, line.CounterPartyIban, line.CounterPartyBic, line.AmountCents, line.AmountCurrency, line.Description, sql.NullInt64{}, line.Priority).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.CreateLines(bulkTransfer.ID, LineList{line})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test CreateLines return error.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO bulk_transfer_lines").
+			WillReturnError(fmt.Errorf("error"))
+
+		err := repo.CreateLines(bulkTransfer.ID, LineList{line})
+		assert.Error(t, err)
+	})
+
+	t.Run("Test ReadLines return success.", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "bulk_transfer_id", "line_index", "counterparty_name", "counterparty_iban", "counterparty_bic", "amount_cents", "amount_currency", "description", "beneficiary_id", "priority", "cancellation_reason", "cancelled_by", "cancelled_at"})
+		rows.AddRow(line.ID, line.BulkTransferID, line.Index, line.CounterPartyName, line.CounterPartyIban, line.CounterPartyBic, line.AmountCents, line.AmountCurrency, line.Description, nil, line.Priority, "", "", nil)
+
+		mock.ExpectQuery("FROM bulk_transfer_lines WHERE bulk_transfer_id = (.+) ORDER BY line_index").
+			WithArgs(bulkTransfer.ID).
+			WillReturnRows(rows)
+
+		s, err := repo.ReadLines(bulkTransfer.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, LineList{line}, s)
+	})
+
+	t.Run("Test CancelLines return success.", func(t *testing.T) {
+		cancelled := line
+		cancelled.CancellationReason = "employee left"
+		cancelled.CancelledBy = "jane.doe"
+		cancelled.CancelledAt = time.Date(2022, 8, 26, 9, 0, 0, 0, time.UTC)
+
+		mock.ExpectExec("UPDATE bulk_transfer_lines (.+) WHERE bulk_transfer_id = (.+) AND line_index = (.+) AND cancelled_at IS NULL").
+			WithArgs(cancelled.CancellationReason, cancelled.CancelledBy, cancelled.CancelledAt, bulkTransfer.ID, cancelled.Index).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.CancelLines(bulkTransfer.ID, LineList{cancelled})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test CancelLines return conflict when the line was already cancelled.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE bulk_transfer_lines").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.CancelLines(bulkTransfer.ID, LineList{line})
+		assert.ErrorIs(t, err, ErrStatusConflict)
+	})
+
+	decision := Decision{
+		ID:             1,
+		BulkTransferID: bulkTransfer.ID,
+		Actor:          "jane.doe",
+		Decision:       "approved",
+		Comment:        "checked against the payroll",
+		CreatedAt:      time.Date(2022, 8, 26, 9, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Test CreateDecision return success.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO bulk_transfer_decisions").
+			WithArgs(decision.BulkTransferID, decision.Actor, decision.Decision, decision.Comment, decision.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		r, err := repo.CreateDecision(decision)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, r)
+	})
+
+	t.Run("Test CreateDecision return error.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO bulk_transfer_decisions").
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.CreateDecision(decision)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test ReadDecisions return success.", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "bulk_transfer_id", "actor", "decision", "comment", "created_at"})
+		rows.AddRow(decision.ID, decision.BulkTransferID, decision.Actor, decision.Decision, decision.Comment, decision.CreatedAt)
+
+		mock.ExpectQuery("FROM bulk_transfer_decisions WHERE bulk_transfer_id = (.+) ORDER BY id").
+			WithArgs(bulkTransfer.ID).
+			WillReturnRows(rows)
+
+		s, err := repo.ReadDecisions(bulkTransfer.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, DecisionList{decision}, s)
+	})
+
+	match := Match{
+		ID:             1,
+		BulkTransferID: bulkTransfer.ID,
+		Index:          1,
+		Field:          "counterparty_name",
+		EntryID:        "EU-1",
+		EntryName:      "Wile E. Coyote",
+		EntrySource:    "EU",
+		Score:          0.97,
+		CreatedAt:      time.Date(2022, 8, 26, 9, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Test Hold return success.", func(t *testing.T) {
+		held := bulkTransfer
+		held.Status = "held_for_review"
+		held.UpdatedAt = match.CreatedAt
+
+		mock.ExpectExec("UPDATE bulk_transfers SET bank_account_id = (.+), status = (.+), screening_hits = (.+), updated_at = (.+) WHERE id = (.+) AND status = (.+)").
+			WithArgs(held.BankAccountID, "held_for_review", 1, held.UpdatedAt, held.ID, "processing").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO screening_matches").
+			WithArgs(held.ID, match.Index, match.Field, match.EntryID, match.EntryName, match.EntrySource, match.Score, held.UpdatedAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		assert.NoError(t, repo.Hold(held, MatchList{match}, "processing"))
+	})
+
+	t.Run("Test Hold return conflict when the status changed.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE bulk_transfers SET bank_account_id").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, repo.Hold(bulkTransfer, MatchList{match}, "processing"), ErrStatusConflict)
+	})
+
+	t.Run("Test ReadMatches return success.", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "bulk_transfer_id", "line_index", "field", "entry_id", "entry_name", "entry_source", "score", "created_at"})
+		rows.AddRow(match.ID, match.BulkTransferID, match.Index, match.Field, match.EntryID, match.EntryName, match.EntrySource, match.Score, match.CreatedAt)
+
+		mock.ExpectQuery("FROM screening_matches WHERE bulk_transfer_id = (.+) ORDER BY line_index, id").
+			WithArgs(bulkTransfer.ID).
+			WillReturnRows(rows)
+
+		s, err := repo.ReadMatches(bulkTransfer.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, MatchList{match}, s)
+	})
+
+	review := Review{
+		ID:             1,
+		BulkTransferID: bulkTransfer.ID,
+		Actor:          "compliance@acme.corp",
+		Outcome:        "released",
+		Comment:        "namesake",
+		CreatedAt:      time.Date(2022, 8, 26, 10, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Test CreateReview return success.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO screening_reviews").
+			WithArgs(review.BulkTransferID, review.Actor, review.Outcome, review.Comment, review.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		r, err := repo.CreateReview(review)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, r)
+	})
+
+	t.Run("Test ReadReview return success.", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "bulk_transfer_id", "actor", "outcome", "comment", "created_at"})
+		rows.AddRow(review.ID, review.BulkTransferID, review.Actor, review.Outcome, review.Comment, review.CreatedAt)
+
+		mock.ExpectQuery("FROM screening_reviews WHERE bulk_transfer_id = (.+)").
+			WithArgs(bulkTransfer.ID).
+			WillReturnRows(rows)
+
+		s, err := repo.ReadReview(bulkTransfer.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, review, s)
+	})
+
+	t.Run("Test ReadReview return no rows when it wasn't reviewed.", func(t *testing.T) {
+		mock.ExpectQuery("FROM screening_reviews WHERE bulk_transfer_id = (.+)").
+			WithArgs(bulkTransfer.ID).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.ReadReview(bulkTransfer.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	outcomes := OutcomeList{
+		{
+			ID:             1,
+			BulkTransferID: bulkTransfer.ID,
+			Index:          0,
+			Status:         "executed",
+			TransactionID:  7,
+			CreatedAt:      time.Date(2022, 8, 25, 10, 0, 1, 0, time.UTC),
+		},
+		{
+			ID:             2,
+			BulkTransferID: bulkTransfer.ID,
+			Index:          1,
+			Status:         "skipped",
+			Reason:         "insufficient_funds",
+			Message:        "Insufficient credits to complete the transfer",
+			CreatedAt:      time.Date(2022, 8, 25, 10, 0, 1, 0, time.UTC),
+		},
+	}
+
+	t.Run("Test CreateOutcomes return success.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO bulk_transfer_outcomes").
+			WithArgs(bulkTransfer.ID, 0, "executed", "", "", sql.NullInt64{Int64: 7, Valid: true}, outcomes[0].CreatedAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO bulk_transfer_outcomes").
+			WithArgs(bulkTransfer.ID, 1, "skipped", "insufficient_funds", outcomes[1].Message, sql.NullInt64{}, outcomes[1].CreatedAt).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+
+		assert.NoError(t, repo.CreateOutcomes(bulkTransfer.ID, outcomes))
+	})
+
+	t.Run("Test CreateOutcomes return error.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO bulk_transfer_outcomes").
+			WillReturnError(fmt.Errorf("error"))
+
+		assert.Error(t, repo.CreateOutcomes(bulkTransfer.ID, outcomes))
+	})
+
+	t.Run("Test ReadOutcomes return success.", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "bulk_transfer_id", "line_index", "status", "reason", "message", "transaction_id", "created_at"})
+		rows.AddRow(1, bulkTransfer.ID, 0, "executed", "", "", 7, outcomes[0].CreatedAt)
+		rows.AddRow(2, bulkTransfer.ID, 1, "skipped", "insufficient_funds", outcomes[1].Message, nil, outcomes[1].CreatedAt)
+
+		mock.ExpectQuery("FROM bulk_transfer_outcomes WHERE bulk_transfer_id = (.+) ORDER BY line_index").
+			WithArgs(bulkTransfer.ID).
+			WillReturnRows(rows)
+
+		s, err := repo.ReadOutcomes(bulkTransfer.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, outcomes, s)
+	})
+
+	job := Job{
+		ID:             5,
+		BulkTransferID: bulkTransfer.ID,
+		Status:         "queued",
+		LinesTotal:     2,
+		CreatedAt:      time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC),
+		UpdatedAt:      time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC),
+	}
+	jobColumns := []string{"id", "bulk_transfer_id", "status", "lines_total", "lines_done", "error", "created_at", "started_at", "finished_at", "updated_at", "owner", "lease_expires_at"}
+
+	t.Run("Test CreateJob return success.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO bulk_transfer_jobs").
+			WithArgs(job.BulkTransferID, job.Status, job.LinesTotal, 0, "", job.CreatedAt, sql.NullTime{}, sql.NullTime{}, job.UpdatedAt).
+			WillReturnResult(sqlmock.NewResult(5, 1))
+
+		r, err := repo.CreateJob(job)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, r)
+	})
+
+	t.Run("Test ReadJob return success.", func(t *testing.T) {
+		rows := sqlmock.NewRows(jobColumns)
+		rows.AddRow(job.ID, job.BulkTransferID, job.Status, job.LinesTotal, 0, "", job.CreatedAt, nil, nil, job.UpdatedAt, "", nil)
+
+		mock.ExpectQuery("FROM bulk_transfer_jobs WHERE id = (.+)").
+			WithArgs(job.ID).
+			WillReturnRows(rows)
+
+		s, err := repo.ReadJob(job.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, job, s)
+	})
+
+	t.Run("Test ReadJob return no rows when it doesn't exist.", func(t *testing.T) {
+		mock.ExpectQuery("FROM bulk_transfer_jobs WHERE id = (.+)").
+			WithArgs(uint(9)).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.ReadJob(9)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("Test ReadPendingJobs return the queued and running jobs.", func(t *testing.T) {
+		started := time.Date(2022, 8, 25, 10, 0, 1, 0, time.UTC)
+		rows := sqlmock.NewRows(jobColumns)
+		rows.AddRow(job.ID, job.BulkTransferID, "running", job.LinesTotal, 0, "", job.CreatedAt, started, nil, started, "worker-1", started.Add(time.Minute))
+
+		mock.ExpectQuery("FROM bulk_transfer_jobs WHERE status IN \\('queued', 'running'\\) ORDER BY id").
+			WillReturnRows(rows)
+
+		s, err := repo.ReadPendingJobs()
+		assert.NoError(t, err)
+		assert.Len(t, s, 1)
+		assert.Equal(t, "running", s[0].Status)
+		assert.Equal(t, started, s[0].StartedAt)
+		assert.Equal(t, "worker-1", s[0].Owner)
+		assert.Equal(t, started.Add(time.Minute), s[0].LeaseExpiresAt)
+	})
+
+	t.Run("Test UpdateJob return success.", func(t *testing.T) {
+		done := job
+		done.Status = "done"
+		done.LinesDone = 2
+		done.StartedAt = time.Date(2022, 8, 25, 10, 0, 1, 0, time.UTC)
+		done.FinishedAt = time.Date(2022, 8, 25, 10, 0, 2, 0, time.UTC)
+		done.UpdatedAt = done.FinishedAt
+
+		mock.ExpectExec("UPDATE bulk_transfer_jobs SET (.+) WHERE id = (.+)").
+			WithArgs("done", 2, "", sql.NullTime{Time: done.StartedAt, Valid: true}, sql.NullTime{Time: done.FinishedAt, Valid: true}, done.UpdatedAt, "", sql.NullTime{}, done.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.UpdateJob(done))
+	})
+
+	running := job
+	running.Status = "running"
+	running.StartedAt = time.Date(2022, 8, 25, 10, 0, 1, 0, time.UTC)
+	running.UpdatedAt = running.StartedAt
+	running.Owner = "worker-1"
+	running.LeaseExpiresAt = running.StartedAt.Add(time.Minute)
+
+	t.Run("Test ClaimJob return success.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE bulk_transfer_jobs SET status = 'running', (.+) WHERE id = (.+) AND \\(status = 'queued' OR \\(status = 'running' AND \\(lease_expires_at IS NULL OR lease_expires_at <= (.+)\\)\\)\\)").
+			WithArgs(sql.NullTime{Time: running.StartedAt, Valid: true}, running.UpdatedAt, "worker-1", sql.NullTime{Time: running.LeaseExpiresAt, Valid: true}, running.ID, running.StartedAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.ClaimJob(running, running.StartedAt))
+	})
+
+	t.Run("Test ClaimJob return conflict when the job is held by another worker.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE bulk_transfer_jobs").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, repo.ClaimJob(running, running.StartedAt), ErrStatusConflict)
+	})
+
+	t.Run("Test RenewJob return success.", func(t *testing.T) {
+		renewed := running
+		renewed.UpdatedAt = running.StartedAt.Add(30 * time.Second)
+		renewed.LeaseExpiresAt = renewed.UpdatedAt.Add(time.Minute)
+
+		mock.ExpectExec("UPDATE bulk_transfer_jobs SET updated_at = (.+), lease_expires_at = (.+) WHERE id = (.+) AND status = 'running' AND owner = (.+)").
+			WithArgs(renewed.UpdatedAt, sql.NullTime{Time: renewed.LeaseExpiresAt, Valid: true}, renewed.ID, "worker-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.RenewJob(renewed))
+	})
+
+	t.Run("Test RenewJob return conflict when the lease was lost.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE bulk_transfer_jobs").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, repo.RenewJob(running), ErrStatusConflict)
+	})
+
+	t.Run("Test FinishJob return success.", func(t *testing.T) {
+		done := running
+		done.Status = "done"
+		done.LinesDone = 2
+		done.FinishedAt = time.Date(2022, 8, 25, 10, 0, 2, 0, time.UTC)
+		done.UpdatedAt = done.FinishedAt
+
+		mock.ExpectExec("UPDATE bulk_transfer_jobs SET (.+), lease_expires_at = NULL WHERE id = (.+) AND status = 'running' AND owner = (.+)").
+			WithArgs("done", 2, "", sql.NullTime{Time: done.FinishedAt, Valid: true}, done.UpdatedAt, done.ID, "worker-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.FinishJob(done))
+	})
+
+	t.Run("Test FinishJob return conflict when the lease was lost.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE bulk_transfer_jobs").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, repo.FinishJob(running), ErrStatusConflict)
+	})
+}
@@ -0,0 +1,126 @@
+package idempotencyrepo
+
+import (
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"time"
+)
+
+// ErrAlreadyExists is returned when the idempotency key is already registered
+var ErrAlreadyExists = errors.New("idempotency key already exists")
+
+// Repo struct
+type Repo struct {
+	DB config.Conn
+}
+
+// IdempotencyKey Struct that represents a request idempotency key and the response it produced.
+// Keys are unique within their scope. A zero StatusCode means the original request is still being processed.
+type IdempotencyKey struct {
+	Scope        string
+	Key          string
+	RequestHash  string
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+	CreatedAt    time.Time
+}
+
+// IdempotencyRepository Interface for the idempotency keys registry
+type IdempotencyRepository interface {
+	Create(data IdempotencyKey) error
+	Read(scope, key string) (IdempotencyKey, error)
+	Update(data IdempotencyKey) error
+	Delete(scope, key string) error
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// New Returns a new instance of DB.
+func New(db config.Conn) Repo {
+	return Repo{
+		DB: db,
+	}
+}
+
+// Create registers a new idempotency key, failing with ErrAlreadyExists if it is already taken in its scope
+func (repo Repo) Create(data IdempotencyKey) error {
+	insertQuery := "INSERT OR IGNORE INTO idempotency_keys" +
+		"(scope, idempotency_key, request_hash, status_code, content_type, response_body, created_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?)"
+
+	res, err := repo.DB.Executor().Exec(insertQuery, data.Scope, data.Key, data.RequestHash, data.StatusCode, data.ContentType, data.ResponseBody, data.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrAlreadyExists
+	}
+
+	return nil
+}
+
+// Read an idempotency key of the scope
+func (repo Repo) Read(scope, key string) (IdempotencyKey, error) {
+	query := "SELECT scope, idempotency_key, request_hash, status_code, content_type, response_body, created_at " +
+		" FROM idempotency_keys" +
+		" WHERE scope = ? AND idempotency_key = ?"
+
+	row := repo.DB.Executor().QueryRow(query, scope, key)
+
+	var idempotencyKey IdempotencyKey
+	err := row.Scan(
+		&idempotencyKey.Scope,
+		&idempotencyKey.Key,
+		&idempotencyKey.RequestHash,
+		&idempotencyKey.StatusCode,
+		&idempotencyKey.ContentType,
+		&idempotencyKey.ResponseBody,
+		&idempotencyKey.CreatedAt,
+	)
+	if err != nil {
+		return IdempotencyKey{}, err
+	}
+
+	return idempotencyKey, nil
+}
+
+// Update stores the response produced for an idempotency key
+func (repo Repo) Update(data IdempotencyKey) error {
+	updateQuery := "UPDATE idempotency_keys " +
+		"SET status_code = ?, content_type = ?, response_body = ? " +
+		"WHERE scope = ? AND idempotency_key = ?"
+
+	_, err := repo.DB.Executor().Exec(updateQuery, data.StatusCode, data.ContentType, data.ResponseBody, data.Scope, data.Key)
+
+	return err
+}
+
+// Delete an idempotency key of the scope
+func (repo Repo) Delete(scope, key string) error {
+	deleteQuery := "DELETE " +
+		" FROM idempotency_keys" +
+		" WHERE scope = ? AND idempotency_key = ?"
+
+	_, err := repo.DB.Executor().Exec(deleteQuery, scope, key)
+
+	return err
+}
+
+// DeleteExpired removes the idempotency keys created before the given time
+func (repo Repo) DeleteExpired(before time.Time) (int64, error) {
+	deleteQuery := "DELETE " +
+		" FROM idempotency_keys" +
+		" WHERE created_at < ?"
+
+	res, err := repo.DB.Executor().Exec(deleteQuery, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
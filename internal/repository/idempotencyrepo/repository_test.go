@@ -0,0 +1,134 @@
+package idempotencyrepo
+
+import (
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+	"time"
+)
+
+func setupIdempotencyRepo() (config.Conn, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return config.Conn{Conn: db}, mock
+}
+
+func TestIdempotencyRepo(t *testing.T) {
+
+	conn, mock := setupIdempotencyRepo()
+	defer func() {
+		mock.ExpectClose()
+		err := conn.Conn.Close()
+		if err != nil {
+			t.Errorf("Error closing connection: %+v", err)
+		}
+	}()
+
+	repo := Repo{DB: conn}
+
+	t.Run("Test constructor.", func(t *testing.T) {
+		r := New(conn)
+
+		assert.NotEmpty(t, r)
+	})
+
+	idempotencyKey := IdempotencyKey{
+		Scope:        "FR81474608000002006107XXXXX",
+		Key:          "0b7c5e2a-payroll-2022-08",
+		RequestHash:  "6f1ed002ab5595859014ebf0951522d9",
+		StatusCode:   201,
+		ContentType:  "application/json; charset=utf-8",
+		ResponseBody: []byte("{}"),
+		CreatedAt:    time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Test Create return success", func(t *testing.T) {
+		mock.ExpectExec("INSERT OR IGNORE INTO idempotency_keys").
+			WithArgs(idempotencyKey.Scope,
+				idempotencyKey.Key,
+				idempotencyKey.RequestHash,
+				idempotencyKey.StatusCode,
+				idempotencyKey.ContentType,
+				idempotencyKey.ResponseBody,
+				idempotencyKey.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.Create(idempotencyKey)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Create return ErrAlreadyExists when the key is taken", func(t *testing.T) {
+		mock.ExpectExec("INSERT OR IGNORE INTO idempotency_keys").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Create(idempotencyKey)
+		assert.ErrorIs(t, err, ErrAlreadyExists)
+	})
+
+	t.Run("Test Create return error while inserting on database.", func(t *testing.T) {
+		mock.ExpectExec("INSERT OR IGNORE INTO idempotency_keys").
+			WillReturnError(fmt.Errorf("error"))
+
+		err := repo.Create(idempotencyKey)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Read return success", func(t *testing.T) {
+		selectQuery := "SELECT scope, idempotency_key, request_hash, status_code, content_type, response_body, created_at FROM idempotency_keys"
+
+		rows := sqlmock.NewRows([]string{"scope", "idempotency_key", "request_hash", "status_code", "content_type", "response_body", "created_at"})
+		rows.AddRow(idempotencyKey.Scope, idempotencyKey.Key, idempotencyKey.RequestHash, idempotencyKey.StatusCode, idempotencyKey.ContentType, idempotencyKey.ResponseBody, idempotencyKey.CreatedAt)
+
+		mock.ExpectQuery(selectQuery).
+			WithArgs(idempotencyKey.Scope, idempotencyKey.Key).
+			WillReturnRows(rows)
+
+		s, err := repo.Read(idempotencyKey.Scope, idempotencyKey.Key)
+		assert.NoError(t, err)
+		assert.Equal(t, idempotencyKey, s)
+	})
+
+	t.Run("Test Read return error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT scope, idempotency_key").
+			WithArgs(idempotencyKey.Scope, idempotencyKey.Key).
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.Read(idempotencyKey.Scope, idempotencyKey.Key)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Update return success.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE idempotency_keys").
+			WithArgs(idempotencyKey.StatusCode, idempotencyKey.ContentType, idempotencyKey.ResponseBody, idempotencyKey.Scope, idempotencyKey.Key).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.Update(idempotencyKey)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Delete return success.", func(t *testing.T) {
+		mock.ExpectExec("DELETE").
+			WithArgs(idempotencyKey.Scope, idempotencyKey.Key).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.Delete(idempotencyKey.Scope, idempotencyKey.Key)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test DeleteExpired return the number of removed keys.", func(t *testing.T) {
+		before := time.Date(2022, 8, 24, 10, 0, 0, 0, time.UTC)
+		mock.ExpectExec("DELETE").
+			WithArgs(before).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		n, err := repo.DeleteExpired(before)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+	})
+}
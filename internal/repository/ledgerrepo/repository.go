@@ -0,0 +1,294 @@
+package ledgerrepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnbalanced is returned when the debits and the credits of a journal don't add up to the same amount
+	ErrUnbalanced = errors.New("ledger journal is not balanced")
+	// ErrBalanceMismatch is returned when, once a journal is posted, the ledger balance of a bank account isn't its balance
+	ErrBalanceMismatch = errors.New("ledger balance doesn't match the balance of the bank account")
+)
+
+const (
+	// AccountExternal the ledger account of the funds sent to, or received from, other banks
+	AccountExternal = "external"
+	// AccountEquity the ledger account balancing the opening balances and the adjustments
+	AccountEquity = "equity"
+	// AccountFees the ledger account of the fees charged on the transfers
+	AccountFees = "fees"
+)
+
+// filterColumns maps the filters of the ledger entries to their columns
+var filterColumns = map[string]string{
+	"account":        "e.account",
+	"journal_id":     "e.journal_id",
+	"transaction_id": "e.transaction_id",
+	"operation":      "j.operation",
+	"reference":      "j.reference",
+}
+
+// bankAccountPrefix the prefix of the ledger accounts of the bank accounts
+const bankAccountPrefix = "bank_account:"
+
+// BankAccount returns the ledger account of a bank account
+func BankAccount(bankAccountID uint) string {
+	return fmt.Sprintf("%s%d", bankAccountPrefix, bankAccountID)
+}
+
+// bankAccountOf returns the bank account of a ledger account, false when it isn't the account of a bank account
+func bankAccountOf(account string) (uint, bool) {
+	if !strings.HasPrefix(account, bankAccountPrefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(account, bankAccountPrefix), 10, 64)
+	return uint(id), err == nil
+}
+
+// Repo struct
+type Repo struct {
+	DB config.Conn
+}
+
+// Journal Struct that represents a stored ledger journal, grouping the entries of an operation
+type Journal struct {
+	ID          uint
+	Operation   string
+	Reference   string
+	Description string
+	CreatedAt   time.Time
+}
+
+// EntryList list of Entry
+type EntryList []Entry
+
+// Entry Struct that represents a stored ledger entry
+type Entry struct {
+	ID          uint
+	JournalID   uint
+	Account     string
+	Side        string
+	AmountCents int64
+	Currency    string
+	// TransactionID the transaction the entry was posted for, zero when there is none
+	TransactionID uint
+	CreatedAt     time.Time
+}
+
+// Debit returns an entry taking the amount out of the account
+func Debit(account string, amountCents int64, transactionID uint) Entry {
+	return Entry{Account: account, Side: string(domain.LedgerDebit), AmountCents: amountCents, Currency: domain.AccountCurrency, TransactionID: transactionID}
+}
+
+// Credit returns an entry putting the amount into the account
+func Credit(account string, amountCents int64, transactionID uint) Entry {
+	return Entry{Account: account, Side: string(domain.LedgerCredit), AmountCents: amountCents, Currency: domain.AccountCurrency, TransactionID: transactionID}
+}
+
+// AgainstEquity returns the entries moving a change of the balance of the account against the equity, the
+// account being credited when the change is positive and debited otherwise
+func AgainstEquity(account string, differenceCents int64) EntryList {
+	if differenceCents < 0 {
+		return EntryList{Debit(account, -differenceCents, 0), Credit(AccountEquity, -differenceCents, 0)}
+	}
+	return EntryList{Debit(AccountEquity, differenceCents, 0), Credit(account, differenceCents, 0)}
+}
+
+// LedgerRepository Interface for the ledger
+type LedgerRepository interface {
+	Post(journal Journal, entries EntryList) (int, error)
+	ReadEntries(filters map[string]string) (domain.LedgerEntryList, error)
+	Balance(account string) (int64, error)
+}
+
+// New Returns a new instance of DB.
+func New(db config.Conn) Repo {
+	return Repo{
+		DB: db,
+	}
+}
+
+// Post stores the journal and its entries, returning the journal id. Journals whose debits and credits
+// don't add up to the same amount are refused with ErrUnbalanced. The balances of the bank accounts must be
+// updated first: ErrBalanceMismatch is returned when the ledger balance of a bank account the journal is posted
+// on isn't its balance afterwards, so the unit of work posting it is rolled back.
+func (repo Repo) Post(journal Journal, entries EntryList) (int, error) {
+	if err := balanced(entries); err != nil {
+		return 0, err
+	}
+
+	insertQuery := "INSERT INTO ledger_journals" +
+		"(operation, reference, description, created_at) " +
+		"VALUES (?, ?, ?, ?)"
+
+	res, err := repo.DB.Executor().Exec(insertQuery, journal.Operation, journal.Reference, journal.Description, journal.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	insertQuery = "INSERT INTO ledger_entries" +
+		"(journal_id, account, side, amount_cents, currency, transaction_id, created_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?)"
+
+	for _, entry := range entries {
+		_, err = repo.DB.Executor().Exec(
+			insertQuery,
+			id,
+			entry.Account,
+			entry.Side,
+			entry.AmountCents,
+			entry.Currency,
+			nullableID(entry.TransactionID),
+			journal.CreatedAt)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err = repo.matchBalances(entries); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// matchBalances checks that the ledger balance of every bank account the entries are posted on is its balance.
+// Bank accounts that no longer exist are left out.
+func (repo Repo) matchBalances(entries EntryList) error {
+	query := "SELECT balance_cents, " +
+		"(SELECT COALESCE(SUM(CASE side WHEN 'credit' THEN amount_cents ELSE -amount_cents END), 0) FROM ledger_entries WHERE account = ?)" +
+		" FROM bank_accounts" +
+		" WHERE id = ?"
+
+	checked := map[string]bool{}
+	for _, entry := range entries {
+		bankAccountID, ok := bankAccountOf(entry.Account)
+		if !ok || checked[entry.Account] {
+			continue
+		}
+		checked[entry.Account] = true
+
+		var balanceCents, ledgerCents int64
+		err := repo.DB.Executor().QueryRow(query, entry.Account, bankAccountID).Scan(&balanceCents, &ledgerCents)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if balanceCents != ledgerCents {
+			return fmt.Errorf("%w: bank account %d has %d, its ledger %d", ErrBalanceMismatch, bankAccountID, balanceCents, ledgerCents)
+		}
+	}
+
+	return nil
+}
+
+// balanced checks that the entries are positive and that their debits and credits add up to the same amount
+func balanced(entries EntryList) error {
+	var debits, credits int64
+	for _, entry := range entries {
+		if entry.AmountCents <= 0 {
+			return fmt.Errorf("%w: %s entry of %d on %s", ErrUnbalanced, entry.Side, entry.AmountCents, entry.Account)
+		}
+		switch entry.Side {
+		case string(domain.LedgerDebit):
+			debits += entry.AmountCents
+		case string(domain.LedgerCredit):
+			credits += entry.AmountCents
+		default:
+			return fmt.Errorf("%w: unknown side %q", ErrUnbalanced, entry.Side)
+		}
+	}
+	if len(entries) == 0 || debits != credits {
+		return fmt.Errorf("%w: debits of %d and credits of %d", ErrUnbalanced, debits, credits)
+	}
+	return nil
+}
+
+// ReadEntries list the ledger entries matching every filter, in the order they were posted
+func (repo Repo) ReadEntries(filters map[string]string) (domain.LedgerEntryList, error) {
+	query := "SELECT e.id, e.journal_id, j.operation, j.reference, j.description, e.account, e.side, e.amount_cents, e.currency, e.transaction_id, e.created_at" +
+		" FROM ledger_entries e" +
+		" INNER JOIN ledger_journals j ON j.id = e.journal_id" +
+		" WHERE 1 = 1"
+
+	var bind []any
+	for k, v := range filters {
+		column, ok := filterColumns[k]
+		if !ok {
+			return nil, fmt.Errorf("ledger entries can't be filtered by %s", k)
+		}
+		query += fmt.Sprintf(" and %s = ?", column)
+		bind = append(bind, v)
+	}
+	query += " ORDER BY e.id"
+
+	rows, err := repo.DB.Executor().Query(query, bind...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	entries := domain.LedgerEntryList{}
+	for rows.Next() {
+		var entry domain.LedgerEntry
+		var amountCents int64
+		var currency string
+		var transactionID sql.NullInt64
+		err := rows.Scan(
+			&entry.ID,
+			&entry.JournalID,
+			&entry.Operation,
+			&entry.Reference,
+			&entry.Description,
+			&entry.Account,
+			&entry.Side,
+			&amountCents,
+			&currency,
+			&transactionID,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entry.Amount = domain.NewMoney(amountCents, currency)
+		entry.TransactionID = uint(transactionID.Int64)
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// Balance returns the credits minus the debits posted on the account
+func (repo Repo) Balance(account string) (int64, error) {
+	query := "SELECT COALESCE(SUM(CASE side WHEN 'credit' THEN amount_cents ELSE -amount_cents END), 0)" +
+		" FROM ledger_entries" +
+		" WHERE account = ?"
+
+	var balance int64
+	err := repo.DB.Executor().QueryRow(query, account).Scan(&balance)
+
+	return balance, err
+}
+
+// nullableID stores unset references as NULL
+func nullableID(id uint) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
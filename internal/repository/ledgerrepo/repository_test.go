@@ -0,0 +1,177 @@
+package ledgerrepo
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+	"time"
+)
+
+func setupLedgerRepo() (config.Conn, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return config.Conn{Conn: db}, mock
+}
+
+func TestLedgerRepo(t *testing.T) {
+
+	conn, mock := setupLedgerRepo()
+	defer func() {
+		mock.ExpectClose()
+		err := conn.Conn.Close()
+		if err != nil {
+			t.Errorf("Error closing connection: %+v", err)
+		}
+	}()
+
+	repo := Repo{DB: conn}
+
+	t.Run("Test constructor.", func(t *testing.T) {
+		r := New(conn)
+
+		assert.NotEmpty(t, r)
+	})
+
+	now := time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC)
+	journal := Journal{Operation: "bulk_transfer", Reference: "bulk_transfer:3", CreatedAt: now}
+
+	t.Run("Test Post return success", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO ledger_journals").
+			WithArgs("bulk_transfer", "bulk_transfer:3", "", now).
+			WillReturnResult(sqlmock.NewResult(7, 1))
+		mock.ExpectExec("INSERT INTO ledger_entries").
+			WithArgs(int64(7), "bank_account:1", "debit", int64(1453), "EUR", sql.NullInt64{Int64: 12, Valid: true}, now).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO ledger_entries").
+			WithArgs(int64(7), "external", "credit", int64(1453), "EUR", sql.NullInt64{}, now).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectQuery("SELECT balance_cents, \\(SELECT COALESCE\\(SUM\\(CASE side WHEN 'credit' THEN amount_cents ELSE -amount_cents END\\), 0\\) FROM ledger_entries WHERE account = \\?\\) FROM bank_accounts WHERE id = \\?").
+			WithArgs("bank_account:1", uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"balance_cents", "ledger_cents"}).AddRow(8547, 8547))
+
+		id, err := repo.Post(journal, EntryList{Debit(BankAccount(1), 1453, 12), Credit(AccountExternal, 1453, 0)})
+		assert.NoError(t, err)
+		assert.Equal(t, 7, id)
+	})
+
+	t.Run("Test Post refuses a ledger balance that isn't the balance of the bank account", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO ledger_journals").
+			WithArgs("bulk_transfer", "bulk_transfer:3", "", now).
+			WillReturnResult(sqlmock.NewResult(8, 1))
+		mock.ExpectExec("INSERT INTO ledger_entries").
+			WithArgs(int64(8), "bank_account:1", "debit", int64(1453), "EUR", sql.NullInt64{}, now).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectExec("INSERT INTO ledger_entries").
+			WithArgs(int64(8), "bank_account:2", "credit", int64(1453), "EUR", sql.NullInt64{}, now).
+			WillReturnResult(sqlmock.NewResult(4, 1))
+		mock.ExpectQuery("SELECT balance_cents").
+			WithArgs("bank_account:1", uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"balance_cents", "ledger_cents"}).AddRow(8547, 8547))
+		mock.ExpectQuery("SELECT balance_cents").
+			WithArgs("bank_account:2", uint(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"balance_cents", "ledger_cents"}).AddRow(0, 1453))
+
+		_, err := repo.Post(journal, EntryList{Debit(BankAccount(1), 1453, 0), Credit(BankAccount(2), 1453, 0)})
+		assert.ErrorIs(t, err, ErrBalanceMismatch)
+	})
+
+	t.Run("Test Post leaves out the bank accounts that no longer exist", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO ledger_journals").
+			WithArgs("bulk_transfer", "bulk_transfer:3", "", now).
+			WillReturnResult(sqlmock.NewResult(9, 1))
+		mock.ExpectExec("INSERT INTO ledger_entries").
+			WithArgs(int64(9), "bank_account:3", "debit", int64(1453), "EUR", sql.NullInt64{}, now).
+			WillReturnResult(sqlmock.NewResult(5, 1))
+		mock.ExpectExec("INSERT INTO ledger_entries").
+			WithArgs(int64(9), "external", "credit", int64(1453), "EUR", sql.NullInt64{}, now).
+			WillReturnResult(sqlmock.NewResult(6, 1))
+		mock.ExpectQuery("SELECT balance_cents").
+			WithArgs("bank_account:3", uint(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"balance_cents", "ledger_cents"}))
+
+		id, err := repo.Post(journal, EntryList{Debit(BankAccount(3), 1453, 0), Credit(AccountExternal, 1453, 0)})
+		assert.NoError(t, err)
+		assert.Equal(t, 9, id)
+	})
+
+	t.Run("Test Post refuses unbalanced journals", func(t *testing.T) {
+		for _, entries := range []EntryList{
+			{},
+			{Debit(BankAccount(1), 1453, 0)},
+			{Debit(BankAccount(1), 1453, 0), Credit(AccountExternal, 1450, 0)},
+			{Debit(BankAccount(1), 0, 0), Credit(AccountExternal, 0, 0)},
+			{Debit(BankAccount(1), -5, 0), Debit(AccountExternal, 5, 0)},
+		} {
+			_, err := repo.Post(journal, entries)
+			assert.ErrorIs(t, err, ErrUnbalanced)
+		}
+	})
+
+	t.Run("Test Post return error while inserting on database.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO ledger_journals").
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.Post(journal, EntryList{Debit(BankAccount(1), 1453, 12), Credit(AccountExternal, 1453, 0)})
+		assert.Error(t, err)
+	})
+
+	t.Run("Test ReadEntries return success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "journal_id", "operation", "reference", "description", "account", "side", "amount_cents", "currency", "transaction_id", "created_at"}).
+			AddRow(1, 7, "bulk_transfer", "bulk_transfer:3", "", "bank_account:1", "debit", 1453, "EUR", 12, now)
+
+		mock.ExpectQuery("SELECT e.id, e.journal_id, j.operation, j.reference, j.description, e.account, e.side, e.amount_cents, e.currency, e.transaction_id, e.created_at FROM ledger_entries e INNER JOIN ledger_journals j ON j.id = e.journal_id WHERE 1 = 1 and e.account = \\?").
+			WithArgs("bank_account:1").
+			WillReturnRows(rows)
+
+		entries, err := repo.ReadEntries(map[string]string{"account": "bank_account:1"})
+		assert.NoError(t, err)
+		assert.Equal(t, domain.LedgerEntryList{{
+			ID:            1,
+			JournalID:     7,
+			Operation:     domain.LedgerBulkTransfer,
+			Reference:     "bulk_transfer:3",
+			Account:       "bank_account:1",
+			Side:          domain.LedgerDebit,
+			Amount:        domain.NewMoney(1453, "EUR"),
+			TransactionID: 12,
+			CreatedAt:     now,
+		}}, entries)
+	})
+
+	t.Run("Test ReadEntries return error on unknown filters", func(t *testing.T) {
+		_, err := repo.ReadEntries(map[string]string{"amount_cents": "1"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Balance return the credits minus the debits", func(t *testing.T) {
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(CASE side WHEN 'credit' THEN amount_cents ELSE -amount_cents END\\), 0\\) FROM ledger_entries WHERE account = \\?").
+			WithArgs("bank_account:1").
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(8547))
+
+		balance, err := repo.Balance(BankAccount(1))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(8547), balance)
+	})
+
+	t.Run("Test Balance return error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT COALESCE").
+			WithArgs("bank_account:1").
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.Balance(BankAccount(1))
+		assert.Error(t, err)
+	})
+}
+
+func TestAgainstEquity(t *testing.T) {
+	assert.Equal(t, EntryList{Debit(AccountEquity, 453, 0), Credit("bank_account:1", 453, 0)}, AgainstEquity(BankAccount(1), 453))
+	assert.Equal(t, EntryList{Debit("bank_account:1", 453, 0), Credit(AccountEquity, 453, 0)}, AgainstEquity(BankAccount(1), -453))
+}
@@ -0,0 +1,103 @@
+package reconciliationrepo
+
+import (
+	"database/sql"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"time"
+)
+
+// Repo struct
+type Repo struct {
+	DB config.Conn
+}
+
+// AccountBalance Struct that represents the balance cached on a bank account along with the balance expected
+// from its transactions and the balance of its ledger account
+type AccountBalance struct {
+	BankAccountID    uint
+	OrganizationName string
+	Iban             string
+	BalanceCents     int64
+	ExpectedCents    int64
+	LedgerCents      int64
+}
+
+// Adjustment Struct that represents a stored balance adjustment, accounting for a drift of the balance
+type Adjustment struct {
+	ID            uint
+	BankAccountID uint
+	AmountCents   int64
+	Reason        string
+	CreatedAt     time.Time
+}
+
+// ReconciliationRepository Interface for the balance reconciliation
+type ReconciliationRepository interface {
+	ReadBalances() ([]AccountBalance, error)
+	CreateAdjustment(adjustment Adjustment) (int, error)
+}
+
+// New Returns a new instance of DB.
+func New(db config.Conn) Repo {
+	return Repo{
+		DB: db,
+	}
+}
+
+// ReadBalances list the cached, the expected and the ledger balances of every bank account. The expected balance is
+// the opening balance plus the adjustments and the incoming transactions, minus the outgoing ones. The ledger balance
+// is the balance of the ledger account of the bank account, its credits minus its debits.
+func (repo Repo) ReadBalances() ([]AccountBalance, error) {
+	query := "SELECT b.id, b.organization_name, b.iban, b.balance_cents, b.opening_balance_cents" +
+		" + COALESCE((SELECT SUM(a.amount_cents) FROM balance_adjustments a WHERE a.bank_account_id = b.id), 0)" +
+		" + COALESCE((SELECT SUM(CASE t.direction WHEN 'incoming' THEN t.amount_cents ELSE -t.amount_cents END)" +
+		" FROM transactions t WHERE t.bank_account_id = b.id), 0)" +
+		", COALESCE((SELECT SUM(CASE e.side WHEN 'credit' THEN e.amount_cents ELSE -e.amount_cents END)" +
+		" FROM ledger_entries e WHERE e.account = 'bank_account:' || b.id), 0)" +
+		" FROM bank_accounts b" +
+		" ORDER BY b.id"
+
+	rows, err := repo.DB.Executor().Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var balances []AccountBalance
+	for rows.Next() {
+		var balance AccountBalance
+		err := rows.Scan(
+			&balance.BankAccountID,
+			&balance.OrganizationName,
+			&balance.Iban,
+			&balance.BalanceCents,
+			&balance.ExpectedCents,
+			&balance.LedgerCents,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		balances = append(balances, balance)
+	}
+
+	return balances, rows.Err()
+}
+
+// CreateAdjustment stores a balance adjustment
+func (repo Repo) CreateAdjustment(adjustment Adjustment) (int, error) {
+	insertQuery := "INSERT INTO balance_adjustments" +
+		"(bank_account_id, amount_cents, reason, created_at) " +
+		"VALUES (?, ?, ?, ?)"
+
+	res, err := repo.DB.Executor().Exec(insertQuery, adjustment.BankAccountID, adjustment.AmountCents, adjustment.Reason, adjustment.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+
+	return int(id), err
+}
@@ -0,0 +1,85 @@
+package reconciliationrepo
+
+import (
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+	"time"
+)
+
+func setupReconciliationRepo() (config.Conn, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return config.Conn{Conn: db}, mock
+}
+
+func TestReconciliationRepo(t *testing.T) {
+
+	conn, mock := setupReconciliationRepo()
+	defer func() {
+		mock.ExpectClose()
+		err := conn.Conn.Close()
+		if err != nil {
+			t.Errorf("Error closing connection: %+v", err)
+		}
+	}()
+
+	repo := Repo{DB: conn}
+
+	t.Run("Test constructor.", func(t *testing.T) {
+		r := New(conn)
+
+		assert.NotEmpty(t, r)
+	})
+
+	t.Run("Test ReadBalances return success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "organization_name", "iban", "balance_cents", "expected_cents", "ledger_cents"}).
+			AddRow(1, "ACME Corp", "FR81474608000002006107XXXXX", 8547, 8547, 8547).
+			AddRow(2, "Bip Bip", "EE303680981021245685", 1000, 1453, 1000)
+
+		mock.ExpectQuery("SELECT b.id, b.organization_name, b.iban, b.balance_cents, b.opening_balance_cents").
+			WillReturnRows(rows)
+
+		balances, err := repo.ReadBalances()
+		assert.NoError(t, err)
+		assert.Equal(t, []AccountBalance{
+			{BankAccountID: 1, OrganizationName: "ACME Corp", Iban: "FR81474608000002006107XXXXX", BalanceCents: 8547, ExpectedCents: 8547, LedgerCents: 8547},
+			{BankAccountID: 2, OrganizationName: "Bip Bip", Iban: "EE303680981021245685", BalanceCents: 1000, ExpectedCents: 1453, LedgerCents: 1000},
+		}, balances)
+	})
+
+	t.Run("Test ReadBalances return error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT b.id").
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.ReadBalances()
+		assert.Error(t, err)
+	})
+
+	now := time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC)
+	adjustment := Adjustment{BankAccountID: 2, AmountCents: -453, Reason: "failed transfer", CreatedAt: now}
+
+	t.Run("Test CreateAdjustment return success", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO balance_adjustments").
+			WithArgs(uint(2), int64(-453), "failed transfer", now).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+
+		id, err := repo.CreateAdjustment(adjustment)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, id)
+	})
+
+	t.Run("Test CreateAdjustment return error while inserting on database.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO balance_adjustments").
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.CreateAdjustment(adjustment)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,354 @@
+package templaterepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"time"
+)
+
+var (
+	// ErrStatusConflict is returned when the transfer template is no longer in the expected status
+	ErrStatusConflict = errors.New("transfer template status changed")
+	// ErrOccurrenceConflict is returned when the occurrence of the transfer template was already claimed
+	ErrOccurrenceConflict = errors.New("transfer template occurrence already claimed")
+)
+
+// filterColumns lists the columns transfer templates can be filtered by
+var filterColumns = map[string]bool{
+	"organization_iban": true,
+	"status":            true,
+}
+
+// Repo struct
+type Repo struct {
+	DB config.Conn
+}
+
+// TemplateList list of Template
+type TemplateList []Template
+
+// Template Struct that represents a stored transfer template
+type Template struct {
+	ID               uint
+	Name             string
+	OrganizationName string
+	OrganizationIban string
+	OrganizationBic  string
+	Frequency        string
+	IntervalCount    int
+	DayOfMonth       int
+	TransfersCount   int
+	TotalCents       int64
+	StartDate        time.Time
+	NextOccurrence   time.Time
+	// LastOccurrence the latest occurrence a bulk transfer was created for, zero when there was none
+	LastOccurrence time.Time
+	Status         string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// LineList list of Line
+type LineList []Line
+
+// Line Struct that represents a stored credit transfer of a transfer template
+type Line struct {
+	ID               uint
+	TemplateID       uint
+	Index            int
+	CounterPartyName string
+	CounterPartyIban string
+	CounterPartyBic  string
+	AmountCents      int64
+	AmountCurrency   string
+	Description      string
+	// BeneficiaryID the beneficiary the line pays, zero when it holds its own counterparty
+	BeneficiaryID uint
+}
+
+// TemplateRepository Interface for the transfer templates registry
+type TemplateRepository interface {
+	Create(data Template) (int, error)
+	Read(templateID uint) (Template, error)
+	ReadByFilter(filters map[string]string) (TemplateList, error)
+	ReadDue(at time.Time) (TemplateList, error)
+	Update(data Template) error
+	Transition(data Template, from string) error
+	Advance(data Template, from time.Time) error
+	ReplaceLines(templateID uint, lines LineList) error
+	ReadLines(templateID uint) (LineList, error)
+}
+
+// New Returns a new instance of DB.
+func New(db config.Conn) Repo {
+	return Repo{
+		DB: db,
+	}
+}
+
+// Create new transfer template
+func (repo Repo) Create(data Template) (int, error) {
+	insertQuery := "INSERT INTO transfer_templates" +
+		"(name, organization_name, organization_iban, organization_bic, frequency, interval_count, day_of_month, transfers_count, total_cents, start_date, next_occurrence, status, created_at, updated_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+	res, err := repo.DB.Executor().Exec(
+		insertQuery,
+		data.Name,
+		data.OrganizationName,
+		data.OrganizationIban,
+		data.OrganizationBic,
+		data.Frequency,
+		data.IntervalCount,
+		data.DayOfMonth,
+		data.TransfersCount,
+		data.TotalCents,
+		data.StartDate,
+		data.NextOccurrence,
+		data.Status,
+		data.CreatedAt,
+		data.UpdatedAt)
+
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+
+	return int(id), err
+}
+
+// Read a transfer template
+func (repo Repo) Read(templateID uint) (Template, error) {
+	query := "SELECT id, name, organization_name, organization_iban, organization_bic, frequency, interval_count, day_of_month, transfers_count, total_cents, start_date, next_occurrence, last_occurrence, status, created_at, updated_at " +
+		" FROM transfer_templates" +
+		" WHERE id = ?"
+
+	row := repo.DB.Executor().QueryRow(query, templateID)
+
+	return scan(row)
+}
+
+// ReadByFilter list the transfer templates matching every filter, most recent first
+func (repo Repo) ReadByFilter(filters map[string]string) (TemplateList, error) {
+	query := "SELECT id, name, organization_name, organization_iban, organization_bic, frequency, interval_count, day_of_month, transfers_count, total_cents, start_date, next_occurrence, last_occurrence, status, created_at, updated_at " +
+		" FROM transfer_templates" +
+		" WHERE 1 = 1"
+
+	var bind []any
+	for k, v := range filters {
+		if !filterColumns[k] {
+			return nil, fmt.Errorf("transfer templates can't be filtered by %s", k)
+		}
+		query += fmt.Sprintf(" and %s = ?", k)
+		bind = append(bind, v)
+	}
+	query += " ORDER BY id DESC"
+
+	return repo.query(query, bind...)
+}
+
+// ReadDue list the active transfer templates with an occurrence due at the given time, oldest first
+func (repo Repo) ReadDue(at time.Time) (TemplateList, error) {
+	query := "SELECT id, name, organization_name, organization_iban, organization_bic, frequency, interval_count, day_of_month, transfers_count, total_cents, start_date, next_occurrence, last_occurrence, status, created_at, updated_at " +
+		" FROM transfer_templates" +
+		" WHERE status = 'active' and next_occurrence <= ?" +
+		" ORDER BY next_occurrence, id"
+
+	return repo.query(query, at)
+}
+
+func (repo Repo) query(query string, args ...any) (TemplateList, error) {
+	rows, err := repo.DB.Executor().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var templates TemplateList
+	for rows.Next() {
+		template, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+// Update the values of a transfer template, its status is only changed by Transition
+func (repo Repo) Update(data Template) error {
+	updateQuery := "UPDATE transfer_templates " +
+		"SET name = ?, organization_name = ?, organization_iban = ?, organization_bic = ?, frequency = ?, interval_count = ?, day_of_month = ?, transfers_count = ?, total_cents = ?, start_date = ?, next_occurrence = ?, updated_at = ? " +
+		"WHERE id = ?"
+
+	_, err := repo.DB.Executor().Exec(
+		updateQuery,
+		data.Name,
+		data.OrganizationName,
+		data.OrganizationIban,
+		data.OrganizationBic,
+		data.Frequency,
+		data.IntervalCount,
+		data.DayOfMonth,
+		data.TransfersCount,
+		data.TotalCents,
+		data.StartDate,
+		data.NextOccurrence,
+		data.UpdatedAt,
+		data.ID)
+
+	return err
+}
+
+// Transition updates the status and the next occurrence of a transfer template only if it is still in the
+// from status, otherwise ErrStatusConflict is returned
+func (repo Repo) Transition(data Template, from string) error {
+	updateQuery := "UPDATE transfer_templates " +
+		"SET status = ?, next_occurrence = ?, updated_at = ? " +
+		"WHERE id = ? AND status = ?"
+
+	return repo.exec(ErrStatusConflict, updateQuery, data.Status, data.NextOccurrence, data.UpdatedAt, data.ID, from)
+}
+
+// Advance moves an active transfer template to its next occurrence only if from is still its next occurrence,
+// otherwise ErrOccurrenceConflict is returned. It lets concurrent processes claim an occurrence.
+func (repo Repo) Advance(data Template, from time.Time) error {
+	updateQuery := "UPDATE transfer_templates " +
+		"SET next_occurrence = ?, last_occurrence = ?, updated_at = ? " +
+		"WHERE id = ? AND status = 'active' AND next_occurrence = ?"
+
+	return repo.exec(ErrOccurrenceConflict, updateQuery, data.NextOccurrence, data.LastOccurrence, data.UpdatedAt, data.ID, from)
+}
+
+// exec runs the guarded update, returning conflict when no transfer template was updated
+func (repo Repo) exec(conflict error, query string, args ...any) error {
+	res, err := repo.DB.Executor().Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return conflict
+	}
+
+	return nil
+}
+
+// ReplaceLines stores the credit transfers of a transfer template in place of the previous ones
+func (repo Repo) ReplaceLines(templateID uint, lines LineList) error {
+	if _, err := repo.DB.Executor().Exec("DELETE FROM transfer_template_lines WHERE template_id = ?", templateID); err != nil {
+		return err
+	}
+
+	insertQuery := "INSERT INTO transfer_template_lines" +
+		"(template_id, line_index, counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, description, beneficiary_id) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+	for _, line := range lines {
+		_, err := repo.DB.Executor().Exec(
+			insertQuery,
+			templateID,
+			line.Index,
+			line.CounterPartyName,
+			line.CounterPartyIban,
+			line.CounterPartyBic,
+			line.AmountCents,
+			line.AmountCurrency,
+			line.Description,
+			nullableID(line.BeneficiaryID))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadLines list the credit transfers of a transfer template in their original order
+func (repo Repo) ReadLines(templateID uint) (LineList, error) {
+	query := "SELECT id, template_id, line_index, counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, description, beneficiary_id " +
+		" FROM transfer_template_lines" +
+		" WHERE template_id = ?" +
+		" ORDER BY line_index"
+
+	rows, err := repo.DB.Executor().Query(query, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var lines LineList
+	for rows.Next() {
+		var line Line
+		var beneficiaryID sql.NullInt64
+		err := rows.Scan(
+			&line.ID,
+			&line.TemplateID,
+			&line.Index,
+			&line.CounterPartyName,
+			&line.CounterPartyIban,
+			&line.CounterPartyBic,
+			&line.AmountCents,
+			&line.AmountCurrency,
+			&line.Description,
+			&beneficiaryID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		line.BeneficiaryID = uint(beneficiaryID.Int64)
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scan(row scanner) (Template, error) {
+	var template Template
+	var lastOccurrence sql.NullTime
+	err := row.Scan(
+		&template.ID,
+		&template.Name,
+		&template.OrganizationName,
+		&template.OrganizationIban,
+		&template.OrganizationBic,
+		&template.Frequency,
+		&template.IntervalCount,
+		&template.DayOfMonth,
+		&template.TransfersCount,
+		&template.TotalCents,
+		&template.StartDate,
+		&template.NextOccurrence,
+		&lastOccurrence,
+		&template.Status,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return Template{}, err
+	}
+	template.LastOccurrence = lastOccurrence.Time
+
+	return template, nil
+}
+
+// nullableID stores unset references as NULL
+func nullableID(id uint) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
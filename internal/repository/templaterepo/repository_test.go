@@ -0,0 +1,243 @@
+package templaterepo
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+	"time"
+)
+
+func setupTemplateRepo() (config.Conn, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return config.Conn{Conn: db}, mock
+}
+
+func TestTemplateRepo(t *testing.T) {
+
+	conn, mock := setupTemplateRepo()
+	defer func() {
+		mock.ExpectClose()
+		err := conn.Conn.Close()
+		if err != nil {
+			t.Errorf("Error closing connection: %+v", err)
+		}
+	}()
+
+	repo := Repo{DB: conn}
+
+	t.Run("Test constructor.", func(t *testing.T) {
+		r := New(conn)
+
+		assert.NotEmpty(t, r)
+	})
+
+	template := Template{
+		ID:               4,
+		Name:             "Monthly payroll",
+		OrganizationName: "ACME Corp",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		OrganizationBic:  "OIVUSCLQXXX",
+		Frequency:        "monthly",
+		IntervalCount:    1,
+		DayOfMonth:       25,
+		TransfersCount:   2,
+		TotalCents:       2906,
+		StartDate:        time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC),
+		NextOccurrence:   time.Date(2022, 9, 25, 0, 0, 0, 0, time.UTC),
+		Status:           "active",
+		CreatedAt:        time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC),
+		UpdatedAt:        time.Date(2022, 8, 25, 10, 0, 1, 0, time.UTC),
+	}
+
+	columns := []string{"id", "name", "organization_name", "organization_iban", "organization_bic", "frequency", "interval_count", "day_of_month", "transfers_count", "total_cents", "start_date", "next_occurrence", "last_occurrence", "status", "created_at", "updated_at"}
+	row := func(rows *sqlmock.Rows) *sqlmock.Rows {
+		return rows.AddRow(template.ID, template.Name, template.OrganizationName, template.OrganizationIban, template.OrganizationBic, template.Frequency,
+			template.IntervalCount, template.DayOfMonth, template.TransfersCount, template.TotalCents, template.StartDate, template.NextOccurrence, nil, template.Status, template.CreatedAt, template.UpdatedAt)
+	}
+
+	t.Run("Test Create return success", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO transfer_templates").
+			WithArgs(template.Name,
+				template.OrganizationName,
+				template.OrganizationIban,
+				template.OrganizationBic,
+				template.Frequency,
+				template.IntervalCount,
+				template.DayOfMonth,
+				template.TransfersCount,
+				template.TotalCents,
+				template.StartDate,
+				template.NextOccurrence,
+				template.Status,
+				template.CreatedAt,
+				template.UpdatedAt).
+			WillReturnResult(sqlmock.NewResult(4, 1))
+
+		r, err := repo.Create(template)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, r)
+	})
+
+	t.Run("Test Create return error while inserting on database.", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO transfer_templates").
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.Create(template)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Read return success", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, name, organization_name, organization_iban, organization_bic, frequency, interval_count, day_of_month, transfers_count, total_cents, start_date, next_occurrence, last_occurrence, status, created_at, updated_at FROM transfer_templates").
+			WithArgs(template.ID).
+			WillReturnRows(row(sqlmock.NewRows(columns)))
+
+		s, err := repo.Read(template.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, template, s)
+	})
+
+	t.Run("Test Read return error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, name").
+			WithArgs(template.ID).
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.Read(template.ID)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test ReadByFilter return success", func(t *testing.T) {
+		mock.ExpectQuery("FROM transfer_templates WHERE 1 = 1 and status = (.+) ORDER BY id DESC").
+			WithArgs("active").
+			WillReturnRows(row(sqlmock.NewRows(columns)))
+
+		s, err := repo.ReadByFilter(map[string]string{"status": "active"})
+		assert.NoError(t, err)
+		assert.Equal(t, TemplateList{template}, s)
+	})
+
+	t.Run("Test ReadByFilter return error on unknown filter", func(t *testing.T) {
+		_, err := repo.ReadByFilter(map[string]string{"1 = 1; DROP TABLE transfer_templates; --": "x"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Test ReadDue return the active templates due", func(t *testing.T) {
+		at := time.Date(2022, 9, 25, 6, 0, 0, 0, time.UTC)
+
+		mock.ExpectQuery("FROM transfer_templates WHERE status = 'active' and next_occurrence <= (.+) ORDER BY next_occurrence, id").
+			WithArgs(at).
+			WillReturnRows(row(sqlmock.NewRows(columns)))
+
+		s, err := repo.ReadDue(at)
+		assert.NoError(t, err)
+		assert.Equal(t, TemplateList{template}, s)
+	})
+
+	t.Run("Test Update return success.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE transfer_templates").
+			WithArgs(template.Name, template.OrganizationName, template.OrganizationIban, template.OrganizationBic, template.Frequency, template.IntervalCount,
+				template.DayOfMonth, template.TransfersCount, template.TotalCents, template.StartDate, template.NextOccurrence, template.UpdatedAt, template.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Update(template)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Update return error.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE transfer_templates").
+			WillReturnError(fmt.Errorf("error"))
+
+		err := repo.Update(template)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Transition return success.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE transfer_templates (.+) WHERE id = (.+) AND status = (.+)").
+			WithArgs(template.Status, template.NextOccurrence, template.UpdatedAt, template.ID, "paused").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Transition(template, "paused")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Transition return conflict when the status changed.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE transfer_templates").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Transition(template, "paused")
+		assert.ErrorIs(t, err, ErrStatusConflict)
+	})
+
+	t.Run("Test Advance return success.", func(t *testing.T) {
+		from := time.Date(2022, 8, 25, 0, 0, 0, 0, time.UTC)
+
+		mock.ExpectExec("UPDATE transfer_templates (.+) WHERE id = (.+) AND status = 'active' AND next_occurrence = (.+)").
+			WithArgs(template.NextOccurrence, template.LastOccurrence, template.UpdatedAt, template.ID, from).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Advance(template, from)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Advance return conflict when the occurrence was claimed.", func(t *testing.T) {
+		mock.ExpectExec("UPDATE transfer_templates").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Advance(template, time.Date(2022, 8, 25, 0, 0, 0, 0, time.UTC))
+		assert.ErrorIs(t, err, ErrOccurrenceConflict)
+	})
+
+	line := Line{
+		ID:               1,
+		TemplateID:       template.ID,
+		Index:            0,
+		CounterPartyName: "Bip Bip",
+		CounterPartyIban: "EE303680981021245685",
+		CounterPartyBic:  "CRLYFRPPTOU",
+		AmountCents:      1453,
+		AmountCurrency:   "EUR",
+		Description:      "Wonderland/4410",
+	}
+
+	t.Run("Test ReplaceLines return success.", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM transfer_template_lines WHERE template_id = (.+)").
+			WithArgs(template.ID).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("INSERT INTO transfer_template_lines").
+			WithArgs(template.ID, line.Index, line.CounterPartyName, line.CounterPartyIban, line.CounterPartyBic, line.AmountCents, line.AmountCurrency, line.Description, sql.NullInt64{}).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.ReplaceLines(template.ID, LineList{line})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test ReplaceLines return error.", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM transfer_template_lines").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO transfer_template_lines").
+			WillReturnError(fmt.Errorf("error"))
+
+		err := repo.ReplaceLines(template.ID, LineList{line})
+		assert.Error(t, err)
+	})
+
+	t.Run("Test ReadLines return success.", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "template_id", "line_index", "counterparty_name", "counterparty_iban", "counterparty_bic", "amount_cents", "amount_currency", "description", "beneficiary_id"})
+		rows.AddRow(line.ID, line.TemplateID, line.Index, line.CounterPartyName, line.CounterPartyIban, line.CounterPartyBic, line.AmountCents, line.AmountCurrency, line.Description, nil)
+
+		mock.ExpectQuery("FROM transfer_template_lines WHERE template_id = (.+) ORDER BY line_index").
+			WithArgs(template.ID).
+			WillReturnRows(rows)
+
+		s, err := repo.ReadLines(template.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, LineList{line}, s)
+	})
+}
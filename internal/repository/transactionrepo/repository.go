@@ -2,11 +2,16 @@ package transactionrepo
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/adrianoccosta/exercise-qonto/cmd/config"
 	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"time"
 )
 
+// ErrReversalExceeded is returned when a reversal would give back more than the amount of the transaction
+var ErrReversalExceeded = errors.New("reversal exceeds the amount not reversed yet")
+
 // Repo struct
 type Repo struct {
 	DB config.Conn
@@ -21,10 +26,23 @@ type Transaction struct {
 	CounterPartyName string
 	CounterPartyIban string
 	CounterPartyBic  string
-	AmountCents      int
+	AmountCents      int64
 	AmountCurrency   string
 	BankAccountID    uint
 	Description      string
+	Direction        string
+	BulkTransferID   uint
+	// ReversedTransactionID the transaction this one reverses, zero when it isn't a reversal
+	ReversedTransactionID uint
+	// ReversedCents the part of the transaction reversed so far
+	ReversedCents int64
+	// BeneficiaryID the beneficiary the credit transfer of the transaction referenced, zero when it referenced none
+	BeneficiaryID uint
+	// OverdrawnCents the part of an outgoing transaction paid from the overdraft, the balance being below zero
+	OverdrawnCents int64
+	// FeeOfTransactionID the transfer a fee transaction was charged for, zero when it isn't a fee
+	FeeOfTransactionID uint
+	CreatedAt          time.Time
 }
 
 // TransactionRepository Interface for the payment transactions
@@ -32,6 +50,9 @@ type TransactionRepository interface {
 	Create(data Transaction) (int, error)
 	Read(transactionID uint) (Transaction, error)
 	ReadByFilter(filters map[string]string) (domain.TransactionList, error)
+	Reverse(transactionID uint, amountCents int64) error
+	SumTransferred(bankAccountID uint, since time.Time) (int64, error)
+	ReadDuplicate(bankAccountID uint, counterpartyIban string, amountCents int64, description string, since time.Time) (Transaction, error)
 }
 
 // New Returns a new instance of DB.
@@ -44,10 +65,10 @@ func New(db config.Conn) Repo {
 // Create new transaction
 func (repo Repo) Create(data Transaction) (int, error) {
 	insertQuery := "INSERT INTO transactions" +
-		"(counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, bank_account_id, description) " +
-		"VALUES (?, ?, ?, ?, ?, ?, ?)"
+		"(counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, bank_account_id, description, direction, bulk_transfer_id, reversed_transaction_id, beneficiary_id, overdrawn_cents, fee_of_transaction_id, created_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
 
-	res, err := repo.DB.Conn.Exec(
+	res, err := repo.DB.Executor().Exec(
 		insertQuery,
 		data.CounterPartyName,
 		data.CounterPartyIban,
@@ -55,7 +76,14 @@ func (repo Repo) Create(data Transaction) (int, error) {
 		data.AmountCents,
 		data.AmountCurrency,
 		data.BankAccountID,
-		data.Description)
+		data.Description,
+		data.Direction,
+		nullableID(data.BulkTransferID),
+		nullableID(data.ReversedTransactionID),
+		nullableID(data.BeneficiaryID),
+		data.OverdrawnCents,
+		nullableID(data.FeeOfTransactionID),
+		data.CreatedAt)
 
 	if err != nil {
 		return 0, err
@@ -68,13 +96,15 @@ func (repo Repo) Create(data Transaction) (int, error) {
 
 // Read a transaction
 func (repo Repo) Read(transactionID uint) (Transaction, error) {
-	query := "SELECT id, counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, bank_account_id, description " +
+	query := "SELECT id, counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, bank_account_id, description, direction, bulk_transfer_id, reversed_transaction_id, reversed_cents, beneficiary_id, fee_of_transaction_id, created_at " +
 		" FROM transactions" +
 		" WHERE id = ?"
 
-	row := repo.DB.Conn.QueryRow(query, transactionID)
+	row := repo.DB.Executor().QueryRow(query, transactionID)
 
 	var transaction Transaction
+	var bulkTransferID, reversedTransactionID, beneficiaryID, feeOfTransactionID sql.NullInt64
+	var createdAt sql.NullTime
 	err := row.Scan(
 		&transaction.ID,
 		&transaction.CounterPartyName,
@@ -84,28 +114,56 @@ func (repo Repo) Read(transactionID uint) (Transaction, error) {
 		&transaction.AmountCurrency,
 		&transaction.BankAccountID,
 		&transaction.Description,
+		&transaction.Direction,
+		&bulkTransferID,
+		&reversedTransactionID,
+		&transaction.ReversedCents,
+		&beneficiaryID,
+		&feeOfTransactionID,
+		&createdAt,
 	)
 	if err != nil {
 		return Transaction{}, err
 	}
+	transaction.BulkTransferID = uint(bulkTransferID.Int64)
+	transaction.ReversedTransactionID = uint(reversedTransactionID.Int64)
+	transaction.BeneficiaryID = uint(beneficiaryID.Int64)
+	transaction.FeeOfTransactionID = uint(feeOfTransactionID.Int64)
+	transaction.CreatedAt = createdAt.Time
 
 	return transaction, nil
 }
 
-// ReadByFilter a transaction
+// ReadByFilter a transaction. The outgoing transactions of bulk transfers, but their fees, are flagged as first
+// payments when the account never paid their counterparty before. The overdrawn filter keeps the transactions paid, in part,
+// from the overdraft.
 func (repo Repo) ReadByFilter(filters map[string]string) (domain.TransactionList, error) {
-	query := "SELECT organization_name as name, iban, bic, counterparty_name, counterparty_iban, counterparty_bic, CAST(amount_cents AS float)/100 as amount, amount_currency, description" +
-		" FROM transactions" +
+	query := "SELECT t.id, organization_name as name, iban, bic, counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, description, direction, bulk_transfer_id, reversed_transaction_id, reversed_cents, beneficiary_id, overdrawn_cents, fee_of_transaction_id, " +
+		"direction = 'outgoing' AND bulk_transfer_id IS NOT NULL AND fee_of_transaction_id IS NULL AND NOT EXISTS (" +
+		"SELECT 1 FROM transactions p" +
+		" WHERE p.bank_account_id = t.bank_account_id AND p.counterparty_iban = t.counterparty_iban" +
+		" AND p.direction = 'outgoing' AND p.bulk_transfer_id IS NOT NULL AND p.id < t.id) AS first_payment, " +
+		"created_at" +
+		" FROM transactions t" +
 		" INNER JOIN bank_accounts b ON b.id = bank_account_id" +
 		" WHERE 1 = 1"
 
 	var bind []any
 	for k, v := range filters {
+		if k == "overdrawn" {
+			if v == "true" {
+				query += " and overdrawn_cents > 0"
+			} else {
+				query += " and overdrawn_cents = 0"
+			}
+			continue
+		}
 		query += fmt.Sprintf(" and %s = ?", k)
 		bind = append(bind, v)
 	}
+	query += " ORDER BY t.id"
 
-	rows, err := repo.DB.Conn.Query(query, bind...)
+	rows, err := repo.DB.Executor().Query(query, bind...)
 
 	if err != nil {
 		return nil, err
@@ -114,6 +172,66 @@ func (repo Repo) ReadByFilter(filters map[string]string) (domain.TransactionList
 	return createFromDB(rows)
 }
 
+// Reverse adds the amount to the part of the transaction reversed so far. The update is guarded by the
+// amount of the transaction, so concurrent reversals can never give back more than it: when it would
+// be exceeded nothing is changed and ErrReversalExceeded is returned.
+func (repo Repo) Reverse(transactionID uint, amountCents int64) error {
+	updateQuery := "UPDATE transactions " +
+		"SET reversed_cents = reversed_cents + ? " +
+		"WHERE id = ? AND reversed_cents + ? <= amount_cents"
+
+	res, err := repo.DB.Executor().Exec(updateQuery, amountCents, transactionID, amountCents)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrReversalExceeded
+	}
+
+	return nil
+}
+
+// SumTransferred returns the total of the outgoing transactions of bulk transfers registered on the bank account
+// since the given time. Reversals aren't deducted, fees aren't transfers.
+func (repo Repo) SumTransferred(bankAccountID uint, since time.Time) (int64, error) {
+	query := "SELECT COALESCE(SUM(amount_cents), 0)" +
+		" FROM transactions" +
+		" WHERE bank_account_id = ? AND direction = ? AND bulk_transfer_id IS NOT NULL AND fee_of_transaction_id IS NULL AND created_at >= ?"
+
+	var total int64
+	err := repo.DB.Executor().QueryRow(query, bankAccountID, string(domain.TransactionOutgoing), since).Scan(&total)
+
+	return total, err
+}
+
+// ReadDuplicate returns the latest outgoing transaction of a bulk transfer registered on the bank account since the
+// given time that pays the same amount, with the same description, to the counterparty iban. Transactions reversed
+// in full aren't duplicates. sql.ErrNoRows is returned when there is none.
+func (repo Repo) ReadDuplicate(bankAccountID uint, counterpartyIban string, amountCents int64, description string, since time.Time) (Transaction, error) {
+	query := "SELECT id, bulk_transfer_id, created_at" +
+		" FROM transactions" +
+		" WHERE bank_account_id = ? AND counterparty_iban = ? AND amount_cents = ? AND created_at >= ?" +
+		" AND direction = ? AND bulk_transfer_id IS NOT NULL AND fee_of_transaction_id IS NULL AND description = ? AND reversed_cents < amount_cents" +
+		" ORDER BY created_at DESC, id DESC LIMIT 1"
+
+	var transaction Transaction
+	var bulkTransferID sql.NullInt64
+	err := repo.DB.Executor().
+		QueryRow(query, bankAccountID, counterpartyIban, amountCents, since, string(domain.TransactionOutgoing), description).
+		Scan(&transaction.ID, &bulkTransferID, &transaction.CreatedAt)
+	if err != nil {
+		return Transaction{}, err
+	}
+	transaction.BulkTransferID = uint(bulkTransferID.Int64)
+
+	return transaction, nil
+}
+
 // createFromDB Transaction List mapper
 func createFromDB(rows *sql.Rows) (domain.TransactionList, error) {
 	var allTransactions domain.TransactionList
@@ -122,24 +240,59 @@ func createFromDB(rows *sql.Rows) (domain.TransactionList, error) {
 	}(rows)
 	for rows.Next() {
 		var transaction domain.Transaction
+		var amountCents int64
+		var bulkTransferID, reversedTransactionID, beneficiaryID, feeOfTransactionID sql.NullInt64
+		var reversedCents, overdrawnCents int64
+		var createdAt sql.NullTime
 		err := rows.Scan(
+			&transaction.ID,
 			&transaction.Name,
 			&transaction.Iban,
 			&transaction.Bic,
 			&transaction.CounterPartyName,
 			&transaction.CounterPartyIban,
 			&transaction.CounterPartyBic,
-			&transaction.Amount,
+			&amountCents,
 			&transaction.Currency,
 			&transaction.Description,
+			&transaction.Direction,
+			&bulkTransferID,
+			&reversedTransactionID,
+			&reversedCents,
+			&beneficiaryID,
+			&overdrawnCents,
+			&feeOfTransactionID,
+			&transaction.FirstPayment,
+			&createdAt,
 		)
 
 		if err != nil {
 			return nil, err
 		}
+		transaction.Amount = domain.NewMoney(amountCents, transaction.Currency)
+		transaction.BulkTransferID = uint(bulkTransferID.Int64)
+		transaction.ReversedTransactionID = uint(reversedTransactionID.Int64)
+		transaction.BeneficiaryID = uint(beneficiaryID.Int64)
+		transaction.FeeOfTransactionID = uint(feeOfTransactionID.Int64)
+		if reversedCents != 0 {
+			reversedAmount := domain.NewMoney(reversedCents, transaction.Currency)
+			transaction.ReversedAmount = &reversedAmount
+		}
+		if overdrawnCents != 0 {
+			overdrawnAmount := domain.NewMoney(overdrawnCents, transaction.Currency)
+			transaction.OverdrawnAmount = &overdrawnAmount
+		}
+		if createdAt.Valid {
+			transaction.CreatedAt = &createdAt.Time
+		}
 
 		allTransactions = append(allTransactions, transaction)
 	}
 
 	return allTransactions, nil
 }
+
+// nullableID stores unset references as NULL
+func nullableID(id uint) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
@@ -1,14 +1,17 @@
 package transactionrepo
 
 import (
+	"database/sql"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
 	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"log"
 	"testing"
+	"time"
 )
 
 func setupTransactionRepo() (config.Conn, sqlmock.Sqlmock) {
@@ -44,19 +47,22 @@ func TestTransactionRepo(t *testing.T) {
 	transaction := Transaction{
 		ID:               22,
 		CounterPartyName: "Bip Bip",
-		CounterPartyIban: "EE383680981021245685",
+		CounterPartyIban: "EE303680981021245685",
 		CounterPartyBic:  "CRLYFRPPTOU",
 		AmountCents:      123123,
 		AmountCurrency:   "EUR",
 		BankAccountID:    1,
 		Description:      "Wonderland/4410",
+		Direction:        "outgoing",
+		BulkTransferID:   3,
+		CreatedAt:        time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC),
 	}
 
 	bankAccount := bankaccountrepo.BankAccount{
 		ID:               1,
 		OrganizationName: "ACME Corp",
 		BalanceCents:     123456,
-		Iban:             "FR10474608000002006107XXXXX",
+		Iban:             "FR81474608000002006107XXXXX",
 		Bic:              "OIVUSCLQXXX",
 	}
 
@@ -70,7 +76,14 @@ func TestTransactionRepo(t *testing.T) {
 				transaction.AmountCents,
 				transaction.AmountCurrency,
 				transaction.BankAccountID,
-				transaction.Description).
+				transaction.Description,
+				transaction.Direction,
+				sql.NullInt64{Int64: 3, Valid: true},
+				sql.NullInt64{},
+				sql.NullInt64{},
+				int64(0),
+				sql.NullInt64{},
+				transaction.CreatedAt).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		r, err := repo.Create(transaction)
@@ -88,7 +101,14 @@ func TestTransactionRepo(t *testing.T) {
 				transaction.AmountCents,
 				transaction.AmountCurrency,
 				transaction.BankAccountID,
-				transaction.Description).
+				transaction.Description,
+				transaction.Direction,
+				sql.NullInt64{Int64: 3, Valid: true},
+				sql.NullInt64{},
+				sql.NullInt64{},
+				int64(0),
+				sql.NullInt64{},
+				transaction.CreatedAt).
 			WillReturnError(fmt.Errorf("error"))
 
 		_, err := repo.Create(transaction)
@@ -96,10 +116,10 @@ func TestTransactionRepo(t *testing.T) {
 	})
 
 	t.Run("Test Read return success", func(t *testing.T) {
-		selectQuery := "SELECT id, counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, bank_account_id, description"
+		selectQuery := "SELECT id, counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, bank_account_id, description, direction, bulk_transfer_id, reversed_transaction_id, reversed_cents, beneficiary_id, fee_of_transaction_id, created_at"
 
-		rows := sqlmock.NewRows([]string{"id", "counterparty_name", "counterparty_iban", "counterparty_bic", "amount_cents", "amount_currency", "bank_account_id", "description"})
-		rows.AddRow(transaction.ID, transaction.CounterPartyName, transaction.CounterPartyIban, transaction.CounterPartyBic, transaction.AmountCents, transaction.AmountCurrency, transaction.BankAccountID, transaction.Description)
+		rows := sqlmock.NewRows([]string{"id", "counterparty_name", "counterparty_iban", "counterparty_bic", "amount_cents", "amount_currency", "bank_account_id", "description", "direction", "bulk_transfer_id", "reversed_transaction_id", "reversed_cents", "beneficiary_id", "fee_of_transaction_id", "created_at"})
+		rows.AddRow(transaction.ID, transaction.CounterPartyName, transaction.CounterPartyIban, transaction.CounterPartyBic, transaction.AmountCents, transaction.AmountCurrency, transaction.BankAccountID, transaction.Description, transaction.Direction, transaction.BulkTransferID, nil, 1000, 5, 21, transaction.CreatedAt)
 
 		mock.ExpectQuery(selectQuery).
 			WithArgs(transaction.ID).
@@ -116,10 +136,17 @@ func TestTransactionRepo(t *testing.T) {
 		assert.Equal(t, transaction.AmountCurrency, s.AmountCurrency)
 		assert.Equal(t, transaction.BankAccountID, s.BankAccountID)
 		assert.Equal(t, transaction.Description, s.Description)
+		assert.Equal(t, transaction.Direction, s.Direction)
+		assert.Equal(t, transaction.BulkTransferID, s.BulkTransferID)
+		assert.Zero(t, s.ReversedTransactionID)
+		assert.Equal(t, int64(1000), s.ReversedCents)
+		assert.Equal(t, uint(5), s.BeneficiaryID)
+		assert.Equal(t, uint(21), s.FeeOfTransactionID)
+		assert.Equal(t, transaction.CreatedAt, s.CreatedAt)
 	})
 
 	t.Run("Test Read return error", func(t *testing.T) {
-		selectQuery := "SELECT id, counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, bank_account_id, description"
+		selectQuery := "SELECT id, counterparty_name, counterparty_iban, counterparty_bic, amount_cents, amount_currency, bank_account_id, description, direction, bulk_transfer_id, reversed_transaction_id, reversed_cents, beneficiary_id, fee_of_transaction_id, created_at"
 
 		mock.ExpectQuery(selectQuery).
 			WithArgs(transaction.ID).
@@ -130,10 +157,10 @@ func TestTransactionRepo(t *testing.T) {
 	})
 
 	t.Run("Test ReadByFilter return success", func(t *testing.T) {
-		selectQuery := "SELECT organization_name as name, iban, bic, counterparty_name, counterparty_iban, counterparty_bic,"
+		selectQuery := "SELECT t.id, organization_name as name, iban, bic, counterparty_name, counterparty_iban, counterparty_bic,"
 
-		rows := sqlmock.NewRows([]string{"organization_name", "iban", "bic", "counterparty_name", "counterparty_iban", "counterparty_bic", "amount_cents", "amount_currency", "description"})
-		rows.AddRow(bankAccount.OrganizationName, bankAccount.Iban, bankAccount.Bic, transaction.CounterPartyName, transaction.CounterPartyIban, transaction.CounterPartyBic, float64(transaction.AmountCents)/100, transaction.AmountCurrency, transaction.Description)
+		rows := sqlmock.NewRows([]string{"id", "organization_name", "iban", "bic", "counterparty_name", "counterparty_iban", "counterparty_bic", "amount_cents", "amount_currency", "description", "direction", "bulk_transfer_id", "reversed_transaction_id", "reversed_cents", "beneficiary_id", "overdrawn_cents", "fee_of_transaction_id", "first_payment", "created_at"})
+		rows.AddRow(transaction.ID, bankAccount.OrganizationName, bankAccount.Iban, bankAccount.Bic, transaction.CounterPartyName, transaction.CounterPartyIban, transaction.CounterPartyBic, transaction.AmountCents, transaction.AmountCurrency, transaction.Description, transaction.Direction, nil, 21, 0, nil, 0, nil, 1, nil)
 
 		mock.ExpectQuery(selectQuery).
 			WillReturnRows(rows)
@@ -142,14 +169,96 @@ func TestTransactionRepo(t *testing.T) {
 
 		s, err := repo.ReadByFilter(filters)
 		assert.NoError(t, err)
+		assert.Equal(t, transaction.ID, s[0].ID)
 		assert.Equal(t, bankAccount.OrganizationName, s[0].Name)
 		assert.Equal(t, bankAccount.Iban, s[0].Iban)
 		assert.Equal(t, bankAccount.Bic, s[0].Bic)
 		assert.Equal(t, transaction.CounterPartyName, s[0].CounterPartyName)
 		assert.Equal(t, transaction.CounterPartyIban, s[0].CounterPartyIban)
 		assert.Equal(t, transaction.CounterPartyBic, s[0].CounterPartyBic)
-		assert.Equal(t, 1231.23, s[0].Amount)
+		assert.Equal(t, domain.NewMoney(transaction.AmountCents, transaction.AmountCurrency), s[0].Amount)
 		assert.Equal(t, transaction.AmountCurrency, s[0].Currency)
 		assert.Equal(t, transaction.Description, s[0].Description)
+		assert.Equal(t, domain.TransactionOutgoing, s[0].Direction)
+		assert.Zero(t, s[0].BulkTransferID)
+		assert.Equal(t, uint(21), s[0].ReversedTransactionID)
+		assert.Nil(t, s[0].ReversedAmount)
+		assert.Zero(t, s[0].BeneficiaryID)
+		assert.Nil(t, s[0].OverdrawnAmount)
+		assert.Zero(t, s[0].FeeOfTransactionID)
+		assert.True(t, s[0].FirstPayment)
+		assert.Nil(t, s[0].CreatedAt)
+	})
+
+	t.Run("Test ReadByFilter return the transactions paid from the overdraft", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "organization_name", "iban", "bic", "counterparty_name", "counterparty_iban", "counterparty_bic", "amount_cents", "amount_currency", "description", "direction", "bulk_transfer_id", "reversed_transaction_id", "reversed_cents", "beneficiary_id", "overdrawn_cents", "fee_of_transaction_id", "first_payment", "created_at"})
+		rows.AddRow(transaction.ID, bankAccount.OrganizationName, bankAccount.Iban, bankAccount.Bic, transaction.CounterPartyName, transaction.CounterPartyIban, transaction.CounterPartyBic, transaction.AmountCents, transaction.AmountCurrency, transaction.Description, transaction.Direction, 3, nil, 0, nil, 23000, 21, 0, nil)
+
+		mock.ExpectQuery("WHERE 1 = 1 and overdrawn_cents > 0 ORDER BY t.id").
+			WillReturnRows(rows)
+
+		s, err := repo.ReadByFilter(map[string]string{"overdrawn": "true"})
+		assert.NoError(t, err)
+		overdrawn := domain.NewMoney(23000, "EUR")
+		assert.Equal(t, &overdrawn, s[0].OverdrawnAmount)
+		assert.Equal(t, uint(21), s[0].FeeOfTransactionID)
+	})
+
+	t.Run("Test SumTransferred return the total of the outgoing transfers", func(t *testing.T) {
+		since := time.Date(2022, 8, 25, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(amount_cents\\), 0\\) FROM transactions WHERE bank_account_id = \\? AND direction = \\? AND bulk_transfer_id IS NOT NULL AND fee_of_transaction_id IS NULL AND created_at >= \\?").
+			WithArgs(uint(1), "outgoing", since).
+			WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(2500))
+
+		total, err := repo.SumTransferred(1, since)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2500), total)
+	})
+
+	t.Run("Test SumTransferred return error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT COALESCE").
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.SumTransferred(1, time.Now())
+		assert.Error(t, err)
+	})
+
+	t.Run("Test ReadDuplicate return the latest payment alike", func(t *testing.T) {
+		since := time.Date(2022, 8, 23, 10, 0, 0, 0, time.UTC)
+		at := time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT id, bulk_transfer_id, created_at FROM transactions WHERE bank_account_id = \\? AND counterparty_iban = \\? AND amount_cents = \\? AND created_at >= \\? "+
+			"AND direction = \\? AND bulk_transfer_id IS NOT NULL AND fee_of_transaction_id IS NULL AND description = \\? AND reversed_cents < amount_cents ORDER BY created_at DESC, id DESC LIMIT 1").
+			WithArgs(uint(1), "EE303680981021245685", int64(1453), since, "outgoing", "August payroll").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "bulk_transfer_id", "created_at"}).AddRow(7, 3, at))
+
+		duplicate, err := repo.ReadDuplicate(1, "EE303680981021245685", 1453, "August payroll", since)
+		assert.NoError(t, err)
+		assert.Equal(t, Transaction{ID: 7, BulkTransferID: 3, CreatedAt: at}, duplicate)
+	})
+
+	t.Run("Test ReadDuplicate return no rows without a payment alike", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, bulk_transfer_id, created_at FROM transactions").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.ReadDuplicate(1, "EE303680981021245685", 1453, "August payroll", time.Now())
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("Test Reverse return success", func(t *testing.T) {
+		mock.ExpectExec("UPDATE transactions SET reversed_cents = reversed_cents \\+ \\? WHERE id = \\? AND reversed_cents \\+ \\? <= amount_cents").
+			WithArgs(1000, transaction.ID, 1000).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Reverse(transaction.ID, 1000)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Reverse return ErrReversalExceeded when the guard doesn't match", func(t *testing.T) {
+		mock.ExpectExec("UPDATE transactions").
+			WithArgs(1000, transaction.ID, 1000).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Reverse(transaction.ID, 1000)
+		assert.ErrorIs(t, err, ErrReversalExceeded)
 	})
 }
@@ -0,0 +1,77 @@
+package uow
+
+import (
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/beneficiaryrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/reconciliationrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/templaterepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/webhookrepo"
+)
+
+// Repositories groups the repositories that take part in a unit of work
+type Repositories struct {
+	BankAccount    bankaccountrepo.BankAccountRepository
+	Transaction    transactionrepo.TransactionRepository
+	BulkTransfer   bulktransferrepo.BulkTransferRepository
+	Template       templaterepo.TemplateRepository
+	Ledger         ledgerrepo.LedgerRepository
+	Reconciliation reconciliationrepo.ReconciliationRepository
+	Beneficiary    beneficiaryrepo.BeneficiaryRepository
+	Webhook        webhookrepo.WebhookRepository
+}
+
+// UnitOfWork Interface to run a set of repository operations inside a single database transaction
+type UnitOfWork interface {
+	// Do runs fn inside a database transaction. The transaction is committed when fn returns nil
+	// and rolled back when it returns an error or panics.
+	Do(fn func(repos Repositories) error) error
+}
+
+// New returns an instance of the unit of work
+func New(db config.Conn) UnitOfWork {
+	return unitOfWork{
+		DB: db,
+	}
+}
+
+type unitOfWork struct {
+	DB config.Conn
+}
+
+// Do runs fn inside a database transaction
+func (u unitOfWork) Do(fn func(repos Repositories) error) (err error) {
+	tx, err := u.DB.Conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	conn := u.DB.WithTx(tx)
+	repos := Repositories{
+		BankAccount:    bankaccountrepo.New(conn),
+		Transaction:    transactionrepo.New(conn),
+		BulkTransfer:   bulktransferrepo.New(conn),
+		Template:       templaterepo.New(conn),
+		Ledger:         ledgerrepo.New(conn),
+		Reconciliation: reconciliationrepo.New(conn),
+		Beneficiary:    beneficiaryrepo.New(conn),
+		Webhook:        webhookrepo.New(conn),
+	}
+
+	if err = fn(repos); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
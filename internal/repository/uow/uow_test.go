@@ -0,0 +1,104 @@
+package uow
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+)
+
+func setupUnitOfWork() (config.Conn, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return config.Conn{Conn: db}, mock
+}
+
+func TestUnitOfWork(t *testing.T) {
+
+	conn, mock := setupUnitOfWork()
+	defer func() {
+		mock.ExpectClose()
+		err := conn.Conn.Close()
+		if err != nil {
+			t.Errorf("Error closing connection: %+v", err)
+		}
+	}()
+
+	bankAccount := bankaccountrepo.BankAccount{
+		ID:               1,
+		OrganizationName: "ACME Corp",
+		BalanceCents:     123456,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	}
+
+	t.Run("Test Do commits when fn succeeds", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE bank_accounts").
+			WithArgs(bankAccount.OrganizationName, bankAccount.BalanceCents, bankAccount.Bic, int64(0), int64(0), int64(0), int64(0), int64(0), 0, int64(0), "", bankAccount.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := New(conn).Do(func(repos Repositories) error {
+			return repos.BankAccount.Update(bankAccount)
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Test Do rolls back when fn fails", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE bank_accounts").
+			WithArgs(bankAccount.OrganizationName, bankAccount.BalanceCents, bankAccount.Bic, int64(0), int64(0), int64(0), int64(0), int64(0), 0, int64(0), "", bankAccount.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO transactions").
+			WillReturnError(fmt.Errorf("error"))
+		mock.ExpectRollback()
+
+		err := New(conn).Do(func(repos Repositories) error {
+			if err := repos.BankAccount.Update(bankAccount); err != nil {
+				return err
+			}
+			_, err := repos.Transaction.Create(transactionrepo.Transaction{BankAccountID: bankAccount.ID})
+			return err
+		})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Test Do rolls back and re-panics when fn panics", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		assert.Panics(t, func() {
+			_ = New(conn).Do(func(repos Repositories) error {
+				panic("boom")
+			})
+		})
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Test Do return error when transaction cannot start", func(t *testing.T) {
+		mock.ExpectBegin().WillReturnError(errors.New("error"))
+
+		called := false
+		err := New(conn).Do(func(repos Repositories) error {
+			called = true
+			return nil
+		})
+
+		assert.Error(t, err)
+		assert.False(t, called)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,336 @@
+package webhookrepo
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"sort"
+	"strings"
+	"time"
+)
+
+// filterColumns maps the filters of the webhooks to their columns
+var filterColumns = map[string]string{
+	"organization_iban": "a.iban",
+}
+
+// Repo struct
+type Repo struct {
+	DB config.Conn
+}
+
+// WebhookList list of Webhook
+type WebhookList []Webhook
+
+// Webhook Struct that represents the subscription of a bank account to some events
+type Webhook struct {
+	ID            uint
+	BankAccountID uint
+	// OrganizationIban the iban of the bank account, read along with the webhook
+	OrganizationIban string
+	URL              string
+	Secret           string
+	Events           []string
+	CreatedAt        time.Time
+}
+
+// DeliveryList list of Delivery
+type DeliveryList []Delivery
+
+// Delivery Struct that represents the delivery of an event to a webhook
+type Delivery struct {
+	ID        uint
+	WebhookID uint
+	Event     string
+	Payload   string
+	Status    string
+	Attempts  int
+	// NextAttemptAt when a pending delivery is attempted, zero once it isn't pending
+	NextAttemptAt  time.Time
+	ResponseStatus int
+	Error          string
+	// ReplayOf the delivery this one replays, 0 when it isn't a replay
+	ReplayOf    uint
+	CreatedAt   time.Time
+	DeliveredAt time.Time
+	UpdatedAt   time.Time
+}
+
+// WebhookRepository Interface for the webhooks and their delivery log
+type WebhookRepository interface {
+	Create(data Webhook) (int, error)
+	Read(webhookID uint) (Webhook, error)
+	ReadByFilter(filters map[string]string) (WebhookList, error)
+	Delete(webhookID uint, at time.Time) error
+	CreateDeliveries(organizationIban string, event string, payload string, at time.Time) error
+	CreateDelivery(data Delivery) (int, error)
+	ReadDelivery(deliveryID uint) (Delivery, error)
+	ReadDeliveries(webhookID uint) (DeliveryList, error)
+	ReadDueDeliveries(now time.Time) (DeliveryList, error)
+	UpdateDelivery(data Delivery) error
+}
+
+// New Returns a new instance of DB.
+func New(db config.Conn) Repo {
+	return Repo{
+		DB: db,
+	}
+}
+
+// Create new webhook along with the events it subscribes to
+func (repo Repo) Create(data Webhook) (int, error) {
+	insertQuery := "INSERT INTO webhooks" +
+		"(bank_account_id, url, secret, created_at) " +
+		"VALUES (?, ?, ?, ?)"
+
+	res, err := repo.DB.Executor().Exec(insertQuery, data.BankAccountID, data.URL, data.Secret, data.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range data.Events {
+		_, err = repo.DB.Executor().Exec("INSERT INTO webhook_events (webhook_id, event) VALUES (?, ?)", id, event)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return int(id), nil
+}
+
+// Read a webhook that wasn't deleted, sql.ErrNoRows otherwise
+func (repo Repo) Read(webhookID uint) (Webhook, error) {
+	query := "SELECT w.id, w.bank_account_id, a.iban, w.url, w.secret, w.created_at, COALESCE(GROUP_CONCAT(e.event), '')" +
+		" FROM webhooks w" +
+		" INNER JOIN bank_accounts a ON a.id = w.bank_account_id" +
+		" LEFT JOIN webhook_events e ON e.webhook_id = w.id" +
+		" WHERE w.id = ? AND w.deleted_at IS NULL" +
+		" GROUP BY w.id"
+
+	return scan(repo.DB.Executor().QueryRow(query, webhookID))
+}
+
+// ReadByFilter list of the webhooks that weren't deleted, oldest first
+func (repo Repo) ReadByFilter(filters map[string]string) (WebhookList, error) {
+	query := "SELECT w.id, w.bank_account_id, a.iban, w.url, w.secret, w.created_at, COALESCE(GROUP_CONCAT(e.event), '')" +
+		" FROM webhooks w" +
+		" INNER JOIN bank_accounts a ON a.id = w.bank_account_id" +
+		" LEFT JOIN webhook_events e ON e.webhook_id = w.id" +
+		" WHERE w.deleted_at IS NULL"
+
+	var bind []any
+	for k, v := range filters {
+		column, ok := filterColumns[k]
+		if !ok {
+			return nil, fmt.Errorf("webhooks can't be filtered by %s", k)
+		}
+		query += fmt.Sprintf(" and %s = ?", column)
+		bind = append(bind, v)
+	}
+	query += " GROUP BY w.id ORDER BY w.id"
+
+	rows, err := repo.DB.Executor().Query(query, bind...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	webhooks := WebhookList{}
+	for rows.Next() {
+		webhook, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// Delete a webhook, its pending deliveries failing. The webhook is kept for its delivery log.
+func (repo Repo) Delete(webhookID uint, at time.Time) error {
+	deleteQuery := "UPDATE webhooks " +
+		"SET deleted_at = ? " +
+		"WHERE id = ? AND deleted_at IS NULL"
+
+	if _, err := repo.DB.Executor().Exec(deleteQuery, at, webhookID); err != nil {
+		return err
+	}
+
+	updateQuery := "UPDATE webhook_deliveries " +
+		"SET status = 'failed', next_attempt_at = NULL, error = 'the webhook was deleted', updated_at = ? " +
+		"WHERE webhook_id = ? AND status = 'pending'"
+
+	_, err := repo.DB.Executor().Exec(updateQuery, at, webhookID)
+
+	return err
+}
+
+// CreateDeliveries queues the delivery of the event to every webhook of the bank account holding the iban that
+// subscribes to it, to be attempted right away
+func (repo Repo) CreateDeliveries(organizationIban string, event string, payload string, at time.Time) error {
+	insertQuery := "INSERT INTO webhook_deliveries" +
+		"(webhook_id, event, payload, status, next_attempt_at, created_at, updated_at) " +
+		"SELECT w.id, e.event, ?, 'pending', ?, ?, ?" +
+		" FROM webhooks w" +
+		" INNER JOIN bank_accounts a ON a.id = w.bank_account_id" +
+		" INNER JOIN webhook_events e ON e.webhook_id = w.id" +
+		" WHERE a.iban = ? AND e.event = ? AND w.deleted_at IS NULL"
+
+	_, err := repo.DB.Executor().Exec(insertQuery, payload, at, at, at, organizationIban, event)
+
+	return err
+}
+
+// CreateDelivery new delivery
+func (repo Repo) CreateDelivery(data Delivery) (int, error) {
+	insertQuery := "INSERT INTO webhook_deliveries" +
+		"(webhook_id, event, payload, status, attempts, next_attempt_at, response_status, error, replay_of, created_at, delivered_at, updated_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+	res, err := repo.DB.Executor().Exec(insertQuery, data.WebhookID, data.Event, data.Payload, data.Status, data.Attempts,
+		nullableTime(data.NextAttemptAt), data.ResponseStatus, data.Error, nullableID(data.ReplayOf), data.CreatedAt,
+		nullableTime(data.DeliveredAt), data.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+
+	return int(id), err
+}
+
+// ReadDelivery a delivery, sql.ErrNoRows when it doesn't exist
+func (repo Repo) ReadDelivery(deliveryID uint) (Delivery, error) {
+	query := "SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at, response_status, error, replay_of, created_at, delivered_at, updated_at" +
+		" FROM webhook_deliveries" +
+		" WHERE id = ?"
+
+	return scanDelivery(repo.DB.Executor().QueryRow(query, deliveryID))
+}
+
+// ReadDeliveries the delivery log of a webhook, latest first
+func (repo Repo) ReadDeliveries(webhookID uint) (DeliveryList, error) {
+	query := "SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at, response_status, error, replay_of, created_at, delivered_at, updated_at" +
+		" FROM webhook_deliveries" +
+		" WHERE webhook_id = ?" +
+		" ORDER BY id DESC"
+
+	return repo.readDeliveries(query, webhookID)
+}
+
+// ReadDueDeliveries the pending deliveries whose next attempt is due, the longest waiting first
+func (repo Repo) ReadDueDeliveries(now time.Time) (DeliveryList, error) {
+	query := "SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at, response_status, error, replay_of, created_at, delivered_at, updated_at" +
+		" FROM webhook_deliveries" +
+		" WHERE status = 'pending' AND next_attempt_at <= ?" +
+		" ORDER BY next_attempt_at, id"
+
+	return repo.readDeliveries(query, now)
+}
+
+// UpdateDelivery records the outcome of an attempt of a delivery
+func (repo Repo) UpdateDelivery(data Delivery) error {
+	updateQuery := "UPDATE webhook_deliveries " +
+		"SET status = ?, attempts = ?, next_attempt_at = ?, response_status = ?, error = ?, delivered_at = ?, updated_at = ? " +
+		"WHERE id = ?"
+
+	_, err := repo.DB.Executor().Exec(updateQuery, data.Status, data.Attempts, nullableTime(data.NextAttemptAt),
+		data.ResponseStatus, data.Error, nullableTime(data.DeliveredAt), data.UpdatedAt, data.ID)
+
+	return err
+}
+
+func (repo Repo) readDeliveries(query string, args ...any) (DeliveryList, error) {
+	rows, err := repo.DB.Executor().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	deliveries := DeliveryList{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scan(row scanner) (Webhook, error) {
+	var webhook Webhook
+	var events string
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.BankAccountID,
+		&webhook.OrganizationIban,
+		&webhook.URL,
+		&webhook.Secret,
+		&webhook.CreatedAt,
+		&events,
+	)
+	if err != nil {
+		return Webhook{}, err
+	}
+	webhook.Events = []string{}
+	if events != "" {
+		webhook.Events = strings.Split(events, ",")
+		sort.Strings(webhook.Events)
+	}
+
+	return webhook, nil
+}
+
+func scanDelivery(row scanner) (Delivery, error) {
+	var delivery Delivery
+	var nextAttemptAt sql.NullTime
+	var replayOf sql.NullInt64
+	var deliveredAt sql.NullTime
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.Event,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&nextAttemptAt,
+		&delivery.ResponseStatus,
+		&delivery.Error,
+		&replayOf,
+		&delivery.CreatedAt,
+		&deliveredAt,
+		&delivery.UpdatedAt,
+	)
+	if err != nil {
+		return Delivery{}, err
+	}
+	delivery.NextAttemptAt = nextAttemptAt.Time
+	delivery.ReplayOf = uint(replayOf.Int64)
+	delivery.DeliveredAt = deliveredAt.Time
+
+	return delivery, nil
+}
+
+func nullableTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func nullableID(id uint) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
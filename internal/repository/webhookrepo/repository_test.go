@@ -0,0 +1,220 @@
+package webhookrepo
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+	"time"
+)
+
+func setupWebhookRepo() (config.Conn, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return config.Conn{Conn: db}, mock
+}
+
+func TestWebhookRepo(t *testing.T) {
+
+	conn, mock := setupWebhookRepo()
+	defer func() {
+		mock.ExpectClose()
+		err := conn.Conn.Close()
+		if err != nil {
+			t.Errorf("Error closing connection: %+v", err)
+		}
+	}()
+
+	repo := Repo{DB: conn}
+
+	t.Run("Test constructor.", func(t *testing.T) {
+		r := New(conn)
+
+		assert.NotEmpty(t, r)
+	})
+
+	now := time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC)
+	webhook := Webhook{
+		ID:               3,
+		BankAccountID:    1,
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		URL:              "https://erp.acme.corp/hooks",
+		Secret:           "5ec2e7",
+		Events:           []string{"bulk_transfer.completed", "transaction.created"},
+		CreatedAt:        now,
+	}
+
+	columns := []string{"id", "bank_account_id", "iban", "url", "secret", "created_at", "events"}
+	row := func(rows *sqlmock.Rows) *sqlmock.Rows {
+		return rows.AddRow(webhook.ID, webhook.BankAccountID, webhook.OrganizationIban, webhook.URL, webhook.Secret, webhook.CreatedAt,
+			"transaction.created,bulk_transfer.completed")
+	}
+
+	delivery := Delivery{
+		ID:            7,
+		WebhookID:     3,
+		Event:         "transaction.created",
+		Payload:       `{"event":"transaction.created"}`,
+		Status:        "pending",
+		Attempts:      1,
+		NextAttemptAt: now.Add(time.Minute),
+		Error:         "connection refused",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	deliveryColumns := []string{"id", "webhook_id", "event", "payload", "status", "attempts", "next_attempt_at", "response_status", "error", "replay_of", "created_at", "delivered_at", "updated_at"}
+	deliveryRow := func(rows *sqlmock.Rows) *sqlmock.Rows {
+		return rows.AddRow(delivery.ID, delivery.WebhookID, delivery.Event, delivery.Payload, delivery.Status, delivery.Attempts,
+			delivery.NextAttemptAt, delivery.ResponseStatus, delivery.Error, nil, delivery.CreatedAt, nil, delivery.UpdatedAt)
+	}
+
+	t.Run("Test Create return success", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO webhooks").
+			WithArgs(webhook.BankAccountID, webhook.URL, webhook.Secret, webhook.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectExec("INSERT INTO webhook_events").
+			WithArgs(int64(3), "bulk_transfer.completed").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO webhook_events").
+			WithArgs(int64(3), "transaction.created").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		r, err := repo.Create(webhook)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, r)
+	})
+
+	t.Run("Test Create return error while inserting the events", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO webhooks").
+			WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectExec("INSERT INTO webhook_events").
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.Create(webhook)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Read return success", func(t *testing.T) {
+		mock.ExpectQuery("FROM webhooks w (.+) WHERE w.id = (.+) AND w.deleted_at IS NULL GROUP BY w.id").
+			WithArgs(webhook.ID).
+			WillReturnRows(row(sqlmock.NewRows(columns)))
+
+		s, err := repo.Read(webhook.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, webhook, s)
+	})
+
+	t.Run("Test Read return no rows", func(t *testing.T) {
+		mock.ExpectQuery("FROM webhooks w").
+			WithArgs(webhook.ID).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := repo.Read(webhook.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("Test ReadByFilter return success", func(t *testing.T) {
+		mock.ExpectQuery("FROM webhooks w (.+) WHERE w.deleted_at IS NULL and a.iban = (.+) GROUP BY w.id ORDER BY w.id").
+			WithArgs(webhook.OrganizationIban).
+			WillReturnRows(row(sqlmock.NewRows(columns)))
+
+		s, err := repo.ReadByFilter(map[string]string{"organization_iban": webhook.OrganizationIban})
+		assert.NoError(t, err)
+		assert.Equal(t, WebhookList{webhook}, s)
+	})
+
+	t.Run("Test ReadByFilter return error on unknown filter", func(t *testing.T) {
+		_, err := repo.ReadByFilter(map[string]string{"1 = 1; DROP TABLE webhooks; --": "x"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Delete fails the pending deliveries", func(t *testing.T) {
+		mock.ExpectExec("UPDATE webhooks SET deleted_at = (.+) WHERE id = (.+) AND deleted_at IS NULL").
+			WithArgs(now, webhook.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE webhook_deliveries SET status = 'failed'(.+) WHERE webhook_id = (.+) AND status = 'pending'").
+			WithArgs(now, webhook.ID).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		assert.NoError(t, repo.Delete(webhook.ID, now))
+	})
+
+	t.Run("Test CreateDeliveries fans the event out to the subscribed webhooks", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO webhook_deliveries(.+) SELECT (.+) FROM webhooks w (.+) WHERE a.iban = (.+) AND e.event = (.+) AND w.deleted_at IS NULL").
+			WithArgs(delivery.Payload, now, now, now, webhook.OrganizationIban, "transaction.created").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		assert.NoError(t, repo.CreateDeliveries(webhook.OrganizationIban, "transaction.created", delivery.Payload, now))
+	})
+
+	t.Run("Test CreateDelivery return success", func(t *testing.T) {
+		replay := delivery
+		replay.ReplayOf = 7
+		mock.ExpectExec("INSERT INTO webhook_deliveries").
+			WithArgs(replay.WebhookID, replay.Event, replay.Payload, replay.Status, replay.Attempts, sql.NullTime{Time: replay.NextAttemptAt, Valid: true},
+				0, replay.Error, sql.NullInt64{Int64: 7, Valid: true}, replay.CreatedAt, sql.NullTime{}, replay.UpdatedAt).
+			WillReturnResult(sqlmock.NewResult(8, 1))
+
+		r, err := repo.CreateDelivery(replay)
+		assert.NoError(t, err)
+		assert.Equal(t, 8, r)
+	})
+
+	t.Run("Test ReadDelivery return success", func(t *testing.T) {
+		mock.ExpectQuery("FROM webhook_deliveries WHERE id = (.+)").
+			WithArgs(delivery.ID).
+			WillReturnRows(deliveryRow(sqlmock.NewRows(deliveryColumns)))
+
+		s, err := repo.ReadDelivery(delivery.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, delivery, s)
+	})
+
+	t.Run("Test ReadDeliveries return the log latest first", func(t *testing.T) {
+		mock.ExpectQuery("FROM webhook_deliveries WHERE webhook_id = (.+) ORDER BY id DESC").
+			WithArgs(webhook.ID).
+			WillReturnRows(deliveryRow(sqlmock.NewRows(deliveryColumns)))
+
+		s, err := repo.ReadDeliveries(webhook.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, DeliveryList{delivery}, s)
+	})
+
+	t.Run("Test ReadDueDeliveries return success", func(t *testing.T) {
+		mock.ExpectQuery("FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= (.+) ORDER BY next_attempt_at, id").
+			WithArgs(now).
+			WillReturnRows(deliveryRow(sqlmock.NewRows(deliveryColumns)))
+
+		s, err := repo.ReadDueDeliveries(now)
+		assert.NoError(t, err)
+		assert.Equal(t, DeliveryList{delivery}, s)
+	})
+
+	t.Run("Test ReadDueDeliveries return error", func(t *testing.T) {
+		mock.ExpectQuery("FROM webhook_deliveries").
+			WillReturnError(fmt.Errorf("error"))
+
+		_, err := repo.ReadDueDeliveries(now)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test UpdateDelivery return success", func(t *testing.T) {
+		delivered := delivery
+		delivered.Status = "delivered"
+		delivered.NextAttemptAt = time.Time{}
+		delivered.ResponseStatus = 204
+		delivered.DeliveredAt = now
+		mock.ExpectExec("UPDATE webhook_deliveries SET status = (.+) WHERE id = (.+)").
+			WithArgs("delivered", delivered.Attempts, sql.NullTime{}, 204, delivered.Error, sql.NullTime{Time: now, Valid: true}, delivered.UpdatedAt, delivered.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.UpdateDelivery(delivered))
+	})
+}
@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Job is a piece of work run on every tick of the scheduler, returning how many items it processed, along with
+// an error when the job, or some of its items, failed
+type Job struct {
+	Name string
+	Run  func() (int, error)
+}
+
+// Scheduler runs its jobs periodically
+type Scheduler interface {
+	Start(ctx context.Context)
+	Tick()
+}
+
+// New returns a scheduler running the jobs every interval
+func New(interval time.Duration, logger log.Logger, jobs ...Job) Scheduler {
+	return scheduler{
+		logger:   logger,
+		interval: interval,
+		jobs:     jobs,
+	}
+}
+
+type scheduler struct {
+	logger   log.Logger
+	interval time.Duration
+	jobs     []Job
+}
+
+// Start runs the jobs right away and then on every interval, until the context is done
+func (s scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.logger.Info("scheduler started", zap.Duration("interval", s.interval))
+	for {
+		s.Tick()
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick runs every job once. A failing job doesn't prevent the others from running.
+func (s scheduler) Tick() {
+	for _, job := range s.jobs {
+		processed, err := job.Run()
+		if err != nil {
+			s.logger.WithError(err).Error("scheduled job failed", zap.String("job", job.Name), zap.Int("processed", processed))
+			continue
+		}
+		if processed > 0 {
+			s.logger.Info("scheduled job done", zap.String("job", job.Name), zap.Int("processed", processed))
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	t.Run("Test Tick runs every job even when one fails", func(t *testing.T) {
+		var runs []string
+		failing := Job{Name: "failing", Run: func() (int, error) {
+			runs = append(runs, "failing")
+			return 1, errors.New("error")
+		}}
+		succeeding := Job{Name: "succeeding", Run: func() (int, error) {
+			runs = append(runs, "succeeding")
+			return 2, nil
+		}}
+
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		// what it processed before failing is reported along with the error
+		logMock.EXPECT().Error("scheduled job failed", zap.String("job", "failing"), zap.Int("processed", 1)).Times(1)
+		logMock.EXPECT().Info("scheduled job done", gomock.Any(), gomock.Any()).Times(1)
+
+		New(time.Minute, logMock, failing, succeeding).Tick()
+
+		assert.Equal(t, []string{"failing", "succeeding"}, runs)
+	})
+
+	t.Run("Test Start runs the jobs until the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		runs := 0
+		job := Job{Name: "job", Run: func() (int, error) {
+			runs++
+			if runs == 3 {
+				cancel()
+			}
+			return 0, nil
+		}}
+
+		logMock.EXPECT().Info("scheduler started", gomock.Any()).Times(1)
+		logMock.EXPECT().Info("scheduler stopped").Times(1)
+
+		done := make(chan struct{})
+		go func() {
+			New(time.Millisecond, logMock, job).Start(ctx)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("scheduler didn't stop")
+		}
+		assert.Equal(t, 3, runs)
+	})
+}
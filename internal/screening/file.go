@@ -0,0 +1,212 @@
+package screening
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnsupportedFormat is returned when the list file is neither a CSV nor a JSON snapshot
+var ErrUnsupportedFormat = errors.New("unsupported list format, expected a .csv or a .json file")
+
+// snapshot the JSON snapshot of a sanctions list, the entries without a source come from the snapshot source
+type snapshot struct {
+	Source  string  `json:"source"`
+	Entries []Entry `json:"entries"`
+}
+
+// FileScreener screens the counterparties against the list of a local file, reloaded when the file changes
+type FileScreener struct {
+	path      string
+	mode      Mode
+	threshold float64
+	logger    log.Logger
+
+	mu      sync.RWMutex
+	list    List
+	modTime time.Time
+	size    int64
+}
+
+// NewFileScreener returns a screener loading the list of the file at path. Counterparty names match the names
+// of the entries at least threshold similar.
+func NewFileScreener(path string, mode Mode, threshold float64, logger log.Logger) (*FileScreener, error) {
+	if mode != ModeReject && mode != ModeReview {
+		return nil, fmt.Errorf("unknown screening mode %q, expected %s or %s", mode, ModeReject, ModeReview)
+	}
+	if threshold <= 0 || threshold > 1 {
+		return nil, fmt.Errorf("the screening threshold must be above 0 and at most 1, got %v", threshold)
+	}
+
+	s := &FileScreener{path: path, mode: mode, threshold: threshold, logger: logger}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Screen returns the entries of the list matching the counterparty, best match first
+func (s *FileScreener) Screen(name, iban string) []Hit {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.list.Screen(name, iban, s.threshold)
+}
+
+// Mode what happens to the bulk transfers paying a listed counterparty
+func (s *FileScreener) Mode() Mode {
+	return s.mode
+}
+
+// Reload loads the list again when the file changed since it was loaded, telling whether it did. The
+// previous list is kept when the file can't be loaded.
+func (s *FileScreener) Reload() (bool, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	unchanged := info.ModTime().Equal(s.modTime) && info.Size() == s.size
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	list, err := Load(s.path)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.list, s.modTime, s.size = list, info.ModTime(), info.Size()
+	s.mu.Unlock()
+
+	s.logger.Info("screening list loaded", zap.String("path", s.path), zap.Int("entries", list.Len()))
+	return true, nil
+}
+
+// Watch reloads the list on every interval when the file changed, until the context is done
+func (s *FileScreener) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Reload(); err != nil {
+				s.logger.WithError(err).Error("error reloading the screening list, the previous one is kept", zap.String("path", s.path))
+			}
+		}
+	}
+}
+
+// Load reads the list of the file at path, a CSV file or a JSON snapshot depending on its extension
+func Load(path string) (List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return List{}, err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	var entries []Entry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		entries, err = readCSV(f)
+	case ".json":
+		entries, err = readSnapshot(f)
+	default:
+		err = ErrUnsupportedFormat
+	}
+	if err != nil {
+		return List{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return NewList(entries), nil
+}
+
+// readCSV reads the entries of a CSV file with a header row. The id and name columns are required, the aliases
+// and ibans columns hold values separated by semicolons and the source column is optional.
+func readCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, required := range []string{"id", "name"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing the %s column", required)
+		}
+	}
+
+	var entries []Entry
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		value := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		entries = append(entries, Entry{
+			ID:      value("id"),
+			Name:    value("name"),
+			Aliases: split(value("aliases")),
+			Ibans:   split(value("ibans")),
+			Source:  value("source"),
+		})
+	}
+}
+
+// readSnapshot reads the entries of a JSON snapshot
+func readSnapshot(r io.Reader) ([]Entry, error) {
+	var s snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+
+	for i := range s.Entries {
+		if s.Entries[i].Source == "" {
+			s.Entries[i].Source = s.Source
+		}
+	}
+	return s.Entries, nil
+}
+
+func split(values string) []string {
+	var res []string
+	for _, v := range strings.Split(values, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
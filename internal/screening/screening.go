@@ -0,0 +1,230 @@
+package screening
+
+import (
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Mode what happens to the bulk transfers paying a listed counterparty
+type Mode string
+
+const (
+	// ModeReject the bulk transfer is rejected with the matched entries
+	ModeReject Mode = "reject"
+	// ModeReview the bulk transfer is held until it is released, or blocked, by a reviewer
+	ModeReview Mode = "review"
+)
+
+// DefaultThreshold the similarity from which a counterparty name matches the name of an entry
+const DefaultThreshold = 0.9
+
+// Field the counterparty field matching an entry
+type Field string
+
+const (
+	// FieldName the counterparty name is similar to a name of the entry
+	FieldName Field = "name"
+	// FieldIban the counterparty iban is an iban of the entry
+	FieldIban Field = "iban"
+)
+
+// Entry Struct that represents a sanctioned, or blocked, party of the list
+type Entry struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	Ibans   []string `json:"ibans,omitempty"`
+	// Source the list the entry comes from, e.g. EU, OFAC or internal
+	Source string `json:"source,omitempty"`
+}
+
+// Hit Struct that represents a counterparty matching an entry of the list
+type Hit struct {
+	Entry Entry
+	Field Field
+	// Score the similarity of the names, from 0 to 1. Iban matches score 1.
+	Score float64
+}
+
+// Screener Interface for the screening of the counterparties
+type Screener interface {
+	// Screen returns the entries matching the counterparty, best match first
+	Screen(name, iban string) []Hit
+	// Mode what happens to the bulk transfers paying a listed counterparty
+	Mode() Mode
+}
+
+// Disabled is the screener used when no list is configured, nothing matches
+type Disabled struct{}
+
+// Screen never matches
+func (Disabled) Screen(string, string) []Hit {
+	return nil
+}
+
+// Mode rejects, though nothing ever matches
+func (Disabled) Mode() Mode {
+	return ModeReject
+}
+
+// List the entries of a sanctions list or blocklist, indexed for the screening
+type List struct {
+	entries []Entry
+	// names the normalized names and aliases of every entry, by position of the entry
+	names [][]string
+	// ibans the position of the entry holding each iban
+	ibans map[string]int
+}
+
+// NewList indexes the entries for the screening
+func NewList(entries []Entry) List {
+	list := List{entries: entries, names: make([][]string, len(entries)), ibans: map[string]int{}}
+	for i, entry := range entries {
+		for _, name := range append([]string{entry.Name}, entry.Aliases...) {
+			if n := normalizeName(name); n != "" {
+				list.names[i] = append(list.names[i], n)
+			}
+		}
+		for _, iban := range entry.Ibans {
+			if n := normalizeIban(iban); n != "" {
+				list.ibans[n] = i
+			}
+		}
+	}
+	return list
+}
+
+// Len how many entries the list holds
+func (l List) Len() int {
+	return len(l.entries)
+}
+
+// Screen returns the entries holding the iban, or with a name or an alias at least threshold similar to the
+// name, best match first
+func (l List) Screen(name, iban string, threshold float64) []Hit {
+	var hits []Hit
+	matched := map[int]bool{}
+	if i, ok := l.ibans[normalizeIban(iban)]; ok && iban != "" {
+		hits = append(hits, Hit{Entry: l.entries[i], Field: FieldIban, Score: 1})
+		matched[i] = true
+	}
+
+	n := normalizeName(name)
+	if n == "" {
+		return hits
+	}
+	var byName []Hit
+	for i, names := range l.names {
+		if matched[i] {
+			continue
+		}
+		best := 0.0
+		for _, candidate := range names {
+			if score := similarity(n, candidate); score > best {
+				best = score
+			}
+		}
+		if best >= threshold {
+			byName = append(byName, Hit{Entry: l.entries[i], Field: FieldName, Score: best})
+		}
+	}
+	sort.SliceStable(byName, func(i, j int) bool {
+		return byName[i].Score > byName[j].Score
+	})
+
+	return append(hits, byName...)
+}
+
+// normalizeName folds the case and the accents of the name and sorts its words, so the order of the
+// first and last names doesn't matter
+func normalizeName(name string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+	if err != nil {
+		folded = name
+	}
+	words := strings.FieldsFunc(strings.ToLower(folded), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// normalizeIban the iban in electronic format
+func normalizeIban(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+}
+
+// similarity the Jaro-Winkler similarity of a and b, from 0 to 1
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	s1, s2 := []rune(a), []rune(b)
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+
+	window := max(len(s1), len(s2))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matched1 := make([]bool, len(s1))
+	matched2 := make([]bool, len(s2))
+	matches := 0
+	for i := range s1 {
+		for j := max(0, i-window); j < min(len(s2), i+window+1); j++ {
+			if !matched2[j] && s1[i] == s2[j] {
+				matched1[i], matched2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range s1 {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[j] {
+			j++
+		}
+		if s1[i] != s2[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(s1)) + m/float64(len(s2)) + (m-float64(transpositions)/2)/m) / 3
+
+	// common prefix, up to 4 characters
+	prefix := 0
+	for prefix < min(4, min(len(s1), len(s2))) && s1[prefix] == s2[prefix] {
+		prefix++
+	}
+
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
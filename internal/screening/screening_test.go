@@ -0,0 +1,182 @@
+package screening
+
+import (
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const listCSV = `id,name,aliases,ibans,source
+EU-1, Wile E. Coyote, Wile Coyote;W. E. Coyote, DE44354208100362090817, EU
+OFAC-7, Acme Shell Company,,,OFAC
+`
+
+const listJSON = `{"source": "OFAC", "entries": [
+	{"id": "OFAC-1", "name": "José Müller", "ibans": ["EE303680981021245685"]},
+	{"id": "EU-2", "name": "Road Runner", "source": "EU"}
+]}`
+
+func TestList(t *testing.T) {
+	list := NewList([]Entry{
+		{ID: "EU-1", Name: "Wile E. Coyote", Aliases: []string{"Wile Coyote"}, Ibans: []string{"DE44 3542 0810 0362 0908 17"}, Source: "EU"},
+		{ID: "OFAC-1", Name: "José Müller", Source: "OFAC"},
+	})
+
+	t.Run("Test Screen matches the iban exactly", func(t *testing.T) {
+		hits := list.Screen("Bip Bip", "DE44354208100362090817", DefaultThreshold)
+
+		assert.Len(t, hits, 1)
+		assert.Equal(t, "EU-1", hits[0].Entry.ID)
+		assert.Equal(t, FieldIban, hits[0].Field)
+		assert.Equal(t, 1.0, hits[0].Score)
+	})
+
+	t.Run("Test Screen matches names regardless of case, accents, punctuation and word order", func(t *testing.T) {
+		for _, name := range []string{"wile e coyote", "COYOTE, Wile E.", "Jose Muller", "Müller José"} {
+			hits := list.Screen(name, "EE303680981021245685", DefaultThreshold)
+
+			assert.Len(t, hits, 1, name)
+			assert.Equal(t, FieldName, hits[0].Field, name)
+			assert.Equal(t, 1.0, hits[0].Score, name)
+		}
+	})
+
+	t.Run("Test Screen matches names with typos", func(t *testing.T) {
+		hits := list.Screen("Wile E. Coyotte", "", DefaultThreshold)
+
+		assert.Len(t, hits, 1)
+		assert.Equal(t, "EU-1", hits[0].Entry.ID)
+		assert.Greater(t, hits[0].Score, DefaultThreshold)
+		assert.Less(t, hits[0].Score, 1.0)
+	})
+
+	t.Run("Test Screen matches the aliases", func(t *testing.T) {
+		hits := list.Screen("Wile Coyote", "", DefaultThreshold)
+
+		assert.Len(t, hits, 1)
+		assert.Equal(t, 1.0, hits[0].Score)
+	})
+
+	t.Run("Test Screen doesn't match other names", func(t *testing.T) {
+		assert.Empty(t, list.Screen("Bip Bip", "EE303680981021245685", DefaultThreshold))
+		assert.Empty(t, list.Screen("Wile Smith", "", DefaultThreshold))
+		assert.Empty(t, list.Screen("", "", DefaultThreshold))
+	})
+
+	t.Run("Test Screen reports an entry once, by its iban first", func(t *testing.T) {
+		hits := list.Screen("Wile E. Coyote", "DE44354208100362090817", DefaultThreshold)
+
+		assert.Len(t, hits, 1)
+		assert.Equal(t, FieldIban, hits[0].Field)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("Test Load reads a CSV list", func(t *testing.T) {
+		list, err := Load(write("list.csv", listCSV))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, list.Len())
+		assert.Equal(t, []Entry{
+			{ID: "EU-1", Name: "Wile E. Coyote", Aliases: []string{"Wile Coyote", "W. E. Coyote"}, Ibans: []string{"DE44354208100362090817"}, Source: "EU"},
+			{ID: "OFAC-7", Name: "Acme Shell Company", Source: "OFAC"},
+		}, list.entries)
+	})
+
+	t.Run("Test Load reads a JSON snapshot", func(t *testing.T) {
+		list, err := Load(write("list.json", listJSON))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Entry{
+			{ID: "OFAC-1", Name: "José Müller", Ibans: []string{"EE303680981021245685"}, Source: "OFAC"},
+			{ID: "EU-2", Name: "Road Runner", Source: "EU"},
+		}, list.entries)
+	})
+
+	t.Run("Test Load return error when a required column is missing", func(t *testing.T) {
+		_, err := Load(write("missing.csv", "id,aliases\nEU-1,Coyote\n"))
+
+		assert.ErrorContains(t, err, "missing the name column")
+	})
+
+	t.Run("Test Load return error on other formats", func(t *testing.T) {
+		_, err := Load(write("list.xml", "<entries/>"))
+
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	})
+}
+
+func TestFileScreener(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+	logMock.EXPECT().Info("screening list loaded", gomock.Any(), gomock.Any()).AnyTimes()
+
+	path := filepath.Join(t.TempDir(), "list.csv")
+	if err := os.WriteFile(path, []byte(listCSV), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Test NewFileScreener return error on an unknown mode", func(t *testing.T) {
+		_, err := NewFileScreener(path, "warn", DefaultThreshold, logMock)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Test NewFileScreener return error when the file can't be loaded", func(t *testing.T) {
+		_, err := NewFileScreener(filepath.Join(t.TempDir(), "missing.csv"), ModeReject, DefaultThreshold, logMock)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Reload loads the list again once the file changed", func(t *testing.T) {
+		s, err := NewFileScreener(path, ModeReview, DefaultThreshold, logMock)
+		assert.NoError(t, err)
+		assert.Equal(t, ModeReview, s.Mode())
+		assert.Len(t, s.Screen("Road Runner", ""), 0)
+
+		reloaded, err := s.Reload()
+		assert.NoError(t, err)
+		assert.False(t, reloaded)
+
+		if err = os.WriteFile(path, []byte(listCSV+"EU-2,Road Runner,,,EU\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		reloaded, err = s.Reload()
+		assert.NoError(t, err)
+		assert.True(t, reloaded)
+		assert.Len(t, s.Screen("Road Runner", ""), 1)
+	})
+
+	t.Run("Test Reload keeps the previous list when the file is invalid", func(t *testing.T) {
+		s, err := NewFileScreener(path, ModeReject, DefaultThreshold, logMock)
+		assert.NoError(t, err)
+
+		if err = os.WriteFile(path, []byte("name\nRoad Runner\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		// the modification time may not change within the resolution of the file system
+		later := time.Now().Add(time.Second)
+		if err = os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = s.Reload()
+		assert.Error(t, err)
+		assert.Len(t, s.Screen("Road Runner", ""), 1)
+	})
+}
@@ -1,11 +1,20 @@
 package bankaccountsvc
 
 import (
+	"errors"
+	"fmt"
 	"github.com/adrianoccosta/exercise-qonto/internal/domain"
 	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
 	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
 )
 
+// ErrBalanceNotEditable is returned when an edition changes the balance of the bank account, balances only move
+// through the transfers, the reversals and the adjustments of the reconciliation
+var ErrBalanceNotEditable = errors.New("the balance of a bank account can't be edited")
+
 // BankAccountService Interface for the back account services
 type BankAccountService interface {
 	Create(data domain.BankAccount) error
@@ -15,31 +24,50 @@ type BankAccountService interface {
 }
 
 // New returns an instance of the back account services
-func New(bankAccountRepo bankaccountrepo.BankAccountRepository, logger log.Logger) BankAccountService {
+func New(unitOfWork uow.UnitOfWork, bankAccountRepo bankaccountrepo.BankAccountRepository, clock tools.Clock, logger log.Logger) BankAccountService {
 	return service{
 		logger:          logger,
+		clock:           clock,
+		unitOfWork:      unitOfWork,
 		bankAccountRepo: bankAccountRepo,
 	}
 }
 
 type service struct {
 	logger          log.Logger
+	clock           tools.Clock
+	unitOfWork      uow.UnitOfWork
 	bankAccountRepo bankaccountrepo.BankAccountRepository
 }
 
-// Create new bank account
+// Create new bank account. Its opening balance is posted on the ledger against the equity.
 func (s service) Create(data domain.BankAccount) error {
-	_, err := s.bankAccountRepo.Create(bankaccountrepo.BankAccount{
-		OrganizationName: data.Name,
-		BalanceCents:     int(data.Balance * 100),
-		Iban:             data.Iban,
-		Bic:              data.Bic,
-	})
+	return s.unitOfWork.Do(func(repos uow.Repositories) error {
+		id, err := repos.BankAccount.Create(bankaccountrepo.BankAccount{
+			OrganizationName: data.Name,
+			BalanceCents:     data.Balance.MinorUnits,
+			Iban:             data.Iban,
+			Bic:              data.Bic,
+			Limits:           toLimits(data.Limits),
+			Approval:         toApproval(data.Approval),
+			OverdraftCents:   toOverdraft(data.Overdraft),
+			Plan:             data.Plan,
+		})
+		if err != nil {
+			return err
+		}
 
-	return err
+		if len(data.Approval.Approvers) > 0 {
+			if err = repos.BankAccount.ReplaceApprovers(uint(id), data.Approval.Approvers); err != nil {
+				return err
+			}
+		}
+
+		return s.postBalanceChange(repos, domain.LedgerOpeningBalance, uint(id), data.Balance.MinorUnits)
+	})
 }
 
-// Read a bank account
+// Read a bank account, its approval policy and what is left of its overdraft
 func (s service) Read(iban string) (domain.BankAccount, error) {
 	info, err := s.bankAccountRepo.ReadByIban(iban)
 
@@ -47,30 +75,151 @@ func (s service) Read(iban string) (domain.BankAccount, error) {
 		return domain.BankAccount{}, err
 	}
 
-	return domain.BankAccount{
-		Name:    info.OrganizationName,
-		Balance: float64(info.BalanceCents) / 100,
-		Iban:    info.Iban,
-		Bic:     info.Bic,
-	}, nil
+	approvers, err := s.bankAccountRepo.ReadApprovers(info.ID)
+	if err != nil {
+		return domain.BankAccount{}, err
+	}
+
+	bankAccount := domain.BankAccount{
+		Name:     info.OrganizationName,
+		Balance:  domain.NewMoney(info.BalanceCents, domain.AccountCurrency),
+		Iban:     info.Iban,
+		Bic:      info.Bic,
+		Limits:   fromLimits(info.Limits),
+		Approval: fromApproval(info.Approval, approvers),
+		Plan:     info.Plan,
+	}
+	if info.OverdraftCents > 0 {
+		overdraft := domain.NewMoney(info.OverdraftCents, domain.AccountCurrency)
+		headroom := domain.NewMoney(overdraftHeadroom(info.BalanceCents, info.OverdraftCents), domain.AccountCurrency)
+		bankAccount.Overdraft = &overdraft
+		bankAccount.OverdraftHeadroom = &headroom
+	}
+
+	return bankAccount, nil
 }
 
-// Update the values of a bank account
+// Update the values of a bank account. Its balance is left as it is, an edition changing it is refused.
 func (s service) Update(data domain.BankAccount) error {
-	info, err := s.bankAccountRepo.ReadByIban(data.Iban)
+	return s.unitOfWork.Do(func(repos uow.Repositories) error {
+		info, err := repos.BankAccount.ReadByIban(data.Iban)
 
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return err
+		}
+		if data.Balance.MinorUnits != info.BalanceCents {
+			return fmt.Errorf("%w: it is %s", ErrBalanceNotEditable, domain.NewMoney(info.BalanceCents, domain.AccountCurrency))
+		}
 
-	info.OrganizationName = data.Name
-	info.BalanceCents = int(data.Balance * 100)
-	info.Bic = data.Bic
+		info.OrganizationName = data.Name
+		info.Bic = data.Bic
+		info.Limits = toLimits(data.Limits)
+		info.Approval = toApproval(data.Approval)
+		info.OverdraftCents = toOverdraft(data.Overdraft)
+		info.Plan = data.Plan
 
-	return s.bankAccountRepo.Update(info)
+		if err = repos.BankAccount.Update(info); err != nil {
+			return err
+		}
+
+		return repos.BankAccount.ReplaceApprovers(info.ID, data.Approval.Approvers)
+	})
+}
+
+// postBalanceChange posts a change of the balance of a bank account against the equity, nothing when it is zero
+func (s service) postBalanceChange(repos uow.Repositories, operation domain.LedgerOperation, bankAccountID uint, differenceCents int64) error {
+	if differenceCents == 0 {
+		return nil
+	}
+
+	account := ledgerrepo.BankAccount(bankAccountID)
+	_, err := repos.Ledger.Post(ledgerrepo.Journal{
+		Operation: string(operation),
+		Reference: account,
+		CreatedAt: s.clock.Now().UTC(),
+	}, ledgerrepo.AgainstEquity(account, differenceCents))
+	return err
 }
 
 // Delete a bank account
 func (s service) Delete(iban string) error {
 	return s.bankAccountRepo.DeleteByIban(iban)
 }
+
+// toLimits the limits to be stored, zero for the ones that don't apply
+func toLimits(limits domain.AccountLimits) bankaccountrepo.Limits {
+	cents := func(limit *domain.Money) int64 {
+		if limit == nil {
+			return 0
+		}
+		return limit.MinorUnits
+	}
+	return bankaccountrepo.Limits{
+		TransactionCents: cents(limits.PerTransaction),
+		BatchCents:       cents(limits.PerBatch),
+		DailyCents:       cents(limits.Daily),
+		MonthlyCents:     cents(limits.Monthly),
+	}
+}
+
+func fromLimits(limits bankaccountrepo.Limits) domain.AccountLimits {
+	money := func(cents int64) *domain.Money {
+		if cents == 0 {
+			return nil
+		}
+		limit := domain.NewMoney(cents, domain.AccountCurrency)
+		return &limit
+	}
+	return domain.AccountLimits{
+		PerTransaction: money(limits.TransactionCents),
+		PerBatch:       money(limits.BatchCents),
+		Daily:          money(limits.DailyCents),
+		Monthly:        money(limits.MonthlyCents),
+	}
+}
+
+// toOverdraft the overdraft to be stored, zero when none is authorised
+func toOverdraft(overdraft *domain.Money) int64 {
+	if overdraft == nil {
+		return 0
+	}
+	return overdraft.MinorUnits
+}
+
+// overdraftHeadroom what is left of the overdraft: all of it while the balance is positive, nothing once the
+// balance went below the overdraft, e.g. after the overdraft was lowered
+func overdraftHeadroom(balanceCents, overdraftCents int64) int64 {
+	if balanceCents >= 0 {
+		return overdraftCents
+	}
+	if balanceCents+overdraftCents < 0 {
+		return 0
+	}
+	return balanceCents + overdraftCents
+}
+
+// toApproval the approval rule to be stored. One approval is required when the threshold is set without
+// saying how many.
+func toApproval(policy domain.ApprovalPolicy) bankaccountrepo.Approval {
+	if policy.Threshold == nil {
+		return bankaccountrepo.Approval{}
+	}
+	approval := bankaccountrepo.Approval{ThresholdCents: policy.Threshold.MinorUnits, RequiredApprovals: policy.RequiredApprovals}
+	if approval.RequiredApprovals == 0 {
+		approval.RequiredApprovals = 1
+	}
+	return approval
+}
+
+func fromApproval(approval bankaccountrepo.Approval, approvers []string) domain.ApprovalPolicy {
+	policy := domain.ApprovalPolicy{Approvers: approvers}
+	if approval.ThresholdCents != 0 {
+		threshold := domain.NewMoney(approval.ThresholdCents, domain.AccountCurrency)
+		policy.Threshold = &threshold
+		policy.RequiredApprovals = approval.RequiredApprovals
+	}
+	if len(policy.Approvers) == 0 {
+		policy.Approvers = nil
+	}
+	return policy
+}
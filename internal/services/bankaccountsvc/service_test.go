@@ -4,11 +4,15 @@ import (
 	"errors"
 	"github.com/adrianoccosta/exercise-qonto/internal/domain"
 	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
 	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
 	"github.com/adrianoccosta/exercise-qonto/test/mocks/repository"
+	"github.com/adrianoccosta/exercise-qonto/tools"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestBankAccountService(t *testing.T) {
@@ -16,28 +20,59 @@ func TestBankAccountService(t *testing.T) {
 	defer ctrl.Finish()
 
 	repoMock := mockrepository.NewMockBankAccountRepository(ctrl)
+	repoMockLedger := mockrepository.NewMockLedgerRepository(ctrl)
 	logMock := mocklog.NewMockLogger(ctrl)
 
+	uowMock := mockrepository.NewMockUnitOfWork(ctrl)
+	uowMock.EXPECT().
+		Do(gomock.Any()).
+		DoAndReturn(func(fn func(repos uow.Repositories) error) error {
+			return fn(uow.Repositories{BankAccount: repoMock, Ledger: repoMockLedger})
+		}).
+		AnyTimes()
+
+	now := time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC)
+	clock := tools.ClockFunc(func() time.Time { return now })
+
+	dailyLimit := domain.NewMoney(50000, "EUR")
+	approvalThreshold := domain.NewMoney(100000, "EUR")
+	approvers := []string{"jane.doe", "john.smith"}
 	bankAccount := domain.BankAccount{
-		Name:    "ACME Corp",
-		Balance: 12.40,
-		Iban:    "FR10474608000002006107XXXXX",
-		Bic:     "OIVUSCLQXXX",
+		Name:     "ACME Corp",
+		Balance:  domain.NewMoney(1240, "EUR"),
+		Iban:     "FR81474608000002006107XXXXX",
+		Bic:      "OIVUSCLQXXX",
+		Limits:   domain.AccountLimits{Daily: &dailyLimit},
+		Approval: domain.ApprovalPolicy{Threshold: &approvalThreshold, RequiredApprovals: 2, Approvers: approvers},
+		Plan:     "premium",
 	}
 
 	bankAccountRepo := bankaccountrepo.BankAccount{
+		ID:               1,
 		OrganizationName: "ACME Corp",
 		BalanceCents:     1240,
-		Iban:             "FR10474608000002006107XXXXX",
+		Iban:             "FR81474608000002006107XXXXX",
 		Bic:              "OIVUSCLQXXX",
+		Limits:           bankaccountrepo.Limits{DailyCents: 50000},
+		Approval:         bankaccountrepo.Approval{ThresholdCents: 100000, RequiredApprovals: 2},
+		Plan:             "premium",
 	}
 
 	t.Run("Test Create return success", func(t *testing.T) {
+		created := bankAccountRepo
+		created.ID = 0
+		repoMock.EXPECT().
+			Create(created).
+			Return(1, nil)
 		repoMock.EXPECT().
-			Create(bankAccountRepo).
+			ReplaceApprovers(uint(1), approvers).
+			Return(nil)
+		repoMockLedger.EXPECT().
+			Post(ledgerrepo.Journal{Operation: "opening_balance", Reference: "bank_account:1", CreatedAt: now},
+				ledgerrepo.EntryList{ledgerrepo.Debit("equity", 1240, 0), ledgerrepo.Credit("bank_account:1", 1240, 0)}).
 			Return(1, nil)
 
-		svc := New(repoMock, logMock)
+		svc := New(uowMock, repoMock, clock, logMock)
 		err := svc.Create(bankAccount)
 
 		assert.Nil(t, err)
@@ -47,56 +82,125 @@ func TestBankAccountService(t *testing.T) {
 		repoMock.EXPECT().
 			Create(gomock.Any()).
 			Return(0, errors.New("error"))
+		repoMockLedger.EXPECT().Post(gomock.Any(), gomock.Any()).Times(0)
 
-		svc := New(repoMock, logMock)
+		svc := New(uowMock, repoMock, clock, logMock)
 		err := svc.Create(domain.BankAccount{})
 
 		assert.Error(t, err)
 	})
 
+	t.Run("Test Create without balance doesn't post on the ledger", func(t *testing.T) {
+		repoMock.EXPECT().
+			Create(gomock.Any()).
+			Return(2, nil)
+		repoMockLedger.EXPECT().Post(gomock.Any(), gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMock, clock, logMock)
+		err := svc.Create(domain.BankAccount{Name: "ACME Corp", Balance: domain.NewMoney(0, "EUR")})
+
+		assert.Nil(t, err)
+	})
+
 	t.Run("Test Read return success", func(t *testing.T) {
 		repoMock.EXPECT().
-			ReadByIban("FR10474608000002006107XXXXX").
+			ReadByIban("FR81474608000002006107XXXXX").
 			Return(bankAccountRepo, nil)
+		repoMock.EXPECT().
+			ReadApprovers(uint(1)).
+			Return(approvers, nil)
 
-		svc := New(repoMock, logMock)
-		res, err := svc.Read("FR10474608000002006107XXXXX")
+		svc := New(uowMock, repoMock, clock, logMock)
+		res, err := svc.Read("FR81474608000002006107XXXXX")
 
 		assert.Nil(t, err)
 		assert.Equal(t, bankAccount, res)
 	})
 
+	t.Run("Test Read return what is left of the overdraft", func(t *testing.T) {
+		overdrawn := bankAccountRepo
+		overdrawn.BalanceCents = -1500
+		overdrawn.OverdraftCents = 5000
+		repoMock.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(overdrawn, nil)
+		repoMock.EXPECT().
+			ReadApprovers(uint(1)).
+			Return(approvers, nil)
+
+		svc := New(uowMock, repoMock, clock, logMock)
+		res, err := svc.Read("FR81474608000002006107XXXXX")
+
+		assert.Nil(t, err)
+		assert.Equal(t, domain.NewMoney(-1500, "EUR"), res.Balance)
+		assert.Equal(t, domain.NewMoney(5000, "EUR"), *res.Overdraft)
+		assert.Equal(t, domain.NewMoney(3500, "EUR"), *res.OverdraftHeadroom)
+	})
+
+	t.Run("Test Read return error reading the approvers", func(t *testing.T) {
+		repoMock.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankAccountRepo, nil)
+		repoMock.EXPECT().
+			ReadApprovers(uint(1)).
+			Return(nil, errors.New("error"))
+
+		svc := New(uowMock, repoMock, clock, logMock)
+		_, err := svc.Read("FR81474608000002006107XXXXX")
+
+		assert.Error(t, err)
+	})
+
 	t.Run("Test Read return error", func(t *testing.T) {
 		repoMock.EXPECT().
 			ReadByIban(gomock.Any()).
 			Return(bankaccountrepo.BankAccount{}, errors.New("error"))
 
-		svc := New(repoMock, logMock)
-		_, err := svc.Read("FR10474608000002006107XXXXX")
+		svc := New(uowMock, repoMock, clock, logMock)
+		_, err := svc.Read("FR81474608000002006107XXXXX")
 
 		assert.Error(t, err)
 	})
 
 	t.Run("Test Update return success", func(t *testing.T) {
 		repoMock.EXPECT().
-			ReadByIban("FR10474608000002006107XXXXX").
+			ReadByIban("FR81474608000002006107XXXXX").
 			Return(bankAccountRepo, nil)
 		repoMock.EXPECT().
-			Update(gomock.Any()).
+			Update(bankAccountRepo).
+			Return(nil)
+		repoMock.EXPECT().
+			ReplaceApprovers(uint(1), approvers).
 			Return(nil)
 
-		svc := New(repoMock, logMock)
+		svc := New(uowMock, repoMock, clock, logMock)
 		err := svc.Update(bankAccount)
 
 		assert.Nil(t, err)
 	})
 
+	t.Run("Test Update return error when the balance changes", func(t *testing.T) {
+		repoMock.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankAccountRepo, nil)
+		repoMock.EXPECT().Update(gomock.Any()).Times(0)
+		repoMockLedger.EXPECT().Post(gomock.Any(), gomock.Any()).Times(0)
+
+		lowered := bankAccount
+		lowered.Balance = domain.NewMoney(1000, "EUR")
+		svc := New(uowMock, repoMock, clock, logMock)
+		err := svc.Update(lowered)
+
+		assert.ErrorIs(t, err, ErrBalanceNotEditable)
+		assert.EqualError(t, err, "the balance of a bank account can't be edited: it is 12.40")
+	})
+
 	t.Run("Test Update return error reading value", func(t *testing.T) {
 		repoMock.EXPECT().
 			ReadByIban(gomock.Any()).
 			Return(bankaccountrepo.BankAccount{}, errors.New("error"))
 
-		svc := New(repoMock, logMock)
+		svc := New(uowMock, repoMock, clock, logMock)
 		err := svc.Update(domain.BankAccount{})
 
 		assert.Error(t, err)
@@ -110,19 +214,19 @@ func TestBankAccountService(t *testing.T) {
 			Update(gomock.Any()).
 			Return(errors.New("error"))
 
-		svc := New(repoMock, logMock)
-		err := svc.Update(domain.BankAccount{})
+		svc := New(uowMock, repoMock, clock, logMock)
+		err := svc.Update(bankAccount)
 
 		assert.Error(t, err)
 	})
 
 	t.Run("Test Delete return success", func(t *testing.T) {
 		repoMock.EXPECT().
-			DeleteByIban("FR10474608000002006107XXXXX").
+			DeleteByIban("FR81474608000002006107XXXXX").
 			Return(nil)
 
-		svc := New(repoMock, logMock)
-		err := svc.Delete("FR10474608000002006107XXXXX")
+		svc := New(uowMock, repoMock, clock, logMock)
+		err := svc.Delete("FR81474608000002006107XXXXX")
 
 		assert.Nil(t, err)
 	})
@@ -132,9 +236,16 @@ func TestBankAccountService(t *testing.T) {
 			DeleteByIban(gomock.Any()).
 			Return(errors.New("error"))
 
-		svc := New(repoMock, logMock)
-		err := svc.Delete("FR10474608000002006107XXXXX")
+		svc := New(uowMock, repoMock, clock, logMock)
+		err := svc.Delete("FR81474608000002006107XXXXX")
 
 		assert.Error(t, err)
 	})
 }
+
+func TestOverdraftHeadroom(t *testing.T) {
+	assert.Equal(t, int64(5000), overdraftHeadroom(1240, 5000))
+	assert.Equal(t, int64(3500), overdraftHeadroom(-1500, 5000))
+	// the overdraft was lowered below what is drawn
+	assert.Equal(t, int64(0), overdraftHeadroom(-6000, 5000))
+}
@@ -0,0 +1,195 @@
+package beneficiarysvc
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/beneficiaryrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+)
+
+var (
+	// ErrBeneficiaryNotFound is returned when the beneficiary doesn't exist on the bank account
+	ErrBeneficiaryNotFound = errors.New("beneficiary not found")
+	// ErrDuplicateBeneficiary is returned when the bank account already has a beneficiary with the iban
+	ErrDuplicateBeneficiary = errors.New("beneficiary with the same iban already exists")
+	// ErrAccountNotFound is returned when the bank account of the beneficiary doesn't exist
+	ErrAccountNotFound = errors.New("bank account not found")
+	// ErrMissingAccount is returned when listing the beneficiaries without the bank account they belong to
+	ErrMissingAccount = errors.New("organization_iban is required")
+)
+
+// BeneficiaryService Interface for the beneficiary services
+type BeneficiaryService interface {
+	Create(data domain.Beneficiary) (domain.BeneficiaryDetail, error)
+	Read(beneficiaryID uint) (domain.BeneficiaryDetail, error)
+	ReadByFilter(filters map[string]string) (domain.BeneficiaryDetailList, error)
+	Update(beneficiaryID uint, data domain.Beneficiary) (domain.BeneficiaryDetail, error)
+	Delete(beneficiaryID uint) error
+}
+
+// New returns an instance of the beneficiary services
+func New(unitOfWork uow.UnitOfWork, beneficiaryRepo beneficiaryrepo.BeneficiaryRepository, clock tools.Clock, logger log.Logger) BeneficiaryService {
+	return service{
+		logger:          logger,
+		clock:           clock,
+		unitOfWork:      unitOfWork,
+		beneficiaryRepo: beneficiaryRepo,
+	}
+}
+
+type service struct {
+	logger          log.Logger
+	clock           tools.Clock
+	unitOfWork      uow.UnitOfWork
+	beneficiaryRepo beneficiaryrepo.BeneficiaryRepository
+}
+
+// Create registers a beneficiary on the bank account, each iban at most once per account
+func (s service) Create(data domain.Beneficiary) (domain.BeneficiaryDetail, error) {
+	now := s.clock.Now().UTC()
+	beneficiary := beneficiaryrepo.Beneficiary{
+		OrganizationIban: data.OrganizationIban,
+		Name:             data.Name,
+		Iban:             data.Iban,
+		Bic:              data.Bic,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		bankAccount, err := repos.BankAccount.ReadByIban(data.OrganizationIban)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrAccountNotFound, data.OrganizationIban)
+		}
+		if err != nil {
+			return err
+		}
+		beneficiary.BankAccountID = bankAccount.ID
+
+		if err = checkUnique(repos, beneficiary); err != nil {
+			return err
+		}
+
+		id, err := repos.Beneficiary.Create(beneficiary)
+		if err != nil {
+			return err
+		}
+		beneficiary.ID = uint(id)
+		return nil
+	})
+	if err != nil {
+		return domain.BeneficiaryDetail{}, err
+	}
+
+	return toDetail(beneficiary), nil
+}
+
+// Read a beneficiary
+func (s service) Read(beneficiaryID uint) (domain.BeneficiaryDetail, error) {
+	beneficiary, err := read(s.beneficiaryRepo, beneficiaryID)
+	if err != nil {
+		return domain.BeneficiaryDetail{}, err
+	}
+
+	return toDetail(beneficiary), nil
+}
+
+// ReadByFilter list of the beneficiaries of a bank account, the organization_iban filter is required
+func (s service) ReadByFilter(filters map[string]string) (domain.BeneficiaryDetailList, error) {
+	if filters["organization_iban"] == "" {
+		return nil, ErrMissingAccount
+	}
+
+	beneficiaries, err := s.beneficiaryRepo.ReadByFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	res := domain.BeneficiaryDetailList{}
+	for _, beneficiary := range beneficiaries {
+		res = append(res, toDetail(beneficiary))
+	}
+
+	return res, nil
+}
+
+// Update replaces the details of a beneficiary. It stays on its bank account, so the organization iban must
+// be that of its account. The bulk transfers referencing it are paid with the new details from now on.
+func (s service) Update(beneficiaryID uint, data domain.Beneficiary) (domain.BeneficiaryDetail, error) {
+	var beneficiary beneficiaryrepo.Beneficiary
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		var err error
+		beneficiary, err = read(repos.Beneficiary, beneficiaryID)
+		if err != nil {
+			return err
+		}
+		if beneficiary.OrganizationIban != data.OrganizationIban {
+			return fmt.Errorf("%w: %d isn't a beneficiary of %s", ErrBeneficiaryNotFound, beneficiaryID, data.OrganizationIban)
+		}
+
+		beneficiary.Name = data.Name
+		beneficiary.Iban = data.Iban
+		beneficiary.Bic = data.Bic
+		beneficiary.UpdatedAt = s.clock.Now().UTC()
+		if err = checkUnique(repos, beneficiary); err != nil {
+			return err
+		}
+
+		return repos.Beneficiary.Update(beneficiary)
+	})
+	if err != nil {
+		return domain.BeneficiaryDetail{}, err
+	}
+
+	return toDetail(beneficiary), nil
+}
+
+// Delete a beneficiary. The bulk transfers still referencing it fail at their execution.
+func (s service) Delete(beneficiaryID uint) error {
+	return s.unitOfWork.Do(func(repos uow.Repositories) error {
+		if _, err := read(repos.Beneficiary, beneficiaryID); err != nil {
+			return err
+		}
+
+		return repos.Beneficiary.Delete(beneficiaryID)
+	})
+}
+
+// checkUnique rejects the beneficiary when another beneficiary of its bank account holds its iban
+func checkUnique(repos uow.Repositories, beneficiary beneficiaryrepo.Beneficiary) error {
+	existing, err := repos.Beneficiary.ReadByIban(beneficiary.BankAccountID, beneficiary.Iban)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != beneficiary.ID {
+		return fmt.Errorf("%w: %s", ErrDuplicateBeneficiary, beneficiary.Iban)
+	}
+	return nil
+}
+
+func read(repo beneficiaryrepo.BeneficiaryRepository, beneficiaryID uint) (beneficiaryrepo.Beneficiary, error) {
+	beneficiary, err := repo.Read(beneficiaryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return beneficiaryrepo.Beneficiary{}, ErrBeneficiaryNotFound
+	}
+	return beneficiary, err
+}
+
+func toDetail(beneficiary beneficiaryrepo.Beneficiary) domain.BeneficiaryDetail {
+	return domain.BeneficiaryDetail{
+		ID:               beneficiary.ID,
+		OrganizationIban: beneficiary.OrganizationIban,
+		Name:             beneficiary.Name,
+		Iban:             beneficiary.Iban,
+		Bic:              beneficiary.Bic,
+		CreatedAt:        beneficiary.CreatedAt,
+		UpdatedAt:        beneficiary.UpdatedAt,
+	}
+}
@@ -0,0 +1,220 @@
+package beneficiarysvc
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/beneficiaryrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/repository"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBeneficiaryService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMockBankAccount := mockrepository.NewMockBankAccountRepository(ctrl)
+	repoMockBeneficiary := mockrepository.NewMockBeneficiaryRepository(ctrl)
+	logMock := mocklog.NewMockLogger(ctrl)
+	uowMock := mockrepository.NewMockUnitOfWork(ctrl)
+	uowMock.EXPECT().
+		Do(gomock.Any()).
+		DoAndReturn(func(fn func(repos uow.Repositories) error) error {
+			return fn(uow.Repositories{BankAccount: repoMockBankAccount, Beneficiary: repoMockBeneficiary})
+		}).
+		AnyTimes()
+
+	now := time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC)
+	clock := tools.ClockFunc(func() time.Time { return now })
+	svc := New(uowMock, repoMockBeneficiary, clock, logMock)
+
+	data := domain.Beneficiary{
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		Name:             "Bip Bip",
+		Iban:             "EE303680981021245685",
+		Bic:              "CRLYFRPPTOU",
+	}
+
+	beneficiary := beneficiaryrepo.Beneficiary{
+		ID:               3,
+		BankAccountID:    1,
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		Name:             "Bip Bip",
+		Iban:             "EE303680981021245685",
+		Bic:              "CRLYFRPPTOU",
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	detail := domain.BeneficiaryDetail{
+		ID:               3,
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		Name:             "Bip Bip",
+		Iban:             "EE303680981021245685",
+		Bic:              "CRLYFRPPTOU",
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	t.Run("Test Create registers the beneficiary on the bank account", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankaccountrepo.BankAccount{ID: 1}, nil)
+		repoMockBeneficiary.EXPECT().
+			ReadByIban(uint(1), "EE303680981021245685").
+			Return(beneficiaryrepo.Beneficiary{}, sql.ErrNoRows)
+		repoMockBeneficiary.EXPECT().
+			Create(gomock.Any()).
+			DoAndReturn(func(data beneficiaryrepo.Beneficiary) (int, error) {
+				assert.Equal(t, uint(1), data.BankAccountID)
+				assert.Equal(t, "Bip Bip", data.Name)
+				return 3, nil
+			})
+
+		res, err := svc.Create(data)
+		assert.NoError(t, err)
+		assert.Equal(t, detail, res)
+	})
+
+	t.Run("Test Create return conflict when the iban is already a beneficiary", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankaccountrepo.BankAccount{ID: 1}, nil)
+		repoMockBeneficiary.EXPECT().
+			ReadByIban(uint(1), "EE303680981021245685").
+			Return(beneficiary, nil)
+		repoMockBeneficiary.EXPECT().Create(gomock.Any()).Times(0)
+
+		_, err := svc.Create(data)
+		assert.ErrorIs(t, err, ErrDuplicateBeneficiary)
+	})
+
+	t.Run("Test Create return error when the bank account doesn't exist", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankaccountrepo.BankAccount{}, sql.ErrNoRows)
+
+		_, err := svc.Create(data)
+		assert.ErrorIs(t, err, ErrAccountNotFound)
+	})
+
+	t.Run("Test Read return the beneficiary", func(t *testing.T) {
+		repoMockBeneficiary.EXPECT().Read(uint(3)).Return(beneficiary, nil)
+
+		res, err := svc.Read(3)
+		assert.NoError(t, err)
+		assert.Equal(t, detail, res)
+	})
+
+	t.Run("Test Read return not found", func(t *testing.T) {
+		repoMockBeneficiary.EXPECT().Read(uint(3)).Return(beneficiaryrepo.Beneficiary{}, sql.ErrNoRows)
+
+		_, err := svc.Read(3)
+		assert.ErrorIs(t, err, ErrBeneficiaryNotFound)
+	})
+
+	t.Run("Test ReadByFilter return the beneficiaries of the bank account", func(t *testing.T) {
+		filters := map[string]string{"organization_iban": "FR81474608000002006107XXXXX"}
+		repoMockBeneficiary.EXPECT().ReadByFilter(filters).Return(beneficiaryrepo.BeneficiaryList{beneficiary}, nil)
+
+		res, err := svc.ReadByFilter(filters)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.BeneficiaryDetailList{detail}, res)
+	})
+
+	t.Run("Test ReadByFilter return error without the bank account", func(t *testing.T) {
+		repoMockBeneficiary.EXPECT().ReadByFilter(gomock.Any()).Times(0)
+
+		_, err := svc.ReadByFilter(map[string]string{"name": "Bip Bip"})
+		assert.ErrorIs(t, err, ErrMissingAccount)
+	})
+
+	t.Run("Test Update replaces the details of the beneficiary", func(t *testing.T) {
+		later := now.Add(time.Hour)
+		svc := New(uowMock, repoMockBeneficiary, tools.ClockFunc(func() time.Time { return later }), logMock)
+
+		updated := data
+		updated.Name = "Bip Bip Ltd"
+		updated.Iban = "DE44354208100362090817"
+
+		repoMockBeneficiary.EXPECT().Read(uint(3)).Return(beneficiary, nil)
+		repoMockBeneficiary.EXPECT().
+			ReadByIban(uint(1), "DE44354208100362090817").
+			Return(beneficiaryrepo.Beneficiary{}, sql.ErrNoRows)
+		repoMockBeneficiary.EXPECT().
+			Update(gomock.Any()).
+			DoAndReturn(func(data beneficiaryrepo.Beneficiary) error {
+				assert.Equal(t, uint(3), data.ID)
+				assert.Equal(t, "Bip Bip Ltd", data.Name)
+				assert.Equal(t, "DE44354208100362090817", data.Iban)
+				assert.Equal(t, later, data.UpdatedAt)
+				return nil
+			})
+
+		res, err := svc.Update(3, updated)
+		assert.NoError(t, err)
+		assert.Equal(t, "Bip Bip Ltd", res.Name)
+		assert.Equal(t, now, res.CreatedAt)
+		assert.Equal(t, later, res.UpdatedAt)
+	})
+
+	t.Run("Test Update keeps the iban of the beneficiary", func(t *testing.T) {
+		repoMockBeneficiary.EXPECT().Read(uint(3)).Return(beneficiary, nil)
+		repoMockBeneficiary.EXPECT().ReadByIban(uint(1), "EE303680981021245685").Return(beneficiary, nil)
+		repoMockBeneficiary.EXPECT().Update(gomock.Any()).Return(nil)
+
+		_, err := svc.Update(3, data)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Update return conflict when another beneficiary holds the iban", func(t *testing.T) {
+		other := beneficiary
+		other.ID = 4
+
+		repoMockBeneficiary.EXPECT().Read(uint(3)).Return(beneficiary, nil)
+		repoMockBeneficiary.EXPECT().ReadByIban(uint(1), "EE303680981021245685").Return(other, nil)
+		repoMockBeneficiary.EXPECT().Update(gomock.Any()).Times(0)
+
+		_, err := svc.Update(3, data)
+		assert.ErrorIs(t, err, ErrDuplicateBeneficiary)
+	})
+
+	t.Run("Test Update return not found when the beneficiary belongs to another account", func(t *testing.T) {
+		moved := data
+		moved.OrganizationIban = "DE44354208100362090817"
+
+		repoMockBeneficiary.EXPECT().Read(uint(3)).Return(beneficiary, nil)
+		repoMockBeneficiary.EXPECT().Update(gomock.Any()).Times(0)
+
+		_, err := svc.Update(3, moved)
+		assert.ErrorIs(t, err, ErrBeneficiaryNotFound)
+	})
+
+	t.Run("Test Delete removes the beneficiary", func(t *testing.T) {
+		repoMockBeneficiary.EXPECT().Read(uint(3)).Return(beneficiary, nil)
+		repoMockBeneficiary.EXPECT().Delete(uint(3)).Return(nil)
+
+		assert.NoError(t, svc.Delete(3))
+	})
+
+	t.Run("Test Delete return not found", func(t *testing.T) {
+		repoMockBeneficiary.EXPECT().Read(uint(3)).Return(beneficiaryrepo.Beneficiary{}, sql.ErrNoRows)
+		repoMockBeneficiary.EXPECT().Delete(gomock.Any()).Times(0)
+
+		assert.ErrorIs(t, svc.Delete(3), ErrBeneficiaryNotFound)
+	})
+
+	t.Run("Test Delete return error", func(t *testing.T) {
+		repoMockBeneficiary.EXPECT().Read(uint(3)).Return(beneficiary, nil)
+		repoMockBeneficiary.EXPECT().Delete(uint(3)).Return(errors.New("error"))
+
+		assert.Error(t, svc.Delete(3))
+	})
+}
@@ -0,0 +1,88 @@
+package ledgersvc
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
+	"github.com/adrianoccosta/exercise-qonto/log"
+)
+
+// ErrBankAccountNotFound is returned when no bank account has the given iban
+var ErrBankAccountNotFound = errors.New("bank account not found")
+
+// LedgerService Interface for the ledger services
+type LedgerService interface {
+	ReadEntries(filters map[string]string) (domain.LedgerEntryList, error)
+	Balance(iban string) (domain.LedgerBalance, error)
+}
+
+// New returns an instance of the ledger services
+func New(ledgerRepo ledgerrepo.LedgerRepository, bankAccountRepo bankaccountrepo.BankAccountRepository, logger log.Logger) LedgerService {
+	return service{
+		logger:          logger,
+		ledgerRepo:      ledgerRepo,
+		bankAccountRepo: bankAccountRepo,
+	}
+}
+
+type service struct {
+	logger          log.Logger
+	ledgerRepo      ledgerrepo.LedgerRepository
+	bankAccountRepo bankaccountrepo.BankAccountRepository
+}
+
+// ReadEntries list the ledger entries. The iban filter selects the entries of the bank account with that iban.
+func (s service) ReadEntries(filters map[string]string) (domain.LedgerEntryList, error) {
+	iban, ok := filters["iban"]
+	if !ok {
+		return s.ledgerRepo.ReadEntries(filters)
+	}
+
+	bankAccount, err := s.readBankAccount(iban)
+	if err != nil {
+		return nil, err
+	}
+
+	accountFilters := map[string]string{"account": ledgerrepo.BankAccount(bankAccount.ID)}
+	for k, v := range filters {
+		if k != "iban" {
+			accountFilters[k] = v
+		}
+	}
+
+	return s.ledgerRepo.ReadEntries(accountFilters)
+}
+
+// Balance derives the balance of a bank account from its ledger entries and tells whether it matches
+// the balance cached on the bank account
+func (s service) Balance(iban string) (domain.LedgerBalance, error) {
+	bankAccount, err := s.readBankAccount(iban)
+	if err != nil {
+		return domain.LedgerBalance{}, err
+	}
+
+	account := ledgerrepo.BankAccount(bankAccount.ID)
+	balanceCents, err := s.ledgerRepo.Balance(account)
+	if err != nil {
+		return domain.LedgerBalance{}, err
+	}
+
+	return domain.LedgerBalance{
+		Iban:          bankAccount.Iban,
+		Account:       account,
+		Balance:       domain.NewMoney(balanceCents, domain.AccountCurrency),
+		CachedBalance: domain.NewMoney(bankAccount.BalanceCents, domain.AccountCurrency),
+		Consistent:    balanceCents == bankAccount.BalanceCents,
+	}, nil
+}
+
+func (s service) readBankAccount(iban string) (bankaccountrepo.BankAccount, error) {
+	bankAccount, err := s.bankAccountRepo.ReadByIban(iban)
+	if errors.Is(err, sql.ErrNoRows) {
+		return bankaccountrepo.BankAccount{}, ErrBankAccountNotFound
+	}
+
+	return bankAccount, err
+}
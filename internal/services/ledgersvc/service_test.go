@@ -0,0 +1,98 @@
+package ledgersvc
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLedgerService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mockrepository.NewMockLedgerRepository(ctrl)
+	repoMockBankAccount := mockrepository.NewMockBankAccountRepository(ctrl)
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	svc := New(repoMock, repoMockBankAccount, logMock)
+
+	bankAccount := bankaccountrepo.BankAccount{
+		ID:               1,
+		OrganizationName: "ACME Corp",
+		BalanceCents:     8547,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	}
+
+	entries := domain.LedgerEntryList{{ID: 1, JournalID: 7, Operation: domain.LedgerBulkTransfer, Account: "bank_account:1", Side: domain.LedgerDebit, Amount: domain.NewMoney(1453, "EUR")}}
+
+	t.Run("Test ReadEntries return success", func(t *testing.T) {
+		repoMock.EXPECT().ReadEntries(map[string]string{"operation": "bulk_transfer"}).Return(entries, nil)
+
+		res, err := svc.ReadEntries(map[string]string{"operation": "bulk_transfer"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, entries, res)
+	})
+
+	t.Run("Test ReadEntries translate the iban into the ledger account", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(bankAccount, nil)
+		repoMock.EXPECT().ReadEntries(map[string]string{"account": "bank_account:1", "operation": "bulk_transfer"}).Return(entries, nil)
+
+		res, err := svc.ReadEntries(map[string]string{"iban": "FR81474608000002006107XXXXX", "operation": "bulk_transfer"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, entries, res)
+	})
+
+	t.Run("Test ReadEntries return not found on unknown iban", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().ReadByIban("EE303680981021245685").Return(bankaccountrepo.BankAccount{}, sql.ErrNoRows)
+		repoMock.EXPECT().ReadEntries(gomock.Any()).Times(0)
+
+		_, err := svc.ReadEntries(map[string]string{"iban": "EE303680981021245685"})
+
+		assert.ErrorIs(t, err, ErrBankAccountNotFound)
+	})
+
+	t.Run("Test Balance return the derived and the cached balances", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(bankAccount, nil)
+		repoMock.EXPECT().Balance("bank_account:1").Return(int64(8547), nil)
+
+		res, err := svc.Balance("FR81474608000002006107XXXXX")
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.LedgerBalance{
+			Iban:          "FR81474608000002006107XXXXX",
+			Account:       "bank_account:1",
+			Balance:       domain.NewMoney(8547, "EUR"),
+			CachedBalance: domain.NewMoney(8547, "EUR"),
+			Consistent:    true,
+		}, res)
+	})
+
+	t.Run("Test Balance tells when the cached balance drifted", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(bankAccount, nil)
+		repoMock.EXPECT().Balance("bank_account:1").Return(int64(10000), nil)
+
+		res, err := svc.Balance("FR81474608000002006107XXXXX")
+
+		assert.NoError(t, err)
+		assert.False(t, res.Consistent)
+		assert.Equal(t, domain.NewMoney(10000, "EUR"), res.Balance)
+	})
+
+	t.Run("Test Balance return error", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(bankAccount, nil)
+		repoMock.EXPECT().Balance("bank_account:1").Return(int64(0), errors.New("error"))
+
+		_, err := svc.Balance("FR81474608000002006107XXXXX")
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,117 @@
+package reconciliationsvc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/reconciliationrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"go.uber.org/zap"
+	"time"
+)
+
+// ErrMissingReason is returned when fixing the drifts without an audit reason
+var ErrMissingReason = errors.New("a reason is required to fix the balance drifts")
+
+// ReconciliationService Interface for the balance reconciliation services
+type ReconciliationService interface {
+	Reconcile(fix bool, reason string) (domain.Reconciliation, error)
+}
+
+// New returns an instance of the balance reconciliation services
+func New(unitOfWork uow.UnitOfWork, clock tools.Clock, logger log.Logger) ReconciliationService {
+	return service{
+		logger:     logger,
+		clock:      clock,
+		unitOfWork: unitOfWork,
+	}
+}
+
+type service struct {
+	logger     log.Logger
+	clock      tools.Clock
+	unitOfWork uow.UnitOfWork
+}
+
+// Reconcile compares the balance of every bank account with the balance expected from its opening balance, its
+// adjustments and its transactions, and cross-checks it against the balance of its ledger account. When fix is set
+// each drift is accounted for by an adjustment carrying the reason, the difference with the ledger being posted on
+// it against the equity, so the balances are left untouched and the next reconciliations find them consistent.
+func (s service) Reconcile(fix bool, reason string) (domain.Reconciliation, error) {
+	if fix && reason == "" {
+		return domain.Reconciliation{}, ErrMissingReason
+	}
+
+	var reconciliation domain.Reconciliation
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		balances, err := repos.Reconciliation.ReadBalances()
+		if err != nil {
+			return err
+		}
+
+		reconciliation = domain.Reconciliation{Accounts: len(balances), Drifts: []domain.BalanceDrift{}}
+		now := s.clock.Now().UTC()
+		for _, balance := range balances {
+			driftCents := balance.BalanceCents - balance.ExpectedCents
+			ledgerDriftCents := balance.BalanceCents - balance.LedgerCents
+			if driftCents == 0 && ledgerDriftCents == 0 {
+				continue
+			}
+
+			drift := domain.BalanceDrift{
+				Iban:            balance.Iban,
+				Name:            balance.OrganizationName,
+				Balance:         domain.NewMoney(balance.BalanceCents, domain.AccountCurrency),
+				ExpectedBalance: domain.NewMoney(balance.ExpectedCents, domain.AccountCurrency),
+				Drift:           domain.NewMoney(driftCents, domain.AccountCurrency),
+				LedgerBalance:   domain.NewMoney(balance.LedgerCents, domain.AccountCurrency),
+				LedgerDrift:     domain.NewMoney(ledgerDriftCents, domain.AccountCurrency),
+			}
+			if fix {
+				if err = adjust(repos, balance.BankAccountID, driftCents, ledgerDriftCents, reason, now); err != nil {
+					return err
+				}
+				drift.Fixed = true
+				s.logger.Info("balance drift adjusted", zap.String("iban", balance.Iban), zap.Int64("drift_cents", driftCents),
+					zap.Int64("ledger_drift_cents", ledgerDriftCents))
+			}
+
+			reconciliation.Drifts = append(reconciliation.Drifts, drift)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return domain.Reconciliation{}, err
+	}
+
+	return reconciliation, nil
+}
+
+// adjust stores the adjustment accounting for the drift of the bank account, and posts the drift from its ledger
+// balance on the ledger when there is one
+func adjust(repos uow.Repositories, bankAccountID uint, driftCents, ledgerDriftCents int64, reason string, now time.Time) error {
+	id, err := repos.Reconciliation.CreateAdjustment(reconciliationrepo.Adjustment{
+		BankAccountID: bankAccountID,
+		AmountCents:   driftCents,
+		Reason:        reason,
+		CreatedAt:     now,
+	})
+	if err != nil {
+		return err
+	}
+	if ledgerDriftCents == 0 {
+		return nil
+	}
+
+	_, err = repos.Ledger.Post(ledgerrepo.Journal{
+		Operation:   string(domain.LedgerAdjustment),
+		Reference:   fmt.Sprintf("balance_adjustment:%d", id),
+		Description: reason,
+		CreatedAt:   now,
+	}, ledgerrepo.AgainstEquity(ledgerrepo.BankAccount(bankAccountID), ledgerDriftCents))
+	return err
+}
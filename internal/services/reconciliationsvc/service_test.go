@@ -0,0 +1,230 @@
+package reconciliationsvc
+
+import (
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/reconciliationrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/repository"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReconciliationService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := mockrepository.NewMockReconciliationRepository(ctrl)
+	ledgerMock := mockrepository.NewMockLedgerRepository(ctrl)
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	uowMock := mockrepository.NewMockUnitOfWork(ctrl)
+	uowMock.EXPECT().
+		Do(gomock.Any()).
+		DoAndReturn(func(fn func(repos uow.Repositories) error) error {
+			return fn(uow.Repositories{Reconciliation: repoMock, Ledger: ledgerMock})
+		}).
+		AnyTimes()
+
+	now := time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC)
+	clock := tools.ClockFunc(func() time.Time { return now })
+	svc := New(uowMock, clock, logMock)
+
+	balances := []reconciliationrepo.AccountBalance{
+		{BankAccountID: 1, OrganizationName: "ACME Corp", Iban: "FR81474608000002006107XXXXX", BalanceCents: 8547, ExpectedCents: 8547, LedgerCents: 8547},
+		{BankAccountID: 2, OrganizationName: "Bip Bip", Iban: "EE303680981021245685", BalanceCents: 1000, ExpectedCents: 1453, LedgerCents: 1453},
+	}
+
+	drift := domain.BalanceDrift{
+		Iban:            "EE303680981021245685",
+		Name:            "Bip Bip",
+		Balance:         domain.NewMoney(1000, "EUR"),
+		ExpectedBalance: domain.NewMoney(1453, "EUR"),
+		Drift:           domain.NewMoney(-453, "EUR"),
+		LedgerBalance:   domain.NewMoney(1453, "EUR"),
+		LedgerDrift:     domain.NewMoney(-453, "EUR"),
+	}
+
+	t.Run("Test Reconcile report the drifts", func(t *testing.T) {
+		repoMock.EXPECT().ReadBalances().Return(balances, nil)
+		repoMock.EXPECT().CreateAdjustment(gomock.Any()).Times(0)
+
+		res, err := svc.Reconcile(false, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.Reconciliation{Accounts: 2, Drifts: []domain.BalanceDrift{drift}}, res)
+		assert.Equal(t, 1, res.Unresolved())
+	})
+
+	t.Run("Test Reconcile report no drift on consistent balances", func(t *testing.T) {
+		repoMock.EXPECT().ReadBalances().Return(balances[:1], nil)
+
+		res, err := svc.Reconcile(false, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.Reconciliation{Accounts: 1, Drifts: []domain.BalanceDrift{}}, res)
+	})
+
+	t.Run("Test Reconcile fix the drifts with adjustments posted on the ledger", func(t *testing.T) {
+		repoMock.EXPECT().ReadBalances().Return(balances, nil)
+		repoMock.EXPECT().
+			CreateAdjustment(reconciliationrepo.Adjustment{BankAccountID: 2, AmountCents: -453, Reason: "failed transfer", CreatedAt: now}).
+			Return(1, nil)
+		ledgerMock.EXPECT().
+			Post(
+				ledgerrepo.Journal{Operation: "adjustment", Reference: "balance_adjustment:1", Description: "failed transfer", CreatedAt: now},
+				ledgerrepo.EntryList{ledgerrepo.Debit("bank_account:2", 453, 0), ledgerrepo.Credit(ledgerrepo.AccountEquity, 453, 0)}).
+			Return(1, nil)
+		logMock.EXPECT().Info("balance drift adjusted", gomock.Any(), gomock.Any(), gomock.Any())
+
+		res, err := svc.Reconcile(true, "failed transfer")
+
+		fixed := drift
+		fixed.Fixed = true
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.BalanceDrift{fixed}, res.Drifts)
+		assert.Equal(t, 0, res.Unresolved())
+	})
+
+	t.Run("Test Reconcile report the drifts from the ledger alone", func(t *testing.T) {
+		repoMock.EXPECT().ReadBalances().Return([]reconciliationrepo.AccountBalance{
+			{BankAccountID: 1, OrganizationName: "ACME Corp", Iban: "FR81474608000002006107XXXXX", BalanceCents: 8547, ExpectedCents: 8547, LedgerCents: 8347},
+		}, nil)
+
+		res, err := svc.Reconcile(false, "")
+
+		assert.NoError(t, err)
+		require.Len(t, res.Drifts, 1)
+		assert.Equal(t, domain.NewMoney(0, "EUR"), res.Drifts[0].Drift)
+		assert.Equal(t, domain.NewMoney(200, "EUR"), res.Drifts[0].LedgerDrift)
+	})
+
+	t.Run("Test Reconcile fix the drifts from the transactions alone without posting on the ledger", func(t *testing.T) {
+		repoMock.EXPECT().ReadBalances().Return([]reconciliationrepo.AccountBalance{
+			{BankAccountID: 1, OrganizationName: "ACME Corp", Iban: "FR81474608000002006107XXXXX", BalanceCents: 8547, ExpectedCents: 8347, LedgerCents: 8547},
+		}, nil)
+		repoMock.EXPECT().
+			CreateAdjustment(reconciliationrepo.Adjustment{BankAccountID: 1, AmountCents: 200, Reason: "transaction lost", CreatedAt: now}).
+			Return(2, nil)
+		ledgerMock.EXPECT().Post(gomock.Any(), gomock.Any()).Times(0)
+		logMock.EXPECT().Info("balance drift adjusted", gomock.Any(), gomock.Any(), gomock.Any())
+
+		res, err := svc.Reconcile(true, "transaction lost")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, res.Unresolved())
+	})
+
+	t.Run("Test Reconcile refuses to fix without a reason", func(t *testing.T) {
+		repoMock.EXPECT().ReadBalances().Times(0)
+
+		_, err := svc.Reconcile(true, "")
+
+		assert.ErrorIs(t, err, ErrMissingReason)
+	})
+
+	t.Run("Test Reconcile return error", func(t *testing.T) {
+		repoMock.EXPECT().ReadBalances().Return(balances, nil)
+		repoMock.EXPECT().CreateAdjustment(gomock.Any()).Return(0, errors.New("error"))
+		ledgerMock.EXPECT().Post(gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := svc.Reconcile(true, "failed transfer")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Reconcile return error when the adjustment can't be posted", func(t *testing.T) {
+		repoMock.EXPECT().ReadBalances().Return(balances, nil)
+		repoMock.EXPECT().CreateAdjustment(gomock.Any()).Return(1, nil)
+		ledgerMock.EXPECT().Post(gomock.Any(), gomock.Any()).Return(0, errors.New("error"))
+
+		_, err := svc.Reconcile(true, "failed transfer")
+
+		assert.Error(t, err)
+	})
+}
+
+// TestReconciliationServiceDrift runs against a real database: a balance edited outside of the ledger is reported
+// until an adjustment posted on the ledger accounts for it
+func TestReconciliationServiceDrift(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+	logMock.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	ledgerRepository := ledgerrepo.New(conn)
+	svc := New(uow.New(conn), tools.SystemClock{}, logMock)
+
+	id, err := bankAccountRepository.Create(bankaccountrepo.BankAccount{OrganizationName: "ACME Corp", BalanceCents: 10000, Iban: "FR81474608000002006107XXXXX", Bic: "OIVUSCLQXXX"})
+	require.NoError(t, err)
+	bankAccountID := uint(id)
+	account := ledgerrepo.BankAccount(bankAccountID)
+	_, err = ledgerRepository.Post(ledgerrepo.Journal{Operation: "opening_balance", Reference: account, CreatedAt: time.Now().UTC()}, ledgerrepo.AgainstEquity(account, 10000))
+	require.NoError(t, err)
+
+	// a transfer of 15.00 debited along with its transaction and its ledger journal
+	_, err = bankAccountRepository.Debit(bankAccountID, 1500)
+	require.NoError(t, err)
+	transactionID, err := transactionrepo.New(conn).Create(transactionrepo.Transaction{CounterPartyName: "Bip Bip", CounterPartyIban: "EE303680981021245685",
+		CounterPartyBic: "CRLYFRPPTOU", AmountCents: 1500, AmountCurrency: "EUR", BankAccountID: bankAccountID, Direction: "outgoing", CreatedAt: time.Now().UTC()})
+	require.NoError(t, err)
+	_, err = ledgerRepository.Post(ledgerrepo.Journal{Operation: "bulk_transfer", Reference: "bulk_transfer:1", CreatedAt: time.Now().UTC()},
+		ledgerrepo.EntryList{ledgerrepo.Debit(account, 1500, uint(transactionID)), ledgerrepo.Credit(ledgerrepo.AccountExternal, 1500, uint(transactionID))})
+	require.NoError(t, err)
+
+	res, err := svc.Reconcile(false, "")
+	require.NoError(t, err)
+	assert.Empty(t, res.Drifts)
+
+	// a debit without its transaction nor its ledger journal
+	_, err = bankAccountRepository.Debit(bankAccountID, 200)
+	require.NoError(t, err)
+
+	res, err = svc.Reconcile(false, "")
+	require.NoError(t, err)
+	require.Len(t, res.Drifts, 1)
+	assert.Equal(t, domain.NewMoney(8500, "EUR"), res.Drifts[0].ExpectedBalance)
+	assert.Equal(t, domain.NewMoney(-200, "EUR"), res.Drifts[0].Drift)
+	assert.Equal(t, domain.NewMoney(8500, "EUR"), res.Drifts[0].LedgerBalance)
+	assert.Equal(t, domain.NewMoney(-200, "EUR"), res.Drifts[0].LedgerDrift)
+
+	res, err = svc.Reconcile(true, "transaction lost on a failed transfer")
+	require.NoError(t, err)
+	assert.Equal(t, 0, res.Unresolved())
+
+	res, err = svc.Reconcile(false, "")
+	require.NoError(t, err)
+	assert.Empty(t, res.Drifts)
+
+	var reason string
+	require.NoError(t, conn.Conn.QueryRow("SELECT reason FROM balance_adjustments WHERE bank_account_id = ?", bankAccountID).Scan(&reason))
+	assert.Equal(t, "transaction lost on a failed transfer", reason)
+
+	entries, err := ledgerRepository.ReadEntries(map[string]string{"operation": "adjustment"})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "transaction lost on a failed transfer", entries[0].Description)
+	balance, err := ledgerRepository.Balance(account)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8300), balance)
+}
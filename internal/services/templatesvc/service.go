@@ -0,0 +1,364 @@
+package templatesvc
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/templaterepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/transfersvc"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	// ErrTemplateNotFound is returned when the transfer template doesn't exist
+	ErrTemplateNotFound = errors.New("transfer template not found")
+	// ErrTemplateNotActive is returned when pausing a transfer template that isn't active
+	ErrTemplateNotActive = errors.New("transfer template is not active")
+	// ErrTemplateNotPaused is returned when resuming a transfer template that isn't paused
+	ErrTemplateNotPaused = errors.New("transfer template is not paused")
+)
+
+// TemplateService Interface for the transfer template services
+type TemplateService interface {
+	Create(data domain.TransferTemplate) (domain.TransferTemplateDetail, error)
+	Read(templateID uint) (domain.TransferTemplateDetail, error)
+	ReadByFilter(filters map[string]string) (domain.TransferTemplateDetailList, error)
+	Update(templateID uint, data domain.TransferTemplate) (domain.TransferTemplateDetail, error)
+	Pause(templateID uint) (domain.TransferTemplateDetail, error)
+	Resume(templateID uint) (domain.TransferTemplateDetail, error)
+	Upcoming(templateID uint, count int) (domain.TransferTemplateOccurrences, error)
+	InstantiateDue() (int, error)
+}
+
+// New returns an instance of the transfer template services. The bulk transfers of the templates are
+// created through the transfer services.
+func New(unitOfWork uow.UnitOfWork, templateRepo templaterepo.TemplateRepository, transferService transfersvc.TransferService, clock tools.Clock, logger log.Logger) TemplateService {
+	return service{
+		logger:          logger,
+		clock:           clock,
+		unitOfWork:      unitOfWork,
+		templateRepo:    templateRepo,
+		transferService: transferService,
+	}
+}
+
+type service struct {
+	logger          log.Logger
+	clock           tools.Clock
+	unitOfWork      uow.UnitOfWork
+	templateRepo    templaterepo.TemplateRepository
+	transferService transfersvc.TransferService
+}
+
+// Create stores an active transfer template and its credit transfers. Its first occurrence is the first
+// one on or after today.
+func (s service) Create(data domain.TransferTemplate) (domain.TransferTemplateDetail, error) {
+	startDate, err := data.StartTime()
+	if err != nil {
+		return domain.TransferTemplateDetail{}, err
+	}
+
+	now := s.clock.Now().UTC()
+	template := toTemplate(data, startDate)
+	template.Status = string(domain.TransferTemplateActive)
+	template.NextOccurrence = s.nextOccurrence(template)
+	template.CreatedAt = now
+	template.UpdatedAt = now
+
+	err = s.unitOfWork.Do(func(repos uow.Repositories) error {
+		id, err := repos.Template.Create(template)
+		if err != nil {
+			return err
+		}
+		template.ID = uint(id)
+
+		return repos.Template.ReplaceLines(template.ID, toLines(data))
+	})
+	if err != nil {
+		return domain.TransferTemplateDetail{}, domain.NotApplied(err)
+	}
+
+	return toDetail(template, data.CreditTransfers), nil
+}
+
+// Read a transfer template and its credit transfers
+func (s service) Read(templateID uint) (domain.TransferTemplateDetail, error) {
+	template, err := read(s.templateRepo, templateID)
+	if err != nil {
+		return domain.TransferTemplateDetail{}, err
+	}
+
+	lines, err := s.templateRepo.ReadLines(templateID)
+	if err != nil {
+		return domain.TransferTemplateDetail{}, err
+	}
+
+	return toDetail(template, fromLines(lines)), nil
+}
+
+// ReadByFilter list of transfer templates, without their credit transfers
+func (s service) ReadByFilter(filters map[string]string) (domain.TransferTemplateDetailList, error) {
+	templates, err := s.templateRepo.ReadByFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	res := domain.TransferTemplateDetailList{}
+	for _, template := range templates {
+		res = append(res, toDetail(template, nil))
+	}
+
+	return res, nil
+}
+
+// Update replaces the values and the credit transfers of a transfer template, keeping its status.
+// Its next occurrence is computed again, never going back to an occurrence that was already created.
+func (s service) Update(templateID uint, data domain.TransferTemplate) (domain.TransferTemplateDetail, error) {
+	startDate, err := data.StartTime()
+	if err != nil {
+		return domain.TransferTemplateDetail{}, err
+	}
+
+	var template templaterepo.Template
+	err = s.unitOfWork.Do(func(repos uow.Repositories) error {
+		stored, err := read(repos.Template, templateID)
+		if err != nil {
+			return err
+		}
+
+		template = toTemplate(data, startDate)
+		template.ID = stored.ID
+		template.Status = stored.Status
+		template.LastOccurrence = stored.LastOccurrence
+		template.NextOccurrence = s.nextOccurrence(template)
+		template.CreatedAt = stored.CreatedAt
+		template.UpdatedAt = s.clock.Now().UTC()
+
+		if err = repos.Template.Update(template); err != nil {
+			return err
+		}
+
+		return repos.Template.ReplaceLines(template.ID, toLines(data))
+	})
+	if err != nil {
+		return domain.TransferTemplateDetail{}, err
+	}
+
+	return toDetail(template, data.CreditTransfers), nil
+}
+
+// Pause stops creating the bulk transfers of an active transfer template
+func (s service) Pause(templateID uint) (domain.TransferTemplateDetail, error) {
+	return s.transition(templateID, domain.TransferTemplateActive, domain.TransferTemplatePaused, ErrTemplateNotActive)
+}
+
+// Resume creates again the bulk transfers of a paused transfer template. The occurrences missed while
+// it was paused are skipped.
+func (s service) Resume(templateID uint) (domain.TransferTemplateDetail, error) {
+	return s.transition(templateID, domain.TransferTemplatePaused, domain.TransferTemplateActive, ErrTemplateNotPaused)
+}
+
+func (s service) transition(templateID uint, from, to domain.TransferTemplateStatus, conflict error) (domain.TransferTemplateDetail, error) {
+	template, err := read(s.templateRepo, templateID)
+	if err != nil {
+		return domain.TransferTemplateDetail{}, err
+	}
+
+	template.Status = string(to)
+	template.NextOccurrence = s.nextOccurrence(template)
+	template.UpdatedAt = s.clock.Now().UTC()
+
+	err = s.templateRepo.Transition(template, string(from))
+	if errors.Is(err, templaterepo.ErrStatusConflict) {
+		return domain.TransferTemplateDetail{}, conflict
+	}
+	if err != nil {
+		return domain.TransferTemplateDetail{}, err
+	}
+
+	return s.Read(templateID)
+}
+
+// Upcoming lists the next count occurrences of the transfer template, none when it is paused
+func (s service) Upcoming(templateID uint, count int) (domain.TransferTemplateOccurrences, error) {
+	template, err := read(s.templateRepo, templateID)
+	if err != nil {
+		return domain.TransferTemplateOccurrences{}, err
+	}
+
+	res := domain.TransferTemplateOccurrences{TemplateID: template.ID, Occurrences: []string{}}
+	if template.Status != string(domain.TransferTemplateActive) {
+		return res, nil
+	}
+
+	for _, occurrence := range recurrence(template).Upcoming(template.StartDate, template.NextOccurrence, count) {
+		res.Occurrences = append(res.Occurrences, occurrence.Format(domain.ExecutionDateLayout))
+	}
+
+	return res, nil
+}
+
+// InstantiateDue creates a bulk transfer for every active transfer template with an occurrence due, returning
+// how many were created. Each occurrence is claimed before its bulk transfer is created so it is never paid
+// twice; a template late by several occurrences catches up one occurrence per run. The bulk transfers follow
+// the same path as the submitted ones, a rejected occurrence is recorded as a failed bulk transfer.
+func (s service) InstantiateDue() (int, error) {
+	due, err := s.templateRepo.ReadDue(s.clock.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	instantiated := 0
+	for _, template := range due {
+		lines, err := s.templateRepo.ReadLines(template.ID)
+		if err != nil {
+			return instantiated, err
+		}
+
+		occurrence := template.NextOccurrence
+		template.LastOccurrence = occurrence
+		template.NextOccurrence = recurrence(template).Next(template.StartDate, occurrence.AddDate(0, 0, 1))
+		template.UpdatedAt = s.clock.Now().UTC()
+		err = s.templateRepo.Advance(template, occurrence)
+		if errors.Is(err, templaterepo.ErrOccurrenceConflict) {
+			// claimed by another scheduler, paused or edited since it was read
+			continue
+		}
+		if err != nil {
+			return instantiated, err
+		}
+
+		instantiated++
+		bulkTransfer := toBulkTransfer(template, lines, occurrence)
+		if _, err = s.transferService.BulkTransfer(bulkTransfer); err != nil {
+			s.logger.WithError(err).Warn("transfer template occurrence failed",
+				zap.Uint("template_id", template.ID), zap.String("occurrence", bulkTransfer.ExecutionDate))
+		}
+	}
+
+	return instantiated, nil
+}
+
+// nextOccurrence the first occurrence of the template on or after today that comes after its last occurrence
+func (s service) nextOccurrence(template templaterepo.Template) time.Time {
+	from := s.clock.Now().UTC()
+	if next := template.LastOccurrence.AddDate(0, 0, 1); !template.LastOccurrence.IsZero() && next.After(from) {
+		from = next
+	}
+	return recurrence(template).Next(template.StartDate, from)
+}
+
+func read(repo templaterepo.TemplateRepository, templateID uint) (templaterepo.Template, error) {
+	template, err := repo.Read(templateID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return templaterepo.Template{}, ErrTemplateNotFound
+	}
+	return template, err
+}
+
+func recurrence(template templaterepo.Template) domain.Recurrence {
+	return domain.Recurrence{
+		Frequency:  template.Frequency,
+		Interval:   template.IntervalCount,
+		DayOfMonth: template.DayOfMonth,
+	}
+}
+
+// toTemplate the values of the transfer template to be stored
+func toTemplate(data domain.TransferTemplate, startDate time.Time) templaterepo.Template {
+	template := templaterepo.Template{
+		Name:             data.Name,
+		OrganizationName: data.OrganizationName,
+		OrganizationIban: data.OrganizationIban,
+		OrganizationBic:  data.OrganizationBic,
+		Frequency:        data.Recurrence.Frequency,
+		IntervalCount:    data.Recurrence.Interval,
+		DayOfMonth:       data.Recurrence.DayOfMonth,
+		TransfersCount:   len(data.CreditTransfers),
+		StartDate:        startDate,
+	}
+	for _, creditTransfer := range data.CreditTransfers {
+		if creditTransfer.Amount.Currency == domain.AccountCurrency {
+			template.TotalCents += creditTransfer.Amount.MinorUnits
+		}
+	}
+	return template
+}
+
+func toDetail(template templaterepo.Template, creditTransfers []domain.CreditTransfer) domain.TransferTemplateDetail {
+	detail := domain.TransferTemplateDetail{
+		ID:               template.ID,
+		Name:             template.Name,
+		OrganizationName: template.OrganizationName,
+		OrganizationBic:  template.OrganizationBic,
+		OrganizationIban: template.OrganizationIban,
+		TransfersCount:   template.TransfersCount,
+		TotalAmount:      domain.NewMoney(template.TotalCents, domain.AccountCurrency),
+		Recurrence:       recurrence(template),
+		StartDate:        template.StartDate.Format(domain.ExecutionDateLayout),
+		Status:           domain.TransferTemplateStatus(template.Status),
+		CreatedAt:        template.CreatedAt,
+		UpdatedAt:        template.UpdatedAt,
+		CreditTransfers:  creditTransfers,
+	}
+	if template.Status == string(domain.TransferTemplateActive) {
+		detail.NextOccurrence = template.NextOccurrence.Format(domain.ExecutionDateLayout)
+	}
+	if !template.LastOccurrence.IsZero() {
+		detail.LastOccurrence = template.LastOccurrence.Format(domain.ExecutionDateLayout)
+	}
+	return detail
+}
+
+// toLines the credit transfers of the transfer template to be stored
+func toLines(data domain.TransferTemplate) templaterepo.LineList {
+	lines := templaterepo.LineList{}
+	for i, creditTransfer := range data.CreditTransfers {
+		lines = append(lines, templaterepo.Line{
+			Index:            i,
+			CounterPartyName: creditTransfer.CounterPartyName,
+			CounterPartyIban: creditTransfer.CounterPartyIban,
+			CounterPartyBic:  creditTransfer.CounterPartyBic,
+			AmountCents:      creditTransfer.Amount.MinorUnits,
+			AmountCurrency:   creditTransfer.Currency,
+			Description:      creditTransfer.Description,
+			BeneficiaryID:    creditTransfer.BeneficiaryID,
+		})
+	}
+	return lines
+}
+
+// fromLines rebuilds the credit transfers of the transfer template from what was stored
+func fromLines(lines templaterepo.LineList) []domain.CreditTransfer {
+	creditTransfers := []domain.CreditTransfer{}
+	for _, line := range lines {
+		creditTransfers = append(creditTransfers, domain.CreditTransfer{
+			Amount:           domain.NewMoney(line.AmountCents, line.AmountCurrency),
+			Currency:         line.AmountCurrency,
+			CounterPartyName: line.CounterPartyName,
+			CounterPartyBic:  line.CounterPartyBic,
+			CounterPartyIban: line.CounterPartyIban,
+			Description:      line.Description,
+			BeneficiaryID:    line.BeneficiaryID,
+		})
+	}
+	return creditTransfers
+}
+
+// toBulkTransfer the bulk transfer of an occurrence of the transfer template, submitted by the template
+func toBulkTransfer(template templaterepo.Template, lines templaterepo.LineList, occurrence time.Time) domain.BulkTransfer {
+	return domain.BulkTransfer{
+		OrganizationName: template.OrganizationName,
+		OrganizationBic:  template.OrganizationBic,
+		OrganizationIban: template.OrganizationIban,
+		CreditTransfers:  fromLines(lines),
+		ExecutionDate:    occurrence.Format(domain.ExecutionDateLayout),
+		TemplateID:       template.ID,
+		SubmittedBy:      fmt.Sprintf("template:%d", template.ID),
+	}
+}
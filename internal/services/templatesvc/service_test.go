@@ -0,0 +1,449 @@
+package templatesvc
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/fees"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/templaterepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/screening"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/transfersvc"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/repository"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/services"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTemplateService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMockTemplate := mockrepository.NewMockTemplateRepository(ctrl)
+	transferServiceMock := mockservice.NewMockTransferService(ctrl)
+	logMock := mocklog.NewMockLogger(ctrl)
+	uowMock := mockrepository.NewMockUnitOfWork(ctrl)
+	uowMock.EXPECT().
+		Do(gomock.Any()).
+		DoAndReturn(func(fn func(repos uow.Repositories) error) error {
+			return fn(uow.Repositories{Template: repoMockTemplate})
+		}).
+		AnyTimes()
+
+	now := time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC)
+	clock := tools.ClockFunc(func() time.Time { return now })
+	svc := New(uowMock, repoMockTemplate, transferServiceMock, clock, logMock)
+
+	data := domain.TransferTemplate{
+		Name:             "Monthly payroll",
+		OrganizationName: "ACME Corp",
+		OrganizationBic:  "OIVUSCLQXXX",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		CreditTransfers: []domain.CreditTransfer{
+			{
+				Amount:           domain.NewMoney(1453, "EUR"),
+				Currency:         "EUR",
+				CounterPartyName: "Bip Bip",
+				CounterPartyBic:  "CRLYFRPPTOU",
+				CounterPartyIban: "EE303680981021245685",
+				Description:      "Salary",
+			},
+		},
+		Recurrence: domain.Recurrence{Frequency: domain.RecurrenceMonthly, DayOfMonth: 25},
+		StartDate:  "2022-08-01",
+	}
+
+	template := templaterepo.Template{
+		ID:               4,
+		Name:             "Monthly payroll",
+		OrganizationName: "ACME Corp",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		OrganizationBic:  "OIVUSCLQXXX",
+		Frequency:        domain.RecurrenceMonthly,
+		DayOfMonth:       25,
+		TransfersCount:   1,
+		TotalCents:       1453,
+		StartDate:        time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC),
+		NextOccurrence:   time.Date(2022, 8, 25, 0, 0, 0, 0, time.UTC),
+		Status:           string(domain.TransferTemplateActive),
+	}
+
+	lines := templaterepo.LineList{
+		{
+			ID:               1,
+			TemplateID:       4,
+			CounterPartyName: "Bip Bip",
+			CounterPartyIban: "EE303680981021245685",
+			CounterPartyBic:  "CRLYFRPPTOU",
+			AmountCents:      1453,
+			AmountCurrency:   "EUR",
+			Description:      "Salary",
+		},
+	}
+
+	t.Run("Test Create return success", func(t *testing.T) {
+		repoMockTemplate.EXPECT().
+			Create(gomock.Any()).
+			DoAndReturn(func(data templaterepo.Template) (int, error) {
+				assert.Equal(t, string(domain.TransferTemplateActive), data.Status)
+				assert.Equal(t, 1, data.TransfersCount)
+				assert.Equal(t, int64(1453), data.TotalCents)
+				// the occurrence of today is still due
+				assert.Equal(t, time.Date(2022, 8, 25, 0, 0, 0, 0, time.UTC), data.NextOccurrence)
+				return 4, nil
+			})
+		repoMockTemplate.EXPECT().
+			ReplaceLines(uint(4), gomock.Len(1)).
+			Return(nil)
+
+		res, err := svc.Create(data)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(4), res.ID)
+		assert.Equal(t, "2022-08-25", res.NextOccurrence)
+		assert.Equal(t, domain.NewMoney(1453, "EUR"), res.TotalAmount)
+		assert.Len(t, res.CreditTransfers, 1)
+	})
+
+	t.Run("Test Create return error when the lines can't be stored", func(t *testing.T) {
+		repoMockTemplate.EXPECT().Create(gomock.Any()).Return(4, nil)
+		repoMockTemplate.EXPECT().ReplaceLines(uint(4), gomock.Any()).Return(errors.New("error"))
+
+		_, err := svc.Create(data)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Read return the template and its lines", func(t *testing.T) {
+		repoMockTemplate.EXPECT().Read(uint(4)).Return(template, nil)
+		repoMockTemplate.EXPECT().ReadLines(uint(4)).Return(lines, nil)
+
+		res, err := svc.Read(4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Monthly payroll", res.Name)
+		assert.Equal(t, data.Recurrence, res.Recurrence)
+		assert.Equal(t, "2022-08-01", res.StartDate)
+		assert.Equal(t, data.CreditTransfers, res.CreditTransfers)
+	})
+
+	t.Run("Test Read return not found", func(t *testing.T) {
+		repoMockTemplate.EXPECT().Read(uint(5)).Return(templaterepo.Template{}, sql.ErrNoRows)
+
+		_, err := svc.Read(5)
+
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+	})
+
+	t.Run("Test ReadByFilter return the templates without their lines", func(t *testing.T) {
+		repoMockTemplate.EXPECT().ReadByFilter(map[string]string{"status": "active"}).Return(templaterepo.TemplateList{template}, nil)
+
+		res, err := svc.ReadByFilter(map[string]string{"status": "active"})
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+		assert.Nil(t, res[0].CreditTransfers)
+	})
+
+	t.Run("Test Update never goes back to an occurrence already created", func(t *testing.T) {
+		stored := template
+		stored.LastOccurrence = time.Date(2022, 8, 25, 0, 0, 0, 0, time.UTC)
+		stored.NextOccurrence = time.Date(2022, 9, 25, 0, 0, 0, 0, time.UTC)
+
+		edited := data
+		edited.Recurrence.DayOfMonth = 26
+
+		repoMockTemplate.EXPECT().Read(uint(4)).Return(stored, nil)
+		repoMockTemplate.EXPECT().
+			Update(gomock.Any()).
+			DoAndReturn(func(data templaterepo.Template) error {
+				assert.Equal(t, uint(4), data.ID)
+				assert.Equal(t, 26, data.DayOfMonth)
+				assert.Equal(t, time.Date(2022, 8, 26, 0, 0, 0, 0, time.UTC), data.NextOccurrence)
+				return nil
+			})
+		repoMockTemplate.EXPECT().ReplaceLines(uint(4), gomock.Len(1)).Return(nil)
+
+		res, err := svc.Update(4, edited)
+		assert.NoError(t, err)
+		assert.Equal(t, "2022-08-26", res.NextOccurrence)
+
+		edited.Recurrence.DayOfMonth = 24
+		repoMockTemplate.EXPECT().Read(uint(4)).Return(stored, nil)
+		repoMockTemplate.EXPECT().
+			Update(gomock.Any()).
+			DoAndReturn(func(data templaterepo.Template) error {
+				assert.Equal(t, time.Date(2022, 9, 24, 0, 0, 0, 0, time.UTC), data.NextOccurrence)
+				return nil
+			})
+		repoMockTemplate.EXPECT().ReplaceLines(uint(4), gomock.Len(1)).Return(nil)
+
+		_, err = svc.Update(4, edited)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Update return not found", func(t *testing.T) {
+		repoMockTemplate.EXPECT().Read(uint(5)).Return(templaterepo.Template{}, sql.ErrNoRows)
+
+		_, err := svc.Update(5, data)
+
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+	})
+
+	t.Run("Test Pause return success", func(t *testing.T) {
+		paused := template
+		paused.Status = string(domain.TransferTemplatePaused)
+
+		repoMockTemplate.EXPECT().Read(uint(4)).Return(template, nil)
+		repoMockTemplate.EXPECT().
+			Transition(gomock.Any(), string(domain.TransferTemplateActive)).
+			DoAndReturn(func(data templaterepo.Template, from string) error {
+				assert.Equal(t, string(domain.TransferTemplatePaused), data.Status)
+				return nil
+			})
+		repoMockTemplate.EXPECT().Read(uint(4)).Return(paused, nil)
+		repoMockTemplate.EXPECT().ReadLines(uint(4)).Return(lines, nil)
+
+		res, err := svc.Pause(4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.TransferTemplatePaused, res.Status)
+		assert.Empty(t, res.NextOccurrence)
+	})
+
+	t.Run("Test Pause return conflict when the template isn't active", func(t *testing.T) {
+		repoMockTemplate.EXPECT().Read(uint(4)).Return(template, nil)
+		repoMockTemplate.EXPECT().Transition(gomock.Any(), gomock.Any()).Return(templaterepo.ErrStatusConflict)
+
+		_, err := svc.Pause(4)
+
+		assert.ErrorIs(t, err, ErrTemplateNotActive)
+	})
+
+	t.Run("Test Resume skips the occurrences missed while paused", func(t *testing.T) {
+		paused := template
+		paused.Status = string(domain.TransferTemplatePaused)
+		paused.NextOccurrence = time.Date(2022, 6, 25, 0, 0, 0, 0, time.UTC)
+		paused.LastOccurrence = time.Date(2022, 5, 25, 0, 0, 0, 0, time.UTC)
+
+		now = time.Date(2022, 8, 26, 10, 0, 0, 0, time.UTC)
+		defer func() { now = time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC) }()
+
+		repoMockTemplate.EXPECT().Read(uint(4)).Return(paused, nil)
+		repoMockTemplate.EXPECT().
+			Transition(gomock.Any(), string(domain.TransferTemplatePaused)).
+			DoAndReturn(func(data templaterepo.Template, from string) error {
+				assert.Equal(t, string(domain.TransferTemplateActive), data.Status)
+				assert.Equal(t, time.Date(2022, 9, 25, 0, 0, 0, 0, time.UTC), data.NextOccurrence)
+				return nil
+			})
+		repoMockTemplate.EXPECT().Read(uint(4)).Return(template, nil)
+		repoMockTemplate.EXPECT().ReadLines(uint(4)).Return(lines, nil)
+
+		_, err := svc.Resume(4)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Resume return conflict when the template isn't paused", func(t *testing.T) {
+		repoMockTemplate.EXPECT().Read(uint(4)).Return(template, nil)
+		repoMockTemplate.EXPECT().Transition(gomock.Any(), gomock.Any()).Return(templaterepo.ErrStatusConflict)
+
+		_, err := svc.Resume(4)
+
+		assert.ErrorIs(t, err, ErrTemplateNotPaused)
+	})
+
+	t.Run("Test Upcoming return the next occurrences", func(t *testing.T) {
+		repoMockTemplate.EXPECT().Read(uint(4)).Return(template, nil)
+
+		res, err := svc.Upcoming(4, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.TransferTemplateOccurrences{TemplateID: 4, Occurrences: []string{"2022-08-25", "2022-09-25", "2022-10-25"}}, res)
+	})
+
+	t.Run("Test Upcoming return no occurrence when the template is paused", func(t *testing.T) {
+		paused := template
+		paused.Status = string(domain.TransferTemplatePaused)
+		repoMockTemplate.EXPECT().Read(uint(4)).Return(paused, nil)
+
+		res, err := svc.Upcoming(4, 3)
+
+		assert.NoError(t, err)
+		assert.Empty(t, res.Occurrences)
+	})
+
+	t.Run("Test InstantiateDue creates the bulk transfer of the occurrence", func(t *testing.T) {
+		repoMockTemplate.EXPECT().ReadDue(now).Return(templaterepo.TemplateList{template}, nil)
+		repoMockTemplate.EXPECT().ReadLines(uint(4)).Return(lines, nil)
+		repoMockTemplate.EXPECT().
+			Advance(gomock.Any(), template.NextOccurrence).
+			DoAndReturn(func(data templaterepo.Template, from time.Time) error {
+				assert.Equal(t, time.Date(2022, 9, 25, 0, 0, 0, 0, time.UTC), data.NextOccurrence)
+				assert.Equal(t, template.NextOccurrence, data.LastOccurrence)
+				return nil
+			})
+		transferServiceMock.EXPECT().
+			BulkTransfer(domain.BulkTransfer{
+				OrganizationName: "ACME Corp",
+				OrganizationBic:  "OIVUSCLQXXX",
+				OrganizationIban: "FR81474608000002006107XXXXX",
+				CreditTransfers:  data.CreditTransfers,
+				ExecutionDate:    "2022-08-25",
+				TemplateID:       4,
+				SubmittedBy:      "template:4",
+			}).
+			Return(domain.BulkTransferDetail{ID: 3, Status: domain.BulkTransferCompleted}, nil)
+
+		instantiated, err := svc.InstantiateDue()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, instantiated)
+	})
+
+	t.Run("Test InstantiateDue skips the occurrences already claimed", func(t *testing.T) {
+		repoMockTemplate.EXPECT().ReadDue(now).Return(templaterepo.TemplateList{template}, nil)
+		repoMockTemplate.EXPECT().ReadLines(uint(4)).Return(lines, nil)
+		repoMockTemplate.EXPECT().Advance(gomock.Any(), gomock.Any()).Return(templaterepo.ErrOccurrenceConflict)
+
+		instantiated, err := svc.InstantiateDue()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, instantiated)
+	})
+
+	t.Run("Test InstantiateDue logs the rejected occurrences", func(t *testing.T) {
+		rejection := domain.NewRejection(domain.RejectionInsufficientFunds, transfersvc.ErrInsufficientFunds)
+		repoMockTemplate.EXPECT().ReadDue(now).Return(templaterepo.TemplateList{template}, nil)
+		repoMockTemplate.EXPECT().ReadLines(uint(4)).Return(lines, nil)
+		repoMockTemplate.EXPECT().Advance(gomock.Any(), gomock.Any()).Return(nil)
+		transferServiceMock.EXPECT().BulkTransfer(gomock.Any()).Return(domain.BulkTransferDetail{ID: 3, Status: domain.BulkTransferFailed}, rejection)
+		logMock.EXPECT().WithError(rejection).Return(logMock)
+		logMock.EXPECT().Warn("transfer template occurrence failed", gomock.Any())
+
+		instantiated, err := svc.InstantiateDue()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, instantiated)
+	})
+
+	t.Run("Test InstantiateDue return error", func(t *testing.T) {
+		repoMockTemplate.EXPECT().ReadDue(now).Return(nil, errors.New("error"))
+
+		_, err := svc.InstantiateDue()
+
+		assert.Error(t, err)
+	})
+}
+
+func TestTemplateServiceRecurringPayroll(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	_, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		BalanceCents:     10000,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2022, 8, 26, 18, 0, 0, 0, time.UTC)
+	clock := tools.ClockFunc(func() time.Time { return now })
+	transferService := transfersvc.New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+	svc := New(uow.New(conn), templaterepo.New(conn), transferService, clock, logMock)
+
+	template, err := svc.Create(domain.TransferTemplate{
+		Name:             "Monthly payroll",
+		OrganizationName: "ACME Corp",
+		OrganizationBic:  "OIVUSCLQXXX",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		CreditTransfers: []domain.CreditTransfer{
+			{
+				Amount:           domain.NewMoney(1500, "EUR"),
+				Currency:         "EUR",
+				CounterPartyName: "Bip Bip",
+				CounterPartyBic:  "CRLYFRPPTOU",
+				CounterPartyIban: "EE303680981021245685",
+				Description:      "Salary",
+			},
+		},
+		Recurrence: domain.Recurrence{Frequency: domain.RecurrenceMonthly, DayOfMonth: 25},
+		StartDate:  "2022-08-01",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2022-09-25", template.NextOccurrence)
+
+	// not due yet
+	instantiated, err := svc.InstantiateDue()
+	require.NoError(t, err)
+	assert.Equal(t, 0, instantiated)
+
+	now = time.Date(2022, 9, 25, 6, 0, 0, 0, time.UTC)
+	instantiated, err = svc.InstantiateDue()
+	require.NoError(t, err)
+	assert.Equal(t, 1, instantiated)
+
+	// already instantiated
+	instantiated, err = svc.InstantiateDue()
+	require.NoError(t, err)
+	assert.Equal(t, 0, instantiated)
+
+	bulkTransfers, err := transferService.ReadByFilter(map[string]string{"template_id": "1"})
+	require.NoError(t, err)
+	require.Len(t, bulkTransfers, 1)
+	assert.Equal(t, domain.BulkTransferCompleted, bulkTransfers[0].Status)
+	assert.Equal(t, "2022-09-25", bulkTransfers[0].ExecutionDate)
+	assert.Equal(t, template.ID, bulkTransfers[0].TemplateID)
+
+	read, err := svc.Read(template.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "2022-09-25", read.LastOccurrence)
+	assert.Equal(t, "2022-10-25", read.NextOccurrence)
+
+	// paused templates are skipped
+	_, err = svc.Pause(template.ID)
+	require.NoError(t, err)
+	now = time.Date(2022, 10, 25, 6, 0, 0, 0, time.UTC)
+	instantiated, err = svc.InstantiateDue()
+	require.NoError(t, err)
+	assert.Equal(t, 0, instantiated)
+
+	balance, err := bankaccountrepo.New(conn).ReadByIban("FR81474608000002006107XXXXX")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8500), balance.BalanceCents)
+}
+
+// openBankAccount registers the bank account with the posting of its opening balance, as the bank account service does
+func openBankAccount(conn config.Conn, bankAccount bankaccountrepo.BankAccount) (int, error) {
+	id, err := bankaccountrepo.New(conn).Create(bankAccount)
+	if err != nil || bankAccount.BalanceCents == 0 {
+		return id, err
+	}
+	account := ledgerrepo.BankAccount(uint(id))
+	_, err = ledgerrepo.New(conn).Post(ledgerrepo.Journal{Operation: string(domain.LedgerOpeningBalance), Reference: account, CreatedAt: time.Now().UTC()},
+		ledgerrepo.AgainstEquity(account, bankAccount.BalanceCents))
+	return id, err
+}
@@ -1,26 +1,48 @@
 package transactionsvc
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
 	"github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/webhooksvc"
 	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+)
+
+var (
+	// ErrTransactionNotFound is returned when the transaction doesn't exist
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrNotReversible is returned when the transaction isn't an outgoing one, e.g. it is itself a reversal
+	ErrNotReversible = errors.New("only outgoing transactions can be reversed")
+	// ErrInsufficientFunds is returned when the local counterparty can't give the amount back
+	ErrInsufficientFunds = errors.New("Insufficient credits on the counterparty account to reverse the transaction")
 )
 
 // TransactionService Interface for the transaction services
 type TransactionService interface {
 	ReadByFilter(filters map[string]string) (domain.TransactionList, error)
+	Reverse(transactionID uint, data domain.TransactionReversal) (domain.Transaction, error)
 }
 
 // New returns an instance of the transaction services
-func New(transactionrepo transactionrepo.TransactionRepository, logger log.Logger) TransactionService {
+func New(unitOfWork uow.UnitOfWork, transactionrepo transactionrepo.TransactionRepository, clock tools.Clock, logger log.Logger) TransactionService {
 	return service{
 		logger:          logger,
+		clock:           clock,
+		unitOfWork:      unitOfWork,
 		transactionrepo: transactionrepo,
 	}
 }
 
 type service struct {
 	logger          log.Logger
+	clock           tools.Clock
+	unitOfWork      uow.UnitOfWork
 	transactionrepo transactionrepo.TransactionRepository
 }
 
@@ -28,3 +50,172 @@ type service struct {
 func (s service) ReadByFilter(filters map[string]string) (domain.TransactionList, error) {
 	return s.transactionrepo.ReadByFilter(filters)
 }
+
+// Reverse gives back the amount, or what wasn't reversed yet when it is missing, of an outgoing transaction.
+// The bank account is credited and a compensating incoming transaction, linked to the reversed one, is
+// registered atomically. A local counterparty is debited back, with an outgoing transaction on its side, and
+// a refunded fee is taken back from the fees account. The movements are posted on the ledger as a single journal.
+func (s service) Reverse(transactionID uint, data domain.TransactionReversal) (domain.Transaction, error) {
+	var reversal domain.Transaction
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		original, err := repos.Transaction.Read(transactionID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTransactionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if original.Direction != string(domain.TransactionOutgoing) || original.ReversedTransactionID != 0 {
+			return ErrNotReversible
+		}
+
+		remaining := original.AmountCents - original.ReversedCents
+		if remaining <= 0 {
+			return fmt.Errorf("%w: nothing is left to reverse", ErrNotReversible)
+		}
+		amountCents := data.Amount.MinorUnits
+		if amountCents == 0 {
+			amountCents = remaining
+		}
+		if amountCents > remaining {
+			return exceeded(remaining, original.AmountCurrency)
+		}
+
+		err = repos.Transaction.Reverse(original.ID, amountCents)
+		if errors.Is(err, transactionrepo.ErrReversalExceeded) {
+			// reversed by someone else since it was read
+			return exceeded(remaining, original.AmountCurrency)
+		}
+		if err != nil {
+			return err
+		}
+
+		bankAccount, err := repos.BankAccount.Read(original.BankAccountID)
+		if err != nil {
+			return err
+		}
+		if err = repos.BankAccount.Credit(bankAccount.ID, amountCents); err != nil {
+			return err
+		}
+
+		now := s.clock.Now().UTC()
+		compensation := transactionrepo.Transaction{
+			CounterPartyName:      original.CounterPartyName,
+			CounterPartyIban:      original.CounterPartyIban,
+			CounterPartyBic:       original.CounterPartyBic,
+			AmountCents:           amountCents,
+			AmountCurrency:        original.AmountCurrency,
+			BankAccountID:         bankAccount.ID,
+			Description:           data.Reason,
+			Direction:             string(domain.TransactionIncoming),
+			ReversedTransactionID: original.ID,
+			CreatedAt:             now,
+		}
+		id, err := repos.Transaction.Create(compensation)
+		if err != nil {
+			return err
+		}
+		compensation.ID = uint(id)
+		if err = webhooksvc.PublishTransaction(repos.Webhook, bankAccount, compensation); err != nil {
+			return err
+		}
+
+		debit := ledgerrepo.Debit(ledgerrepo.AccountFees, amountCents, compensation.ID)
+		if original.FeeOfTransactionID == 0 {
+			if debit, err = debitCounterparty(repos, bankAccount, compensation); err != nil {
+				return err
+			}
+		}
+
+		_, err = repos.Ledger.Post(ledgerrepo.Journal{
+			Operation:   string(domain.LedgerReversal),
+			Reference:   fmt.Sprintf("transaction:%d", original.ID),
+			Description: data.Reason,
+			CreatedAt:   now,
+		}, ledgerrepo.EntryList{debit, ledgerrepo.Credit(ledgerrepo.BankAccount(bankAccount.ID), amountCents, compensation.ID)})
+		if err != nil {
+			return err
+		}
+
+		reversal = toDomain(bankAccount, compensation)
+		return nil
+	})
+	if err != nil {
+		// the unit of work was rolled back
+		return domain.Transaction{}, domain.NotApplied(err)
+	}
+
+	return reversal, nil
+}
+
+// debitCounterparty takes the reversed amount back from the counterparty when it holds a local bank account, within
+// its overdraft, returning the ledger entry of the debit. The funds given back by other banks are debited from the external account.
+func debitCounterparty(repos uow.Repositories, bankAccount bankaccountrepo.BankAccount, compensation transactionrepo.Transaction) (ledgerrepo.Entry, error) {
+	counterparty, err := repos.BankAccount.ReadByIban(compensation.CounterPartyIban)
+	if errors.Is(err, sql.ErrNoRows) {
+		// held by another bank
+		return ledgerrepo.Debit(ledgerrepo.AccountExternal, compensation.AmountCents, compensation.ID), nil
+	}
+	if err != nil {
+		return ledgerrepo.Entry{}, err
+	}
+
+	balanceCents, err := repos.BankAccount.Debit(counterparty.ID, compensation.AmountCents)
+	if errors.Is(err, bankaccountrepo.ErrInsufficientFunds) {
+		available := domain.NewMoney(counterparty.BalanceCents+counterparty.OverdraftCents, domain.AccountCurrency)
+		return ledgerrepo.Entry{}, domain.NewInsufficientFundsRejection(ErrInsufficientFunds, domain.NewMoney(compensation.AmountCents, compensation.AmountCurrency), &available)
+	}
+	if err != nil {
+		return ledgerrepo.Entry{}, err
+	}
+
+	debit := transactionrepo.Transaction{
+		CounterPartyName:      bankAccount.OrganizationName,
+		CounterPartyIban:      bankAccount.Iban,
+		CounterPartyBic:       bankAccount.Bic,
+		AmountCents:           compensation.AmountCents,
+		AmountCurrency:        compensation.AmountCurrency,
+		BankAccountID:         counterparty.ID,
+		Description:           compensation.Description,
+		Direction:             string(domain.TransactionOutgoing),
+		ReversedTransactionID: compensation.ReversedTransactionID,
+		OverdrawnCents:        domain.OverdrawnCents(balanceCents, compensation.AmountCents),
+		CreatedAt:             compensation.CreatedAt,
+	}
+	id, err := repos.Transaction.Create(debit)
+	if err != nil {
+		return ledgerrepo.Entry{}, err
+	}
+	debit.ID = uint(id)
+	if err = webhooksvc.PublishTransaction(repos.Webhook, counterparty, debit); err != nil {
+		return ledgerrepo.Entry{}, err
+	}
+
+	return ledgerrepo.Debit(ledgerrepo.BankAccount(counterparty.ID), compensation.AmountCents, debit.ID), nil
+}
+
+// exceeded rejects a reversal of more than the remaining amount of the transaction
+func exceeded(remainingCents int64, currency string) error {
+	remaining := domain.NewMoney(remainingCents, currency)
+	return domain.NewRejection(domain.RejectionInvalidFields, transactionrepo.ErrReversalExceeded).
+		WithFieldError("amount", "lte", fmt.Sprintf("must be at most %s", remaining))
+}
+
+func toDomain(bankAccount bankaccountrepo.BankAccount, transaction transactionrepo.Transaction) domain.Transaction {
+	createdAt := transaction.CreatedAt
+	return domain.Transaction{
+		ID:                    transaction.ID,
+		Name:                  bankAccount.OrganizationName,
+		Iban:                  bankAccount.Iban,
+		Bic:                   bankAccount.Bic,
+		CounterPartyName:      transaction.CounterPartyName,
+		CounterPartyIban:      transaction.CounterPartyIban,
+		CounterPartyBic:       transaction.CounterPartyBic,
+		Amount:                domain.NewMoney(transaction.AmountCents, transaction.AmountCurrency),
+		Currency:              transaction.AmountCurrency,
+		Description:           transaction.Description,
+		Direction:             domain.TransactionDirection(transaction.Direction),
+		ReversedTransactionID: transaction.ReversedTransactionID,
+		CreatedAt:             &createdAt,
+	}
+}
@@ -1,13 +1,24 @@
 package transactionsvc
 
 import (
+	"database/sql"
 	"errors"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
 	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
 	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
 	"github.com/adrianoccosta/exercise-qonto/test/mocks/repository"
+	"github.com/adrianoccosta/exercise-qonto/tools"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestTransactionService(t *testing.T) {
@@ -15,17 +26,31 @@ func TestTransactionService(t *testing.T) {
 	defer ctrl.Finish()
 
 	repoMock := mockrepository.NewMockTransactionRepository(ctrl)
+	repoMockBankAccount := mockrepository.NewMockBankAccountRepository(ctrl)
+	repoMockLedger := mockrepository.NewMockLedgerRepository(ctrl)
+	repoMockWebhook := mockrepository.NewMockWebhookRepository(ctrl)
 	logMock := mocklog.NewMockLogger(ctrl)
+	uowMock := mockrepository.NewMockUnitOfWork(ctrl)
+	uowMock.EXPECT().
+		Do(gomock.Any()).
+		DoAndReturn(func(fn func(repos uow.Repositories) error) error {
+			return fn(uow.Repositories{BankAccount: repoMockBankAccount, Transaction: repoMock, Ledger: repoMockLedger, Webhook: repoMockWebhook})
+		}).
+		AnyTimes()
+	repoMockWebhook.EXPECT().CreateDeliveries(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	now := time.Date(2022, 8, 26, 10, 0, 0, 0, time.UTC)
+	clock := tools.ClockFunc(func() time.Time { return now })
 
 	transactionList := domain.TransactionList{
 		{
 			Name:             "ACME Corp",
-			Iban:             "FR10474608000002006107XXXXX",
+			Iban:             "FR81474608000002006107XXXXX",
 			Bic:              "OIVUSCLQXXX",
 			CounterPartyName: "Bip Bip",
-			CounterPartyIban: "EE383680981021245685",
+			CounterPartyIban: "EE303680981021245685",
 			CounterPartyBic:  "CRLYFRPPTOU",
-			Amount:           14.50,
+			Amount:           domain.NewMoney(1450, "EUR"),
 			Currency:         "EUR",
 			Description:      "Wonderland/4410",
 		},
@@ -36,7 +61,7 @@ func TestTransactionService(t *testing.T) {
 			ReadByFilter(gomock.Any()).
 			Return(transactionList, nil)
 
-		svc := New(repoMock, logMock)
+		svc := New(uowMock, repoMock, clock, logMock)
 		res, err := svc.ReadByFilter(make(map[string]string))
 
 		assert.Nil(t, err)
@@ -48,9 +73,268 @@ func TestTransactionService(t *testing.T) {
 			ReadByFilter(gomock.Any()).
 			Return(domain.TransactionList{}, errors.New("error"))
 
-		svc := New(repoMock, logMock)
+		svc := New(uowMock, repoMock, clock, logMock)
 		_, err := svc.ReadByFilter(make(map[string]string))
 
 		assert.Error(t, err)
 	})
+
+	bankAccount := bankaccountrepo.BankAccount{ID: 1, OrganizationName: "ACME Corp", BalanceCents: 100, Iban: "FR81474608000002006107XXXXX", Bic: "OIVUSCLQXXX"}
+	outgoing := transactionrepo.Transaction{
+		ID:               4,
+		CounterPartyName: "Bip Bip",
+		CounterPartyIban: "EE303680981021245685",
+		CounterPartyBic:  "CRLYFRPPTOU",
+		AmountCents:      1450,
+		AmountCurrency:   "EUR",
+		BankAccountID:    1,
+		Direction:        "outgoing",
+		ReversedCents:    450,
+	}
+
+	t.Run("Test Reverse gives back what wasn't reversed yet", func(t *testing.T) {
+		repoMock.EXPECT().Read(uint(4)).Return(outgoing, nil)
+		repoMock.EXPECT().Reverse(uint(4), int64(1000)).Return(nil)
+		repoMockBankAccount.EXPECT().Read(uint(1)).Return(bankAccount, nil)
+		repoMockBankAccount.EXPECT().Credit(uint(1), int64(1000)).Return(nil)
+		repoMock.EXPECT().
+			Create(gomock.Any()).
+			DoAndReturn(func(data transactionrepo.Transaction) (int, error) {
+				assert.Equal(t, "incoming", data.Direction)
+				assert.Equal(t, uint(4), data.ReversedTransactionID)
+				assert.Equal(t, "paid twice", data.Description)
+				assert.Equal(t, now, data.CreatedAt)
+				return 8, nil
+			})
+		repoMockBankAccount.EXPECT().ReadByIban("EE303680981021245685").Return(bankaccountrepo.BankAccount{}, sql.ErrNoRows)
+		repoMockLedger.EXPECT().
+			Post(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(journal ledgerrepo.Journal, entries ledgerrepo.EntryList) (int, error) {
+				assert.Equal(t, "reversal", journal.Operation)
+				assert.Equal(t, "transaction:4", journal.Reference)
+				assert.Equal(t, ledgerrepo.EntryList{ledgerrepo.Debit("external", 1000, 8), ledgerrepo.Credit("bank_account:1", 1000, 8)}, entries)
+				return 1, nil
+			})
+
+		svc := New(uowMock, repoMock, clock, logMock)
+		res, err := svc.Reverse(4, domain.TransactionReversal{Reason: "paid twice"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(8), res.ID)
+		assert.Equal(t, uint(4), res.ReversedTransactionID)
+		assert.Equal(t, domain.NewMoney(1000, "EUR"), res.Amount)
+		assert.Equal(t, domain.TransactionIncoming, res.Direction)
+		assert.Equal(t, "FR81474608000002006107XXXXX", res.Iban)
+	})
+
+	t.Run("Test Reverse refunds a fee from the fees account", func(t *testing.T) {
+		fee := transactionrepo.Transaction{ID: 5, CounterPartyName: "fees", AmountCents: 50, AmountCurrency: "EUR", BankAccountID: 1, Description: "sepa", Direction: "outgoing", FeeOfTransactionID: 4}
+		repoMock.EXPECT().Read(uint(5)).Return(fee, nil)
+		repoMock.EXPECT().Reverse(uint(5), int64(50)).Return(nil)
+		repoMockBankAccount.EXPECT().Read(uint(1)).Return(bankAccount, nil)
+		repoMockBankAccount.EXPECT().Credit(uint(1), int64(50)).Return(nil)
+		repoMock.EXPECT().Create(gomock.Any()).Return(9, nil)
+		repoMockBankAccount.EXPECT().ReadByIban(gomock.Any()).Times(0)
+		repoMockLedger.EXPECT().
+			Post(gomock.Any(), ledgerrepo.EntryList{ledgerrepo.Debit("fees", 50, 9), ledgerrepo.Credit("bank_account:1", 50, 9)}).
+			Return(1, nil)
+
+		svc := New(uowMock, repoMock, clock, logMock)
+		res, err := svc.Reverse(5, domain.TransactionReversal{Reason: "fee waived"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.NewMoney(50, "EUR"), res.Amount)
+	})
+
+	t.Run("Test Reverse rejects more than the amount not reversed yet", func(t *testing.T) {
+		repoMock.EXPECT().Read(uint(4)).Return(outgoing, nil)
+		repoMock.EXPECT().Reverse(gomock.Any(), gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMock, clock, logMock)
+		_, err := svc.Reverse(4, domain.TransactionReversal{Amount: domain.NewMoney(1001, "EUR"), Reason: "paid twice"})
+
+		rejection := domain.AsRejection(err)
+		assert.Equal(t, domain.RejectionInvalidFields, rejection.Reason)
+		assert.Equal(t, []domain.LineError{{Field: "amount", Rule: "lte", Message: "must be at most 10.00"}}, rejection.Errors)
+	})
+
+	t.Run("Test Reverse rejects concurrent reversals exceeding the amount", func(t *testing.T) {
+		repoMock.EXPECT().Read(uint(4)).Return(outgoing, nil)
+		repoMock.EXPECT().Reverse(uint(4), int64(500)).Return(transactionrepo.ErrReversalExceeded)
+
+		svc := New(uowMock, repoMock, clock, logMock)
+		_, err := svc.Reverse(4, domain.TransactionReversal{Amount: domain.NewMoney(500, "EUR"), Reason: "paid twice"})
+
+		assert.ErrorIs(t, err, transactionrepo.ErrReversalExceeded)
+	})
+
+	t.Run("Test Reverse return error when the transaction isn't outgoing", func(t *testing.T) {
+		reversal := outgoing
+		reversal.Direction = "incoming"
+		reversal.ReversedTransactionID = 3
+		repoMock.EXPECT().Read(uint(4)).Return(reversal, nil)
+
+		svc := New(uowMock, repoMock, clock, logMock)
+		_, err := svc.Reverse(4, domain.TransactionReversal{Reason: "paid twice"})
+
+		assert.ErrorIs(t, err, ErrNotReversible)
+	})
+
+	t.Run("Test Reverse return error when nothing is left to reverse", func(t *testing.T) {
+		for _, transaction := range []transactionrepo.Transaction{
+			{ID: 4, AmountCents: 1000, ReversedCents: 1000, Direction: "outgoing"},
+			{ID: 4, AmountCents: -1000, Direction: "outgoing"},
+		} {
+			repoMock.EXPECT().Read(uint(4)).Return(transaction, nil)
+			repoMock.EXPECT().Reverse(gomock.Any(), gomock.Any()).Times(0)
+
+			svc := New(uowMock, repoMock, clock, logMock)
+			_, err := svc.Reverse(4, domain.TransactionReversal{Reason: "paid twice"})
+
+			assert.ErrorIs(t, err, ErrNotReversible)
+		}
+	})
+
+	t.Run("Test Reverse return error when not found", func(t *testing.T) {
+		repoMock.EXPECT().Read(uint(9)).Return(transactionrepo.Transaction{}, sql.ErrNoRows)
+
+		svc := New(uowMock, repoMock, clock, logMock)
+		_, err := svc.Reverse(9, domain.TransactionReversal{Reason: "paid twice"})
+
+		assert.ErrorIs(t, err, ErrTransactionNotFound)
+	})
+}
+
+func TestTransactionServiceReversal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	transactionRepository := transactionrepo.New(conn)
+	svc := New(uow.New(conn), transactionRepository, tools.SystemClock{}, logMock)
+
+	// payer and payee have both already been debited and credited for 15.00
+	create := func(bankAccount bankaccountrepo.BankAccount) uint {
+		id, err := openBankAccount(conn, bankAccount)
+		require.NoError(t, err)
+		return uint(id)
+	}
+	payerID := create(bankaccountrepo.BankAccount{OrganizationName: "ACME Corp", BalanceCents: 8500, Iban: "FR81474608000002006107XXXXX", Bic: "OIVUSCLQXXX"})
+	payeeID := create(bankaccountrepo.BankAccount{OrganizationName: "Bip Bip", BalanceCents: 1500, Iban: "EE303680981021245685", Bic: "CRLYFRPPTOU"})
+	id, err := transactionRepository.Create(transactionrepo.Transaction{
+		CounterPartyName: "Bip Bip",
+		CounterPartyIban: "EE303680981021245685",
+		CounterPartyBic:  "CRLYFRPPTOU",
+		AmountCents:      1500,
+		AmountCurrency:   "EUR",
+		BankAccountID:    payerID,
+		Description:      "Salary September",
+		Direction:        "outgoing",
+		CreatedAt:        time.Now().UTC(),
+	})
+	require.NoError(t, err)
+	transactionID := uint(id)
+
+	balance := func(bankAccountID uint) int64 {
+		bankAccount, err := bankAccountRepository.Read(bankAccountID)
+		require.NoError(t, err)
+		return bankAccount.BalanceCents
+	}
+
+	reversal, err := svc.Reverse(transactionID, domain.TransactionReversal{Amount: domain.NewMoney(500, "EUR"), Reason: "paid twice"})
+	require.NoError(t, err)
+	assert.Equal(t, transactionID, reversal.ReversedTransactionID)
+	assert.Equal(t, int64(9000), balance(payerID))
+	assert.Equal(t, int64(1000), balance(payeeID))
+
+	_, err = svc.Reverse(transactionID, domain.TransactionReversal{Amount: domain.NewMoney(1001, "EUR"), Reason: "paid twice"})
+	assert.ErrorIs(t, err, transactionrepo.ErrReversalExceeded)
+
+	_, err = svc.Reverse(transactionID, domain.TransactionReversal{Reason: "paid twice"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10000), balance(payerID))
+	assert.Equal(t, int64(0), balance(payeeID))
+
+	_, err = svc.Reverse(transactionID, domain.TransactionReversal{Reason: "paid twice"})
+	assert.ErrorIs(t, err, ErrNotReversible)
+
+	_, err = svc.Reverse(reversal.ID, domain.TransactionReversal{Reason: "paid twice"})
+	assert.ErrorIs(t, err, ErrNotReversible)
+
+	transactions, err := svc.ReadByFilter(map[string]string{"iban": "FR81474608000002006107XXXXX"})
+	require.NoError(t, err)
+	require.Len(t, transactions, 3)
+	assert.Equal(t, domain.NewMoney(1500, "EUR"), *transactions[0].ReversedAmount)
+	assert.Equal(t, transactionID, transactions[1].ReversedTransactionID)
+	assert.Equal(t, transactionID, transactions[2].ReversedTransactionID)
+
+	payeeTransactions, err := svc.ReadByFilter(map[string]string{"iban": "EE303680981021245685", "direction": "outgoing"})
+	require.NoError(t, err)
+	assert.Len(t, payeeTransactions, 2)
+
+	// both reversals moved the funds from the payee back to the payer on the ledger, on top of its opening balance
+	ledgerBalance, err := ledgerrepo.New(conn).Balance(ledgerrepo.BankAccount(payerID))
+	require.NoError(t, err)
+	assert.Equal(t, balance(payerID), ledgerBalance)
+}
+
+func TestTransactionServiceLegacyTransactions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	// the transactions recorded before the series, outgoing payments being negative amounts
+	path := filepath.Join(t.TempDir(), "qonto.sqlite")
+	fixture, err := os.ReadFile(filepath.Join("..", "..", "..", "test", "qonto_accounts.sqlite"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, fixture, 0600))
+
+	conn := config.InitDBConnection(config.DBConnection{Path: path, MaxIdleConns: 1, MaxOpenConns: 1}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	svc := New(uow.New(conn), transactionrepo.New(conn), tools.SystemClock{}, logMock)
+	balance := func() int64 {
+		bankAccount, err := bankaccountrepo.New(conn).Read(1)
+		require.NoError(t, err)
+		return bankAccount.BalanceCents
+	}
+
+	t.Run("Test Reverse return error on the income received", func(t *testing.T) {
+		_, err := svc.Reverse(1, domain.TransactionReversal{Reason: "paid twice"})
+
+		assert.ErrorIs(t, err, ErrNotReversible)
+		assert.Equal(t, int64(10000000), balance())
+	})
+
+	t.Run("Test Reverse gives back an outgoing payment", func(t *testing.T) {
+		reversal, err := svc.Reverse(2, domain.TransactionReversal{Reason: "paid twice"})
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.NewMoney(1000000, "EUR"), reversal.Amount)
+		assert.Equal(t, int64(11000000), balance())
+	})
+}
+
+// openBankAccount registers the bank account with the posting of its opening balance, as the bank account service does
+func openBankAccount(conn config.Conn, bankAccount bankaccountrepo.BankAccount) (int, error) {
+	id, err := bankaccountrepo.New(conn).Create(bankAccount)
+	if err != nil || bankAccount.BalanceCents == 0 {
+		return id, err
+	}
+	account := ledgerrepo.BankAccount(uint(id))
+	_, err = ledgerrepo.New(conn).Post(ledgerrepo.Journal{Operation: string(domain.LedgerOpeningBalance), Reference: account, CreatedAt: time.Now().UTC()},
+		ledgerrepo.AgainstEquity(account, bankAccount.BalanceCents))
+	return id, err
 }
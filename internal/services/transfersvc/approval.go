@@ -0,0 +1,246 @@
+package transfersvc
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/webhooksvc"
+	"time"
+)
+
+var (
+	// ErrNotPendingApproval is returned when deciding on a bulk transfer that isn't pending approval
+	ErrNotPendingApproval = errors.New("bulk transfer isn't pending approval")
+	// ErrSelfApproval is returned when the submitter of a bulk transfer decides on it
+	ErrSelfApproval = errors.New("the submitter of a bulk transfer can't approve or reject it")
+	// ErrNotApprover is returned when the actor isn't an approver of the organization bank account
+	ErrNotApprover = errors.New("not an approver of the bank account")
+	// ErrAlreadyDecided is returned when the actor already approved or rejected the bulk transfer
+	ErrAlreadyDecided = errors.New("bulk transfer already decided on by the actor")
+	// ErrMissingSubmitter is returned when the submitter of a bulk transfer needing approval is unknown
+	ErrMissingSubmitter = errors.New("the submitter of a bulk transfer needing approval must be known")
+)
+
+// Approve records the approval of a bulk transfer pending approval by the actor. Once it has every approval
+// it needs it is executed, its funds being checked only then, or scheduled when its execution date is ahead.
+func (s service) Approve(bulkTransferID uint, data domain.BulkTransferApproval, actor string) (domain.BulkTransferDetail, error) {
+	now := s.clock.Now().UTC()
+	var bulkTransfer bulktransferrepo.BulkTransfer
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		var decisions bulktransferrepo.DecisionList
+		var err error
+		bulkTransfer, decisions, err = readForDecision(repos, bulkTransferID, actor)
+		if err != nil {
+			return err
+		}
+
+		if _, err = repos.BulkTransfer.CreateDecision(decision(bulkTransferID, actor, domain.ApprovalApproved, data.Comment, now)); err != nil {
+			return err
+		}
+		if approvals(decisions)+1 < bulkTransfer.RequiredApprovals {
+			return nil
+		}
+
+		bulkTransfer.Status = string(domain.BulkTransferProcessing)
+		if bulkTransfer.ExecutionDate.After(now) {
+			bulkTransfer.Status = string(domain.BulkTransferScheduled)
+		}
+		bulkTransfer.UpdatedAt = now
+		return repos.BulkTransfer.Transition(bulkTransfer, string(domain.BulkTransferPendingApproval))
+	})
+	if errors.Is(err, bulktransferrepo.ErrStatusConflict) {
+		// decided on by someone else since it was read
+		return domain.BulkTransferDetail{}, fmt.Errorf("%w: it changed while being approved", ErrNotPendingApproval)
+	}
+	if err != nil {
+		return domain.BulkTransferDetail{}, err
+	}
+
+	if bulkTransfer.Status != string(domain.BulkTransferProcessing) {
+		return s.Read(bulkTransferID)
+	}
+
+	lines, err := s.bulkTransferRepo.ReadLines(bulkTransferID)
+	if err != nil {
+		s.fail(&bulkTransfer, err)
+		return domain.BulkTransferDetail{}, err
+	}
+
+	return s.execute(bulkTransfer, fromLines(bulkTransfer, lines))
+}
+
+// Reject records the rejection of a bulk transfer pending approval by the actor. A single rejection is enough
+// to reject it, nothing was debited so there is nothing to release.
+func (s service) Reject(bulkTransferID uint, data domain.BulkTransferRejection, actor string) (domain.BulkTransferDetail, error) {
+	now := s.clock.Now().UTC()
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		bulkTransfer, _, err := readForDecision(repos, bulkTransferID, actor)
+		if err != nil {
+			return err
+		}
+
+		if _, err = repos.BulkTransfer.CreateDecision(decision(bulkTransferID, actor, domain.ApprovalRejected, data.Reason, now)); err != nil {
+			return err
+		}
+
+		bulkTransfer.Status = string(domain.BulkTransferRejected)
+		bulkTransfer.UpdatedAt = now
+		if err = repos.BulkTransfer.Transition(bulkTransfer, string(domain.BulkTransferPendingApproval)); err != nil {
+			return err
+		}
+		return webhooksvc.PublishBulkTransfer(repos.Webhook, bulkTransfer)
+	})
+	if errors.Is(err, bulktransferrepo.ErrStatusConflict) {
+		return domain.BulkTransferDetail{}, fmt.Errorf("%w: it changed while being rejected", ErrNotPendingApproval)
+	}
+	if err != nil {
+		return domain.BulkTransferDetail{}, err
+	}
+
+	return s.Read(bulkTransferID)
+}
+
+// readForDecision reads the bulk transfer the actor decides on and the decisions taken on it so far. Only bulk
+// transfers pending approval can be decided on, by an approver of the organization account who didn't submit
+// them and didn't decide on them yet. Anyone but the submitter is an approver of the accounts without approvers,
+// so nobody decides on the bulk transfers whose submitter is unknown.
+func readForDecision(repos uow.Repositories, bulkTransferID uint, actor string) (bulktransferrepo.BulkTransfer, bulktransferrepo.DecisionList, error) {
+	bulkTransfer, err := repos.BulkTransfer.Read(bulkTransferID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return bulktransferrepo.BulkTransfer{}, nil, ErrBulkTransferNotFound
+	}
+	if err != nil {
+		return bulktransferrepo.BulkTransfer{}, nil, err
+	}
+	if bulkTransfer.Status != string(domain.BulkTransferPendingApproval) {
+		return bulktransferrepo.BulkTransfer{}, nil, fmt.Errorf("%w: it is %s", ErrNotPendingApproval, bulkTransfer.Status)
+	}
+	if bulkTransfer.SubmittedBy == "" {
+		return bulktransferrepo.BulkTransfer{}, nil, ErrMissingSubmitter
+	}
+	if actor == bulkTransfer.SubmittedBy {
+		return bulktransferrepo.BulkTransfer{}, nil, ErrSelfApproval
+	}
+
+	bankAccount, err := repos.BankAccount.ReadByIban(bulkTransfer.OrganizationIban)
+	if err != nil {
+		return bulktransferrepo.BulkTransfer{}, nil, err
+	}
+	approvers, err := repos.BankAccount.ReadApprovers(bankAccount.ID)
+	if err != nil {
+		return bulktransferrepo.BulkTransfer{}, nil, err
+	}
+	if len(approvers) > 0 && !contains(approvers, actor) {
+		return bulktransferrepo.BulkTransfer{}, nil, fmt.Errorf("%w: %s", ErrNotApprover, actor)
+	}
+
+	decisions, err := repos.BulkTransfer.ReadDecisions(bulkTransferID)
+	if err != nil {
+		return bulktransferrepo.BulkTransfer{}, nil, err
+	}
+	for _, d := range decisions {
+		if d.Actor == actor {
+			return bulktransferrepo.BulkTransfer{}, nil, fmt.Errorf("%w: it was %s", ErrAlreadyDecided, d.Decision)
+		}
+	}
+
+	return bulkTransfer, decisions, nil
+}
+
+// reassessApproval recomputes the approvals needed by a bulk transfer pending approval once some of its lines were
+// cancelled, never more than it was waiting for. When its lower total is within the approval threshold, or it
+// already has the approvals it needs, it is released for its execution, or scheduled when its execution date is ahead.
+func reassessApproval(repos uow.Repositories, bulkTransfer *bulktransferrepo.BulkTransfer, now time.Time) error {
+	required, err := requiredApprovals(repos, bulkTransfer.OrganizationIban, bulkTransfer.TotalCents)
+	if err != nil {
+		return err
+	}
+	if required > bulkTransfer.RequiredApprovals {
+		required = bulkTransfer.RequiredApprovals
+	}
+	bulkTransfer.RequiredApprovals = required
+
+	decisions, err := repos.BulkTransfer.ReadDecisions(bulkTransfer.ID)
+	if err != nil {
+		return err
+	}
+	if approvals(decisions) < required {
+		return nil
+	}
+
+	bulkTransfer.Status = string(domain.BulkTransferProcessing)
+	if bulkTransfer.ExecutionDate.After(now) {
+		bulkTransfer.Status = string(domain.BulkTransferScheduled)
+	}
+	return nil
+}
+
+// requiredApprovals how many approvals the bulk transfer needs before its execution, zero when its total is
+// within the approval threshold of the organization account. Unknown accounts are left to the checks.
+func requiredApprovals(repos uow.Repositories, organizationIban string, totalCents int64) (int, error) {
+	bankAccount, err := repos.BankAccount.ReadByIban(organizationIban)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !needsApproval(bankAccount, totalCents) {
+		return 0, nil
+	}
+	if bankAccount.Approval.RequiredApprovals < 1 {
+		return 1, nil
+	}
+	return bankAccount.Approval.RequiredApprovals, nil
+}
+
+// needsApproval whether the total is above the approval threshold of the bank account
+func needsApproval(bankAccount bankaccountrepo.BankAccount, totalCents int64) bool {
+	return bankAccount.Approval.ThresholdCents > 0 && totalCents > bankAccount.Approval.ThresholdCents
+}
+
+func approvals(decisions bulktransferrepo.DecisionList) int {
+	count := 0
+	for _, d := range decisions {
+		if d.Decision == string(domain.ApprovalApproved) {
+			count++
+		}
+	}
+	return count
+}
+
+func decision(bulkTransferID uint, actor string, decision domain.ApprovalDecision, comment string, at time.Time) bulktransferrepo.Decision {
+	return bulktransferrepo.Decision{
+		BulkTransferID: bulkTransferID,
+		Actor:          actor,
+		Decision:       string(decision),
+		Comment:        comment,
+		CreatedAt:      at,
+	}
+}
+
+func toDecisions(decisions bulktransferrepo.DecisionList) []domain.Decision {
+	res := []domain.Decision{}
+	for _, d := range decisions {
+		res = append(res, domain.Decision{
+			Actor:    d.Actor,
+			Decision: domain.ApprovalDecision(d.Decision),
+			Comment:  d.Comment,
+			At:       d.CreatedAt,
+		})
+	}
+	return res
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package transfersvc
+
+import (
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"sort"
+	"time"
+)
+
+// selection is the outcome of the walk of a best effort bulk transfer over the plan of its checks
+type selection struct {
+	// order the positions of the credit transfers to execute, in the order they are executed
+	order []int
+	// outcomes the outcome of every credit transfer, by position
+	outcomes []domain.LineOutcome
+	// fees the fees of the credit transfers to execute
+	fees       []domain.Fee
+	totalCents int64
+	feesCents  int64
+	// blocking the problem rejecting the bulk transfer as a whole, when it has one or no line can be executed
+	blocking *domain.Rejection
+}
+
+// selectLines walks the credit transfers of a best effort bulk transfer by priority. The lines with a problem of
+// their own, invalid fields, a per transaction limit, a screening match or a suspected duplicate, are rejected and
+// the walk goes on. The others are executed until the funds, counting the overdraft and the fees, or the headroom
+// of a batch, daily or monthly limit run out, the remaining lines being skipped. Any problem of the request itself
+// blocks the whole bulk transfer.
+func selectLines(repos uow.Repositories, p plan, data domain.BulkTransfer, now time.Time) (selection, error) {
+	sel := selection{outcomes: make([]domain.LineOutcome, len(data.CreditTransfers))}
+	for i := range sel.outcomes {
+		sel.outcomes[i] = domain.LineOutcome{Index: i, Status: domain.LineExecuted}
+	}
+	reject := func(index int, reason domain.RejectionReason, message string) {
+		if index < 0 || index >= len(sel.outcomes) || sel.outcomes[index].Status == domain.LineRejected {
+			// the first problem of a line is reported
+			return
+		}
+		sel.outcomes[index] = domain.LineOutcome{Index: index, Status: domain.LineRejected, Reason: reason, Message: message}
+	}
+
+	for _, problem := range p.problems {
+		switch {
+		case problem.LinesOnly():
+			for _, lineError := range problem.Errors {
+				reject(*lineError.Index, problem.Reason, fmt.Sprintf("%s: %s", lineError.Field, lineError.Message))
+			}
+		case len(problem.Matches) > 0:
+			for _, m := range problem.Matches {
+				reject(m.Index, problem.Reason, fmt.Sprintf("%s matches %s %q", m.Field, m.EntryID, m.EntryName))
+			}
+		case len(problem.Duplicates) > 0:
+			for _, d := range problem.Duplicates {
+				reject(d.Index, problem.Reason, fmt.Sprintf("pays what bulk transfer %d paid at %s", d.BulkTransferID, d.At.Format(time.RFC3339)))
+			}
+		case problem.Reason == domain.RejectionInsufficientFunds, problem.Reason == domain.RejectionLimitExceeded:
+			// the funds and the limits are walked line by line
+		default:
+			sel.blocking = problem
+			return sel, nil
+		}
+	}
+
+	limits, err := periodLimits(repos, p.bankAccount, now)
+	if err != nil {
+		return selection{}, err
+	}
+	if p.bankAccount.Limits.BatchCents > 0 {
+		limits = append(limits, cumulativeLimit{limit: domain.LimitPerBatch, limitCents: p.bankAccount.Limits.BatchCents})
+	}
+
+	feeOf := map[int]domain.Fee{}
+	for _, fee := range p.fees {
+		feeOf[fee.Index] = fee
+	}
+
+	// the authorised overdraft is available on top of the balance
+	availableCents := p.bankAccount.BalanceCents + p.bankAccount.OverdraftCents
+	var stop *domain.Rejection
+	for _, i := range byPriority(data.CreditTransfers) {
+		if sel.outcomes[i].Status == domain.LineRejected {
+			continue
+		}
+		if stop == nil {
+			stop = exhausted(availableCents, limits, p.creditTransfers[i].Amount.MinorUnits, feeOf[i].Amount.MinorUnits)
+		}
+		if stop != nil {
+			sel.outcomes[i] = domain.LineOutcome{Index: i, Status: domain.LineSkipped, Reason: stop.Reason, Message: stop.Message}
+			continue
+		}
+
+		amountCents := p.creditTransfers[i].Amount.MinorUnits
+		availableCents -= amountCents + feeOf[i].Amount.MinorUnits
+		for j := range limits {
+			limits[j].transferredCents += amountCents
+		}
+		sel.order = append(sel.order, i)
+		sel.totalCents += amountCents
+		if fee, ok := feeOf[i]; ok {
+			sel.fees = append(sel.fees, fee)
+			sel.feesCents += fee.Amount.MinorUnits
+		}
+	}
+
+	if len(sel.order) == 0 {
+		sel.blocking = domain.NewRejection(domain.RejectionInvalidFields, ErrNothingToExecute)
+		if len(p.problems) > 0 {
+			sel.blocking = p.problems[0]
+		}
+	}
+	return sel, nil
+}
+
+// exhausted reports why a credit transfer of amountCents charged feeCents doesn't fit in what is left of the funds
+// or of the limits, nil when it fits
+func exhausted(availableCents int64, limits []cumulativeLimit, amountCents, feeCents int64) *domain.Rejection {
+	if amountCents+feeCents > availableCents {
+		return domain.NewInsufficientFundsRejection(ErrInsufficientFunds, domain.NewMoney(amountCents+feeCents, domain.AccountCurrency), nil)
+	}
+	for _, l := range limits {
+		if amountCents > l.headroom() {
+			return limitRejection(l.limit, l.limitCents, l.headroom())
+		}
+	}
+	return nil
+}
+
+// byPriority the positions of the credit transfers, the highest priority first, those with the same priority in
+// their order
+func byPriority(creditTransfers []domain.CreditTransfer) []int {
+	order := make([]int, len(creditTransfers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return creditTransfers[order[a]].Priority > creditTransfers[order[b]].Priority
+	})
+	return order
+}
+
+// executeSelection registers the credit transfers of the selection in their order, recording the outcome of every
+// line of the bulk transfer. It is completed when every line was executed, partially completed otherwise.
+func (s service) executeSelection(repos uow.Repositories, bulkTransfer *bulktransferrepo.BulkTransfer, p plan, data domain.BulkTransfer, sel selection, tracker func(done int)) error {
+	position := map[int]int{}
+	subset := data
+	subset.CreditTransfers = nil
+	for k, i := range sel.order {
+		position[i] = k
+		subset.CreditTransfers = append(subset.CreditTransfers, p.creditTransfers[i])
+	}
+	var charged []domain.Fee
+	for _, fee := range sel.fees {
+		fee.Index = position[fee.Index]
+		charged = append(charged, fee)
+	}
+
+	ids, err := registerTransfers(repos, p.bankAccount, *bulkTransfer, subset, charged, tracker)
+	if err != nil {
+		return err
+	}
+
+	outcomes := bulktransferrepo.OutcomeList{}
+	for _, outcome := range sel.outcomes {
+		if k, ok := position[outcome.Index]; ok {
+			outcome.TransactionID = ids[k]
+		}
+		outcomes = append(outcomes, bulktransferrepo.Outcome{
+			Index:         outcome.Index,
+			Status:        string(outcome.Status),
+			Reason:        string(outcome.Reason),
+			Message:       outcome.Message,
+			TransactionID: outcome.TransactionID,
+			CreatedAt:     bulkTransfer.UpdatedAt,
+		})
+	}
+	if err = repos.BulkTransfer.CreateOutcomes(bulkTransfer.ID, outcomes); err != nil {
+		return err
+	}
+
+	status := domain.BulkTransferCompleted
+	if len(sel.order) < len(sel.outcomes) {
+		status = domain.BulkTransferPartiallyCompleted
+	}
+	return s.settle(repos, bulkTransfer, status, "")
+}
+
+// readReport the outcome of every line of an executed best effort bulk transfer, nil when there is none, and the
+// line of every executed transaction
+func (s service) readReport(bulkTransferID uint) (*domain.ExecutionReport, map[uint]int, error) {
+	outcomes, err := s.bulkTransferRepo.ReadOutcomes(bulkTransferID)
+	if err != nil || len(outcomes) == 0 {
+		return nil, nil, err
+	}
+
+	lineOf := map[uint]int{}
+	var lines []domain.LineOutcome
+	for _, o := range outcomes {
+		if o.TransactionID != 0 {
+			lineOf[o.TransactionID] = o.Index
+		}
+		lines = append(lines, domain.LineOutcome{
+			Index:         o.Index,
+			Status:        domain.LineStatus(o.Status),
+			TransactionID: o.TransactionID,
+			Reason:        domain.RejectionReason(o.Reason),
+			Message:       o.Message,
+		})
+	}
+	return domain.NewExecutionReport(lines), lineOf, nil
+}
@@ -0,0 +1,328 @@
+package transfersvc
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/fees"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/screening"
+	"math"
+	"time"
+)
+
+// plan is the outcome of the checks of a bulk transfer. The execution and the quote of a bulk transfer
+// are both built from it, so they can't disagree.
+type plan struct {
+	bankAccount bankaccountrepo.BankAccount
+	totalCents  int64
+	// creditTransfers the credit transfers to register, those referencing a beneficiary holding its details
+	creditTransfers []domain.CreditTransfer
+	// matches the counterparties matching the screening list, they are problems when the screener rejects them
+	matches []domain.ScreeningMatch
+	// duplicates the credit transfers looking like duplicates of recent ones, they are problems unless allowed
+	duplicates []domain.Duplicate
+	// fees the fees charged for the credit transfers, debited on top of the total
+	fees      []domain.Fee
+	feesCents int64
+	// problems every reason the bulk transfer can't be executed, in the order they were found
+	problems []*domain.Rejection
+}
+
+func (p plan) executable() bool {
+	return len(p.problems) == 0
+}
+
+// check runs every check of the bulk transfer without writing anything: the validation of its fields,
+// the currency and the beneficiary of its lines, the screening of their counterparties, the organization
+// bank account, the payments it made within the duplicate window, its balance and overdraft, covering the
+// fees of the schedule too, and its transfer limits, which don't count the fees.
+// Problems are reported in the plan, the error is only returned when the checks can't run.
+func check(repos uow.Repositories, screener screening.Screener, duplicateWindow time.Duration, schedule fees.Schedule, data domain.BulkTransfer, now time.Time) (plan, error) {
+	p := plan{totalCents: linesTotal(data)}
+
+	var lines *domain.Rejection
+	if err := data.Validate(); err != nil {
+		lines = domain.AsRejection(err)
+	}
+	for i, creditTransfer := range data.CreditTransfers {
+		if creditTransfer.Currency == "" || creditTransfer.Currency == domain.AccountCurrency {
+			continue
+		}
+		if lines == nil {
+			lines = domain.NewRejection(domain.RejectionInvalidFields, fmt.Errorf("%w: the account is held in %s", ErrUnsupportedCurrency, domain.AccountCurrency))
+		}
+		lines.WithLineError(i, "currency", "currency", fmt.Sprintf("%q is not the currency of the account", creditTransfer.Currency))
+	}
+	for i, creditTransfer := range data.CreditTransfers {
+		resolved, found, err := resolveBeneficiary(repos, data.OrganizationIban, creditTransfer)
+		if err != nil {
+			return plan{}, err
+		}
+		if !found {
+			if lines == nil {
+				lines = domain.NewRejection(domain.RejectionInvalidFields, ErrBeneficiaryNotFound)
+			}
+			lines.WithLineError(i, "beneficiary_id", "beneficiary", fmt.Sprintf("no beneficiary %d on the account", creditTransfer.BeneficiaryID))
+		}
+		p.creditTransfers = append(p.creditTransfers, resolved)
+	}
+	if lines != nil {
+		p.problems = append(p.problems, lines)
+	}
+
+	p.matches = screen(screener, p.creditTransfers)
+	if len(p.matches) > 0 && screener.Mode() == screening.ModeReject {
+		p.problems = append(p.problems, domain.NewScreeningRejection(screeningError(p.matches), p.matches))
+	}
+
+	bankAccount, err := repos.BankAccount.ReadByIban(data.OrganizationIban)
+	if errors.Is(err, sql.ErrNoRows) {
+		p.problems = append(p.problems, domain.NewRejection(domain.RejectionAccountNotFound, fmt.Errorf("%w: %s", ErrAccountNotFound, data.OrganizationIban)))
+		return p, nil
+	}
+	if err != nil {
+		return plan{}, err
+	}
+	p.bankAccount = bankAccount
+
+	if duplicateWindow > 0 && data.TemplateID == 0 {
+		// the occurrences of a template pay the same counterparties on purpose
+		if p.duplicates, err = findDuplicates(repos, bankAccount.ID, p.creditTransfers, now.Add(-duplicateWindow)); err != nil {
+			return plan{}, err
+		}
+		if len(p.duplicates) > 0 && !data.AllowDuplicates {
+			p.problems = append(p.problems, domain.NewDuplicateRejection(duplicateError(p.duplicates), p.duplicates))
+		}
+	}
+
+	p.fees, p.feesCents = charge(schedule, bankAccount.Plan, p.creditTransfers)
+
+	// the authorised overdraft is available on top of the balance
+	if bankAccount.BalanceCents+bankAccount.OverdraftCents < p.totalCents+p.feesCents {
+		available := domain.NewMoney(bankAccount.BalanceCents+bankAccount.OverdraftCents, domain.AccountCurrency)
+		p.problems = append(p.problems, domain.NewInsufficientFundsRejection(ErrInsufficientFunds, domain.NewMoney(p.totalCents+p.feesCents, domain.AccountCurrency), &available))
+	}
+
+	limits, err := checkLimits(repos, bankAccount, data, p.totalCents, now)
+	if err != nil {
+		return plan{}, err
+	}
+	p.problems = append(p.problems, limits...)
+
+	return p, nil
+}
+
+// checkLimits reports every transfer limit of the bank account breached by the bulk transfer. The daily and
+// monthly totals are those of the transfers registered so far, read in the unit of work of the debit so
+// concurrent bulk transfers can't both fit in the same headroom.
+func checkLimits(repos uow.Repositories, bankAccount bankaccountrepo.BankAccount, data domain.BulkTransfer, totalCents int64, now time.Time) ([]*domain.Rejection, error) {
+	limits := bankAccount.Limits
+	var problems []*domain.Rejection
+
+	if limits.TransactionCents > 0 {
+		var lines *domain.Rejection
+		for i, creditTransfer := range data.CreditTransfers {
+			if creditTransfer.Amount.MinorUnits <= limits.TransactionCents {
+				continue
+			}
+			if lines == nil {
+				lines = limitRejection(domain.LimitPerTransaction, limits.TransactionCents, limits.TransactionCents)
+			}
+			lines.WithLineError(i, "amount", "per_transaction_limit", fmt.Sprintf("exceeds the per transaction limit of %s", domain.NewMoney(limits.TransactionCents, domain.AccountCurrency)))
+		}
+		if lines != nil {
+			problems = append(problems, lines)
+		}
+	}
+
+	if limits.BatchCents > 0 && totalCents > limits.BatchCents {
+		problems = append(problems, limitRejection(domain.LimitPerBatch, limits.BatchCents, limits.BatchCents))
+	}
+
+	periods, err := periodLimits(repos, bankAccount, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, period := range periods {
+		if period.transferredCents+totalCents <= period.limitCents {
+			continue
+		}
+		problems = append(problems, limitRejection(period.limit, period.limitCents, period.headroom()))
+	}
+
+	return problems, nil
+}
+
+// cumulativeLimit a transfer limit on a total and what was already transferred within it
+type cumulativeLimit struct {
+	limit            domain.TransferLimit
+	limitCents       int64
+	transferredCents int64
+}
+
+// headroom what can still be transferred within the limit
+func (l cumulativeLimit) headroom() int64 {
+	if l.transferredCents >= l.limitCents {
+		return 0
+	}
+	return l.limitCents - l.transferredCents
+}
+
+// periodLimits the daily and monthly limits set on the bank account with the totals of the transfers registered
+// so far within their period
+func periodLimits(repos uow.Repositories, bankAccount bankaccountrepo.BankAccount, now time.Time) ([]cumulativeLimit, error) {
+	limits := bankAccount.Limits
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var res []cumulativeLimit
+	for _, period := range []struct {
+		limit      domain.TransferLimit
+		limitCents int64
+		since      time.Time
+	}{
+		{domain.LimitDaily, limits.DailyCents, today},
+		{domain.LimitMonthly, limits.MonthlyCents, today.AddDate(0, 0, 1-today.Day())},
+	} {
+		if period.limitCents == 0 {
+			continue
+		}
+		transferred, err := repos.Transaction.SumTransferred(bankAccount.ID, period.since)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, cumulativeLimit{limit: period.limit, limitCents: period.limitCents, transferredCents: transferred})
+	}
+
+	return res, nil
+}
+
+// limitRejection rejects the bulk transfer for breaching the limit, reporting what can still be transferred within it
+func limitRejection(limit domain.TransferLimit, limitCents, headroomCents int64) *domain.Rejection {
+	headroom := domain.NewMoney(headroomCents, domain.AccountCurrency)
+	err := fmt.Errorf("%w: the %s limit is %s, %s left", ErrLimitExceeded, limit, domain.NewMoney(limitCents, domain.AccountCurrency), headroom)
+	return domain.NewLimitRejection(err, limit, headroom)
+}
+
+// resolveBeneficiary fills the counterparty of a credit transfer referencing a beneficiary with the current details
+// of the beneficiary, found tells whether it is a beneficiary of the organization account. Credit transfers holding
+// their counterparty are returned as they are.
+func resolveBeneficiary(repos uow.Repositories, organizationIban string, creditTransfer domain.CreditTransfer) (domain.CreditTransfer, bool, error) {
+	if creditTransfer.BeneficiaryID == 0 {
+		return creditTransfer, true, nil
+	}
+
+	beneficiary, err := repos.Beneficiary.Read(creditTransfer.BeneficiaryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return creditTransfer, false, nil
+	}
+	if err != nil {
+		return domain.CreditTransfer{}, false, err
+	}
+	if beneficiary.OrganizationIban != organizationIban {
+		return creditTransfer, false, nil
+	}
+
+	creditTransfer.CounterPartyName = beneficiary.Name
+	creditTransfer.CounterPartyIban = beneficiary.Iban
+	creditTransfer.CounterPartyBic = beneficiary.Bic
+	return creditTransfer, true, nil
+}
+
+// screen matches the counterparty of every credit transfer against the screening list
+func screen(screener screening.Screener, creditTransfers []domain.CreditTransfer) []domain.ScreeningMatch {
+	var matches []domain.ScreeningMatch
+	for i, creditTransfer := range creditTransfers {
+		for _, hit := range screener.Screen(creditTransfer.CounterPartyName, creditTransfer.CounterPartyIban) {
+			matches = append(matches, domain.ScreeningMatch{
+				Index:       i,
+				Field:       "counterparty_" + string(hit.Field),
+				EntryID:     hit.Entry.ID,
+				EntryName:   hit.Entry.Name,
+				EntrySource: hit.Entry.Source,
+				Score:       math.Round(hit.Score*1000) / 1000,
+			})
+		}
+	}
+	return matches
+}
+
+// screeningError names the entry matched first
+func screeningError(matches []domain.ScreeningMatch) error {
+	first := matches[0]
+	return fmt.Errorf("%w: credit_transfers[%d].%s matches %s %q", ErrScreeningHit, first.Index, first.Field, first.EntryID, first.EntryName)
+}
+
+// findDuplicates reports the credit transfers paying the same counterparty the same amount with the same description
+// as an outgoing transaction of a bulk transfer registered on the bank account since the given time
+func findDuplicates(repos uow.Repositories, bankAccountID uint, creditTransfers []domain.CreditTransfer, since time.Time) ([]domain.Duplicate, error) {
+	var duplicates []domain.Duplicate
+	for i, creditTransfer := range creditTransfers {
+		if creditTransfer.CounterPartyIban == "" {
+			// an unknown beneficiary, already reported
+			continue
+		}
+		transaction, err := repos.Transaction.ReadDuplicate(bankAccountID, creditTransfer.CounterPartyIban, creditTransfer.Amount.MinorUnits, creditTransfer.Description, since)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		duplicates = append(duplicates, domain.Duplicate{
+			Index:          i,
+			TransactionID:  transaction.ID,
+			BulkTransferID: transaction.BulkTransferID,
+			At:             transaction.CreatedAt,
+		})
+	}
+	return duplicates, nil
+}
+
+// duplicateError names the first duplicate and how to execute the bulk transfer anyway
+func duplicateError(duplicates []domain.Duplicate) error {
+	first := duplicates[0]
+	return fmt.Errorf("%w: credit_transfers[%d] pays what bulk transfer %d paid at %s, set allow_duplicates to execute it anyway",
+		ErrDuplicateSuspected, first.Index, first.BulkTransferID, first.At.Format(time.RFC3339))
+}
+
+// charge computes the fee of every credit transfer in the currency of the account with the rules of the schedule
+// for the plan, the destination country being the prefix of the counterparty iban. It returns the fees that
+// aren't zero and their total.
+func charge(schedule fees.Schedule, accountPlan string, creditTransfers []domain.CreditTransfer) ([]domain.Fee, int64) {
+	var res []domain.Fee
+	var totalCents int64 = 0
+	for i, creditTransfer := range creditTransfers {
+		if creditTransfer.Amount.Currency != domain.AccountCurrency {
+			continue
+		}
+		c, ok := schedule.Fee(accountPlan, creditTransfer.Amount.Currency, creditTransfer.CounterPartyIban, creditTransfer.Amount.MinorUnits)
+		if !ok || c.AmountCents == 0 {
+			continue
+		}
+		res = append(res, domain.Fee{Index: i, Rule: c.Rule, Amount: domain.NewMoney(c.AmountCents, domain.AccountCurrency)})
+		totalCents += c.AmountCents
+	}
+	return res, totalCents
+}
+
+// feeBreakdown the fees and their total, nil when there is none
+func feeBreakdown(charged []domain.Fee, totalCents int64) *domain.FeeBreakdown {
+	if len(charged) == 0 {
+		return nil
+	}
+	return &domain.FeeBreakdown{TotalAmount: domain.NewMoney(totalCents, domain.AccountCurrency), Fees: charged}
+}
+
+// linesTotal sum of the amounts of the credit transfers in the currency of the account
+func linesTotal(data domain.BulkTransfer) int64 {
+	var totalCents int64 = 0
+	for _, creditTransfer := range data.CreditTransfers {
+		if creditTransfer.Amount.Currency == domain.AccountCurrency {
+			totalCents += creditTransfer.Amount.MinorUnits
+		}
+	}
+	return totalCents
+}
@@ -0,0 +1,281 @@
+package transfersvc
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"go.uber.org/zap"
+	"os"
+	"sync"
+	"time"
+)
+
+// jobLease how long a worker holds a running job without renewing its lease, a job whose lease expired is claimed
+// again by any worker. The lease is renewed every jobLease / 4 while the job runs.
+const jobLease = 2 * time.Minute
+
+var (
+	// ErrJobNotFound is returned when the job doesn't exist
+	ErrJobNotFound = errors.New("job not found")
+	// errJobUnfinished is returned when the bulk transfer of a job is still processing, the job is resumed later
+	errJobUnfinished = errors.New("the bulk transfer of the job is still processing")
+)
+
+// Submit stores the bulk transfer as queued, along with the job executing it, and returns the job without waiting
+// for its execution. Bulk transfers above the approval threshold or with a future execution date are stored pending
+// approval or scheduled, as when they are executed right away, their job being deferred as soon as a worker picks it.
+func (s service) Submit(data domain.BulkTransfer) (domain.BulkTransferJob, error) {
+	var job bulktransferrepo.Job
+	_, err := s.store(data, domain.BulkTransferQueued, func(repos uow.Repositories, bulkTransfer bulktransferrepo.BulkTransfer) error {
+		job = bulktransferrepo.Job{
+			BulkTransferID: bulkTransfer.ID,
+			Status:         string(domain.JobQueued),
+			LinesTotal:     bulkTransfer.TransfersCount,
+			CreatedAt:      bulkTransfer.CreatedAt,
+			UpdatedAt:      bulkTransfer.CreatedAt,
+		}
+		id, err := repos.BulkTransfer.CreateJob(job)
+		job.ID = uint(id)
+		return err
+	})
+	if err != nil {
+		return domain.BulkTransferJob{}, err
+	}
+
+	return toJob(job), nil
+}
+
+// ReadJob a job, the progress of a running job being the credit transfers its worker registered so far
+func (s service) ReadJob(jobID uint) (domain.BulkTransferJob, error) {
+	job, err := s.bulkTransferRepo.ReadJob(jobID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.BulkTransferJob{}, ErrJobNotFound
+	}
+	if err != nil {
+		return domain.BulkTransferJob{}, err
+	}
+
+	if done, ok := s.progress.get(jobID); ok && job.Status == string(domain.JobRunning) {
+		job.LinesDone = done
+	}
+
+	return toJob(job), nil
+}
+
+// PendingJobs the jobs queued or left running, oldest first
+func (s service) PendingJobs() ([]uint, error) {
+	jobs, err := s.bulkTransferRepo.ReadPendingJobs()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []uint{}
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+
+	return ids, nil
+}
+
+// RunJob executes the bulk transfer of a queued job, once claimed with a lease renewed while it runs: a job claimed
+// by another worker meanwhile, or still held by the worker of another process, is left to it. A job whose lease
+// expired, its worker having stopped, is resumed: its bulk transfer is executed again unless its execution was
+// committed, the debit, the registration of its credit transfers and the end of its processing being atomic. The job
+// ends up done, or failed with the reason its bulk transfer was rejected, or deferred when its bulk transfer is left
+// pending approval, scheduled or held for review. Jobs that are already finished are left as they are.
+func (s service) RunJob(jobID uint) error {
+	job, err := s.bulkTransferRepo.ReadJob(jobID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if job.Status != string(domain.JobQueued) && job.Status != string(domain.JobRunning) {
+		return nil
+	}
+
+	now := s.clock.Now().UTC()
+	job.Status = string(domain.JobRunning)
+	if job.StartedAt.IsZero() {
+		job.StartedAt = now
+	}
+	job.UpdatedAt = now
+	if job.Owner, err = newOwner(); err != nil {
+		return err
+	}
+	job.LeaseExpiresAt = now.Add(jobLease)
+	err = s.bulkTransferRepo.ClaimJob(job, now)
+	if errors.Is(err, bulktransferrepo.ErrStatusConflict) {
+		// claimed by another worker, still held by its worker, or finished, since it was read
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	stop := s.keepLease(job)
+	defer stop()
+
+	bulkTransfer, err := s.bulkTransferRepo.Read(job.BulkTransferID)
+	if err != nil {
+		return err
+	}
+
+	if bulkTransfer.Status == string(domain.BulkTransferQueued) {
+		bulkTransfer.Status = string(domain.BulkTransferProcessing)
+		bulkTransfer.UpdatedAt = now
+		if err = s.bulkTransferRepo.Transition(bulkTransfer, string(domain.BulkTransferQueued)); err != nil {
+			return err
+		}
+	}
+
+	if bulkTransfer.Status == string(domain.BulkTransferProcessing) {
+		lines, err := s.bulkTransferRepo.ReadLines(bulkTransfer.ID)
+		if err != nil {
+			// the job is left running, it is resumed once its lease expired
+			return err
+		}
+
+		defer s.progress.clear(jobID)
+		// failures are recorded on the bulk transfer
+		_, _ = s.executeTracked(bulkTransfer, fromLines(bulkTransfer, lines), func(done int) {
+			s.progress.set(jobID, done)
+		})
+	}
+
+	return s.finishJob(job)
+}
+
+// keepLease renews the lease of the running job until the returned function is called, which waits for the last
+// renewal. It stops renewing once the lease is lost.
+func (s service) keepLease(job bulktransferrepo.Job) func() {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(jobLease / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			now := s.clock.Now().UTC()
+			job.UpdatedAt = now
+			job.LeaseExpiresAt = now.Add(jobLease)
+			err := s.bulkTransferRepo.RenewJob(job)
+			if errors.Is(err, bulktransferrepo.ErrStatusConflict) {
+				s.logger.Warn("job lease lost", zap.Uint("job_id", job.ID))
+				return
+			}
+			if err != nil {
+				s.logger.WithError(err).Error("error renewing job lease", zap.Uint("job_id", job.ID))
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// finishJob records the outcome of the bulk transfer of the job on it, unless its lease was lost to another worker
+// meanwhile, which records it instead
+func (s service) finishJob(job bulktransferrepo.Job) error {
+	detail, err := s.Read(job.BulkTransferID)
+	if err != nil {
+		return err
+	}
+	if detail.Status == domain.BulkTransferProcessing {
+		// its failure couldn't be recorded
+		return errJobUnfinished
+	}
+
+	now := s.clock.Now().UTC()
+	job.Status = string(domain.JobDone)
+	job.LinesDone = len(detail.Transactions)
+	switch detail.Status {
+	case domain.BulkTransferFailed:
+		job.Status = string(domain.JobFailed)
+		job.Error = detail.FailureReason
+	case domain.BulkTransferPendingApproval, domain.BulkTransferScheduled, domain.BulkTransferHeldForReview:
+		job.Status = string(domain.JobDeferred)
+	}
+	job.FinishedAt = now
+	job.UpdatedAt = now
+
+	err = s.bulkTransferRepo.FinishJob(job)
+	if errors.Is(err, bulktransferrepo.ErrStatusConflict) {
+		return nil
+	}
+	return err
+}
+
+// newOwner identifies the worker of this process claiming a job
+func newOwner() (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, 8)
+	if _, err = rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(b)), nil
+}
+
+func toJob(job bulktransferrepo.Job) domain.BulkTransferJob {
+	return domain.BulkTransferJob{
+		ID:             job.ID,
+		BulkTransferID: job.BulkTransferID,
+		Status:         domain.JobStatus(job.Status),
+		LinesTotal:     job.LinesTotal,
+		LinesDone:      job.LinesDone,
+		Error:          job.Error,
+		CreatedAt:      job.CreatedAt,
+		StartedAt:      optionalTime(job.StartedAt),
+		FinishedAt:     optionalTime(job.FinishedAt),
+	}
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// progress how many credit transfers the jobs being run registered so far. It is shared by the copies of the service.
+type progress struct {
+	mu   sync.Mutex
+	done map[uint]int
+}
+
+func (p *progress) set(jobID uint, done int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done[jobID] = done
+}
+
+func (p *progress) get(jobID uint) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	done, ok := p.done[jobID]
+	return done, ok
+}
+
+func (p *progress) clear(jobID uint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.done, jobID)
+}
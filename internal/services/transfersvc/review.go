@@ -0,0 +1,175 @@
+package transfersvc
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/webhooksvc"
+	"time"
+)
+
+var (
+	// ErrNotHeldForReview is returned when reviewing a bulk transfer that isn't held for review
+	ErrNotHeldForReview = errors.New("bulk transfer isn't held for review")
+	// ErrSelfReview is returned when the submitter of a bulk transfer reviews it
+	ErrSelfReview = errors.New("the submitter of a bulk transfer can't release or block it")
+)
+
+// Release clears the screening matches of a bulk transfer held for review. It is executed right away, without
+// screening its counterparties again, or scheduled when its execution date is ahead.
+func (s service) Release(bulkTransferID uint, data domain.BulkTransferRelease, actor string) (domain.BulkTransferDetail, error) {
+	now := s.clock.Now().UTC()
+	var bulkTransfer bulktransferrepo.BulkTransfer
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		var err error
+		bulkTransfer, err = readForReview(repos, bulkTransferID, actor)
+		if err != nil {
+			return err
+		}
+
+		if _, err = repos.BulkTransfer.CreateReview(review(bulkTransferID, actor, domain.ScreeningReleased, data.Comment, now)); err != nil {
+			return err
+		}
+
+		bulkTransfer.Status = string(domain.BulkTransferProcessing)
+		if bulkTransfer.ExecutionDate.After(now) {
+			bulkTransfer.Status = string(domain.BulkTransferScheduled)
+		}
+		bulkTransfer.UpdatedAt = now
+		return repos.BulkTransfer.Transition(bulkTransfer, string(domain.BulkTransferHeldForReview))
+	})
+	if errors.Is(err, bulktransferrepo.ErrStatusConflict) {
+		// reviewed by someone else since it was read
+		return domain.BulkTransferDetail{}, fmt.Errorf("%w: it changed while being released", ErrNotHeldForReview)
+	}
+	if err != nil {
+		return domain.BulkTransferDetail{}, err
+	}
+
+	if bulkTransfer.Status != string(domain.BulkTransferProcessing) {
+		return s.Read(bulkTransferID)
+	}
+
+	lines, err := s.bulkTransferRepo.ReadLines(bulkTransferID)
+	if err != nil {
+		s.fail(&bulkTransfer, err)
+		return domain.BulkTransferDetail{}, err
+	}
+
+	return s.execute(bulkTransfer, fromLines(bulkTransfer, lines))
+}
+
+// Block confirms a screening match of a bulk transfer held for review, it is never executed. Nothing was
+// debited so there is nothing to release.
+func (s service) Block(bulkTransferID uint, data domain.BulkTransferBlock, actor string) (domain.BulkTransferDetail, error) {
+	now := s.clock.Now().UTC()
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		bulkTransfer, err := readForReview(repos, bulkTransferID, actor)
+		if err != nil {
+			return err
+		}
+
+		if _, err = repos.BulkTransfer.CreateReview(review(bulkTransferID, actor, domain.ScreeningBlocked, data.Reason, now)); err != nil {
+			return err
+		}
+
+		bulkTransfer.Status = string(domain.BulkTransferBlocked)
+		bulkTransfer.UpdatedAt = now
+		if err = repos.BulkTransfer.Transition(bulkTransfer, string(domain.BulkTransferHeldForReview)); err != nil {
+			return err
+		}
+		return webhooksvc.PublishBulkTransfer(repos.Webhook, bulkTransfer)
+	})
+	if errors.Is(err, bulktransferrepo.ErrStatusConflict) {
+		return domain.BulkTransferDetail{}, fmt.Errorf("%w: it changed while being blocked", ErrNotHeldForReview)
+	}
+	if err != nil {
+		return domain.BulkTransferDetail{}, err
+	}
+
+	return s.Read(bulkTransferID)
+}
+
+// readForReview reads the bulk transfer the actor reviews. Only bulk transfers held for review can be reviewed,
+// by anyone but their submitter.
+func readForReview(repos uow.Repositories, bulkTransferID uint, actor string) (bulktransferrepo.BulkTransfer, error) {
+	bulkTransfer, err := repos.BulkTransfer.Read(bulkTransferID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return bulktransferrepo.BulkTransfer{}, ErrBulkTransferNotFound
+	}
+	if err != nil {
+		return bulktransferrepo.BulkTransfer{}, err
+	}
+	if bulkTransfer.Status != string(domain.BulkTransferHeldForReview) {
+		return bulktransferrepo.BulkTransfer{}, fmt.Errorf("%w: it is %s", ErrNotHeldForReview, bulkTransfer.Status)
+	}
+	if actor == bulkTransfer.SubmittedBy {
+		return bulktransferrepo.BulkTransfer{}, ErrSelfReview
+	}
+
+	return bulkTransfer, nil
+}
+
+// readScreening the screening matches that held the bulk transfer and its review, if any
+func (s service) readScreening(bulkTransferID uint) (*domain.Screening, error) {
+	matches, err := s.bulkTransferRepo.ReadMatches(bulkTransferID)
+	if err != nil {
+		return nil, err
+	}
+
+	screening := &domain.Screening{Matches: []domain.ScreeningMatch{}}
+	for _, m := range matches {
+		screening.Matches = append(screening.Matches, domain.ScreeningMatch{
+			Index:       m.Index,
+			Field:       m.Field,
+			EntryID:     m.EntryID,
+			EntryName:   m.EntryName,
+			EntrySource: m.EntrySource,
+			Score:       m.Score,
+		})
+	}
+
+	r, err := s.bulkTransferRepo.ReadReview(bulkTransferID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return screening, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	screening.Review = &domain.ScreeningReview{
+		Actor:   r.Actor,
+		Outcome: domain.ScreeningOutcome(r.Outcome),
+		Comment: r.Comment,
+		At:      r.CreatedAt,
+	}
+
+	return screening, nil
+}
+
+func review(bulkTransferID uint, actor string, outcome domain.ScreeningOutcome, comment string, at time.Time) bulktransferrepo.Review {
+	return bulktransferrepo.Review{
+		BulkTransferID: bulkTransferID,
+		Actor:          actor,
+		Outcome:        string(outcome),
+		Comment:        comment,
+		CreatedAt:      at,
+	}
+}
+
+func toMatches(matches []domain.ScreeningMatch) bulktransferrepo.MatchList {
+	res := bulktransferrepo.MatchList{}
+	for _, m := range matches {
+		res = append(res, bulktransferrepo.Match{
+			Index:       m.Index,
+			Field:       m.Field,
+			EntryID:     m.EntryID,
+			EntryName:   m.EntryName,
+			EntrySource: m.EntrySource,
+			Score:       m.Score,
+		})
+	}
+	return res
+}
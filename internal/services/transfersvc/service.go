@@ -1,68 +1,824 @@
 package transfersvc
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/fees"
 	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
 	"github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/screening"
+	"github.com/adrianoccosta/exercise-qonto/internal/services/webhooksvc"
 	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrInsufficientFunds is returned when the balance doesn't cover the bulk transfer
+	ErrInsufficientFunds = errors.New("Insufficient credits to complete the transfer")
+	// ErrLimitExceeded is returned when the bulk transfer breaches a transfer limit of the account
+	ErrLimitExceeded = errors.New("transfer limit exceeded")
+	// ErrUnsupportedCurrency is returned when a credit transfer isn't in the currency of the account
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	// ErrAccountNotFound is returned when the organization bank account doesn't exist
+	ErrAccountNotFound = errors.New("bank account not found")
+	// ErrBeneficiaryNotFound is returned when a credit transfer references a beneficiary the account doesn't have
+	ErrBeneficiaryNotFound = errors.New("beneficiary not found")
+	// ErrScreeningHit is returned when a counterparty matches the screening list
+	ErrScreeningHit = errors.New("counterparty matches the screening list")
+	// ErrDuplicateSuspected is returned when a credit transfer looks like a duplicate of a recent one
+	ErrDuplicateSuspected = errors.New("duplicate payment suspected")
+	// ErrNothingToExecute is returned when no credit transfer of a best effort bulk transfer can be executed
+	ErrNothingToExecute = errors.New("no credit transfer can be executed")
+	// ErrBulkTransferNotFound is returned when the bulk transfer doesn't exist
+	ErrBulkTransferNotFound = errors.New("bulk transfer not found")
+	// ErrScheduledExecutionFailed is returned when some of the due bulk transfers failed at their execution
+	ErrScheduledExecutionFailed = errors.New("scheduled bulk transfers failed")
+	// ErrNotCancellable is returned when the bulk transfer was executed or is being executed
+	ErrNotCancellable = errors.New("bulk transfer can't be cancelled")
+	// ErrLinesNotCancellable is returned when a line to cancel doesn't exist or is already cancelled
+	ErrLinesNotCancellable = errors.New("lines can't be cancelled")
 )
 
 // TransferService Interface for the transfer services
 type TransferService interface {
-	BulkTransfer(data domain.BulkTransfer) error
+	BulkTransfer(data domain.BulkTransfer) (domain.BulkTransferDetail, error)
+	Quote(data domain.BulkTransfer) (domain.BulkTransferQuote, error)
+	Read(bulkTransferID uint) (domain.BulkTransferDetail, error)
+	ReadByFilter(filters map[string]string) (domain.BulkTransferDetailList, error)
+	ExecuteDue() (int, error)
+	Cancel(bulkTransferID uint, data domain.BulkTransferCancellation, actor string) (domain.BulkTransferDetail, error)
+	Approve(bulkTransferID uint, data domain.BulkTransferApproval, actor string) (domain.BulkTransferDetail, error)
+	Reject(bulkTransferID uint, data domain.BulkTransferRejection, actor string) (domain.BulkTransferDetail, error)
+	Release(bulkTransferID uint, data domain.BulkTransferRelease, actor string) (domain.BulkTransferDetail, error)
+	Block(bulkTransferID uint, data domain.BulkTransferBlock, actor string) (domain.BulkTransferDetail, error)
+	Submit(data domain.BulkTransfer) (domain.BulkTransferJob, error)
+	ReadJob(jobID uint) (domain.BulkTransferJob, error)
+	PendingJobs() ([]uint, error)
+	RunJob(jobID uint) error
 }
 
-// New returns an instance of the back account services
-func New(transactionrepo transactionrepo.TransactionRepository, bankAccountRepo bankaccountrepo.BankAccountRepository, logger log.Logger) TransferService {
+// New returns an instance of the transfer services, the counterparties being screened by the screener. Credit
+// transfers paying what was paid within the duplicate window are suspected duplicates, a zero window disables
+// the detection. The credit transfers are charged the fees of the schedule.
+func New(unitOfWork uow.UnitOfWork, bulkTransferRepo bulktransferrepo.BulkTransferRepository, transactionrepo transactionrepo.TransactionRepository, screener screening.Screener, duplicateWindow time.Duration, feeSchedule fees.Schedule, clock tools.Clock, logger log.Logger) TransferService {
 	return service{
-		logger:          logger,
-		transactionrepo: transactionrepo,
-		bankAccountRepo: bankAccountRepo,
+		logger:           logger,
+		clock:            clock,
+		unitOfWork:       unitOfWork,
+		bulkTransferRepo: bulkTransferRepo,
+		transactionrepo:  transactionrepo,
+		screener:         screener,
+		duplicateWindow:  duplicateWindow,
+		feeSchedule:      feeSchedule,
+		progress:         &progress{done: map[uint]int{}},
 	}
 }
 
 type service struct {
-	logger          log.Logger
-	transactionrepo transactionrepo.TransactionRepository
-	bankAccountRepo bankaccountrepo.BankAccountRepository
+	logger           log.Logger
+	clock            tools.Clock
+	unitOfWork       uow.UnitOfWork
+	bulkTransferRepo bulktransferrepo.BulkTransferRepository
+	transactionrepo  transactionrepo.TransactionRepository
+	screener         screening.Screener
+	duplicateWindow  time.Duration
+	feeSchedule      fees.Schedule
+	progress         *progress
 }
 
-func (s service) BulkTransfer(data domain.BulkTransfer) error {
+// BulkTransfer stores the bulk transfer and its credit transfers. Bulk transfers above the approval threshold
+// of the organization account are left pending approval, their submitter being required, those with a future execution date are left scheduled,
+// the others are executed right away. Credit transfers referencing a beneficiary are stored as references, the
+// beneficiary is only resolved at the execution. A best effort bulk transfer executes what it can of its lines.
+func (s service) BulkTransfer(data domain.BulkTransfer) (domain.BulkTransferDetail, error) {
+	bulkTransfer, err := s.store(data, domain.BulkTransferReceived, nil)
+	if err != nil {
+		return domain.BulkTransferDetail{}, err
+	}
 
-	bankAccount, err := s.bankAccountRepo.ReadByIban(data.OrganizationIban)
+	if bulkTransfer.Status != string(domain.BulkTransferReceived) {
+		return toDetail(bulkTransfer, nil), nil
+	}
 
+	if err = s.updateStatus(s.bulkTransferRepo, &bulkTransfer, domain.BulkTransferProcessing, ""); err != nil {
+		return domain.BulkTransferDetail{}, err
+	}
+
+	return s.execute(bulkTransfer, data)
+}
+
+// store stores the bulk transfer and its credit transfers in the ready status, unless it is pending approval or
+// scheduled. The also function, when set, is run in the same unit of work once the bulk transfer is stored. Its
+// errors are marked as not applied, nothing being stored when it fails.
+func (s service) store(data domain.BulkTransfer, ready domain.BulkTransferStatus, also func(repos uow.Repositories, bulkTransfer bulktransferrepo.BulkTransfer) error) (bulktransferrepo.BulkTransfer, error) {
+	executionDate, err := data.ExecutionTime()
 	if err != nil {
+		return bulktransferrepo.BulkTransfer{}, domain.NotApplied(domain.AsRejection(err))
+	}
+
+	now := s.clock.Now().UTC()
+	bulkTransfer := bulktransferrepo.BulkTransfer{
+		OrganizationName: data.OrganizationName,
+		OrganizationIban: data.OrganizationIban,
+		OrganizationBic:  data.OrganizationBic,
+		TransfersCount:   len(data.CreditTransfers),
+		TotalCents:       linesTotal(data),
+		Status:           string(ready),
+		ExecutionDate:    executionDate,
+		TemplateID:       data.TemplateID,
+		SubmittedBy:      data.SubmittedBy,
+		AllowDuplicates:  data.AllowDuplicates,
+		ExecutionMode:    string(data.Mode()),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if executionDate.After(now) {
+		bulkTransfer.Status = string(domain.BulkTransferScheduled)
+	}
+
+	err = s.unitOfWork.Do(func(repos uow.Repositories) error {
+		required, err := requiredApprovals(repos, data.OrganizationIban, bulkTransfer.TotalCents)
+		if err != nil {
+			return err
+		}
+		if required > 0 && bulkTransfer.SubmittedBy == "" {
+			return ErrMissingSubmitter
+		}
+		if required > 0 {
+			bulkTransfer.Status = string(domain.BulkTransferPendingApproval)
+			bulkTransfer.RequiredApprovals = required
+		}
+
+		id, err := repos.BulkTransfer.Create(bulkTransfer)
+		if err != nil {
+			return err
+		}
+		bulkTransfer.ID = uint(id)
+
+		if err = repos.BulkTransfer.CreateLines(bulkTransfer.ID, toLines(data)); err != nil {
+			return err
+		}
+		if also == nil {
+			return nil
+		}
+		return also(repos, bulkTransfer)
+	})
+	if err != nil {
+		// nothing was stored
+		return bulktransferrepo.BulkTransfer{}, domain.NotApplied(err)
+	}
+
+	return bulkTransfer, nil
+}
+
+// ExecuteDue executes the scheduled bulk transfers that are due, returning how many were executed successfully.
+// Each bulk transfer is claimed before its execution so it is never executed twice, and its failure is recorded
+// on it; the failures are reported with ErrScheduledExecutionFailed once every due bulk transfer was run.
+func (s service) ExecuteDue() (int, error) {
+	due, err := s.bulkTransferRepo.ReadDue(s.clock.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	executed, failed := 0, 0
+	for _, bulkTransfer := range due {
+		bulkTransfer.Status = string(domain.BulkTransferProcessing)
+		bulkTransfer.UpdatedAt = s.clock.Now().UTC()
+		err = s.bulkTransferRepo.Transition(bulkTransfer, string(domain.BulkTransferScheduled))
+		if errors.Is(err, bulktransferrepo.ErrStatusConflict) {
+			// claimed by another scheduler or no longer scheduled
+			continue
+		}
+		if err != nil {
+			return executed, err
+		}
+
+		lines, err := s.bulkTransferRepo.ReadLines(bulkTransfer.ID)
+		if err != nil {
+			s.fail(&bulkTransfer, err)
+			return executed, err
+		}
+
+		if _, err = s.execute(bulkTransfer, fromLines(bulkTransfer, lines)); err != nil {
+			failed++
+			s.logger.WithError(err).Warn("scheduled bulk transfer failed", zap.Uint("bulk_transfer_id", bulkTransfer.ID))
+			continue
+		}
+		executed++
+	}
+
+	if failed > 0 {
+		return executed, fmt.Errorf("%w: %d of %d", ErrScheduledExecutionFailed, failed, executed+failed)
+	}
+	return executed, nil
+}
+
+// execute checks the bulk transfer being processed, then debits the organization account and registers
+// every credit transfer atomically. The stored bulk transfer ends up completed or failed with the reason,
+// or held for review when a counterparty matches the screening list and the screener holds them. A best
+// effort bulk transfer only fails when none of its lines can be executed, it ends up partially completed
+// when some of them weren't.
+func (s service) execute(bulkTransfer bulktransferrepo.BulkTransfer, data domain.BulkTransfer) (domain.BulkTransferDetail, error) {
+	return s.executeTracked(bulkTransfer, data, nil)
+}
+
+// executeTracked executes the bulk transfer, telling the tracker how many credit transfers were registered so far
+func (s service) executeTracked(bulkTransfer bulktransferrepo.BulkTransfer, data domain.BulkTransfer, tracker func(done int)) (domain.BulkTransferDetail, error) {
+	screener := s.screener
+	if bulkTransfer.ScreeningHits > 0 {
+		// it was held then released by a reviewer, its counterparties aren't screened again
+		screener = screening.Disabled{}
+	}
+
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		p, err := check(repos, screener, s.duplicateWindow, s.feeSchedule, data, s.clock.Now().UTC())
+		if err != nil {
+			return err
+		}
+		bulkTransfer.BankAccountID = p.bankAccount.ID
+
+		var sel selection
+		if data.Mode() == domain.ExecutionBestEffort {
+			if sel, err = selectLines(repos, p, data, s.clock.Now().UTC()); err != nil {
+				return err
+			}
+			if sel.blocking != nil {
+				return sel.blocking
+			}
+		} else if !p.executable() {
+			return p.problems[0]
+		}
+
+		if len(p.matches) > 0 && screener.Mode() == screening.ModeReview {
+			bulkTransfer.Status = string(domain.BulkTransferHeldForReview)
+			bulkTransfer.ScreeningHits = len(p.matches)
+			bulkTransfer.UpdatedAt = s.clock.Now().UTC()
+			return repos.BulkTransfer.Hold(bulkTransfer, toMatches(p.matches), string(domain.BulkTransferProcessing))
+		}
+
+		if data.Mode() == domain.ExecutionBestEffort {
+			return s.executeSelection(repos, &bulkTransfer, p, data, sel, tracker)
+		}
+
+		// the credit transfers referencing a beneficiary are paid with its details at the time of the execution
+		data.CreditTransfers = p.creditTransfers
+		if _, err = registerTransfers(repos, p.bankAccount, bulkTransfer, data, p.fees, tracker); err != nil {
+			return err
+		}
+
+		return s.settle(repos, &bulkTransfer, domain.BulkTransferCompleted, "")
+	})
+
+	if errors.Is(err, bulktransferrepo.ErrStatusConflict) {
+		// settled by another runner meanwhile, its outcome stands
+		return s.Read(bulkTransfer.ID)
+	}
+	if err != nil {
+		s.fail(&bulkTransfer, err)
+		return toDetail(bulkTransfer, nil), err
+	}
+
+	return s.Read(bulkTransfer.ID)
+}
+
+// fail flags the bulk transfer being processed as failed with the reason, unless another runner settled it meanwhile
+func (s service) fail(bulkTransfer *bulktransferrepo.BulkTransfer, reason error) {
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		return s.settle(repos, bulkTransfer, domain.BulkTransferFailed, reason.Error())
+	})
+	if errors.Is(err, bulktransferrepo.ErrStatusConflict) {
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("error flagging bulk transfer as failed", zap.Uint("bulk_transfer_id", bulkTransfer.ID))
+	}
+}
+
+// cancellable the statuses of the bulk transfers whose execution didn't start yet
+var cancellable = map[string]bool{
+	string(domain.BulkTransferScheduled):       true,
+	string(domain.BulkTransferQueued):          true,
+	string(domain.BulkTransferPendingApproval): true,
+	string(domain.BulkTransferHeldForReview):   true,
+}
+
+// Cancel cancels a bulk transfer whose execution didn't start yet, or only some of its lines, recording the reason
+// and the actor. Funds are only debited at execution so there is nothing to release on the bank account. Bulk
+// transfers that were executed, or are being executed, can't be cancelled. A bulk transfer pending approval whose
+// remaining lines no longer need the approvals it is waiting for is executed, or scheduled, right away.
+func (s service) Cancel(bulkTransferID uint, data domain.BulkTransferCancellation, actor string) (domain.BulkTransferDetail, error) {
+	now := s.clock.Now().UTC()
+	var bulkTransfer bulktransferrepo.BulkTransfer
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		var err error
+		bulkTransfer, err = repos.BulkTransfer.Read(bulkTransferID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrBulkTransferNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !cancellable[bulkTransfer.Status] {
+			return fmt.Errorf("%w: it is %s", ErrNotCancellable, bulkTransfer.Status)
+		}
+		from := bulkTransfer.Status
+
+		if len(data.LineIndexes) > 0 {
+			lines, err := repos.BulkTransfer.ReadLines(bulkTransferID)
+			if err != nil {
+				return err
+			}
+
+			cancelled, err := linesToCancel(lines, data, actor, now)
+			if err != nil {
+				return err
+			}
+			if err = repos.BulkTransfer.CancelLines(bulkTransferID, cancelled); err != nil {
+				return err
+			}
+
+			remaining := fromLines(bulkTransfer, lines)
+			if len(remaining.CreditTransfers) > 0 {
+				// the bulk transfer goes on with the remaining lines only
+				bulkTransfer.TransfersCount = len(remaining.CreditTransfers)
+				bulkTransfer.TotalCents = linesTotal(remaining)
+				bulkTransfer.UpdatedAt = now
+				if from == string(domain.BulkTransferPendingApproval) {
+					if err = reassessApproval(repos, &bulkTransfer, now); err != nil {
+						return err
+					}
+				}
+				return repos.BulkTransfer.Cancel(bulkTransfer, from)
+			}
+		}
+
+		bulkTransfer.Status = string(domain.BulkTransferCancelled)
+		bulkTransfer.CancellationReason = data.Reason
+		bulkTransfer.CancelledBy = actor
+		bulkTransfer.CancelledAt = now
+		bulkTransfer.UpdatedAt = now
+		return repos.BulkTransfer.Cancel(bulkTransfer, from)
+	})
+	if errors.Is(err, bulktransferrepo.ErrStatusConflict) {
+		// claimed by the scheduler or a worker, decided on, or cancelled by someone else, since it was read
+		return domain.BulkTransferDetail{}, fmt.Errorf("%w: it changed while being cancelled", ErrNotCancellable)
+	}
+	if err != nil {
+		return domain.BulkTransferDetail{}, err
+	}
+
+	if bulkTransfer.Status != string(domain.BulkTransferProcessing) {
+		return s.Read(bulkTransferID)
+	}
+
+	lines, err := s.bulkTransferRepo.ReadLines(bulkTransferID)
+	if err != nil {
+		s.fail(&bulkTransfer, err)
+		return domain.BulkTransferDetail{}, err
+	}
+
+	return s.execute(bulkTransfer, fromLines(bulkTransfer, lines))
+}
+
+// linesToCancel flags the lines at the indexes to cancel as cancelled, rejecting the indexes of unknown or
+// already cancelled lines. It returns the lines that were flagged.
+func linesToCancel(lines bulktransferrepo.LineList, data domain.BulkTransferCancellation, actor string, at time.Time) (bulktransferrepo.LineList, error) {
+	rejection := domain.NewRejection(domain.RejectionInvalidFields, ErrLinesNotCancellable)
+	cancelled := bulktransferrepo.LineList{}
+	for i, index := range data.LineIndexes {
+		field := fmt.Sprintf("line_indexes[%d]", i)
+		switch {
+		case index >= len(lines):
+			rejection.WithLineError(index, field, "exists", "no such line")
+		case !lines[index].CancelledAt.IsZero():
+			rejection.WithLineError(index, field, "cancelled", "is already cancelled")
+		default:
+			lines[index].CancellationReason = data.Reason
+			lines[index].CancelledBy = actor
+			lines[index].CancelledAt = at
+			cancelled = append(cancelled, lines[index])
+		}
+	}
+	if len(rejection.Errors) > 0 {
+		return nil, rejection
+	}
+	return cancelled, nil
+}
+
+// Quote runs every check of the bulk transfer without executing it, returning its total, its fees, the balance
+// after its execution and the problems that would reject it. The quote of a best effort bulk transfer reports
+// the outcome each line would have, its fees and its balance after being those of the lines it would execute.
+func (s service) Quote(data domain.BulkTransfer) (domain.BulkTransferQuote, error) {
+	var p plan
+	var sel *selection
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		var err error
+		now := s.clock.Now().UTC()
+		p, err = check(repos, s.screener, s.duplicateWindow, s.feeSchedule, data, now)
+		if err != nil || data.Mode() != domain.ExecutionBestEffort {
+			return err
+		}
+		selected, err := selectLines(repos, p, data, now)
+		sel = &selected
 		return err
+	})
+	if err != nil {
+		return domain.BulkTransferQuote{}, err
+	}
+
+	debitCents := p.totalCents + p.feesCents
+
+	quote := domain.BulkTransferQuote{
+		OrganizationIban: data.OrganizationIban,
+		TransfersCount:   len(data.CreditTransfers),
+		TotalAmount:      domain.NewMoney(p.totalCents, domain.AccountCurrency),
+		Executable:       p.executable(),
+		RequiresApproval: needsApproval(p.bankAccount, p.totalCents),
+		Duplicates:       p.duplicates,
+		Fees:             feeBreakdown(p.fees, p.feesCents),
+		Problems:         append([]*domain.Rejection{}, p.problems...),
+	}
+	if sel != nil {
+		quote.Executable = sel.blocking == nil
+		quote.Fees = feeBreakdown(sel.fees, sel.feesCents)
+		debitCents = sel.totalCents + sel.feesCents
+		if sel.blocking == nil {
+			quote.Report = domain.NewExecutionReport(sel.outcomes)
+		}
+	}
+	if s.screener.Mode() == screening.ModeReview {
+		quote.RequiresReview = len(p.matches) > 0
+		quote.ScreeningMatches = p.matches
+	}
+	if p.bankAccount.ID != 0 {
+		balance := domain.NewMoney(p.bankAccount.BalanceCents, domain.AccountCurrency)
+		balanceAfter := domain.NewMoney(p.bankAccount.BalanceCents-debitCents, domain.AccountCurrency)
+		quote.Balance = &balance
+		quote.BalanceAfter = &balanceAfter
+		if overdrawn := domain.OverdrawnCents(balanceAfter.MinorUnits, debitCents); overdrawn > 0 {
+			overdrawnAmount := domain.NewMoney(overdrawn, domain.AccountCurrency)
+			quote.OverdrawnAmount = &overdrawnAmount
+		}
+	}
+
+	return quote, nil
+}
+
+// Read a bulk transfer, its transactions and their fees, its cancelled lines, its approval decisions, its
+// screening matches and the outcome of its lines when it was executed best effort
+func (s service) Read(bulkTransferID uint) (domain.BulkTransferDetail, error) {
+	bulkTransfer, err := s.bulkTransferRepo.Read(bulkTransferID)
+	if err != nil {
+		return domain.BulkTransferDetail{}, err
 	}
 
-	var totalAmount float64 = 0
-	for _, creditTransfer := range data.CreditTransfers {
-		totalAmount += creditTransfer.Amount
+	lines, err := s.bulkTransferRepo.ReadLines(bulkTransferID)
+	if err != nil {
+		return domain.BulkTransferDetail{}, err
 	}
 
-	if bankAccount.BalanceCents < int(totalAmount*100) {
-		return errors.New("Insufficient credits to complete the transfer")
+	transactions, err := s.transactionrepo.ReadByFilter(map[string]string{
+		"bulk_transfer_id": strconv.FormatUint(uint64(bulkTransferID), 10),
+		"direction":        string(domain.TransactionOutgoing),
+	})
+	if err != nil {
+		return domain.BulkTransferDetail{}, err
 	}
 
-	bankAccount.BalanceCents -= int(totalAmount * 100)
+	var report *domain.ExecutionReport
+	var lineOf map[uint]int
+	if bulkTransfer.ExecutionMode == string(domain.ExecutionBestEffort) {
+		if report, lineOf, err = s.readReport(bulkTransferID); err != nil {
+			return domain.BulkTransferDetail{}, err
+		}
+	}
 
-	go s.registerTransfers(bankAccount, data)
+	transactions, breakdown := splitFees(transactions, lineOf)
+	detail := toDetail(bulkTransfer, transactions)
+	detail.Fees = breakdown
+	detail.Report = report
+	for _, line := range lines {
+		if !line.CancelledAt.IsZero() {
+			index := line.Index
+			detail.CancelledLines = append(detail.CancelledLines, cancellation(&index, line.CancellationReason, line.CancelledBy, line.CancelledAt))
+		}
+	}
 
-	return nil
+	if detail.Approval != nil {
+		decisions, err := s.bulkTransferRepo.ReadDecisions(bulkTransferID)
+		if err != nil {
+			return domain.BulkTransferDetail{}, err
+		}
+		detail.Approval.Decisions = toDecisions(decisions)
+	}
+
+	if bulkTransfer.ScreeningHits > 0 {
+		if detail.Screening, err = s.readScreening(bulkTransferID); err != nil {
+			return domain.BulkTransferDetail{}, err
+		}
+	}
+
+	return detail, nil
+}
+
+// ReadByFilter list of bulk transfers, without their transactions
+func (s service) ReadByFilter(filters map[string]string) (domain.BulkTransferDetailList, error) {
+	bulkTransfers, err := s.bulkTransferRepo.ReadByFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	res := domain.BulkTransferDetailList{}
+	for _, bulkTransfer := range bulkTransfers {
+		res = append(res, toDetail(bulkTransfer, nil))
+	}
+
+	return res, nil
 }
 
-func (s service) registerTransfers(bankAccount bankaccountrepo.BankAccount, data domain.BulkTransfer) {
-	s.bankAccountRepo.Update(bankAccount)
-	for _, creditTransfer := range data.CreditTransfers {
-		s.transactionrepo.Create(transactionrepo.Transaction{
+func (s service) updateStatus(repo bulktransferrepo.BulkTransferRepository, bulkTransfer *bulktransferrepo.BulkTransfer, status domain.BulkTransferStatus, reason string) error {
+	bulkTransfer.Status = string(status)
+	bulkTransfer.FailureReason = reason
+	bulkTransfer.UpdatedAt = s.clock.Now().UTC()
+
+	return repo.Update(*bulkTransfer)
+}
+
+// settle moves the bulk transfer being processed to the status ending its execution, notifying the webhooks of its
+// organization. ErrStatusConflict is returned when it is no longer processing, another runner having settled it
+// meanwhile, so that the unit of work is rolled back along with the debit it made.
+func (s service) settle(repos uow.Repositories, bulkTransfer *bulktransferrepo.BulkTransfer, status domain.BulkTransferStatus, reason string) error {
+	bulkTransfer.Status = string(status)
+	bulkTransfer.FailureReason = reason
+	bulkTransfer.UpdatedAt = s.clock.Now().UTC()
+	if err := repos.BulkTransfer.Transition(*bulkTransfer, string(domain.BulkTransferProcessing)); err != nil {
+		return err
+	}
+
+	return webhooksvc.PublishBulkTransfer(repos.Webhook, *bulkTransfer)
+}
+
+// registerTransfers debits the organization account and registers the outgoing transaction of every credit
+// transfer, tagged with the part of it paid from the overdraft, followed by the outgoing transaction of its fee.
+// Counterparties holding a local bank account are credited in the same unit of work, with an incoming transaction
+// on their side, so transfers between local accounts settle instantly. The movements are posted on the ledger as
+// a single journal, the fees being credited to the fees account. It returns the transaction of every credit transfer,
+// telling the tracker, when set, how many were registered so far.
+func registerTransfers(repos uow.Repositories, bankAccount bankaccountrepo.BankAccount, bulkTransfer bulktransferrepo.BulkTransfer, data domain.BulkTransfer, charged []domain.Fee, tracker func(done int)) ([]uint, error) {
+	feeOf := map[int]domain.Fee{}
+	var feesCents int64 = 0
+	for _, fee := range charged {
+		feeOf[fee.Index] = fee
+		feesCents += fee.Amount.MinorUnits
+	}
+
+	debitCents := linesTotal(data) + feesCents
+	balanceCents, err := repos.BankAccount.Debit(bankAccount.ID, debitCents)
+	if errors.Is(err, bankaccountrepo.ErrInsufficientFunds) {
+		// the balance was drained by a concurrent debit since it was read, so it isn't reported
+		return nil, domain.NewInsufficientFundsRejection(ErrInsufficientFunds, domain.NewMoney(debitCents, domain.AccountCurrency), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the credit transfers and their fees are paid in their order, from the balance before the debit
+	balanceCents += debitCents
+	var ids []uint
+	entries := ledgerrepo.EntryList{}
+	for i, creditTransfer := range data.CreditTransfers {
+		balanceCents -= creditTransfer.Amount.MinorUnits
+		id, err := createTransaction(repos, bankAccount, transactionrepo.Transaction{
 			CounterPartyName: creditTransfer.CounterPartyName,
 			CounterPartyIban: creditTransfer.CounterPartyIban,
 			CounterPartyBic:  creditTransfer.CounterPartyBic,
-			AmountCents:      int(creditTransfer.Amount * 100),
+			AmountCents:      creditTransfer.Amount.MinorUnits,
 			AmountCurrency:   creditTransfer.Currency,
 			BankAccountID:    bankAccount.ID,
 			Description:      creditTransfer.Description,
+			Direction:        string(domain.TransactionOutgoing),
+			BulkTransferID:   bulkTransfer.ID,
+			BeneficiaryID:    creditTransfer.BeneficiaryID,
+			OverdrawnCents:   domain.OverdrawnCents(balanceCents, creditTransfer.Amount.MinorUnits),
+			CreatedAt:        bulkTransfer.UpdatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		credit, err := creditCounterparty(repos, bankAccount, bulkTransfer, creditTransfer)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, uint(id))
+		if tracker != nil {
+			tracker(len(ids))
+		}
+		if credit.TransactionID == 0 {
+			credit.TransactionID = uint(id)
+		}
+		entries = append(entries, ledgerrepo.Debit(ledgerrepo.BankAccount(bankAccount.ID), creditTransfer.Amount.MinorUnits, uint(id)), credit)
+
+		fee, ok := feeOf[i]
+		if !ok {
+			continue
+		}
+		balanceCents -= fee.Amount.MinorUnits
+		feeID, err := createTransaction(repos, bankAccount, transactionrepo.Transaction{
+			CounterPartyName:   ledgerrepo.AccountFees,
+			AmountCents:        fee.Amount.MinorUnits,
+			AmountCurrency:     domain.AccountCurrency,
+			BankAccountID:      bankAccount.ID,
+			Description:        fee.Rule,
+			Direction:          string(domain.TransactionOutgoing),
+			BulkTransferID:     bulkTransfer.ID,
+			OverdrawnCents:     domain.OverdrawnCents(balanceCents, fee.Amount.MinorUnits),
+			FeeOfTransactionID: uint(id),
+			CreatedAt:          bulkTransfer.UpdatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries,
+			ledgerrepo.Debit(ledgerrepo.BankAccount(bankAccount.ID), fee.Amount.MinorUnits, uint(feeID)),
+			ledgerrepo.Credit(ledgerrepo.AccountFees, fee.Amount.MinorUnits, uint(feeID)))
+	}
+
+	_, err = repos.Ledger.Post(ledgerrepo.Journal{
+		Operation: string(domain.LedgerBulkTransfer),
+		Reference: fmt.Sprintf("bulk_transfer:%d", bulkTransfer.ID),
+		CreatedAt: bulkTransfer.UpdatedAt,
+	}, entries)
+	return ids, err
+}
+
+// creditCounterparty credits the counterparty of the credit transfer when it holds a local bank account,
+// returning the ledger entry of the credit. The funds sent to other banks are credited to the external account.
+func creditCounterparty(repos uow.Repositories, bankAccount bankaccountrepo.BankAccount, bulkTransfer bulktransferrepo.BulkTransfer, creditTransfer domain.CreditTransfer) (ledgerrepo.Entry, error) {
+	receiver, err := repos.BankAccount.ReadByIban(creditTransfer.CounterPartyIban)
+	if errors.Is(err, sql.ErrNoRows) {
+		// held by another bank
+		return ledgerrepo.Credit(ledgerrepo.AccountExternal, creditTransfer.Amount.MinorUnits, 0), nil
+	}
+	if err != nil {
+		return ledgerrepo.Entry{}, err
+	}
+
+	if err = repos.BankAccount.Credit(receiver.ID, creditTransfer.Amount.MinorUnits); err != nil {
+		return ledgerrepo.Entry{}, err
+	}
+
+	id, err := createTransaction(repos, receiver, transactionrepo.Transaction{
+		CounterPartyName: bankAccount.OrganizationName,
+		CounterPartyIban: bankAccount.Iban,
+		CounterPartyBic:  bankAccount.Bic,
+		AmountCents:      creditTransfer.Amount.MinorUnits,
+		AmountCurrency:   creditTransfer.Currency,
+		BankAccountID:    receiver.ID,
+		Description:      creditTransfer.Description,
+		Direction:        string(domain.TransactionIncoming),
+		BulkTransferID:   bulkTransfer.ID,
+		CreatedAt:        bulkTransfer.UpdatedAt,
+	})
+	if err != nil {
+		return ledgerrepo.Entry{}, err
+	}
+
+	return ledgerrepo.Credit(ledgerrepo.BankAccount(receiver.ID), creditTransfer.Amount.MinorUnits, uint(id)), nil
+}
+
+// createTransaction registers the transaction on the bank account, notifying the webhooks of the account
+func createTransaction(repos uow.Repositories, bankAccount bankaccountrepo.BankAccount, transaction transactionrepo.Transaction) (int, error) {
+	id, err := repos.Transaction.Create(transaction)
+	if err != nil {
+		return 0, err
+	}
+	transaction.ID = uint(id)
+
+	return id, webhooksvc.PublishTransaction(repos.Webhook, bankAccount, transaction)
+}
+
+func toDetail(bulkTransfer bulktransferrepo.BulkTransfer, transactions domain.TransactionList) domain.BulkTransferDetail {
+	detail := domain.BulkTransferDetail{
+		ID:               bulkTransfer.ID,
+		OrganizationName: bulkTransfer.OrganizationName,
+		OrganizationBic:  bulkTransfer.OrganizationBic,
+		OrganizationIban: bulkTransfer.OrganizationIban,
+		TransfersCount:   bulkTransfer.TransfersCount,
+		TotalAmount:      domain.NewMoney(bulkTransfer.TotalCents, domain.AccountCurrency),
+		Status:           domain.BulkTransferStatus(bulkTransfer.Status),
+		FailureReason:    bulkTransfer.FailureReason,
+		ExecutionDate:    executionDate(bulkTransfer),
+		TemplateID:       bulkTransfer.TemplateID,
+		SubmittedBy:      bulkTransfer.SubmittedBy,
+		AllowDuplicates:  bulkTransfer.AllowDuplicates,
+		ExecutionMode:    domain.ExecutionMode(bulkTransfer.ExecutionMode),
+		CreatedAt:        bulkTransfer.CreatedAt,
+		UpdatedAt:        bulkTransfer.UpdatedAt,
+		Transactions:     transactions,
+	}
+	if bulkTransfer.RequiredApprovals > 0 {
+		detail.Approval = &domain.Approval{RequiredApprovals: bulkTransfer.RequiredApprovals, Decisions: []domain.Decision{}}
+	}
+	if !bulkTransfer.CancelledAt.IsZero() {
+		c := cancellation(nil, bulkTransfer.CancellationReason, bulkTransfer.CancelledBy, bulkTransfer.CancelledAt)
+		detail.Cancellation = &c
+	}
+	return detail
+}
+
+// splitFees separates the fee transactions of a bulk transfer from the transactions of its credit transfers, the
+// fee of the credit transfer at Index following its transaction. The description of a fee is its rule. The index
+// of a credit transfer is the position of its transaction unless lineOf maps the transaction to its line.
+func splitFees(transactions domain.TransactionList, lineOf map[uint]int) (domain.TransactionList, *domain.FeeBreakdown) {
+	var transfers domain.TransactionList
+	var charged []domain.Fee
+	var totalCents int64 = 0
+	indexOf := map[uint]int{}
+	for _, transaction := range transactions {
+		if transaction.FeeOfTransactionID == 0 {
+			indexOf[transaction.ID] = len(transfers)
+			if line, ok := lineOf[transaction.ID]; ok {
+				indexOf[transaction.ID] = line
+			}
+			transfers = append(transfers, transaction)
+			continue
+		}
+		charged = append(charged, domain.Fee{
+			Index:         indexOf[transaction.FeeOfTransactionID],
+			Rule:          transaction.Description,
+			Amount:        transaction.Amount,
+			TransactionID: transaction.ID,
+		})
+		totalCents += transaction.Amount.MinorUnits
+	}
+	return transfers, feeBreakdown(charged, totalCents)
+}
+
+func cancellation(index *int, reason, actor string, at time.Time) domain.Cancellation {
+	return domain.Cancellation{Index: index, Reason: reason, Actor: actor, At: at}
+}
+
+func executionDate(bulkTransfer bulktransferrepo.BulkTransfer) string {
+	if bulkTransfer.ExecutionDate.IsZero() {
+		return ""
+	}
+	return bulkTransfer.ExecutionDate.Format(domain.ExecutionDateLayout)
+}
+
+// toLines the credit transfers of the bulk transfer to be stored
+func toLines(data domain.BulkTransfer) bulktransferrepo.LineList {
+	lines := bulktransferrepo.LineList{}
+	for i, creditTransfer := range data.CreditTransfers {
+		lines = append(lines, bulktransferrepo.Line{
+			Index:            i,
+			CounterPartyName: creditTransfer.CounterPartyName,
+			CounterPartyIban: creditTransfer.CounterPartyIban,
+			CounterPartyBic:  creditTransfer.CounterPartyBic,
+			AmountCents:      creditTransfer.Amount.MinorUnits,
+			AmountCurrency:   creditTransfer.Currency,
+			Description:      creditTransfer.Description,
+			BeneficiaryID:    creditTransfer.BeneficiaryID,
+			Priority:         creditTransfer.Priority,
+		})
+	}
+	return lines
+}
+
+// fromLines rebuilds the bulk transfer request from what was stored, leaving out the cancelled lines
+func fromLines(bulkTransfer bulktransferrepo.BulkTransfer, lines bulktransferrepo.LineList) domain.BulkTransfer {
+	data := domain.BulkTransfer{
+		OrganizationName: bulkTransfer.OrganizationName,
+		OrganizationBic:  bulkTransfer.OrganizationBic,
+		OrganizationIban: bulkTransfer.OrganizationIban,
+		ExecutionDate:    executionDate(bulkTransfer),
+		AllowDuplicates:  bulkTransfer.AllowDuplicates,
+		ExecutionMode:    domain.ExecutionMode(bulkTransfer.ExecutionMode),
+		TemplateID:       bulkTransfer.TemplateID,
+		SubmittedBy:      bulkTransfer.SubmittedBy,
+	}
+	for _, line := range lines {
+		if !line.CancelledAt.IsZero() {
+			continue
+		}
+		data.CreditTransfers = append(data.CreditTransfers, domain.CreditTransfer{
+			Amount:           domain.NewMoney(line.AmountCents, line.AmountCurrency),
+			Currency:         line.AmountCurrency,
+			CounterPartyName: line.CounterPartyName,
+			CounterPartyBic:  line.CounterPartyBic,
+			CounterPartyIban: line.CounterPartyIban,
+			Description:      line.Description,
+			BeneficiaryID:    line.BeneficiaryID,
+			Priority:         line.Priority,
 		})
 	}
+	return data
 }
@@ -1,14 +1,32 @@
 package transfersvc
 
 import (
+	"database/sql"
 	"errors"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
 	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/fees"
 	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/beneficiaryrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/webhookrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/screening"
 	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
 	"github.com/adrianoccosta/exercise-qonto/test/mocks/repository"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/screening"
+	"github.com/adrianoccosta/exercise-qonto/tools"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestTransferService(t *testing.T) {
@@ -17,75 +35,276 @@ func TestTransferService(t *testing.T) {
 
 	repoMockTransaction := mockrepository.NewMockTransactionRepository(ctrl)
 	repoMockBankAccount := mockrepository.NewMockBankAccountRepository(ctrl)
+	repoMockBulkTransfer := mockrepository.NewMockBulkTransferRepository(ctrl)
+	repoMockLedger := mockrepository.NewMockLedgerRepository(ctrl)
+	repoMockBeneficiary := mockrepository.NewMockBeneficiaryRepository(ctrl)
+	repoMockWebhook := mockrepository.NewMockWebhookRepository(ctrl)
 	logMock := mocklog.NewMockLogger(ctrl)
+	uowMock := mockrepository.NewMockUnitOfWork(ctrl)
+	uowMock.EXPECT().
+		Do(gomock.Any()).
+		DoAndReturn(func(fn func(repos uow.Repositories) error) error {
+			return fn(uow.Repositories{BankAccount: repoMockBankAccount, Transaction: repoMockTransaction, BulkTransfer: repoMockBulkTransfer, Ledger: repoMockLedger, Beneficiary: repoMockBeneficiary, Webhook: repoMockWebhook})
+		}).
+		AnyTimes()
+	repoMockWebhook.EXPECT().CreateDeliveries(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	now := time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC)
+	clock := tools.ClockFunc(func() time.Time { return now })
 
 	bankAccountRepo := bankaccountrepo.BankAccount{
 		ID:               1,
 		OrganizationName: "ACME Corp",
 		BalanceCents:     1460,
-		Iban:             "FR10474608000002006107XXXXX",
+		Iban:             "FR81474608000002006107XXXXX",
 		Bic:              "OIVUSCLQXXX",
 	}
 
 	bulkTransfer := domain.BulkTransfer{
 		OrganizationName: "ACME Corp",
 		OrganizationBic:  "OIVUSCLQXXX",
-		OrganizationIban: "FR10474608000002006107XXXXX",
+		OrganizationIban: "FR81474608000002006107XXXXX",
 		CreditTransfers: []domain.CreditTransfer{
 			{
-				Amount:           14.53,
+				Amount:           domain.NewMoney(1453, "EUR"),
 				Currency:         "EUR",
 				CounterPartyName: "Bip Bip",
 				CounterPartyBic:  "CRLYFRPPTOU",
-				CounterPartyIban: "EE383680981021245685",
+				CounterPartyIban: "EE303680981021245685",
 				Description:      "Wonderland/4410",
 			},
 		},
 	}
 
+	// expectStored expects the bulk transfer to be stored, below the approval threshold, and moved to
+	// processing, returning the statuses it is moved to afterwards
+	expectStored := func() *[]string {
+		var statuses []string
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankAccountRepo, nil)
+		repoMockBulkTransfer.EXPECT().
+			Create(gomock.Any()).
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer) (int, error) {
+				assert.Equal(t, string(domain.BulkTransferReceived), data.Status)
+				assert.Equal(t, 1, data.TransfersCount)
+				assert.Equal(t, int64(1453), data.TotalCents)
+				return 3, nil
+			})
+		repoMockBulkTransfer.EXPECT().
+			CreateLines(uint(3), gomock.Len(1)).
+			Return(nil)
+		repoMockBulkTransfer.EXPECT().
+			Update(gomock.Any()).
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer) error {
+				assert.Equal(t, uint(3), data.ID)
+				statuses = append(statuses, data.Status)
+				return nil
+			})
+		repoMockBulkTransfer.EXPECT().
+			Transition(gomock.Any(), "processing").
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer, from string) error {
+				assert.Equal(t, uint(3), data.ID)
+				statuses = append(statuses, data.Status)
+				return nil
+			})
+		return &statuses
+	}
+
 	t.Run("Test BulkTransfer return success", func(t *testing.T) {
+		statuses := expectStored()
 		repoMockBankAccount.EXPECT().
-			ReadByIban("FR10474608000002006107XXXXX").
+			ReadByIban("FR81474608000002006107XXXXX").
 			Return(bankAccountRepo, nil)
 		repoMockTransaction.EXPECT().
 			Create(gomock.Any()).
-			Times(1).
-			Return(1, nil)
-		repoMockBankAccount.EXPECT().
-			Update(bankaccountrepo.BankAccount{
-				ID:               1,
-				OrganizationName: "ACME Corp",
-				BalanceCents:     7,
-				Iban:             "FR10474608000002006107XXXXX",
-				Bic:              "OIVUSCLQXXX",
+			DoAndReturn(func(data transactionrepo.Transaction) (int, error) {
+				assert.Equal(t, uint(3), data.BulkTransferID)
+				assert.Equal(t, int64(1453), data.AmountCents)
+				return 1, nil
 			}).
+			Times(1)
+		repoMockBankAccount.EXPECT().
+			Debit(uint(1), int64(1453)).
 			Times(1).
-			Return(nil)
+			Return(int64(10000), nil)
+		repoMockBankAccount.EXPECT().ReadByIban("EE303680981021245685").Return(bankaccountrepo.BankAccount{}, sql.ErrNoRows)
+		repoMockLedger.EXPECT().Post(gomock.Any(), gomock.Len(2)).Return(1, nil)
+		repoMockBulkTransfer.EXPECT().
+			Read(uint(3)).
+			Return(bulktransferrepo.BulkTransfer{ID: 3, Status: string(domain.BulkTransferCompleted), TotalCents: 1453, TransfersCount: 1}, nil)
+		repoMockBulkTransfer.EXPECT().ReadLines(uint(3)).Return(bulktransferrepo.LineList{}, nil)
+		repoMockTransaction.EXPECT().
+			ReadByFilter(map[string]string{"bulk_transfer_id": "3", "direction": "outgoing"}).
+			Return(domain.TransactionList{{ID: 1, BulkTransferID: 3}}, nil)
 
-		svc := New(repoMockTransaction, repoMockBankAccount, logMock)
-		err := svc.BulkTransfer(bulkTransfer)
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.BulkTransfer(bulkTransfer)
 
 		assert.Nil(t, err)
+		assert.Equal(t, uint(3), res.ID)
+		assert.Equal(t, domain.BulkTransferCompleted, res.Status)
+		assert.Equal(t, domain.NewMoney(1453, "EUR"), res.TotalAmount)
+		assert.Len(t, res.Transactions, 1)
+		assert.Equal(t, []string{"processing", "completed"}, *statuses)
+	})
+
+	t.Run("Test BulkTransfer credits the counterparties holding a local bank account", func(t *testing.T) {
+		statuses := expectStored()
+		receiver := bankaccountrepo.BankAccount{ID: 2, OrganizationName: "Bip Bip", Iban: "EE303680981021245685", Bic: "CRLYFRPPTOU"}
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(bankAccountRepo, nil)
+		repoMockBankAccount.EXPECT().Debit(uint(1), int64(1453)).Return(int64(10000), nil)
+		repoMockBankAccount.EXPECT().ReadByIban("EE303680981021245685").Return(receiver, nil)
+		repoMockBankAccount.EXPECT().Credit(uint(2), int64(1453)).Return(nil)
+		repoMockLedger.EXPECT().
+			Post(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(journal ledgerrepo.Journal, entries ledgerrepo.EntryList) (int, error) {
+				assert.Equal(t, "bulk_transfer", journal.Operation)
+				assert.Equal(t, "bulk_transfer:3", journal.Reference)
+				assert.Equal(t, ledgerrepo.EntryList{ledgerrepo.Debit("bank_account:1", 1453, 1), ledgerrepo.Credit("bank_account:2", 1453, 2)}, entries)
+				return 1, nil
+			})
+		var directions []string
+		repoMockTransaction.EXPECT().
+			Create(gomock.Any()).
+			DoAndReturn(func(data transactionrepo.Transaction) (int, error) {
+				directions = append(directions, data.Direction)
+				if data.Direction == string(domain.TransactionIncoming) {
+					assert.Equal(t, uint(2), data.BankAccountID)
+					assert.Equal(t, "FR81474608000002006107XXXXX", data.CounterPartyIban)
+					assert.Equal(t, "ACME Corp", data.CounterPartyName)
+				}
+				return len(directions), nil
+			}).
+			Times(2)
+		repoMockBulkTransfer.EXPECT().Read(uint(3)).Return(bulktransferrepo.BulkTransfer{ID: 3, Status: string(domain.BulkTransferCompleted)}, nil)
+		repoMockBulkTransfer.EXPECT().ReadLines(uint(3)).Return(bulktransferrepo.LineList{}, nil)
+		repoMockTransaction.EXPECT().ReadByFilter(gomock.Any()).Return(domain.TransactionList{}, nil)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.BulkTransfer(bulkTransfer)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"outgoing", "incoming"}, directions)
+		assert.Equal(t, []string{"processing", "completed"}, *statuses)
+	})
+
+	t.Run("Test BulkTransfer pays the beneficiary referenced by a credit transfer", func(t *testing.T) {
+		statuses := expectStored()
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankAccountRepo, nil)
+		repoMockBeneficiary.EXPECT().
+			Read(uint(5)).
+			Return(beneficiaryrepo.Beneficiary{ID: 5, BankAccountID: 1, OrganizationIban: "FR81474608000002006107XXXXX", Name: "Bip Bip", Iban: "EE303680981021245685", Bic: "CRLYFRPPTOU"}, nil)
+		repoMockBankAccount.EXPECT().Debit(uint(1), int64(1453)).Return(int64(10000), nil)
+		repoMockTransaction.EXPECT().
+			Create(gomock.Any()).
+			DoAndReturn(func(data transactionrepo.Transaction) (int, error) {
+				assert.Equal(t, "Bip Bip", data.CounterPartyName)
+				assert.Equal(t, "EE303680981021245685", data.CounterPartyIban)
+				assert.Equal(t, "CRLYFRPPTOU", data.CounterPartyBic)
+				assert.Equal(t, uint(5), data.BeneficiaryID)
+				return 1, nil
+			})
+		repoMockBankAccount.EXPECT().ReadByIban("EE303680981021245685").Return(bankaccountrepo.BankAccount{}, sql.ErrNoRows)
+		repoMockLedger.EXPECT().Post(gomock.Any(), gomock.Len(2)).Return(1, nil)
+		repoMockBulkTransfer.EXPECT().
+			Read(uint(3)).
+			Return(bulktransferrepo.BulkTransfer{ID: 3, Status: string(domain.BulkTransferCompleted), TotalCents: 1453, TransfersCount: 1}, nil)
+		repoMockBulkTransfer.EXPECT().ReadLines(uint(3)).Return(bulktransferrepo.LineList{}, nil)
+		repoMockTransaction.EXPECT().
+			ReadByFilter(map[string]string{"bulk_transfer_id": "3", "direction": "outgoing"}).
+			Return(domain.TransactionList{{ID: 1, BulkTransferID: 3, BeneficiaryID: 5, FirstPayment: true}}, nil)
+
+		referencing := bulkTransfer
+		referencing.CreditTransfers = []domain.CreditTransfer{{Amount: domain.NewMoney(1453, "EUR"), Currency: "EUR", BeneficiaryID: 5}}
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.BulkTransfer(referencing)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferCompleted, res.Status)
+		assert.True(t, res.Transactions[0].FirstPayment)
+		assert.Equal(t, []string{"processing", "completed"}, *statuses)
+	})
+
+	t.Run("Test BulkTransfer return error when the local counterparty cannot be credited", func(t *testing.T) {
+		statuses := expectStored()
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(bankAccountRepo, nil)
+		repoMockBankAccount.EXPECT().Debit(uint(1), int64(1453)).Return(int64(10000), nil)
+		repoMockTransaction.EXPECT().Create(gomock.Any()).Return(1, nil)
+		repoMockBankAccount.EXPECT().ReadByIban("EE303680981021245685").Return(bankaccountrepo.BankAccount{ID: 2}, nil)
+		repoMockBankAccount.EXPECT().Credit(uint(2), int64(1453)).Return(errors.New("error"))
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.BulkTransfer(bulkTransfer)
+
+		assert.Error(t, err)
+		assert.Equal(t, []string{"processing", "failed"}, *statuses)
+	})
+
+	t.Run("Test BulkTransfer return error when a credit transfer is not in the account currency", func(t *testing.T) {
+		var statuses []string
+		repoMockBulkTransfer.EXPECT().Create(gomock.Any()).Return(3, nil)
+		repoMockBulkTransfer.EXPECT().CreateLines(uint(3), gomock.Any()).Return(nil)
+		repoMockBulkTransfer.EXPECT().
+			Update(gomock.Any()).
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer) error {
+				statuses = append(statuses, data.Status)
+				return nil
+			})
+		repoMockBulkTransfer.EXPECT().
+			Transition(gomock.Any(), "processing").
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer, from string) error {
+				statuses = append(statuses, data.Status)
+				return nil
+			})
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankAccountRepo, nil).
+			Times(2)
+		repoMockBankAccount.EXPECT().Debit(gomock.Any(), gomock.Any()).Times(0)
+
+		foreign := bulkTransfer
+		foreign.CreditTransfers = []domain.CreditTransfer{bulkTransfer.CreditTransfers[0]}
+		foreign.CreditTransfers[0].Currency = "USD"
+		foreign.CreditTransfers[0].Amount = domain.NewMoney(1453, "USD")
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.BulkTransfer(foreign)
+
+		var rejection *domain.Rejection
+		assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+		assert.ErrorAs(t, err, &rejection)
+		assert.Len(t, rejection.Errors, 1)
+		assert.Equal(t, 0, *rejection.Errors[0].Index)
+		assert.Equal(t, "currency", rejection.Errors[0].Field)
+		assert.Equal(t, []string{"processing", "failed"}, statuses)
 	})
 
 	t.Run("Test BulkTransfer return error when user not found", func(t *testing.T) {
+		statuses := expectStored()
 		repoMockBankAccount.EXPECT().
 			ReadByIban(gomock.Any()).
 			Return(bankaccountrepo.BankAccount{}, errors.New("error"))
 
-		svc := New(repoMockTransaction, repoMockBankAccount, logMock)
-		err := svc.BulkTransfer(bulkTransfer)
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.BulkTransfer(bulkTransfer)
 
 		assert.Error(t, err)
+		assert.Equal(t, domain.BulkTransferFailed, res.Status)
+		assert.Equal(t, []string{"processing", "failed"}, *statuses)
 	})
 
 	t.Run("Test BulkTransfer return error when funds are not enough", func(t *testing.T) {
+		statuses := expectStored()
 
 		bankAccountRepoLowBudget := bankaccountrepo.BankAccount{
 			ID:               1,
 			OrganizationName: "ACME Corp",
 			BalanceCents:     1450,
-			Iban:             "FR10474608000002006107XXXXX",
+			Iban:             "FR81474608000002006107XXXXX",
 			Bic:              "OIVUSCLQXXX",
 		}
 
@@ -93,9 +312,2349 @@ func TestTransferService(t *testing.T) {
 			ReadByIban(gomock.Any()).
 			Return(bankAccountRepoLowBudget, nil)
 
-		svc := New(repoMockTransaction, repoMockBankAccount, logMock)
-		err := svc.BulkTransfer(bulkTransfer)
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.BulkTransfer(bulkTransfer)
+
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		assert.Equal(t, ErrInsufficientFunds.Error(), res.FailureReason)
+		assert.Equal(t, []string{"processing", "failed"}, *statuses)
+
+		var rejection *domain.Rejection
+		assert.ErrorAs(t, err, &rejection)
+		assert.Equal(t, domain.RejectionInsufficientFunds, rejection.Reason)
+		assert.Equal(t, domain.NewMoney(1453, "EUR"), *rejection.RequiredAmount)
+		assert.Equal(t, domain.NewMoney(1450, "EUR"), *rejection.AvailableAmount)
+	})
+
+	t.Run("Test BulkTransfer return error when a transfer limit is breached", func(t *testing.T) {
+		statuses := expectStored()
+
+		limited := bankAccountRepo
+		limited.Limits = bankaccountrepo.Limits{DailyCents: 2000}
+		repoMockBankAccount.EXPECT().
+			ReadByIban(gomock.Any()).
+			Return(limited, nil)
+		repoMockTransaction.EXPECT().
+			SumTransferred(uint(1), time.Date(2022, 8, 25, 0, 0, 0, 0, time.UTC)).
+			Return(int64(1000), nil)
+		repoMockBankAccount.EXPECT().Debit(gomock.Any(), gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.BulkTransfer(bulkTransfer)
+
+		assert.ErrorIs(t, err, ErrLimitExceeded)
+		assert.Equal(t, "transfer limit exceeded: the daily limit is 20.00, 10.00 left", res.FailureReason)
+		assert.Equal(t, []string{"processing", "failed"}, *statuses)
+
+		var rejection *domain.Rejection
+		assert.ErrorAs(t, err, &rejection)
+		assert.Equal(t, domain.RejectionLimitExceeded, rejection.Reason)
+		assert.Equal(t, domain.LimitDaily, rejection.Limit)
+		assert.Equal(t, domain.NewMoney(1000, "EUR"), *rejection.Headroom)
+	})
+
+	t.Run("Test BulkTransfer return error when the bank account doesn't exist", func(t *testing.T) {
+		statuses := expectStored()
+		repoMockBankAccount.EXPECT().
+			ReadByIban(gomock.Any()).
+			Return(bankaccountrepo.BankAccount{}, sql.ErrNoRows)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.BulkTransfer(bulkTransfer)
+
+		var rejection *domain.Rejection
+		assert.ErrorIs(t, err, ErrAccountNotFound)
+		assert.ErrorAs(t, err, &rejection)
+		assert.Equal(t, domain.RejectionAccountNotFound, rejection.Reason)
+		assert.Equal(t, []string{"processing", "failed"}, *statuses)
+	})
+
+	t.Run("Test BulkTransfer return error when a transaction cannot be registered", func(t *testing.T) {
+		statuses := expectStored()
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankAccountRepo, nil)
+		repoMockBankAccount.EXPECT().
+			Debit(gomock.Any(), gomock.Any()).
+			Return(int64(10000), nil)
+		repoMockTransaction.EXPECT().
+			Create(gomock.Any()).
+			Return(0, errors.New("error"))
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.BulkTransfer(bulkTransfer)
+
+		assert.Error(t, err)
+		// the bulk transfer was stored before its execution failed
+		assert.False(t, domain.IsNotApplied(err))
+		assert.Equal(t, []string{"processing", "failed"}, *statuses)
+	})
+
+	t.Run("Test BulkTransfer return error when a concurrent debit drained the funds", func(t *testing.T) {
+		statuses := expectStored()
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankAccountRepo, nil)
+		repoMockBankAccount.EXPECT().
+			Debit(uint(1), int64(1453)).
+			Return(int64(0), bankaccountrepo.ErrInsufficientFunds)
+		repoMockTransaction.EXPECT().
+			Create(gomock.Any()).
+			Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.BulkTransfer(bulkTransfer)
+
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		assert.Equal(t, []string{"processing", "failed"}, *statuses)
+	})
+
+	t.Run("Test BulkTransfer return error when the balance cannot be updated", func(t *testing.T) {
+		expectStored()
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankAccountRepo, nil)
+		repoMockBankAccount.EXPECT().
+			Debit(gomock.Any(), gomock.Any()).
+			Return(int64(0), errors.New("error"))
+		repoMockTransaction.EXPECT().
+			Create(gomock.Any()).
+			Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.BulkTransfer(bulkTransfer)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Test BulkTransfer return error when the bulk transfer cannot be stored", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().
+			Create(gomock.Any()).
+			Return(0, errors.New("error"))
+		// read for the approval threshold only, nothing is executed
+		repoMockBankAccount.EXPECT().
+			ReadByIban(gomock.Any()).
+			Return(bankAccountRepo, nil).
+			Times(1)
+		repoMockBankAccount.EXPECT().Debit(gomock.Any(), gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.BulkTransfer(bulkTransfer)
+
+		assert.Error(t, err)
+		assert.True(t, domain.IsNotApplied(err))
+	})
+
+	t.Run("Test BulkTransfer with a future execution date is scheduled", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().
+			Create(gomock.Any()).
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer) (int, error) {
+				assert.Equal(t, string(domain.BulkTransferScheduled), data.Status)
+				assert.Equal(t, time.Date(2022, 8, 31, 0, 0, 0, 0, time.UTC), data.ExecutionDate)
+				return 4, nil
+			})
+		repoMockBulkTransfer.EXPECT().CreateLines(uint(4), gomock.Len(1)).Return(nil)
+		repoMockBulkTransfer.EXPECT().Update(gomock.Any()).Times(0)
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(bankAccountRepo, nil)
+		repoMockBankAccount.EXPECT().Debit(gomock.Any(), gomock.Any()).Times(0)
+
+		scheduled := bulkTransfer
+		scheduled.ExecutionDate = "2022-08-31"
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.BulkTransfer(scheduled)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(4), res.ID)
+		assert.Equal(t, domain.BulkTransferScheduled, res.Status)
+		assert.Equal(t, "2022-08-31", res.ExecutionDate)
+	})
+
+	t.Run("Test BulkTransfer with a past execution date is executed right away", func(t *testing.T) {
+		statuses := expectStored()
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankAccountRepo, nil)
+		repoMockBankAccount.EXPECT().Debit(uint(1), int64(1453)).Return(int64(10000), nil)
+		repoMockTransaction.EXPECT().Create(gomock.Any()).Return(1, nil)
+		repoMockBankAccount.EXPECT().ReadByIban("EE303680981021245685").Return(bankaccountrepo.BankAccount{}, sql.ErrNoRows)
+		repoMockLedger.EXPECT().Post(gomock.Any(), gomock.Len(2)).Return(1, nil)
+		repoMockBulkTransfer.EXPECT().
+			Read(uint(3)).
+			Return(bulktransferrepo.BulkTransfer{ID: 3, Status: string(domain.BulkTransferCompleted)}, nil)
+		repoMockBulkTransfer.EXPECT().ReadLines(uint(3)).Return(bulktransferrepo.LineList{}, nil)
+		repoMockTransaction.EXPECT().ReadByFilter(gomock.Any()).Return(domain.TransactionList{}, nil)
+
+		past := bulkTransfer
+		past.ExecutionDate = "2022-08-25"
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.BulkTransfer(past)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferCompleted, res.Status)
+		assert.Equal(t, []string{"processing", "completed"}, *statuses)
+	})
+
+	scheduledBulkTransfer := bulktransferrepo.BulkTransfer{
+		ID:               5,
+		OrganizationName: "ACME Corp",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		OrganizationBic:  "OIVUSCLQXXX",
+		TransfersCount:   1,
+		TotalCents:       1453,
+		Status:           string(domain.BulkTransferScheduled),
+		ExecutionDate:    time.Date(2022, 8, 25, 0, 0, 0, 0, time.UTC),
+	}
+	scheduledLines := bulktransferrepo.LineList{{
+		BulkTransferID:   5,
+		CounterPartyName: "Bip Bip",
+		CounterPartyIban: "EE303680981021245685",
+		CounterPartyBic:  "CRLYFRPPTOU",
+		AmountCents:      1453,
+		AmountCurrency:   "EUR",
+	}}
+
+	t.Run("Test ExecuteDue executes the scheduled bulk transfers that are due", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().ReadDue(now).Return(bulktransferrepo.BulkTransferList{scheduledBulkTransfer}, nil)
+		repoMockBulkTransfer.EXPECT().
+			Transition(gomock.Any(), "scheduled").
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer, from string) error {
+				assert.Equal(t, string(domain.BulkTransferProcessing), data.Status)
+				return nil
+			})
+		repoMockBulkTransfer.EXPECT().ReadLines(uint(5)).Return(scheduledLines, nil)
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(bankAccountRepo, nil)
+		repoMockBankAccount.EXPECT().Debit(uint(1), int64(1453)).Return(int64(10000), nil)
+		repoMockTransaction.EXPECT().
+			Create(gomock.Any()).
+			DoAndReturn(func(data transactionrepo.Transaction) (int, error) {
+				assert.Equal(t, uint(5), data.BulkTransferID)
+				return 1, nil
+			})
+		repoMockBankAccount.EXPECT().ReadByIban("EE303680981021245685").Return(bankaccountrepo.BankAccount{}, sql.ErrNoRows)
+		repoMockLedger.EXPECT().Post(gomock.Any(), gomock.Len(2)).Return(1, nil)
+		repoMockBulkTransfer.EXPECT().
+			Transition(gomock.Any(), "processing").
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer, from string) error {
+				assert.Equal(t, string(domain.BulkTransferCompleted), data.Status)
+				return nil
+			})
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(scheduledBulkTransfer, nil)
+		repoMockBulkTransfer.EXPECT().ReadLines(uint(5)).Return(scheduledLines, nil)
+		repoMockTransaction.EXPECT().ReadByFilter(gomock.Any()).Return(domain.TransactionList{}, nil)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		executed, err := svc.ExecuteDue()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, executed)
+	})
+
+	t.Run("Test ExecuteDue records the failure on the bulk transfer", func(t *testing.T) {
+		lowBudget := bankAccountRepo
+		lowBudget.BalanceCents = 100
+
+		repoMockBulkTransfer.EXPECT().ReadDue(now).Return(bulktransferrepo.BulkTransferList{scheduledBulkTransfer}, nil)
+		repoMockBulkTransfer.EXPECT().Transition(gomock.Any(), "scheduled").Return(nil)
+		repoMockBulkTransfer.EXPECT().ReadLines(uint(5)).Return(scheduledLines, nil)
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(lowBudget, nil)
+		repoMockBulkTransfer.EXPECT().
+			Transition(gomock.Any(), "processing").
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer, from string) error {
+				assert.Equal(t, string(domain.BulkTransferFailed), data.Status)
+				assert.Equal(t, ErrInsufficientFunds.Error(), data.FailureReason)
+				return nil
+			})
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock)
+		logMock.EXPECT().Warn("scheduled bulk transfer failed", gomock.Any())
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		executed, err := svc.ExecuteDue()
+
+		assert.ErrorIs(t, err, ErrScheduledExecutionFailed)
+		assert.EqualError(t, err, "scheduled bulk transfers failed: 1 of 1")
+		assert.Equal(t, 0, executed)
+	})
+
+	t.Run("Test ExecuteDue skips the bulk transfers claimed by someone else", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().ReadDue(now).Return(bulktransferrepo.BulkTransferList{scheduledBulkTransfer}, nil)
+		repoMockBulkTransfer.EXPECT().Transition(gomock.Any(), "scheduled").Return(bulktransferrepo.ErrStatusConflict)
+		repoMockBulkTransfer.EXPECT().ReadLines(gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		executed, err := svc.ExecuteDue()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, executed)
+	})
+
+	t.Run("Test ExecuteDue return error when the due bulk transfers cannot be read", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().ReadDue(now).Return(nil, errors.New("error"))
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.ExecuteDue()
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Quote return the total and the balance after execution without writing", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankAccountRepo, nil)
+		repoMockBulkTransfer.EXPECT().Create(gomock.Any()).Times(0)
+		repoMockBankAccount.EXPECT().Debit(gomock.Any(), gomock.Any()).Times(0)
+		repoMockTransaction.EXPECT().Create(gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.Quote(bulkTransfer)
+
+		assert.NoError(t, err)
+		assert.True(t, res.Executable)
+		assert.Empty(t, res.Problems)
+		assert.Equal(t, 1, res.TransfersCount)
+		assert.Equal(t, domain.NewMoney(1453, "EUR"), res.TotalAmount)
+		assert.Equal(t, domain.NewMoney(1460, "EUR"), *res.Balance)
+		assert.Equal(t, domain.NewMoney(7, "EUR"), *res.BalanceAfter)
+	})
+
+	t.Run("Test Quote return every problem", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankAccountRepo, nil)
+
+		invalid := bulkTransfer
+		invalid.CreditTransfers = append([]domain.CreditTransfer{}, bulkTransfer.CreditTransfers...)
+		invalid.CreditTransfers = append(invalid.CreditTransfers, bulkTransfer.CreditTransfers[0])
+		invalid.CreditTransfers[1].CounterPartyIban = "EE383680981021245685"
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.Quote(invalid)
+
+		assert.NoError(t, err)
+		assert.False(t, res.Executable)
+		assert.Len(t, res.Problems, 2)
+		assert.Equal(t, domain.RejectionInvalidFields, res.Problems[0].Reason)
+		assert.Equal(t, 1, *res.Problems[0].Errors[0].Index)
+		assert.Equal(t, domain.RejectionInsufficientFunds, res.Problems[1].Reason)
+		assert.Equal(t, domain.NewMoney(-1446, "EUR"), *res.BalanceAfter)
+	})
+
+	t.Run("Test Quote reports the beneficiaries the account doesn't have", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(bankAccountRepo, nil)
+		repoMockBeneficiary.EXPECT().Read(uint(5)).Return(beneficiaryrepo.Beneficiary{}, sql.ErrNoRows)
+		repoMockBeneficiary.EXPECT().
+			Read(uint(6)).
+			Return(beneficiaryrepo.Beneficiary{ID: 6, BankAccountID: 2, OrganizationIban: "DE44354208100362090817", Name: "Bip Bip", Iban: "EE303680981021245685", Bic: "CRLYFRPPTOU"}, nil)
+
+		referencing := bulkTransfer
+		referencing.CreditTransfers = []domain.CreditTransfer{
+			{Amount: domain.NewMoney(453, "EUR"), Currency: "EUR", BeneficiaryID: 5},
+			{Amount: domain.NewMoney(1000, "EUR"), Currency: "EUR", BeneficiaryID: 6},
+		}
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.Quote(referencing)
+
+		assert.NoError(t, err)
+		assert.False(t, res.Executable)
+		require.Len(t, res.Problems, 1)
+		assert.ErrorIs(t, res.Problems[0], ErrBeneficiaryNotFound)
+		first, second := 0, 1
+		assert.Equal(t, []domain.LineError{
+			{Index: &first, Field: "beneficiary_id", Rule: "beneficiary", Message: "no beneficiary 5 on the account"},
+			{Index: &second, Field: "beneficiary_id", Rule: "beneficiary", Message: "no beneficiary 6 on the account"},
+		}, res.Problems[0].Errors)
+	})
+
+	t.Run("Test Quote return every breached transfer limit", func(t *testing.T) {
+		limited := bankAccountRepo
+		limited.Limits = bankaccountrepo.Limits{TransactionCents: 1000, BatchCents: 1000, DailyCents: 2000, MonthlyCents: 100000}
+		repoMockBankAccount.EXPECT().
+			ReadByIban("FR81474608000002006107XXXXX").
+			Return(limited, nil)
+		repoMockTransaction.EXPECT().
+			SumTransferred(uint(1), time.Date(2022, 8, 25, 0, 0, 0, 0, time.UTC)).
+			Return(int64(2500), nil)
+		repoMockTransaction.EXPECT().
+			SumTransferred(uint(1), time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)).
+			Return(int64(2500), nil)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.Quote(bulkTransfer)
+
+		assert.NoError(t, err)
+		assert.False(t, res.Executable)
+		require.Len(t, res.Problems, 3)
+		assert.Equal(t, domain.LimitPerTransaction, res.Problems[0].Limit)
+		assert.Equal(t, domain.NewMoney(1000, "EUR"), *res.Problems[0].Headroom)
+		assert.Equal(t, []domain.LineError{{Index: res.Problems[0].Errors[0].Index, Field: "amount", Rule: "per_transaction_limit", Message: "exceeds the per transaction limit of 10.00"}}, res.Problems[0].Errors)
+		assert.Equal(t, 0, *res.Problems[0].Errors[0].Index)
+		assert.Equal(t, domain.LimitPerBatch, res.Problems[1].Limit)
+		// already beyond the daily limit
+		assert.Equal(t, domain.LimitDaily, res.Problems[2].Limit)
+		assert.Equal(t, domain.NewMoney(0, "EUR"), *res.Problems[2].Headroom)
+		for _, problem := range res.Problems {
+			assert.Equal(t, domain.RejectionLimitExceeded, problem.Reason)
+		}
+	})
+
+	t.Run("Test Quote return the missing bank account", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().
+			ReadByIban(gomock.Any()).
+			Return(bankaccountrepo.BankAccount{}, sql.ErrNoRows)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.Quote(bulkTransfer)
+
+		assert.NoError(t, err)
+		assert.False(t, res.Executable)
+		assert.Equal(t, domain.RejectionAccountNotFound, res.Problems[0].Reason)
+		assert.Nil(t, res.Balance)
+	})
+
+	t.Run("Test Quote return error when the bank account cannot be read", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().
+			ReadByIban(gomock.Any()).
+			Return(bankaccountrepo.BankAccount{}, errors.New("error"))
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Quote(bulkTransfer)
 
 		assert.Error(t, err)
 	})
+
+	t.Run("Test Cancel cancels the whole scheduled bulk transfer", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(scheduledBulkTransfer, nil)
+		repoMockBulkTransfer.EXPECT().
+			Cancel(gomock.Any(), "scheduled").
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer, from string) error {
+				assert.Equal(t, string(domain.BulkTransferCancelled), data.Status)
+				assert.Equal(t, "duplicate payroll", data.CancellationReason)
+				assert.Equal(t, "jane@acme.corp", data.CancelledBy)
+				assert.Equal(t, now, data.CancelledAt)
+				return nil
+			})
+		repoMockBankAccount.EXPECT().Debit(gomock.Any(), gomock.Any()).Times(0)
+		cancelled := scheduledBulkTransfer
+		cancelled.Status = string(domain.BulkTransferCancelled)
+		cancelled.CancellationReason = "duplicate payroll"
+		cancelled.CancelledBy = "jane@acme.corp"
+		cancelled.CancelledAt = now
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(cancelled, nil)
+		repoMockBulkTransfer.EXPECT().ReadLines(uint(5)).Return(scheduledLines, nil)
+		repoMockTransaction.EXPECT().ReadByFilter(gomock.Any()).Return(domain.TransactionList{}, nil)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.Cancel(5, domain.BulkTransferCancellation{Reason: "duplicate payroll"}, "jane@acme.corp")
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferCancelled, res.Status)
+		assert.Equal(t, &domain.Cancellation{Reason: "duplicate payroll", Actor: "jane@acme.corp", At: now}, res.Cancellation)
+	})
+
+	t.Run("Test Cancel cancels the bulk transfers whose execution didn't start", func(t *testing.T) {
+		for _, status := range []domain.BulkTransferStatus{domain.BulkTransferQueued, domain.BulkTransferPendingApproval, domain.BulkTransferHeldForReview} {
+			waiting := scheduledBulkTransfer
+			waiting.Status = string(status)
+			repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(waiting, nil)
+			repoMockBulkTransfer.EXPECT().Cancel(gomock.Any(), string(status)).Return(nil)
+			cancelled := waiting
+			cancelled.Status = string(domain.BulkTransferCancelled)
+			repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(cancelled, nil)
+			repoMockBulkTransfer.EXPECT().ReadLines(uint(5)).Return(scheduledLines, nil)
+			repoMockTransaction.EXPECT().ReadByFilter(gomock.Any()).Return(domain.TransactionList{}, nil)
+
+			svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+			res, err := svc.Cancel(5, domain.BulkTransferCancellation{Reason: "duplicate payroll"}, "jane@acme.corp")
+
+			assert.NoError(t, err, status)
+			assert.Equal(t, domain.BulkTransferCancelled, res.Status, status)
+		}
+	})
+
+	t.Run("Test Cancel return conflict when the bulk transfer is being executed", func(t *testing.T) {
+		processing := scheduledBulkTransfer
+		processing.Status = string(domain.BulkTransferProcessing)
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(processing, nil)
+		repoMockBulkTransfer.EXPECT().Cancel(gomock.Any(), gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Cancel(5, domain.BulkTransferCancellation{Reason: "duplicate payroll"}, "jane@acme.corp")
+
+		assert.ErrorIs(t, err, ErrNotCancellable)
+	})
+
+	t.Run("Test Cancel return conflict when the bulk transfer was executed", func(t *testing.T) {
+		completed := scheduledBulkTransfer
+		completed.Status = string(domain.BulkTransferCompleted)
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(completed, nil)
+		repoMockBulkTransfer.EXPECT().Cancel(gomock.Any(), gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Cancel(5, domain.BulkTransferCancellation{Reason: "duplicate payroll"}, "jane@acme.corp")
+
+		assert.ErrorIs(t, err, ErrNotCancellable)
+		assert.EqualError(t, err, "bulk transfer can't be cancelled: it is completed")
+	})
+
+	t.Run("Test Cancel return conflict when the bulk transfer was claimed meanwhile", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(scheduledBulkTransfer, nil)
+		repoMockBulkTransfer.EXPECT().Cancel(gomock.Any(), "scheduled").Return(bulktransferrepo.ErrStatusConflict)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Cancel(5, domain.BulkTransferCancellation{Reason: "duplicate payroll"}, "jane@acme.corp")
+
+		assert.ErrorIs(t, err, ErrNotCancellable)
+	})
+
+	t.Run("Test Cancel return not found", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().Read(uint(9)).Return(bulktransferrepo.BulkTransfer{}, sql.ErrNoRows)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Cancel(9, domain.BulkTransferCancellation{Reason: "duplicate payroll"}, "jane@acme.corp")
+
+		assert.ErrorIs(t, err, ErrBulkTransferNotFound)
+	})
+
+	t.Run("Test Cancel rejects unknown or already cancelled lines", func(t *testing.T) {
+		lines := bulktransferrepo.LineList{scheduledLines[0], scheduledLines[0]}
+		lines[1].Index = 1
+		lines[1].CancelledAt = now
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(scheduledBulkTransfer, nil)
+		repoMockBulkTransfer.EXPECT().ReadLines(uint(5)).Return(lines, nil)
+		repoMockBulkTransfer.EXPECT().CancelLines(gomock.Any(), gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Cancel(5, domain.BulkTransferCancellation{Reason: "wrong amount", LineIndexes: []int{1, 2}}, "jane@acme.corp")
+
+		rejection := domain.AsRejection(err)
+		assert.Equal(t, domain.RejectionInvalidFields, rejection.Reason)
+		assert.Len(t, rejection.Errors, 2)
+		assert.Equal(t, "is already cancelled", rejection.Errors[0].Message)
+		assert.Equal(t, "no such line", rejection.Errors[1].Message)
+	})
+
+	approvalAccount := bankAccountRepo
+	approvalAccount.Approval = bankaccountrepo.Approval{ThresholdCents: 1000, RequiredApprovals: 2}
+	pendingBulkTransfer := scheduledBulkTransfer
+	pendingBulkTransfer.Status = string(domain.BulkTransferPendingApproval)
+	pendingBulkTransfer.ExecutionDate = time.Time{}
+	pendingBulkTransfer.SubmittedBy = "john@acme.corp"
+	pendingBulkTransfer.RequiredApprovals = 2
+
+	t.Run("Test BulkTransfer above the approval threshold is left pending approval", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(approvalAccount, nil)
+		repoMockBulkTransfer.EXPECT().
+			Create(gomock.Any()).
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer) (int, error) {
+				assert.Equal(t, string(domain.BulkTransferPendingApproval), data.Status)
+				assert.Equal(t, 2, data.RequiredApprovals)
+				assert.Equal(t, "john@acme.corp", data.SubmittedBy)
+				return 5, nil
+			})
+		repoMockBulkTransfer.EXPECT().CreateLines(uint(5), gomock.Len(1)).Return(nil)
+		repoMockBulkTransfer.EXPECT().Update(gomock.Any()).Times(0)
+		repoMockBankAccount.EXPECT().Debit(gomock.Any(), gomock.Any()).Times(0)
+
+		submitted := bulkTransfer
+		submitted.SubmittedBy = "john@acme.corp"
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.BulkTransfer(submitted)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferPendingApproval, res.Status)
+		assert.Equal(t, "john@acme.corp", res.SubmittedBy)
+		assert.Equal(t, &domain.Approval{RequiredApprovals: 2, Decisions: []domain.Decision{}}, res.Approval)
+	})
+
+	t.Run("Test BulkTransfer above the approval threshold return error without its submitter", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(approvalAccount, nil)
+		repoMockBulkTransfer.EXPECT().Create(gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.BulkTransfer(bulkTransfer)
+
+		assert.ErrorIs(t, err, ErrMissingSubmitter)
+	})
+
+	t.Run("Test Quote reports the approval the bulk transfer needs", func(t *testing.T) {
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(approvalAccount, nil)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.Quote(bulkTransfer)
+
+		assert.NoError(t, err)
+		assert.True(t, res.Executable)
+		assert.True(t, res.RequiresApproval)
+	})
+
+	// expectDecidable expects the approvers of the organization account and the decisions taken so far to be read
+	expectDecidable := func(approvers []string, decisions bulktransferrepo.DecisionList) {
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(pendingBulkTransfer, nil)
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(approvalAccount, nil)
+		repoMockBankAccount.EXPECT().ReadApprovers(uint(1)).Return(approvers, nil)
+		repoMockBulkTransfer.EXPECT().ReadDecisions(uint(5)).Return(decisions, nil)
+	}
+
+	t.Run("Test Approve records the approval and waits for the other approvers", func(t *testing.T) {
+		expectDecidable([]string{"jane@acme.corp", "joe@acme.corp"}, nil)
+		repoMockBulkTransfer.EXPECT().
+			CreateDecision(bulktransferrepo.Decision{BulkTransferID: 5, Actor: "jane@acme.corp", Decision: "approved", Comment: "checked", CreatedAt: now}).
+			Return(1, nil)
+		repoMockBulkTransfer.EXPECT().Transition(gomock.Any(), gomock.Any()).Times(0)
+		repoMockBankAccount.EXPECT().Debit(gomock.Any(), gomock.Any()).Times(0)
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(pendingBulkTransfer, nil)
+		repoMockBulkTransfer.EXPECT().ReadLines(uint(5)).Return(scheduledLines, nil)
+		repoMockTransaction.EXPECT().ReadByFilter(gomock.Any()).Return(domain.TransactionList{}, nil)
+		repoMockBulkTransfer.EXPECT().
+			ReadDecisions(uint(5)).
+			Return(bulktransferrepo.DecisionList{{ID: 1, BulkTransferID: 5, Actor: "jane@acme.corp", Decision: "approved", Comment: "checked", CreatedAt: now}}, nil)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.Approve(5, domain.BulkTransferApproval{Comment: "checked"}, "jane@acme.corp")
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferPendingApproval, res.Status)
+		assert.Equal(t, &domain.Approval{
+			RequiredApprovals: 2,
+			Decisions:         []domain.Decision{{Actor: "jane@acme.corp", Decision: domain.ApprovalApproved, Comment: "checked", At: now}},
+		}, res.Approval)
+	})
+
+	t.Run("Test Approve executes the bulk transfer once it has every approval", func(t *testing.T) {
+		expectDecidable(nil, bulktransferrepo.DecisionList{{Actor: "jane@acme.corp", Decision: "approved"}})
+		repoMockBulkTransfer.EXPECT().CreateDecision(gomock.Any()).Return(2, nil)
+		repoMockBulkTransfer.EXPECT().
+			Transition(gomock.Any(), "pending_approval").
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer, from string) error {
+				assert.Equal(t, string(domain.BulkTransferProcessing), data.Status)
+				return nil
+			})
+		repoMockBulkTransfer.EXPECT().ReadLines(uint(5)).Return(scheduledLines, nil)
+		// the funds are checked at approval
+		lowBudget := approvalAccount
+		lowBudget.BalanceCents = 100
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(lowBudget, nil)
+		repoMockBankAccount.EXPECT().Debit(gomock.Any(), gomock.Any()).Times(0)
+		repoMockBulkTransfer.EXPECT().
+			Transition(gomock.Any(), "processing").
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer, from string) error {
+				assert.Equal(t, string(domain.BulkTransferFailed), data.Status)
+				return nil
+			})
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.Approve(5, domain.BulkTransferApproval{}, "joe@acme.corp")
+
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		assert.Equal(t, domain.BulkTransferFailed, res.Status)
+	})
+
+	t.Run("Test Approve return error when the submitter approves", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(pendingBulkTransfer, nil)
+		repoMockBulkTransfer.EXPECT().CreateDecision(gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Approve(5, domain.BulkTransferApproval{}, "john@acme.corp")
+
+		assert.ErrorIs(t, err, ErrSelfApproval)
+	})
+
+	t.Run("Test Approve return error when the submitter is unknown", func(t *testing.T) {
+		unknownSubmitter := pendingBulkTransfer
+		unknownSubmitter.SubmittedBy = ""
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(unknownSubmitter, nil)
+		repoMockBulkTransfer.EXPECT().CreateDecision(gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Approve(5, domain.BulkTransferApproval{}, "jane@acme.corp")
+		assert.ErrorIs(t, err, ErrMissingSubmitter)
+
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(unknownSubmitter, nil)
+		_, err = svc.Reject(5, domain.BulkTransferRejection{Reason: "unknown submitter"}, "jane@acme.corp")
+		assert.ErrorIs(t, err, ErrMissingSubmitter)
+	})
+
+	t.Run("Test Approve return error when the actor isn't an approver", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(pendingBulkTransfer, nil)
+		repoMockBankAccount.EXPECT().ReadByIban("FR81474608000002006107XXXXX").Return(approvalAccount, nil)
+		repoMockBankAccount.EXPECT().ReadApprovers(uint(1)).Return([]string{"jane@acme.corp"}, nil)
+		repoMockBulkTransfer.EXPECT().CreateDecision(gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Approve(5, domain.BulkTransferApproval{}, "joe@acme.corp")
+
+		assert.ErrorIs(t, err, ErrNotApprover)
+	})
+
+	t.Run("Test Approve return error when the approver already decided", func(t *testing.T) {
+		expectDecidable(nil, bulktransferrepo.DecisionList{{Actor: "jane@acme.corp", Decision: "approved"}})
+		repoMockBulkTransfer.EXPECT().CreateDecision(gomock.Any()).Times(0)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Approve(5, domain.BulkTransferApproval{}, "jane@acme.corp")
+
+		assert.ErrorIs(t, err, ErrAlreadyDecided)
+	})
+
+	t.Run("Test Approve return conflict when the bulk transfer isn't pending approval", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(scheduledBulkTransfer, nil)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Approve(5, domain.BulkTransferApproval{}, "jane@acme.corp")
+
+		assert.ErrorIs(t, err, ErrNotPendingApproval)
+		assert.EqualError(t, err, "bulk transfer isn't pending approval: it is scheduled")
+	})
+
+	t.Run("Test Approve return not found", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().Read(uint(9)).Return(bulktransferrepo.BulkTransfer{}, sql.ErrNoRows)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Approve(9, domain.BulkTransferApproval{}, "jane@acme.corp")
+
+		assert.ErrorIs(t, err, ErrBulkTransferNotFound)
+	})
+
+	t.Run("Test Reject rejects the bulk transfer", func(t *testing.T) {
+		expectDecidable(nil, nil)
+		repoMockBulkTransfer.EXPECT().
+			CreateDecision(bulktransferrepo.Decision{BulkTransferID: 5, Actor: "jane@acme.corp", Decision: "rejected", Comment: "wrong month", CreatedAt: now}).
+			Return(1, nil)
+		repoMockBulkTransfer.EXPECT().
+			Transition(gomock.Any(), "pending_approval").
+			DoAndReturn(func(data bulktransferrepo.BulkTransfer, from string) error {
+				assert.Equal(t, string(domain.BulkTransferRejected), data.Status)
+				return nil
+			})
+		rejected := pendingBulkTransfer
+		rejected.Status = string(domain.BulkTransferRejected)
+		repoMockBulkTransfer.EXPECT().Read(uint(5)).Return(rejected, nil)
+		repoMockBulkTransfer.EXPECT().ReadLines(uint(5)).Return(scheduledLines, nil)
+		repoMockTransaction.EXPECT().ReadByFilter(gomock.Any()).Return(domain.TransactionList{}, nil)
+		repoMockBulkTransfer.EXPECT().ReadDecisions(uint(5)).Return(bulktransferrepo.DecisionList{{Actor: "jane@acme.corp", Decision: "rejected", Comment: "wrong month"}}, nil)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.Reject(5, domain.BulkTransferRejection{Reason: "wrong month"}, "jane@acme.corp")
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferRejected, res.Status)
+		assert.Equal(t, domain.ApprovalRejected, res.Approval.Decisions[0].Decision)
+	})
+
+	t.Run("Test Reject return conflict when the bulk transfer was decided on meanwhile", func(t *testing.T) {
+		expectDecidable(nil, nil)
+		repoMockBulkTransfer.EXPECT().CreateDecision(gomock.Any()).Return(1, nil)
+		repoMockBulkTransfer.EXPECT().Transition(gomock.Any(), "pending_approval").Return(bulktransferrepo.ErrStatusConflict)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Reject(5, domain.BulkTransferRejection{Reason: "wrong month"}, "jane@acme.corp")
+
+		assert.ErrorIs(t, err, ErrNotPendingApproval)
+	})
+
+	t.Run("Test Read return error when not found", func(t *testing.T) {
+		repoMockBulkTransfer.EXPECT().
+			Read(uint(9)).
+			Return(bulktransferrepo.BulkTransfer{}, errors.New("error"))
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		_, err := svc.Read(9)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Test ReadByFilter return success", func(t *testing.T) {
+		filters := map[string]string{"status": "completed"}
+		repoMockBulkTransfer.EXPECT().
+			ReadByFilter(filters).
+			Return(bulktransferrepo.BulkTransferList{{ID: 3, Status: "completed", TotalCents: 1453}}, nil)
+
+		svc := New(uowMock, repoMockBulkTransfer, repoMockTransaction, screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+		res, err := svc.ReadByFilter(filters)
+
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, domain.NewMoney(1453, "EUR"), res[0].TotalAmount)
+	})
+}
+
+func TestTransferServiceConcurrentDebits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+	logMock.EXPECT().WithError(gomock.Any()).Return(logMock).AnyTimes()
+	logMock.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 10,
+		MaxOpenConns: 10,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	accountID, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		BalanceCents:     10000,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	})
+	require.NoError(t, err)
+
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screening.Disabled{}, 0, fees.Schedule{}, tools.SystemClock{}, logMock)
+
+	bulkTransfer := domain.BulkTransfer{
+		OrganizationName: "ACME Corp",
+		OrganizationBic:  "OIVUSCLQXXX",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		CreditTransfers: []domain.CreditTransfer{
+			{
+				Amount:           domain.NewMoney(1500, "EUR"),
+				Currency:         "EUR",
+				CounterPartyName: "Bip Bip",
+				CounterPartyBic:  "CRLYFRPPTOU",
+				CounterPartyIban: "EE303680981021245685",
+				Description:      "Wonderland/4410",
+			},
+		},
+	}
+
+	// 20 requests of 15.00 against 100.00: only 6 can be covered
+	const requests = 20
+	var wg sync.WaitGroup
+	var succeeded, rejected int32
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.BulkTransfer(bulkTransfer)
+			switch {
+			case err == nil:
+				atomic.AddInt32(&succeeded, 1)
+			case errors.Is(err, ErrInsufficientFunds):
+				atomic.AddInt32(&rejected, 1)
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	bankAccount, err := bankAccountRepository.Read(uint(accountID))
+	require.NoError(t, err)
+
+	var transactions int
+	require.NoError(t, conn.Conn.QueryRow("SELECT COUNT(*) FROM transactions").Scan(&transactions))
+
+	assert.Equal(t, int32(6), succeeded)
+	assert.Equal(t, int32(requests-6), rejected)
+	assert.Equal(t, int64(1000), bankAccount.BalanceCents)
+	assert.GreaterOrEqual(t, bankAccount.BalanceCents, int64(0))
+	assert.Equal(t, 6, transactions)
+}
+
+func TestTransferServiceScheduledExecution(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	_, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		BalanceCents:     10000,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2022, 9, 29, 18, 0, 0, 0, time.UTC)
+	clock := tools.ClockFunc(func() time.Time { return now })
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+
+	detail, err := svc.BulkTransfer(domain.BulkTransfer{
+		OrganizationName: "ACME Corp",
+		OrganizationBic:  "OIVUSCLQXXX",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		ExecutionDate:    "2022-09-30",
+		CreditTransfers: []domain.CreditTransfer{
+			{
+				Amount:           domain.NewMoney(1500, "EUR"),
+				Currency:         "EUR",
+				CounterPartyName: "Bip Bip",
+				CounterPartyBic:  "CRLYFRPPTOU",
+				CounterPartyIban: "EE303680981021245685",
+				Description:      "Salary September",
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferScheduled, detail.Status)
+
+	// not due yet
+	executed, err := svc.ExecuteDue()
+	require.NoError(t, err)
+	assert.Equal(t, 0, executed)
+
+	now = time.Date(2022, 9, 30, 0, 0, 0, 0, time.UTC)
+	executed, err = svc.ExecuteDue()
+	require.NoError(t, err)
+	assert.Equal(t, 1, executed)
+
+	// already executed
+	executed, err = svc.ExecuteDue()
+	require.NoError(t, err)
+	assert.Equal(t, 0, executed)
+
+	detail, err = svc.Read(detail.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+	assert.Len(t, detail.Transactions, 1)
+	assert.Equal(t, "Salary September", detail.Transactions[0].Description)
+
+	bankAccount, err := bankAccountRepository.ReadByIban("FR81474608000002006107XXXXX")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8500), bankAccount.BalanceCents)
+}
+
+func TestTransferServiceCancellation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	_, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		BalanceCents:     10000,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2022, 9, 29, 18, 0, 0, 0, time.UTC)
+	clock := tools.ClockFunc(func() time.Time { return now })
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screening.Disabled{}, 0, fees.Schedule{}, clock, logMock)
+
+	creditTransfer := func(cents int64, description string) domain.CreditTransfer {
+		return domain.CreditTransfer{
+			Amount:           domain.NewMoney(cents, "EUR"),
+			Currency:         "EUR",
+			CounterPartyName: "Bip Bip",
+			CounterPartyBic:  "CRLYFRPPTOU",
+			CounterPartyIban: "EE303680981021245685",
+			Description:      description,
+		}
+	}
+	schedule := func() domain.BulkTransferDetail {
+		detail, err := svc.BulkTransfer(domain.BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+			ExecutionDate:    "2022-09-30",
+			CreditTransfers:  []domain.CreditTransfer{creditTransfer(1500, "Salary Bip"), creditTransfer(2500, "Salary Coyote")},
+		})
+		require.NoError(t, err)
+		return detail
+	}
+
+	partial := schedule()
+	whole := schedule()
+
+	detail, err := svc.Cancel(partial.ID, domain.BulkTransferCancellation{Reason: "left the company", LineIndexes: []int{1}}, "jane@acme.corp")
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferScheduled, detail.Status)
+	assert.Equal(t, 1, detail.TransfersCount)
+	assert.Equal(t, domain.NewMoney(1500, "EUR"), detail.TotalAmount)
+	index := 1
+	assert.Equal(t, []domain.Cancellation{{Index: &index, Reason: "left the company", Actor: "jane@acme.corp", At: now}}, detail.CancelledLines)
+
+	detail, err = svc.Cancel(whole.ID, domain.BulkTransferCancellation{Reason: "duplicate payroll"}, "jane@acme.corp")
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferCancelled, detail.Status)
+	assert.Equal(t, "duplicate payroll", detail.Cancellation.Reason)
+
+	// only the remaining line of the partially cancelled bulk transfer is executed
+	now = time.Date(2022, 9, 30, 0, 0, 0, 0, time.UTC)
+	executed, err := svc.ExecuteDue()
+	require.NoError(t, err)
+	assert.Equal(t, 1, executed)
+
+	detail, err = svc.Read(partial.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+	assert.Len(t, detail.Transactions, 1)
+	assert.Equal(t, "Salary Bip", detail.Transactions[0].Description)
+
+	_, err = svc.Cancel(partial.ID, domain.BulkTransferCancellation{Reason: "too late"}, "jane@acme.corp")
+	assert.ErrorIs(t, err, ErrNotCancellable)
+
+	// a queued bulk transfer is cancelled before a worker runs its job
+	job, err := svc.Submit(domain.BulkTransfer{
+		OrganizationName: "ACME Corp",
+		OrganizationBic:  "OIVUSCLQXXX",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		CreditTransfers:  []domain.CreditTransfer{creditTransfer(1000, "Salary Bip")},
+	})
+	require.NoError(t, err)
+	detail, err = svc.Cancel(job.BulkTransferID, domain.BulkTransferCancellation{Reason: "wrong month"}, "jane@acme.corp")
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferCancelled, detail.Status)
+
+	require.NoError(t, svc.RunJob(job.ID))
+	detail, err = svc.Read(job.BulkTransferID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferCancelled, detail.Status)
+	assert.Empty(t, detail.Transactions)
+
+	bankAccount, err := bankAccountRepository.ReadByIban("FR81474608000002006107XXXXX")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8500), bankAccount.BalanceCents)
+}
+
+func TestTransferServiceInternalTransfer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	for _, bankAccount := range []bankaccountrepo.BankAccount{
+		{OrganizationName: "ACME Corp", BalanceCents: 10000, Iban: "FR81474608000002006107XXXXX", Bic: "OIVUSCLQXXX"},
+		{OrganizationName: "Bip Bip", BalanceCents: 500, Iban: "EE303680981021245685", Bic: "CRLYFRPPTOU"},
+	} {
+		_, err := openBankAccount(conn, bankAccount)
+		require.NoError(t, err)
+	}
+
+	transactionRepository := transactionrepo.New(conn)
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionRepository, screening.Disabled{}, 0, fees.Schedule{}, tools.SystemClock{}, logMock)
+
+	creditTransfer := func(cents int64, name, bic, iban string) domain.CreditTransfer {
+		return domain.CreditTransfer{
+			Amount:           domain.NewMoney(cents, "EUR"),
+			Currency:         "EUR",
+			CounterPartyName: name,
+			CounterPartyBic:  bic,
+			CounterPartyIban: iban,
+			Description:      "Invoice 42",
+		}
+	}
+	detail, err := svc.BulkTransfer(domain.BulkTransfer{
+		OrganizationName: "ACME Corp",
+		OrganizationBic:  "OIVUSCLQXXX",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		CreditTransfers: []domain.CreditTransfer{
+			creditTransfer(1500, "Bip Bip", "CRLYFRPPTOU", "EE303680981021245685"),
+			creditTransfer(2000, "Road Runner", "CRLYFRPPTOU", "DE44354208100362090817"),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+	assert.Len(t, detail.Transactions, 2)
+
+	payer, err := bankAccountRepository.ReadByIban("FR81474608000002006107XXXXX")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6500), payer.BalanceCents)
+
+	receiver, err := bankAccountRepository.ReadByIban("EE303680981021245685")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2000), receiver.BalanceCents)
+
+	incoming, err := transactionRepository.ReadByFilter(map[string]string{"iban": "EE303680981021245685"})
+	require.NoError(t, err)
+	require.Len(t, incoming, 1)
+	assert.Equal(t, domain.TransactionIncoming, incoming[0].Direction)
+	assert.Equal(t, "FR81474608000002006107XXXXX", incoming[0].CounterPartyIban)
+	assert.Equal(t, domain.NewMoney(1500, "EUR"), incoming[0].Amount)
+	assert.Equal(t, detail.ID, incoming[0].BulkTransferID)
+
+	// the ledger holds the opening balances and the movements, matching the balances of the accounts
+	ledgerRepository := ledgerrepo.New(conn)
+	for account, expected := range map[string]int64{
+		ledgerrepo.BankAccount(payer.ID):    6500,
+		ledgerrepo.BankAccount(receiver.ID): 2000,
+		ledgerrepo.AccountExternal:          2000,
+	} {
+		balance, err := ledgerRepository.Balance(account)
+		require.NoError(t, err)
+		assert.Equal(t, expected, balance, account)
+	}
+}
+
+// TestTransferServiceLimits runs against a real database: the bulk transfers executed during the day count
+// towards the daily limit of the account, in execution and in dry-run
+func TestTransferServiceLimits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	_, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		BalanceCents:     10000,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+		Limits:           bankaccountrepo.Limits{DailyCents: 3000},
+	})
+	require.NoError(t, err)
+
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screening.Disabled{}, 0, fees.Schedule{}, tools.SystemClock{}, logMock)
+
+	bulkTransfer := func(cents int64) domain.BulkTransfer {
+		return domain.BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+			CreditTransfers: []domain.CreditTransfer{{
+				Amount:           domain.NewMoney(cents, "EUR"),
+				Currency:         "EUR",
+				CounterPartyName: "Road Runner",
+				CounterPartyBic:  "CRLYFRPPTOU",
+				CounterPartyIban: "DE44354208100362090817",
+				Description:      "Invoice 42",
+			}},
+		}
+	}
+
+	detail, err := svc.BulkTransfer(bulkTransfer(2000))
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+
+	quote, err := svc.Quote(bulkTransfer(1500))
+	require.NoError(t, err)
+	require.Len(t, quote.Problems, 1)
+	assert.Equal(t, domain.LimitDaily, quote.Problems[0].Limit)
+	assert.Equal(t, domain.NewMoney(1000, "EUR"), *quote.Problems[0].Headroom)
+
+	_, err = svc.BulkTransfer(bulkTransfer(1500))
+	var rejection *domain.Rejection
+	require.ErrorAs(t, err, &rejection)
+	assert.Equal(t, domain.RejectionLimitExceeded, rejection.Reason)
+	assert.Equal(t, domain.NewMoney(1000, "EUR"), *rejection.Headroom)
+
+	detail, err = svc.BulkTransfer(bulkTransfer(1000))
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+
+	payer, err := bankAccountRepository.ReadByIban("FR81474608000002006107XXXXX")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7000), payer.BalanceCents)
+}
+
+func TestTransferServiceApproval(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	id, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		BalanceCents:     10000,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+		Approval:         bankaccountrepo.Approval{ThresholdCents: 5000, RequiredApprovals: 2},
+	})
+	require.NoError(t, err)
+	require.NoError(t, bankAccountRepository.ReplaceApprovers(uint(id), []string{"alice", "bob", "carol"}))
+
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screening.Disabled{}, 0, fees.Schedule{}, tools.SystemClock{}, logMock)
+
+	bulkTransfer := func(cents int64) domain.BulkTransfer {
+		return domain.BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+			CreditTransfers: []domain.CreditTransfer{{
+				Amount:           domain.NewMoney(cents, "EUR"),
+				Currency:         "EUR",
+				CounterPartyName: "Road Runner",
+				CounterPartyBic:  "CRLYFRPPTOU",
+				CounterPartyIban: "DE44354208100362090817",
+				Description:      "Payroll",
+			}},
+			SubmittedBy: "mallory",
+		}
+	}
+
+	payroll, err := svc.BulkTransfer(bulkTransfer(6000))
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferPendingApproval, payroll.Status)
+
+	_, err = svc.Approve(payroll.ID, domain.BulkTransferApproval{}, "mallory")
+	assert.ErrorIs(t, err, ErrSelfApproval)
+	_, err = svc.Approve(payroll.ID, domain.BulkTransferApproval{}, "dave")
+	assert.ErrorIs(t, err, ErrNotApprover)
+
+	detail, err := svc.Approve(payroll.ID, domain.BulkTransferApproval{Comment: "matches the payroll"}, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferPendingApproval, detail.Status)
+	require.Len(t, detail.Approval.Decisions, 1)
+	assert.Equal(t, "matches the payroll", detail.Approval.Decisions[0].Comment)
+
+	_, err = svc.Approve(payroll.ID, domain.BulkTransferApproval{}, "alice")
+	assert.ErrorIs(t, err, ErrAlreadyDecided)
+
+	// nothing was debited while waiting for approval
+	payer, err := bankAccountRepository.ReadByIban("FR81474608000002006107XXXXX")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10000), payer.BalanceCents)
+
+	detail, err = svc.Approve(payroll.ID, domain.BulkTransferApproval{}, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+	assert.Len(t, detail.Transactions, 1)
+	assert.Len(t, detail.Approval.Decisions, 2)
+
+	// the funds are checked at approval, not at submission
+	bonus, err := svc.BulkTransfer(bulkTransfer(5500))
+	require.NoError(t, err)
+	_, err = svc.Approve(bonus.ID, domain.BulkTransferApproval{}, "alice")
+	require.NoError(t, err)
+	_, err = svc.Approve(bonus.ID, domain.BulkTransferApproval{}, "carol")
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	detail, err = svc.Read(bonus.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferFailed, detail.Status)
+
+	duplicate, err := svc.BulkTransfer(bulkTransfer(6000))
+	require.NoError(t, err)
+	detail, err = svc.Reject(duplicate.ID, domain.BulkTransferRejection{Reason: "duplicate payroll"}, "carol")
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferRejected, detail.Status)
+	_, err = svc.Approve(duplicate.ID, domain.BulkTransferApproval{}, "alice")
+	assert.ErrorIs(t, err, ErrNotPendingApproval)
+
+	payer, err = bankAccountRepository.ReadByIban("FR81474608000002006107XXXXX")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4000), payer.BalanceCents)
+
+	// cancelling lines lowers the total the approvals are computed from
+	split := bulkTransfer(3000)
+	split.CreditTransfers = append(split.CreditTransfers, split.CreditTransfers[0], split.CreditTransfers[0])
+	split.CreditTransfers[1].Description = "Bonus"
+	split.CreditTransfers[2].Description = "Expenses"
+	expenses, err := svc.BulkTransfer(split)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferPendingApproval, expenses.Status)
+	_, err = svc.Approve(expenses.ID, domain.BulkTransferApproval{}, "alice")
+	require.NoError(t, err)
+
+	detail, err = svc.Cancel(expenses.ID, domain.BulkTransferCancellation{Reason: "paid in cash", LineIndexes: []int{2}}, "mallory")
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferPendingApproval, detail.Status)
+	assert.Equal(t, domain.NewMoney(6000, "EUR"), detail.TotalAmount)
+	assert.Equal(t, 2, detail.Approval.RequiredApprovals)
+
+	detail, err = svc.Cancel(expenses.ID, domain.BulkTransferCancellation{Reason: "not due", LineIndexes: []int{1}}, "mallory")
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+	assert.Equal(t, domain.NewMoney(3000, "EUR"), detail.TotalAmount)
+	require.Len(t, detail.Transactions, 1)
+	assert.Equal(t, "Payroll", detail.Transactions[0].Description)
+
+	payer, err = bankAccountRepository.ReadByIban("FR81474608000002006107XXXXX")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), payer.BalanceCents)
+}
+
+func TestTransferServiceBeneficiaries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	_, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		BalanceCents:     10000,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	})
+	require.NoError(t, err)
+
+	beneficiaryRepository := beneficiaryrepo.New(conn)
+	beneficiary := beneficiaryrepo.Beneficiary{BankAccountID: 1, Name: "Bip Bip", Iban: "EE303680981021245685", Bic: "CRLYFRPPTOU", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	id, err := beneficiaryRepository.Create(beneficiary)
+	require.NoError(t, err)
+	beneficiary.ID = uint(id)
+
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screening.Disabled{}, 0, fees.Schedule{}, tools.SystemClock{}, logMock)
+
+	bulkTransfer := func(creditTransfers ...domain.CreditTransfer) domain.BulkTransfer {
+		return domain.BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+			CreditTransfers:  creditTransfers,
+		}
+	}
+	referencing := domain.CreditTransfer{Amount: domain.NewMoney(1000, "EUR"), Currency: "EUR", BeneficiaryID: beneficiary.ID}
+
+	detail, err := svc.BulkTransfer(bulkTransfer(referencing, domain.CreditTransfer{
+		Amount:           domain.NewMoney(500, "EUR"),
+		Currency:         "EUR",
+		CounterPartyName: "Road Runner",
+		CounterPartyBic:  "CRLYFRPPTOU",
+		CounterPartyIban: "DE44354208100362090817",
+	}))
+	require.NoError(t, err)
+	require.Len(t, detail.Transactions, 2)
+	assert.Equal(t, "Bip Bip", detail.Transactions[0].CounterPartyName)
+	assert.Equal(t, beneficiary.ID, detail.Transactions[0].BeneficiaryID)
+	assert.True(t, detail.Transactions[0].FirstPayment)
+	assert.True(t, detail.Transactions[1].FirstPayment)
+
+	// the beneficiary is paid with its details at the time of the execution
+	beneficiary.Name = "Bip Bip Ltd"
+	require.NoError(t, beneficiaryRepository.Update(beneficiary))
+
+	detail, err = svc.BulkTransfer(bulkTransfer(referencing))
+	require.NoError(t, err)
+	require.Len(t, detail.Transactions, 1)
+	assert.Equal(t, "Bip Bip Ltd", detail.Transactions[0].CounterPartyName)
+	assert.False(t, detail.Transactions[0].FirstPayment)
+
+	referencing.BeneficiaryID = 99
+	_, err = svc.BulkTransfer(bulkTransfer(referencing))
+	var rejection *domain.Rejection
+	require.ErrorAs(t, err, &rejection)
+	assert.ErrorIs(t, rejection, ErrBeneficiaryNotFound)
+}
+
+func TestTransferServiceScreening(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+	screenerMock := mockscreening.NewMockScreener(ctrl)
+	screenerMock.EXPECT().Mode().Return(screening.ModeReject).AnyTimes()
+	reviewScreenerMock := mockscreening.NewMockScreener(ctrl)
+	reviewScreenerMock.EXPECT().Mode().Return(screening.ModeReview).AnyTimes()
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	_, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		BalanceCents:     10000,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	})
+	require.NoError(t, err)
+
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screenerMock, 0, fees.Schedule{}, tools.SystemClock{}, logMock)
+
+	data := domain.BulkTransfer{
+		OrganizationName: "ACME Corp",
+		OrganizationBic:  "OIVUSCLQXXX",
+		OrganizationIban: "FR81474608000002006107XXXXX",
+		SubmittedBy:      "john@acme.corp",
+		CreditTransfers: []domain.CreditTransfer{
+			{Amount: domain.NewMoney(1000, "EUR"), Currency: "EUR", CounterPartyName: "Bip Bip", CounterPartyBic: "CRLYFRPPTOU", CounterPartyIban: "EE303680981021245685"},
+			{Amount: domain.NewMoney(500, "EUR"), Currency: "EUR", CounterPartyName: "Wile E. Coyotte", CounterPartyBic: "CRLYFRPPTOU", CounterPartyIban: "DE44354208100362090817"},
+		},
+	}
+	hit := screening.Hit{Entry: screening.Entry{ID: "EU-1", Name: "Wile E. Coyote", Source: "EU"}, Field: screening.FieldName, Score: 0.9733}
+	match := domain.ScreeningMatch{Index: 1, Field: "counterparty_name", EntryID: "EU-1", EntryName: "Wile E. Coyote", EntrySource: "EU", Score: 0.973}
+	screen := func(screener *mockscreening.MockScreener, times int) {
+		screener.EXPECT().Screen("Bip Bip", "EE303680981021245685").Return(nil).Times(times)
+		screener.EXPECT().Screen("Wile E. Coyotte", "DE44354208100362090817").Return([]screening.Hit{hit}).Times(times)
+	}
+	balance := func() int64 {
+		bankAccount, err := bankAccountRepository.ReadByIban("FR81474608000002006107XXXXX")
+		require.NoError(t, err)
+		return bankAccount.BalanceCents
+	}
+
+	t.Run("Test BulkTransfer is rejected with the matched entry in reject mode", func(t *testing.T) {
+		screen(screenerMock, 2)
+
+		quote, err := svc.Quote(data)
+		require.NoError(t, err)
+		assert.False(t, quote.Executable)
+		assert.False(t, quote.RequiresReview)
+		require.Len(t, quote.Problems, 1)
+		assert.Equal(t, domain.RejectionScreeningHit, quote.Problems[0].Reason)
+
+		detail, err := svc.BulkTransfer(data)
+		var rejection *domain.Rejection
+		require.ErrorAs(t, err, &rejection)
+		assert.ErrorIs(t, rejection, ErrScreeningHit)
+		assert.Equal(t, `counterparty matches the screening list: credit_transfers[1].counterparty_name matches EU-1 "Wile E. Coyote"`, rejection.Message)
+		assert.Equal(t, []domain.ScreeningMatch{match}, rejection.Matches)
+		assert.Equal(t, domain.BulkTransferFailed, detail.Status)
+		assert.Equal(t, int64(10000), balance())
+	})
+
+	svc = New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), reviewScreenerMock, 0, fees.Schedule{}, tools.SystemClock{}, logMock)
+
+	t.Run("Test BulkTransfer is held until a reviewer releases it in review mode", func(t *testing.T) {
+		screen(reviewScreenerMock, 2)
+
+		quote, err := svc.Quote(data)
+		require.NoError(t, err)
+		assert.True(t, quote.Executable)
+		assert.True(t, quote.RequiresReview)
+		assert.Equal(t, []domain.ScreeningMatch{match}, quote.ScreeningMatches)
+
+		detail, err := svc.BulkTransfer(data)
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferHeldForReview, detail.Status)
+		require.NotNil(t, detail.Screening)
+		assert.Equal(t, []domain.ScreeningMatch{match}, detail.Screening.Matches)
+		assert.Nil(t, detail.Screening.Review)
+		assert.Empty(t, detail.Transactions)
+		assert.Equal(t, int64(10000), balance())
+
+		_, err = svc.Release(detail.ID, domain.BulkTransferRelease{}, "john@acme.corp")
+		assert.ErrorIs(t, err, ErrSelfReview)
+
+		// released bulk transfers aren't screened again
+		released, err := svc.Release(detail.ID, domain.BulkTransferRelease{Comment: "namesake"}, "compliance@acme.corp")
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferCompleted, released.Status)
+		assert.Len(t, released.Transactions, 2)
+		require.NotNil(t, released.Screening)
+		assert.Equal(t, []domain.ScreeningMatch{match}, released.Screening.Matches)
+		require.NotNil(t, released.Screening.Review)
+		assert.Equal(t, domain.ScreeningReleased, released.Screening.Review.Outcome)
+		assert.Equal(t, "compliance@acme.corp", released.Screening.Review.Actor)
+		assert.Equal(t, "namesake", released.Screening.Review.Comment)
+		assert.Equal(t, int64(8500), balance())
+
+		_, err = svc.Release(detail.ID, domain.BulkTransferRelease{}, "compliance@acme.corp")
+		assert.ErrorIs(t, err, ErrNotHeldForReview)
+	})
+
+	t.Run("Test BulkTransfer held for review is never executed once blocked", func(t *testing.T) {
+		screen(reviewScreenerMock, 1)
+
+		detail, err := svc.BulkTransfer(data)
+		require.NoError(t, err)
+		require.Equal(t, domain.BulkTransferHeldForReview, detail.Status)
+
+		blocked, err := svc.Block(detail.ID, domain.BulkTransferBlock{Reason: "confirmed match"}, "compliance@acme.corp")
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferBlocked, blocked.Status)
+		assert.Equal(t, domain.ScreeningBlocked, blocked.Screening.Review.Outcome)
+		assert.Equal(t, int64(8500), balance())
+
+		_, err = svc.Release(detail.ID, domain.BulkTransferRelease{}, "compliance@acme.corp")
+		assert.ErrorIs(t, err, ErrNotHeldForReview)
+	})
+}
+
+func TestTransferServiceDuplicates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	_, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		BalanceCents:     10000,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2022, 8, 23, 10, 0, 0, 0, time.UTC)
+	clock := tools.ClockFunc(func() time.Time { return now })
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screening.Disabled{}, 48*time.Hour, fees.Schedule{}, clock, logMock)
+
+	payroll := func(description string, allowDuplicates bool) domain.BulkTransfer {
+		return domain.BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+			AllowDuplicates:  allowDuplicates,
+			CreditTransfers: []domain.CreditTransfer{
+				{Amount: domain.NewMoney(1000, "EUR"), Currency: "EUR", CounterPartyName: "Bip Bip", CounterPartyBic: "CRLYFRPPTOU", CounterPartyIban: "EE303680981021245685", Description: description},
+			},
+		}
+	}
+	balance := func() int64 {
+		bankAccount, err := bankAccountRepository.ReadByIban("FR81474608000002006107XXXXX")
+		require.NoError(t, err)
+		return bankAccount.BalanceCents
+	}
+
+	first, err := svc.BulkTransfer(payroll("August payroll", false))
+	require.NoError(t, err)
+	require.Equal(t, domain.BulkTransferCompleted, first.Status)
+	duplicate := domain.Duplicate{Index: 0, TransactionID: first.Transactions[0].ID, BulkTransferID: first.ID, At: now}
+
+	now = now.Add(time.Hour)
+
+	t.Run("Test Quote warns about the duplicates", func(t *testing.T) {
+		quote, err := svc.Quote(payroll("August payroll", false))
+		require.NoError(t, err)
+		assert.False(t, quote.Executable)
+		assert.Equal(t, []domain.Duplicate{duplicate}, quote.Duplicates)
+		require.Len(t, quote.Problems, 1)
+		assert.Equal(t, domain.RejectionDuplicateSuspected, quote.Problems[0].Reason)
+
+		quote, err = svc.Quote(payroll("August payroll", true))
+		require.NoError(t, err)
+		assert.True(t, quote.Executable)
+		assert.Equal(t, []domain.Duplicate{duplicate}, quote.Duplicates)
+		assert.Empty(t, quote.Problems)
+	})
+
+	t.Run("Test BulkTransfer is rejected as a duplicate unless duplicates are allowed", func(t *testing.T) {
+		detail, err := svc.BulkTransfer(payroll("August payroll", false))
+		var rejection *domain.Rejection
+		require.ErrorAs(t, err, &rejection)
+		assert.ErrorIs(t, rejection, ErrDuplicateSuspected)
+		assert.Equal(t, []domain.Duplicate{duplicate}, rejection.Duplicates)
+		assert.Equal(t, domain.BulkTransferFailed, detail.Status)
+		assert.Equal(t, int64(9000), balance())
+
+		detail, err = svc.BulkTransfer(payroll("August payroll", true))
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+		assert.True(t, detail.AllowDuplicates)
+		assert.Equal(t, int64(8000), balance())
+	})
+
+	t.Run("Test BulkTransfer with another description isn't a duplicate", func(t *testing.T) {
+		detail, err := svc.BulkTransfer(payroll("August bonus", false))
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+	})
+
+	t.Run("Test BulkTransfer isn't a duplicate of the payments outside the window", func(t *testing.T) {
+		now = now.Add(49 * time.Hour)
+
+		quote, err := svc.Quote(payroll("August payroll", false))
+		require.NoError(t, err)
+		assert.True(t, quote.Executable)
+		assert.Empty(t, quote.Duplicates)
+	})
+}
+
+func TestTransferServiceOverdraft(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	_, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		BalanceCents:     1000,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+		OverdraftCents:   5000,
+	})
+	require.NoError(t, err)
+
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screening.Disabled{}, 0, fees.Schedule{}, tools.SystemClock{}, logMock)
+
+	bulkTransfer := func(amounts ...int64) domain.BulkTransfer {
+		data := domain.BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+		}
+		for _, amount := range amounts {
+			data.CreditTransfers = append(data.CreditTransfers, domain.CreditTransfer{Amount: domain.NewMoney(amount, "EUR"), Currency: "EUR", CounterPartyName: "Bip Bip", CounterPartyBic: "CRLYFRPPTOU", CounterPartyIban: "EE303680981021245685"})
+		}
+		return data
+	}
+	balance := func() int64 {
+		bankAccount, err := bankAccountRepository.ReadByIban("FR81474608000002006107XXXXX")
+		require.NoError(t, err)
+		return bankAccount.BalanceCents
+	}
+
+	t.Run("Test Quote reports the part paid from the overdraft", func(t *testing.T) {
+		quote, err := svc.Quote(bulkTransfer(800, 700))
+		require.NoError(t, err)
+		assert.True(t, quote.Executable)
+		assert.Equal(t, domain.NewMoney(-500, "EUR"), *quote.BalanceAfter)
+		assert.Equal(t, domain.NewMoney(500, "EUR"), *quote.OverdrawnAmount)
+	})
+
+	t.Run("Test BulkTransfer tags the transactions paid from the overdraft", func(t *testing.T) {
+		detail, err := svc.BulkTransfer(bulkTransfer(800, 700))
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+		require.Len(t, detail.Transactions, 2)
+		assert.Nil(t, detail.Transactions[0].OverdrawnAmount)
+		require.NotNil(t, detail.Transactions[1].OverdrawnAmount)
+		assert.Equal(t, domain.NewMoney(500, "EUR"), *detail.Transactions[1].OverdrawnAmount)
+		assert.Equal(t, int64(-500), balance())
+	})
+
+	t.Run("Test BulkTransfer is rejected beyond the overdraft", func(t *testing.T) {
+		_, err := svc.BulkTransfer(bulkTransfer(4501))
+		var rejection *domain.Rejection
+		require.ErrorAs(t, err, &rejection)
+		assert.ErrorIs(t, rejection, ErrInsufficientFunds)
+		assert.Equal(t, domain.NewMoney(4500, "EUR"), *rejection.AvailableAmount)
+
+		detail, err := svc.BulkTransfer(bulkTransfer(4500))
+		require.NoError(t, err)
+		assert.Equal(t, domain.NewMoney(4500, "EUR"), *detail.Transactions[0].OverdrawnAmount)
+		assert.Equal(t, int64(-5000), balance())
+	})
+}
+
+// TestTransferServiceFees runs against a real database: the credit transfers are charged the fees of the
+// schedule, on top of the total in the funds check, and the fees are posted as transactions of their own
+func TestTransferServiceFees(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	id, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+		Plan:             "standard",
+	})
+	require.NoError(t, err)
+	require.NoError(t, fundBankAccount(conn, uint(id), 10000))
+
+	schedule, err := fees.NewSchedule([]fees.Rule{
+		{Name: "international", Fixed: "5.00", RateBps: 10},
+		{Name: "sepa", Currency: "EUR", Countries: []string{"FR", "EE"}, Fixed: "0.50"},
+		{Name: "domestic-premium", Plan: "premium", Currency: "EUR", Countries: []string{"FR"}},
+	})
+	require.NoError(t, err)
+
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screening.Disabled{}, 0, schedule, tools.SystemClock{}, logMock)
+
+	bulkTransfer := func(creditTransfers ...domain.CreditTransfer) domain.BulkTransfer {
+		return domain.BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+			CreditTransfers:  creditTransfers,
+		}
+	}
+	creditTransfer := func(amount int64, iban string) domain.CreditTransfer {
+		return domain.CreditTransfer{Amount: domain.NewMoney(amount, "EUR"), Currency: "EUR", CounterPartyName: "Bip Bip", CounterPartyBic: "CRLYFRPPTOU", CounterPartyIban: iban}
+	}
+	sepa := creditTransfer(2000, "EE303680981021245685")
+	international := creditTransfer(5000, "GB33BUKB20201555555555")
+
+	t.Run("Test Quote reports the fees of every credit transfer", func(t *testing.T) {
+		quote, err := svc.Quote(bulkTransfer(sepa, international))
+		require.NoError(t, err)
+		assert.True(t, quote.Executable)
+		assert.Equal(t, domain.NewMoney(7000, "EUR"), quote.TotalAmount)
+		assert.Equal(t, &domain.FeeBreakdown{
+			TotalAmount: domain.NewMoney(555, "EUR"),
+			Fees: []domain.Fee{
+				{Index: 0, Rule: "sepa", Amount: domain.NewMoney(50, "EUR")},
+				{Index: 1, Rule: "international", Amount: domain.NewMoney(505, "EUR")},
+			},
+		}, quote.Fees)
+		assert.Equal(t, domain.NewMoney(2445, "EUR"), *quote.BalanceAfter)
+	})
+
+	t.Run("Test BulkTransfer is rejected when the balance doesn't cover the fees", func(t *testing.T) {
+		_, err := svc.BulkTransfer(bulkTransfer(creditTransfer(9990, "EE303680981021245685")))
+		var rejection *domain.Rejection
+		require.ErrorAs(t, err, &rejection)
+		assert.ErrorIs(t, rejection, ErrInsufficientFunds)
+		assert.Equal(t, domain.NewMoney(10040, "EUR"), *rejection.RequiredAmount)
+	})
+
+	t.Run("Test BulkTransfer posts the fees as transactions of the bulk transfer", func(t *testing.T) {
+		detail, err := svc.BulkTransfer(bulkTransfer(sepa, international))
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+		require.Len(t, detail.Transactions, 2)
+		require.NotNil(t, detail.Fees)
+		assert.Equal(t, domain.NewMoney(555, "EUR"), detail.Fees.TotalAmount)
+		require.Len(t, detail.Fees.Fees, 2)
+		assert.Equal(t, 1, detail.Fees.Fees[1].Index)
+		assert.Equal(t, "international", detail.Fees.Fees[1].Rule)
+
+		feeTransactions, err := transactionrepo.New(conn).ReadByFilter(map[string]string{"fee_of_transaction_id": strconv.FormatUint(uint64(detail.Transactions[1].ID), 10)})
+		require.NoError(t, err)
+		require.Len(t, feeTransactions, 1)
+		assert.Equal(t, detail.Fees.Fees[1].TransactionID, feeTransactions[0].ID)
+		assert.Equal(t, detail.ID, feeTransactions[0].BulkTransferID)
+		assert.Equal(t, domain.NewMoney(505, "EUR"), feeTransactions[0].Amount)
+		assert.False(t, feeTransactions[0].FirstPayment)
+
+		bankAccount, err := bankAccountRepository.Read(uint(id))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2445), bankAccount.BalanceCents)
+
+		balance, err := ledgerrepo.New(conn).Balance(ledgerrepo.AccountFees)
+		require.NoError(t, err)
+		assert.Equal(t, int64(555), balance)
+	})
+
+	t.Run("Test Quote applies the rules of the plan of the account", func(t *testing.T) {
+		bankAccount, err := bankAccountRepository.Read(uint(id))
+		require.NoError(t, err)
+		bankAccount.Plan = "premium"
+		require.NoError(t, bankAccountRepository.Update(bankAccount))
+
+		quote, err := svc.Quote(bulkTransfer(creditTransfer(1000, "FR7630006000011234567890189")))
+		require.NoError(t, err)
+		assert.Nil(t, quote.Fees)
+	})
+}
+
+func TestTransferServiceBestEffort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+	logMock.EXPECT().WithError(gomock.Any()).Return(logMock).AnyTimes()
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	id, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	})
+	require.NoError(t, err)
+	require.NoError(t, fundBankAccount(conn, uint(id), 10000))
+
+	schedule, err := fees.NewSchedule([]fees.Rule{{Name: "sepa", Currency: "EUR", Countries: []string{"EE"}, Fixed: "0.50"}})
+	require.NoError(t, err)
+
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screening.Disabled{}, 0, schedule, tools.SystemClock{}, logMock)
+
+	bulkTransfer := func(creditTransfers ...domain.CreditTransfer) domain.BulkTransfer {
+		return domain.BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+			ExecutionMode:    domain.ExecutionBestEffort,
+			CreditTransfers:  creditTransfers,
+		}
+	}
+	creditTransfer := func(amount int64, priority int) domain.CreditTransfer {
+		return domain.CreditTransfer{Amount: domain.NewMoney(amount, "EUR"), Currency: "EUR", CounterPartyName: "Bip Bip",
+			CounterPartyBic: "CRLYFRPPTOU", CounterPartyIban: "EE303680981021245685", Priority: priority}
+	}
+	dollars := creditTransfer(1000, 0)
+	dollars.Amount = domain.NewMoney(1000, "USD")
+	dollars.Currency = "USD"
+
+	// executed by priority: 4000 then 3000, 7100 with the fees, then 2900 isn't enough for 2900 and its fee
+	data := bulkTransfer(creditTransfer(3000, 0), dollars, creditTransfer(4000, 1), creditTransfer(2900, 0), creditTransfer(100, 0))
+
+	t.Run("Test Quote reports the outcome every line would have", func(t *testing.T) {
+		quote, err := svc.Quote(data)
+		require.NoError(t, err)
+		assert.True(t, quote.Executable)
+		require.NotNil(t, quote.Report)
+		assert.Equal(t, 2, quote.Report.Executed)
+		assert.Equal(t, 1, quote.Report.Rejected)
+		assert.Equal(t, 2, quote.Report.Skipped)
+		assert.Equal(t, domain.NewMoney(100, "EUR"), quote.Fees.TotalAmount)
+		assert.Equal(t, domain.NewMoney(2900, "EUR"), *quote.BalanceAfter)
+	})
+
+	t.Run("Test BulkTransfer executes the valid lines by priority until funds run out", func(t *testing.T) {
+		detail, err := svc.BulkTransfer(data)
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferPartiallyCompleted, detail.Status)
+		assert.Equal(t, domain.ExecutionBestEffort, detail.ExecutionMode)
+		require.Len(t, detail.Transactions, 2)
+		assert.Equal(t, domain.NewMoney(4000, "EUR"), detail.Transactions[0].Amount)
+
+		require.NotNil(t, detail.Report)
+		lines := detail.Report.Lines
+		require.Len(t, lines, 5)
+		assert.Equal(t, domain.LineExecuted, lines[0].Status)
+		assert.Equal(t, detail.Transactions[1].ID, lines[0].TransactionID)
+		assert.Equal(t, domain.LineRejected, lines[1].Status)
+		assert.Equal(t, domain.RejectionInvalidFields, lines[1].Reason)
+		assert.Equal(t, domain.LineExecuted, lines[2].Status)
+		assert.Equal(t, detail.Transactions[0].ID, lines[2].TransactionID)
+		for _, line := range lines[3:] {
+			assert.Equal(t, domain.LineSkipped, line.Status)
+			assert.Equal(t, domain.RejectionInsufficientFunds, line.Reason)
+			assert.Zero(t, line.TransactionID)
+		}
+
+		require.NotNil(t, detail.Fees)
+		require.Len(t, detail.Fees.Fees, 2)
+		assert.Equal(t, 2, detail.Fees.Fees[0].Index)
+		assert.Equal(t, 0, detail.Fees.Fees[1].Index)
+
+		bankAccount, err := bankAccountRepository.Read(uint(id))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2900), bankAccount.BalanceCents)
+	})
+
+	t.Run("Test BulkTransfer skips the lines beyond the headroom of a limit", func(t *testing.T) {
+		bankAccount, err := bankAccountRepository.Read(uint(id))
+		require.NoError(t, err)
+		// 7000 were transferred today
+		bankAccount.Limits.DailyCents = 7500
+		require.NoError(t, bankAccountRepository.Update(bankAccount))
+
+		detail, err := svc.BulkTransfer(bulkTransfer(creditTransfer(400, 0), creditTransfer(200, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferPartiallyCompleted, detail.Status)
+		assert.Equal(t, domain.LineExecuted, detail.Report.Lines[0].Status)
+		assert.Equal(t, domain.LineSkipped, detail.Report.Lines[1].Status)
+		assert.Equal(t, domain.RejectionLimitExceeded, detail.Report.Lines[1].Reason)
+	})
+
+	t.Run("Test BulkTransfer fails when no line can be executed", func(t *testing.T) {
+		detail, err := svc.BulkTransfer(bulkTransfer(dollars))
+		var rejection *domain.Rejection
+		require.ErrorAs(t, err, &rejection)
+		assert.Equal(t, domain.RejectionInvalidFields, rejection.Reason)
+		assert.Equal(t, domain.BulkTransferFailed, detail.Status)
+
+		bankAccount, err := bankAccountRepository.Read(uint(id))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2900-400-50), bankAccount.BalanceCents)
+	})
+
+	t.Run("Test BulkTransfer rejects an empty bulk transfer", func(t *testing.T) {
+		quote, err := svc.Quote(bulkTransfer())
+		require.NoError(t, err)
+		assert.False(t, quote.Executable)
+		require.NotEmpty(t, quote.Problems)
+		assert.Equal(t, domain.RejectionInvalidFields, quote.Problems[0].Reason)
+
+		var rejection *domain.Rejection
+		_, err = svc.BulkTransfer(bulkTransfer())
+		require.ErrorAs(t, err, &rejection)
+		assert.Equal(t, domain.RejectionInvalidFields, rejection.Reason)
+
+		// even when nothing reported a problem
+		err = uow.New(conn).Do(func(repos uow.Repositories) error {
+			sel, err := selectLines(repos, plan{}, bulkTransfer(), time.Now().UTC())
+			require.NoError(t, err)
+			require.NotNil(t, sel.blocking)
+			assert.ErrorIs(t, sel.blocking, ErrNothingToExecute)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Test BulkTransfer is completed when every line was executed", func(t *testing.T) {
+		require.NoError(t, fundBankAccount(conn, uint(id), 10000))
+		bankAccount, err := bankAccountRepository.Read(uint(id))
+		require.NoError(t, err)
+		bankAccount.Limits.DailyCents = 0
+		require.NoError(t, bankAccountRepository.Update(bankAccount))
+
+		detail, err := svc.BulkTransfer(bulkTransfer(creditTransfer(100, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+		assert.Equal(t, 1, detail.Report.Executed)
+	})
+}
+
+func TestTransferServiceJobs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+	logMock.EXPECT().WithError(gomock.Any()).Return(logMock).AnyTimes()
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	bankAccountRepository := bankaccountrepo.New(conn)
+	id, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	})
+	require.NoError(t, err)
+	require.NoError(t, fundBankAccount(conn, uint(id), 10000))
+
+	bulkTransferRepository := bulktransferrepo.New(conn)
+	svc := New(uow.New(conn), bulkTransferRepository, transactionrepo.New(conn), screening.Disabled{}, 0, fees.Schedule{}, tools.SystemClock{}, logMock)
+
+	bulkTransfer := func(amounts ...int64) domain.BulkTransfer {
+		data := domain.BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+		}
+		for _, amount := range amounts {
+			data.CreditTransfers = append(data.CreditTransfers, domain.CreditTransfer{Amount: domain.NewMoney(amount, "EUR"),
+				Currency: "EUR", CounterPartyName: "Bip Bip", CounterPartyBic: "CRLYFRPPTOU", CounterPartyIban: "EE303680981021245685"})
+		}
+		return data
+	}
+	balance := func() int64 {
+		bankAccount, err := bankAccountRepository.Read(uint(id))
+		require.NoError(t, err)
+		return bankAccount.BalanceCents
+	}
+
+	t.Run("Test Submit queues the bulk transfer without executing it", func(t *testing.T) {
+		job, err := svc.Submit(bulkTransfer(1000, 2000))
+		require.NoError(t, err)
+		assert.NotZero(t, job.ID)
+		assert.Equal(t, domain.JobQueued, job.Status)
+		assert.Equal(t, 2, job.LinesTotal)
+		assert.Nil(t, job.StartedAt)
+
+		detail, err := svc.Read(job.BulkTransferID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferQueued, detail.Status)
+		assert.Equal(t, int64(10000), balance())
+
+		pending, err := svc.PendingJobs()
+		require.NoError(t, err)
+		assert.Equal(t, []uint{job.ID}, pending)
+	})
+
+	t.Run("Test RunJob executes the bulk transfer and records its progress", func(t *testing.T) {
+		job, err := svc.Submit(bulkTransfer(500))
+		require.NoError(t, err)
+
+		require.NoError(t, svc.RunJob(job.ID))
+
+		job, err = svc.ReadJob(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.JobDone, job.Status)
+		assert.Equal(t, 1, job.LinesDone)
+		assert.NotNil(t, job.StartedAt)
+		assert.NotNil(t, job.FinishedAt)
+
+		detail, err := svc.Read(job.BulkTransferID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+		assert.Equal(t, int64(9500), balance())
+	})
+
+	t.Run("Test RunJob records the reason the bulk transfer failed", func(t *testing.T) {
+		job, err := svc.Submit(bulkTransfer(100000))
+		require.NoError(t, err)
+
+		require.NoError(t, svc.RunJob(job.ID))
+
+		job, err = svc.ReadJob(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.JobFailed, job.Status)
+		assert.Equal(t, ErrInsufficientFunds.Error(), job.Error)
+		assert.Zero(t, job.LinesDone)
+		assert.Equal(t, int64(9500), balance())
+	})
+
+	t.Run("Test RunJob resumes a job left running before its bulk transfer was executed", func(t *testing.T) {
+		job, err := svc.Submit(bulkTransfer(200))
+		require.NoError(t, err)
+
+		// the process stopped while executing the bulk transfer
+		stored, err := bulkTransferRepository.Read(job.BulkTransferID)
+		require.NoError(t, err)
+		stored.Status = string(domain.BulkTransferProcessing)
+		require.NoError(t, bulkTransferRepository.Transition(stored, string(domain.BulkTransferQueued)))
+		running, err := bulkTransferRepository.ReadJob(job.ID)
+		require.NoError(t, err)
+		running.Status = string(domain.JobRunning)
+		running.StartedAt = time.Now().UTC()
+		require.NoError(t, bulkTransferRepository.UpdateJob(running))
+
+		require.NoError(t, svc.RunJob(job.ID))
+
+		job, err = svc.ReadJob(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.JobDone, job.Status)
+		assert.Equal(t, int64(9300), balance())
+	})
+
+	t.Run("Test RunJob doesn't execute again a bulk transfer committed before the process stopped", func(t *testing.T) {
+		job, err := svc.Submit(bulkTransfer(300))
+		require.NoError(t, err)
+		require.NoError(t, svc.RunJob(job.ID))
+
+		// the process stopped before the job was finished
+		running, err := bulkTransferRepository.ReadJob(job.ID)
+		require.NoError(t, err)
+		running.Status = string(domain.JobRunning)
+		running.FinishedAt = time.Time{}
+		require.NoError(t, bulkTransferRepository.UpdateJob(running))
+
+		require.NoError(t, svc.RunJob(job.ID))
+
+		job, err = svc.ReadJob(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.JobDone, job.Status)
+		assert.Equal(t, 1, job.LinesDone)
+		assert.Equal(t, int64(9000), balance())
+	})
+
+	t.Run("Test PendingJobs lists only the unfinished jobs", func(t *testing.T) {
+		pending, err := svc.PendingJobs()
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+
+		require.NoError(t, svc.RunJob(pending[0]))
+
+		pending, err = svc.PendingJobs()
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+		assert.Equal(t, int64(6000), balance())
+	})
+
+	t.Run("Test RunJob leaves a job claimed by another worker since it was read", func(t *testing.T) {
+		job, err := svc.Submit(bulkTransfer(400))
+		require.NoError(t, err)
+		queued, err := bulkTransferRepository.ReadJob(job.ID)
+		require.NoError(t, err)
+
+		// claimed by the other worker
+		claimed := queued
+		claimed.StartedAt = time.Now().UTC()
+		claimed.Owner = "other-worker"
+		claimed.LeaseExpiresAt = time.Now().UTC().Add(time.Minute)
+		require.NoError(t, bulkTransferRepository.ClaimJob(claimed, time.Now().UTC()))
+
+		late := New(uow.New(conn), staleJobs{Repo: bulkTransferRepository, job: queued}, transactionrepo.New(conn), screening.Disabled{}, 0, fees.Schedule{}, tools.SystemClock{}, logMock)
+		require.NoError(t, late.RunJob(job.ID))
+
+		detail, err := svc.Read(job.BulkTransferID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferQueued, detail.Status)
+		assert.Equal(t, int64(6000), balance())
+	})
+
+	t.Run("Test RunJob leaves a running job whose lease didn't expire", func(t *testing.T) {
+		job, err := svc.Submit(bulkTransfer(400))
+		require.NoError(t, err)
+
+		// held by the worker of another process, still executing it
+		running, err := bulkTransferRepository.ReadJob(job.ID)
+		require.NoError(t, err)
+		running.Status = string(domain.JobRunning)
+		running.StartedAt = time.Now().UTC()
+		running.Owner = "other-process"
+		running.LeaseExpiresAt = time.Now().UTC().Add(time.Minute)
+		require.NoError(t, bulkTransferRepository.UpdateJob(running))
+
+		require.NoError(t, svc.RunJob(job.ID))
+
+		stored, err := bulkTransferRepository.ReadJob(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, string(domain.JobRunning), stored.Status)
+		assert.Equal(t, "other-process", stored.Owner)
+		detail, err := svc.Read(job.BulkTransferID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferQueued, detail.Status)
+		assert.Equal(t, int64(6000), balance())
+
+		// the lease expired, the other process stopped
+		running.LeaseExpiresAt = time.Now().UTC().Add(-time.Second)
+		require.NoError(t, bulkTransferRepository.UpdateJob(running))
+
+		require.NoError(t, svc.RunJob(job.ID))
+
+		stored, err = bulkTransferRepository.ReadJob(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, string(domain.JobDone), stored.Status)
+		assert.NotEqual(t, "other-process", stored.Owner)
+		assert.True(t, stored.LeaseExpiresAt.IsZero())
+		assert.Equal(t, int64(5600), balance())
+	})
+
+	t.Run("Test RunJob rolls back the execution of a bulk transfer settled by another runner meanwhile", func(t *testing.T) {
+		job, err := svc.Submit(bulkTransfer(600))
+		require.NoError(t, err)
+		stored, err := bulkTransferRepository.Read(job.BulkTransferID)
+		require.NoError(t, err)
+		stored.Status = string(domain.BulkTransferProcessing)
+		require.NoError(t, bulkTransferRepository.Transition(stored, string(domain.BulkTransferQueued)))
+
+		// the other runner executed it after this one read it as processing, then lost its lease
+		require.NoError(t, svc.RunJob(job.ID))
+		assert.Equal(t, int64(5000), balance())
+		running, err := bulkTransferRepository.ReadJob(job.ID)
+		require.NoError(t, err)
+		running.Status = string(domain.JobRunning)
+		running.FinishedAt = time.Time{}
+		require.NoError(t, bulkTransferRepository.UpdateJob(running))
+
+		late := New(uow.New(conn), &staleBulkTransfers{Repo: bulkTransferRepository, bulkTransfer: stored}, transactionrepo.New(conn), screening.Disabled{}, 0, fees.Schedule{}, tools.SystemClock{}, logMock)
+		require.NoError(t, late.RunJob(job.ID))
+
+		detail, err := svc.Read(job.BulkTransferID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferCompleted, detail.Status)
+		assert.Len(t, detail.Transactions, 1)
+		assert.Equal(t, int64(5000), balance())
+	})
+
+	t.Run("Test RunJob defers the job of a bulk transfer left pending approval", func(t *testing.T) {
+		approvalID, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+			OrganizationName: "Bip Bip",
+			Iban:             "EE303680981021245685",
+			Bic:              "CRLYFRPPTOU",
+			Approval:         bankaccountrepo.Approval{ThresholdCents: 1000, RequiredApprovals: 1},
+		})
+		require.NoError(t, err)
+		require.NoError(t, fundBankAccount(conn, uint(approvalID), 10000))
+
+		data := domain.BulkTransfer{
+			OrganizationName: "Bip Bip",
+			OrganizationBic:  "CRLYFRPPTOU",
+			OrganizationIban: "EE303680981021245685",
+			SubmittedBy:      "john@acme.corp",
+			CreditTransfers: []domain.CreditTransfer{{Amount: domain.NewMoney(2000, "EUR"), Currency: "EUR",
+				CounterPartyName: "Wile E Coyote", CounterPartyBic: "CRLYFRPPTOU", CounterPartyIban: "DE44354208100362090817"}},
+		}
+		job, err := svc.Submit(data)
+		require.NoError(t, err)
+
+		require.NoError(t, svc.RunJob(job.ID))
+
+		job, err = svc.ReadJob(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.JobDeferred, job.Status)
+		assert.Zero(t, job.LinesDone)
+		assert.Empty(t, job.Error)
+		assert.NotNil(t, job.FinishedAt)
+
+		detail, err := svc.Read(job.BulkTransferID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.BulkTransferPendingApproval, detail.Status)
+		bankAccount, err := bankAccountRepository.Read(uint(approvalID))
+		require.NoError(t, err)
+		assert.Equal(t, int64(10000), bankAccount.BalanceCents)
+
+		pending, err := svc.PendingJobs()
+		require.NoError(t, err)
+		assert.NotContains(t, pending, job.ID)
+	})
+
+	t.Run("Test ReadJob return error when not found", func(t *testing.T) {
+		_, err := svc.ReadJob(1000)
+		assert.ErrorIs(t, err, ErrJobNotFound)
+		assert.ErrorIs(t, svc.RunJob(1000), ErrJobNotFound)
+	})
+}
+
+// staleJobs reads the job as it was before another worker claimed it
+type staleJobs struct {
+	bulktransferrepo.Repo
+	job bulktransferrepo.Job
+}
+
+func (r staleJobs) ReadJob(uint) (bulktransferrepo.Job, error) {
+	return r.job, nil
+}
+
+// staleBulkTransfers reads the bulk transfer, the first time, as it was before another runner settled it
+type staleBulkTransfers struct {
+	bulktransferrepo.Repo
+	bulkTransfer bulktransferrepo.BulkTransfer
+	read         bool
+}
+
+func (r *staleBulkTransfers) Read(bulkTransferID uint) (bulktransferrepo.BulkTransfer, error) {
+	if r.read {
+		return r.Repo.Read(bulkTransferID)
+	}
+	r.read = true
+	return r.bulkTransfer, nil
+}
+
+func TestTransferServiceWebhooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+	logMock.EXPECT().WithError(gomock.Any()).Return(logMock).AnyTimes()
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	id, err := openBankAccount(conn, bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	})
+	require.NoError(t, err)
+	require.NoError(t, fundBankAccount(conn, uint(id), 10000))
+
+	webhookRepository := webhookrepo.New(conn)
+	webhookID, err := webhookRepository.Create(webhookrepo.Webhook{
+		BankAccountID: uint(id),
+		URL:           "https://erp.acme.corp/hooks",
+		Secret:        "5ec2e7",
+		Events:        []string{string(domain.WebhookBulkTransferCompleted), string(domain.WebhookBulkTransferRejected), string(domain.WebhookTransactionCreated)},
+		CreatedAt:     time.Now().UTC(),
+	})
+	require.NoError(t, err)
+
+	svc := New(uow.New(conn), bulktransferrepo.New(conn), transactionrepo.New(conn), screening.Disabled{}, 0, fees.Schedule{}, tools.SystemClock{}, logMock)
+
+	bulkTransfer := func(amounts ...int64) domain.BulkTransfer {
+		data := domain.BulkTransfer{
+			OrganizationName: "ACME Corp",
+			OrganizationBic:  "OIVUSCLQXXX",
+			OrganizationIban: "FR81474608000002006107XXXXX",
+		}
+		for _, amount := range amounts {
+			data.CreditTransfers = append(data.CreditTransfers, domain.CreditTransfer{Amount: domain.NewMoney(amount, "EUR"),
+				Currency: "EUR", CounterPartyName: "Bip Bip", CounterPartyBic: "CRLYFRPPTOU", CounterPartyIban: "EE303680981021245685"})
+		}
+		return data
+	}
+	events := func() []string {
+		deliveries, err := webhookRepository.ReadDeliveries(uint(webhookID))
+		require.NoError(t, err)
+		res := []string{}
+		for _, delivery := range deliveries {
+			res = append(res, delivery.Event)
+		}
+		return res
+	}
+
+	t.Run("Test BulkTransfer queues the events of its transactions and of its completion", func(t *testing.T) {
+		_, err := svc.BulkTransfer(bulkTransfer(1000, 2000))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"bulk_transfer.completed", "transaction.created", "transaction.created"}, events())
+	})
+
+	t.Run("Test BulkTransfer queues the rejection of a failed bulk transfer only", func(t *testing.T) {
+		_, err := svc.BulkTransfer(bulkTransfer(50000))
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+		assert.Equal(t, []string{"bulk_transfer.rejected", "bulk_transfer.completed", "transaction.created", "transaction.created"}, events())
+	})
+}
+
+// openBankAccount registers the bank account with the posting of its opening balance, as the bank account service does
+func openBankAccount(conn config.Conn, bankAccount bankaccountrepo.BankAccount) (int, error) {
+	id, err := bankaccountrepo.New(conn).Create(bankAccount)
+	if err != nil || bankAccount.BalanceCents == 0 {
+		return id, err
+	}
+	account := ledgerrepo.BankAccount(uint(id))
+	_, err = ledgerrepo.New(conn).Post(ledgerrepo.Journal{Operation: string(domain.LedgerOpeningBalance), Reference: account, CreatedAt: time.Now().UTC()},
+		ledgerrepo.AgainstEquity(account, bankAccount.BalanceCents))
+	return id, err
+}
+
+// fundBankAccount credits the bank account and posts the deposit on the ledger as an adjustment against the equity
+func fundBankAccount(conn config.Conn, id uint, cents int64) error {
+	if err := bankaccountrepo.New(conn).Credit(id, cents); err != nil {
+		return err
+	}
+	account := ledgerrepo.BankAccount(id)
+	_, err := ledgerrepo.New(conn).Post(ledgerrepo.Journal{Operation: string(domain.LedgerAdjustment), Reference: account, CreatedAt: time.Now().UTC()},
+		ledgerrepo.AgainstEquity(account, cents))
+	return err
 }
@@ -0,0 +1,138 @@
+package webhooksvc
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/webhookrepo"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// PendingDeliveries the deliveries whose next attempt is due, the longest waiting first
+func (s service) PendingDeliveries() ([]uint, error) {
+	deliveries, err := s.webhookRepo.ReadDueDeliveries(s.clock.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []uint{}
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.ID)
+	}
+
+	return ids, nil
+}
+
+// Deliver posts the payload of a pending delivery to its webhook, signed with the secret of the webhook. It is
+// delivered once the receiver answers with a 2xx status. Otherwise the next attempt waits for the backoff, doubled
+// after every attempt, until the attempts run out and it fails. Deliveries that aren't pending are left as they are.
+func (s service) Deliver(deliveryID uint) error {
+	delivery, err := s.webhookRepo.ReadDelivery(deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %d", ErrDeliveryNotFound, deliveryID)
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status != string(domain.DeliveryPending) {
+		return nil
+	}
+
+	webhook, err := read(s.webhookRepo, delivery.WebhookID)
+	if errors.Is(err, ErrWebhookNotFound) {
+		delivery.Status = string(domain.DeliveryFailed)
+		delivery.NextAttemptAt = time.Time{}
+		delivery.Error = "the webhook was deleted"
+		delivery.UpdatedAt = s.clock.Now().UTC()
+		return s.webhookRepo.UpdateDelivery(delivery)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := s.clock.Now().UTC()
+	delivery.Attempts++
+	delivery.ResponseStatus, err = s.post(webhook, delivery, now)
+	delivery.UpdatedAt = now
+	switch {
+	case err == nil:
+		delivery.Status = string(domain.DeliveryDelivered)
+		delivery.NextAttemptAt = time.Time{}
+		delivery.Error = ""
+		delivery.DeliveredAt = now
+	case delivery.Attempts >= s.retry.MaxAttempts:
+		delivery.Status = string(domain.DeliveryFailed)
+		delivery.NextAttemptAt = time.Time{}
+		delivery.Error = err.Error()
+	default:
+		delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempts))
+		delivery.Error = err.Error()
+	}
+	if err != nil {
+		s.logger.Warn("webhook delivery failed", zap.Uint("delivery_id", delivery.ID), zap.Int("attempts", delivery.Attempts),
+			zap.String("status", delivery.Status), zap.String("error", delivery.Error))
+	}
+
+	return s.webhookRepo.UpdateDelivery(delivery)
+}
+
+// post sends the delivery to the url of the webhook, returning the status the receiver answered with, if it answered,
+// and why it wasn't acknowledged
+func (s service) post(webhook webhookrepo.Webhook, delivery webhookrepo.Delivery, now time.Time) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(tools.HeaderContentType, "application/json")
+	req.Header.Set(tools.HeaderWebhookEvent, delivery.Event)
+	req.Header.Set(tools.HeaderWebhookDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(tools.HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(tools.HeaderWebhookSignature, Sign(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("the receiver answered %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// backoff the wait after the given number of attempts
+func (s service) backoff(attempts int) time.Duration {
+	wait := s.retry.Backoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		return maxBackoff
+	}
+	return wait
+}
+
+// Sign the signature of a delivery: sha256= followed by the hex HMAC-SHA256, keyed with the secret of the webhook,
+// of the timestamp, a dot and the body. Receivers compute it the same way to check the delivery.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,79 @@
+package webhooksvc
+
+import (
+	"encoding/json"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/webhookrepo"
+	"time"
+)
+
+// bulkTransferEvents the event raised by the bulk transfers reaching each status
+var bulkTransferEvents = map[domain.BulkTransferStatus]domain.WebhookEvent{
+	domain.BulkTransferCompleted:          domain.WebhookBulkTransferCompleted,
+	domain.BulkTransferPartiallyCompleted: domain.WebhookBulkTransferCompleted,
+	domain.BulkTransferFailed:             domain.WebhookBulkTransferRejected,
+	domain.BulkTransferRejected:           domain.WebhookBulkTransferRejected,
+	domain.BulkTransferBlocked:            domain.WebhookBulkTransferRejected,
+}
+
+// PublishBulkTransfer queues the event of the status the bulk transfer reached, if it raises one, for the webhooks of
+// its organization. It is called with the repository of the unit of work changing the status, so the event is
+// delivered if and only if the change is committed.
+func PublishBulkTransfer(repo webhookrepo.WebhookRepository, bulkTransfer bulktransferrepo.BulkTransfer) error {
+	event, ok := bulkTransferEvents[domain.BulkTransferStatus(bulkTransfer.Status)]
+	if !ok {
+		return nil
+	}
+
+	return publish(repo, bulkTransfer.OrganizationIban, event, bulkTransfer.UpdatedAt, domain.BulkTransferEvent{
+		ID:               bulkTransfer.ID,
+		OrganizationIban: bulkTransfer.OrganizationIban,
+		TransfersCount:   bulkTransfer.TransfersCount,
+		TotalAmount:      domain.NewMoney(bulkTransfer.TotalCents, domain.AccountCurrency),
+		Status:           domain.BulkTransferStatus(bulkTransfer.Status),
+		FailureReason:    bulkTransfer.FailureReason,
+	})
+}
+
+// PublishTransaction queues the transaction.created event of a transaction registered on the bank account for the
+// webhooks of the account, within the unit of work registering it
+func PublishTransaction(repo webhookrepo.WebhookRepository, bankAccount bankaccountrepo.BankAccount, transaction transactionrepo.Transaction) error {
+	createdAt := transaction.CreatedAt
+	data := domain.Transaction{
+		ID:                    transaction.ID,
+		Name:                  bankAccount.OrganizationName,
+		Iban:                  bankAccount.Iban,
+		Bic:                   bankAccount.Bic,
+		CounterPartyName:      transaction.CounterPartyName,
+		CounterPartyIban:      transaction.CounterPartyIban,
+		CounterPartyBic:       transaction.CounterPartyBic,
+		Amount:                domain.NewMoney(transaction.AmountCents, transaction.AmountCurrency),
+		Currency:              transaction.AmountCurrency,
+		Description:           transaction.Description,
+		Direction:             domain.TransactionDirection(transaction.Direction),
+		BulkTransferID:        transaction.BulkTransferID,
+		ReversedTransactionID: transaction.ReversedTransactionID,
+		BeneficiaryID:         transaction.BeneficiaryID,
+		FeeOfTransactionID:    transaction.FeeOfTransactionID,
+		CreatedAt:             &createdAt,
+	}
+	if transaction.OverdrawnCents > 0 {
+		overdrawn := domain.NewMoney(transaction.OverdrawnCents, transaction.AmountCurrency)
+		data.OverdrawnAmount = &overdrawn
+	}
+
+	return publish(repo, bankAccount.Iban, domain.WebhookTransactionCreated, transaction.CreatedAt, data)
+}
+
+// publish queues a delivery of the event to every webhook of the organization subscribing to it
+func publish(repo webhookrepo.WebhookRepository, organizationIban string, event domain.WebhookEvent, occurredAt time.Time, data interface{}) error {
+	payload, err := json.Marshal(domain.WebhookPayload{Event: event, OccurredAt: occurredAt, Data: data})
+	if err != nil {
+		return err
+	}
+
+	return repo.CreateDeliveries(organizationIban, string(event), string(payload), occurredAt)
+}
@@ -0,0 +1,279 @@
+package webhooksvc
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/webhookrepo"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrWebhookNotFound is returned when the webhook doesn't exist or was deleted
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrDeliveryNotFound is returned when the delivery doesn't exist on the webhook
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	// ErrDeliveryPending is returned when replaying a delivery that is still being attempted
+	ErrDeliveryPending = errors.New("the delivery is still pending")
+	// ErrAccountNotFound is returned when the bank account of the webhook doesn't exist
+	ErrAccountNotFound = errors.New("bank account not found")
+	// ErrMissingAccount is returned when listing the webhooks without the bank account they belong to
+	ErrMissingAccount = errors.New("organization_iban is required")
+)
+
+// Retry how the deliveries the receiver didn't acknowledge are attempted again
+type Retry struct {
+	// MaxAttempts the attempts of a delivery before it fails
+	MaxAttempts int
+	// Backoff the wait before the second attempt, doubled after every attempt
+	Backoff time.Duration
+}
+
+// maxBackoff caps the wait between two attempts
+const maxBackoff = 6 * time.Hour
+
+// WebhookService Interface for the webhook services
+type WebhookService interface {
+	Create(data domain.Webhook) (domain.WebhookDetail, error)
+	Read(webhookID uint) (domain.WebhookDetail, error)
+	ReadByFilter(filters map[string]string) (domain.WebhookDetailList, error)
+	Delete(webhookID uint) error
+	ReadDeliveries(webhookID uint) (domain.WebhookDeliveryList, error)
+	Replay(webhookID uint, deliveryID uint) (domain.WebhookDelivery, error)
+	PendingDeliveries() ([]uint, error)
+	Deliver(deliveryID uint) error
+}
+
+// New returns an instance of the webhook services, posting the deliveries with the client
+func New(unitOfWork uow.UnitOfWork, webhookRepo webhookrepo.WebhookRepository, client *http.Client, retry Retry, clock tools.Clock, logger log.Logger) WebhookService {
+	return service{
+		logger:      logger,
+		clock:       clock,
+		unitOfWork:  unitOfWork,
+		webhookRepo: webhookRepo,
+		client:      client,
+		retry:       retry,
+	}
+}
+
+type service struct {
+	logger      log.Logger
+	clock       tools.Clock
+	unitOfWork  uow.UnitOfWork
+	webhookRepo webhookrepo.WebhookRepository
+	client      *http.Client
+	retry       Retry
+}
+
+// Create subscribes the bank account to the events, generating the secret signing their deliveries. The secret is
+// only returned here.
+func (s service) Create(data domain.Webhook) (domain.WebhookDetail, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return domain.WebhookDetail{}, err
+	}
+
+	webhook := webhookrepo.Webhook{
+		OrganizationIban: data.OrganizationIban,
+		URL:              data.URL,
+		Secret:           secret,
+		CreatedAt:        s.clock.Now().UTC(),
+	}
+	seen := map[domain.WebhookEvent]bool{}
+	for _, event := range data.Events {
+		if !seen[event] {
+			seen[event] = true
+			webhook.Events = append(webhook.Events, string(event))
+		}
+	}
+
+	err = s.unitOfWork.Do(func(repos uow.Repositories) error {
+		bankAccount, err := repos.BankAccount.ReadByIban(data.OrganizationIban)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrAccountNotFound, data.OrganizationIban)
+		}
+		if err != nil {
+			return err
+		}
+		webhook.BankAccountID = bankAccount.ID
+
+		id, err := repos.Webhook.Create(webhook)
+		if err != nil {
+			return err
+		}
+		webhook.ID = uint(id)
+		return nil
+	})
+	if err != nil {
+		return domain.WebhookDetail{}, err
+	}
+
+	detail := toDetail(webhook)
+	detail.Secret = webhook.Secret
+	return detail, nil
+}
+
+// Read a webhook
+func (s service) Read(webhookID uint) (domain.WebhookDetail, error) {
+	webhook, err := read(s.webhookRepo, webhookID)
+	if err != nil {
+		return domain.WebhookDetail{}, err
+	}
+
+	return toDetail(webhook), nil
+}
+
+// ReadByFilter list of the webhooks of a bank account, the organization_iban filter is required
+func (s service) ReadByFilter(filters map[string]string) (domain.WebhookDetailList, error) {
+	if filters["organization_iban"] == "" {
+		return nil, ErrMissingAccount
+	}
+
+	webhooks, err := s.webhookRepo.ReadByFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	res := domain.WebhookDetailList{}
+	for _, webhook := range webhooks {
+		res = append(res, toDetail(webhook))
+	}
+
+	return res, nil
+}
+
+// Delete a webhook, it is no longer notified and its pending deliveries fail. Its delivery log is kept.
+func (s service) Delete(webhookID uint) error {
+	return s.unitOfWork.Do(func(repos uow.Repositories) error {
+		if _, err := read(repos.Webhook, webhookID); err != nil {
+			return err
+		}
+
+		return repos.Webhook.Delete(webhookID, s.clock.Now().UTC())
+	})
+}
+
+// ReadDeliveries the delivery log of a webhook, latest first
+func (s service) ReadDeliveries(webhookID uint) (domain.WebhookDeliveryList, error) {
+	if _, err := read(s.webhookRepo, webhookID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.webhookRepo.ReadDeliveries(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := domain.WebhookDeliveryList{}
+	for _, delivery := range deliveries {
+		res = append(res, toDelivery(delivery))
+	}
+
+	return res, nil
+}
+
+// Replay delivers again the payload of a delivery of the webhook that was delivered or failed, as a new delivery
+// attempted right away
+func (s service) Replay(webhookID uint, deliveryID uint) (domain.WebhookDelivery, error) {
+	var replay webhookrepo.Delivery
+	err := s.unitOfWork.Do(func(repos uow.Repositories) error {
+		if _, err := read(repos.Webhook, webhookID); err != nil {
+			return err
+		}
+
+		original, err := repos.Webhook.ReadDelivery(deliveryID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && original.WebhookID != webhookID) {
+			return fmt.Errorf("%w: %d isn't a delivery of webhook %d", ErrDeliveryNotFound, deliveryID, webhookID)
+		}
+		if err != nil {
+			return err
+		}
+		if original.Status == string(domain.DeliveryPending) {
+			return ErrDeliveryPending
+		}
+
+		now := s.clock.Now().UTC()
+		replay = webhookrepo.Delivery{
+			WebhookID:     webhookID,
+			Event:         original.Event,
+			Payload:       original.Payload,
+			Status:        string(domain.DeliveryPending),
+			NextAttemptAt: now,
+			ReplayOf:      original.ID,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		id, err := repos.Webhook.CreateDelivery(replay)
+		replay.ID = uint(id)
+		return err
+	})
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+
+	return toDelivery(replay), nil
+}
+
+func read(repo webhookrepo.WebhookRepository, webhookID uint) (webhookrepo.Webhook, error) {
+	webhook, err := repo.Read(webhookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhookrepo.Webhook{}, ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+// newSecret a random secret to sign the deliveries of a webhook
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func toDetail(webhook webhookrepo.Webhook) domain.WebhookDetail {
+	events := []domain.WebhookEvent{}
+	for _, event := range webhook.Events {
+		events = append(events, domain.WebhookEvent(event))
+	}
+
+	return domain.WebhookDetail{
+		ID:               webhook.ID,
+		OrganizationIban: webhook.OrganizationIban,
+		URL:              webhook.URL,
+		Events:           events,
+		CreatedAt:        webhook.CreatedAt,
+	}
+}
+
+func toDelivery(delivery webhookrepo.Delivery) domain.WebhookDelivery {
+	return domain.WebhookDelivery{
+		ID:             delivery.ID,
+		WebhookID:      delivery.WebhookID,
+		Event:          domain.WebhookEvent(delivery.Event),
+		Payload:        json.RawMessage(delivery.Payload),
+		Status:         domain.DeliveryStatus(delivery.Status),
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  optionalTime(delivery.NextAttemptAt),
+		ResponseStatus: delivery.ResponseStatus,
+		Error:          delivery.Error,
+		ReplayOf:       delivery.ReplayOf,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    optionalTime(delivery.DeliveredAt),
+	}
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
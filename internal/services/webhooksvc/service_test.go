@@ -0,0 +1,265 @@
+package webhooksvc
+
+import (
+	"encoding/json"
+	"github.com/adrianoccosta/exercise-qonto/cmd/config"
+	"github.com/adrianoccosta/exercise-qonto/internal/domain"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bankaccountrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/uow"
+	"github.com/adrianoccosta/exercise-qonto/internal/repository/webhookrepo"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/adrianoccosta/exercise-qonto/tools"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+	logMock.EXPECT().Warn("webhook delivery failed", gomock.Any()).AnyTimes()
+
+	conn := config.InitDBConnection(config.DBConnection{
+		Path:         filepath.Join(t.TempDir(), "qonto.sqlite"),
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}, logMock)
+	defer conn.Conn.Close()
+	require.NoError(t, config.Migrate(conn))
+
+	_, err := bankaccountrepo.New(conn).Create(bankaccountrepo.BankAccount{
+		OrganizationName: "ACME Corp",
+		BalanceCents:     10000,
+		Iban:             "FR81474608000002006107XXXXX",
+		Bic:              "OIVUSCLQXXX",
+	})
+	require.NoError(t, err)
+	bankAccount, err := bankaccountrepo.New(conn).ReadByIban("FR81474608000002006107XXXXX")
+	require.NoError(t, err)
+
+	received := []*http.Request{}
+	bodies := []string{}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	now := time.Date(2022, 8, 25, 10, 0, 0, 0, time.UTC)
+	clock := tools.ClockFunc(func() time.Time { return now })
+	svc := New(uow.New(conn), webhookrepo.New(conn), receiver.Client(), Retry{MaxAttempts: 3, Backoff: 30 * time.Second}, clock, logMock)
+
+	publishCompleted := func(t *testing.T, id uint) {
+		err := uow.New(conn).Do(func(repos uow.Repositories) error {
+			return PublishBulkTransfer(repos.Webhook, bulktransferrepo.BulkTransfer{
+				ID:               id,
+				OrganizationIban: "FR81474608000002006107XXXXX",
+				TransfersCount:   1,
+				TotalCents:       1500,
+				Status:           string(domain.BulkTransferCompleted),
+				UpdatedAt:        now,
+			})
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("Test Create return error when the bank account doesn't exist", func(t *testing.T) {
+		_, err := svc.Create(domain.Webhook{
+			OrganizationIban: "EE303680981021245685",
+			URL:              receiver.URL,
+			Events:           []domain.WebhookEvent{domain.WebhookTransactionCreated},
+		})
+
+		assert.ErrorIs(t, err, ErrAccountNotFound)
+	})
+
+	var webhook domain.WebhookDetail
+	t.Run("Test Create return the secret only once", func(t *testing.T) {
+		webhook, err = svc.Create(domain.Webhook{
+			OrganizationIban: "FR81474608000002006107XXXXX",
+			URL:              receiver.URL,
+			Events:           []domain.WebhookEvent{domain.WebhookBulkTransferCompleted, domain.WebhookBulkTransferCompleted},
+		})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(webhook.Secret, "whsec_"))
+		assert.Equal(t, []domain.WebhookEvent{domain.WebhookBulkTransferCompleted}, webhook.Events)
+
+		read, err := svc.Read(webhook.ID)
+		require.NoError(t, err)
+		assert.Empty(t, read.Secret)
+		assert.Equal(t, webhook.URL, read.URL)
+	})
+
+	t.Run("Test ReadByFilter requires the bank account", func(t *testing.T) {
+		_, err := svc.ReadByFilter(map[string]string{})
+		assert.ErrorIs(t, err, ErrMissingAccount)
+
+		webhooks, err := svc.ReadByFilter(map[string]string{"organization_iban": "FR81474608000002006107XXXXX"})
+		require.NoError(t, err)
+		assert.Len(t, webhooks, 1)
+	})
+
+	t.Run("Test Deliver posts the signed event to the subscribed webhook", func(t *testing.T) {
+		publishCompleted(t, 1)
+		err := uow.New(conn).Do(func(repos uow.Repositories) error {
+			// not subscribed to
+			return PublishTransaction(repos.Webhook, bankAccount, transactionrepo.Transaction{ID: 1, AmountCents: 1500, AmountCurrency: "EUR", CreatedAt: now})
+		})
+		require.NoError(t, err)
+
+		pending, err := svc.PendingDeliveries()
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+
+		require.NoError(t, svc.Deliver(pending[0]))
+		require.Len(t, received, 1)
+		req := received[0]
+		assert.Equal(t, string(domain.WebhookBulkTransferCompleted), req.Header.Get(tools.HeaderWebhookEvent))
+		assert.Equal(t, strconv.FormatUint(uint64(pending[0]), 10), req.Header.Get(tools.HeaderWebhookDelivery))
+		timestamp := req.Header.Get(tools.HeaderWebhookTimestamp)
+		assert.Equal(t, strconv.FormatInt(now.Unix(), 10), timestamp)
+		assert.Equal(t, Sign(webhook.Secret, timestamp, []byte(bodies[0])), req.Header.Get(tools.HeaderWebhookSignature))
+
+		var payload struct {
+			Event domain.WebhookEvent    `json:"event"`
+			Data  map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(bodies[0]), &payload))
+		assert.Equal(t, domain.WebhookBulkTransferCompleted, payload.Event)
+		assert.Equal(t, float64(1), payload.Data["id"])
+		assert.Equal(t, domain.NewMoney(1500, "EUR").String(), payload.Data["total_amount"])
+
+		deliveries, err := svc.ReadDeliveries(webhook.ID)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, domain.DeliveryDelivered, deliveries[0].Status)
+		assert.Equal(t, 1, deliveries[0].Attempts)
+		assert.Equal(t, http.StatusNoContent, deliveries[0].ResponseStatus)
+		assert.Nil(t, deliveries[0].NextAttemptAt)
+
+		pending, err = svc.PendingDeliveries()
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("Test Deliver retries with backoff until the attempts run out", func(t *testing.T) {
+		failing, err := svc.Create(domain.Webhook{
+			OrganizationIban: "FR81474608000002006107XXXXX",
+			URL:              unavailable.URL,
+			Events:           []domain.WebhookEvent{domain.WebhookBulkTransferCompleted},
+		})
+		require.NoError(t, err)
+		defer func() { require.NoError(t, svc.Delete(failing.ID)) }()
+		publishCompleted(t, 2)
+
+		deliveries, err := svc.ReadDeliveries(failing.ID)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		id := deliveries[0].ID
+
+		// the other webhook acknowledges right away
+		pending, err := svc.PendingDeliveries()
+		require.NoError(t, err)
+		for _, other := range pending {
+			if other != id {
+				require.NoError(t, svc.Deliver(other))
+			}
+		}
+
+		for _, wait := range []time.Duration{30 * time.Second, time.Minute} {
+			require.NoError(t, svc.Deliver(id))
+			deliveries, err = svc.ReadDeliveries(failing.ID)
+			require.NoError(t, err)
+			assert.Equal(t, domain.DeliveryPending, deliveries[0].Status)
+			assert.Equal(t, http.StatusServiceUnavailable, deliveries[0].ResponseStatus)
+			assert.Equal(t, "the receiver answered 503", deliveries[0].Error)
+			require.NotNil(t, deliveries[0].NextAttemptAt)
+			assert.True(t, now.Add(wait).Equal(*deliveries[0].NextAttemptAt))
+
+			_, err = svc.Replay(failing.ID, id)
+			assert.ErrorIs(t, err, ErrDeliveryPending)
+
+			pending, err := svc.PendingDeliveries()
+			require.NoError(t, err)
+			assert.Empty(t, pending)
+			now = now.Add(wait)
+		}
+
+		require.NoError(t, svc.Deliver(id))
+		deliveries, err = svc.ReadDeliveries(failing.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.DeliveryFailed, deliveries[0].Status)
+		assert.Equal(t, 3, deliveries[0].Attempts)
+		assert.Nil(t, deliveries[0].NextAttemptAt)
+	})
+
+	t.Run("Test Replay delivers the payload again as a new delivery", func(t *testing.T) {
+		deliveries, err := svc.ReadDeliveries(webhook.ID)
+		require.NoError(t, err)
+		original := deliveries[len(deliveries)-1]
+
+		replay, err := svc.Replay(webhook.ID, original.ID)
+		require.NoError(t, err)
+		assert.Equal(t, original.ID, replay.ReplayOf)
+		assert.Equal(t, domain.DeliveryPending, replay.Status)
+		assert.JSONEq(t, string(original.Payload), string(replay.Payload))
+
+		pending, err := svc.PendingDeliveries()
+		require.NoError(t, err)
+		assert.Equal(t, []uint{replay.ID}, pending)
+	})
+
+	t.Run("Test Replay return error when the delivery isn't one of the webhook", func(t *testing.T) {
+		_, err := svc.Replay(webhook.ID, 999)
+		assert.ErrorIs(t, err, ErrDeliveryNotFound)
+	})
+
+	t.Run("Test Delete fails the pending deliveries", func(t *testing.T) {
+		require.NoError(t, svc.Delete(webhook.ID))
+
+		_, err := svc.Read(webhook.ID)
+		assert.ErrorIs(t, err, ErrWebhookNotFound)
+		assert.ErrorIs(t, svc.Delete(webhook.ID), ErrWebhookNotFound)
+
+		pending, err := svc.PendingDeliveries()
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+
+		// no longer notified
+		publishCompleted(t, 3)
+		pending, err = svc.PendingDeliveries()
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("Test Deliver return error when the delivery doesn't exist", func(t *testing.T) {
+		assert.ErrorIs(t, svc.Deliver(999), ErrDeliveryNotFound)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	svc := service{retry: Retry{MaxAttempts: 20, Backoff: time.Minute}}
+
+	assert.Equal(t, time.Minute, svc.backoff(1))
+	assert.Equal(t, 2*time.Minute, svc.backoff(2))
+	assert.Equal(t, 8*time.Minute, svc.backoff(4))
+	assert.Equal(t, maxBackoff, svc.backoff(15))
+}
@@ -0,0 +1,121 @@
+package workerpool
+
+import (
+	"context"
+	"github.com/adrianoccosta/exercise-qonto/log"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// Source is the durable queue of the jobs the pool executes
+type Source struct {
+	// Pending lists the jobs left to execute, oldest first, including those left running by a previous process
+	Pending func() ([]uint, error)
+	// Run executes a job, a job that fails stays pending when it wasn't finished
+	Run func(jobID uint) error
+}
+
+// Pool executes the pending jobs of its source with a bounded number of workers
+type Pool interface {
+	Start(ctx context.Context)
+}
+
+// New returns a pool of workers executing the jobs of the source, looking for pending jobs every interval
+func New(workers int, interval time.Duration, source Source, logger log.Logger) Pool {
+	return pool{
+		logger:   logger,
+		workers:  workers,
+		interval: interval,
+		source:   source,
+	}
+}
+
+type pool struct {
+	logger   log.Logger
+	workers  int
+	interval time.Duration
+	source   Source
+}
+
+// Start dispatches the pending jobs right away, resuming those left unfinished, and then on every interval, until
+// the context is done. A job is never run by two workers at once. It returns once the running jobs are finished.
+func (p pool) Start(ctx context.Context) {
+	jobs := make(chan uint)
+	running := &inFlight{jobs: map[uint]bool{}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for jobID := range jobs {
+				if err := p.source.Run(jobID); err != nil {
+					p.logger.WithError(err).Error("job failed", zap.Uint("job_id", jobID))
+				}
+				running.remove(jobID)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	p.logger.Info("worker pool started", zap.Int("workers", p.workers))
+	for {
+		p.dispatch(ctx, jobs, running)
+
+		select {
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			p.logger.Info("worker pool stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch hands the pending jobs that aren't running to the workers, waiting for free workers, until the context is done
+func (p pool) dispatch(ctx context.Context, jobs chan<- uint, running *inFlight) {
+	pending, err := p.source.Pending()
+	if err != nil {
+		p.logger.WithError(err).Error("error listing pending jobs")
+		return
+	}
+
+	for _, jobID := range pending {
+		if !running.add(jobID) {
+			continue
+		}
+		select {
+		case jobs <- jobID:
+		case <-ctx.Done():
+			running.remove(jobID)
+			return
+		}
+	}
+}
+
+// inFlight the jobs handed to the workers that aren't finished yet
+type inFlight struct {
+	mu   sync.Mutex
+	jobs map[uint]bool
+}
+
+// add tells whether the job was added, it isn't when it is already running
+func (f *inFlight) add(jobID uint) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.jobs[jobID] {
+		return false
+	}
+	f.jobs[jobID] = true
+	return true
+}
+
+func (f *inFlight) remove(jobID uint) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.jobs, jobID)
+}
@@ -0,0 +1,120 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"github.com/adrianoccosta/exercise-qonto/test/mocks/log"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logMock := mocklog.NewMockLogger(ctrl)
+	logMock.EXPECT().Info("worker pool started", gomock.Any()).AnyTimes()
+	logMock.EXPECT().Info("worker pool stopped").AnyTimes()
+
+	t.Run("Test Start runs every pending job once with at most the given workers", func(t *testing.T) {
+		var mu sync.Mutex
+		pending := map[uint]bool{1: true, 2: true, 3: true, 4: true, 5: true}
+		runs := map[uint]int{}
+		busy, maxBusy := 0, 0
+
+		source := Source{
+			Pending: func() ([]uint, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				var ids []uint
+				for id := uint(1); id <= 5; id++ {
+					if pending[id] {
+						ids = append(ids, id)
+					}
+				}
+				return ids, nil
+			},
+			Run: func(jobID uint) error {
+				mu.Lock()
+				busy++
+				if busy > maxBusy {
+					maxBusy = busy
+				}
+				mu.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+
+				mu.Lock()
+				defer mu.Unlock()
+				busy--
+				runs[jobID]++
+				delete(pending, jobID)
+				return nil
+			},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			New(2, time.Millisecond, source, logMock).Start(ctx)
+			close(done)
+		}()
+
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(pending) == 0
+		}, time.Second, time.Millisecond)
+		cancel()
+		<-done
+
+		assert.Equal(t, map[uint]int{1: 1, 2: 1, 3: 1, 4: 1, 5: 1}, runs)
+		assert.LessOrEqual(t, maxBusy, 2)
+	})
+
+	t.Run("Test Start retries the jobs left pending by a failure", func(t *testing.T) {
+		var mu sync.Mutex
+		attempts := 0
+
+		logMock.EXPECT().WithError(gomock.Any()).Return(logMock).Times(1)
+		logMock.EXPECT().Error("job failed", gomock.Any()).Times(1)
+
+		source := Source{
+			Pending: func() ([]uint, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if attempts < 2 {
+					return []uint{7}, nil
+				}
+				return nil, nil
+			},
+			Run: func(jobID uint) error {
+				mu.Lock()
+				defer mu.Unlock()
+				attempts++
+				if attempts == 1 {
+					return errors.New("error")
+				}
+				return nil
+			},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			New(1, time.Millisecond, source, logMock).Start(ctx)
+			close(done)
+		}()
+
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return attempts == 2
+		}, time.Second, time.Millisecond)
+		cancel()
+		<-done
+	})
+}
@@ -52,6 +52,8 @@ func main() {
 		Before:      setupBefore,
 		Commands: []*cli.Command{
 			cmd.APICommand,
+			cmd.SchedulerCommand,
+			cmd.ReconcileCommand,
 		},
 	}
 	app.Flags = append(app.Flags, []cli.Flag{}...)
@@ -49,6 +49,35 @@ func (mr *MockBankAccountRepositoryMockRecorder) Create(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockBankAccountRepository)(nil).Create), arg0)
 }
 
+// Credit mocks base method.
+func (m *MockBankAccountRepository) Credit(arg0 uint, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Credit", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Credit indicates an expected call of Credit.
+func (mr *MockBankAccountRepositoryMockRecorder) Credit(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Credit", reflect.TypeOf((*MockBankAccountRepository)(nil).Credit), arg0, arg1)
+}
+
+// Debit mocks base method.
+func (m *MockBankAccountRepository) Debit(arg0 uint, arg1 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Debit", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Debit indicates an expected call of Debit.
+func (mr *MockBankAccountRepositoryMockRecorder) Debit(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Debit", reflect.TypeOf((*MockBankAccountRepository)(nil).Debit), arg0, arg1)
+}
+
 // DeleteByIban mocks base method.
 func (m *MockBankAccountRepository) DeleteByIban(arg0 string) error {
 	m.ctrl.T.Helper()
@@ -78,6 +107,21 @@ func (mr *MockBankAccountRepositoryMockRecorder) Read(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockBankAccountRepository)(nil).Read), arg0)
 }
 
+// ReadApprovers mocks base method.
+func (m *MockBankAccountRepository) ReadApprovers(arg0 uint) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadApprovers", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadApprovers indicates an expected call of ReadApprovers.
+func (mr *MockBankAccountRepositoryMockRecorder) ReadApprovers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadApprovers", reflect.TypeOf((*MockBankAccountRepository)(nil).ReadApprovers), arg0)
+}
+
 // ReadByIban mocks base method.
 func (m *MockBankAccountRepository) ReadByIban(arg0 string) (bankaccountrepo.BankAccount, error) {
 	m.ctrl.T.Helper()
@@ -93,6 +137,20 @@ func (mr *MockBankAccountRepositoryMockRecorder) ReadByIban(arg0 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByIban", reflect.TypeOf((*MockBankAccountRepository)(nil).ReadByIban), arg0)
 }
 
+// ReplaceApprovers mocks base method.
+func (m *MockBankAccountRepository) ReplaceApprovers(arg0 uint, arg1 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceApprovers", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceApprovers indicates an expected call of ReplaceApprovers.
+func (mr *MockBankAccountRepositoryMockRecorder) ReplaceApprovers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceApprovers", reflect.TypeOf((*MockBankAccountRepository)(nil).ReplaceApprovers), arg0, arg1)
+}
+
 // Update mocks base method.
 func (m *MockBankAccountRepository) Update(arg0 bankaccountrepo.BankAccount) error {
 	m.ctrl.T.Helper()
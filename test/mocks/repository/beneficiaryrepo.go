@@ -0,0 +1,123 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/adrianoccosta/exercise-qonto/internal/repository/beneficiaryrepo (interfaces: BeneficiaryRepository)
+
+// Package mockrepository is a generated GoMock package.
+package mockrepository
+
+import (
+	reflect "reflect"
+
+	beneficiaryrepo "github.com/adrianoccosta/exercise-qonto/internal/repository/beneficiaryrepo"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockBeneficiaryRepository is a mock of BeneficiaryRepository interface.
+type MockBeneficiaryRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockBeneficiaryRepositoryMockRecorder
+}
+
+// MockBeneficiaryRepositoryMockRecorder is the mock recorder for MockBeneficiaryRepository.
+type MockBeneficiaryRepositoryMockRecorder struct {
+	mock *MockBeneficiaryRepository
+}
+
+// NewMockBeneficiaryRepository creates a new mock instance.
+func NewMockBeneficiaryRepository(ctrl *gomock.Controller) *MockBeneficiaryRepository {
+	mock := &MockBeneficiaryRepository{ctrl: ctrl}
+	mock.recorder = &MockBeneficiaryRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBeneficiaryRepository) EXPECT() *MockBeneficiaryRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockBeneficiaryRepository) Create(arg0 beneficiaryrepo.Beneficiary) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockBeneficiaryRepositoryMockRecorder) Create(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockBeneficiaryRepository)(nil).Create), arg0)
+}
+
+// Delete mocks base method.
+func (m *MockBeneficiaryRepository) Delete(arg0 uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockBeneficiaryRepositoryMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockBeneficiaryRepository)(nil).Delete), arg0)
+}
+
+// Read mocks base method.
+func (m *MockBeneficiaryRepository) Read(arg0 uint) (beneficiaryrepo.Beneficiary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", arg0)
+	ret0, _ := ret[0].(beneficiaryrepo.Beneficiary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockBeneficiaryRepositoryMockRecorder) Read(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockBeneficiaryRepository)(nil).Read), arg0)
+}
+
+// ReadByFilter mocks base method.
+func (m *MockBeneficiaryRepository) ReadByFilter(arg0 map[string]string) (beneficiaryrepo.BeneficiaryList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadByFilter", arg0)
+	ret0, _ := ret[0].(beneficiaryrepo.BeneficiaryList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadByFilter indicates an expected call of ReadByFilter.
+func (mr *MockBeneficiaryRepositoryMockRecorder) ReadByFilter(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByFilter", reflect.TypeOf((*MockBeneficiaryRepository)(nil).ReadByFilter), arg0)
+}
+
+// ReadByIban mocks base method.
+func (m *MockBeneficiaryRepository) ReadByIban(arg0 uint, arg1 string) (beneficiaryrepo.Beneficiary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadByIban", arg0, arg1)
+	ret0, _ := ret[0].(beneficiaryrepo.Beneficiary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadByIban indicates an expected call of ReadByIban.
+func (mr *MockBeneficiaryRepositoryMockRecorder) ReadByIban(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByIban", reflect.TypeOf((*MockBeneficiaryRepository)(nil).ReadByIban), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockBeneficiaryRepository) Update(arg0 beneficiaryrepo.Beneficiary) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockBeneficiaryRepositoryMockRecorder) Update(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockBeneficiaryRepository)(nil).Update), arg0)
+}
@@ -0,0 +1,400 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo (interfaces: BulkTransferRepository)
+
+// Package mockrepository is a generated GoMock package.
+package mockrepository
+
+import (
+	reflect "reflect"
+	time "time"
+
+	bulktransferrepo "github.com/adrianoccosta/exercise-qonto/internal/repository/bulktransferrepo"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockBulkTransferRepository is a mock of BulkTransferRepository interface.
+type MockBulkTransferRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockBulkTransferRepositoryMockRecorder
+}
+
+// MockBulkTransferRepositoryMockRecorder is the mock recorder for MockBulkTransferRepository.
+type MockBulkTransferRepositoryMockRecorder struct {
+	mock *MockBulkTransferRepository
+}
+
+// NewMockBulkTransferRepository creates a new mock instance.
+func NewMockBulkTransferRepository(ctrl *gomock.Controller) *MockBulkTransferRepository {
+	mock := &MockBulkTransferRepository{ctrl: ctrl}
+	mock.recorder = &MockBulkTransferRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBulkTransferRepository) EXPECT() *MockBulkTransferRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Cancel mocks base method.
+func (m *MockBulkTransferRepository) Cancel(arg0 bulktransferrepo.BulkTransfer, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cancel", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Cancel indicates an expected call of Cancel.
+func (mr *MockBulkTransferRepositoryMockRecorder) Cancel(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cancel", reflect.TypeOf((*MockBulkTransferRepository)(nil).Cancel), arg0, arg1)
+}
+
+// CancelLines mocks base method.
+func (m *MockBulkTransferRepository) CancelLines(arg0 uint, arg1 bulktransferrepo.LineList) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelLines", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelLines indicates an expected call of CancelLines.
+func (mr *MockBulkTransferRepositoryMockRecorder) CancelLines(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelLines", reflect.TypeOf((*MockBulkTransferRepository)(nil).CancelLines), arg0, arg1)
+}
+
+// ClaimJob mocks base method.
+func (m *MockBulkTransferRepository) ClaimJob(arg0 bulktransferrepo.Job, arg1 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimJob", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClaimJob indicates an expected call of ClaimJob.
+func (mr *MockBulkTransferRepositoryMockRecorder) ClaimJob(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimJob", reflect.TypeOf((*MockBulkTransferRepository)(nil).ClaimJob), arg0, arg1)
+}
+
+// Create mocks base method.
+func (m *MockBulkTransferRepository) Create(arg0 bulktransferrepo.BulkTransfer) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockBulkTransferRepositoryMockRecorder) Create(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockBulkTransferRepository)(nil).Create), arg0)
+}
+
+// CreateDecision mocks base method.
+func (m *MockBulkTransferRepository) CreateDecision(arg0 bulktransferrepo.Decision) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDecision", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDecision indicates an expected call of CreateDecision.
+func (mr *MockBulkTransferRepositoryMockRecorder) CreateDecision(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDecision", reflect.TypeOf((*MockBulkTransferRepository)(nil).CreateDecision), arg0)
+}
+
+// CreateJob mocks base method.
+func (m *MockBulkTransferRepository) CreateJob(arg0 bulktransferrepo.Job) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateJob", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateJob indicates an expected call of CreateJob.
+func (mr *MockBulkTransferRepositoryMockRecorder) CreateJob(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJob", reflect.TypeOf((*MockBulkTransferRepository)(nil).CreateJob), arg0)
+}
+
+// CreateLines mocks base method.
+func (m *MockBulkTransferRepository) CreateLines(arg0 uint, arg1 bulktransferrepo.LineList) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLines", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateLines indicates an expected call of CreateLines.
+func (mr *MockBulkTransferRepositoryMockRecorder) CreateLines(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLines", reflect.TypeOf((*MockBulkTransferRepository)(nil).CreateLines), arg0, arg1)
+}
+
+// CreateOutcomes mocks base method.
+func (m *MockBulkTransferRepository) CreateOutcomes(arg0 uint, arg1 bulktransferrepo.OutcomeList) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOutcomes", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOutcomes indicates an expected call of CreateOutcomes.
+func (mr *MockBulkTransferRepositoryMockRecorder) CreateOutcomes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOutcomes", reflect.TypeOf((*MockBulkTransferRepository)(nil).CreateOutcomes), arg0, arg1)
+}
+
+// CreateReview mocks base method.
+func (m *MockBulkTransferRepository) CreateReview(arg0 bulktransferrepo.Review) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReview", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateReview indicates an expected call of CreateReview.
+func (mr *MockBulkTransferRepositoryMockRecorder) CreateReview(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReview", reflect.TypeOf((*MockBulkTransferRepository)(nil).CreateReview), arg0)
+}
+
+// FinishJob mocks base method.
+func (m *MockBulkTransferRepository) FinishJob(arg0 bulktransferrepo.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishJob", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishJob indicates an expected call of FinishJob.
+func (mr *MockBulkTransferRepositoryMockRecorder) FinishJob(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishJob", reflect.TypeOf((*MockBulkTransferRepository)(nil).FinishJob), arg0)
+}
+
+// Hold mocks base method.
+func (m *MockBulkTransferRepository) Hold(arg0 bulktransferrepo.BulkTransfer, arg1 bulktransferrepo.MatchList, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Hold", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Hold indicates an expected call of Hold.
+func (mr *MockBulkTransferRepositoryMockRecorder) Hold(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Hold", reflect.TypeOf((*MockBulkTransferRepository)(nil).Hold), arg0, arg1, arg2)
+}
+
+// Read mocks base method.
+func (m *MockBulkTransferRepository) Read(arg0 uint) (bulktransferrepo.BulkTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", arg0)
+	ret0, _ := ret[0].(bulktransferrepo.BulkTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockBulkTransferRepositoryMockRecorder) Read(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockBulkTransferRepository)(nil).Read), arg0)
+}
+
+// ReadByFilter mocks base method.
+func (m *MockBulkTransferRepository) ReadByFilter(arg0 map[string]string) (bulktransferrepo.BulkTransferList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadByFilter", arg0)
+	ret0, _ := ret[0].(bulktransferrepo.BulkTransferList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadByFilter indicates an expected call of ReadByFilter.
+func (mr *MockBulkTransferRepositoryMockRecorder) ReadByFilter(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByFilter", reflect.TypeOf((*MockBulkTransferRepository)(nil).ReadByFilter), arg0)
+}
+
+// ReadDecisions mocks base method.
+func (m *MockBulkTransferRepository) ReadDecisions(arg0 uint) (bulktransferrepo.DecisionList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadDecisions", arg0)
+	ret0, _ := ret[0].(bulktransferrepo.DecisionList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadDecisions indicates an expected call of ReadDecisions.
+func (mr *MockBulkTransferRepositoryMockRecorder) ReadDecisions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadDecisions", reflect.TypeOf((*MockBulkTransferRepository)(nil).ReadDecisions), arg0)
+}
+
+// ReadDue mocks base method.
+func (m *MockBulkTransferRepository) ReadDue(arg0 time.Time) (bulktransferrepo.BulkTransferList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadDue", arg0)
+	ret0, _ := ret[0].(bulktransferrepo.BulkTransferList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadDue indicates an expected call of ReadDue.
+func (mr *MockBulkTransferRepositoryMockRecorder) ReadDue(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadDue", reflect.TypeOf((*MockBulkTransferRepository)(nil).ReadDue), arg0)
+}
+
+// ReadJob mocks base method.
+func (m *MockBulkTransferRepository) ReadJob(arg0 uint) (bulktransferrepo.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadJob", arg0)
+	ret0, _ := ret[0].(bulktransferrepo.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadJob indicates an expected call of ReadJob.
+func (mr *MockBulkTransferRepositoryMockRecorder) ReadJob(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadJob", reflect.TypeOf((*MockBulkTransferRepository)(nil).ReadJob), arg0)
+}
+
+// ReadLines mocks base method.
+func (m *MockBulkTransferRepository) ReadLines(arg0 uint) (bulktransferrepo.LineList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadLines", arg0)
+	ret0, _ := ret[0].(bulktransferrepo.LineList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadLines indicates an expected call of ReadLines.
+func (mr *MockBulkTransferRepositoryMockRecorder) ReadLines(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadLines", reflect.TypeOf((*MockBulkTransferRepository)(nil).ReadLines), arg0)
+}
+
+// ReadMatches mocks base method.
+func (m *MockBulkTransferRepository) ReadMatches(arg0 uint) (bulktransferrepo.MatchList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadMatches", arg0)
+	ret0, _ := ret[0].(bulktransferrepo.MatchList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadMatches indicates an expected call of ReadMatches.
+func (mr *MockBulkTransferRepositoryMockRecorder) ReadMatches(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadMatches", reflect.TypeOf((*MockBulkTransferRepository)(nil).ReadMatches), arg0)
+}
+
+// ReadOutcomes mocks base method.
+func (m *MockBulkTransferRepository) ReadOutcomes(arg0 uint) (bulktransferrepo.OutcomeList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadOutcomes", arg0)
+	ret0, _ := ret[0].(bulktransferrepo.OutcomeList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadOutcomes indicates an expected call of ReadOutcomes.
+func (mr *MockBulkTransferRepositoryMockRecorder) ReadOutcomes(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadOutcomes", reflect.TypeOf((*MockBulkTransferRepository)(nil).ReadOutcomes), arg0)
+}
+
+// ReadPendingJobs mocks base method.
+func (m *MockBulkTransferRepository) ReadPendingJobs() (bulktransferrepo.JobList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadPendingJobs")
+	ret0, _ := ret[0].(bulktransferrepo.JobList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadPendingJobs indicates an expected call of ReadPendingJobs.
+func (mr *MockBulkTransferRepositoryMockRecorder) ReadPendingJobs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPendingJobs", reflect.TypeOf((*MockBulkTransferRepository)(nil).ReadPendingJobs))
+}
+
+// ReadReview mocks base method.
+func (m *MockBulkTransferRepository) ReadReview(arg0 uint) (bulktransferrepo.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadReview", arg0)
+	ret0, _ := ret[0].(bulktransferrepo.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadReview indicates an expected call of ReadReview.
+func (mr *MockBulkTransferRepositoryMockRecorder) ReadReview(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadReview", reflect.TypeOf((*MockBulkTransferRepository)(nil).ReadReview), arg0)
+}
+
+// RenewJob mocks base method.
+func (m *MockBulkTransferRepository) RenewJob(arg0 bulktransferrepo.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenewJob", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenewJob indicates an expected call of RenewJob.
+func (mr *MockBulkTransferRepositoryMockRecorder) RenewJob(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenewJob", reflect.TypeOf((*MockBulkTransferRepository)(nil).RenewJob), arg0)
+}
+
+// Transition mocks base method.
+func (m *MockBulkTransferRepository) Transition(arg0 bulktransferrepo.BulkTransfer, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transition", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transition indicates an expected call of Transition.
+func (mr *MockBulkTransferRepositoryMockRecorder) Transition(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transition", reflect.TypeOf((*MockBulkTransferRepository)(nil).Transition), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockBulkTransferRepository) Update(arg0 bulktransferrepo.BulkTransfer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockBulkTransferRepositoryMockRecorder) Update(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockBulkTransferRepository)(nil).Update), arg0)
+}
+
+// UpdateJob mocks base method.
+func (m *MockBulkTransferRepository) UpdateJob(arg0 bulktransferrepo.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateJob", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateJob indicates an expected call of UpdateJob.
+func (mr *MockBulkTransferRepositoryMockRecorder) UpdateJob(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJob", reflect.TypeOf((*MockBulkTransferRepository)(nil).UpdateJob), arg0)
+}
@@ -0,0 +1,81 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo (interfaces: LedgerRepository)
+
+// Package mockrepository is a generated GoMock package.
+package mockrepository
+
+import (
+	reflect "reflect"
+
+	domain "github.com/adrianoccosta/exercise-qonto/internal/domain"
+	ledgerrepo "github.com/adrianoccosta/exercise-qonto/internal/repository/ledgerrepo"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockLedgerRepository is a mock of LedgerRepository interface.
+type MockLedgerRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLedgerRepositoryMockRecorder
+}
+
+// MockLedgerRepositoryMockRecorder is the mock recorder for MockLedgerRepository.
+type MockLedgerRepositoryMockRecorder struct {
+	mock *MockLedgerRepository
+}
+
+// NewMockLedgerRepository creates a new mock instance.
+func NewMockLedgerRepository(ctrl *gomock.Controller) *MockLedgerRepository {
+	mock := &MockLedgerRepository{ctrl: ctrl}
+	mock.recorder = &MockLedgerRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLedgerRepository) EXPECT() *MockLedgerRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Balance mocks base method.
+func (m *MockLedgerRepository) Balance(arg0 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Balance", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Balance indicates an expected call of Balance.
+func (mr *MockLedgerRepositoryMockRecorder) Balance(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Balance", reflect.TypeOf((*MockLedgerRepository)(nil).Balance), arg0)
+}
+
+// Post mocks base method.
+func (m *MockLedgerRepository) Post(arg0 ledgerrepo.Journal, arg1 ledgerrepo.EntryList) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Post", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Post indicates an expected call of Post.
+func (mr *MockLedgerRepositoryMockRecorder) Post(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Post", reflect.TypeOf((*MockLedgerRepository)(nil).Post), arg0, arg1)
+}
+
+// ReadEntries mocks base method.
+func (m *MockLedgerRepository) ReadEntries(arg0 map[string]string) (domain.LedgerEntryList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadEntries", arg0)
+	ret0, _ := ret[0].(domain.LedgerEntryList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadEntries indicates an expected call of ReadEntries.
+func (mr *MockLedgerRepositoryMockRecorder) ReadEntries(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadEntries", reflect.TypeOf((*MockLedgerRepository)(nil).ReadEntries), arg0)
+}
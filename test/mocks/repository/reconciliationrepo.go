@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/adrianoccosta/exercise-qonto/internal/repository/reconciliationrepo (interfaces: ReconciliationRepository)
+
+// Package mockrepository is a generated GoMock package.
+package mockrepository
+
+import (
+	reflect "reflect"
+
+	reconciliationrepo "github.com/adrianoccosta/exercise-qonto/internal/repository/reconciliationrepo"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockReconciliationRepository is a mock of ReconciliationRepository interface.
+type MockReconciliationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockReconciliationRepositoryMockRecorder
+}
+
+// MockReconciliationRepositoryMockRecorder is the mock recorder for MockReconciliationRepository.
+type MockReconciliationRepositoryMockRecorder struct {
+	mock *MockReconciliationRepository
+}
+
+// NewMockReconciliationRepository creates a new mock instance.
+func NewMockReconciliationRepository(ctrl *gomock.Controller) *MockReconciliationRepository {
+	mock := &MockReconciliationRepository{ctrl: ctrl}
+	mock.recorder = &MockReconciliationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReconciliationRepository) EXPECT() *MockReconciliationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateAdjustment mocks base method.
+func (m *MockReconciliationRepository) CreateAdjustment(arg0 reconciliationrepo.Adjustment) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAdjustment", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAdjustment indicates an expected call of CreateAdjustment.
+func (mr *MockReconciliationRepositoryMockRecorder) CreateAdjustment(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAdjustment", reflect.TypeOf((*MockReconciliationRepository)(nil).CreateAdjustment), arg0)
+}
+
+// ReadBalances mocks base method.
+func (m *MockReconciliationRepository) ReadBalances() ([]reconciliationrepo.AccountBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadBalances")
+	ret0, _ := ret[0].([]reconciliationrepo.AccountBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadBalances indicates an expected call of ReadBalances.
+func (mr *MockReconciliationRepositoryMockRecorder) ReadBalances() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadBalances", reflect.TypeOf((*MockReconciliationRepository)(nil).ReadBalances))
+}
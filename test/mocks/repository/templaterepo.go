@@ -0,0 +1,167 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/adrianoccosta/exercise-qonto/internal/repository/templaterepo (interfaces: TemplateRepository)
+
+// Package mockrepository is a generated GoMock package.
+package mockrepository
+
+import (
+	reflect "reflect"
+	time "time"
+
+	templaterepo "github.com/adrianoccosta/exercise-qonto/internal/repository/templaterepo"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTemplateRepository is a mock of TemplateRepository interface.
+type MockTemplateRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTemplateRepositoryMockRecorder
+}
+
+// MockTemplateRepositoryMockRecorder is the mock recorder for MockTemplateRepository.
+type MockTemplateRepositoryMockRecorder struct {
+	mock *MockTemplateRepository
+}
+
+// NewMockTemplateRepository creates a new mock instance.
+func NewMockTemplateRepository(ctrl *gomock.Controller) *MockTemplateRepository {
+	mock := &MockTemplateRepository{ctrl: ctrl}
+	mock.recorder = &MockTemplateRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTemplateRepository) EXPECT() *MockTemplateRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Advance mocks base method.
+func (m *MockTemplateRepository) Advance(arg0 templaterepo.Template, arg1 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Advance", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Advance indicates an expected call of Advance.
+func (mr *MockTemplateRepositoryMockRecorder) Advance(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Advance", reflect.TypeOf((*MockTemplateRepository)(nil).Advance), arg0, arg1)
+}
+
+// Create mocks base method.
+func (m *MockTemplateRepository) Create(arg0 templaterepo.Template) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockTemplateRepositoryMockRecorder) Create(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockTemplateRepository)(nil).Create), arg0)
+}
+
+// Read mocks base method.
+func (m *MockTemplateRepository) Read(arg0 uint) (templaterepo.Template, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", arg0)
+	ret0, _ := ret[0].(templaterepo.Template)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockTemplateRepositoryMockRecorder) Read(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockTemplateRepository)(nil).Read), arg0)
+}
+
+// ReadByFilter mocks base method.
+func (m *MockTemplateRepository) ReadByFilter(arg0 map[string]string) (templaterepo.TemplateList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadByFilter", arg0)
+	ret0, _ := ret[0].(templaterepo.TemplateList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadByFilter indicates an expected call of ReadByFilter.
+func (mr *MockTemplateRepositoryMockRecorder) ReadByFilter(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByFilter", reflect.TypeOf((*MockTemplateRepository)(nil).ReadByFilter), arg0)
+}
+
+// ReadDue mocks base method.
+func (m *MockTemplateRepository) ReadDue(arg0 time.Time) (templaterepo.TemplateList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadDue", arg0)
+	ret0, _ := ret[0].(templaterepo.TemplateList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadDue indicates an expected call of ReadDue.
+func (mr *MockTemplateRepositoryMockRecorder) ReadDue(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadDue", reflect.TypeOf((*MockTemplateRepository)(nil).ReadDue), arg0)
+}
+
+// ReadLines mocks base method.
+func (m *MockTemplateRepository) ReadLines(arg0 uint) (templaterepo.LineList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadLines", arg0)
+	ret0, _ := ret[0].(templaterepo.LineList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadLines indicates an expected call of ReadLines.
+func (mr *MockTemplateRepositoryMockRecorder) ReadLines(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadLines", reflect.TypeOf((*MockTemplateRepository)(nil).ReadLines), arg0)
+}
+
+// ReplaceLines mocks base method.
+func (m *MockTemplateRepository) ReplaceLines(arg0 uint, arg1 templaterepo.LineList) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceLines", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceLines indicates an expected call of ReplaceLines.
+func (mr *MockTemplateRepositoryMockRecorder) ReplaceLines(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceLines", reflect.TypeOf((*MockTemplateRepository)(nil).ReplaceLines), arg0, arg1)
+}
+
+// Transition mocks base method.
+func (m *MockTemplateRepository) Transition(arg0 templaterepo.Template, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transition", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transition indicates an expected call of Transition.
+func (mr *MockTemplateRepositoryMockRecorder) Transition(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transition", reflect.TypeOf((*MockTemplateRepository)(nil).Transition), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockTemplateRepository) Update(arg0 templaterepo.Template) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockTemplateRepositoryMockRecorder) Update(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockTemplateRepository)(nil).Update), arg0)
+}
@@ -6,6 +6,7 @@ package mockrepository
 
 import (
 	reflect "reflect"
+	time "time"
 
 	domain "github.com/adrianoccosta/exercise-qonto/internal/domain"
 	transactionrepo "github.com/adrianoccosta/exercise-qonto/internal/repository/transactionrepo"
@@ -79,3 +80,47 @@ func (mr *MockTransactionRepositoryMockRecorder) ReadByFilter(arg0 interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByFilter", reflect.TypeOf((*MockTransactionRepository)(nil).ReadByFilter), arg0)
 }
+
+// ReadDuplicate mocks base method.
+func (m *MockTransactionRepository) ReadDuplicate(arg0 uint, arg1 string, arg2 int64, arg3 string, arg4 time.Time) (transactionrepo.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadDuplicate", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(transactionrepo.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadDuplicate indicates an expected call of ReadDuplicate.
+func (mr *MockTransactionRepositoryMockRecorder) ReadDuplicate(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadDuplicate", reflect.TypeOf((*MockTransactionRepository)(nil).ReadDuplicate), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Reverse mocks base method.
+func (m *MockTransactionRepository) Reverse(arg0 uint, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reverse", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reverse indicates an expected call of Reverse.
+func (mr *MockTransactionRepositoryMockRecorder) Reverse(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reverse", reflect.TypeOf((*MockTransactionRepository)(nil).Reverse), arg0, arg1)
+}
+
+// SumTransferred mocks base method.
+func (m *MockTransactionRepository) SumTransferred(arg0 uint, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SumTransferred", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SumTransferred indicates an expected call of SumTransferred.
+func (mr *MockTransactionRepositoryMockRecorder) SumTransferred(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SumTransferred", reflect.TypeOf((*MockTransactionRepository)(nil).SumTransferred), arg0, arg1)
+}
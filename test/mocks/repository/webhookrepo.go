@@ -0,0 +1,183 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/adrianoccosta/exercise-qonto/internal/repository/webhookrepo (interfaces: WebhookRepository)
+
+// Package mockrepository is a generated GoMock package.
+package mockrepository
+
+import (
+	reflect "reflect"
+	time "time"
+
+	webhookrepo "github.com/adrianoccosta/exercise-qonto/internal/repository/webhookrepo"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockWebhookRepository is a mock of WebhookRepository interface.
+type MockWebhookRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookRepositoryMockRecorder
+}
+
+// MockWebhookRepositoryMockRecorder is the mock recorder for MockWebhookRepository.
+type MockWebhookRepositoryMockRecorder struct {
+	mock *MockWebhookRepository
+}
+
+// NewMockWebhookRepository creates a new mock instance.
+func NewMockWebhookRepository(ctrl *gomock.Controller) *MockWebhookRepository {
+	mock := &MockWebhookRepository{ctrl: ctrl}
+	mock.recorder = &MockWebhookRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookRepository) EXPECT() *MockWebhookRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockWebhookRepository) Create(arg0 webhookrepo.Webhook) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockWebhookRepositoryMockRecorder) Create(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockWebhookRepository)(nil).Create), arg0)
+}
+
+// CreateDeliveries mocks base method.
+func (m *MockWebhookRepository) CreateDeliveries(arg0, arg1, arg2 string, arg3 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDeliveries", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateDeliveries indicates an expected call of CreateDeliveries.
+func (mr *MockWebhookRepositoryMockRecorder) CreateDeliveries(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDeliveries", reflect.TypeOf((*MockWebhookRepository)(nil).CreateDeliveries), arg0, arg1, arg2, arg3)
+}
+
+// CreateDelivery mocks base method.
+func (m *MockWebhookRepository) CreateDelivery(arg0 webhookrepo.Delivery) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDelivery", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDelivery indicates an expected call of CreateDelivery.
+func (mr *MockWebhookRepositoryMockRecorder) CreateDelivery(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDelivery", reflect.TypeOf((*MockWebhookRepository)(nil).CreateDelivery), arg0)
+}
+
+// Delete mocks base method.
+func (m *MockWebhookRepository) Delete(arg0 uint, arg1 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockWebhookRepositoryMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockWebhookRepository)(nil).Delete), arg0, arg1)
+}
+
+// Read mocks base method.
+func (m *MockWebhookRepository) Read(arg0 uint) (webhookrepo.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", arg0)
+	ret0, _ := ret[0].(webhookrepo.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockWebhookRepositoryMockRecorder) Read(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockWebhookRepository)(nil).Read), arg0)
+}
+
+// ReadByFilter mocks base method.
+func (m *MockWebhookRepository) ReadByFilter(arg0 map[string]string) (webhookrepo.WebhookList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadByFilter", arg0)
+	ret0, _ := ret[0].(webhookrepo.WebhookList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadByFilter indicates an expected call of ReadByFilter.
+func (mr *MockWebhookRepositoryMockRecorder) ReadByFilter(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByFilter", reflect.TypeOf((*MockWebhookRepository)(nil).ReadByFilter), arg0)
+}
+
+// ReadDeliveries mocks base method.
+func (m *MockWebhookRepository) ReadDeliveries(arg0 uint) (webhookrepo.DeliveryList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadDeliveries", arg0)
+	ret0, _ := ret[0].(webhookrepo.DeliveryList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadDeliveries indicates an expected call of ReadDeliveries.
+func (mr *MockWebhookRepositoryMockRecorder) ReadDeliveries(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadDeliveries", reflect.TypeOf((*MockWebhookRepository)(nil).ReadDeliveries), arg0)
+}
+
+// ReadDelivery mocks base method.
+func (m *MockWebhookRepository) ReadDelivery(arg0 uint) (webhookrepo.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadDelivery", arg0)
+	ret0, _ := ret[0].(webhookrepo.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadDelivery indicates an expected call of ReadDelivery.
+func (mr *MockWebhookRepositoryMockRecorder) ReadDelivery(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadDelivery", reflect.TypeOf((*MockWebhookRepository)(nil).ReadDelivery), arg0)
+}
+
+// ReadDueDeliveries mocks base method.
+func (m *MockWebhookRepository) ReadDueDeliveries(arg0 time.Time) (webhookrepo.DeliveryList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadDueDeliveries", arg0)
+	ret0, _ := ret[0].(webhookrepo.DeliveryList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadDueDeliveries indicates an expected call of ReadDueDeliveries.
+func (mr *MockWebhookRepositoryMockRecorder) ReadDueDeliveries(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadDueDeliveries", reflect.TypeOf((*MockWebhookRepository)(nil).ReadDueDeliveries), arg0)
+}
+
+// UpdateDelivery mocks base method.
+func (m *MockWebhookRepository) UpdateDelivery(arg0 webhookrepo.Delivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDelivery", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDelivery indicates an expected call of UpdateDelivery.
+func (mr *MockWebhookRepositoryMockRecorder) UpdateDelivery(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDelivery", reflect.TypeOf((*MockWebhookRepository)(nil).UpdateDelivery), arg0)
+}
@@ -0,0 +1,63 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/adrianoccosta/exercise-qonto/internal/screening (interfaces: Screener)
+
+// Package mockscreening is a generated GoMock package.
+package mockscreening
+
+import (
+	reflect "reflect"
+
+	screening "github.com/adrianoccosta/exercise-qonto/internal/screening"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockScreener is a mock of Screener interface.
+type MockScreener struct {
+	ctrl     *gomock.Controller
+	recorder *MockScreenerMockRecorder
+}
+
+// MockScreenerMockRecorder is the mock recorder for MockScreener.
+type MockScreenerMockRecorder struct {
+	mock *MockScreener
+}
+
+// NewMockScreener creates a new mock instance.
+func NewMockScreener(ctrl *gomock.Controller) *MockScreener {
+	mock := &MockScreener{ctrl: ctrl}
+	mock.recorder = &MockScreenerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockScreener) EXPECT() *MockScreenerMockRecorder {
+	return m.recorder
+}
+
+// Mode mocks base method.
+func (m *MockScreener) Mode() screening.Mode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Mode")
+	ret0, _ := ret[0].(screening.Mode)
+	return ret0
+}
+
+// Mode indicates an expected call of Mode.
+func (mr *MockScreenerMockRecorder) Mode() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mode", reflect.TypeOf((*MockScreener)(nil).Mode))
+}
+
+// Screen mocks base method.
+func (m *MockScreener) Screen(arg0, arg1 string) []screening.Hit {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Screen", arg0, arg1)
+	ret0, _ := ret[0].([]screening.Hit)
+	return ret0
+}
+
+// Screen indicates an expected call of Screen.
+func (mr *MockScreenerMockRecorder) Screen(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Screen", reflect.TypeOf((*MockScreener)(nil).Screen), arg0, arg1)
+}
@@ -0,0 +1,109 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/adrianoccosta/exercise-qonto/internal/services/beneficiarysvc (interfaces: BeneficiaryService)
+
+// Package mockservice is a generated GoMock package.
+package mockservice
+
+import (
+	reflect "reflect"
+
+	domain "github.com/adrianoccosta/exercise-qonto/internal/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockBeneficiaryService is a mock of BeneficiaryService interface.
+type MockBeneficiaryService struct {
+	ctrl     *gomock.Controller
+	recorder *MockBeneficiaryServiceMockRecorder
+}
+
+// MockBeneficiaryServiceMockRecorder is the mock recorder for MockBeneficiaryService.
+type MockBeneficiaryServiceMockRecorder struct {
+	mock *MockBeneficiaryService
+}
+
+// NewMockBeneficiaryService creates a new mock instance.
+func NewMockBeneficiaryService(ctrl *gomock.Controller) *MockBeneficiaryService {
+	mock := &MockBeneficiaryService{ctrl: ctrl}
+	mock.recorder = &MockBeneficiaryServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBeneficiaryService) EXPECT() *MockBeneficiaryServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockBeneficiaryService) Create(arg0 domain.Beneficiary) (domain.BeneficiaryDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0)
+	ret0, _ := ret[0].(domain.BeneficiaryDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockBeneficiaryServiceMockRecorder) Create(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockBeneficiaryService)(nil).Create), arg0)
+}
+
+// Delete mocks base method.
+func (m *MockBeneficiaryService) Delete(arg0 uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockBeneficiaryServiceMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockBeneficiaryService)(nil).Delete), arg0)
+}
+
+// Read mocks base method.
+func (m *MockBeneficiaryService) Read(arg0 uint) (domain.BeneficiaryDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", arg0)
+	ret0, _ := ret[0].(domain.BeneficiaryDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockBeneficiaryServiceMockRecorder) Read(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockBeneficiaryService)(nil).Read), arg0)
+}
+
+// ReadByFilter mocks base method.
+func (m *MockBeneficiaryService) ReadByFilter(arg0 map[string]string) (domain.BeneficiaryDetailList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadByFilter", arg0)
+	ret0, _ := ret[0].(domain.BeneficiaryDetailList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadByFilter indicates an expected call of ReadByFilter.
+func (mr *MockBeneficiaryServiceMockRecorder) ReadByFilter(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByFilter", reflect.TypeOf((*MockBeneficiaryService)(nil).ReadByFilter), arg0)
+}
+
+// Update mocks base method.
+func (m *MockBeneficiaryService) Update(arg0 uint, arg1 domain.Beneficiary) (domain.BeneficiaryDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(domain.BeneficiaryDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockBeneficiaryServiceMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockBeneficiaryService)(nil).Update), arg0, arg1)
+}
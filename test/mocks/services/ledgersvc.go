@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/adrianoccosta/exercise-qonto/internal/services/ledgersvc (interfaces: LedgerService)
+
+// Package mockservice is a generated GoMock package.
+package mockservice
+
+import (
+	reflect "reflect"
+
+	domain "github.com/adrianoccosta/exercise-qonto/internal/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockLedgerService is a mock of LedgerService interface.
+type MockLedgerService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLedgerServiceMockRecorder
+}
+
+// MockLedgerServiceMockRecorder is the mock recorder for MockLedgerService.
+type MockLedgerServiceMockRecorder struct {
+	mock *MockLedgerService
+}
+
+// NewMockLedgerService creates a new mock instance.
+func NewMockLedgerService(ctrl *gomock.Controller) *MockLedgerService {
+	mock := &MockLedgerService{ctrl: ctrl}
+	mock.recorder = &MockLedgerServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLedgerService) EXPECT() *MockLedgerServiceMockRecorder {
+	return m.recorder
+}
+
+// Balance mocks base method.
+func (m *MockLedgerService) Balance(arg0 string) (domain.LedgerBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Balance", arg0)
+	ret0, _ := ret[0].(domain.LedgerBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Balance indicates an expected call of Balance.
+func (mr *MockLedgerServiceMockRecorder) Balance(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Balance", reflect.TypeOf((*MockLedgerService)(nil).Balance), arg0)
+}
+
+// ReadEntries mocks base method.
+func (m *MockLedgerService) ReadEntries(arg0 map[string]string) (domain.LedgerEntryList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadEntries", arg0)
+	ret0, _ := ret[0].(domain.LedgerEntryList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadEntries indicates an expected call of ReadEntries.
+func (mr *MockLedgerServiceMockRecorder) ReadEntries(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadEntries", reflect.TypeOf((*MockLedgerService)(nil).ReadEntries), arg0)
+}
@@ -0,0 +1,155 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/adrianoccosta/exercise-qonto/internal/services/templatesvc (interfaces: TemplateService)
+
+// Package mockservice is a generated GoMock package.
+package mockservice
+
+import (
+	reflect "reflect"
+
+	domain "github.com/adrianoccosta/exercise-qonto/internal/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTemplateService is a mock of TemplateService interface.
+type MockTemplateService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTemplateServiceMockRecorder
+}
+
+// MockTemplateServiceMockRecorder is the mock recorder for MockTemplateService.
+type MockTemplateServiceMockRecorder struct {
+	mock *MockTemplateService
+}
+
+// NewMockTemplateService creates a new mock instance.
+func NewMockTemplateService(ctrl *gomock.Controller) *MockTemplateService {
+	mock := &MockTemplateService{ctrl: ctrl}
+	mock.recorder = &MockTemplateServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTemplateService) EXPECT() *MockTemplateServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockTemplateService) Create(arg0 domain.TransferTemplate) (domain.TransferTemplateDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0)
+	ret0, _ := ret[0].(domain.TransferTemplateDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockTemplateServiceMockRecorder) Create(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockTemplateService)(nil).Create), arg0)
+}
+
+// InstantiateDue mocks base method.
+func (m *MockTemplateService) InstantiateDue() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstantiateDue")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InstantiateDue indicates an expected call of InstantiateDue.
+func (mr *MockTemplateServiceMockRecorder) InstantiateDue() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstantiateDue", reflect.TypeOf((*MockTemplateService)(nil).InstantiateDue))
+}
+
+// Pause mocks base method.
+func (m *MockTemplateService) Pause(arg0 uint) (domain.TransferTemplateDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pause", arg0)
+	ret0, _ := ret[0].(domain.TransferTemplateDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Pause indicates an expected call of Pause.
+func (mr *MockTemplateServiceMockRecorder) Pause(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pause", reflect.TypeOf((*MockTemplateService)(nil).Pause), arg0)
+}
+
+// Read mocks base method.
+func (m *MockTemplateService) Read(arg0 uint) (domain.TransferTemplateDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", arg0)
+	ret0, _ := ret[0].(domain.TransferTemplateDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockTemplateServiceMockRecorder) Read(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockTemplateService)(nil).Read), arg0)
+}
+
+// ReadByFilter mocks base method.
+func (m *MockTemplateService) ReadByFilter(arg0 map[string]string) (domain.TransferTemplateDetailList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadByFilter", arg0)
+	ret0, _ := ret[0].(domain.TransferTemplateDetailList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadByFilter indicates an expected call of ReadByFilter.
+func (mr *MockTemplateServiceMockRecorder) ReadByFilter(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByFilter", reflect.TypeOf((*MockTemplateService)(nil).ReadByFilter), arg0)
+}
+
+// Resume mocks base method.
+func (m *MockTemplateService) Resume(arg0 uint) (domain.TransferTemplateDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resume", arg0)
+	ret0, _ := ret[0].(domain.TransferTemplateDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Resume indicates an expected call of Resume.
+func (mr *MockTemplateServiceMockRecorder) Resume(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resume", reflect.TypeOf((*MockTemplateService)(nil).Resume), arg0)
+}
+
+// Upcoming mocks base method.
+func (m *MockTemplateService) Upcoming(arg0 uint, arg1 int) (domain.TransferTemplateOccurrences, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upcoming", arg0, arg1)
+	ret0, _ := ret[0].(domain.TransferTemplateOccurrences)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Upcoming indicates an expected call of Upcoming.
+func (mr *MockTemplateServiceMockRecorder) Upcoming(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upcoming", reflect.TypeOf((*MockTemplateService)(nil).Upcoming), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockTemplateService) Update(arg0 uint, arg1 domain.TransferTemplate) (domain.TransferTemplateDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(domain.TransferTemplateDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockTemplateServiceMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockTemplateService)(nil).Update), arg0, arg1)
+}
@@ -48,3 +48,18 @@ func (mr *MockTransactionServiceMockRecorder) ReadByFilter(arg0 interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByFilter", reflect.TypeOf((*MockTransactionService)(nil).ReadByFilter), arg0)
 }
+
+// Reverse mocks base method.
+func (m *MockTransactionService) Reverse(arg0 uint, arg1 domain.TransactionReversal) (domain.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reverse", arg0, arg1)
+	ret0, _ := ret[0].(domain.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reverse indicates an expected call of Reverse.
+func (mr *MockTransactionServiceMockRecorder) Reverse(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reverse", reflect.TypeOf((*MockTransactionService)(nil).Reverse), arg0, arg1)
+}
@@ -34,12 +34,43 @@ func (m *MockTransferService) EXPECT() *MockTransferServiceMockRecorder {
 	return m.recorder
 }
 
+// Approve mocks base method.
+func (m *MockTransferService) Approve(arg0 uint, arg1 domain.BulkTransferApproval, arg2 string) (domain.BulkTransferDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Approve", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.BulkTransferDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Approve indicates an expected call of Approve.
+func (mr *MockTransferServiceMockRecorder) Approve(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Approve", reflect.TypeOf((*MockTransferService)(nil).Approve), arg0, arg1, arg2)
+}
+
+// Block mocks base method.
+func (m *MockTransferService) Block(arg0 uint, arg1 domain.BulkTransferBlock, arg2 string) (domain.BulkTransferDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Block", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.BulkTransferDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Block indicates an expected call of Block.
+func (mr *MockTransferServiceMockRecorder) Block(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Block", reflect.TypeOf((*MockTransferService)(nil).Block), arg0, arg1, arg2)
+}
+
 // BulkTransfer mocks base method.
-func (m *MockTransferService) BulkTransfer(arg0 domain.BulkTransfer) error {
+func (m *MockTransferService) BulkTransfer(arg0 domain.BulkTransfer) (domain.BulkTransferDetail, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "BulkTransfer", arg0)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(domain.BulkTransferDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // BulkTransfer indicates an expected call of BulkTransfer.
@@ -47,3 +78,167 @@ func (mr *MockTransferServiceMockRecorder) BulkTransfer(arg0 interface{}) *gomoc
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkTransfer", reflect.TypeOf((*MockTransferService)(nil).BulkTransfer), arg0)
 }
+
+// Cancel mocks base method.
+func (m *MockTransferService) Cancel(arg0 uint, arg1 domain.BulkTransferCancellation, arg2 string) (domain.BulkTransferDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cancel", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.BulkTransferDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Cancel indicates an expected call of Cancel.
+func (mr *MockTransferServiceMockRecorder) Cancel(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cancel", reflect.TypeOf((*MockTransferService)(nil).Cancel), arg0, arg1, arg2)
+}
+
+// ExecuteDue mocks base method.
+func (m *MockTransferService) ExecuteDue() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExecuteDue")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecuteDue indicates an expected call of ExecuteDue.
+func (mr *MockTransferServiceMockRecorder) ExecuteDue() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteDue", reflect.TypeOf((*MockTransferService)(nil).ExecuteDue))
+}
+
+// PendingJobs mocks base method.
+func (m *MockTransferService) PendingJobs() ([]uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingJobs")
+	ret0, _ := ret[0].([]uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PendingJobs indicates an expected call of PendingJobs.
+func (mr *MockTransferServiceMockRecorder) PendingJobs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingJobs", reflect.TypeOf((*MockTransferService)(nil).PendingJobs))
+}
+
+// Quote mocks base method.
+func (m *MockTransferService) Quote(arg0 domain.BulkTransfer) (domain.BulkTransferQuote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Quote", arg0)
+	ret0, _ := ret[0].(domain.BulkTransferQuote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Quote indicates an expected call of Quote.
+func (mr *MockTransferServiceMockRecorder) Quote(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Quote", reflect.TypeOf((*MockTransferService)(nil).Quote), arg0)
+}
+
+// Read mocks base method.
+func (m *MockTransferService) Read(arg0 uint) (domain.BulkTransferDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", arg0)
+	ret0, _ := ret[0].(domain.BulkTransferDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockTransferServiceMockRecorder) Read(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockTransferService)(nil).Read), arg0)
+}
+
+// ReadByFilter mocks base method.
+func (m *MockTransferService) ReadByFilter(arg0 map[string]string) (domain.BulkTransferDetailList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadByFilter", arg0)
+	ret0, _ := ret[0].(domain.BulkTransferDetailList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadByFilter indicates an expected call of ReadByFilter.
+func (mr *MockTransferServiceMockRecorder) ReadByFilter(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByFilter", reflect.TypeOf((*MockTransferService)(nil).ReadByFilter), arg0)
+}
+
+// ReadJob mocks base method.
+func (m *MockTransferService) ReadJob(arg0 uint) (domain.BulkTransferJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadJob", arg0)
+	ret0, _ := ret[0].(domain.BulkTransferJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadJob indicates an expected call of ReadJob.
+func (mr *MockTransferServiceMockRecorder) ReadJob(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadJob", reflect.TypeOf((*MockTransferService)(nil).ReadJob), arg0)
+}
+
+// Reject mocks base method.
+func (m *MockTransferService) Reject(arg0 uint, arg1 domain.BulkTransferRejection, arg2 string) (domain.BulkTransferDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reject", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.BulkTransferDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reject indicates an expected call of Reject.
+func (mr *MockTransferServiceMockRecorder) Reject(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reject", reflect.TypeOf((*MockTransferService)(nil).Reject), arg0, arg1, arg2)
+}
+
+// Release mocks base method.
+func (m *MockTransferService) Release(arg0 uint, arg1 domain.BulkTransferRelease, arg2 string) (domain.BulkTransferDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.BulkTransferDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockTransferServiceMockRecorder) Release(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockTransferService)(nil).Release), arg0, arg1, arg2)
+}
+
+// RunJob mocks base method.
+func (m *MockTransferService) RunJob(arg0 uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunJob", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RunJob indicates an expected call of RunJob.
+func (mr *MockTransferServiceMockRecorder) RunJob(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunJob", reflect.TypeOf((*MockTransferService)(nil).RunJob), arg0)
+}
+
+// Submit mocks base method.
+func (m *MockTransferService) Submit(arg0 domain.BulkTransfer) (domain.BulkTransferJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Submit", arg0)
+	ret0, _ := ret[0].(domain.BulkTransferJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Submit indicates an expected call of Submit.
+func (mr *MockTransferServiceMockRecorder) Submit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Submit", reflect.TypeOf((*MockTransferService)(nil).Submit), arg0)
+}
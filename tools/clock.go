@@ -0,0 +1,25 @@
+package tools
+
+import "time"
+
+// Clock tells the current time. It is injected wherever the time drives a decision, so it can be
+// fixed in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock the clock of the system
+type SystemClock struct{}
+
+// Now returns the current time in UTC
+func (SystemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// ClockFunc adapts a function to a Clock
+type ClockFunc func() time.Time
+
+// Now returns the time given by the function
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
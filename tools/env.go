@@ -127,3 +127,24 @@ func GetEnvBoolOrDefault(key string, defaultValue bool) (bool, bool) {
 
 	return boolValue, ok
 }
+
+// EnvFloat64OrDefault returns the environment float64 if found, or the default value if not found
+func EnvFloat64OrDefault(key string, defaultValue float64) float64 {
+	value, _ := GetEnvFloat64OrDefault(key, defaultValue)
+	return value
+}
+
+// GetEnvFloat64OrDefault returns the environment float64 if found, or the default value if not found, as well as a flag that specifies if the env key was found or not.
+func GetEnvFloat64OrDefault(key string, defaultValue float64) (float64, bool) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue, ok
+	}
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue, false
+	}
+
+	return floatValue, ok
+}
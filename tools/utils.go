@@ -12,6 +12,27 @@ const (
 	// HeaderXPartnerURN defines the x-partner-urn header
 	HeaderXPartnerURN = "x-partner-urn"
 
+	// HeaderIdempotencyKey defines the idempotency-key header
+	HeaderIdempotencyKey = "idempotency-key"
+
+	// HeaderIdempotentReplayed defines the idempotent-replayed header
+	HeaderIdempotentReplayed = "idempotent-replayed"
+
+	// HeaderActor defines the x-actor header, identifying who performs the request
+	HeaderActor = "x-actor"
+
+	// HeaderWebhookEvent defines the x-webhook-event header, the event a webhook delivery is about
+	HeaderWebhookEvent = "x-webhook-event"
+
+	// HeaderWebhookDelivery defines the x-webhook-delivery header, identifying a webhook delivery across its attempts
+	HeaderWebhookDelivery = "x-webhook-delivery"
+
+	// HeaderWebhookTimestamp defines the x-webhook-timestamp header, the unix time a webhook delivery was attempted at
+	HeaderWebhookTimestamp = "x-webhook-timestamp"
+
+	// HeaderWebhookSignature defines the x-webhook-signature header, the HMAC-SHA256 of the timestamp and the body
+	HeaderWebhookSignature = "x-webhook-signature"
+
 	jsonContentType = "application/json; charset=utf-8"
 )
 